@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// maxErrorBodyBytes bounds how much of an error response is printed
+const maxErrorBodyBytes = 64 << 10
+
+// downloadToFile downloads the share at rawURL into outputFile, or stdout
+// when outputFile is empty, reporting the content type and size on stderr
+func downloadToFile(ctx context.Context, client *http.Client, rawURL, outputFile string) error {
+	if outputFile == "" {
+		result, err := download(ctx, client, rawURL, os.Stdout)
+		if err != nil {
+			return err
+		}
+		reportDownload(result)
+		return nil
+	}
+
+	file, err := os.Create(outputFile)
+	if err != nil {
+		return fmt.Errorf("failed to create output file: %w", err)
+	}
+	result, err := download(ctx, client, rawURL, file)
+	if closeErr := file.Close(); err == nil && closeErr != nil {
+		err = fmt.Errorf("failed to write output file: %w", closeErr)
+	}
+	if err != nil {
+		// Leave no partial or error output behind
+		os.Remove(outputFile)
+		return err
+	}
+	reportDownload(result)
+	return nil
+}
+
+// reportDownload prints the content type and size of a download on stderr,
+// keeping stdout for the body
+func reportDownload(result *downloadResult) {
+	fmt.Fprintf(os.Stderr, "Content type: %s\n", result.contentType)
+	fmt.Fprintf(os.Stderr, "Size: %d bytes\n", result.size)
+}
+
+// downloadResult describes a downloaded share
+type downloadResult struct {
+	contentType string
+	size        int64
+}
+
+// download fetches the share at rawURL and writes its body to w. Responses
+// other than 200 OK fail with the error body the server returned.
+func download(ctx context.Context, client *http.Client, rawURL string, w io.Writer) (*downloadResult, error) {
+	shareURL, err := url.Parse(rawURL)
+	if err != nil || (shareURL.Scheme != "http" && shareURL.Scheme != "https") || shareURL.Host == "" {
+		return nil, fmt.Errorf("invalid share URL %q: must be an absolute http or https URL", rawURL)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, shareURL.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download share: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBodyBytes))
+		return nil, fmt.Errorf("server returned %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+
+	size, err := io.Copy(w, resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to write download: %w", err)
+	}
+
+	return &downloadResult{contentType: resp.Header.Get("Content-Type"), size: size}, nil
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDownload(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/1735689600/secret/images/photo.jpg" {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"error":"unauthorized","code":"UNAUTHORIZED","status":401,"message":"unauthorized"}`))
+			return
+		}
+		w.Header().Set("Content-Type", "image/jpeg")
+		w.Write([]byte("jpeg-bytes"))
+	}))
+	defer server.Close()
+
+	t.Run("writes the body", func(t *testing.T) {
+		var body bytes.Buffer
+		result, err := download(context.Background(), server.Client(), server.URL+"/1735689600/secret/images/photo.jpg", &body)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if body.String() != "jpeg-bytes" {
+			t.Errorf("expected body %q, got %q", "jpeg-bytes", body.String())
+		}
+		if result.contentType != "image/jpeg" || result.size != int64(len("jpeg-bytes")) {
+			t.Errorf("unexpected result: %+v", result)
+		}
+	})
+
+	t.Run("reports the server error", func(t *testing.T) {
+		var body bytes.Buffer
+		_, err := download(context.Background(), server.Client(), server.URL+"/1735689600/wrong/images/photo.jpg", &body)
+		if err == nil || !strings.Contains(err.Error(), "401") || !strings.Contains(err.Error(), `"error":"unauthorized"`) {
+			t.Errorf("expected the server error JSON, got %v", err)
+		}
+		if body.Len() != 0 {
+			t.Errorf("expected nothing written, got %q", body.String())
+		}
+	})
+
+	t.Run("rejects relative URLs", func(t *testing.T) {
+		if _, err := download(context.Background(), server.Client(), "/1735689600/secret/images/photo.jpg", &bytes.Buffer{}); err == nil {
+			t.Error("expected a relative URL to be rejected")
+		}
+	})
+
+	t.Run("writes the output file", func(t *testing.T) {
+		output := filepath.Join(t.TempDir(), "photo.jpg")
+		if err := downloadToFile(context.Background(), server.Client(), server.URL+"/1735689600/secret/images/photo.jpg", output); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		data, err := os.ReadFile(output)
+		if err != nil || string(data) != "jpeg-bytes" {
+			t.Errorf("expected the file to hold the body, got %q (%v)", data, err)
+		}
+	})
+
+	t.Run("removes the output file on failure", func(t *testing.T) {
+		output := filepath.Join(t.TempDir(), "photo.jpg")
+		if err := downloadToFile(context.Background(), server.Client(), server.URL+"/missing.jpg", output); err == nil {
+			t.Fatal("expected an error")
+		}
+		if _, err := os.Stat(output); !os.IsNotExist(err) {
+			t.Errorf("expected the output file to be removed, got %v", err)
+		}
+	})
+}
@@ -3,78 +3,238 @@ package main
 import (
 	"context"
 	"crypto/rand"
+	"crypto/tls"
 	"encoding/hex"
+	"errors"
 	"fmt"
+	"io"
 	"log"
+	"net/http"
 	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
 	"time"
-	"crypto/tls"
 
-	awsConfig "github.com/aws/aws-sdk-go-v2/config"
-	"github.com/aws/aws-sdk-go-v2/service/s3"
 	"github.com/redis/go-redis/v9"
 	"github.com/vchitai/go-s3-sharing/internal/config"
 	"github.com/vchitai/go-s3-sharing/internal/domain"
 	"github.com/vchitai/go-s3-sharing/internal/service"
 )
 
+const usage = `Usage:
+  go-s3-sharing-cli create [--qr] [--qr-out file.png] <s3-path> [expiration-hours]
+  go-s3-sharing-cli revoke <s3-path>
+  go-s3-sharing-cli list
+  go-s3-sharing-cli download <url> [output-file]
+
+Examples:
+  go-s3-sharing-cli create images/photo.jpg 24
+  go-s3-sharing-cli create --qr --qr-out photo-qr.png images/photo.jpg 24
+  go-s3-sharing-cli revoke images/photo.jpg
+  go-s3-sharing-cli list
+  go-s3-sharing-cli download https://files.example.com/1735689600/secret/images/photo.jpg photo.jpg`
+
+// errUsage reports command-line arguments that do not form a valid command
+var errUsage = errors.New("invalid arguments")
+
+// command is a parsed CLI invocation
+type command struct {
+	// name is the subcommand, "create", "revoke", "list" or "download"
+	name            string
+	s3Path          string
+	expirationHours int
+	// url and outputFile are the share link and destination of download;
+	// an empty outputFile writes to stdout
+	url        string
+	outputFile string
+	// qr prints the share link of create as a QR code, and qrOut, when set,
+	// also writes it as a PNG image
+	qr    bool
+	qrOut string
+	// legacy is set when create was invoked without naming the subcommand
+	legacy bool
+}
+
+// parseArgs parses the arguments following the program name. Arguments not
+// starting with a subcommand are parsed as create, as before subcommands
+// existed.
+func parseArgs(args []string) (*command, error) {
+	if len(args) == 0 {
+		return nil, errUsage
+	}
+
+	cmd := &command{name: args[0]}
+	switch args[0] {
+	case "create", "revoke", "list", "download":
+		args = args[1:]
+	default:
+		cmd.name = "create"
+		cmd.legacy = true
+	}
+
+	switch cmd.name {
+	case "list":
+		if len(args) != 0 {
+			return nil, errUsage
+		}
+		return cmd, nil
+
+	case "download":
+		if len(args) < 1 || len(args) > 2 {
+			return nil, errUsage
+		}
+		cmd.url = args[0]
+		if len(args) > 1 {
+			cmd.outputFile = args[1]
+		}
+		if cmd.url == "" {
+			return nil, errUsage
+		}
+		return cmd, nil
+
+	case "revoke":
+		if len(args) != 1 {
+			return nil, errUsage
+		}
+		cmd.s3Path = args[0]
+
+	case "create":
+		args, err := parseQRFlags(cmd, args)
+		if err != nil {
+			return nil, err
+		}
+		if len(args) < 1 || len(args) > 2 {
+			return nil, errUsage
+		}
+		cmd.s3Path = args[0]
+		cmd.expirationHours = 24
+		if len(args) > 1 {
+			hours, err := strconv.Atoi(args[1])
+			if err != nil || hours <= 0 {
+				return nil, fmt.Errorf("invalid expiration hours %q", args[1])
+			}
+			cmd.expirationHours = hours
+		}
+	}
+
+	if cmd.s3Path == "" {
+		return nil, errUsage
+	}
+	return cmd, nil
+}
+
+// parseQRFlags removes the QR code flags of create from args into cmd and
+// returns the remaining positional arguments
+func parseQRFlags(cmd *command, args []string) ([]string, error) {
+	var positional []string
+	for i := 0; i < len(args); i++ {
+		switch arg := args[i]; {
+		case arg == "--qr":
+			cmd.qr = true
+		case arg == "--qr-out":
+			if i+1 >= len(args) || args[i+1] == "" {
+				return nil, errors.New("--qr-out requires a file name")
+			}
+			i++
+			cmd.qrOut = args[i]
+		case strings.HasPrefix(arg, "--qr-out="):
+			cmd.qrOut = strings.TrimPrefix(arg, "--qr-out=")
+			if cmd.qrOut == "" {
+				return nil, errors.New("--qr-out requires a file name")
+			}
+		case strings.HasPrefix(arg, "--"):
+			return nil, fmt.Errorf("unknown flag %s", arg)
+		default:
+			positional = append(positional, arg)
+		}
+	}
+	return positional, nil
+}
+
 func main() {
-	if len(os.Args) < 2 {
-		fmt.Println("Usage: go-s3-sharing-cli <s3-path> [expiration-hours]")
-		fmt.Println("Example: go-s3-sharing-cli images/photo.jpg 24")
+	cmd, err := parseArgs(os.Args[1:])
+	if err != nil {
+		if err != errUsage {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		}
+		fmt.Fprintln(os.Stderr, usage)
 		os.Exit(1)
 	}
+	if cmd.legacy {
+		fmt.Fprintln(os.Stderr, "note: invoking without a subcommand is deprecated; use \"go-s3-sharing-cli create\"")
+	}
 
-	s3Path := os.Args[1]
-	expirationHours := 24
+	ctx := context.Background()
 
-	if len(os.Args) > 2 {
-		if _, err := fmt.Sscanf(os.Args[2], "%d", &expirationHours); err != nil {
-			log.Fatalf("invalid expiration hours: %v", err)
+	// Downloads only talk to the server and need no configuration
+	if cmd.name == "download" {
+		if err := downloadToFile(ctx, http.DefaultClient, cmd.url, cmd.outputFile); err != nil {
+			log.Fatal(err)
 		}
+		return
 	}
 
-	// Load configuration
-	cfg, err := config.Load()
+	// Load configuration, from CONFIG_FILE when set
+	loadConfig := config.Load
+	if path := os.Getenv("CONFIG_FILE"); path != "" {
+		loadConfig = func() (*config.Config, error) { return config.LoadFromFile(path) }
+	}
+	cfg, err := loadConfig()
 	if err != nil {
 		log.Fatalf("failed to load config: %v", err)
 	}
 
-	ctx := context.Background()
-
-	// Initialize AWS S3 client
-	awsCfg, err := awsConfig.LoadDefaultConfig(ctx)
+	shareService, err := newShareService(ctx, cfg)
 	if err != nil {
-		log.Fatalf("failed to load AWS config: %v", err)
+		log.Fatal(err)
 	}
 
-	s3Client := s3.NewFromConfig(awsCfg)
+	switch cmd.name {
+	case "create":
+		create(ctx, shareService, cmd)
+	case "revoke":
+		revoke(ctx, shareService, cmd)
+	case "list":
+		list(ctx, shareService)
+	}
+}
 
-	// Initialize Redis client
-	redisOptions := &redis.Options{
-		Addr:     cfg.Redis.Addr,
-		Password: cfg.Redis.Password,
-		DB:       cfg.Redis.DB,
+// newShareService connects to the storage backend and Redis as configured by
+// cfg
+func newShareService(ctx context.Context, cfg *config.Config) (*service.ShareService, error) {
+	// Initialize the configured storage backend, as the server does
+	storageService, err := service.NewStorageBackend(ctx, cfg.Storage.Backend, cfg)
+	if err != nil {
+		return nil, err
 	}
 
+	// Initialize Redis client
+	redisOptions := &redis.UniversalOptions{
+		Addrs:            cfg.Redis.Addresses(),
+		MasterName:       cfg.Redis.MasterName,
+		SentinelPassword: cfg.Redis.SentinelPassword,
+		Password:         cfg.Redis.Password,
+		DB:               cfg.Redis.DB,
+	}
 	if cfg.Redis.TLSEnabled {
 		redisOptions.TLSConfig = &tls.Config{
 			InsecureSkipVerify: true, // #nosec G402
 		}
 	}
-	redisClient := redis.NewClient(redisOptions)
+	redisClient, err := service.NewRedisClient(cfg.Redis.Mode, redisOptions)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Redis client: %w", err)
+	}
 
 	// Initialize services
-	storageService := service.NewS3Service(s3Client, cfg.AWS.Bucket)
 	cacheService := service.NewRedisService(redisClient)
 
-	shareConfig := &service.ShareConfig{
-		MaxAgeDays: cfg.Security.MaxAgeDays,
-		BaseURL:    cfg.BaseURL, // This should come from config
-	}
-
-	shareService := service.NewShareService(storageService, cacheService, shareConfig)
+	return service.NewShareService(storageService, cacheService, service.NewShareConfig(cfg)), nil
+}
 
+// create creates a share of cmd.s3Path and prints its link
+func create(ctx context.Context, shareService *service.ShareService, cmd *command) {
 	// Generate a secure secret
 	secret, err := generateSecret()
 	if err != nil {
@@ -82,9 +242,9 @@ func main() {
 	}
 
 	// Create share request
-	expiresAt := time.Now().Add(time.Duration(expirationHours) * time.Hour)
+	expiresAt := time.Now().Add(time.Duration(cmd.expirationHours) * time.Hour)
 	req := &domain.ShareRequest{
-		S3Path:    s3Path,
+		S3Path:    cmd.s3Path,
 		Secret:    secret,
 		ExpiresAt: expiresAt,
 	}
@@ -99,6 +259,60 @@ func main() {
 	fmt.Printf("Shareable URL: %s\n", resp.URL)
 	fmt.Printf("Expires at: %s\n", resp.ExpiresAt.Format(time.RFC3339))
 	fmt.Printf("Max age: %s\n", resp.MaxAge)
+
+	if cmd.qr {
+		fmt.Println()
+		if err := printQR(os.Stdout, resp.URL); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if cmd.qrOut != "" {
+		if err := writeQRPNG(cmd.qrOut, resp.URL); err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("QR code: %s\n", cmd.qrOut)
+	}
+}
+
+// revoke revokes the share of cmd.s3Path
+func revoke(ctx context.Context, shareService *service.ShareService, cmd *command) {
+	if err := shareService.RevokeShare(ctx, cmd.s3Path); err != nil {
+		if errors.Is(err, domain.ErrNotFound) {
+			log.Fatalf("no share exists for %s", cmd.s3Path)
+		}
+		log.Fatalf("failed to revoke share: %v", err)
+	}
+
+	fmt.Printf("Revoked share: %s\n", cmd.s3Path)
+}
+
+// list prints a table of the active shares
+func list(ctx context.Context, shareService *service.ShareService) {
+	shares, err := shareService.ListShares(ctx)
+	if err != nil {
+		log.Fatalf("failed to list shares: %v", err)
+	}
+
+	if err := printShares(os.Stdout, shares); err != nil {
+		log.Fatalf("failed to print shares: %v", err)
+	}
+}
+
+// printShares writes shares as a table with their expiry and remaining TTL
+func printShares(w io.Writer, shares []domain.ShareInfo) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "PATH\tEXPIRES AT\tTTL")
+	for _, share := range shares {
+		expiresAt, ttl := "never", "none"
+		if !share.ExpiresAt.IsZero() {
+			expiresAt = share.ExpiresAt.Format(time.RFC3339)
+		}
+		if share.TTL > 0 {
+			ttl = share.TTL.Round(time.Second).String()
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", share.S3Path, expiresAt, ttl)
+	}
+	return tw.Flush()
 }
 
 // generateSecret generates a cryptographically secure random secret
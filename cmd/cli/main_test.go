@@ -0,0 +1,161 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/vchitai/go-s3-sharing/internal/domain"
+)
+
+func TestParseArgs(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		expected *command
+		usageErr bool
+		wantErr  bool
+	}{
+		{
+			name:     "revoke",
+			args:     []string{"revoke", "images/photo.jpg"},
+			expected: &command{name: "revoke", s3Path: "images/photo.jpg"},
+		},
+		{
+			name:     "revoke without path",
+			args:     []string{"revoke"},
+			usageErr: true,
+		},
+		{
+			name:     "revoke with extra arguments",
+			args:     []string{"revoke", "images/photo.jpg", "24"},
+			usageErr: true,
+		},
+		{
+			name:     "list",
+			args:     []string{"list"},
+			expected: &command{name: "list"},
+		},
+		{
+			name:     "list with arguments",
+			args:     []string{"list", "images/"},
+			usageErr: true,
+		},
+		{
+			name:     "create",
+			args:     []string{"create", "images/photo.jpg", "48"},
+			expected: &command{name: "create", s3Path: "images/photo.jpg", expirationHours: 48},
+		},
+		{
+			name:     "create with default expiration",
+			args:     []string{"create", "images/photo.jpg"},
+			expected: &command{name: "create", s3Path: "images/photo.jpg", expirationHours: 24},
+		},
+		{
+			name:     "bare invocation creates",
+			args:     []string{"images/photo.jpg", "12"},
+			expected: &command{name: "create", s3Path: "images/photo.jpg", expirationHours: 12, legacy: true},
+		},
+		{
+			name:     "download to stdout",
+			args:     []string{"download", "https://files.example.com/1735689600/secret/images/photo.jpg"},
+			expected: &command{name: "download", url: "https://files.example.com/1735689600/secret/images/photo.jpg"},
+		},
+		{
+			name:     "download to file",
+			args:     []string{"download", "https://files.example.com/s/abc123", "photo.jpg"},
+			expected: &command{name: "download", url: "https://files.example.com/s/abc123", outputFile: "photo.jpg"},
+		},
+		{
+			name:     "download without URL",
+			args:     []string{"download"},
+			usageErr: true,
+		},
+		{
+			name:     "create with QR code",
+			args:     []string{"create", "--qr", "images/photo.jpg", "--qr-out", "photo.png", "48"},
+			expected: &command{name: "create", s3Path: "images/photo.jpg", expirationHours: 48, qr: true, qrOut: "photo.png"},
+		},
+		{
+			name:     "legacy create with QR file",
+			args:     []string{"images/photo.jpg", "--qr-out=photo.png"},
+			expected: &command{name: "create", s3Path: "images/photo.jpg", expirationHours: 24, qrOut: "photo.png", legacy: true},
+		},
+		{
+			name:    "QR file without name",
+			args:    []string{"create", "images/photo.jpg", "--qr-out"},
+			wantErr: true,
+		},
+		{
+			name:    "unknown flag",
+			args:    []string{"create", "--qrcode", "images/photo.jpg"},
+			wantErr: true,
+		},
+		{
+			name:    "invalid expiration",
+			args:    []string{"create", "images/photo.jpg", "soon"},
+			wantErr: true,
+		},
+		{
+			name:     "no arguments",
+			usageErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd, err := parseArgs(tt.args)
+			switch {
+			case tt.usageErr:
+				if !errors.Is(err, errUsage) {
+					t.Errorf("expected %v, got %v", errUsage, err)
+				}
+			case tt.wantErr:
+				if err == nil || errors.Is(err, errUsage) {
+					t.Errorf("expected an argument error, got %v", err)
+				}
+			default:
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if !reflect.DeepEqual(cmd, tt.expected) {
+					t.Errorf("expected %+v, got %+v", tt.expected, cmd)
+				}
+			}
+		})
+	}
+}
+
+func TestPrintShares(t *testing.T) {
+	expiresAt := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	shares := []domain.ShareInfo{
+		{S3Path: "images/photo.jpg", ExpiresAt: expiresAt, TTL: 90*time.Minute + 300*time.Millisecond, Active: true},
+		{S3Path: "docs/report.pdf", Active: true, TTL: -1},
+	}
+
+	var buf bytes.Buffer
+	if err := printShares(&buf, shares); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	expected := [][]string{
+		{"PATH", "EXPIRES", "AT", "TTL"},
+		{"images/photo.jpg", "2025-01-02T03:04:05Z", "1h30m0s"},
+		{"docs/report.pdf", "never", "none"},
+	}
+	if len(lines) != len(expected) {
+		t.Fatalf("expected %d lines, got %d: %q", len(expected), len(lines), buf.String())
+	}
+	for i, line := range lines {
+		if fields := strings.Fields(line); !reflect.DeepEqual(fields, expected[i]) {
+			t.Errorf("expected line %d to be %v, got %v", i, expected[i], fields)
+		}
+	}
+	if strings.Index(lines[0], "TTL") != strings.Index(lines[1], "1h30m0s") {
+		t.Errorf("expected aligned columns, got %q", buf.String())
+	}
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/skip2/go-qrcode"
+)
+
+// qrPNGSize is the width and height in pixels of QR code PNG files
+const qrPNGSize = 512
+
+// printQR renders content as a QR code of Unicode half blocks, which keeps
+// it small enough to scan from a terminal
+func printQR(w io.Writer, content string) error {
+	code, err := qrcode.New(content, qrcode.Medium)
+	if err != nil {
+		return fmt.Errorf("failed to encode QR code: %w", err)
+	}
+	_, err = io.WriteString(w, code.ToSmallString(false))
+	return err
+}
+
+// writeQRPNG writes content as a QR code PNG image to path
+func writeQRPNG(path, content string) error {
+	if err := qrcode.WriteFile(content, qrcode.Medium, qrPNGSize, path); err != nil {
+		return fmt.Errorf("failed to write QR code: %w", err)
+	}
+	return nil
+}
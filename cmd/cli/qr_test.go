@@ -0,0 +1,59 @@
+package main
+
+import (
+	"bytes"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/makiuchi-d/gozxing"
+	gozxingqr "github.com/makiuchi-d/gozxing/qrcode"
+)
+
+const testShareURL = "https://files.example.com/1735689600/0123456789abcdef0123456789abcdef/images/photo.jpg"
+
+func TestWriteQRPNG(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "share.png")
+	if err := writeQRPNG(path, testShareURL); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("expected the PNG file to exist: %v", err)
+	}
+	defer file.Close()
+
+	img, err := png.Decode(file)
+	if err != nil {
+		t.Fatalf("failed to decode PNG: %v", err)
+	}
+	bitmap, err := gozxing.NewBinaryBitmapFromImage(img)
+	if err != nil {
+		t.Fatalf("failed to read image: %v", err)
+	}
+	result, err := gozxingqr.NewQRCodeReader().Decode(bitmap, nil)
+	if err != nil {
+		t.Fatalf("failed to decode QR code: %v", err)
+	}
+	if result.GetText() != testShareURL {
+		t.Errorf("expected %s, got %s", testShareURL, result.GetText())
+	}
+}
+
+func TestPrintQR(t *testing.T) {
+	var buf bytes.Buffer
+	if err := printQR(&buf, testShareURL); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimRight(buf.String(), "\n"), "\n")
+	if len(lines) < 10 {
+		t.Fatalf("expected a QR code, got %q", buf.String())
+	}
+	if !strings.ContainsAny(buf.String(), "█▀▄") {
+		t.Errorf("expected block characters, got %q", buf.String())
+	}
+}
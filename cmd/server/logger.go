@@ -0,0 +1,55 @@
+package main
+
+import (
+	"io"
+	"log/slog"
+	"strings"
+
+	"github.com/vchitai/go-s3-sharing/internal/config"
+)
+
+// parseLogLevel parses debug, info, warn or error, reporting whether level
+// was recognized. Unknown levels parse as info.
+func parseLogLevel(level string) (slog.Level, bool) {
+	switch strings.ToLower(strings.TrimSpace(level)) {
+	case "debug":
+		return slog.LevelDebug, true
+	case "info", "":
+		return slog.LevelInfo, true
+	case "warn", "warning":
+		return slog.LevelWarn, true
+	case "error":
+		return slog.LevelError, true
+	default:
+		return slog.LevelInfo, false
+	}
+}
+
+// newLogHandler creates a json or text handler writing to w, reporting
+// whether format was recognized. Unknown formats use json.
+func newLogHandler(w io.Writer, format string, opts *slog.HandlerOptions) (slog.Handler, bool) {
+	switch strings.ToLower(strings.TrimSpace(format)) {
+	case "json", "":
+		return slog.NewJSONHandler(w, opts), true
+	case "text":
+		return slog.NewTextHandler(w, opts), true
+	default:
+		return slog.NewJSONHandler(w, opts), false
+	}
+}
+
+// newLogger creates the logger configured by cfg, warning through it about
+// values it fell back from
+func newLogger(w io.Writer, cfg config.LogConfig) *slog.Logger {
+	level, levelOK := parseLogLevel(cfg.Level)
+	handler, formatOK := newLogHandler(w, cfg.Format, &slog.HandlerOptions{Level: level})
+
+	logger := slog.New(handler)
+	if !levelOK {
+		logger.Warn("unknown log level, using info", "log_level", cfg.Level)
+	}
+	if !formatOK {
+		logger.Warn("unknown log format, using json", "log_format", cfg.Format)
+	}
+	return logger
+}
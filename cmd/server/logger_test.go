@@ -0,0 +1,86 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/vchitai/go-s3-sharing/internal/config"
+)
+
+func TestParseLogLevel(t *testing.T) {
+	tests := []struct {
+		level         string
+		expected      slog.Level
+		expectedKnown bool
+	}{
+		{level: "debug", expected: slog.LevelDebug, expectedKnown: true},
+		{level: "info", expected: slog.LevelInfo, expectedKnown: true},
+		{level: "", expected: slog.LevelInfo, expectedKnown: true},
+		{level: "WARN", expected: slog.LevelWarn, expectedKnown: true},
+		{level: "warning", expected: slog.LevelWarn, expectedKnown: true},
+		{level: "error", expected: slog.LevelError, expectedKnown: true},
+		{level: "verbose", expected: slog.LevelInfo, expectedKnown: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.level, func(t *testing.T) {
+			level, known := parseLogLevel(tt.level)
+			if level != tt.expected {
+				t.Errorf("expected level %v, got %v", tt.expected, level)
+			}
+			if known != tt.expectedKnown {
+				t.Errorf("expected known %v, got %v", tt.expectedKnown, known)
+			}
+		})
+	}
+}
+
+func TestNewLogger(t *testing.T) {
+	t.Run("text at debug", func(t *testing.T) {
+		var buf bytes.Buffer
+		logger := newLogger(&buf, config.LogConfig{Level: "debug", Format: "text"})
+
+		logger.Debug("probe", "key", "value")
+		if out := buf.String(); !strings.Contains(out, "level=DEBUG") || !strings.Contains(out, "key=value") {
+			t.Errorf("expected a text debug line, got %q", out)
+		}
+	})
+
+	t.Run("level filters lower records", func(t *testing.T) {
+		var buf bytes.Buffer
+		logger := newLogger(&buf, config.LogConfig{Level: "error", Format: "json"})
+
+		logger.Warn("probe")
+		if buf.Len() != 0 {
+			t.Errorf("expected warnings to be filtered at error level, got %q", buf.String())
+		}
+	})
+
+	t.Run("invalid values fall back with warnings", func(t *testing.T) {
+		var buf bytes.Buffer
+		logger := newLogger(&buf, config.LogConfig{Level: "verbose", Format: "xml"})
+
+		lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+		if len(lines) != 2 {
+			t.Fatalf("expected 2 warnings, got %q", buf.String())
+		}
+		for _, line := range lines {
+			var record map[string]any
+			if err := json.Unmarshal([]byte(line), &record); err != nil {
+				t.Fatalf("expected json output, got %q", line)
+			}
+			if record["level"] != "WARN" {
+				t.Errorf("expected a warning, got %v", record["level"])
+			}
+		}
+
+		buf.Reset()
+		logger.Debug("probe")
+		if buf.Len() != 0 {
+			t.Errorf("expected debug records to be filtered at the info fallback, got %q", buf.String())
+		}
+	})
+}
@@ -3,56 +3,68 @@ package main
 import (
 	"context"
 	"crypto/tls"
+	"html/template"
 	"log"
-	"log/slog"
 	"os"
 	"os/signal"
 	"syscall"
 	"time"
 
-	awsConfig "github.com/aws/aws-sdk-go-v2/config"
-	"github.com/aws/aws-sdk-go-v2/service/s3"
 	"github.com/redis/go-redis/v9"
 	"github.com/vchitai/go-s3-sharing/internal/config"
 	"github.com/vchitai/go-s3-sharing/internal/service"
+	"github.com/vchitai/go-s3-sharing/internal/telemetry"
 	"github.com/vchitai/go-s3-sharing/internal/transport/http"
 )
 
 func main() {
-	// Load configuration
-	cfg, err := config.Load()
+	// Load configuration, from CONFIG_FILE when set
+	loadConfig := config.Load
+	if path := os.Getenv("CONFIG_FILE"); path != "" {
+		loadConfig = func() (*config.Config, error) { return config.LoadFromFile(path) }
+	}
+	cfg, err := loadConfig()
 	if err != nil {
 		log.Fatalf("failed to load config: %v", err)
 	}
 
 	// Setup logger
-	logger := slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{
-		Level: slog.LevelInfo,
-	}))
+	logger := newLogger(os.Stdout, cfg.Log)
 
 	ctx := context.Background()
 
-	// Initialize AWS S3 client
-	awsCfg, err := awsConfig.LoadDefaultConfig(ctx)
+	// Initialize tracing; spans are only exported when an OTLP endpoint is set
+	shutdownTracing, err := telemetry.Setup(ctx, cfg.Tracing.OTLPEndpoint, cfg.Tracing.ServiceName)
 	if err != nil {
-		logger.Error("failed to load AWS config", "error", err)
+		logger.Error("failed to set up tracing", "error", err)
 		os.Exit(1)
 	}
 
-	s3Client := s3.NewFromConfig(awsCfg)
+	// Initialize the configured storage backend
+	backend, err := service.NewStorageBackend(ctx, cfg.Storage.Backend, cfg)
+	if err != nil {
+		logger.Error("failed to create storage backend", "backend", cfg.Storage.Backend, "error", err)
+		os.Exit(1)
+	}
 
 	// Initialize Redis client
-	redisOptions := &redis.Options{
-		Addr:     cfg.Redis.Addr,
-		Password: cfg.Redis.Password,
-		DB:       cfg.Redis.DB,
+	redisOptions := &redis.UniversalOptions{
+		Addrs:            cfg.Redis.Addresses(),
+		MasterName:       cfg.Redis.MasterName,
+		SentinelPassword: cfg.Redis.SentinelPassword,
+		Password:         cfg.Redis.Password,
+		DB:               cfg.Redis.DB,
 	}
 	if cfg.Redis.TLSEnabled {
 		redisOptions.TLSConfig = &tls.Config{
 			InsecureSkipVerify: true, // #nosec G402
 		}
 	}
-	redisClient := redis.NewClient(redisOptions)
+	redisClient, err := service.NewRedisClient(cfg.Redis.Mode, redisOptions)
+	if err != nil {
+		logger.Error("failed to create Redis client", "error", err)
+		os.Exit(1)
+	}
 
 	// Test Redis connection
 	if err := redisClient.Ping(ctx).Err(); err != nil {
@@ -61,18 +73,43 @@ func main() {
 	}
 
 	// Initialize services
-	storageService := service.NewS3Service(s3Client, cfg.AWS.Bucket)
-	cacheService := service.NewRedisService(redisClient)
+	storageService := http.InstrumentStorage(cfg.Storage.Backend, backend)
+	if cfg.Breaker.FailureThreshold > 0 {
+		storageService = service.NewBreakerStorage(storageService, service.BreakerConfig{
+			FailureThreshold: cfg.Breaker.FailureThreshold,
+			OpenTimeout:      cfg.Breaker.OpenTimeout,
+			OnStateChange: func(state service.BreakerState) {
+				logger.Warn("storage circuit breaker changed state", "state", state.String())
+				http.ObserveBreakerState(state)
+			},
+		})
+	}
+	// Serve small objects from memory on single-instance deployments
+	if cfg.ObjectCache.MemoryBytes > 0 {
+		storageService = service.NewLRUCache(int64(cfg.ObjectCache.MemoryBytes)).Wrap(storageService)
+	}
+	cacheService := http.InstrumentCache("redis", service.NewRedisService(redisClient))
+
+	shareConfig := service.NewShareConfig(cfg)
+	shareConfig.OnObjectCacheLookup = http.ObserveObjectCacheLookup
 
-	shareConfig := &service.ShareConfig{
-		MaxAgeDays: cfg.Security.MaxAgeDays,
-		BaseURL:    cfg.BaseURL, // This should come from config
+	if cfg.Audit.Enabled {
+		shareConfig.Audit = service.NewRedisAuditSink(redisClient, cfg.Audit.MaxEntries, cfg.Audit.Retention)
 	}
 
 	shareService := service.NewShareService(storageService, cacheService, shareConfig)
 
-	// Initialize HTTP server
-	server := http.NewServer(cfg, shareService, logger)
+	// Initialize HTTP server, with the branded error page when configured
+	var serverOptions []http.ServerOption
+	if cfg.Server.ErrorPageTemplate != "" {
+		page, err := template.ParseFiles(cfg.Server.ErrorPageTemplate)
+		if err != nil {
+			logger.Error("failed to load error page template", "path", cfg.Server.ErrorPageTemplate, "error", err)
+			os.Exit(1)
+		}
+		serverOptions = append(serverOptions, http.WithErrorPage(page))
+	}
+	server := http.NewServer(cfg, shareService, logger, serverOptions...)
 
 	// Start server in a goroutine
 	go func() {
@@ -97,5 +134,9 @@ func main() {
 		os.Exit(1)
 	}
 
+	if err := shutdownTracing(shutdownCtx); err != nil {
+		logger.Error("tracing shutdown error", "error", err)
+	}
+
 	logger.Info("server stopped")
 }
@@ -51,7 +51,9 @@ func main() {
 
 	shareConfig := &service.ShareConfig{
 		MaxAgeDays: cfg.Security.MaxAgeDays,
-		BaseURL:    "https://your-domain.com",
+		BaseURL:    cfg.BaseURL,
+		SigningKey: cfg.Security.SigningKey,
+		SignedURLs: cfg.Security.SignedURLs,
 	}
 
 	shareService := service.NewShareService(storageService, cacheService, shareConfig)
@@ -85,7 +87,7 @@ func main() {
 
 	// Example: Validate a share
 	fmt.Printf("\nValidating share...\n")
-	err = shareService.ValidateShare(ctx, s3Path, secret)
+	err = shareService.ValidateShare(ctx, s3Path, secret, expiresAt)
 	if err != nil {
 		log.Fatalf("Failed to validate share: %v", err)
 	}
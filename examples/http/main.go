@@ -51,7 +51,7 @@ func main() {
 	shareURL := createResp["url"].(string)
 
 	// Extract the path from the URL for the GET request
-	// Assuming the URL format is: http://localhost:8080/yy/mm/dd/secret/path
+	// Assuming the URL format is: http://localhost:8080/expiry/secret/path
 	// We need to extract the path part after the base URL
 	path := shareURL[len(baseURL):]
 
@@ -1,79 +1,940 @@
 package config
 
 import (
+	"crypto/tls"
+	"encoding/base64"
 	"fmt"
+	"io"
+	"mime"
+	"net/url"
 	"os"
+	"slices"
 	"strconv"
+	"strings"
 	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/vchitai/go-s3-sharing/internal/domain"
 )
 
 // Config holds all configuration for the S3 sharing service
 type Config struct {
-	Server   ServerConfig
-	AWS      AWSConfig
-	Redis    RedisConfig
-	Security SecurityConfig
-	BaseURL  string
+	Server      ServerConfig      `yaml:"server"`
+	Storage     StorageConfig     `yaml:"storage"`
+	AWS         AWSConfig         `yaml:"aws"`
+	Redis       RedisConfig       `yaml:"redis"`
+	Security    SecurityConfig    `yaml:"security"`
+	RateLimit   RateLimitConfig   `yaml:"rate_limit"`
+	Tracing     TracingConfig     `yaml:"tracing"`
+	Log         LogConfig         `yaml:"log"`
+	CORS        CORSConfig        `yaml:"cors"`
+	Compression CompressionConfig `yaml:"compression"`
+	Cache       CacheConfig       `yaml:"cache_control"`
+	ObjectCache ObjectCacheConfig `yaml:"object_cache"`
+	Resize      ResizeConfig      `yaml:"resize"`
+	Audit       AuditConfig       `yaml:"audit"`
+	Webhook     WebhookConfig     `yaml:"webhook"`
+	Auth        AuthConfig        `yaml:"auth"`
+	Breaker     BreakerConfig     `yaml:"circuit_breaker"`
+	// Tenants configures individual tenants by ID; tenants not listed use the
+	// default key prefix and bucket
+	Tenants map[string]TenantConfig `yaml:"tenants"`
+	// BaseURL is the absolute http or https URL share links are generated
+	// under, without a trailing slash
+	BaseURL string `yaml:"base_url"`
 }
 
+// Bounds of ServerConfig.StreamBufferSize; smaller buffers cost a syscall per
+// few packets, larger ones only hold memory per concurrent download
+const (
+	minStreamBufferSize = 4 << 10
+	maxStreamBufferSize = 8 << 20
+)
+
 // ServerConfig holds HTTP server configuration
 type ServerConfig struct {
-	Port         string
-	ReadTimeout  time.Duration
-	WriteTimeout time.Duration
-	IdleTimeout  time.Duration
+	Port         string        `yaml:"port"`
+	ReadTimeout  time.Duration `yaml:"read_timeout"`
+	WriteTimeout time.Duration `yaml:"write_timeout"`
+	IdleTimeout  time.Duration `yaml:"idle_timeout"`
+	// DownloadWriteTimeout replaces WriteTimeout on the routes streaming
+	// shared objects and archives; zero lets downloads run indefinitely
+	DownloadWriteTimeout time.Duration `yaml:"download_write_timeout"`
+	// ErrorPageTemplate is the path of an html/template rendering the errors
+	// of share links for browsers; empty answers every client with JSON
+	ErrorPageTemplate string `yaml:"error_page_template"`
+	// TLSCertFile and TLSKeyFile enable HTTPS when both are set
+	TLSCertFile string `yaml:"tls_cert_file"`
+	TLSKeyFile  string `yaml:"tls_key_file"`
+	// TLSMinVersion is the minimum accepted TLS version, e.g. tls.VersionTLS12
+	TLSMinVersion TLSVersion `yaml:"tls_min_version"`
+	// MaxBatchSize bounds the number of shares of a batch creation
+	MaxBatchSize int `yaml:"max_batch_size"`
+	// MaxRequestBytes bounds the JSON bodies of API requests and the password
+	// forms of share links
+	MaxRequestBytes int `yaml:"max_request_bytes"`
+	// StreamBufferSize is the size of the pooled buffers objects are streamed
+	// to clients through
+	StreamBufferSize int `yaml:"stream_buffer_size"`
+	// MaxConcurrentStreams bounds the downloads streaming at once; zero is
+	// unlimited
+	MaxConcurrentStreams int `yaml:"max_concurrent_streams"`
+	// StreamQueueTimeout is how long downloads over MaxConcurrentStreams wait
+	// for a stream to finish before being rejected; zero rejects them at once
+	StreamQueueTimeout time.Duration `yaml:"stream_queue_timeout"`
+	// TrustedProxies lists the CIDRs or addresses of the proxies whose
+	// X-Forwarded-For headers identify the client; empty trusts none and
+	// uses the connection's address
+	TrustedProxies []string `yaml:"trusted_proxies"`
+	// PprofEnabled serves net/http/pprof profiles on PprofAddr, a listener
+	// separate from Port that should not be reachable publicly
+	PprofEnabled bool   `yaml:"pprof_enabled"`
+	PprofAddr    string `yaml:"pprof_addr"`
+}
+
+// TLSVersion is a crypto/tls version constant written as "1.2" in config files
+type TLSVersion uint16
+
+// UnmarshalYAML parses a version such as "1.2"
+func (v *TLSVersion) UnmarshalYAML(node *yaml.Node) error {
+	version, err := parseTLSVersion(node.Value)
+	if err != nil {
+		return err
+	}
+	*v = version
+	return nil
+}
+
+// StorageConfig selects the backend objects are stored in
+type StorageConfig struct {
+	// Backend names the storage backend: s3, gcs, fs or one registered with
+	// service.RegisterStorageBackend
+	Backend string `yaml:"backend"`
+	// FSRoot is the directory the fs backend serves objects from
+	FSRoot string `yaml:"fs_root"`
+	// GCSBucket is the bucket the gcs backend serves objects from
+	GCSBucket string `yaml:"gcs_bucket"`
 }
 
 // AWSConfig holds AWS S3 configuration
 type AWSConfig struct {
-	Region string
-	Bucket string
+	Region string `yaml:"region"`
+	Bucket string `yaml:"bucket"`
+	// Endpoint overrides the S3 endpoint, e.g. for MinIO or LocalStack
+	Endpoint     string `yaml:"endpoint"`
+	UsePathStyle bool   `yaml:"use_path_style"`
+	// UseAccelerate sends requests to the S3 Transfer Acceleration endpoint
+	// of the bucket, which must have acceleration enabled
+	UseAccelerate bool `yaml:"use_accelerate"`
+	// UseDualStack sends requests to the IPv4 and IPv6 dual-stack endpoints
+	UseDualStack bool `yaml:"use_dualstack"`
+	// RetryMaxAttempts bounds the attempts of object reads failing with
+	// transient errors; one disables retries
+	RetryMaxAttempts int `yaml:"retry_max_attempts"`
+	// RetryBaseDelay is the backoff before the first retry, doubling after each
+	RetryBaseDelay time.Duration `yaml:"retry_base_delay"`
+	// PrefixBuckets maps key prefixes, such as "videos/", to the buckets
+	// storing their objects; other keys are stored in Bucket
+	PrefixBuckets map[string]string `yaml:"prefix_buckets"`
+	// SSECustomerKey is the base64-encoded 256-bit key objects are encrypted
+	// with using SSE-C; empty reads objects without SSE-C
+	SSECustomerKey string `yaml:"sse_customer_key"`
+	// DefaultContentType is served for objects S3 reports no specific type
+	// for and whose extension is unknown
+	DefaultContentType string `yaml:"default_content_type"`
+}
+
+// SSECustomerKeyBytes returns the decoded SSE-C key, or nil when none is
+// configured
+func (c AWSConfig) SSECustomerKeyBytes() []byte {
+	// validate has checked that the key decodes
+	key, _ := base64.StdEncoding.DecodeString(c.SSECustomerKey)
+	if len(key) == 0 {
+		return nil
+	}
+	return key
 }
 
 // RedisConfig holds Redis configuration
 type RedisConfig struct {
-	Addr       string
-	Password   string
-	DB         int
-	TLSEnabled bool
+	// Mode is standalone, cluster or sentinel
+	Mode string `yaml:"mode"`
+	Addr string `yaml:"addr"`
+	// Addrs lists the cluster seed nodes in cluster mode and the sentinels
+	// in sentinel mode; Addr is used when empty
+	Addrs []string `yaml:"addrs"`
+	// MasterName is the name of the master set monitored by the sentinels
+	MasterName string `yaml:"master_name"`
+	// SentinelPassword authenticates with the sentinels themselves when
+	// they require a password of their own
+	SentinelPassword string `yaml:"sentinel_password"`
+	Password         string `yaml:"password"`
+	DB               int    `yaml:"db"`
+	TLSEnabled       bool   `yaml:"tls_enabled"`
+	// KeyPrefix namespaces every key, so that environments can share one
+	// Redis without seeing each other's shares
+	KeyPrefix string `yaml:"key_prefix"`
+	// SweepInterval is how often share records past their expiry but still
+	// stored are deleted; zero disables the sweeper
+	SweepInterval time.Duration `yaml:"sweep_interval"`
+}
+
+// Addresses returns the addresses to connect to: Addrs when set, else Addr
+func (c RedisConfig) Addresses() []string {
+	if len(c.Addrs) > 0 {
+		return c.Addrs
+	}
+	return []string{c.Addr}
 }
 
 // SecurityConfig holds security-related configuration
 type SecurityConfig struct {
-	MaxAgeDays int
+	MaxAgeDays int    `yaml:"max_age_days"`
+	SigningKey string `yaml:"signing_key"`
+	SignedURLs bool   `yaml:"signed_urls"`
+	// SignedURLFallback serves signed links on their signature and expiry
+	// alone while Redis is unreachable, without the revocations, download
+	// limits, passwords and referer and IP restrictions kept in Redis
+	SignedURLFallback bool `yaml:"signed_url_fallback"`
+	// ShareCookies lets holders of a share link exchange it for a signed
+	// cookie granting the share, signed with SigningKey
+	ShareCookies bool `yaml:"share_cookies"`
+	// MaxFailedAttempts is the number of invalid secrets within LockoutWindow
+	// that locks a path; zero disables the lockout
+	MaxFailedAttempts int           `yaml:"max_failed_attempts"`
+	LockoutWindow     time.Duration `yaml:"lockout_window"`
+	// IdempotencyWindow is how long share creations are replayed for repeats
+	// of their Idempotency-Key header; zero ignores the header
+	IdempotencyWindow time.Duration `yaml:"idempotency_window"`
+	// SecretGracePeriod is how long a share secret replaced by a rotation
+	// stays valid; zero invalidates it at once
+	SecretGracePeriod time.Duration `yaml:"secret_grace_period"`
+	// AllowedReferers restricts downloads of shares without their own
+	// allowlist to pages on these hosts, e.g. "*.example.com"; empty allows
+	// every referer
+	AllowedReferers []string `yaml:"allowed_referers"`
+	// AllowEmptyReferer lets requests without a Referer header through a
+	// referer allowlist, as sent by direct visits and privacy settings
+	AllowEmptyReferer bool `yaml:"allow_empty_referer"`
+	// ShareablePrefixes restricts shares to keys under these prefixes, e.g.
+	// "public/"; empty allows every key
+	ShareablePrefixes []string `yaml:"shareable_prefixes"`
+	// HardenedErrors answers every failed share link with the same 404, so
+	// that missing, expired and protected shares cannot be told apart
+	HardenedErrors bool `yaml:"hardened_errors"`
+	// HardenedErrorDelay is the time hardened 404s are sent after, measured
+	// from the arrival of the request, hiding how far validation got
+	HardenedErrorDelay time.Duration `yaml:"hardened_error_delay"`
+}
+
+// RateLimitConfig holds per-client-IP rate limiting configuration
+type RateLimitConfig struct {
+	// RPS is the sustained requests per second allowed per client; zero disables limiting
+	RPS   float64 `yaml:"rps"`
+	Burst int     `yaml:"burst"`
+	// DownloadBytesPerSec limits the rate of each download stream; zero is unlimited
+	DownloadBytesPerSec int `yaml:"download_bytes_per_sec"`
+}
+
+// TracingConfig holds OpenTelemetry tracing configuration
+type TracingConfig struct {
+	// OTLPEndpoint is the OTLP/HTTP collector URL; empty disables span export
+	OTLPEndpoint string `yaml:"otlp_endpoint"`
+	ServiceName  string `yaml:"service_name"`
+}
+
+// LogConfig holds structured logging configuration
+type LogConfig struct {
+	// Level is debug, info, warn or error
+	Level string `yaml:"level"`
+	// Format is json or text
+	Format string `yaml:"format"`
+}
+
+// BreakerConfig holds the storage circuit breaker configuration
+type BreakerConfig struct {
+	// FailureThreshold is the number of consecutive storage failures that
+	// opens the breaker; zero disables it
+	FailureThreshold int `yaml:"failure_threshold"`
+	// OpenTimeout is how long storage calls fail fast before a probe
+	OpenTimeout time.Duration `yaml:"open_timeout"`
+}
+
+// CORSConfig holds CORS configuration for the image and download routes
+type CORSConfig struct {
+	// AllowedOrigins lists the origins allowed to fetch shared objects; "*"
+	// allows every origin and an empty list disables CORS
+	AllowedOrigins []string `yaml:"allowed_origins"`
+}
+
+// CompressionConfig holds response compression configuration
+type CompressionConfig struct {
+	// ContentTypes lists the media types gzipped for clients that accept it,
+	// e.g. "image/svg+xml" or "text/*"; an empty list disables compression
+	ContentTypes []string `yaml:"content_types"`
+}
+
+// CacheConfig holds the Cache-Control directives of shared objects.
+// Password-protected and download-limited shares are always sent with
+// "private, no-store", and max-age never exceeds a share's remaining lifetime.
+type CacheConfig struct {
+	// Rules map media types to directives; the first rule matching a
+	// response's Content-Type wins
+	Rules []CacheRule `yaml:"rules"`
+	// Default applies to content types matching no rule; empty sends no
+	// Cache-Control header
+	Default string `yaml:"default"`
+}
+
+// ObjectCacheConfig holds the caches of small object bodies
+type ObjectCacheConfig struct {
+	// MaxBytes is the size up to which objects are cached in Redis; zero
+	// disables the Redis object cache
+	MaxBytes int `yaml:"max_bytes"`
+	// TTL is how long an object cached in Redis is served without
+	// refetching it
+	TTL time.Duration `yaml:"ttl"`
+	// MemoryBytes is the budget of the in-process LRU cache, which
+	// revalidates every read against the object's ETag; zero disables it
+	MemoryBytes int `yaml:"memory_bytes"`
+}
+
+// ResizeConfig holds the resizing of shared images through the w, h and fit
+// query parameters
+type ResizeConfig struct {
+	// MaxDimension bounds the requested width and height; zero disables
+	// resizing, serving images at their stored size
+	MaxDimension int `yaml:"max_dimension"`
+	// MaxPixels bounds the width times height of images decoded for
+	// resizing, protecting memory from huge or malicious images
+	MaxPixels int `yaml:"max_pixels"`
+	// Sizes, when set, lists the widths and heights images are resized to;
+	// requested sizes are rounded up to the next listed size, bounding the
+	// number of variants clients can make the service compute and cache
+	Sizes []int `yaml:"sizes"`
+	// CacheTTL is how long resized images are cached; zero resizes on every
+	// request
+	CacheTTL time.Duration `yaml:"cache_ttl"`
+}
+
+// AuditConfig holds the audit trail of share link accesses
+type AuditConfig struct {
+	// Enabled records every access to a share link in Redis
+	Enabled bool `yaml:"enabled"`
+	// MaxEntries is the number of most recent accesses kept per path
+	MaxEntries int `yaml:"max_entries"`
+	// Retention expires the trail of a path that long after its last
+	// access; zero keeps trails forever
+	Retention time.Duration `yaml:"retention"`
+}
+
+// WebhookConfig holds the delivery of share webhook notifications
+type WebhookConfig struct {
+	// Timeout bounds each delivery attempt
+	Timeout time.Duration `yaml:"timeout"`
+	// MaxAttempts bounds the attempts of a delivery failing with network
+	// errors or 5xx responses
+	MaxAttempts int `yaml:"max_attempts"`
+}
+
+// CacheRule sets the Cache-Control directives for a media type
+type CacheRule struct {
+	// ContentType is an exact media type or a wildcard such as "image/*"
+	ContentType string `yaml:"content_type"`
+	// Directives is the Cache-Control value, e.g. "public, max-age=86400"
+	Directives string `yaml:"directives"`
+}
+
+// AuthConfig holds JWT bearer-token authentication of the /api routes
+type AuthConfig struct {
+	// JWTSecret verifies HMAC-signed tokens; at most one of JWTSecret and
+	// JWKSURL may be set, and setting neither disables authentication
+	JWTSecret string `yaml:"jwt_secret"`
+	// JWKSURL serves the public keys verifying RSA and ECDSA signed tokens
+	JWKSURL string `yaml:"jwks_url"`
+	// Audience, when set, must appear in the aud claim of every token
+	Audience string `yaml:"audience"`
+	// RequiredScope, when set, must appear in the space-separated scope claim
+	RequiredScope string `yaml:"required_scope"`
+	// APIKeys are accepted in the X-API-Key header in place of a bearer token
+	APIKeys []APIKeyConfig `yaml:"api_keys"`
+	// APIKeyDailyQuota is the daily share creation quota of API keys that do
+	// not set their own; zero is unlimited
+	APIKeyDailyQuota int `yaml:"api_key_daily_quota"`
+}
+
+// APIKeyConfig holds a single API key
+type APIKeyConfig struct {
+	// Name identifies the key's owner in configuration only
+	Name string `yaml:"name"`
+	Key  string `yaml:"key"`
+	// DailyQuota overrides APIKeyDailyQuota for this key
+	DailyQuota int `yaml:"daily_quota"`
+	// Tenants lists the tenants the key may act on, "*" granting every
+	// tenant; keys without tenants act on the default tenant only
+	Tenants []string `yaml:"tenants"`
+}
+
+// Enabled reports whether the /api routes require a bearer token or API key
+func (c AuthConfig) Enabled() bool {
+	return c.JWTEnabled() || len(c.APIKeys) > 0
+}
+
+// JWTEnabled reports whether the /api routes accept bearer tokens
+func (c AuthConfig) JWTEnabled() bool {
+	return c.JWTSecret != "" || c.JWKSURL != ""
+}
+
+// TenantConfig holds the configuration of a single tenant
+type TenantConfig struct {
+	// KeyPrefix overrides the Redis key prefix of the tenant's shares
+	KeyPrefix string `yaml:"key_prefix"`
+	// Bucket stores the tenant's objects in its own S3 bucket
+	Bucket string `yaml:"bucket"`
+}
+
+// TenantKeyPrefixes returns the configured key prefix of each tenant that sets one
+func (c *Config) TenantKeyPrefixes() map[string]string {
+	prefixes := make(map[string]string)
+	for id, tenant := range c.Tenants {
+		if tenant.KeyPrefix != "" {
+			prefixes[id] = tenant.KeyPrefix
+		}
+	}
+	return prefixes
+}
+
+// TenantBuckets returns the configured bucket of each tenant that sets one
+func (c *Config) TenantBuckets() map[string]string {
+	buckets := make(map[string]string)
+	for id, tenant := range c.Tenants {
+		if tenant.Bucket != "" {
+			buckets[id] = tenant.Bucket
+		}
+	}
+	return buckets
+}
+
+// defaultCompressibleTypes are compressed unless configured otherwise
+var defaultCompressibleTypes = []string{
+	"text/*",
+	"application/json",
+	"application/javascript",
+	"application/xml",
+	"image/svg+xml",
 }
 
 // Load loads configuration from environment variables
 func Load() (*Config, error) {
-	cfg := &Config{
+	cfg := defaultConfig()
+	if err := applyEnv(cfg); err != nil {
+		return nil, err
+	}
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// LoadFromFile loads configuration from a YAML file (JSON, being valid YAML,
+// works too). Values are resolved with a fixed precedence: built-in defaults,
+// then the file, then environment variables, so a set environment variable
+// always wins over the file. Unknown keys in the file are an error.
+func LoadFromFile(path string) (*Config, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open config file: %w", err)
+	}
+	defer file.Close()
+
+	cfg := defaultConfig()
+	decoder := yaml.NewDecoder(file)
+	decoder.KnownFields(true)
+	if err := decoder.Decode(cfg); err != nil && err != io.EOF {
+		return nil, fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+
+	if err := applyEnv(cfg); err != nil {
+		return nil, err
+	}
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// defaultConfig returns the configuration used when nothing is set
+func defaultConfig() *Config {
+	return &Config{
 		Server: ServerConfig{
-			Port:         getEnv("PORT", "8080"),
-			ReadTimeout:  getDurationEnv("READ_TIMEOUT", 30*time.Second),
-			WriteTimeout: getDurationEnv("WRITE_TIMEOUT", 30*time.Second),
-			IdleTimeout:  getDurationEnv("IDLE_TIMEOUT", 120*time.Second),
+			Port:                 "8080",
+			ReadTimeout:          30 * time.Second,
+			WriteTimeout:         30 * time.Second,
+			IdleTimeout:          120 * time.Second,
+			DownloadWriteTimeout: time.Hour,
+			TLSMinVersion:        tls.VersionTLS12,
+			MaxBatchSize:         100,
+			MaxRequestBytes:      1 << 20,
+			StreamBufferSize:     32 << 10,
+			PprofAddr:            "localhost:6060",
+		},
+		Storage: StorageConfig{
+			Backend: "s3",
 		},
 		AWS: AWSConfig{
-			Region: getEnv("AWS_REGION", "us-east-1"),
-			Bucket: getEnv("S3_BUCKET", ""),
+			Region:             "us-east-1",
+			RetryMaxAttempts:   3,
+			RetryBaseDelay:     100 * time.Millisecond,
+			DefaultContentType: "application/octet-stream",
 		},
 		Redis: RedisConfig{
-			Addr:       getEnv("REDIS_ADDR", "localhost:6379"),
-			Password:   getEnv("REDIS_PASSWORD", ""),
-			DB:         getIntEnv("REDIS_DB", 0),
-			TLSEnabled: getBoolEnv("REDIS_TLS_ENABLED", false),
+			Mode:      "standalone",
+			Addr:      "localhost:6379",
+			KeyPrefix: "image-auth",
 		},
 		Security: SecurityConfig{
-			MaxAgeDays: getIntEnv("MAX_AGE_DAYS", 90),
+			MaxAgeDays:         90,
+			LockoutWindow:      15 * time.Minute,
+			IdempotencyWindow:  24 * time.Hour,
+			SecretGracePeriod:  15 * time.Minute,
+			AllowEmptyReferer:  true,
+			HardenedErrorDelay: 100 * time.Millisecond,
+		},
+		RateLimit: RateLimitConfig{
+			Burst: 20,
+		},
+		Tracing: TracingConfig{
+			ServiceName: "go-s3-sharing",
+		},
+		Log: LogConfig{
+			Level:  "info",
+			Format: "json",
+		},
+		Breaker: BreakerConfig{
+			FailureThreshold: 5,
+			OpenTimeout:      30 * time.Second,
+		},
+		Compression: CompressionConfig{
+			ContentTypes: append([]string(nil), defaultCompressibleTypes...),
+		},
+		Cache: CacheConfig{
+			Default: "public, max-age=3600",
+		},
+		ObjectCache: ObjectCacheConfig{
+			TTL: 5 * time.Minute,
+		},
+		Resize: ResizeConfig{
+			MaxPixels: 50_000_000,
+			CacheTTL:  time.Hour,
+		},
+		Audit: AuditConfig{
+			MaxEntries: 1000,
+			Retention:  30 * 24 * time.Hour,
+		},
+		Webhook: WebhookConfig{
+			Timeout:     5 * time.Second,
+			MaxAttempts: 3,
 		},
-		BaseURL: getEnv("BASE_URL", "http://localhost:8080"),
+		BaseURL: "http://localhost:8080",
 	}
+}
 
-	// Validate required fields
-	if cfg.AWS.Bucket == "" {
-		return nil, fmt.Errorf("S3_BUCKET environment variable is required")
+// applyEnv overrides cfg with every environment variable that is set
+func applyEnv(cfg *Config) error {
+	cfg.Server.Port = getEnv("PORT", cfg.Server.Port)
+	cfg.Server.ReadTimeout = getDurationEnv("READ_TIMEOUT", cfg.Server.ReadTimeout)
+	cfg.Server.WriteTimeout = getDurationEnv("WRITE_TIMEOUT", cfg.Server.WriteTimeout)
+	cfg.Server.DownloadWriteTimeout = getDurationEnv("DOWNLOAD_WRITE_TIMEOUT", cfg.Server.DownloadWriteTimeout)
+	cfg.Server.ErrorPageTemplate = getEnv("ERROR_PAGE_TEMPLATE", cfg.Server.ErrorPageTemplate)
+	cfg.Server.TrustedProxies = getListEnv("TRUSTED_PROXIES", cfg.Server.TrustedProxies)
+	cfg.Server.IdleTimeout = getDurationEnv("IDLE_TIMEOUT", cfg.Server.IdleTimeout)
+	cfg.Server.TLSCertFile = getEnv("TLS_CERT_FILE", cfg.Server.TLSCertFile)
+	cfg.Server.TLSKeyFile = getEnv("TLS_KEY_FILE", cfg.Server.TLSKeyFile)
+	if value := os.Getenv("TLS_MIN_VERSION"); value != "" {
+		version, err := parseTLSVersion(value)
+		if err != nil {
+			return err
+		}
+		cfg.Server.TLSMinVersion = version
 	}
+	cfg.Server.MaxBatchSize = getIntEnv("MAX_BATCH_SIZE", cfg.Server.MaxBatchSize)
+	cfg.Server.MaxRequestBytes = getIntEnv("MAX_REQUEST_BYTES", cfg.Server.MaxRequestBytes)
+	cfg.Server.StreamBufferSize = getIntEnv("STREAM_BUFFER_SIZE", cfg.Server.StreamBufferSize)
+	cfg.Server.MaxConcurrentStreams = getIntEnv("MAX_CONCURRENT_STREAMS", cfg.Server.MaxConcurrentStreams)
+	cfg.Server.StreamQueueTimeout = getDurationEnv("STREAM_QUEUE_TIMEOUT", cfg.Server.StreamQueueTimeout)
+	cfg.Server.PprofEnabled = getBoolEnv("ENABLE_PPROF", cfg.Server.PprofEnabled)
+	cfg.Server.PprofAddr = getEnv("PPROF_ADDR", cfg.Server.PprofAddr)
 
-	return cfg, nil
+	cfg.Storage.Backend = getEnv("STORAGE_BACKEND", cfg.Storage.Backend)
+	cfg.Storage.FSRoot = getEnv("STORAGE_FS_ROOT", cfg.Storage.FSRoot)
+	cfg.Storage.GCSBucket = getEnv("GCS_BUCKET", cfg.Storage.GCSBucket)
+
+	cfg.AWS.Region = getEnv("AWS_REGION", cfg.AWS.Region)
+	cfg.AWS.Bucket = getEnv("S3_BUCKET", cfg.AWS.Bucket)
+	cfg.AWS.Endpoint = getEnv("S3_ENDPOINT", cfg.AWS.Endpoint)
+	cfg.AWS.UsePathStyle = getBoolEnv("S3_USE_PATH_STYLE", cfg.AWS.UsePathStyle)
+	cfg.AWS.UseAccelerate = getBoolEnv("S3_USE_ACCELERATE", cfg.AWS.UseAccelerate)
+	cfg.AWS.UseDualStack = getBoolEnv("S3_USE_DUALSTACK", cfg.AWS.UseDualStack)
+	cfg.AWS.RetryMaxAttempts = getIntEnv("S3_RETRY_MAX_ATTEMPTS", cfg.AWS.RetryMaxAttempts)
+	cfg.AWS.RetryBaseDelay = getDurationEnv("S3_RETRY_BASE_DELAY", cfg.AWS.RetryBaseDelay)
+	cfg.AWS.SSECustomerKey = getEnv("S3_SSE_CUSTOMER_KEY", cfg.AWS.SSECustomerKey)
+	cfg.AWS.DefaultContentType = getEnv("S3_DEFAULT_CONTENT_TYPE", cfg.AWS.DefaultContentType)
+	if routes := getListEnv("S3_PREFIX_BUCKETS", nil); routes != nil {
+		cfg.AWS.PrefixBuckets = make(map[string]string, len(routes))
+		for _, route := range routes {
+			prefix, bucket, ok := strings.Cut(route, "=")
+			if !ok {
+				return fmt.Errorf("invalid S3_PREFIX_BUCKETS entry %q: must be prefix=bucket", route)
+			}
+			cfg.AWS.PrefixBuckets[strings.TrimSpace(prefix)] = strings.TrimSpace(bucket)
+		}
+	}
+
+	cfg.Redis.Mode = getEnv("REDIS_MODE", cfg.Redis.Mode)
+	cfg.Redis.Addr = getEnv("REDIS_ADDR", cfg.Redis.Addr)
+	cfg.Redis.Addrs = getListEnv("REDIS_ADDRS", cfg.Redis.Addrs)
+	cfg.Redis.MasterName = getEnv("REDIS_MASTER_NAME", cfg.Redis.MasterName)
+	cfg.Redis.SentinelPassword = getEnv("REDIS_SENTINEL_PASSWORD", cfg.Redis.SentinelPassword)
+	cfg.Redis.Password = getEnv("REDIS_PASSWORD", cfg.Redis.Password)
+	cfg.Redis.DB = getIntEnv("REDIS_DB", cfg.Redis.DB)
+	cfg.Redis.TLSEnabled = getBoolEnv("REDIS_TLS_ENABLED", cfg.Redis.TLSEnabled)
+	cfg.Redis.KeyPrefix = getEnv("REDIS_KEY_PREFIX", cfg.Redis.KeyPrefix)
+	cfg.Redis.SweepInterval = getDurationEnv("REDIS_SWEEP_INTERVAL", cfg.Redis.SweepInterval)
+
+	cfg.Security.MaxAgeDays = getIntEnv("MAX_AGE_DAYS", cfg.Security.MaxAgeDays)
+	cfg.Security.SigningKey = getEnv("SIGNING_KEY", cfg.Security.SigningKey)
+	cfg.Security.SignedURLs = getBoolEnv("SIGNED_URLS_ENABLED", cfg.Security.SignedURLs)
+	cfg.Security.SignedURLFallback = getBoolEnv("SIGNED_URLS_CACHE_FALLBACK", cfg.Security.SignedURLFallback)
+	cfg.Security.ShareCookies = getBoolEnv("SHARE_COOKIES_ENABLED", cfg.Security.ShareCookies)
+	cfg.Security.MaxFailedAttempts = getIntEnv("MAX_FAILED_ATTEMPTS", cfg.Security.MaxFailedAttempts)
+	cfg.Security.LockoutWindow = getDurationEnv("LOCKOUT_WINDOW", cfg.Security.LockoutWindow)
+	cfg.Security.IdempotencyWindow = getDurationEnv("IDEMPOTENCY_WINDOW", cfg.Security.IdempotencyWindow)
+	cfg.Security.SecretGracePeriod = getDurationEnv("SECRET_GRACE_PERIOD", cfg.Security.SecretGracePeriod)
+	cfg.Security.AllowedReferers = getListEnv("ALLOWED_REFERERS", cfg.Security.AllowedReferers)
+	cfg.Security.ShareablePrefixes = getListEnv("SHAREABLE_PREFIXES", cfg.Security.ShareablePrefixes)
+	cfg.Security.AllowEmptyReferer = getBoolEnv("ALLOW_EMPTY_REFERER", cfg.Security.AllowEmptyReferer)
+	cfg.Security.HardenedErrors = getBoolEnv("HARDENED_ERRORS", cfg.Security.HardenedErrors)
+	cfg.Security.HardenedErrorDelay = getDurationEnv("HARDENED_ERROR_DELAY", cfg.Security.HardenedErrorDelay)
+
+	cfg.RateLimit.RPS = getFloatEnv("RATE_LIMIT_RPS", cfg.RateLimit.RPS)
+	cfg.RateLimit.Burst = getIntEnv("RATE_LIMIT_BURST", cfg.RateLimit.Burst)
+	cfg.RateLimit.DownloadBytesPerSec = getIntEnv("DOWNLOAD_BYTES_PER_SEC", cfg.RateLimit.DownloadBytesPerSec)
+
+	cfg.Tracing.OTLPEndpoint = getEnv("OTEL_EXPORTER_OTLP_ENDPOINT", cfg.Tracing.OTLPEndpoint)
+	cfg.Tracing.ServiceName = getEnv("OTEL_SERVICE_NAME", cfg.Tracing.ServiceName)
+
+	cfg.Log.Level = getEnv("LOG_LEVEL", cfg.Log.Level)
+	cfg.Log.Format = getEnv("LOG_FORMAT", cfg.Log.Format)
+
+	cfg.Breaker.FailureThreshold = getIntEnv("BREAKER_FAILURE_THRESHOLD", cfg.Breaker.FailureThreshold)
+	cfg.Breaker.OpenTimeout = getDurationEnv("BREAKER_OPEN_TIMEOUT", cfg.Breaker.OpenTimeout)
+
+	cfg.CORS.AllowedOrigins = getListEnv("CORS_ALLOWED_ORIGINS", cfg.CORS.AllowedOrigins)
+	cfg.Compression.ContentTypes = getListEnv("COMPRESSIBLE_TYPES", cfg.Compression.ContentTypes)
+	cfg.Cache.Default = getEnv("CACHE_CONTROL_DEFAULT", cfg.Cache.Default)
+	cfg.ObjectCache.MaxBytes = getIntEnv("OBJECT_CACHE_MAX_BYTES", cfg.ObjectCache.MaxBytes)
+	cfg.ObjectCache.TTL = getDurationEnv("OBJECT_CACHE_TTL", cfg.ObjectCache.TTL)
+	cfg.ObjectCache.MemoryBytes = getIntEnv("OBJECT_CACHE_MEMORY_BYTES", cfg.ObjectCache.MemoryBytes)
+
+	cfg.Resize.MaxDimension = getIntEnv("RESIZE_MAX_DIMENSION", cfg.Resize.MaxDimension)
+	cfg.Resize.MaxPixels = getIntEnv("RESIZE_MAX_PIXELS", cfg.Resize.MaxPixels)
+	if value := os.Getenv("RESIZE_SIZES"); value != "" {
+		sizes, err := parseSizes(value)
+		if err != nil {
+			return fmt.Errorf("RESIZE_SIZES: %w", err)
+		}
+		cfg.Resize.Sizes = sizes
+	}
+	cfg.Resize.CacheTTL = getDurationEnv("RESIZE_CACHE_TTL", cfg.Resize.CacheTTL)
+
+	cfg.Audit.Enabled = getBoolEnv("AUDIT_ENABLED", cfg.Audit.Enabled)
+	cfg.Audit.MaxEntries = getIntEnv("AUDIT_MAX_ENTRIES", cfg.Audit.MaxEntries)
+	cfg.Audit.Retention = getDurationEnv("AUDIT_RETENTION", cfg.Audit.Retention)
+
+	cfg.Webhook.Timeout = getDurationEnv("WEBHOOK_TIMEOUT", cfg.Webhook.Timeout)
+	cfg.Webhook.MaxAttempts = getIntEnv("WEBHOOK_MAX_ATTEMPTS", cfg.Webhook.MaxAttempts)
+
+	cfg.Auth.JWTSecret = getEnv("JWT_SECRET", cfg.Auth.JWTSecret)
+	cfg.Auth.JWKSURL = getEnv("JWT_JWKS_URL", cfg.Auth.JWKSURL)
+	cfg.Auth.Audience = getEnv("JWT_AUDIENCE", cfg.Auth.Audience)
+	cfg.Auth.RequiredScope = getEnv("JWT_REQUIRED_SCOPE", cfg.Auth.RequiredScope)
+	if keys := getListEnv("API_KEYS", nil); keys != nil {
+		cfg.Auth.APIKeys = make([]APIKeyConfig, 0, len(keys))
+		for _, key := range keys {
+			cfg.Auth.APIKeys = append(cfg.Auth.APIKeys, APIKeyConfig{Key: key})
+		}
+	}
+	cfg.Auth.APIKeyDailyQuota = getIntEnv("API_KEY_DAILY_QUOTA", cfg.Auth.APIKeyDailyQuota)
+
+	cfg.BaseURL = strings.TrimRight(getEnv("BASE_URL", cfg.BaseURL), "/")
+	return nil
+}
+
+// validate checks required and mutually dependent fields
+func (c *Config) validate() error {
+	// Other backends validate their own configuration when created
+	switch c.Storage.Backend {
+	case "s3":
+		if c.AWS.Bucket == "" {
+			return fmt.Errorf("S3_BUCKET environment variable is required")
+		}
+	case "gcs":
+		if c.Storage.GCSBucket == "" {
+			return fmt.Errorf("GCS_BUCKET is required with the gcs storage backend")
+		}
+	case "fs":
+		if c.Storage.FSRoot == "" {
+			return fmt.Errorf("STORAGE_FS_ROOT is required with the fs storage backend")
+		}
+	case "":
+		return fmt.Errorf("STORAGE_BACKEND must not be empty")
+	}
+
+	for prefix, bucket := range c.AWS.PrefixBuckets {
+		if !validKeyPrefix(prefix) {
+			return fmt.Errorf("invalid S3_PREFIX_BUCKETS prefix %q: must be a relative key prefix", prefix)
+		}
+		if bucket == "" {
+			return fmt.Errorf("S3_PREFIX_BUCKETS prefix %q has no bucket", prefix)
+		}
+	}
+
+	if c.AWS.SSECustomerKey != "" {
+		key, err := base64.StdEncoding.DecodeString(c.AWS.SSECustomerKey)
+		if err != nil || len(key) != 32 {
+			return fmt.Errorf("S3_SSE_CUSTOMER_KEY must be a base64-encoded 256-bit key")
+		}
+	}
+
+	// Accelerated endpoints only support virtual-hosted-style addressing
+	if c.AWS.UseAccelerate && c.AWS.UsePathStyle {
+		return fmt.Errorf("S3_USE_ACCELERATE cannot be combined with S3_USE_PATH_STYLE")
+	}
+
+	if c.AWS.DefaultContentType != "" {
+		if _, _, err := mime.ParseMediaType(c.AWS.DefaultContentType); err != nil {
+			return fmt.Errorf("invalid S3_DEFAULT_CONTENT_TYPE %q: %w", c.AWS.DefaultContentType, err)
+		}
+	}
+
+	if (c.Server.TLSCertFile == "") != (c.Server.TLSKeyFile == "") {
+		return fmt.Errorf("TLS_CERT_FILE and TLS_KEY_FILE must be set together")
+	}
+
+	if err := validateBaseURL(c.BaseURL); err != nil {
+		return err
+	}
+
+	switch c.Redis.Mode {
+	case "standalone":
+	case "cluster":
+		if c.Redis.DB != 0 {
+			return fmt.Errorf("REDIS_DB must be 0 in cluster mode")
+		}
+	case "sentinel":
+		if c.Redis.MasterName == "" {
+			return fmt.Errorf("REDIS_MASTER_NAME is required in sentinel mode")
+		}
+	default:
+		return fmt.Errorf("REDIS_MODE must be standalone, cluster or sentinel, got %q", c.Redis.Mode)
+	}
+
+	if c.Redis.KeyPrefix == "" {
+		return fmt.Errorf("REDIS_KEY_PREFIX must not be empty")
+	}
+	if c.Redis.SweepInterval < 0 {
+		return fmt.Errorf("REDIS_SWEEP_INTERVAL must not be negative")
+	}
+
+	if c.Security.HardenedErrorDelay < 0 {
+		return fmt.Errorf("HARDENED_ERROR_DELAY must not be negative")
+	}
+
+	if c.Server.DownloadWriteTimeout < 0 {
+		return fmt.Errorf("DOWNLOAD_WRITE_TIMEOUT must not be negative")
+	}
+
+	if c.Server.MaxBatchSize < 1 {
+		return fmt.Errorf("MAX_BATCH_SIZE must be at least 1")
+	}
+
+	if c.Server.MaxRequestBytes < 1 {
+		return fmt.Errorf("MAX_REQUEST_BYTES must be at least 1")
+	}
+
+	if c.Server.MaxConcurrentStreams < 0 {
+		return fmt.Errorf("MAX_CONCURRENT_STREAMS must not be negative")
+	}
+	if c.Server.StreamQueueTimeout < 0 {
+		return fmt.Errorf("STREAM_QUEUE_TIMEOUT must not be negative")
+	}
+
+	if c.Server.StreamBufferSize < minStreamBufferSize || c.Server.StreamBufferSize > maxStreamBufferSize {
+		return fmt.Errorf("STREAM_BUFFER_SIZE must be between %d and %d bytes", minStreamBufferSize, maxStreamBufferSize)
+	}
+
+	if c.Server.PprofEnabled && c.Server.PprofAddr == "" {
+		return fmt.Errorf("PPROF_ADDR is required when ENABLE_PPROF is true")
+	}
+
+	for _, proxy := range c.Server.TrustedProxies {
+		if !domain.ValidIPPattern(proxy) {
+			return fmt.Errorf("invalid TRUSTED_PROXIES entry %q: must be a CIDR or an IP address", proxy)
+		}
+	}
+
+	if c.Security.SecretGracePeriod < 0 {
+		return fmt.Errorf("SECRET_GRACE_PERIOD must not be negative")
+	}
+
+	for _, pattern := range c.Security.AllowedReferers {
+		if !domain.ValidRefererPattern(pattern) {
+			return fmt.Errorf("invalid ALLOWED_REFERERS entry %q: must be a host name, optionally starting with *.", pattern)
+		}
+	}
+
+	for _, prefix := range c.Security.ShareablePrefixes {
+		if !validKeyPrefix(prefix) {
+			return fmt.Errorf("invalid SHAREABLE_PREFIXES entry %q: must be a relative key prefix", prefix)
+		}
+	}
+
+	if c.Security.SignedURLs && c.Security.SigningKey == "" {
+		return fmt.Errorf("SIGNING_KEY environment variable is required when SIGNED_URLS_ENABLED is true")
+	}
+	if c.Security.SignedURLFallback && !c.Security.SignedURLs {
+		return fmt.Errorf("SIGNED_URLS_CACHE_FALLBACK requires SIGNED_URLS_ENABLED")
+	}
+	if c.Security.ShareCookies && c.Security.SigningKey == "" {
+		return fmt.Errorf("SIGNING_KEY environment variable is required when SHARE_COOKIES_ENABLED is true")
+	}
+
+	if c.Auth.JWTSecret != "" && c.Auth.JWKSURL != "" {
+		return fmt.Errorf("JWT_SECRET and JWT_JWKS_URL must not be set together")
+	}
+
+	for i, key := range c.Auth.APIKeys {
+		if key.Key == "" {
+			return fmt.Errorf("API key %d has no key", i)
+		}
+		if key.DailyQuota < 0 {
+			return fmt.Errorf("API key %d has a negative daily quota", i)
+		}
+		for _, tenant := range key.Tenants {
+			if tenant != "*" && !domain.IsValidTenantID(tenant) {
+				return fmt.Errorf("API key %d has an invalid tenant %q", i, tenant)
+			}
+		}
+	}
+	if c.Auth.APIKeyDailyQuota < 0 {
+		return fmt.Errorf("API_KEY_DAILY_QUOTA must not be negative")
+	}
+
+	for i, rule := range c.Cache.Rules {
+		if rule.ContentType == "" || rule.Directives == "" {
+			return fmt.Errorf("cache control rule %d needs a content type and directives", i)
+		}
+	}
+
+	if c.ObjectCache.MaxBytes < 0 {
+		return fmt.Errorf("OBJECT_CACHE_MAX_BYTES must not be negative")
+	}
+	if c.ObjectCache.MemoryBytes < 0 {
+		return fmt.Errorf("OBJECT_CACHE_MEMORY_BYTES must not be negative")
+	}
+
+	if c.Resize.MaxDimension < 0 {
+		return fmt.Errorf("RESIZE_MAX_DIMENSION must not be negative")
+	}
+	if c.Resize.MaxDimension > 0 && c.Resize.MaxPixels <= 0 {
+		return fmt.Errorf("RESIZE_MAX_PIXELS must be positive when resizing is enabled")
+	}
+	for _, size := range c.Resize.Sizes {
+		if size <= 0 || size > c.Resize.MaxDimension {
+			return fmt.Errorf("RESIZE_SIZES must lie between 1 and RESIZE_MAX_DIMENSION, got %d", size)
+		}
+	}
+	if c.Resize.CacheTTL < 0 {
+		return fmt.Errorf("RESIZE_CACHE_TTL must not be negative")
+	}
+
+	if c.Audit.Enabled && c.Audit.MaxEntries <= 0 {
+		return fmt.Errorf("AUDIT_MAX_ENTRIES must be positive when AUDIT_ENABLED is true")
+	}
+
+	if c.Webhook.Timeout <= 0 {
+		return fmt.Errorf("WEBHOOK_TIMEOUT must be positive")
+	}
+	if c.Webhook.MaxAttempts < 1 {
+		return fmt.Errorf("WEBHOOK_MAX_ATTEMPTS must be at least 1")
+	}
+
+	for id := range c.Tenants {
+		if !domain.IsValidTenantID(id) {
+			return fmt.Errorf("invalid tenant ID %q: must be 1-64 letters, digits, '-' or '_'", id)
+		}
+	}
+
+	return nil
+}
+
+// TLSEnabled reports whether the server should serve HTTPS
+func (c ServerConfig) TLSEnabled() bool {
+	return c.TLSCertFile != "" && c.TLSKeyFile != ""
+}
+
+// parseTLSVersion converts a version such as "1.2" to its crypto/tls constant
+func parseTLSVersion(version string) (TLSVersion, error) {
+	switch version {
+	case "1.0":
+		return tls.VersionTLS10, nil
+	case "1.1":
+		return tls.VersionTLS11, nil
+	case "1.2":
+		return tls.VersionTLS12, nil
+	case "1.3":
+		return tls.VersionTLS13, nil
+	default:
+		return 0, fmt.Errorf("invalid TLS_MIN_VERSION %q: must be one of 1.0, 1.1, 1.2, 1.3", version)
+	}
+}
+
+// validKeyPrefix reports whether prefix is a non-empty object key prefix
+// that is relative and never leaves its parent
+func validKeyPrefix(prefix string) bool {
+	return prefix != "" && !strings.HasPrefix(prefix, "/") && !slices.Contains(strings.Split(prefix, "/"), "..")
+}
+
+// validateBaseURL checks that share links can be generated under baseURL
+func validateBaseURL(baseURL string) error {
+	if baseURL == "" {
+		return fmt.Errorf("BASE_URL is required to generate share URLs")
+	}
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return fmt.Errorf("invalid BASE_URL %q: %w", baseURL, err)
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("invalid BASE_URL %q: must be an absolute http or https URL", baseURL)
+	}
+	if u.RawQuery != "" || u.Fragment != "" {
+		return fmt.Errorf("invalid BASE_URL %q: must not have a query or fragment", baseURL)
+	}
+	return nil
+}
+
+// parseSizes parses a comma-separated list of sizes in pixels
+func parseSizes(value string) ([]int, error) {
+	var sizes []int
+	for _, item := range getListValues(value) {
+		size, err := strconv.Atoi(item)
+		if err != nil {
+			return nil, fmt.Errorf("invalid size %q", item)
+		}
+		sizes = append(sizes, size)
+	}
+	return sizes, nil
 }
 
 func getEnv(key, defaultValue string) string {
@@ -99,6 +960,34 @@ func getIntEnv(key string, defaultValue int) int {
 	return defaultValue
 }
 
+func getFloatEnv(key string, defaultValue float64) float64 {
+	if value := os.Getenv(key); value != "" {
+		if floatValue, err := strconv.ParseFloat(value, 64); err == nil {
+			return floatValue
+		}
+	}
+	return defaultValue
+}
+
+func getListEnv(key string, defaultValue []string) []string {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	return getListValues(value)
+}
+
+// getListValues splits a comma-separated list, dropping empty items
+func getListValues(value string) []string {
+	var values []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			values = append(values, item)
+		}
+	}
+	return values
+}
+
 func getDurationEnv(key string, defaultValue time.Duration) time.Duration {
 	if value := os.Getenv(key); value != "" {
 		if duration, err := time.ParseDuration(value); err == nil {
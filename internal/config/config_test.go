@@ -0,0 +1,969 @@
+package config
+
+import (
+	"crypto/tls"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestLoad_S3Endpoint(t *testing.T) {
+	t.Run("defaults to AWS endpoints", func(t *testing.T) {
+		t.Setenv("S3_BUCKET", "test-bucket")
+
+		cfg, err := Load()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if cfg.AWS.Endpoint != "" {
+			t.Errorf("expected empty endpoint, got %s", cfg.AWS.Endpoint)
+		}
+		if cfg.AWS.UsePathStyle {
+			t.Errorf("expected path style to be disabled by default")
+		}
+	})
+
+	t.Run("custom endpoint with path style", func(t *testing.T) {
+		t.Setenv("S3_BUCKET", "test-bucket")
+		t.Setenv("S3_ENDPOINT", "http://localhost:9000")
+		t.Setenv("S3_USE_PATH_STYLE", "true")
+
+		cfg, err := Load()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if cfg.AWS.Endpoint != "http://localhost:9000" {
+			t.Errorf("expected endpoint http://localhost:9000, got %s", cfg.AWS.Endpoint)
+		}
+		if !cfg.AWS.UsePathStyle {
+			t.Errorf("expected path style to be enabled")
+		}
+	})
+}
+
+func TestLoad_S3AccelerateDualStack(t *testing.T) {
+	t.Setenv("S3_BUCKET", "test-bucket")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.AWS.UseAccelerate || cfg.AWS.UseDualStack {
+		t.Error("expected acceleration and dual-stack to be disabled by default")
+	}
+
+	t.Setenv("S3_USE_ACCELERATE", "true")
+	t.Setenv("S3_USE_DUALSTACK", "true")
+	cfg, err = Load()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !cfg.AWS.UseAccelerate {
+		t.Error("expected acceleration to be enabled")
+	}
+	if !cfg.AWS.UseDualStack {
+		t.Error("expected dual-stack to be enabled")
+	}
+
+	t.Setenv("S3_USE_PATH_STYLE", "true")
+	if _, err := Load(); err == nil {
+		t.Error("expected error for acceleration with path style, got nil")
+	}
+}
+
+func TestLoad_PrefixBuckets(t *testing.T) {
+	t.Run("routes from the environment", func(t *testing.T) {
+		t.Setenv("S3_BUCKET", "test-bucket")
+		t.Setenv("S3_PREFIX_BUCKETS", "images/=images-bucket, videos/ = videos-bucket")
+
+		cfg, err := Load()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(cfg.AWS.PrefixBuckets) != 2 || cfg.AWS.PrefixBuckets["images/"] != "images-bucket" || cfg.AWS.PrefixBuckets["videos/"] != "videos-bucket" {
+			t.Errorf("unexpected prefix buckets %v", cfg.AWS.PrefixBuckets)
+		}
+	})
+
+	tests := []struct {
+		name   string
+		routes string
+	}{
+		{name: "missing separator", routes: "images/"},
+		{name: "empty prefix", routes: "=images-bucket"},
+		{name: "empty bucket", routes: "images/="},
+		{name: "absolute prefix", routes: "/images/=images-bucket"},
+		{name: "traversal prefix", routes: "images/../=images-bucket"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("S3_BUCKET", "test-bucket")
+			t.Setenv("S3_PREFIX_BUCKETS", tt.routes)
+
+			if _, err := Load(); err == nil {
+				t.Errorf("expected error for S3_PREFIX_BUCKETS %q", tt.routes)
+			}
+		})
+	}
+}
+
+func TestLoad_ShareablePrefixes(t *testing.T) {
+	t.Run("defaults to every path", func(t *testing.T) {
+		t.Setenv("S3_BUCKET", "test-bucket")
+
+		cfg, err := Load()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(cfg.Security.ShareablePrefixes) != 0 {
+			t.Errorf("expected no shareable prefixes, got %v", cfg.Security.ShareablePrefixes)
+		}
+	})
+
+	t.Run("prefixes from the environment", func(t *testing.T) {
+		t.Setenv("S3_BUCKET", "test-bucket")
+		t.Setenv("SHAREABLE_PREFIXES", "public/, exports/")
+
+		cfg, err := Load()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(cfg.Security.ShareablePrefixes) != 2 || cfg.Security.ShareablePrefixes[0] != "public/" || cfg.Security.ShareablePrefixes[1] != "exports/" {
+			t.Errorf("unexpected shareable prefixes %v", cfg.Security.ShareablePrefixes)
+		}
+	})
+
+	for _, prefix := range []string{"/public/", "public/../"} {
+		t.Run("invalid "+prefix, func(t *testing.T) {
+			t.Setenv("S3_BUCKET", "test-bucket")
+			t.Setenv("SHAREABLE_PREFIXES", prefix)
+
+			if _, err := Load(); err == nil {
+				t.Errorf("expected error for SHAREABLE_PREFIXES %q", prefix)
+			}
+		})
+	}
+}
+
+func TestLoad_SSECustomerKey(t *testing.T) {
+	t.Run("unset", func(t *testing.T) {
+		t.Setenv("S3_BUCKET", "test-bucket")
+
+		cfg, err := Load()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if cfg.AWS.SSECustomerKeyBytes() != nil {
+			t.Errorf("expected no SSE-C key, got %x", cfg.AWS.SSECustomerKeyBytes())
+		}
+	})
+
+	t.Run("valid key", func(t *testing.T) {
+		t.Setenv("S3_BUCKET", "test-bucket")
+		t.Setenv("S3_SSE_CUSTOMER_KEY", "MDEyMzQ1Njc4OWFiY2RlZjAxMjM0NTY3ODlhYmNkZWY=")
+
+		cfg, err := Load()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got := string(cfg.AWS.SSECustomerKeyBytes()); got != "0123456789abcdef0123456789abcdef" {
+			t.Errorf("unexpected SSE-C key %q", got)
+		}
+	})
+
+	for _, key := range []string{"not base64!", "c2hvcnQ="} {
+		t.Run("invalid "+key, func(t *testing.T) {
+			t.Setenv("S3_BUCKET", "test-bucket")
+			t.Setenv("S3_SSE_CUSTOMER_KEY", key)
+
+			if _, err := Load(); err == nil {
+				t.Errorf("expected error for S3_SSE_CUSTOMER_KEY %q", key)
+			}
+		})
+	}
+}
+
+func TestLoad_DefaultContentType(t *testing.T) {
+	t.Setenv("S3_BUCKET", "test-bucket")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.AWS.DefaultContentType != "application/octet-stream" {
+		t.Errorf("expected default content type application/octet-stream, got %s", cfg.AWS.DefaultContentType)
+	}
+
+	t.Setenv("S3_DEFAULT_CONTENT_TYPE", "text/plain; charset=utf-8")
+	cfg, err = Load()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.AWS.DefaultContentType != "text/plain; charset=utf-8" {
+		t.Errorf("expected default content type text/plain; charset=utf-8, got %s", cfg.AWS.DefaultContentType)
+	}
+
+	t.Setenv("S3_DEFAULT_CONTENT_TYPE", "text/")
+	if _, err := Load(); err == nil {
+		t.Error("expected error for an invalid content type, got nil")
+	}
+}
+
+func TestLoad_StorageBackend(t *testing.T) {
+	tests := []struct {
+		name        string
+		env         map[string]string
+		expected    string
+		expectError bool
+	}{
+		{name: "defaults to s3", env: map[string]string{"S3_BUCKET": "test-bucket"}, expected: "s3"},
+		{name: "s3 requires a bucket", env: map[string]string{"STORAGE_BACKEND": "s3"}, expectError: true},
+		{name: "fs with a root", env: map[string]string{"STORAGE_BACKEND": "fs", "STORAGE_FS_ROOT": "/srv/files"}, expected: "fs"},
+		{name: "fs requires a root", env: map[string]string{"STORAGE_BACKEND": "fs"}, expectError: true},
+		{name: "gcs with a bucket", env: map[string]string{"STORAGE_BACKEND": "gcs", "GCS_BUCKET": "media"}, expected: "gcs"},
+		{name: "gcs requires a bucket", env: map[string]string{"STORAGE_BACKEND": "gcs"}, expectError: true},
+		{name: "registered backends configure themselves", env: map[string]string{"STORAGE_BACKEND": "minio-cluster"}, expected: "minio-cluster"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for key, value := range tt.env {
+				t.Setenv(key, value)
+			}
+
+			cfg, err := Load()
+			if tt.expectError {
+				if err == nil {
+					t.Error("expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if cfg.Storage.Backend != tt.expected {
+				t.Errorf("expected backend %s, got %s", tt.expected, cfg.Storage.Backend)
+			}
+		})
+	}
+}
+
+func TestLoad_CORSAllowedOrigins(t *testing.T) {
+	t.Setenv("S3_BUCKET", "test-bucket")
+	t.Setenv("CORS_ALLOWED_ORIGINS", "https://a.example.com, https://b.example.com,,")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := []string{"https://a.example.com", "https://b.example.com"}
+	if len(cfg.CORS.AllowedOrigins) != len(expected) {
+		t.Fatalf("expected origins %v, got %v", expected, cfg.CORS.AllowedOrigins)
+	}
+	for i, origin := range expected {
+		if cfg.CORS.AllowedOrigins[i] != origin {
+			t.Errorf("expected origin %s at %d, got %s", origin, i, cfg.CORS.AllowedOrigins[i])
+		}
+	}
+}
+
+func TestLoad_TLS(t *testing.T) {
+	t.Run("plaintext by default", func(t *testing.T) {
+		t.Setenv("S3_BUCKET", "test-bucket")
+
+		cfg, err := Load()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if cfg.Server.TLSEnabled() {
+			t.Errorf("expected TLS to be disabled by default")
+		}
+		if cfg.Server.TLSMinVersion != tls.VersionTLS12 {
+			t.Errorf("expected minimum TLS version %x, got %x", tls.VersionTLS12, cfg.Server.TLSMinVersion)
+		}
+	})
+
+	t.Run("certificate and key", func(t *testing.T) {
+		t.Setenv("S3_BUCKET", "test-bucket")
+		t.Setenv("TLS_CERT_FILE", "/etc/tls/cert.pem")
+		t.Setenv("TLS_KEY_FILE", "/etc/tls/key.pem")
+		t.Setenv("TLS_MIN_VERSION", "1.3")
+
+		cfg, err := Load()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if !cfg.Server.TLSEnabled() {
+			t.Errorf("expected TLS to be enabled")
+		}
+		if cfg.Server.TLSMinVersion != tls.VersionTLS13 {
+			t.Errorf("expected minimum TLS version %x, got %x", tls.VersionTLS13, cfg.Server.TLSMinVersion)
+		}
+	})
+
+	t.Run("certificate without key", func(t *testing.T) {
+		t.Setenv("S3_BUCKET", "test-bucket")
+		t.Setenv("TLS_CERT_FILE", "/etc/tls/cert.pem")
+
+		if _, err := Load(); err == nil {
+			t.Error("expected error for certificate without key")
+		}
+	})
+
+	t.Run("invalid minimum version", func(t *testing.T) {
+		t.Setenv("S3_BUCKET", "test-bucket")
+		t.Setenv("TLS_MIN_VERSION", "2.0")
+
+		if _, err := Load(); err == nil {
+			t.Error("expected error for invalid minimum TLS version")
+		}
+	})
+}
+
+func TestLoad_RedisMode(t *testing.T) {
+	t.Run("standalone by default", func(t *testing.T) {
+		t.Setenv("S3_BUCKET", "test-bucket")
+
+		cfg, err := Load()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if cfg.Redis.Mode != "standalone" {
+			t.Errorf("expected standalone mode, got %s", cfg.Redis.Mode)
+		}
+		if addrs := cfg.Redis.Addresses(); len(addrs) != 1 || addrs[0] != "localhost:6379" {
+			t.Errorf("expected the single address localhost:6379, got %v", addrs)
+		}
+	})
+
+	t.Run("sentinel", func(t *testing.T) {
+		t.Setenv("S3_BUCKET", "test-bucket")
+		t.Setenv("REDIS_MODE", "sentinel")
+		t.Setenv("REDIS_ADDRS", "sentinel-1:26379, sentinel-2:26379")
+		t.Setenv("REDIS_MASTER_NAME", "mymaster")
+
+		cfg, err := Load()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		expected := []string{"sentinel-1:26379", "sentinel-2:26379"}
+		addrs := cfg.Redis.Addresses()
+		if len(addrs) != len(expected) || addrs[0] != expected[0] || addrs[1] != expected[1] {
+			t.Errorf("expected addresses %v, got %v", expected, addrs)
+		}
+		if cfg.Redis.MasterName != "mymaster" {
+			t.Errorf("expected master name mymaster, got %s", cfg.Redis.MasterName)
+		}
+	})
+
+	t.Run("sentinel without master name", func(t *testing.T) {
+		t.Setenv("S3_BUCKET", "test-bucket")
+		t.Setenv("REDIS_MODE", "sentinel")
+
+		if _, err := Load(); err == nil {
+			t.Error("expected error for sentinel mode without a master name")
+		}
+	})
+
+	t.Run("cluster with database", func(t *testing.T) {
+		t.Setenv("S3_BUCKET", "test-bucket")
+		t.Setenv("REDIS_MODE", "cluster")
+		t.Setenv("REDIS_DB", "2")
+
+		if _, err := Load(); err == nil {
+			t.Error("expected error for a database other than 0 in cluster mode")
+		}
+	})
+
+	t.Run("unknown mode", func(t *testing.T) {
+		t.Setenv("S3_BUCKET", "test-bucket")
+		t.Setenv("REDIS_MODE", "replicated")
+
+		if _, err := Load(); err == nil {
+			t.Error("expected error for an unknown mode")
+		}
+	})
+}
+
+func TestLoad_BaseURL(t *testing.T) {
+	t.Run("defaults for local development", func(t *testing.T) {
+		t.Setenv("S3_BUCKET", "test-bucket")
+
+		cfg, err := Load()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if cfg.BaseURL != "http://localhost:8080" {
+			t.Errorf("expected base URL http://localhost:8080, got %s", cfg.BaseURL)
+		}
+	})
+
+	t.Run("present", func(t *testing.T) {
+		t.Setenv("S3_BUCKET", "test-bucket")
+		t.Setenv("BASE_URL", "https://files.example.com/shares/")
+
+		cfg, err := Load()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if cfg.BaseURL != "https://files.example.com/shares" {
+			t.Errorf("expected base URL https://files.example.com/shares, got %s", cfg.BaseURL)
+		}
+	})
+
+	t.Run("absent", func(t *testing.T) {
+		t.Setenv("S3_BUCKET", "test-bucket")
+		path := filepath.Join(t.TempDir(), "config.yaml")
+		if err := os.WriteFile(path, []byte("base_url: \"\"\n"), 0o600); err != nil {
+			t.Fatalf("failed to write config file: %v", err)
+		}
+
+		if _, err := LoadFromFile(path); err == nil {
+			t.Error("expected error for a missing base URL")
+		}
+	})
+
+	for _, baseURL := range []string{
+		"files.example.com",
+		"/shares",
+		"ftp://files.example.com",
+		"https://",
+		"https://files.example.com/?tenant=acme",
+		"https://files.example.com/%zz",
+	} {
+		t.Run("malformed "+baseURL, func(t *testing.T) {
+			t.Setenv("S3_BUCKET", "test-bucket")
+			t.Setenv("BASE_URL", baseURL)
+
+			if _, err := Load(); err == nil {
+				t.Errorf("expected error for base URL %q", baseURL)
+			}
+		})
+	}
+}
+
+func TestLoad_AllowedReferers(t *testing.T) {
+	t.Run("valid", func(t *testing.T) {
+		t.Setenv("S3_BUCKET", "test-bucket")
+		t.Setenv("ALLOWED_REFERERS", "example.com, *.example.com")
+		t.Setenv("ALLOW_EMPTY_REFERER", "false")
+
+		cfg, err := Load()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(cfg.Security.AllowedReferers) != 2 || cfg.Security.AllowedReferers[1] != "*.example.com" {
+			t.Errorf("expected two referer patterns, got %v", cfg.Security.AllowedReferers)
+		}
+		if cfg.Security.AllowEmptyReferer {
+			t.Error("expected empty referers to be rejected")
+		}
+	})
+
+	t.Run("invalid pattern", func(t *testing.T) {
+		t.Setenv("S3_BUCKET", "test-bucket")
+		t.Setenv("ALLOWED_REFERERS", "https://example.com/")
+
+		if _, err := Load(); err == nil {
+			t.Error("expected error for a referer pattern that is not a host name")
+		}
+	})
+}
+
+func TestLoad_StreamBufferSize(t *testing.T) {
+	tests := []struct {
+		name        string
+		value       string
+		expected    int
+		expectError bool
+	}{
+		{name: "default", expected: 32 << 10},
+		{name: "configured", value: "262144", expected: 256 << 10},
+		{name: "too small", value: "1024", expectError: true},
+		{name: "too large", value: "16777216", expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("S3_BUCKET", "test-bucket")
+			if tt.value != "" {
+				t.Setenv("STREAM_BUFFER_SIZE", tt.value)
+			}
+
+			cfg, err := Load()
+			if tt.expectError {
+				if err == nil {
+					t.Error("expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if cfg.Server.StreamBufferSize != tt.expected {
+				t.Errorf("expected stream buffer size %d, got %d", tt.expected, cfg.Server.StreamBufferSize)
+			}
+		})
+	}
+}
+
+func TestLoad_DownloadWriteTimeout(t *testing.T) {
+	tests := []struct {
+		name        string
+		value       string
+		expected    time.Duration
+		expectError bool
+	}{
+		{name: "default", expected: time.Hour},
+		{name: "configured", value: "10m", expected: 10 * time.Minute},
+		{name: "disabled", value: "0s", expected: 0},
+		{name: "negative", value: "-1m", expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("S3_BUCKET", "test-bucket")
+			if tt.value != "" {
+				t.Setenv("DOWNLOAD_WRITE_TIMEOUT", tt.value)
+			}
+
+			cfg, err := Load()
+			if tt.expectError {
+				if err == nil {
+					t.Error("expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if cfg.Server.DownloadWriteTimeout != tt.expected {
+				t.Errorf("expected download write timeout %v, got %v", tt.expected, cfg.Server.DownloadWriteTimeout)
+			}
+		})
+	}
+}
+
+func TestLoad_HardenedErrors(t *testing.T) {
+	t.Setenv("S3_BUCKET", "test-bucket")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.Security.HardenedErrors {
+		t.Error("expected detailed errors by default")
+	}
+	if cfg.Security.HardenedErrorDelay != 100*time.Millisecond {
+		t.Errorf("expected hardened error delay 100ms, got %v", cfg.Security.HardenedErrorDelay)
+	}
+
+	t.Setenv("HARDENED_ERRORS", "true")
+	t.Setenv("HARDENED_ERROR_DELAY", "250ms")
+	cfg, err = Load()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !cfg.Security.HardenedErrors || cfg.Security.HardenedErrorDelay != 250*time.Millisecond {
+		t.Errorf("expected hardened errors after 250ms, got %v after %v", cfg.Security.HardenedErrors, cfg.Security.HardenedErrorDelay)
+	}
+
+	t.Setenv("HARDENED_ERROR_DELAY", "-1s")
+	if _, err := Load(); err == nil {
+		t.Error("expected error for a negative delay, got nil")
+	}
+}
+
+func TestLoad_SignedURLFallback(t *testing.T) {
+	t.Setenv("S3_BUCKET", "test-bucket")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.Security.SignedURLFallback {
+		t.Error("expected the signed URL fallback to be disabled by default")
+	}
+
+	t.Setenv("SIGNED_URLS_CACHE_FALLBACK", "true")
+	if _, err := Load(); err == nil {
+		t.Error("expected error for a fallback without signed URLs, got nil")
+	}
+
+	t.Setenv("SIGNED_URLS_ENABLED", "true")
+	t.Setenv("SIGNING_KEY", "test-signing-key")
+	cfg, err = Load()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !cfg.Security.SignedURLFallback {
+		t.Error("expected the signed URL fallback to be enabled")
+	}
+}
+
+func TestLoad_ShareCookies(t *testing.T) {
+	t.Setenv("S3_BUCKET", "test-bucket")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.Security.ShareCookies {
+		t.Error("expected share cookies to be disabled by default")
+	}
+
+	t.Setenv("SHARE_COOKIES_ENABLED", "true")
+	if _, err := Load(); err == nil {
+		t.Error("expected error for share cookies without a signing key, got nil")
+	}
+
+	t.Setenv("SIGNING_KEY", "test-signing-key")
+	cfg, err = Load()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !cfg.Security.ShareCookies {
+		t.Error("expected share cookies to be enabled")
+	}
+}
+
+func TestLoad_StreamLimit(t *testing.T) {
+	t.Setenv("S3_BUCKET", "test-bucket")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.Server.MaxConcurrentStreams != 0 || cfg.Server.StreamQueueTimeout != 0 {
+		t.Errorf("expected unlimited streams by default, got %d with queue timeout %v", cfg.Server.MaxConcurrentStreams, cfg.Server.StreamQueueTimeout)
+	}
+
+	t.Setenv("MAX_CONCURRENT_STREAMS", "64")
+	t.Setenv("STREAM_QUEUE_TIMEOUT", "2s")
+	cfg, err = Load()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.Server.MaxConcurrentStreams != 64 {
+		t.Errorf("expected 64 concurrent streams, got %d", cfg.Server.MaxConcurrentStreams)
+	}
+	if cfg.Server.StreamQueueTimeout != 2*time.Second {
+		t.Errorf("expected queue timeout 2s, got %v", cfg.Server.StreamQueueTimeout)
+	}
+
+	t.Setenv("MAX_CONCURRENT_STREAMS", "-1")
+	if _, err := Load(); err == nil {
+		t.Error("expected error for a negative stream limit, got nil")
+	}
+}
+
+func TestLoad_RedisSweepInterval(t *testing.T) {
+	t.Setenv("S3_BUCKET", "test-bucket")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.Redis.SweepInterval != 0 {
+		t.Errorf("expected the sweeper to be disabled by default, got %v", cfg.Redis.SweepInterval)
+	}
+
+	t.Setenv("REDIS_SWEEP_INTERVAL", "30m")
+	cfg, err = Load()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.Redis.SweepInterval != 30*time.Minute {
+		t.Errorf("expected sweep interval 30m, got %v", cfg.Redis.SweepInterval)
+	}
+
+	t.Setenv("REDIS_SWEEP_INTERVAL", "-1m")
+	if _, err := Load(); err == nil {
+		t.Error("expected error for a negative interval, got nil")
+	}
+}
+
+func TestLoad_TrustedProxies(t *testing.T) {
+	tests := []struct {
+		name        string
+		value       string
+		expected    []string
+		expectError bool
+	}{
+		{name: "default"},
+		{name: "CIDRs and addresses", value: "10.0.0.0/8, 2001:db8::1", expected: []string{"10.0.0.0/8", "2001:db8::1"}},
+		{name: "invalid CIDR", value: "10.0.0.0/33", expectError: true},
+		{name: "host name", value: "proxy.internal", expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("S3_BUCKET", "test-bucket")
+			if tt.value != "" {
+				t.Setenv("TRUSTED_PROXIES", tt.value)
+			}
+
+			cfg, err := Load()
+			if tt.expectError {
+				if err == nil {
+					t.Error("expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !slices.Equal(cfg.Server.TrustedProxies, tt.expected) {
+				t.Errorf("expected trusted proxies %v, got %v", tt.expected, cfg.Server.TrustedProxies)
+			}
+		})
+	}
+}
+
+func TestLoad_Resize(t *testing.T) {
+	t.Run("defaults", func(t *testing.T) {
+		t.Setenv("S3_BUCKET", "test-bucket")
+
+		cfg, err := Load()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if cfg.Resize.MaxDimension != 0 || cfg.Resize.MaxPixels != 50_000_000 || cfg.Resize.CacheTTL != time.Hour {
+			t.Errorf("unexpected resize defaults: %+v", cfg.Resize)
+		}
+	})
+
+	t.Run("overrides", func(t *testing.T) {
+		t.Setenv("S3_BUCKET", "test-bucket")
+		t.Setenv("RESIZE_MAX_DIMENSION", "1024")
+		t.Setenv("RESIZE_SIZES", "320, 640,1024")
+		t.Setenv("RESIZE_CACHE_TTL", "0s")
+
+		cfg, err := Load()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if cfg.Resize.MaxDimension != 1024 || cfg.Resize.CacheTTL != 0 {
+			t.Errorf("unexpected resize config: %+v", cfg.Resize)
+		}
+		if !slices.Equal(cfg.Resize.Sizes, []int{320, 640, 1024}) {
+			t.Errorf("unexpected resize sizes: %v", cfg.Resize.Sizes)
+		}
+	})
+
+	t.Run("no pixel limit", func(t *testing.T) {
+		t.Setenv("S3_BUCKET", "test-bucket")
+		t.Setenv("RESIZE_MAX_DIMENSION", "1024")
+		t.Setenv("RESIZE_MAX_PIXELS", "0")
+
+		if _, err := Load(); err == nil {
+			t.Error("expected error for resizing without a pixel limit")
+		}
+	})
+
+	for _, sizes := range []string{"320,wide", "320,2048", "0"} {
+		t.Run("invalid sizes "+sizes, func(t *testing.T) {
+			t.Setenv("S3_BUCKET", "test-bucket")
+			t.Setenv("RESIZE_MAX_DIMENSION", "1024")
+			t.Setenv("RESIZE_SIZES", sizes)
+
+			if _, err := Load(); err == nil {
+				t.Errorf("expected error for RESIZE_SIZES %q", sizes)
+			}
+		})
+	}
+}
+
+func TestLoad_Auth(t *testing.T) {
+	t.Run("disabled by default", func(t *testing.T) {
+		t.Setenv("S3_BUCKET", "test-bucket")
+
+		cfg, err := Load()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if cfg.Auth.Enabled() {
+			t.Error("expected auth to be disabled by default")
+		}
+	})
+
+	t.Run("shared secret", func(t *testing.T) {
+		t.Setenv("S3_BUCKET", "test-bucket")
+		t.Setenv("JWT_SECRET", "jwt-secret")
+		t.Setenv("JWT_REQUIRED_SCOPE", "shares:write")
+
+		cfg, err := Load()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !cfg.Auth.Enabled() || cfg.Auth.RequiredScope != "shares:write" {
+			t.Errorf("unexpected auth config: %+v", cfg.Auth)
+		}
+	})
+
+	t.Run("API keys", func(t *testing.T) {
+		t.Setenv("S3_BUCKET", "test-bucket")
+		t.Setenv("API_KEYS", "key-a, key-b")
+		t.Setenv("API_KEY_DAILY_QUOTA", "50")
+
+		cfg, err := Load()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !cfg.Auth.Enabled() || cfg.Auth.JWTEnabled() {
+			t.Errorf("expected API key auth only, got %+v", cfg.Auth)
+		}
+		if len(cfg.Auth.APIKeys) != 2 || cfg.Auth.APIKeys[1].Key != "key-b" || cfg.Auth.APIKeyDailyQuota != 50 {
+			t.Errorf("unexpected API keys: %+v", cfg.Auth)
+		}
+	})
+
+	t.Run("secret and JWKS URL together", func(t *testing.T) {
+		t.Setenv("S3_BUCKET", "test-bucket")
+		t.Setenv("JWT_SECRET", "jwt-secret")
+		t.Setenv("JWT_JWKS_URL", "https://auth.example.com/.well-known/jwks.json")
+
+		if _, err := Load(); err == nil {
+			t.Error("expected error when both JWT_SECRET and JWT_JWKS_URL are set")
+		}
+	})
+}
+
+func TestLoadFromFile(t *testing.T) {
+	t.Run("file values over defaults", func(t *testing.T) {
+		cfg, err := LoadFromFile("testdata/config.yaml")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if cfg.Server.Port != "9090" {
+			t.Errorf("expected port 9090, got %s", cfg.Server.Port)
+		}
+		if cfg.Server.ReadTimeout != 10*time.Second {
+			t.Errorf("expected read timeout 10s, got %v", cfg.Server.ReadTimeout)
+		}
+		if cfg.Server.WriteTimeout != 30*time.Second {
+			t.Errorf("expected default write timeout 30s, got %v", cfg.Server.WriteTimeout)
+		}
+		if cfg.Server.TLSMinVersion != tls.VersionTLS13 {
+			t.Errorf("expected minimum TLS version %x, got %x", tls.VersionTLS13, cfg.Server.TLSMinVersion)
+		}
+		if cfg.AWS.Bucket != "file-bucket" || cfg.AWS.Region != "eu-west-1" || !cfg.AWS.UsePathStyle {
+			t.Errorf("unexpected AWS config: %+v", cfg.AWS)
+		}
+		if cfg.Redis.Addr != "redis:6379" || cfg.Redis.DB != 2 {
+			t.Errorf("unexpected Redis config: %+v", cfg.Redis)
+		}
+		if cfg.Security.MaxAgeDays != 30 || cfg.Security.LockoutWindow != 5*time.Minute {
+			t.Errorf("unexpected security config: %+v", cfg.Security)
+		}
+		if cfg.Security.MaxFailedAttempts != 0 {
+			t.Errorf("expected lockout disabled by default, got %d", cfg.Security.MaxFailedAttempts)
+		}
+		if cfg.RateLimit.RPS != 2.5 || cfg.RateLimit.Burst != 20 {
+			t.Errorf("unexpected rate limit config: %+v", cfg.RateLimit)
+		}
+		if len(cfg.CORS.AllowedOrigins) != 1 || cfg.CORS.AllowedOrigins[0] != "https://app.example.com" {
+			t.Errorf("unexpected CORS origins: %v", cfg.CORS.AllowedOrigins)
+		}
+		if cfg.BaseURL != "https://files.example.com" {
+			t.Errorf("expected base URL https://files.example.com, got %s", cfg.BaseURL)
+		}
+		if rules := cfg.Cache.Rules; len(rules) != 1 || rules[0].ContentType != "image/*" || rules[0].Directives != "public, max-age=86400, immutable" {
+			t.Errorf("unexpected cache control rules: %+v", rules)
+		}
+		if cfg.Cache.Default != "public, max-age=3600" {
+			t.Errorf("expected default cache control to be kept, got %q", cfg.Cache.Default)
+		}
+		if prefixes := cfg.TenantKeyPrefixes(); len(prefixes) != 1 || prefixes["acme"] != "acme:" {
+			t.Errorf("unexpected tenant key prefixes: %v", prefixes)
+		}
+		if buckets := cfg.TenantBuckets(); len(buckets) != 2 || buckets["acme"] != "acme-bucket" || buckets["globex"] != "globex-bucket" {
+			t.Errorf("unexpected tenant buckets: %v", buckets)
+		}
+	})
+
+	t.Run("env overrides file", func(t *testing.T) {
+		t.Setenv("S3_BUCKET", "env-bucket")
+		t.Setenv("RATE_LIMIT_BURST", "50")
+
+		cfg, err := LoadFromFile("testdata/config.yaml")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if cfg.AWS.Bucket != "env-bucket" {
+			t.Errorf("expected env bucket to win, got %s", cfg.AWS.Bucket)
+		}
+		if cfg.AWS.Region != "eu-west-1" {
+			t.Errorf("expected file region to be kept, got %s", cfg.AWS.Region)
+		}
+		if cfg.RateLimit.Burst != 50 || cfg.RateLimit.RPS != 2.5 {
+			t.Errorf("expected burst from env and RPS from file, got %+v", cfg.RateLimit)
+		}
+	})
+
+	t.Run("JSON file", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "config.json")
+		if err := os.WriteFile(path, []byte(`{"aws": {"bucket": "json-bucket"}, "server": {"port": "7070"}}`), 0o600); err != nil {
+			t.Fatalf("failed to write config: %v", err)
+		}
+
+		cfg, err := LoadFromFile(path)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if cfg.AWS.Bucket != "json-bucket" || cfg.Server.Port != "7070" {
+			t.Errorf("unexpected config from JSON: bucket %s, port %s", cfg.AWS.Bucket, cfg.Server.Port)
+		}
+	})
+
+	t.Run("unknown key", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "config.yaml")
+		if err := os.WriteFile(path, []byte("aws:\n  bucket: b\n  buckett: typo\n"), 0o600); err != nil {
+			t.Fatalf("failed to write config: %v", err)
+		}
+
+		if _, err := LoadFromFile(path); err == nil {
+			t.Error("expected error for unknown key")
+		}
+	})
+
+	t.Run("invalid tenant ID", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "config.yaml")
+		if err := os.WriteFile(path, []byte("aws:\n  bucket: b\ntenants:\n  \"acme:evil\":\n    bucket: x\n"), 0o600); err != nil {
+			t.Fatalf("failed to write config: %v", err)
+		}
+
+		if _, err := LoadFromFile(path); err == nil {
+			t.Error("expected error for invalid tenant ID")
+		}
+	})
+
+	t.Run("invalid API key tenant", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "config.yaml")
+		if err := os.WriteFile(path, []byte("aws:\n  bucket: b\nauth:\n  api_keys:\n    - key: k\n      tenants: [\"acme:evil\"]\n"), 0o600); err != nil {
+			t.Fatalf("failed to write config: %v", err)
+		}
+
+		if _, err := LoadFromFile(path); err == nil {
+			t.Error("expected error for invalid API key tenant")
+		}
+	})
+
+	t.Run("missing file", func(t *testing.T) {
+		if _, err := LoadFromFile(filepath.Join(t.TempDir(), "missing.yaml")); err == nil {
+			t.Error("expected error for missing file")
+		}
+	})
+}
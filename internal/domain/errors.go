@@ -1,6 +1,9 @@
 package domain
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+)
 
 // Common domain errors
 var (
@@ -9,4 +12,36 @@ var (
 	ErrExpired      = errors.New("expired")
 	ErrInvalidPath  = errors.New("invalid path")
 	ErrInvalidDate  = errors.New("invalid date")
+
+	// ErrForbiddenPath rejects shares of valid paths outside the shareable
+	// prefixes
+	ErrForbiddenPath = errors.New("path not shareable")
+
+	ErrPasswordRequired  = errors.New("password required")
+	ErrQuotaExceeded     = errors.New("quota exceeded")
+	ErrRefererNotAllowed = errors.New("referer not allowed")
+	ErrIPNotAllowed      = errors.New("client IP not allowed")
+
+	ErrMaxAgeExceeded       = errors.New("max age exceeded")
+	ErrPresignNotSupported  = errors.New("presigned URLs not supported by storage")
+	ErrStorageUnavailable   = errors.New("storage unavailable")
+	ErrCacheUnavailable     = errors.New("cache unavailable")
+	ErrVersionsNotSupported = errors.New("object versions not supported by storage")
+	ErrAuditDisabled        = errors.New("audit logging disabled")
+	ErrWebhooksDisabled     = errors.New("webhooks disabled")
+	ErrCookiesDisabled      = errors.New("share cookies disabled")
+	ErrNotResizable         = errors.New("object is not a resizable image")
+	ErrImageTooLarge        = errors.New("image too large to resize")
+	ErrRotationNotSupported = errors.New("secret rotation not supported for this share")
+
+	// ErrInvalidSignature rejects signed share links whose path or expiry
+	// were altered; it is also an ErrUnauthorized
+	ErrInvalidSignature = fmt.Errorf("invalid link signature: %w", ErrUnauthorized)
+
+	// ErrConsumed rejects valid links of shares revoked by reaching their
+	// download limit; it is also an ErrUnauthorized
+	ErrConsumed = fmt.Errorf("share consumed: %w", ErrUnauthorized)
+
+	ErrIdempotencyInProgress = errors.New("request with idempotency key in progress")
+	ErrIdempotencyKeyReused  = errors.New("idempotency key reused for a different share")
 )
@@ -0,0 +1,49 @@
+package domain
+
+import (
+	"net/netip"
+	"strings"
+)
+
+// parseIPPattern parses a CIDR such as 203.0.113.0/24 or 2001:db8::/32, or a
+// single address standing for itself
+func parseIPPattern(pattern string) (netip.Prefix, bool) {
+	if strings.Contains(pattern, "/") {
+		prefix, err := netip.ParsePrefix(pattern)
+		if err != nil {
+			return netip.Prefix{}, false
+		}
+		return prefix.Masked(), true
+	}
+
+	addr, err := netip.ParseAddr(pattern)
+	if err != nil {
+		return netip.Prefix{}, false
+	}
+	addr = addr.Unmap()
+	return netip.PrefixFrom(addr, addr.BitLen()), true
+}
+
+// ValidIPPattern reports whether pattern is a CIDR or a single IP address
+func ValidIPPattern(pattern string) bool {
+	_, ok := parseIPPattern(pattern)
+	return ok
+}
+
+// MatchIP reports whether ip lies in one of patterns. IPv4 addresses match
+// IPv4 patterns also when written as IPv4-mapped IPv6 addresses.
+func MatchIP(ip string, patterns []string) bool {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+
+	for _, pattern := range patterns {
+		prefix, ok := parseIPPattern(pattern)
+		if ok && prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,52 @@
+package domain
+
+import "testing"
+
+func TestValidIPPattern(t *testing.T) {
+	tests := []struct {
+		pattern string
+		valid   bool
+	}{
+		{pattern: "203.0.113.0/24", valid: true},
+		{pattern: "203.0.113.7", valid: true},
+		{pattern: "2001:db8::/32", valid: true},
+		{pattern: "::1", valid: true},
+		{pattern: "203.0.113.0/33", valid: false},
+		{pattern: "example.com", valid: false},
+		{pattern: "", valid: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pattern, func(t *testing.T) {
+			if got := ValidIPPattern(tt.pattern); got != tt.valid {
+				t.Errorf("expected %v, got %v", tt.valid, got)
+			}
+		})
+	}
+}
+
+func TestMatchIP(t *testing.T) {
+	patterns := []string{"203.0.113.0/24", "198.51.100.7", "2001:db8:abcd::/48"}
+
+	tests := []struct {
+		ip      string
+		matches bool
+	}{
+		{ip: "203.0.113.42", matches: true},
+		{ip: "198.51.100.7", matches: true},
+		{ip: "::ffff:203.0.113.42", matches: true},
+		{ip: "2001:db8:abcd:12::1", matches: true},
+		{ip: "203.0.114.1", matches: false},
+		{ip: "198.51.100.8", matches: false},
+		{ip: "2001:db8:abce::1", matches: false},
+		{ip: "not-an-ip", matches: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.ip, func(t *testing.T) {
+			if got := MatchIP(tt.ip, patterns); got != tt.matches {
+				t.Errorf("expected %v, got %v", tt.matches, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,20 @@
+package domain
+
+import "context"
+
+// objectVersionContextKey carries the pinned object version in a context
+type objectVersionContextKey struct{}
+
+// WithObjectVersion returns a copy of ctx whose storage calls address
+// versionID of an object instead of its latest version; an empty ID is the
+// latest version
+func WithObjectVersion(ctx context.Context, versionID string) context.Context {
+	return context.WithValue(ctx, objectVersionContextKey{}, versionID)
+}
+
+// ObjectVersionFromContext returns the object version ctx pins, or "" for the
+// latest version
+func ObjectVersionFromContext(ctx context.Context) string {
+	versionID, _ := ctx.Value(objectVersionContextKey{}).(string)
+	return versionID
+}
@@ -0,0 +1,48 @@
+package domain
+
+import (
+	"net/url"
+	"strings"
+)
+
+// ValidRefererPattern reports whether pattern is a host name such as
+// example.com, optionally prefixed with "*." to match its subdomains
+func ValidRefererPattern(pattern string) bool {
+	host := strings.TrimPrefix(pattern, "*.")
+	if host == "" || strings.HasPrefix(host, ".") || strings.HasSuffix(host, ".") {
+		return false
+	}
+	for _, c := range host {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9', c == '-', c == '.':
+		default:
+			return false
+		}
+	}
+	return !strings.Contains(host, "..")
+}
+
+// MatchReferer reports whether the host of the referer URL matches one of
+// patterns. A pattern "*.example.com" matches every subdomain of
+// example.com but not example.com itself. Hosts compare case-insensitively.
+func MatchReferer(referer string, patterns []string) bool {
+	u, err := url.Parse(referer)
+	if err != nil || u.Host == "" {
+		return false
+	}
+	host := strings.ToLower(u.Hostname())
+
+	for _, pattern := range patterns {
+		pattern = strings.ToLower(pattern)
+		if suffix, ok := strings.CutPrefix(pattern, "*."); ok {
+			if strings.HasSuffix(host, "."+suffix) {
+				return true
+			}
+			continue
+		}
+		if host == pattern {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,57 @@
+package domain
+
+import "testing"
+
+func TestValidRefererPattern(t *testing.T) {
+	tests := []struct {
+		pattern string
+		valid   bool
+	}{
+		{pattern: "example.com", valid: true},
+		{pattern: "*.example.com", valid: true},
+		{pattern: "localhost", valid: true},
+		{pattern: "", valid: false},
+		{pattern: "*.", valid: false},
+		{pattern: "https://example.com", valid: false},
+		{pattern: "example.com/images", valid: false},
+		{pattern: "example.com:8080", valid: false},
+		{pattern: "ex*mple.com", valid: false},
+		{pattern: "example..com", valid: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pattern, func(t *testing.T) {
+			if got := ValidRefererPattern(tt.pattern); got != tt.valid {
+				t.Errorf("expected %v, got %v", tt.valid, got)
+			}
+		})
+	}
+}
+
+func TestMatchReferer(t *testing.T) {
+	patterns := []string{"example.com", "*.partner.org"}
+
+	tests := []struct {
+		referer string
+		matches bool
+	}{
+		{referer: "https://example.com/gallery", matches: true},
+		{referer: "http://EXAMPLE.com:8080/", matches: true},
+		{referer: "https://cdn.partner.org/page", matches: true},
+		{referer: "https://a.b.partner.org/", matches: true},
+		{referer: "https://partner.org/", matches: false},
+		{referer: "https://www.example.com/", matches: false},
+		{referer: "https://example.com.evil.net/", matches: false},
+		{referer: "https://evilpartner.org/", matches: false},
+		{referer: "not a url", matches: false},
+		{referer: "", matches: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.referer, func(t *testing.T) {
+			if got := MatchReferer(tt.referer, patterns); got != tt.matches {
+				t.Errorf("expected %v, got %v", tt.matches, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,90 @@
+package domain
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ShareRecord is the share metadata persisted in the cache
+type ShareRecord struct {
+	Secret        string    `json:"secret"`
+	CreatedAt     time.Time `json:"created_at"`
+	ExpiresAt     time.Time `json:"expires_at"`
+	MaxDownloads  int       `json:"max_downloads,omitempty"`
+	DownloadCount int       `json:"download_count,omitempty"`
+	Mode          ShareMode `json:"mode,omitempty"`
+	// PasswordHash is the bcrypt hash of the share password, if any
+	PasswordHash string `json:"password_hash,omitempty"`
+	// Public shares validate without a secret
+	Public bool `json:"public,omitempty"`
+	// VersionID pins the share to one version of a versioned object
+	VersionID string `json:"version_id,omitempty"`
+	// WebhookURL is notified of the first successful download
+	WebhookURL string `json:"webhook_url,omitempty"`
+	// AllowedReferers overrides the configured referer allowlist
+	AllowedReferers []string `json:"allowed_referers,omitempty"`
+	// AllowedIPs lists the client CIDRs allowed to download the share
+	AllowedIPs []string `json:"allowed_ips,omitempty"`
+	// LinkExpiresAt is the expiry encoded in the links of a share whose
+	// ExpiresAt was extended since; zero for shares never extended
+	LinkExpiresAt time.Time `json:"link_expires_at,omitempty"`
+	// PreviousSecret is the secret replaced by the last rotation, still
+	// accepted until PreviousSecretExpiresAt
+	PreviousSecret          string    `json:"previous_secret,omitempty"`
+	PreviousSecretExpiresAt time.Time `json:"previous_secret_expires_at,omitempty"`
+	// ShortCode is the short code created with the share, if any
+	ShortCode string `json:"short_code,omitempty"`
+	// Consumed records are kept after their last permitted download only
+	// for resuming the downloads already counted
+	Consumed bool `json:"consumed,omitempty"`
+}
+
+// ShortLink is the share a short code resolves to
+type ShortLink struct {
+	S3Path    string    `json:"s3_path"`
+	Secret    string    `json:"secret"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// ShareCookie is the grant of a signed share cookie: the share of S3Path,
+// and for prefix shares every object under it, until ExpiresAt. Generation
+// identifies the share record the cookie was issued for.
+type ShareCookie struct {
+	S3Path     string
+	ExpiresAt  time.Time
+	Generation string
+}
+
+// Grants reports whether the cookie grants the object at s3Path
+func (c *ShareCookie) Grants(s3Path string) bool {
+	if strings.HasSuffix(c.S3Path, "/") {
+		return strings.HasPrefix(s3Path, c.S3Path) && len(s3Path) > len(c.S3Path)
+	}
+	return s3Path == c.S3Path
+}
+
+// EncodeShareRecord encodes a share record for storage in the cache
+func EncodeShareRecord(record *ShareRecord) (string, error) {
+	value, err := json.Marshal(record)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode share record: %w", err)
+	}
+	return string(value), nil
+}
+
+// DecodeShareRecord decodes a share record read from the cache. Values written
+// before share records were introduced hold the bare secret and are decoded as
+// a record with only the secret set.
+func DecodeShareRecord(value string) (*ShareRecord, error) {
+	if !strings.HasPrefix(value, "{") {
+		return &ShareRecord{Secret: value}, nil
+	}
+
+	var record ShareRecord
+	if err := json.Unmarshal([]byte(value), &record); err != nil {
+		return nil, fmt.Errorf("failed to decode share record: %w", err)
+	}
+	return &record, nil
+}
@@ -0,0 +1,74 @@
+package domain
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestShareRecord_RoundTrip(t *testing.T) {
+	record := &ShareRecord{
+		Secret:          "test-secret",
+		CreatedAt:       time.Date(2024, 12, 1, 10, 0, 0, 0, time.UTC),
+		ExpiresAt:       time.Date(2024, 12, 31, 23, 59, 59, 0, time.UTC),
+		MaxDownloads:    3,
+		DownloadCount:   1,
+		AllowedReferers: []string{"example.com", "*.example.com"},
+	}
+
+	value, err := EncodeShareRecord(record)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	decoded, err := DecodeShareRecord(value)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !reflect.DeepEqual(decoded, record) {
+		t.Errorf("expected %+v, got %+v", record, decoded)
+	}
+}
+
+func TestDecodeShareRecord(t *testing.T) {
+	t.Run("legacy bare secret", func(t *testing.T) {
+		record, err := DecodeShareRecord("test-secret")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if record.Secret != "test-secret" || !record.ExpiresAt.IsZero() {
+			t.Errorf("expected record with only the secret, got %+v", record)
+		}
+	})
+
+	t.Run("malformed record", func(t *testing.T) {
+		if _, err := DecodeShareRecord(`{"secret":`); err == nil {
+			t.Errorf("expected error for malformed record")
+		}
+	})
+}
+
+func TestShareCookie_Grants(t *testing.T) {
+	tests := []struct {
+		share    string
+		path     string
+		expected bool
+	}{
+		{share: "images/photo.jpg", path: "images/photo.jpg", expected: true},
+		{share: "images/photo.jpg", path: "images/photo.jpg.bak", expected: false},
+		{share: "images/", path: "images/a.jpg", expected: true},
+		{share: "images/", path: "images/sub/b.jpg", expected: true},
+		{share: "images/", path: "images/", expected: false},
+		{share: "images/", path: "images-other/c.jpg", expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.share+" "+tt.path, func(t *testing.T) {
+			cookie := &ShareCookie{S3Path: tt.share}
+			if got := cookie.Grants(tt.path); got != tt.expected {
+				t.Errorf("expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
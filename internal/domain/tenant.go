@@ -0,0 +1,38 @@
+package domain
+
+import "context"
+
+// maxTenantIDLength bounds tenant IDs so they stay short in keys and URLs
+const maxTenantIDLength = 64
+
+// tenantContextKey carries the tenant of a request in its context
+type tenantContextKey struct{}
+
+// WithTenant returns a copy of ctx scoped to tenantID; an empty ID is the
+// default tenant
+func WithTenant(ctx context.Context, tenantID string) context.Context {
+	return context.WithValue(ctx, tenantContextKey{}, tenantID)
+}
+
+// TenantFromContext returns the tenant ctx is scoped to, or "" for the
+// default tenant
+func TenantFromContext(ctx context.Context) string {
+	tenantID, _ := ctx.Value(tenantContextKey{}).(string)
+	return tenantID
+}
+
+// IsValidTenantID reports whether id is a usable tenant ID: 1 to 64 ASCII
+// letters, digits, '-' or '_', so it can never smuggle a key or path separator
+func IsValidTenantID(id string) bool {
+	if id == "" || len(id) > maxTenantIDLength {
+		return false
+	}
+	for _, r := range id {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_':
+		default:
+			return false
+		}
+	}
+	return true
+}
@@ -5,24 +5,122 @@ import (
 	"time"
 )
 
+// ShareMode selects how a share's object is delivered to clients
+type ShareMode string
+
+// Share delivery modes
+const (
+	// ShareModeProxy streams the object through the service; it is the default
+	ShareModeProxy ShareMode = "proxy"
+	// ShareModeRedirect redirects clients to a presigned storage URL
+	ShareModeRedirect ShareMode = "redirect"
+)
+
+// ResizeFit selects how an image is fitted to a requested width and height
+type ResizeFit string
+
+// Image resize fits
+const (
+	// ResizeFitContain scales the image to fit within the box, keeping its
+	// aspect ratio; it is the default
+	ResizeFitContain ResizeFit = "contain"
+	// ResizeFitCover scales the image to fill the box, cropping the centre
+	ResizeFitCover ResizeFit = "cover"
+	// ResizeFitFill stretches the image to the box
+	ResizeFitFill ResizeFit = "fill"
+)
+
+// ResizeOptions asks for an image scaled to Width by Height pixels. When one
+// of them is zero it follows from the other and the image's aspect ratio, and
+// Fit does not apply.
+type ResizeOptions struct {
+	Width  int
+	Height int
+	Fit    ResizeFit
+}
+
 // ShareRequest represents a request to create a shareable link
 type ShareRequest struct {
 	S3Path    string
 	Secret    string
 	ExpiresAt time.Time
+	// MaxDownloads limits how many times the share can be downloaded; zero is unlimited
+	MaxDownloads int
+	// Mode selects proxying or presigned redirects; empty means ShareModeProxy
+	Mode ShareMode
+	// Password, when set, must also be presented to download the share; only
+	// its hash is stored
+	Password string
+	// Public shares need no secret and their URLs carry - in its place
+	Public bool
+	// Short shares are linked through a random code that hides the path
+	Short bool
+	// VersionID, when set, shares that version of the object even after its
+	// key is overwritten
+	VersionID string
+	// WebhookURL, when set, is notified of the first successful download
+	WebhookURL string
+	// AllowedReferers restricts downloads to pages on these hosts, in place
+	// of the configured allowlist; see ValidRefererPattern
+	AllowedReferers []string
+	// AllowedIPs restricts downloads to clients in these CIDRs or single
+	// addresses; empty is unrestricted
+	AllowedIPs []string
 }
 
 // ShareResponse represents the response after creating a shareable link
 type ShareResponse struct {
-	URL       string
+	URL string
+	// Secret is the secret segment of URL, which ValidateShare checks: the
+	// caller secret, or the signed token with SignedURLs. Public shares have
+	// none.
+	Secret    string
 	ExpiresAt time.Time
 	MaxAge    time.Duration
 }
 
+// ShareResult is the outcome of one share of a batch creation
+type ShareResult struct {
+	Response *ShareResponse
+	Err      error
+}
+
+// UploadResponse represents the response after creating an upload and its share
+type UploadResponse struct {
+	// UploadURL is a presigned URL accepting a PUT of the object body
+	UploadURL       string
+	UploadExpiresAt time.Time
+	Share           *ShareResponse
+}
+
+// ShareInfo describes the current state of an existing share
+type ShareInfo struct {
+	S3Path    string
+	ExpiresAt time.Time
+	TTL       time.Duration
+	Active    bool
+	// DownloadCount is the number of downloads of the share so far
+	DownloadCount int64
+	// LastAccessedAt is the time of the latest download; zero when the share
+	// was never downloaded
+	LastAccessedAt time.Time
+}
+
+// CachePolicy describes how responses for a share may be cached
+type CachePolicy struct {
+	// Private shares, protected by a password, a download limit or an IP or
+	// referer allowlist, must never be stored by caches
+	Private bool
+	// ExpiresAt bounds the freshness of cached responses; zero is unbounded
+	ExpiresAt time.Time
+}
+
 // ShareService defines the interface for sharing operations
 type ShareService interface {
 	CreateShare(ctx context.Context, req *ShareRequest) (*ShareResponse, error)
-	ValidateShare(ctx context.Context, s3Path, secret string) error
+	ValidateShare(ctx context.Context, s3Path, secret string, expiresAt time.Time) error
+	RevokeShare(ctx context.Context, s3Path string) error
+	GetShareInfo(ctx context.Context, s3Path string) (*ShareInfo, error)
 	GetObject(ctx context.Context, s3Path string) (ObjectReader, error)
 }
 
@@ -31,25 +129,99 @@ type ObjectReader interface {
 	Read(p []byte) (n int, err error)
 	Close() error
 	ContentType() string
+	// Size returns the length of the object in bytes; -1 when the backend
+	// does not report one
 	Size() int64
+	ETag() string
+	// LastModified returns the modification time of the object; zero when
+	// the backend does not report one
+	LastModified() time.Time
+	// Checksum returns the hex-encoded SHA-256 of the whole object as
+	// recorded by the backend; empty when the backend records none
+	Checksum() string
 }
 
 // CacheService defines the interface for cache operations
 type CacheService interface {
 	Set(ctx context.Context, key, value string, expiration time.Duration) error
+	// SetNX stores a value only if the key does not exist and reports whether
+	// it was stored
+	SetNX(ctx context.Context, key, value string, expiration time.Duration) (bool, error)
 	Get(ctx context.Context, key string) (string, error)
 	Delete(ctx context.Context, key string) error
+	// Incr atomically increments a counter and refreshes its expiration when positive
+	Incr(ctx context.Context, key string, expiration time.Duration) (int64, error)
+	// IncrBy is Incr adding delta, which may be negative
+	IncrBy(ctx context.Context, key string, delta int64, expiration time.Duration) (int64, error)
+	// TTL returns the remaining lifetime of a key, a negative duration when the
+	// key has no expiration, or ErrNotFound when the key does not exist
+	TTL(ctx context.Context, key string) (time.Duration, error)
+	// Expire replaces the expiration of a key, doing nothing when the key
+	// does not exist
+	Expire(ctx context.Context, key string, expiration time.Duration) error
+	// Scan returns a batch of keys starting with prefix, resuming at cursor,
+	// without blocking the cache like listing every key at once would. Start
+	// at cursor zero; iteration is complete when the returned cursor is zero.
+	// As with Redis SCAN, batches may be empty and keys may repeat.
+	Scan(ctx context.Context, prefix string, cursor uint64) ([]string, uint64, error)
+	Ping(ctx context.Context) error
 }
 
 // StorageService defines the interface for object storage operations
 type StorageService interface {
 	GetObject(ctx context.Context, key string) (ObjectReader, error)
+	GetObjectRange(ctx context.Context, key string, start, end int64) (ObjectReader, error)
 	HeadObject(ctx context.Context, key string) (*ObjectMetadata, error)
+	// DeleteObject removes an object, returning ErrNotFound when it does not exist
+	DeleteObject(ctx context.Context, key string) error
+	// ListObjects returns a page of objects whose keys start with prefix, in key
+	// order, resuming after token. The returned token is empty on the last page.
+	ListObjects(ctx context.Context, prefix, token string) ([]ObjectMetadata, string, error)
+	HealthCheck(ctx context.Context) error
+}
+
+// Presigner is implemented by storage backends that can issue time-limited
+// URLs granting direct access to an object
+type Presigner interface {
+	PresignGetObject(ctx context.Context, key string, expiry time.Duration) (string, error)
+	PresignPutObject(ctx context.Context, key string, expiry time.Duration) (string, error)
 }
 
-// ObjectMetadata contains metadata about a stored object
+// ObjectMetadata contains metadata about a stored object. Size is -1 when the
+// backend does not report the length of the object.
 type ObjectMetadata struct {
+	Key          string
 	ContentType  string
 	Size         int64
 	LastModified time.Time
+	ETag         string
+	// Checksum is the hex-encoded SHA-256 of the object; empty when unknown
+	Checksum string
+}
+
+// AuditEntry records one access to a share link, served or denied
+type AuditEntry struct {
+	S3Path    string    `json:"s3_path"`
+	Timestamp time.Time `json:"timestamp"`
+	ClientIP  string    `json:"client_ip"`
+	UserAgent string    `json:"user_agent"`
+	// Status is the HTTP status the access was answered with
+	Status int `json:"status"`
+}
+
+// WebhookEvent is posted to the webhook URL of a share on its first
+// successful download
+type WebhookEvent struct {
+	S3Path     string    `json:"s3_path"`
+	AccessedAt time.Time `json:"accessed_at"`
+	ClientIP   string    `json:"client_ip"`
+}
+
+// AuditSink stores audit entries in bounded lists, one per key
+type AuditSink interface {
+	// Append adds entry to the list of key, dropping its oldest entries
+	// beyond the sink's bound
+	Append(ctx context.Context, key string, entry *AuditEntry) error
+	// Recent returns up to limit entries of key, newest first
+	Recent(ctx context.Context, key string, limit int) ([]AuditEntry, error)
 }
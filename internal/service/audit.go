@@ -0,0 +1,110 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/vchitai/go-s3-sharing/internal/domain"
+)
+
+// RedisAuditSink implements AuditSink with one Redis list per key, newest
+// entry first
+type RedisAuditSink struct {
+	client redis.UniversalClient
+	// maxEntries bounds each list with LTRIM
+	maxEntries int
+	// retention expires lists that see no access for that long; zero keeps
+	// them forever
+	retention time.Duration
+}
+
+// NewRedisAuditSink creates an audit sink keeping the newest maxEntries
+// entries of each key for retention after its last entry
+func NewRedisAuditSink(client redis.UniversalClient, maxEntries int, retention time.Duration) *RedisAuditSink {
+	return &RedisAuditSink{
+		client:     client,
+		maxEntries: maxEntries,
+		retention:  retention,
+	}
+}
+
+// Append pushes entry onto the list of key and trims the list to its bound
+// in a single round trip
+func (a *RedisAuditSink) Append(ctx context.Context, key string, entry *domain.AuditEntry) (err error) {
+	ctx, span := startRedisSpan(ctx, "LPUSH")
+	defer func() { endSpan(span, err) }()
+
+	value, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to encode audit entry: %w", err)
+	}
+
+	pipe := a.client.TxPipeline()
+	pipe.LPush(ctx, key, value)
+	pipe.LTrim(ctx, key, 0, int64(a.maxEntries)-1)
+	if a.retention > 0 {
+		pipe.Expire(ctx, key, a.retention)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to append audit entry in Redis: %w", err)
+	}
+	return nil
+}
+
+// Recent returns up to limit entries of key, newest first
+func (a *RedisAuditSink) Recent(ctx context.Context, key string, limit int) (entries []domain.AuditEntry, err error) {
+	ctx, span := startRedisSpan(ctx, "LRANGE")
+	defer func() { endSpan(span, err) }()
+
+	values, err := a.client.LRange(ctx, key, 0, int64(limit)-1).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read audit entries from Redis: %w", err)
+	}
+
+	entries = make([]domain.AuditEntry, 0, len(values))
+	for _, value := range values {
+		var entry domain.AuditEntry
+		if err := json.Unmarshal([]byte(value), &entry); err != nil {
+			return nil, fmt.Errorf("failed to decode audit entry: %w", err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// RecordAccess appends an access to a share link to the audit trail of its
+// path. It does nothing when no audit sink is configured.
+func (s *ShareService) RecordAccess(ctx context.Context, entry *domain.AuditEntry) error {
+	if s.config.Audit == nil {
+		return nil
+	}
+	if err := s.config.Audit.Append(ctx, s.generateAuditKey(ctx, entry.S3Path), entry); err != nil {
+		return fmt.Errorf("failed to record access: %w", err)
+	}
+	return nil
+}
+
+// AuditLog returns up to limit of the most recent accesses to s3Path, newest
+// first. It returns ErrAuditDisabled when no audit sink is configured.
+func (s *ShareService) AuditLog(ctx context.Context, s3Path string, limit int) ([]domain.AuditEntry, error) {
+	if s.config.Audit == nil {
+		return nil, domain.ErrAuditDisabled
+	}
+	if !s.isValidS3Path(s3Path) {
+		return nil, domain.ErrInvalidPath
+	}
+
+	entries, err := s.config.Audit.Recent(ctx, s.generateAuditKey(ctx, s3Path), limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read audit log: %w", err)
+	}
+	return entries, nil
+}
+
+// generateAuditKey creates the audit list key of the S3 path
+func (s *ShareService) generateAuditKey(ctx context.Context, s3Path string) string {
+	return fmt.Sprintf("%s%s:%s", s.tenantKeyPrefix(ctx), s.keyName("audit"), s3Path)
+}
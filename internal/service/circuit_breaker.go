@@ -0,0 +1,219 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/vchitai/go-s3-sharing/internal/domain"
+)
+
+// BreakerState is the state of a circuit breaker
+type BreakerState int
+
+// Circuit breaker states
+const (
+	// BreakerClosed passes every call through
+	BreakerClosed BreakerState = iota
+	// BreakerOpen fails every call fast with ErrStorageUnavailable
+	BreakerOpen
+	// BreakerHalfOpen lets a single probe call through to test recovery
+	BreakerHalfOpen
+)
+
+// String returns the lowercase name of the state
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerClosed:
+		return "closed"
+	case BreakerOpen:
+		return "open"
+	case BreakerHalfOpen:
+		return "half-open"
+	default:
+		return "unknown"
+	}
+}
+
+// BreakerConfig configures a storage circuit breaker
+type BreakerConfig struct {
+	// FailureThreshold is the number of consecutive failed calls that opens
+	// the breaker
+	FailureThreshold int
+	// OpenTimeout is how long the breaker stays open before probing recovery
+	OpenTimeout time.Duration
+	// OnStateChange, when set, is called with each new state; it must not
+	// call back into the breaker
+	OnStateChange func(BreakerState)
+}
+
+// circuitBreaker tracks consecutive failures of a backend
+type circuitBreaker struct {
+	config BreakerConfig
+	// now returns the current time and is replaced in tests
+	now func() time.Time
+
+	mu       sync.Mutex
+	state    BreakerState
+	failures int
+	openedAt time.Time
+	probing  bool
+}
+
+// allow reports whether a call may proceed, moving an open breaker whose
+// timeout has passed to half-open. A half-open breaker allows one probe at
+// a time.
+func (b *circuitBreaker) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case BreakerOpen:
+		if b.now().Sub(b.openedAt) < b.config.OpenTimeout {
+			return false
+		}
+		b.setState(BreakerHalfOpen)
+		b.probing = true
+		return true
+	case BreakerHalfOpen:
+		if b.probing {
+			return false
+		}
+		b.probing = true
+		return true
+	default:
+		return true
+	}
+}
+
+// record counts the outcome of an allowed call. Successes close the breaker;
+// failures open it once the threshold is reached, or at once while probing.
+func (b *circuitBreaker) record(failed bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.probing = false
+	if !failed {
+		b.failures = 0
+		b.setState(BreakerClosed)
+		return
+	}
+
+	b.failures++
+	if b.state == BreakerHalfOpen || b.failures >= b.config.FailureThreshold {
+		b.openedAt = b.now()
+		b.setState(BreakerOpen)
+	}
+}
+
+// setState moves the breaker to state and reports changes; b.mu must be held
+func (b *circuitBreaker) setState(state BreakerState) {
+	if b.state == state {
+		return
+	}
+	b.state = state
+	if b.config.OnStateChange != nil {
+		b.config.OnStateChange(state)
+	}
+}
+
+// isBackendFailure reports whether err means the backend is unhealthy.
+// Missing objects, invalid keys, unsupported versions and callers going away
+// are not failures.
+func isBackendFailure(err error) bool {
+	return err != nil &&
+		!errors.Is(err, domain.ErrNotFound) &&
+		!errors.Is(err, domain.ErrInvalidPath) &&
+		!errors.Is(err, domain.ErrVersionsNotSupported) &&
+		!errors.Is(err, context.Canceled)
+}
+
+// breakerStorage guards a StorageService with a circuit breaker
+type breakerStorage struct {
+	next    domain.StorageService
+	breaker *circuitBreaker
+}
+
+// breakerPresigningStorage is a breakerStorage over a backend that also
+// presigns URLs. Presigning is local and bypasses the breaker.
+type breakerPresigningStorage struct {
+	*breakerStorage
+	domain.Presigner
+}
+
+// NewBreakerStorage wraps storage in a circuit breaker. After
+// FailureThreshold consecutive failed calls every call fails fast with
+// ErrStorageUnavailable for OpenTimeout, after which a single probe call
+// decides whether the breaker closes again. The wrapper implements
+// domain.Presigner exactly when storage does.
+func NewBreakerStorage(storage domain.StorageService, config BreakerConfig) domain.StorageService {
+	guarded := &breakerStorage{
+		next:    storage,
+		breaker: &circuitBreaker{config: config, now: time.Now},
+	}
+	if presigner, ok := storage.(domain.Presigner); ok {
+		return &breakerPresigningStorage{breakerStorage: guarded, Presigner: presigner}
+	}
+	return guarded
+}
+
+// call runs fn unless the breaker is open and records its outcome
+func (s *breakerStorage) call(fn func() error) error {
+	if !s.breaker.allow() {
+		return domain.ErrStorageUnavailable
+	}
+	err := fn()
+	s.breaker.record(isBackendFailure(err))
+	return err
+}
+
+// GetObject retrieves an object unless the breaker is open
+func (s *breakerStorage) GetObject(ctx context.Context, key string) (reader domain.ObjectReader, err error) {
+	err = s.call(func() error {
+		reader, err = s.next.GetObject(ctx, key)
+		return err
+	})
+	return reader, err
+}
+
+// GetObjectRange retrieves an object range unless the breaker is open
+func (s *breakerStorage) GetObjectRange(ctx context.Context, key string, start, end int64) (reader domain.ObjectReader, err error) {
+	err = s.call(func() error {
+		reader, err = s.next.GetObjectRange(ctx, key, start, end)
+		return err
+	})
+	return reader, err
+}
+
+// HeadObject retrieves object metadata unless the breaker is open
+func (s *breakerStorage) HeadObject(ctx context.Context, key string) (metadata *domain.ObjectMetadata, err error) {
+	err = s.call(func() error {
+		metadata, err = s.next.HeadObject(ctx, key)
+		return err
+	})
+	return metadata, err
+}
+
+// DeleteObject removes an object unless the breaker is open
+func (s *breakerStorage) DeleteObject(ctx context.Context, key string) error {
+	return s.call(func() error {
+		return s.next.DeleteObject(ctx, key)
+	})
+}
+
+// ListObjects lists objects unless the breaker is open
+func (s *breakerStorage) ListObjects(ctx context.Context, prefix, token string) (objects []domain.ObjectMetadata, next string, err error) {
+	err = s.call(func() error {
+		objects, next, err = s.next.ListObjects(ctx, prefix, token)
+		return err
+	})
+	return objects, next, err
+}
+
+// HealthCheck checks the backend unless the breaker is open
+func (s *breakerStorage) HealthCheck(ctx context.Context) error {
+	return s.call(func() error {
+		return s.next.HealthCheck(ctx)
+	})
+}
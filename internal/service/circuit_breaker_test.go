@@ -0,0 +1,133 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/vchitai/go-s3-sharing/internal/domain"
+)
+
+// flakyStorage is a mockStorageService whose HeadObject fails with err when set
+type flakyStorage struct {
+	*mockStorageService
+	err   error
+	calls int
+}
+
+func (f *flakyStorage) HeadObject(ctx context.Context, key string) (*domain.ObjectMetadata, error) {
+	f.calls++
+	if f.err != nil {
+		return nil, f.err
+	}
+	return f.mockStorageService.HeadObject(ctx, key)
+}
+
+func TestBreakerStorage(t *testing.T) {
+	flaky := &flakyStorage{mockStorageService: &mockStorageService{objects: map[string]*domain.ObjectMetadata{
+		"images/photo.jpg": {ContentType: "image/jpeg"},
+	}}}
+
+	var transitions []BreakerState
+	storage := NewBreakerStorage(flaky, BreakerConfig{
+		FailureThreshold: 3,
+		OpenTimeout:      time.Minute,
+		OnStateChange:    func(state BreakerState) { transitions = append(transitions, state) },
+	})
+	now := time.Now()
+	storage.(*breakerStorage).breaker.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	head := func() error {
+		_, err := storage.HeadObject(ctx, "images/photo.jpg")
+		return err
+	}
+
+	// Missing objects are not backend failures
+	for i := 0; i < 5; i++ {
+		if _, err := storage.HeadObject(ctx, "images/missing.jpg"); !errors.Is(err, domain.ErrNotFound) {
+			t.Fatalf("expected %v, got %v", domain.ErrNotFound, err)
+		}
+	}
+	if len(transitions) != 0 {
+		t.Fatalf("expected missing objects to keep the breaker closed, got %v", transitions)
+	}
+
+	// Consecutive failures open the breaker
+	flaky.err = errors.New("connection reset")
+	for i := 0; i < 3; i++ {
+		if err := head(); err == nil || errors.Is(err, domain.ErrStorageUnavailable) {
+			t.Fatalf("expected backend error on failure %d, got %v", i+1, err)
+		}
+	}
+
+	// An open breaker fails fast without calling the backend
+	calls := flaky.calls
+	if err := head(); !errors.Is(err, domain.ErrStorageUnavailable) {
+		t.Errorf("expected %v while open, got %v", domain.ErrStorageUnavailable, err)
+	}
+	if flaky.calls != calls {
+		t.Error("expected an open breaker not to call the backend")
+	}
+
+	// A failed probe reopens the breaker
+	now = now.Add(time.Minute)
+	if err := head(); err == nil || errors.Is(err, domain.ErrStorageUnavailable) {
+		t.Errorf("expected the probe to reach the backend, got %v", err)
+	}
+	if err := head(); !errors.Is(err, domain.ErrStorageUnavailable) {
+		t.Errorf("expected %v after a failed probe, got %v", domain.ErrStorageUnavailable, err)
+	}
+
+	// A successful probe closes it
+	now = now.Add(time.Minute)
+	flaky.err = nil
+	if err := head(); err != nil {
+		t.Errorf("expected the probe to succeed, got %v", err)
+	}
+	if err := head(); err != nil {
+		t.Errorf("expected a closed breaker to pass calls, got %v", err)
+	}
+
+	expected := []BreakerState{BreakerOpen, BreakerHalfOpen, BreakerOpen, BreakerHalfOpen, BreakerClosed}
+	if !reflect.DeepEqual(transitions, expected) {
+		t.Errorf("expected transitions %v, got %v", expected, transitions)
+	}
+}
+
+func TestCircuitBreaker_SingleProbe(t *testing.T) {
+	now := time.Now()
+	breaker := &circuitBreaker{
+		config: BreakerConfig{FailureThreshold: 1, OpenTimeout: time.Second},
+		now:    func() time.Time { return now },
+	}
+
+	breaker.record(true)
+	now = now.Add(time.Second)
+
+	if !breaker.allow() {
+		t.Fatal("expected the first call after the timeout to probe")
+	}
+	if breaker.allow() {
+		t.Error("expected a second call to fail fast while the probe is in flight")
+	}
+
+	breaker.record(false)
+	if !breaker.allow() || !breaker.allow() {
+		t.Error("expected a closed breaker to allow every call")
+	}
+}
+
+func TestBreakerStorage_Presigner(t *testing.T) {
+	plain := NewBreakerStorage(&mockStorageService{}, BreakerConfig{FailureThreshold: 1})
+	if _, ok := plain.(domain.Presigner); ok {
+		t.Error("expected no presigner over a storage that cannot presign")
+	}
+
+	presigning := NewBreakerStorage(&mockPresigningStorage{mockStorageService: &mockStorageService{}}, BreakerConfig{FailureThreshold: 1})
+	if _, ok := presigning.(domain.Presigner); !ok {
+		t.Error("expected the presigner to be preserved")
+	}
+}
@@ -0,0 +1,175 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/vchitai/go-s3-sharing/internal/domain"
+)
+
+func newTestFSService(t *testing.T) *FSService {
+	t.Helper()
+
+	root := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(root, "images"), 0o755); err != nil {
+		t.Fatalf("failed to create dir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "images", "photo.jpg"), []byte("0123456789"), 0o644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+	// A file outside the root that traversal attempts would target
+	if err := os.WriteFile(filepath.Join(filepath.Dir(root), "secret.txt"), []byte("secret"), 0o644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+
+	return NewFSService(root)
+}
+
+func TestFSService_HeadObject(t *testing.T) {
+	storage := newTestFSService(t)
+
+	metadata, err := storage.HeadObject(context.Background(), "images/photo.jpg")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if metadata.Size != 10 {
+		t.Errorf("expected size 10, got %d", metadata.Size)
+	}
+	if metadata.ContentType != "image/jpeg" {
+		t.Errorf("expected content type image/jpeg, got %s", metadata.ContentType)
+	}
+	if metadata.LastModified.IsZero() {
+		t.Errorf("expected last modified to be set")
+	}
+	if metadata.ETag == "" {
+		t.Errorf("expected ETag to be set")
+	}
+}
+
+func TestFSService_GetObject(t *testing.T) {
+	storage := newTestFSService(t)
+
+	reader, err := storage.GetObject(context.Background(), "images/photo.jpg")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer reader.Close()
+
+	body, err := io.ReadAll(reader)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(body) != "0123456789" {
+		t.Errorf("expected file contents, got %q", body)
+	}
+	if reader.Size() != 10 {
+		t.Errorf("expected size 10, got %d", reader.Size())
+	}
+
+	rangeReader, err := storage.GetObjectRange(context.Background(), "images/photo.jpg", 2, 5)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer rangeReader.Close()
+
+	body, err = io.ReadAll(rangeReader)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(body) != "2345" {
+		t.Errorf("expected range contents, got %q", body)
+	}
+}
+
+func TestFSService_Errors(t *testing.T) {
+	storage := newTestFSService(t)
+
+	tests := []struct {
+		name      string
+		key       string
+		version   string
+		errorType error
+	}{
+		{name: "missing file", key: "images/missing.jpg", errorType: domain.ErrNotFound},
+		{name: "directory", key: "images", errorType: domain.ErrNotFound},
+		{name: "parent traversal", key: "../secret.txt", errorType: domain.ErrInvalidPath},
+		{name: "nested traversal", key: "images/../../secret.txt", errorType: domain.ErrInvalidPath},
+		{name: "absolute path", key: "/etc/passwd", errorType: domain.ErrInvalidPath},
+		{name: "pinned version", key: "images/photo.jpg", version: "v1", errorType: domain.ErrVersionsNotSupported},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := domain.WithObjectVersion(context.Background(), tt.version)
+			if _, err := storage.HeadObject(ctx, tt.key); !errors.Is(err, tt.errorType) {
+				t.Errorf("HeadObject: expected %v, got %v", tt.errorType, err)
+			}
+			if _, err := storage.GetObject(ctx, tt.key); !errors.Is(err, tt.errorType) {
+				t.Errorf("GetObject: expected %v, got %v", tt.errorType, err)
+			}
+		})
+	}
+}
+
+func TestFSService_DeleteObject(t *testing.T) {
+	storage := newTestFSService(t)
+	ctx := context.Background()
+
+	if err := storage.DeleteObject(ctx, "images/photo.jpg"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := storage.HeadObject(ctx, "images/photo.jpg"); !errors.Is(err, domain.ErrNotFound) {
+		t.Errorf("expected deleted file to be gone, got %v", err)
+	}
+
+	for _, key := range []string{"images/photo.jpg", "images"} {
+		if err := storage.DeleteObject(ctx, key); !errors.Is(err, domain.ErrNotFound) {
+			t.Errorf("DeleteObject(%q): expected %v, got %v", key, domain.ErrNotFound, err)
+		}
+	}
+	if err := storage.DeleteObject(ctx, "../secret.txt"); !errors.Is(err, domain.ErrInvalidPath) {
+		t.Errorf("expected %v, got %v", domain.ErrInvalidPath, err)
+	}
+}
+
+func TestFSService_ListObjects(t *testing.T) {
+	storage := newTestFSService(t)
+	for _, key := range []string{"images/photo-2.jpg", "docs/readme.txt"} {
+		path := filepath.Join(storage.rootDir, filepath.FromSlash(key))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("failed to create dir: %v", err)
+		}
+		if err := os.WriteFile(path, []byte("data"), 0o644); err != nil {
+			t.Fatalf("failed to write file: %v", err)
+		}
+	}
+	ctx := context.Background()
+
+	objects, next, err := storage.ListObjects(ctx, "images/", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if next != "" {
+		t.Errorf("expected a single page, got token %q", next)
+	}
+	if len(objects) != 2 || objects[0].Key != "images/photo-2.jpg" || objects[1].Key != "images/photo.jpg" {
+		t.Fatalf("unexpected objects %+v", objects)
+	}
+	if objects[1].Size != 10 || objects[1].ContentType != "image/jpeg" {
+		t.Errorf("unexpected metadata %+v", objects[1])
+	}
+
+	objects, _, err = storage.ListObjects(ctx, "images/", "images/photo-2.jpg")
+	if err != nil || len(objects) != 1 || objects[0].Key != "images/photo.jpg" {
+		t.Errorf("expected listing to resume after the token, got %+v, %v", objects, err)
+	}
+
+	if _, _, err := storage.ListObjects(ctx, "../", ""); !errors.Is(err, domain.ErrInvalidPath) {
+		t.Errorf("expected %v, got %v", domain.ErrInvalidPath, err)
+	}
+}
@@ -0,0 +1,144 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/storage"
+	"github.com/vchitai/go-s3-sharing/internal/domain"
+)
+
+// fakeGCSBucket is an in-memory implementation of gcsBucket
+type fakeGCSBucket struct {
+	objects map[string][]byte
+	err     error
+}
+
+func (f *fakeGCSBucket) NewRangeReader(ctx context.Context, key string, offset, length int64) (*gcsObjectReader, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	data, exists := f.objects[key]
+	if !exists {
+		return nil, storage.ErrObjectNotExist
+	}
+	if length < 0 {
+		length = int64(len(data)) - offset
+	}
+	data = data[offset : offset+length]
+	return &gcsObjectReader{
+		body:        io.NopCloser(bytes.NewReader(data)),
+		contentType: "image/png",
+		size:        int64(len(data)),
+		etag:        generationETag(7),
+	}, nil
+}
+
+func (f *fakeGCSBucket) Attrs(ctx context.Context, key string) (*storage.ObjectAttrs, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	data, exists := f.objects[key]
+	if !exists {
+		return nil, storage.ErrObjectNotExist
+	}
+	return &storage.ObjectAttrs{
+		ContentType: "image/png",
+		Size:        int64(len(data)),
+		Updated:     time.Unix(1700000000, 0),
+		Generation:  7,
+	}, nil
+}
+
+func (f *fakeGCSBucket) BucketAttrs(ctx context.Context) error {
+	return f.err
+}
+
+func (f *fakeGCSBucket) List(ctx context.Context, prefix, token string, pageSize int) ([]*storage.ObjectAttrs, string, error) {
+	if f.err != nil {
+		return nil, "", f.err
+	}
+	var attrs []*storage.ObjectAttrs
+	for key, data := range f.objects {
+		if strings.HasPrefix(key, prefix) {
+			attrs = append(attrs, &storage.ObjectAttrs{Name: key, Size: int64(len(data)), Generation: 7})
+		}
+	}
+	sort.Slice(attrs, func(i, j int) bool { return attrs[i].Name < attrs[j].Name })
+	return attrs, "", nil
+}
+
+func (f *fakeGCSBucket) Delete(ctx context.Context, key string) error {
+	if f.err != nil {
+		return f.err
+	}
+	if _, exists := f.objects[key]; !exists {
+		return storage.ErrObjectNotExist
+	}
+	delete(f.objects, key)
+	return nil
+}
+
+func TestGCSService_GetAndHeadObject(t *testing.T) {
+	gcs := &GCSService{bucket: &fakeGCSBucket{objects: map[string][]byte{
+		"images/logo.png": []byte("0123456789"),
+	}}}
+	ctx := context.Background()
+
+	reader, err := gcs.GetObject(ctx, "images/logo.png")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer reader.Close()
+
+	if reader.ContentType() != "image/png" || reader.Size() != 10 {
+		t.Errorf("unexpected reader attrs: %s %d", reader.ContentType(), reader.Size())
+	}
+
+	rangeReader, err := gcs.GetObjectRange(ctx, "images/logo.png", 3, 5)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	body, _ := io.ReadAll(rangeReader)
+	if string(body) != "345" {
+		t.Errorf("expected range body 345, got %q", body)
+	}
+
+	metadata, err := gcs.HeadObject(ctx, "images/logo.png")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if metadata.Size != 10 || metadata.ContentType != "image/png" || metadata.LastModified.IsZero() {
+		t.Errorf("unexpected metadata: %+v", metadata)
+	}
+	if metadata.ETag != reader.ETag() {
+		t.Errorf("expected matching ETags, got %s and %s", metadata.ETag, reader.ETag())
+	}
+}
+
+func TestGCSService_Errors(t *testing.T) {
+	ctx := context.Background()
+
+	missing := &GCSService{bucket: &fakeGCSBucket{objects: map[string][]byte{}}}
+	if _, err := missing.GetObject(ctx, "images/missing.png"); !errors.Is(err, domain.ErrNotFound) {
+		t.Errorf("GetObject: expected %v, got %v", domain.ErrNotFound, err)
+	}
+	if _, err := missing.HeadObject(ctx, "images/missing.png"); !errors.Is(err, domain.ErrNotFound) {
+		t.Errorf("HeadObject: expected %v, got %v", domain.ErrNotFound, err)
+	}
+
+	failure := errors.New("backend unavailable")
+	broken := &GCSService{bucket: &fakeGCSBucket{err: failure}}
+	if _, err := broken.GetObject(ctx, "images/logo.png"); !errors.Is(err, failure) || errors.Is(err, domain.ErrNotFound) {
+		t.Errorf("GetObject: expected wrapped backend error, got %v", err)
+	}
+	if _, err := broken.HeadObject(ctx, "images/logo.png"); !errors.Is(err, failure) || errors.Is(err, domain.ErrNotFound) {
+		t.Errorf("HeadObject: expected wrapped backend error, got %v", err)
+	}
+}
@@ -0,0 +1,115 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/vchitai/go-s3-sharing/internal/domain"
+)
+
+// idempotencyPendingTTL bounds how long an idempotency key stays claimed by a
+// creation that never completes, such as one interrupted by a crash
+const idempotencyPendingTTL = time.Minute
+
+// IdempotencyKey is the Idempotency-Key of a share creation
+type IdempotencyKey struct {
+	// Caller identifies the authenticated client sending the key, so that
+	// clients never see each other's responses; empty without authentication
+	Caller string
+	// Key is the key the client sent
+	Key string
+	// RequestHash fingerprints the request sent with the key
+	RequestHash string
+}
+
+// idempotencyRecord is the cached outcome of an idempotency key. Response is
+// nil while the first request holding the key is still creating the share.
+type idempotencyRecord struct {
+	RequestHash string                `json:"request_hash"`
+	Response    *domain.ShareResponse `json:"response,omitempty"`
+}
+
+// CreateShareIdempotent creates a share like CreateShare, except that repeats
+// of key within IdempotencyWindow return the response of the first creation
+// instead of replacing the share with a new secret, reporting replayed. It
+// returns ErrIdempotencyInProgress while the first creation is still running
+// and ErrIdempotencyKeyReused when key was first sent with another request.
+// Failed creations release the key so that they can be retried.
+func (s *ShareService) CreateShareIdempotent(ctx context.Context, key IdempotencyKey, req *domain.ShareRequest) (resp *domain.ShareResponse, replayed bool, err error) {
+	if key.Key == "" || s.config.IdempotencyWindow <= 0 {
+		resp, err = s.CreateShare(ctx, req)
+		return resp, false, err
+	}
+
+	cacheKey := s.generateIdempotencyKey(ctx, key)
+	pending, err := json.Marshal(&idempotencyRecord{RequestHash: key.RequestHash})
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to encode idempotency record: %w", err)
+	}
+
+	claimed, err := s.cache.SetNX(ctx, cacheKey, string(pending), idempotencyPendingTTL)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to claim idempotency key: %w", err)
+	}
+	if !claimed {
+		resp, err = s.replayIdempotent(ctx, cacheKey, key.RequestHash)
+		return resp, err == nil, err
+	}
+
+	resp, err = s.CreateShare(ctx, req)
+	if err != nil {
+		s.cache.Delete(ctx, cacheKey)
+		return nil, false, err
+	}
+
+	completed, err := json.Marshal(&idempotencyRecord{RequestHash: key.RequestHash, Response: resp})
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to encode idempotency record: %w", err)
+	}
+	if err := s.cache.Set(ctx, cacheKey, string(completed), s.config.IdempotencyWindow); err != nil {
+		return nil, false, fmt.Errorf("failed to store idempotency record: %w", err)
+	}
+
+	return resp, false, nil
+}
+
+// replayIdempotent returns the stored response of an idempotency key claimed
+// by an earlier request with the same requestHash
+func (s *ShareService) replayIdempotent(ctx context.Context, cacheKey, requestHash string) (*domain.ShareResponse, error) {
+	value, err := s.cache.Get(ctx, cacheKey)
+	if err != nil {
+		if err == domain.ErrNotFound {
+			// The earlier request failed or its record expired in between
+			return nil, domain.ErrIdempotencyInProgress
+		}
+		return nil, fmt.Errorf("failed to get idempotency record: %w", err)
+	}
+
+	var record idempotencyRecord
+	if err := json.Unmarshal([]byte(value), &record); err != nil {
+		return nil, fmt.Errorf("failed to decode idempotency record: %w", err)
+	}
+
+	if record.RequestHash != requestHash {
+		return nil, domain.ErrIdempotencyKeyReused
+	}
+	if record.Response == nil {
+		return nil, domain.ErrIdempotencyInProgress
+	}
+
+	return record.Response, nil
+}
+
+// generateIdempotencyKey creates the cache key of a client idempotency key,
+// below a hash of its caller for authenticated callers
+func (s *ShareService) generateIdempotencyKey(ctx context.Context, key IdempotencyKey) string {
+	if key.Caller == "" {
+		return fmt.Sprintf("%s%s:%s", s.tenantKeyPrefix(ctx), s.keyName("idempotency"), key.Key)
+	}
+	caller := sha256.Sum256([]byte(key.Caller))
+	return fmt.Sprintf("%s%s:%s:%s", s.tenantKeyPrefix(ctx), s.keyName("idempotency"), hex.EncodeToString(caller[:8]), key.Key)
+}
@@ -0,0 +1,117 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/vchitai/go-s3-sharing/internal/domain"
+)
+
+func TestShareService_CreateShareIdempotent(t *testing.T) {
+	storage := &mockStorageService{objects: map[string]*domain.ObjectMetadata{
+		"images/photo.jpg": {ContentType: "image/jpeg", Size: 1024},
+		"images/other.jpg": {ContentType: "image/jpeg", Size: 1024},
+	}}
+	cache := &mockCacheService{store: make(map[string]string), ttls: make(map[string]time.Duration)}
+
+	service := NewShareService(storage, cache, &ShareConfig{
+		MaxAgeDays:        90,
+		BaseURL:           "https://example.com",
+		IdempotencyWindow: time.Hour,
+	})
+	ctx := context.Background()
+
+	newRequest := func(s3Path, secret string) *domain.ShareRequest {
+		return &domain.ShareRequest{S3Path: s3Path, Secret: secret, ExpiresAt: time.Now().Add(24 * time.Hour)}
+	}
+
+	key := IdempotencyKey{Key: "key-1", RequestHash: "request-1"}
+	first, replayed, err := service.CreateShareIdempotent(ctx, key, newRequest("images/photo.jpg", "first-secret"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if replayed {
+		t.Errorf("expected the first request not to be replayed")
+	}
+	if ttl := cache.ttls["image-idempotency:key-1"]; ttl != time.Hour {
+		t.Errorf("expected the response to be kept for the window, got %v", ttl)
+	}
+
+	t.Run("repeated key returns the cached response", func(t *testing.T) {
+		resp, replayed, err := service.CreateShareIdempotent(ctx, key, newRequest("images/photo.jpg", "second-secret"))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !replayed {
+			t.Errorf("expected the response to be replayed")
+		}
+		if resp.URL != first.URL || !resp.ExpiresAt.Equal(first.ExpiresAt) || resp.MaxAge != first.MaxAge {
+			t.Errorf("expected %+v, got %+v", first, resp)
+		}
+		// The share keeps the secret of the first request
+		if err := service.ValidateShare(ctx, "images/photo.jpg", "first-secret", time.Now()); err != nil {
+			t.Errorf("expected the original share to stay valid, got %v", err)
+		}
+	})
+
+	t.Run("different key creates a new share", func(t *testing.T) {
+		resp, replayed, err := service.CreateShareIdempotent(ctx, IdempotencyKey{Key: "key-2", RequestHash: "request-1"}, newRequest("images/photo.jpg", "third-secret"))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if replayed || resp.URL == first.URL {
+			t.Errorf("expected a new share, got %+v", resp)
+		}
+		if err := service.ValidateShare(ctx, "images/photo.jpg", "third-secret", time.Now()); err != nil {
+			t.Errorf("expected the new share to be valid, got %v", err)
+		}
+	})
+
+	t.Run("key reused for another request", func(t *testing.T) {
+		_, _, err := service.CreateShareIdempotent(ctx, IdempotencyKey{Key: "key-1", RequestHash: "request-2"}, newRequest("images/other.jpg", "other-secret"))
+		if !errors.Is(err, domain.ErrIdempotencyKeyReused) {
+			t.Errorf("expected %v, got %v", domain.ErrIdempotencyKeyReused, err)
+		}
+	})
+
+	t.Run("keys are scoped to their caller", func(t *testing.T) {
+		other := IdempotencyKey{Caller: "api-key:other", Key: "key-1", RequestHash: "request-2"}
+		resp, replayed, err := service.CreateShareIdempotent(ctx, other, newRequest("images/other.jpg", "other-secret"))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if replayed || resp.URL == first.URL {
+			t.Errorf("expected a new share for another caller, got %+v", resp)
+		}
+		if _, exists := cache.store[service.generateIdempotencyKey(ctx, other)]; !exists || service.generateIdempotencyKey(ctx, other) == "image-idempotency:key-1" {
+			t.Errorf("expected a key of the caller, got %v", cache.store)
+		}
+	})
+
+	t.Run("request in progress", func(t *testing.T) {
+		cache.store["image-idempotency:key-3"] = `{"request_hash":"request-1"}`
+		_, _, err := service.CreateShareIdempotent(ctx, IdempotencyKey{Key: "key-3", RequestHash: "request-1"}, newRequest("images/photo.jpg", "secret"))
+		if !errors.Is(err, domain.ErrIdempotencyInProgress) {
+			t.Errorf("expected %v, got %v", domain.ErrIdempotencyInProgress, err)
+		}
+	})
+
+	t.Run("failed creation releases the key", func(t *testing.T) {
+		_, _, err := service.CreateShareIdempotent(ctx, IdempotencyKey{Key: "key-4"}, newRequest("images/missing.jpg", "secret"))
+		if err == nil {
+			t.Fatal("expected an error for a missing object")
+		}
+		if _, exists := cache.store["image-idempotency:key-4"]; exists {
+			t.Errorf("expected the key to be released")
+		}
+	})
+
+	t.Run("no key", func(t *testing.T) {
+		_, replayed, err := service.CreateShareIdempotent(ctx, IdempotencyKey{}, newRequest("images/photo.jpg", "secret"))
+		if err != nil || replayed {
+			t.Errorf("expected a plain creation, got %v, %v", replayed, err)
+		}
+	})
+}
@@ -0,0 +1,32 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/vchitai/go-s3-sharing/internal/domain"
+)
+
+// ValidateClientIP checks the client IP of a download of the share of s3Path
+// against the share's allowed IPs. It returns ErrIPNotAllowed when the share
+// has allowed IPs and ip is in none of them.
+func (s *ShareService) ValidateClientIP(ctx context.Context, s3Path, ip string) error {
+	record, err := s.getShareRecord(ctx, s3Path)
+	if err != nil {
+		if err == domain.ErrNotFound {
+			return domain.ErrUnauthorized
+		}
+		if s.cacheFallback(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to validate client IP: %w", err)
+	}
+
+	if len(record.AllowedIPs) == 0 {
+		return nil
+	}
+	if !domain.MatchIP(ip, record.AllowedIPs) {
+		return domain.ErrIPNotAllowed
+	}
+	return nil
+}
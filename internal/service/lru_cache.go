@@ -0,0 +1,201 @@
+package service
+
+import (
+	"container/list"
+	"context"
+	"sync"
+
+	"github.com/vchitai/go-s3-sharing/internal/domain"
+)
+
+// lruEntryFraction bounds cached objects to this fraction of the byte budget,
+// so that a single object can never flush the whole cache
+const lruEntryFraction = 8
+
+// lruKey identifies a cached object; tenants may map keys to different
+// buckets, and pinned versions are cached apart from the latest version
+type lruKey struct {
+	tenant  string
+	key     string
+	version string
+}
+
+// lruEntry is a cached object body with the metadata it was served with
+type lruEntry struct {
+	key    lruKey
+	header cachedObjectHeader
+	data   []byte
+}
+
+// LRUCache is an in-process cache of small object bodies evicting the least
+// recently used entries once its byte budget is exceeded. It is safe for
+// concurrent use.
+type LRUCache struct {
+	maxBytes int64
+
+	mu      sync.Mutex
+	size    int64
+	order   *list.List
+	entries map[lruKey]*list.Element
+}
+
+// NewLRUCache creates a cache holding at most maxBytes of object bodies.
+// Objects larger than an eighth of the budget are never cached.
+func NewLRUCache(maxBytes int64) *LRUCache {
+	return &LRUCache{
+		maxBytes: maxBytes,
+		order:    list.New(),
+		entries:  make(map[lruKey]*list.Element),
+	}
+}
+
+// get returns the cached body of key when it was cached with etag. An entry
+// with another ETag is stale and is evicted.
+func (c *LRUCache) get(key lruKey, etag string) (*lruEntry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	entry := element.Value.(*lruEntry)
+	if entry.header.ETag != etag {
+		c.removeElement(element)
+		return nil, false
+	}
+	c.order.MoveToFront(element)
+	return entry, true
+}
+
+// add caches entry, replacing any entry of the same key and evicting the
+// least recently used entries until the cache fits its budget
+func (c *LRUCache) add(entry *lruEntry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if element, ok := c.entries[entry.key]; ok {
+		c.removeElement(element)
+	}
+	c.entries[entry.key] = c.order.PushFront(entry)
+	c.size += int64(len(entry.data))
+
+	for c.size > c.maxBytes {
+		c.removeElement(c.order.Back())
+	}
+}
+
+// contains reports whether an entry of key is cached, whatever its ETag
+func (c *LRUCache) contains(key lruKey) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	_, ok := c.entries[key]
+	return ok
+}
+
+// remove evicts the entry of key, if any
+func (c *LRUCache) remove(key lruKey) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if element, ok := c.entries[key]; ok {
+		c.removeElement(element)
+	}
+}
+
+// removeElement evicts element; c.mu must be held
+func (c *LRUCache) removeElement(element *list.Element) {
+	entry := c.order.Remove(element).(*lruEntry)
+	delete(c.entries, entry.key)
+	c.size -= int64(len(entry.data))
+}
+
+// Len returns the number of cached objects
+func (c *LRUCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
+
+// Size returns the total size in bytes of the cached objects
+func (c *LRUCache) Size() int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.size
+}
+
+// lruStorage serves small objects from an LRUCache in front of a StorageService
+type lruStorage struct {
+	domain.StorageService
+	cache *LRUCache
+}
+
+// lruPresigningStorage is an lruStorage over a backend that also presigns URLs
+type lruPresigningStorage struct {
+	*lruStorage
+	domain.Presigner
+}
+
+// Wrap returns storage with GetObject served from the cache. Reads of cached
+// objects check the object's current ETag with HeadObject so that stale
+// bodies are never served; objects without an ETag are not cached. The wrapper
+// implements domain.Presigner exactly when storage does.
+func (c *LRUCache) Wrap(storage domain.StorageService) domain.StorageService {
+	cached := &lruStorage{StorageService: storage, cache: c}
+	if presigner, ok := storage.(domain.Presigner); ok {
+		return &lruPresigningStorage{lruStorage: cached, Presigner: presigner}
+	}
+	return cached
+}
+
+// GetObject serves key from the cache when its ETag is unchanged and caches
+// small objects fetched from storage
+func (s *lruStorage) GetObject(ctx context.Context, key string) (domain.ObjectReader, error) {
+	cacheKey := lruKey{
+		tenant:  domain.TenantFromContext(ctx),
+		key:     key,
+		version: domain.ObjectVersionFromContext(ctx),
+	}
+	// Objects not cached are fetched without checking their ETag first
+	if s.cache.contains(cacheKey) {
+		metadata, err := s.StorageService.HeadObject(ctx, key)
+		if err != nil {
+			return nil, err
+		}
+		if entry, ok := s.cache.get(cacheKey, metadata.ETag); ok {
+			return newCachedObjectReader(entry.header, entry.data), nil
+		}
+	}
+
+	reader, err := s.StorageService.GetObject(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+
+	limit := s.cache.maxBytes / lruEntryFraction
+	if reader.ETag() == "" || reader.Size() < 0 || reader.Size() > limit {
+		return reader, nil
+	}
+
+	header := cachedObjectHeader{
+		ContentType:  reader.ContentType(),
+		ETag:         reader.ETag(),
+		LastModified: reader.LastModified(),
+		Checksum:     reader.Checksum(),
+	}
+
+	// Buffer the small object
+	data, oversized, err := bufferObject(reader, limit)
+	if err != nil || oversized != nil {
+		return oversized, err
+	}
+	s.cache.add(&lruEntry{key: cacheKey, header: header, data: data})
+	return newCachedObjectReader(header, data), nil
+}
+
+// DeleteObject deletes key from storage and evicts it from the cache
+func (s *lruStorage) DeleteObject(ctx context.Context, key string) error {
+	s.cache.remove(lruKey{tenant: domain.TenantFromContext(ctx), key: key})
+	return s.StorageService.DeleteObject(ctx, key)
+}
@@ -0,0 +1,180 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+// newLRUTestStorage writes objects into a temporary FSService root
+func newLRUTestStorage(t *testing.T, objects map[string]string) (*countingStorage, string) {
+	t.Helper()
+
+	root := t.TempDir()
+	for name, body := range objects {
+		writeLRUTestObject(t, root, name, body)
+	}
+	return &countingStorage{FSService: NewFSService(root)}, root
+}
+
+// writeLRUTestObject writes body as the object name under root
+func writeLRUTestObject(t *testing.T, root, name, body string) {
+	t.Helper()
+
+	path := filepath.Join(root, name)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatalf("failed to create directory: %v", err)
+	}
+	if err := os.WriteFile(path, []byte(body), 0o600); err != nil {
+		t.Fatalf("failed to write object: %v", err)
+	}
+}
+
+func TestLRUCache_Eviction(t *testing.T) {
+	objects := map[string]string{}
+	for i := range 9 {
+		objects[fmt.Sprintf("images/%d.png", i)] = fmt.Sprintf("object-%d", i)
+	}
+	backend, _ := newLRUTestStorage(t, objects)
+	// Eight 8-byte objects fit the budget
+	cache := NewLRUCache(64)
+	storage := cache.Wrap(backend)
+	ctx := context.Background()
+
+	read := func(key string) {
+		t.Helper()
+		reader, err := storage.GetObject(ctx, key)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		defer reader.Close()
+		data, err := io.ReadAll(reader)
+		if err != nil || string(data) != objects[key] {
+			t.Fatalf("expected %q, got %q (%v)", objects[key], data, err)
+		}
+	}
+
+	for i := range 8 {
+		read(fmt.Sprintf("images/%d.png", i))
+	}
+	if cache.Len() != 8 || cache.Size() != 64 {
+		t.Fatalf("expected 8 objects in 64 bytes, got %d in %d", cache.Len(), cache.Size())
+	}
+
+	// Touch the oldest object so that the next oldest is evicted instead
+	read("images/0.png")
+	read("images/8.png")
+	if cache.Len() != 8 || cache.Size() > 64 {
+		t.Errorf("expected the cache to stay within budget, got %d objects in %d bytes", cache.Len(), cache.Size())
+	}
+
+	before := backend.gets
+	read("images/0.png")
+	if backend.gets != before {
+		t.Error("expected the recently used object to be served from the cache")
+	}
+	read("images/1.png")
+	if backend.gets != before+1 {
+		t.Error("expected the least recently used object to have been evicted")
+	}
+}
+
+func TestLRUCache_ETagInvalidation(t *testing.T) {
+	backend, root := newLRUTestStorage(t, map[string]string{"images/photo.png": "v1"})
+	storage := NewLRUCache(1024).Wrap(backend)
+	ctx := context.Background()
+
+	read := func(expected string) {
+		t.Helper()
+		reader, err := storage.GetObject(ctx, "images/photo.png")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		defer reader.Close()
+		data, err := io.ReadAll(reader)
+		if err != nil || string(data) != expected {
+			t.Fatalf("expected %q, got %q (%v)", expected, data, err)
+		}
+	}
+
+	read("v1")
+	if backend.heads != 0 {
+		t.Errorf("expected an uncached object to be fetched without a HEAD, got %d", backend.heads)
+	}
+	read("v1")
+	if backend.gets != 1 || backend.heads != 1 {
+		t.Fatalf("expected one storage fetch and one ETag check, got %d and %d", backend.gets, backend.heads)
+	}
+
+	// Overwriting the object changes its ETag
+	writeLRUTestObject(t, root, "images/photo.png", "version-2")
+	read("version-2")
+	if backend.gets != 2 {
+		t.Errorf("expected the changed object to be refetched, got %d fetches", backend.gets)
+	}
+}
+
+func TestLRUCache_Concurrent(t *testing.T) {
+	objects := map[string]string{}
+	for i := range 16 {
+		objects[fmt.Sprintf("images/%d.png", i)] = fmt.Sprintf("object-%02d", i)
+	}
+	backend, _ := newLRUTestStorage(t, objects)
+	cache := NewLRUCache(8 * 9)
+	// The counting wrapper is not safe for concurrent use
+	storage := cache.Wrap(backend.FSService)
+
+	var wg sync.WaitGroup
+	for g := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range 64 {
+				key := fmt.Sprintf("images/%d.png", (g+i)%16)
+				reader, err := storage.GetObject(context.Background(), key)
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+					return
+				}
+				data, _ := io.ReadAll(reader)
+				reader.Close()
+				if string(data) != objects[key] {
+					t.Errorf("expected %q, got %q", objects[key], data)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if cache.Size() > 8*9 {
+		t.Errorf("expected the cache to stay within budget, got %d bytes", cache.Size())
+	}
+}
+
+func TestLRUCache_ObjectLargerThanReported(t *testing.T) {
+	const body = "a body much longer than reported"
+	backend, _ := newLRUTestStorage(t, map[string]string{"images/photo.png": body})
+	backend.understate = true
+	cache := NewLRUCache(64)
+	storage := cache.Wrap(backend)
+
+	reader, err := storage.GetObject(context.Background(), "images/photo.png")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer reader.Close()
+	data, err := io.ReadAll(reader)
+	if err != nil || string(data) != body {
+		t.Errorf("expected the whole object, got %q (%v)", data, err)
+	}
+	if reader.Size() != -1 {
+		t.Errorf("expected an unknown size, got %d", reader.Size())
+	}
+	if cache.Len() != 0 {
+		t.Errorf("expected the object not to be cached, got %d objects", cache.Len())
+	}
+}
@@ -0,0 +1,226 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vchitai/go-s3-sharing/internal/domain"
+)
+
+// memorySweepInterval is how often expired entries are purged from memory
+const memorySweepInterval = time.Minute
+
+// memoryEntry is a cached value with an optional expiry
+type memoryEntry struct {
+	value     string
+	expiresAt time.Time
+}
+
+// expired reports whether the entry has passed its expiry
+func (e memoryEntry) expired(now time.Time) bool {
+	return !e.expiresAt.IsZero() && !now.Before(e.expiresAt)
+}
+
+// MemoryCacheService implements CacheService with an in-process map.
+// It is intended for single-node deployments and tests.
+type MemoryCacheService struct {
+	mu      sync.RWMutex
+	entries map[string]memoryEntry
+	stop    chan struct{}
+	once    sync.Once
+}
+
+// NewMemoryCacheService creates a new in-memory cache service and starts its
+// background sweeper. Call Close to stop the sweeper.
+func NewMemoryCacheService() *MemoryCacheService {
+	m := &MemoryCacheService{
+		entries: make(map[string]memoryEntry),
+		stop:    make(chan struct{}),
+	}
+	go m.sweep(memorySweepInterval)
+	return m
+}
+
+// Set stores a key-value pair with expiration; zero expiration never expires
+func (m *MemoryCacheService) Set(ctx context.Context, key, value string, expiration time.Duration) error {
+	entry := memoryEntry{value: value}
+	if expiration > 0 {
+		entry.expiresAt = time.Now().Add(expiration)
+	}
+
+	m.mu.Lock()
+	m.entries[key] = entry
+	m.mu.Unlock()
+	return nil
+}
+
+// SetNX stores a key-value pair unless a live entry exists for the key
+func (m *MemoryCacheService) SetNX(ctx context.Context, key, value string, expiration time.Duration) (bool, error) {
+	now := time.Now()
+	entry := memoryEntry{value: value}
+	if expiration > 0 {
+		entry.expiresAt = now.Add(expiration)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if existing, exists := m.entries[key]; exists && !existing.expired(now) {
+		return false, nil
+	}
+	m.entries[key] = entry
+	return true, nil
+}
+
+// Get retrieves a value by key, returning ErrNotFound for missing or expired keys
+func (m *MemoryCacheService) Get(ctx context.Context, key string) (string, error) {
+	m.mu.RLock()
+	entry, exists := m.entries[key]
+	m.mu.RUnlock()
+
+	if !exists || entry.expired(time.Now()) {
+		return "", domain.ErrNotFound
+	}
+	return entry.value, nil
+}
+
+// Delete removes a key
+func (m *MemoryCacheService) Delete(ctx context.Context, key string) error {
+	m.mu.Lock()
+	delete(m.entries, key)
+	m.mu.Unlock()
+	return nil
+}
+
+// Incr atomically increments a counter and refreshes its expiration
+func (m *MemoryCacheService) Incr(ctx context.Context, key string, expiration time.Duration) (int64, error) {
+	return m.IncrBy(ctx, key, 1, expiration)
+}
+
+// IncrBy atomically adds delta to a counter and refreshes its expiration
+func (m *MemoryCacheService) IncrBy(ctx context.Context, key string, delta int64, expiration time.Duration) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	entry, exists := m.entries[key]
+	var count int64
+	if exists && !entry.expired(time.Now()) {
+		parsed, err := strconv.ParseInt(entry.value, 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("value for key %s is not an integer", key)
+		}
+		count = parsed
+	} else {
+		entry = memoryEntry{}
+	}
+
+	count += delta
+	entry.value = strconv.FormatInt(count, 10)
+	if expiration > 0 {
+		entry.expiresAt = time.Now().Add(expiration)
+	}
+	m.entries[key] = entry
+
+	return count, nil
+}
+
+// TTL returns the remaining lifetime of a key
+func (m *MemoryCacheService) TTL(ctx context.Context, key string) (time.Duration, error) {
+	m.mu.RLock()
+	entry, exists := m.entries[key]
+	m.mu.RUnlock()
+
+	now := time.Now()
+	if !exists || entry.expired(now) {
+		return 0, domain.ErrNotFound
+	}
+	if entry.expiresAt.IsZero() {
+		return -1, nil
+	}
+	return entry.expiresAt.Sub(now), nil
+}
+
+// Expire replaces the expiration of a live key
+func (m *MemoryCacheService) Expire(ctx context.Context, key string, expiration time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	entry, exists := m.entries[key]
+	if !exists || entry.expired(time.Now()) {
+		return nil
+	}
+	entry.expiresAt = time.Now().Add(expiration)
+	m.entries[key] = entry
+	return nil
+}
+
+// memoryScanCount is the number of keys returned per Scan batch
+const memoryScanCount = 100
+
+// Scan returns the live keys starting with prefix in key order, a batch at a
+// time; the cursor is the number of matching keys already returned
+func (m *MemoryCacheService) Scan(ctx context.Context, prefix string, cursor uint64) ([]string, uint64, error) {
+	now := time.Now()
+	m.mu.RLock()
+	var keys []string
+	for key, entry := range m.entries {
+		if strings.HasPrefix(key, prefix) && !entry.expired(now) {
+			keys = append(keys, key)
+		}
+	}
+	m.mu.RUnlock()
+	sort.Strings(keys)
+
+	if cursor >= uint64(len(keys)) {
+		return nil, 0, nil
+	}
+	end := min(cursor+memoryScanCount, uint64(len(keys)))
+	next := end
+	if end == uint64(len(keys)) {
+		next = 0
+	}
+	return keys[cursor:end], next, nil
+}
+
+// Ping always succeeds for the in-memory cache
+func (m *MemoryCacheService) Ping(ctx context.Context) error {
+	return nil
+}
+
+// Close stops the background sweeper
+func (m *MemoryCacheService) Close() error {
+	m.once.Do(func() { close(m.stop) })
+	return nil
+}
+
+// sweep periodically removes expired entries until Close is called
+func (m *MemoryCacheService) sweep(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-m.stop:
+			return
+		case now := <-ticker.C:
+			m.purgeExpired(now)
+		}
+	}
+}
+
+// purgeExpired removes all entries that have expired as of now
+func (m *MemoryCacheService) purgeExpired(now time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for key, entry := range m.entries {
+		if entry.expired(now) {
+			delete(m.entries, key)
+		}
+	}
+}
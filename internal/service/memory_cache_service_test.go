@@ -0,0 +1,235 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/vchitai/go-s3-sharing/internal/domain"
+)
+
+func TestMemoryCacheService_SetGetDelete(t *testing.T) {
+	cache := NewMemoryCacheService()
+	defer cache.Close()
+	ctx := context.Background()
+
+	if err := cache.Set(ctx, "key", "value", time.Hour); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	value, err := cache.Get(ctx, "key")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if value != "value" {
+		t.Errorf("expected value, got %s", value)
+	}
+
+	if err := cache.Delete(ctx, "key"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if _, err := cache.Get(ctx, "key"); !errors.Is(err, domain.ErrNotFound) {
+		t.Errorf("expected %v after delete, got %v", domain.ErrNotFound, err)
+	}
+}
+
+func TestMemoryCacheService_Expiry(t *testing.T) {
+	cache := NewMemoryCacheService()
+	defer cache.Close()
+	ctx := context.Background()
+
+	if err := cache.Set(ctx, "short", "value", 20*time.Millisecond); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := cache.Set(ctx, "forever", "value", 0); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if _, err := cache.Get(ctx, "short"); err != nil {
+		t.Fatalf("expected key before expiry, got %v", err)
+	}
+
+	time.Sleep(40 * time.Millisecond)
+
+	if _, err := cache.Get(ctx, "short"); !errors.Is(err, domain.ErrNotFound) {
+		t.Errorf("expected %v after expiry, got %v", domain.ErrNotFound, err)
+	}
+	if _, err := cache.Get(ctx, "forever"); err != nil {
+		t.Errorf("expected key without expiration to remain, got %v", err)
+	}
+
+	// The sweeper removes expired entries from memory
+	cache.purgeExpired(time.Now())
+	cache.mu.RLock()
+	_, exists := cache.entries["short"]
+	cache.mu.RUnlock()
+	if exists {
+		t.Errorf("expected expired entry to be purged")
+	}
+}
+
+func TestMemoryCacheService_Concurrency(t *testing.T) {
+	cache := NewMemoryCacheService()
+	defer cache.Close()
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			key := fmt.Sprintf("key-%d", i%10)
+			for j := 0; j < 100; j++ {
+				_ = cache.Set(ctx, key, fmt.Sprintf("value-%d", j), time.Minute)
+				_, _ = cache.Get(ctx, key)
+				if j%10 == 0 {
+					_ = cache.Delete(ctx, key)
+				}
+			}
+			cache.purgeExpired(time.Now())
+		}(i)
+	}
+	wg.Wait()
+}
+
+func TestMemoryCacheService_TTL(t *testing.T) {
+	cache := NewMemoryCacheService()
+	defer cache.Close()
+	ctx := context.Background()
+
+	_ = cache.Set(ctx, "expiring", "value", time.Hour)
+	_ = cache.Set(ctx, "forever", "value", 0)
+
+	if ttl, err := cache.TTL(ctx, "expiring"); err != nil || ttl <= 59*time.Minute || ttl > time.Hour {
+		t.Errorf("expected TTL close to 1h, got %v (%v)", ttl, err)
+	}
+	if ttl, err := cache.TTL(ctx, "forever"); err != nil || ttl >= 0 {
+		t.Errorf("expected negative TTL for key without expiration, got %v (%v)", ttl, err)
+	}
+	if _, err := cache.TTL(ctx, "missing"); !errors.Is(err, domain.ErrNotFound) {
+		t.Errorf("expected %v for missing key, got %v", domain.ErrNotFound, err)
+	}
+}
+
+func TestMemoryCacheService_Expire(t *testing.T) {
+	cache := NewMemoryCacheService()
+	defer cache.Close()
+	ctx := context.Background()
+
+	_ = cache.Set(ctx, "key", "value", time.Minute)
+
+	if err := cache.Expire(ctx, "key", 2*time.Hour); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if ttl, err := cache.TTL(ctx, "key"); err != nil || ttl <= time.Hour {
+		t.Errorf("expected TTL close to 2h, got %v (%v)", ttl, err)
+	}
+	if value, err := cache.Get(ctx, "key"); err != nil || value != "value" {
+		t.Errorf("expected the value to be kept, got %q (%v)", value, err)
+	}
+
+	if err := cache.Expire(ctx, "missing", time.Hour); err != nil {
+		t.Errorf("expected no error for missing key, got %v", err)
+	}
+	if _, err := cache.Get(ctx, "missing"); !errors.Is(err, domain.ErrNotFound) {
+		t.Errorf("expected Expire not to create missing keys, got %v", err)
+	}
+}
+
+func TestMemoryCacheService_Incr(t *testing.T) {
+	cache := NewMemoryCacheService()
+	defer cache.Close()
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, _ = cache.Incr(ctx, "counter", time.Minute)
+		}()
+	}
+	wg.Wait()
+
+	count, err := cache.Incr(ctx, "counter", time.Minute)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if count != 101 {
+		t.Errorf("expected count 101, got %d", count)
+	}
+	if ttl, _ := cache.TTL(ctx, "counter"); ttl <= 0 {
+		t.Errorf("expected counter to have an expiration, got %v", ttl)
+	}
+}
+
+func TestMemoryCacheService_SetNX(t *testing.T) {
+	cache := NewMemoryCacheService()
+	defer cache.Close()
+	ctx := context.Background()
+
+	stored, err := cache.SetNX(ctx, "key", "first", 50*time.Millisecond)
+	if err != nil || !stored {
+		t.Fatalf("expected first SetNX to store, got %v, %v", stored, err)
+	}
+
+	stored, err = cache.SetNX(ctx, "key", "second", time.Hour)
+	if err != nil || stored {
+		t.Fatalf("expected SetNX on a live key to be refused, got %v, %v", stored, err)
+	}
+	if value, _ := cache.Get(ctx, "key"); value != "first" {
+		t.Errorf("expected first, got %s", value)
+	}
+
+	// An expired key may be claimed again
+	time.Sleep(60 * time.Millisecond)
+	if stored, err := cache.SetNX(ctx, "key", "third", time.Hour); err != nil || !stored {
+		t.Errorf("expected SetNX on an expired key to store, got %v, %v", stored, err)
+	}
+}
+
+func TestMemoryCacheService_Scan(t *testing.T) {
+	cache := NewMemoryCacheService()
+	defer cache.Close()
+	ctx := context.Background()
+
+	total := memoryScanCount + 5
+	for i := 0; i < total; i++ {
+		cache.Set(ctx, fmt.Sprintf("image-auth:%04d", i), "secret", time.Hour)
+	}
+	cache.Set(ctx, "image-auth:expired", "secret", time.Millisecond)
+	cache.Set(ctx, "image-downloads:0000", "1", time.Hour)
+	time.Sleep(5 * time.Millisecond)
+
+	var keys []string
+	var cursor uint64
+	batches := 0
+	for {
+		batch, next, err := cache.Scan(ctx, "image-auth:", cursor)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		keys = append(keys, batch...)
+		batches++
+		if next == 0 {
+			break
+		}
+		cursor = next
+	}
+
+	if len(keys) != total {
+		t.Errorf("expected %d keys, got %d", total, len(keys))
+	}
+	if batches != 2 {
+		t.Errorf("expected 2 batches, got %d", batches)
+	}
+	for _, key := range keys {
+		if key == "image-auth:expired" {
+			t.Errorf("expected expired key to be skipped")
+		}
+	}
+}
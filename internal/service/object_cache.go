@@ -0,0 +1,170 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/vchitai/go-s3-sharing/internal/domain"
+)
+
+// cachedObjectHeader is the metadata stored ahead of a cached object's bytes
+type cachedObjectHeader struct {
+	ContentType  string    `json:"content_type"`
+	ETag         string    `json:"etag,omitempty"`
+	LastModified time.Time `json:"last_modified"`
+	Checksum     string    `json:"checksum,omitempty"`
+}
+
+// cachedObjectReader is an ObjectReader over an object held in memory
+type cachedObjectReader struct {
+	*bytes.Reader
+	header cachedObjectHeader
+	size   int64
+}
+
+func (r *cachedObjectReader) Close() error {
+	return nil
+}
+
+func (r *cachedObjectReader) ContentType() string {
+	return r.header.ContentType
+}
+
+func (r *cachedObjectReader) Size() int64 {
+	return r.size
+}
+
+func (r *cachedObjectReader) ETag() string {
+	return r.header.ETag
+}
+
+func (r *cachedObjectReader) LastModified() time.Time {
+	return r.header.LastModified
+}
+
+func (r *cachedObjectReader) Checksum() string {
+	return r.header.Checksum
+}
+
+// newCachedObjectReader returns a reader over data described by header
+func newCachedObjectReader(header cachedObjectHeader, data []byte) *cachedObjectReader {
+	return &cachedObjectReader{Reader: bytes.NewReader(data), header: header, size: int64(len(data))}
+}
+
+// oversizedObjectReader streams an object that turned out larger than its
+// reported size: the bytes already read from it, then the rest
+type oversizedObjectReader struct {
+	domain.ObjectReader
+	body io.Reader
+}
+
+func (r *oversizedObjectReader) Read(p []byte) (int, error) {
+	return r.body.Read(p)
+}
+
+// Size reports the size as unknown, as the reported one is wrong
+func (r *oversizedObjectReader) Size() int64 {
+	return -1
+}
+
+// bufferObject reads reader, whose reported size is at most limit, into
+// memory and closes it. Objects holding more than limit bytes are not
+// buffered; they are returned as a reader streaming the whole object, with
+// nil data.
+func bufferObject(reader domain.ObjectReader, limit int64) ([]byte, domain.ObjectReader, error) {
+	// Read one byte past the limit so that an object larger than reported
+	// is never buffered
+	data, err := io.ReadAll(io.LimitReader(reader, limit+1))
+	if err != nil {
+		reader.Close()
+		return nil, nil, fmt.Errorf("failed to read object: %w", err)
+	}
+	if int64(len(data)) > limit {
+		return nil, &oversizedObjectReader{ObjectReader: reader, body: io.MultiReader(bytes.NewReader(data), reader)}, nil
+	}
+	reader.Close()
+	return data, nil, nil
+}
+
+// encodeCachedObject encodes an object as its JSON header, a newline and its
+// raw bytes. JSON escapes newlines, so the first one ends the header.
+func encodeCachedObject(header cachedObjectHeader, data []byte) (string, error) {
+	encoded, err := json.Marshal(header)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode cached object: %w", err)
+	}
+	return string(encoded) + "\n" + string(data), nil
+}
+
+// decodeCachedObject decodes an object written by encodeCachedObject
+func decodeCachedObject(value string) (*cachedObjectReader, error) {
+	encoded, data, ok := strings.Cut(value, "\n")
+	if !ok {
+		return nil, fmt.Errorf("failed to decode cached object: missing header")
+	}
+	var header cachedObjectHeader
+	if err := json.Unmarshal([]byte(encoded), &header); err != nil {
+		return nil, fmt.Errorf("failed to decode cached object: %w", err)
+	}
+	return newCachedObjectReader(header, []byte(data)), nil
+}
+
+// objectCacheEnabled reports whether small objects are cached
+func (s *ShareService) objectCacheEnabled() bool {
+	return s.config.ObjectCacheMaxBytes > 0 && s.config.ObjectCacheTTL > 0
+}
+
+// getCachedObject serves s3Path from the object cache, falling back to
+// storage on a miss and caching objects of at most ObjectCacheMaxBytes.
+// Cache failures never fail the request; the object is fetched from storage.
+func (s *ShareService) getCachedObject(ctx context.Context, s3Path string) (domain.ObjectReader, error) {
+	key := s.generateObjectKey(ctx, s3Path)
+
+	if value, err := s.cache.Get(ctx, key); err == nil {
+		if reader, err := decodeCachedObject(value); err == nil {
+			s.observeObjectCache(true)
+			return reader, nil
+		}
+	}
+	s.observeObjectCache(false)
+
+	reader, err := s.storage.GetObject(ctx, s3Path)
+	if err != nil {
+		return nil, err
+	}
+
+	limit := int64(s.config.ObjectCacheMaxBytes)
+	if reader.Size() < 0 || reader.Size() > limit {
+		return reader, nil
+	}
+
+	header := cachedObjectHeader{
+		ContentType:  reader.ContentType(),
+		ETag:         reader.ETag(),
+		LastModified: reader.LastModified(),
+		Checksum:     reader.Checksum(),
+	}
+
+	// Buffer the small object
+	data, oversized, err := bufferObject(reader, limit)
+	if err != nil || oversized != nil {
+		return oversized, err
+	}
+	// A failed write only costs a later miss
+	if value, err := encodeCachedObject(header, data); err == nil {
+		_ = s.cache.Set(ctx, key, value, s.config.ObjectCacheTTL)
+	}
+	return newCachedObjectReader(header, data), nil
+}
+
+// observeObjectCache reports an object cache lookup when observed
+func (s *ShareService) observeObjectCache(hit bool) {
+	if s.config.OnObjectCacheLookup != nil {
+		s.config.OnObjectCacheLookup(hit)
+	}
+}
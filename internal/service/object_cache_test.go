@@ -0,0 +1,145 @@
+package service
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/vchitai/go-s3-sharing/internal/domain"
+)
+
+// countingStorage counts the GetObject calls reaching an FSService
+type countingStorage struct {
+	*FSService
+	gets  int
+	heads int
+	// understate reports every object read as a single byte long
+	understate bool
+}
+
+// understatedObjectReader reports a size smaller than its object
+type understatedObjectReader struct {
+	domain.ObjectReader
+}
+
+func (r understatedObjectReader) Size() int64 {
+	return 1
+}
+
+func (c *countingStorage) GetObject(ctx context.Context, key string) (domain.ObjectReader, error) {
+	c.gets++
+	reader, err := c.FSService.GetObject(ctx, key)
+	if err != nil || !c.understate {
+		return reader, err
+	}
+	return understatedObjectReader{reader}, nil
+}
+
+func (c *countingStorage) HeadObject(ctx context.Context, key string) (*domain.ObjectMetadata, error) {
+	c.heads++
+	return c.FSService.HeadObject(ctx, key)
+}
+
+func TestShareService_ObjectCache(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		"images/small.png": "small-png",
+		"images/large.png": "a much larger image body",
+	}
+	for name, body := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(body), 0o600); err != nil {
+			t.Fatalf("failed to write object: %v", err)
+		}
+	}
+
+	storage := &countingStorage{FSService: NewFSService(root)}
+	cache := NewMemoryCacheService()
+	defer cache.Close()
+	lookups := map[bool]int{}
+	service := NewShareService(storage, cache, &ShareConfig{
+		MaxAgeDays:          90,
+		ObjectCacheMaxBytes: 16,
+		ObjectCacheTTL:      time.Minute,
+		OnObjectCacheLookup: func(hit bool) { lookups[hit]++ },
+	})
+	ctx := context.Background()
+
+	read := func(s3Path string) domain.ObjectReader {
+		t.Helper()
+		reader, err := service.GetObject(ctx, s3Path)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		defer reader.Close()
+		data, err := io.ReadAll(reader)
+		if err != nil {
+			t.Fatalf("failed to read object: %v", err)
+		}
+		if string(data) != files[s3Path] || reader.Size() != int64(len(data)) {
+			t.Errorf("expected %q, got %q (size %d)", files[s3Path], data, reader.Size())
+		}
+		return reader
+	}
+
+	t.Run("miss fetches from storage", func(t *testing.T) {
+		read("images/small.png")
+		if storage.gets != 1 || lookups[false] != 1 {
+			t.Errorf("expected one storage fetch and miss, got %d fetches and %v", storage.gets, lookups)
+		}
+	})
+
+	t.Run("hit is served from the cache", func(t *testing.T) {
+		reader := read("images/small.png")
+		if storage.gets != 1 || lookups[true] != 1 {
+			t.Errorf("expected no storage fetch and one hit, got %d fetches and %v", storage.gets, lookups)
+		}
+		if reader.ContentType() != "image/png" || reader.ETag() == "" || reader.LastModified().IsZero() {
+			t.Errorf("expected cached metadata, got %q %q %v", reader.ContentType(), reader.ETag(), reader.LastModified())
+		}
+	})
+
+	t.Run("objects over the threshold are never cached", func(t *testing.T) {
+		before := storage.gets
+		read("images/large.png")
+		read("images/large.png")
+		if storage.gets != before+2 {
+			t.Errorf("expected every read to fetch from storage, got %d fetches", storage.gets-before)
+		}
+		if _, err := cache.Get(ctx, service.generateObjectKey(ctx, "images/large.png")); err != domain.ErrNotFound {
+			t.Errorf("expected large object not to be cached, got %v", err)
+		}
+	})
+
+	t.Run("deleting the object evicts it", func(t *testing.T) {
+		if err := service.DeleteObject(ctx, "images/small.png"); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if _, err := service.GetObject(ctx, "images/small.png"); err == nil {
+			t.Error("expected deleted object not to be served from the cache")
+		}
+	})
+
+	t.Run("object larger than reported", func(t *testing.T) {
+		storage.understate = true
+		defer func() { storage.understate = false }()
+
+		reader, err := service.GetObject(ctx, "images/large.png")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		defer reader.Close()
+		if data, err := io.ReadAll(reader); err != nil || string(data) != files["images/large.png"] {
+			t.Errorf("expected the whole object, got %q (%v)", data, err)
+		}
+		if _, err := cache.Get(ctx, service.generateObjectKey(ctx, "images/large.png")); err != domain.ErrNotFound {
+			t.Errorf("expected the object not to be cached, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,45 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/vchitai/go-s3-sharing/internal/domain"
+)
+
+// ConsumeDailyQuota counts uses against the daily quota of limit uses held by
+// the caller identified by id, and returns how many uses remain today. Days
+// are UTC and counters expire at the end of their day. It returns
+// ErrQuotaExceeded, consuming nothing, when fewer than uses remain.
+func (s *ShareService) ConsumeDailyQuota(ctx context.Context, id string, limit, uses int) (int, error) {
+	now := time.Now().UTC()
+	endOfDay := time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, time.UTC)
+
+	key := s.generateQuotaKey(id, now)
+	count, err := s.cache.IncrBy(ctx, key, int64(uses), endOfDay.Sub(now))
+	if err != nil {
+		return 0, fmt.Errorf("failed to count quota: %w", err)
+	}
+
+	if count > int64(limit) {
+		// Give back uses that were refused, so a large request does not
+		// use up what smaller ones could still have
+		if _, err := s.cache.IncrBy(ctx, key, -int64(uses), endOfDay.Sub(now)); err != nil {
+			return 0, fmt.Errorf("failed to count quota: %w", err)
+		}
+		return 0, domain.ErrQuotaExceeded
+	}
+	return limit - int(count), nil
+}
+
+// generateQuotaKey creates the cache key of the quota counter of id on the
+// UTC day of now. Quotas of the default key prefix keep their original
+// "api-quota" name.
+func (s *ShareService) generateQuotaKey(id string, now time.Time) string {
+	name := "api-quota"
+	if prefix := s.keyPrefix(); prefix != DefaultKeyPrefix {
+		name = prefix + "-api-quota"
+	}
+	return fmt.Sprintf("%s:%s:%s", name, id, now.Format("2006-01-02"))
+}
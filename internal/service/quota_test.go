@@ -0,0 +1,56 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/vchitai/go-s3-sharing/internal/domain"
+)
+
+func TestShareService_ConsumeDailyQuota(t *testing.T) {
+	cache := &mockCacheService{store: make(map[string]string)}
+	service := NewShareService(nil, cache, &ShareConfig{MaxAgeDays: 90})
+	ctx := context.Background()
+
+	for expected := 2; expected >= 0; expected-- {
+		remaining, err := service.ConsumeDailyQuota(ctx, "key-a", 3, 1)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if remaining != expected {
+			t.Errorf("expected %d remaining, got %d", expected, remaining)
+		}
+	}
+
+	if _, err := service.ConsumeDailyQuota(ctx, "key-a", 3, 1); !errors.Is(err, domain.ErrQuotaExceeded) {
+		t.Errorf("expected %v, got %v", domain.ErrQuotaExceeded, err)
+	}
+
+	// Quotas are counted per key and per UTC day
+	if remaining, err := service.ConsumeDailyQuota(ctx, "key-b", 3, 1); err != nil || remaining != 2 {
+		t.Errorf("expected an independent quota for another key, got %d, %v", remaining, err)
+	}
+	if _, exists := cache.store[service.generateQuotaKey("key-a", time.Now().UTC())]; !exists {
+		t.Errorf("expected a dated quota counter, got %v", cache.store)
+	}
+}
+
+func TestShareService_ConsumeDailyQuotaUses(t *testing.T) {
+	cache := &mockCacheService{store: make(map[string]string)}
+	service := NewShareService(nil, cache, &ShareConfig{MaxAgeDays: 90})
+	ctx := context.Background()
+
+	if remaining, err := service.ConsumeDailyQuota(ctx, "key-a", 5, 3); err != nil || remaining != 2 {
+		t.Fatalf("expected 2 remaining, got %d, %v", remaining, err)
+	}
+
+	// Refused uses are given back
+	if _, err := service.ConsumeDailyQuota(ctx, "key-a", 5, 3); !errors.Is(err, domain.ErrQuotaExceeded) {
+		t.Errorf("expected %v, got %v", domain.ErrQuotaExceeded, err)
+	}
+	if remaining, err := service.ConsumeDailyQuota(ctx, "key-a", 5, 2); err != nil || remaining != 0 {
+		t.Errorf("expected the remaining uses to be consumable, got %d, %v", remaining, err)
+	}
+}
@@ -2,51 +2,233 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
+	"net"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/redis/go-redis/v9"
 	"github.com/vchitai/go-s3-sharing/internal/domain"
 )
 
+// Redis deployment modes accepted by NewRedisClient
+const (
+	RedisModeStandalone = "standalone"
+	RedisModeCluster    = "cluster"
+	RedisModeSentinel   = "sentinel"
+)
+
+// NewRedisClient creates a client for the Redis deployment of mode: a single
+// node at the first address, a cluster seeded with the addresses, or the
+// master named opts.MasterName found through the sentinels at the addresses
+func NewRedisClient(mode string, opts *redis.UniversalOptions) (redis.UniversalClient, error) {
+	if len(opts.Addrs) == 0 {
+		return nil, fmt.Errorf("no Redis address configured")
+	}
+
+	switch mode {
+	case RedisModeStandalone:
+		return redis.NewClient(opts.Simple()), nil
+	case RedisModeCluster:
+		return redis.NewClusterClient(opts.Cluster()), nil
+	case RedisModeSentinel:
+		if opts.MasterName == "" {
+			return nil, fmt.Errorf("sentinel mode requires a master name")
+		}
+		return redis.NewFailoverClient(opts.Failover()), nil
+	default:
+		return nil, fmt.Errorf("unknown Redis mode %q", mode)
+	}
+}
+
 // RedisService implements CacheService for Redis
 type RedisService struct {
-	client *redis.Client
+	client redis.UniversalClient
 }
 
-// NewRedisService creates a new Redis service
-func NewRedisService(client *redis.Client) *RedisService {
+// NewRedisService creates a new Redis service over a standalone, cluster or
+// sentinel client
+func NewRedisService(client redis.UniversalClient) *RedisService {
 	return &RedisService{
 		client: client,
 	}
 }
 
 // Set stores a key-value pair in Redis with expiration
-func (r *RedisService) Set(ctx context.Context, key, value string, expiration time.Duration) error {
-	err := r.client.Set(ctx, key, value, expiration).Err()
+func (r *RedisService) Set(ctx context.Context, key, value string, expiration time.Duration) (err error) {
+	ctx, span := startRedisSpan(ctx, "SET")
+	defer func() { endSpan(span, err) }()
+
+	err = r.client.Set(ctx, key, value, expiration).Err()
 	if err != nil {
-		return fmt.Errorf("failed to set key in Redis: %w", err)
+		return fmt.Errorf("failed to set key in Redis: %w", redisError(err))
 	}
 	return nil
 }
 
+// SetNX stores a key-value pair in Redis unless the key already exists
+func (r *RedisService) SetNX(ctx context.Context, key, value string, expiration time.Duration) (stored bool, err error) {
+	ctx, span := startRedisSpan(ctx, "SET NX")
+	defer func() { endSpan(span, err) }()
+
+	stored, err = r.client.SetNX(ctx, key, value, expiration).Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to set key in Redis: %w", redisError(err))
+	}
+	return stored, nil
+}
+
 // Get retrieves a value from Redis by key
-func (r *RedisService) Get(ctx context.Context, key string) (string, error) {
-	val, err := r.client.Get(ctx, key).Result()
+func (r *RedisService) Get(ctx context.Context, key string) (val string, err error) {
+	ctx, span := startRedisSpan(ctx, "GET")
+	defer func() { endSpan(span, err) }()
+
+	val, err = r.client.Get(ctx, key).Result()
 	if err == redis.Nil {
 		return "", domain.ErrNotFound
 	}
 	if err != nil {
-		return "", fmt.Errorf("failed to get key from Redis: %w", err)
+		return "", fmt.Errorf("failed to get key from Redis: %w", redisError(err))
 	}
 	return val, nil
 }
 
+// Incr atomically increments a counter in Redis and refreshes its expiration
+func (r *RedisService) Incr(ctx context.Context, key string, expiration time.Duration) (count int64, err error) {
+	return r.IncrBy(ctx, key, 1, expiration)
+}
+
+// IncrBy atomically adds delta to a counter in Redis and refreshes its
+// expiration
+func (r *RedisService) IncrBy(ctx context.Context, key string, delta int64, expiration time.Duration) (count int64, err error) {
+	ctx, span := startRedisSpan(ctx, "INCRBY")
+	defer func() { endSpan(span, err) }()
+
+	pipe := r.client.TxPipeline()
+	incr := pipe.IncrBy(ctx, key, delta)
+	if expiration > 0 {
+		pipe.Expire(ctx, key, expiration)
+	}
+
+	if _, err = pipe.Exec(ctx); err != nil {
+		return 0, fmt.Errorf("failed to increment key in Redis: %w", redisError(err))
+	}
+	return incr.Val(), nil
+}
+
+// TTL returns the remaining time to live of a key in Redis
+func (r *RedisService) TTL(ctx context.Context, key string) (ttl time.Duration, err error) {
+	ctx, span := startRedisSpan(ctx, "TTL")
+	defer func() { endSpan(span, err) }()
+
+	ttl, err = r.client.TTL(ctx, key).Result()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get key TTL from Redis: %w", redisError(err))
+	}
+	// Redis reports -2 for missing keys and -1 for keys without expiration
+	if ttl == -2 {
+		return 0, domain.ErrNotFound
+	}
+	return ttl, nil
+}
+
+// Expire replaces the expiration of a key in Redis
+func (r *RedisService) Expire(ctx context.Context, key string, expiration time.Duration) (err error) {
+	ctx, span := startRedisSpan(ctx, "EXPIRE")
+	defer func() { endSpan(span, err) }()
+
+	if err := r.client.Expire(ctx, key, expiration).Err(); err != nil {
+		return fmt.Errorf("failed to set key expiration in Redis: %w", redisError(err))
+	}
+	return nil
+}
+
+// redisScanCount is the number of keys Redis inspects per SCAN call
+const redisScanCount = 100
+
+// redisGlobEscaper escapes the glob metacharacters of SCAN MATCH patterns
+var redisGlobEscaper = strings.NewReplacer(`\`, `\\`, "*", `\*`, "?", `\?`, "[", `\[`, "]", `\]`)
+
+// Scan iterates the keys starting with prefix with SCAN, which unlike KEYS
+// never blocks Redis for long. Cursors are per node, so on a cluster every
+// master is scanned to completion in a single call.
+func (r *RedisService) Scan(ctx context.Context, prefix string, cursor uint64) (keys []string, next uint64, err error) {
+	ctx, span := startRedisSpan(ctx, "SCAN")
+	defer func() { endSpan(span, err) }()
+
+	match := redisGlobEscaper.Replace(prefix) + "*"
+	if cluster, ok := r.client.(*redis.ClusterClient); ok {
+		keys, err = scanCluster(ctx, cluster, match)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to scan keys in Redis: %w", redisError(err))
+		}
+		return keys, 0, nil
+	}
+
+	keys, next, err = r.client.Scan(ctx, cursor, match, redisScanCount).Result()
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to scan keys in Redis: %w", redisError(err))
+	}
+	return keys, next, nil
+}
+
+// scanCluster collects the keys matching match on every master of cluster
+func scanCluster(ctx context.Context, cluster *redis.ClusterClient, match string) ([]string, error) {
+	var (
+		mu   sync.Mutex
+		keys []string
+	)
+	err := cluster.ForEachMaster(ctx, func(ctx context.Context, node *redis.Client) error {
+		iter := node.Scan(ctx, 0, match, redisScanCount).Iterator()
+		var nodeKeys []string
+		for iter.Next(ctx) {
+			nodeKeys = append(nodeKeys, iter.Val())
+		}
+		if err := iter.Err(); err != nil {
+			return err
+		}
+
+		mu.Lock()
+		keys = append(keys, nodeKeys...)
+		mu.Unlock()
+		return nil
+	})
+	return keys, err
+}
+
+// Ping checks connectivity to Redis
+func (r *RedisService) Ping(ctx context.Context) (err error) {
+	ctx, span := startRedisSpan(ctx, "PING")
+	defer func() { endSpan(span, err) }()
+
+	if err = r.client.Ping(ctx).Err(); err != nil {
+		return fmt.Errorf("failed to ping Redis: %w", redisError(err))
+	}
+	return nil
+}
+
 // Delete removes a key from Redis
-func (r *RedisService) Delete(ctx context.Context, key string) error {
-	err := r.client.Del(ctx, key).Err()
+func (r *RedisService) Delete(ctx context.Context, key string) (err error) {
+	ctx, span := startRedisSpan(ctx, "DEL")
+	defer func() { endSpan(span, err) }()
+
+	err = r.client.Del(ctx, key).Err()
 	if err != nil {
-		return fmt.Errorf("failed to delete key from Redis: %w", err)
+		return fmt.Errorf("failed to delete key from Redis: %w", redisError(err))
 	}
 	return nil
 }
+
+// redisError marks err with ErrCacheUnavailable when it means Redis could not
+// be reached, as opposed to an error reply of the server
+func redisError(err error) error {
+	var netErr net.Error
+	if errors.As(err, &netErr) || errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, redis.ErrClosed) || errors.Is(err, redis.ErrPoolTimeout) || errors.Is(err, redis.ErrPoolExhausted) {
+		return fmt.Errorf("%w: %w", domain.ErrCacheUnavailable, err)
+	}
+	return err
+}
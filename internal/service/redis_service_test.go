@@ -0,0 +1,258 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/vchitai/go-s3-sharing/internal/domain"
+)
+
+// mockUniversalClient implements the commands RedisService issues over an
+// in-memory map. The embedded interface panics on any other command.
+type mockUniversalClient struct {
+	redis.UniversalClient
+	store map[string]string
+	ttls  map[string]time.Duration
+	err   error
+}
+
+func (m *mockUniversalClient) Set(ctx context.Context, key string, value interface{}, expiration time.Duration) *redis.StatusCmd {
+	if m.err != nil {
+		return redis.NewStatusResult("", m.err)
+	}
+	m.store[key] = value.(string)
+	m.ttls[key] = expiration
+	return redis.NewStatusResult("OK", nil)
+}
+
+func (m *mockUniversalClient) SetNX(ctx context.Context, key string, value interface{}, expiration time.Duration) *redis.BoolCmd {
+	if _, exists := m.store[key]; exists {
+		return redis.NewBoolResult(false, nil)
+	}
+	m.store[key] = value.(string)
+	m.ttls[key] = expiration
+	return redis.NewBoolResult(true, nil)
+}
+
+func (m *mockUniversalClient) Get(ctx context.Context, key string) *redis.StringCmd {
+	if m.err != nil {
+		return redis.NewStringResult("", m.err)
+	}
+	value, exists := m.store[key]
+	if !exists {
+		return redis.NewStringResult("", redis.Nil)
+	}
+	return redis.NewStringResult(value, nil)
+}
+
+func (m *mockUniversalClient) TTL(ctx context.Context, key string) *redis.DurationCmd {
+	if _, exists := m.store[key]; !exists {
+		return redis.NewDurationResult(-2, nil)
+	}
+	return redis.NewDurationResult(m.ttls[key], nil)
+}
+
+func (m *mockUniversalClient) Expire(ctx context.Context, key string, expiration time.Duration) *redis.BoolCmd {
+	if _, exists := m.store[key]; !exists {
+		return redis.NewBoolResult(false, nil)
+	}
+	m.ttls[key] = expiration
+	return redis.NewBoolResult(true, nil)
+}
+
+func (m *mockUniversalClient) Scan(ctx context.Context, cursor uint64, match string, count int64) *redis.ScanCmd {
+	var keys []string
+	for key := range m.store {
+		if strings.HasPrefix(key, strings.TrimSuffix(match, "*")) {
+			keys = append(keys, key)
+		}
+	}
+	return redis.NewScanCmdResult(keys, 0, nil)
+}
+
+func (m *mockUniversalClient) Del(ctx context.Context, keys ...string) *redis.IntCmd {
+	for _, key := range keys {
+		delete(m.store, key)
+		delete(m.ttls, key)
+	}
+	return redis.NewIntResult(int64(len(keys)), nil)
+}
+
+func (m *mockUniversalClient) Ping(ctx context.Context) *redis.StatusCmd {
+	if m.err != nil {
+		return redis.NewStatusResult("", m.err)
+	}
+	return redis.NewStatusResult("PONG", nil)
+}
+
+func TestRedisService_UniversalClient(t *testing.T) {
+	client := &mockUniversalClient{store: make(map[string]string), ttls: make(map[string]time.Duration)}
+	cache := NewRedisService(client)
+	ctx := context.Background()
+
+	if err := cache.Set(ctx, "image-auth:images/photo.jpg", "secret", time.Hour); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	value, err := cache.Get(ctx, "image-auth:images/photo.jpg")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if value != "secret" {
+		t.Errorf("expected value secret, got %s", value)
+	}
+
+	if _, err := cache.Get(ctx, "image-auth:images/missing.jpg"); err != domain.ErrNotFound {
+		t.Errorf("expected ErrNotFound for a missing key, got %v", err)
+	}
+
+	stored, err := cache.SetNX(ctx, "image-auth:images/photo.jpg", "other", time.Hour)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if stored {
+		t.Error("expected SetNX not to overwrite an existing key")
+	}
+
+	if err := cache.Expire(ctx, "image-auth:images/photo.jpg", 2*time.Hour); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	ttl, err := cache.TTL(ctx, "image-auth:images/photo.jpg")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if ttl != 2*time.Hour {
+		t.Errorf("expected TTL 2h, got %v", ttl)
+	}
+	if _, err := cache.TTL(ctx, "image-auth:images/missing.jpg"); err != domain.ErrNotFound {
+		t.Errorf("expected ErrNotFound for the TTL of a missing key, got %v", err)
+	}
+
+	keys, next, err := cache.Scan(ctx, "image-auth:", 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(keys) != 1 || keys[0] != "image-auth:images/photo.jpg" || next != 0 {
+		t.Errorf("expected a single scanned key, got %v with cursor %d", keys, next)
+	}
+
+	if err := cache.Delete(ctx, "image-auth:images/photo.jpg"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := cache.Get(ctx, "image-auth:images/photo.jpg"); err != domain.ErrNotFound {
+		t.Errorf("expected ErrNotFound after Delete, got %v", err)
+	}
+
+	if err := cache.Ping(ctx); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	t.Run("client errors", func(t *testing.T) {
+		failing := NewRedisService(&mockUniversalClient{
+			store: make(map[string]string),
+			ttls:  make(map[string]time.Duration),
+			err:   errors.New("CLUSTERDOWN The cluster is down"),
+		})
+		if err := failing.Set(ctx, "key", "value", time.Hour); err == nil {
+			t.Error("expected error from Set")
+		}
+		if _, err := failing.Get(ctx, "key"); err == nil || err == domain.ErrNotFound {
+			t.Errorf("expected a client error from Get, got %v", err)
+		}
+		if err := failing.Ping(ctx); err == nil {
+			t.Error("expected error from Ping")
+		}
+	})
+}
+
+func TestRedisService_ConnectionErrors(t *testing.T) {
+	tests := []struct {
+		name        string
+		err         error
+		unavailable bool
+	}{
+		{name: "connection refused", err: &net.OpError{Op: "dial", Net: "tcp", Err: syscall.ECONNREFUSED}, unavailable: true},
+		{name: "connection closed", err: io.EOF, unavailable: true},
+		{name: "pool timeout", err: redis.ErrPoolTimeout, unavailable: true},
+		{name: "client closed", err: redis.ErrClosed, unavailable: true},
+		{name: "error reply", err: errors.New("CLUSTERDOWN The cluster is down")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cache := NewRedisService(&mockUniversalClient{
+				store: make(map[string]string),
+				ttls:  make(map[string]time.Duration),
+				err:   tt.err,
+			})
+
+			_, err := cache.Get(context.Background(), "key")
+			if !errors.Is(err, tt.err) {
+				t.Errorf("expected wrapped %v, got %v", tt.err, err)
+			}
+			if errors.Is(err, domain.ErrCacheUnavailable) != tt.unavailable {
+				t.Errorf("expected unavailable %v, got %v", tt.unavailable, err)
+			}
+			if errors.Is(err, domain.ErrNotFound) {
+				t.Errorf("expected a connection error not to be %v", domain.ErrNotFound)
+			}
+		})
+	}
+
+	t.Run("missing key", func(t *testing.T) {
+		cache := NewRedisService(&mockUniversalClient{store: make(map[string]string), ttls: make(map[string]time.Duration)})
+		if _, err := cache.Get(context.Background(), "key"); err != domain.ErrNotFound || errors.Is(err, domain.ErrCacheUnavailable) {
+			t.Errorf("expected %v, got %v", domain.ErrNotFound, err)
+		}
+	})
+}
+
+func TestNewRedisClient(t *testing.T) {
+	tests := []struct {
+		name        string
+		mode        string
+		opts        *redis.UniversalOptions
+		expectError bool
+	}{
+		{name: "standalone", mode: RedisModeStandalone, opts: &redis.UniversalOptions{Addrs: []string{"localhost:6379"}}},
+		{name: "cluster", mode: RedisModeCluster, opts: &redis.UniversalOptions{Addrs: []string{"node-1:6379", "node-2:6379"}}},
+		{name: "sentinel", mode: RedisModeSentinel, opts: &redis.UniversalOptions{Addrs: []string{"sentinel-1:26379"}, MasterName: "mymaster"}},
+		{name: "sentinel without master name", mode: RedisModeSentinel, opts: &redis.UniversalOptions{Addrs: []string{"sentinel-1:26379"}}, expectError: true},
+		{name: "no addresses", mode: RedisModeCluster, opts: &redis.UniversalOptions{}, expectError: true},
+		{name: "unknown mode", mode: "replicated", opts: &redis.UniversalOptions{Addrs: []string{"localhost:6379"}}, expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, err := NewRedisClient(tt.mode, tt.opts)
+			if tt.expectError {
+				if err == nil {
+					t.Error("expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			defer client.Close()
+
+			var ok bool
+			switch tt.mode {
+			case RedisModeStandalone, RedisModeSentinel:
+				_, ok = client.(*redis.Client)
+			case RedisModeCluster:
+				_, ok = client.(*redis.ClusterClient)
+			}
+			if !ok {
+				t.Errorf("unexpected client type %T for mode %s", client, tt.mode)
+			}
+		})
+	}
+}
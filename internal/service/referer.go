@@ -0,0 +1,45 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/vchitai/go-s3-sharing/internal/domain"
+)
+
+// ValidateReferer checks the Referer header value of a download of the share
+// of s3Path against the share's allowed referers, or the configured ones when
+// the share has none. It returns ErrRefererNotAllowed when the referer does
+// not match, or is empty and empty referers are not allowed.
+func (s *ShareService) ValidateReferer(ctx context.Context, s3Path, referer string) error {
+	record, err := s.getShareRecord(ctx, s3Path)
+	if err != nil {
+		if err == domain.ErrNotFound {
+			return domain.ErrUnauthorized
+		}
+		// Only the configured referers are known while the cache is down
+		if !s.cacheFallback(err) {
+			return fmt.Errorf("failed to validate referer: %w", err)
+		}
+		record = &domain.ShareRecord{}
+	}
+
+	patterns := record.AllowedReferers
+	if len(patterns) == 0 {
+		patterns = s.config.AllowedReferers
+	}
+	if len(patterns) == 0 {
+		return nil
+	}
+
+	if referer == "" {
+		if s.config.AllowEmptyReferer {
+			return nil
+		}
+		return domain.ErrRefererNotAllowed
+	}
+	if !domain.MatchReferer(referer, patterns) {
+		return domain.ErrRefererNotAllowed
+	}
+	return nil
+}
@@ -0,0 +1,235 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"image"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"math"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+	"golang.org/x/image/draw"
+
+	"github.com/vchitai/go-s3-sharing/internal/domain"
+)
+
+// resizeJPEGQuality is the quality resized JPEG images are encoded at
+const resizeJPEGQuality = 85
+
+// IsResizableImage reports whether objects of contentType can be resized
+func IsResizableImage(contentType string) bool {
+	mediaType, _, _ := strings.Cut(contentType, ";")
+	switch strings.ToLower(strings.TrimSpace(mediaType)) {
+	case "image/jpeg", "image/png", "image/gif":
+		return true
+	default:
+		return false
+	}
+}
+
+// ResizeImage returns the image of the share of s3Path scaled as opts asks,
+// from the resize cache when it holds that size. It returns ErrNotResizable
+// when resizing is disabled or the object is not a JPEG, PNG or GIF image,
+// and ErrImageTooLarge when the requested or stored image exceeds the
+// configured limits.
+func (s *ShareService) ResizeImage(ctx context.Context, s3Path string, opts domain.ResizeOptions) (_ domain.ObjectReader, err error) {
+	ctx, span := startSpan(ctx, "ShareService.ResizeImage",
+		attribute.String("share.path", s3Path),
+		attribute.Int("resize.width", opts.Width),
+		attribute.Int("resize.height", opts.Height),
+	)
+	defer func() { endSpan(span, err) }()
+
+	if s.config.ResizeMaxDimension <= 0 {
+		return nil, domain.ErrNotResizable
+	}
+	if opts.Width < 0 || opts.Height < 0 || opts.Width == 0 && opts.Height == 0 {
+		return nil, fmt.Errorf("invalid resize dimensions %dx%d", opts.Width, opts.Height)
+	}
+	if opts.Width > s.config.ResizeMaxDimension || opts.Height > s.config.ResizeMaxDimension {
+		return nil, domain.ErrImageTooLarge
+	}
+	opts.Width = roundResizeSize(opts.Width, s.config.ResizeSizes)
+	opts.Height = roundResizeSize(opts.Height, s.config.ResizeSizes)
+	opts = normalizeResizeOptions(opts)
+
+	key := s.generateResizedKey(ctx, s3Path, opts)
+	if s.config.ResizeCacheTTL > 0 {
+		if value, err := s.cache.Get(ctx, key); err == nil {
+			if reader, err := decodeCachedObject(value); err == nil {
+				return reader, nil
+			}
+		}
+	}
+
+	// Check the type from metadata so that other objects are never fetched
+	metadata, err := s.HeadObject(ctx, s3Path)
+	if err != nil {
+		return nil, err
+	}
+	if !IsResizableImage(metadata.ContentType) {
+		return nil, domain.ErrNotResizable
+	}
+
+	reader, err := s.GetObject(ctx, s3Path)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	data, contentType, err := s.resize(reader, opts)
+	if err != nil {
+		return nil, err
+	}
+	header := cachedObjectHeader{
+		ContentType:  contentType,
+		ETag:         resizedETag(reader.ETag(), opts),
+		LastModified: reader.LastModified(),
+	}
+	if s.config.ResizeCacheTTL > 0 {
+		// A failed write only costs resizing again
+		if value, err := encodeCachedObject(header, data); err == nil {
+			_ = s.cache.Set(ctx, key, value, s.config.ResizeCacheTTL)
+		}
+	}
+	return newCachedObjectReader(header, data), nil
+}
+
+// resize decodes the image read from r, scales it as opts asks and encodes it
+// in its original format, returning the encoded bytes and their content type
+func (s *ShareService) resize(r io.Reader, opts domain.ResizeOptions) ([]byte, string, error) {
+	// Check the dimensions in the image header before decoding, so that huge
+	// images are rejected without allocating their pixels
+	var header bytes.Buffer
+	config, _, err := image.DecodeConfig(io.TeeReader(r, &header))
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to decode image: %w", err)
+	}
+	if config.Width <= 0 || config.Height <= 0 {
+		return nil, "", fmt.Errorf("failed to decode image: empty image")
+	}
+	if int64(config.Width)*int64(config.Height) > int64(s.config.ResizeMaxPixels) {
+		return nil, "", domain.ErrImageTooLarge
+	}
+
+	src, format, err := image.Decode(io.MultiReader(&header, r))
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to decode image: %w", err)
+	}
+
+	width, height, crop := resizeBounds(src.Bounds(), opts)
+	if width > s.config.ResizeMaxDimension || height > s.config.ResizeMaxDimension {
+		return nil, "", domain.ErrImageTooLarge
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), src, crop, draw.Src, nil)
+
+	var buf bytes.Buffer
+	switch format {
+	case "jpeg":
+		err = jpeg.Encode(&buf, dst, &jpeg.Options{Quality: resizeJPEGQuality})
+	case "png":
+		err = png.Encode(&buf, dst)
+	case "gif":
+		err = gif.Encode(&buf, dst, nil)
+	default:
+		return nil, "", domain.ErrNotResizable
+	}
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to encode resized image: %w", err)
+	}
+	return buf.Bytes(), "image/" + format, nil
+}
+
+// normalizeResizeOptions defaults the fit to ResizeFitContain, which is also
+// the fit of sizes with a single dimension, so that equal sizes share a cache
+// key
+func normalizeResizeOptions(opts domain.ResizeOptions) domain.ResizeOptions {
+	if opts.Fit == "" || opts.Width == 0 || opts.Height == 0 {
+		opts.Fit = domain.ResizeFitContain
+	}
+	return opts
+}
+
+// roundResizeSize rounds a requested size up to the smallest of sizes not
+// below it, or the largest of sizes when all are below it. Unset sizes and
+// sizes with no list to round to are returned unchanged.
+func roundResizeSize(size int, sizes []int) int {
+	if size == 0 || len(sizes) == 0 {
+		return size
+	}
+	rounded, largest := 0, 0
+	for _, candidate := range sizes {
+		largest = max(largest, candidate)
+		if candidate >= size && (rounded == 0 || candidate < rounded) {
+			rounded = candidate
+		}
+	}
+	if rounded == 0 {
+		return largest
+	}
+	return rounded
+}
+
+// resizeBounds returns the size an image with bounds src is resized to and
+// the part of src scaled into it
+func resizeBounds(src image.Rectangle, opts domain.ResizeOptions) (int, int, image.Rectangle) {
+	srcWidth, srcHeight := float64(src.Dx()), float64(src.Dy())
+
+	switch {
+	case opts.Height == 0:
+		return opts.Width, scaledSide(srcHeight * float64(opts.Width) / srcWidth), src
+	case opts.Width == 0:
+		return scaledSide(srcWidth * float64(opts.Height) / srcHeight), opts.Height, src
+	}
+
+	switch opts.Fit {
+	case domain.ResizeFitFill:
+		return opts.Width, opts.Height, src
+	case domain.ResizeFitCover:
+		// Crop the centre of the source to the aspect ratio of the box
+		crop := src
+		if srcWidth*float64(opts.Height) > srcHeight*float64(opts.Width) {
+			cropWidth := scaledSide(srcHeight * float64(opts.Width) / float64(opts.Height))
+			crop.Min.X += (src.Dx() - cropWidth) / 2
+			crop.Max.X = crop.Min.X + cropWidth
+		} else {
+			cropHeight := scaledSide(srcWidth * float64(opts.Height) / float64(opts.Width))
+			crop.Min.Y += (src.Dy() - cropHeight) / 2
+			crop.Max.Y = crop.Min.Y + cropHeight
+		}
+		return opts.Width, opts.Height, crop
+	default:
+		scale := min(float64(opts.Width)/srcWidth, float64(opts.Height)/srcHeight)
+		return scaledSide(srcWidth * scale), scaledSide(srcHeight * scale), src
+	}
+}
+
+// scaledSide rounds a scaled side length to whole pixels, keeping at least one
+func scaledSide(length float64) int {
+	return max(1, int(math.Round(length)))
+}
+
+// resizedETag derives the entity tag of a resized image from the tag of its
+// source, so that every size revalidates separately and changes with the
+// source
+func resizedETag(sourceETag string, opts domain.ResizeOptions) string {
+	if sourceETag == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s:%dx%d:%s", sourceETag, opts.Width, opts.Height, opts.Fit)))
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// generateResizedKey creates the resize cache key of s3Path at the size opts
+// asks for
+func (s *ShareService) generateResizedKey(ctx context.Context, s3Path string, opts domain.ResizeOptions) string {
+	return fmt.Sprintf("%s%s:%dx%d:%s:%s", s.tenantKeyPrefix(ctx), s.keyName("resized"), opts.Width, opts.Height, opts.Fit, s3Path)
+}
@@ -0,0 +1,155 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/png"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/vchitai/go-s3-sharing/internal/domain"
+)
+
+func TestResizeBounds(t *testing.T) {
+	src := image.Rect(0, 0, 400, 200)
+
+	tests := []struct {
+		name           string
+		opts           domain.ResizeOptions
+		expectedWidth  int
+		expectedHeight int
+		expectedCrop   image.Rectangle
+	}{
+		{name: "width only", opts: domain.ResizeOptions{Width: 100}, expectedWidth: 100, expectedHeight: 50, expectedCrop: src},
+		{name: "height only", opts: domain.ResizeOptions{Height: 100}, expectedWidth: 200, expectedHeight: 100, expectedCrop: src},
+		{name: "contain", opts: domain.ResizeOptions{Width: 100, Height: 100, Fit: domain.ResizeFitContain}, expectedWidth: 100, expectedHeight: 50, expectedCrop: src},
+		{name: "cover crops the sides", opts: domain.ResizeOptions{Width: 100, Height: 100, Fit: domain.ResizeFitCover}, expectedWidth: 100, expectedHeight: 100, expectedCrop: image.Rect(100, 0, 300, 200)},
+		{name: "cover crops the top and bottom", opts: domain.ResizeOptions{Width: 100, Height: 10, Fit: domain.ResizeFitCover}, expectedWidth: 100, expectedHeight: 10, expectedCrop: image.Rect(0, 80, 400, 120)},
+		{name: "fill", opts: domain.ResizeOptions{Width: 100, Height: 100, Fit: domain.ResizeFitFill}, expectedWidth: 100, expectedHeight: 100, expectedCrop: src},
+		{name: "keeps a pixel", opts: domain.ResizeOptions{Width: 1}, expectedWidth: 1, expectedHeight: 1, expectedCrop: src},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			width, height, crop := resizeBounds(src, normalizeResizeOptions(tt.opts))
+			if width != tt.expectedWidth || height != tt.expectedHeight {
+				t.Errorf("expected %dx%d, got %dx%d", tt.expectedWidth, tt.expectedHeight, width, height)
+			}
+			if crop != tt.expectedCrop {
+				t.Errorf("expected crop %v, got %v", tt.expectedCrop, crop)
+			}
+		})
+	}
+}
+
+func TestRoundResizeSize(t *testing.T) {
+	sizes := []int{640, 320, 1280}
+
+	tests := []struct {
+		size     int
+		sizes    []int
+		expected int
+	}{
+		{size: 100, sizes: sizes, expected: 320},
+		{size: 320, sizes: sizes, expected: 320},
+		{size: 321, sizes: sizes, expected: 640},
+		{size: 2000, sizes: sizes, expected: 1280},
+		{size: 0, sizes: sizes, expected: 0},
+		{size: 333, expected: 333},
+	}
+
+	for _, tt := range tests {
+		if got := roundResizeSize(tt.size, tt.sizes); got != tt.expected {
+			t.Errorf("roundResizeSize(%d, %v): expected %d, got %d", tt.size, tt.sizes, tt.expected, got)
+		}
+	}
+}
+
+func TestShareService_ResizeImage(t *testing.T) {
+	root := t.TempDir()
+	var encoded bytes.Buffer
+	if err := png.Encode(&encoded, image.NewRGBA(image.Rect(0, 0, 300, 200))); err != nil {
+		t.Fatalf("failed to encode image: %v", err)
+	}
+	files := map[string][]byte{
+		"images/photo.png": encoded.Bytes(),
+		"docs/notes.txt":   []byte("notes"),
+	}
+	for name, body := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, body, 0o600); err != nil {
+			t.Fatalf("failed to write object: %v", err)
+		}
+	}
+
+	newService := func(config *ShareConfig) (*ShareService, *countingStorage) {
+		storage := &countingStorage{FSService: NewFSService(root)}
+		cache := NewMemoryCacheService()
+		t.Cleanup(func() { cache.Close() })
+		config.MaxAgeDays = 90
+		return NewShareService(storage, cache, config), storage
+	}
+	ctx := context.Background()
+
+	t.Run("resizes and caches", func(t *testing.T) {
+		service, storage := newService(&ShareConfig{ResizeMaxDimension: 1000, ResizeMaxPixels: 100_000, ResizeCacheTTL: time.Minute})
+
+		for i := 0; i < 2; i++ {
+			reader, err := service.ResizeImage(ctx, "images/photo.png", domain.ResizeOptions{Width: 30})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			data, err := io.ReadAll(reader)
+			reader.Close()
+			if err != nil {
+				t.Fatalf("failed to read resized image: %v", err)
+			}
+			if reader.ContentType() != "image/png" {
+				t.Errorf("expected content type image/png, got %s", reader.ContentType())
+			}
+			config, err := png.DecodeConfig(bytes.NewReader(data))
+			if err != nil {
+				t.Fatalf("failed to decode resized image: %v", err)
+			}
+			if config.Width != 30 || config.Height != 20 {
+				t.Errorf("expected 30x20, got %dx%d", config.Width, config.Height)
+			}
+		}
+		if storage.gets != 1 {
+			t.Errorf("expected the second resize to be cached, got %d storage reads", storage.gets)
+		}
+	})
+
+	tests := []struct {
+		name        string
+		config      *ShareConfig
+		s3Path      string
+		opts        domain.ResizeOptions
+		expectedErr error
+	}{
+		{name: "disabled", config: &ShareConfig{}, s3Path: "images/photo.png", opts: domain.ResizeOptions{Width: 30}, expectedErr: domain.ErrNotResizable},
+		{name: "not an image", config: &ShareConfig{ResizeMaxDimension: 1000, ResizeMaxPixels: 100_000}, s3Path: "docs/notes.txt", opts: domain.ResizeOptions{Width: 30}, expectedErr: domain.ErrNotResizable},
+		{name: "requested size too large", config: &ShareConfig{ResizeMaxDimension: 100, ResizeMaxPixels: 100_000}, s3Path: "images/photo.png", opts: domain.ResizeOptions{Width: 101}, expectedErr: domain.ErrImageTooLarge},
+		{name: "derived size too large", config: &ShareConfig{ResizeMaxDimension: 100, ResizeMaxPixels: 100_000}, s3Path: "images/photo.png", opts: domain.ResizeOptions{Height: 90}, expectedErr: domain.ErrImageTooLarge},
+		{name: "source too large", config: &ShareConfig{ResizeMaxDimension: 1000, ResizeMaxPixels: 50_000}, s3Path: "images/photo.png", opts: domain.ResizeOptions{Width: 30}, expectedErr: domain.ErrImageTooLarge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service, storage := newService(tt.config)
+			if _, err := service.ResizeImage(ctx, tt.s3Path, tt.opts); err != tt.expectedErr {
+				t.Errorf("expected error %v, got %v", tt.expectedErr, err)
+			}
+			if tt.expectedErr == domain.ErrNotResizable && storage.gets != 0 {
+				t.Errorf("expected the object not to be fetched, got %d storage reads", storage.gets)
+			}
+		})
+	}
+}
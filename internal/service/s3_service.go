@@ -2,19 +2,37 @@ package service
 
 import (
 	"context"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
+	"math/rand/v2"
+	"mime"
+	"net/http"
+	"path"
+	"sort"
+	"strings"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
 	"github.com/vchitai/go-s3-sharing/internal/domain"
 )
 
 // s3ObjectReader wraps S3 GetObjectOutput to implement ObjectReader
 type s3ObjectReader struct {
-	body        io.ReadCloser
-	contentType string
-	size        int64
+	body         io.ReadCloser
+	contentType  string
+	size         int64
+	etag         string
+	lastModified time.Time
+	checksum     string
 }
 
 func (r *s3ObjectReader) Read(p []byte) (n int, err error) {
@@ -33,63 +51,282 @@ func (r *s3ObjectReader) Size() int64 {
 	return r.size
 }
 
+func (r *s3ObjectReader) ETag() string {
+	return r.etag
+}
+
+func (r *s3ObjectReader) LastModified() time.Time {
+	return r.lastModified
+}
+
+func (r *s3ObjectReader) Checksum() string {
+	return r.checksum
+}
+
+// S3API is the subset of the S3 client used by S3Service
+type S3API interface {
+	GetObject(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error)
+	HeadObject(ctx context.Context, params *s3.HeadObjectInput, optFns ...func(*s3.Options)) (*s3.HeadObjectOutput, error)
+	HeadBucket(ctx context.Context, params *s3.HeadBucketInput, optFns ...func(*s3.Options)) (*s3.HeadBucketOutput, error)
+	DeleteObject(ctx context.Context, params *s3.DeleteObjectInput, optFns ...func(*s3.Options)) (*s3.DeleteObjectOutput, error)
+	ListObjectsV2(ctx context.Context, params *s3.ListObjectsV2Input, optFns ...func(*s3.Options)) (*s3.ListObjectsV2Output, error)
+}
+
+// S3Presigner is the subset of the S3 presign client used by S3Service
+type S3Presigner interface {
+	PresignGetObject(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.PresignOptions)) (*v4.PresignedHTTPRequest, error)
+	PresignPutObject(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.PresignOptions)) (*v4.PresignedHTTPRequest, error)
+}
+
+// listPageSize is the number of objects returned per ListObjects page
+const listPageSize = 1000
+
+// maxRetryDelay caps the backoff between retries of transient errors
+const maxRetryDelay = 5 * time.Second
+
+// maxPresignExpiry is the longest lifetime S3 accepts for presigned URLs
+const maxPresignExpiry = 7 * 24 * time.Hour
+
 // S3Service implements StorageService for AWS S3
 type S3Service struct {
-	client *s3.Client
-	bucket string
+	client    S3API
+	presigner S3Presigner
+	bucket    string
+	// tenantBuckets maps tenant IDs to their own buckets; other tenants use bucket
+	tenantBuckets map[string]string
+	// prefixBuckets routes keys to buckets by prefix, longest prefix first
+	prefixBuckets []prefixBucket
+	// sseCustomerKey and sseCustomerKeyMD5 are the base64-encoded SSE-C key
+	// objects are read with and its MD5 digest; nil without SSE-C
+	sseCustomerKey    *string
+	sseCustomerKeyMD5 *string
+	// retryAttempts and retryBaseDelay configure retries of reads failing
+	// with transient errors
+	retryAttempts  int
+	retryBaseDelay time.Duration
+	// defaultContentType is the content type of objects whose type is
+	// neither reported nor known from their extension
+	defaultContentType string
 }
 
-// NewS3Service creates a new S3 service
-func NewS3Service(client *s3.Client, bucket string) *S3Service {
-	return &S3Service{
+// NewS3Service creates a new S3 service. Presigned URLs are available when
+// client is an *s3.Client.
+func NewS3Service(client S3API, bucket string) *S3Service {
+	s := &S3Service{
 		client: client,
 		bucket: bucket,
 	}
+	if c, ok := client.(*s3.Client); ok {
+		s.presigner = s3.NewPresignClient(c)
+	}
+	return s
+}
+
+// WithTenantBuckets stores the objects of each listed tenant in its own bucket
+// and returns s; tenants not listed share the default bucket
+func (s *S3Service) WithTenantBuckets(buckets map[string]string) *S3Service {
+	s.tenantBuckets = buckets
+	return s
+}
+
+// prefixBucket routes the keys starting with prefix to bucket
+type prefixBucket struct {
+	prefix string
+	bucket string
+}
+
+// WithPrefixBuckets stores the objects whose keys start with a listed prefix
+// in its bucket and returns s. The longest matching prefix wins; other keys,
+// and all keys of tenants with their own bucket, use the default bucket of
+// their tenant. Keys are routed as given, so they must have passed path
+// validation first.
+func (s *S3Service) WithPrefixBuckets(buckets map[string]string) *S3Service {
+	s.prefixBuckets = make([]prefixBucket, 0, len(buckets))
+	for prefix, bucket := range buckets {
+		s.prefixBuckets = append(s.prefixBuckets, prefixBucket{prefix: prefix, bucket: bucket})
+	}
+	sort.Slice(s.prefixBuckets, func(i, j int) bool {
+		return len(s.prefixBuckets[i].prefix) > len(s.prefixBuckets[j].prefix)
+	})
+	return s
+}
+
+// WithSSECustomerKey reads objects encrypted with SSE-C under the 256-bit
+// key and returns s. S3 rejects SSE-C parameters for objects stored without
+// them, so every object read must be encrypted with the key.
+func (s *S3Service) WithSSECustomerKey(key []byte) *S3Service {
+	digest := md5.Sum(key) // #nosec G401 -- S3 requires the MD5 of SSE-C keys
+	s.sseCustomerKey = aws.String(base64.StdEncoding.EncodeToString(key))
+	s.sseCustomerKeyMD5 = aws.String(base64.StdEncoding.EncodeToString(digest[:]))
+	return s
+}
+
+// sseCustomerAlgorithm returns the SSE-C algorithm sent with reads, or nil
+// without SSE-C
+func (s *S3Service) sseCustomerAlgorithm() *string {
+	if s.sseCustomerKey == nil {
+		return nil
+	}
+	return aws.String(string(types.ServerSideEncryptionAes256))
+}
+
+// WithRetry retries object reads failing with transient errors, such as 503
+// SlowDown, up to maxAttempts attempts in total and returns s. Retries back
+// off exponentially from baseDelay with full jitter; maxAttempts of one or
+// less disables retries. The reads are then made with the retries of the S3
+// client disabled, so that maxAttempts bounds the attempts rather than
+// multiplying those of the client.
+func (s *S3Service) WithRetry(maxAttempts int, baseDelay time.Duration) *S3Service {
+	s.retryAttempts = maxAttempts
+	s.retryBaseDelay = baseDelay
+	return s
+}
+
+// WithDefaultContentType serves objects whose type S3 does not report and
+// their extension does not tell as contentType and returns s; empty keeps
+// application/octet-stream
+func (s *S3Service) WithDefaultContentType(contentType string) *S3Service {
+	s.defaultContentType = contentType
+	return s
+}
+
+// bucketFor returns the bucket storing key for the tenant ctx is scoped to
+func (s *S3Service) bucketFor(ctx context.Context, key string) string {
+	if bucket, ok := s.tenantBuckets[domain.TenantFromContext(ctx)]; ok {
+		return bucket
+	}
+	for _, route := range s.prefixBuckets {
+		if strings.HasPrefix(key, route.prefix) {
+			return route.bucket
+		}
+	}
+	return s.bucket
+}
+
+// objectVersion returns the version ID pinned by ctx, or nil to address the
+// latest version
+func objectVersion(ctx context.Context) *string {
+	if versionID := domain.ObjectVersionFromContext(ctx); versionID != "" {
+		return aws.String(versionID)
+	}
+	return nil
 }
 
 // GetObject retrieves an object from S3
 func (s *S3Service) GetObject(ctx context.Context, key string) (domain.ObjectReader, error) {
-	result, err := s.client.GetObject(ctx, &s3.GetObjectInput{
-		Bucket: aws.String(s.bucket),
-		Key:    aws.String(key),
+	return s.getObject(ctx, &s3.GetObjectInput{
+		Bucket:               aws.String(s.bucketFor(ctx, key)),
+		Key:                  aws.String(key),
+		VersionId:            objectVersion(ctx),
+		ChecksumMode:         types.ChecksumModeEnabled,
+		SSECustomerAlgorithm: s.sseCustomerAlgorithm(),
+		SSECustomerKey:       s.sseCustomerKey,
+		SSECustomerKeyMD5:    s.sseCustomerKeyMD5,
+	})
+}
+
+// GetObjectRange retrieves the inclusive byte range [start, end] of an object from S3
+func (s *S3Service) GetObjectRange(ctx context.Context, key string, start, end int64) (domain.ObjectReader, error) {
+	return s.getObject(ctx, &s3.GetObjectInput{
+		Bucket:               aws.String(s.bucketFor(ctx, key)),
+		Key:                  aws.String(key),
+		Range:                aws.String(fmt.Sprintf("bytes=%d-%d", start, end)),
+		VersionId:            objectVersion(ctx),
+		SSECustomerAlgorithm: s.sseCustomerAlgorithm(),
+		SSECustomerKey:       s.sseCustomerKey,
+		SSECustomerKeyMD5:    s.sseCustomerKeyMD5,
+	})
+}
+
+// getObject issues a GetObject call and wraps the result as an ObjectReader
+func (s *S3Service) getObject(ctx context.Context, input *s3.GetObjectInput) (domain.ObjectReader, error) {
+	var result *s3.GetObjectOutput
+	err := s.retry(ctx, func() (err error) {
+		result, err = s.client.GetObject(ctx, input, s.readOptions()...)
+		return err
 	})
 	if err != nil {
+		if isS3NotFound(err) {
+			return nil, domain.ErrNotFound
+		}
 		return nil, fmt.Errorf("failed to get object from S3: %w", err)
 	}
 
-	contentType := "application/octet-stream"
-	if result.ContentType != nil {
-		contentType = *result.ContentType
-	}
+	contentType := s.objectContentType(aws.ToString(input.Key), result.ContentType)
 
-	var size int64
+	size := int64(-1)
 	if result.ContentLength != nil {
 		size = *result.ContentLength
 	}
 
 	return &s3ObjectReader{
-		body:        result.Body,
-		contentType: contentType,
-		size:        size,
+		body:         result.Body,
+		contentType:  contentType,
+		size:         size,
+		etag:         aws.ToString(result.ETag),
+		lastModified: aws.ToTime(result.LastModified),
+		checksum:     objectChecksum(result.ChecksumSHA256, result.ChecksumType),
 	}, nil
 }
 
+// objectChecksum returns the SHA-256 S3 recorded for an object, hex-encoded.
+// Checksums combining the checksums of a multipart object's parts are no hash
+// of its content and are dropped, as are objects uploaded without one.
+func objectChecksum(checksum *string, checksumType types.ChecksumType) string {
+	if checksumType == types.ChecksumTypeComposite {
+		return ""
+	}
+	// Composite checksums also carry a "-{parts}" suffix that fails decoding
+	decoded, err := base64.StdEncoding.DecodeString(aws.ToString(checksum))
+	if err != nil || len(decoded) != sha256.Size {
+		return ""
+	}
+	return hex.EncodeToString(decoded)
+}
+
+// objectContentType returns the content type S3 reported for key, guessing
+// it from the key's extension when S3 reported none or only the generic
+// application/octet-stream of objects uploaded without a content type, and
+// falling back to the default content type when the extension is unknown
+func (s *S3Service) objectContentType(key string, reported *string) string {
+	if contentType := aws.ToString(reported); contentType != "" && contentType != "application/octet-stream" {
+		return contentType
+	}
+	if contentType := mime.TypeByExtension(path.Ext(key)); contentType != "" {
+		return contentType
+	}
+	if s.defaultContentType != "" {
+		return s.defaultContentType
+	}
+	return "application/octet-stream"
+}
+
 // HeadObject retrieves object metadata from S3
 func (s *S3Service) HeadObject(ctx context.Context, key string) (*domain.ObjectMetadata, error) {
-	result, err := s.client.HeadObject(ctx, &s3.HeadObjectInput{
-		Bucket: aws.String(s.bucket),
-		Key:    aws.String(key),
+	var result *s3.HeadObjectOutput
+	err := s.retry(ctx, func() (err error) {
+		result, err = s.client.HeadObject(ctx, &s3.HeadObjectInput{
+			Bucket:               aws.String(s.bucketFor(ctx, key)),
+			Key:                  aws.String(key),
+			VersionId:            objectVersion(ctx),
+			ChecksumMode:         types.ChecksumModeEnabled,
+			SSECustomerAlgorithm: s.sseCustomerAlgorithm(),
+			SSECustomerKey:       s.sseCustomerKey,
+			SSECustomerKeyMD5:    s.sseCustomerKeyMD5,
+		}, s.readOptions()...)
+		return err
 	})
 	if err != nil {
+		if isS3NotFound(err) {
+			return nil, domain.ErrNotFound
+		}
 		return nil, fmt.Errorf("failed to head object from S3: %w", err)
 	}
 
 	metadata := &domain.ObjectMetadata{
-		Size: 0,
-	}
-
-	if result.ContentType != nil {
-		metadata.ContentType = *result.ContentType
+		Key:         key,
+		ContentType: s.objectContentType(key, result.ContentType),
+		Size:        -1,
 	}
 
 	if result.ContentLength != nil {
@@ -100,5 +337,218 @@ func (s *S3Service) HeadObject(ctx context.Context, key string) (*domain.ObjectM
 		metadata.LastModified = *result.LastModified
 	}
 
+	if result.ETag != nil {
+		metadata.ETag = *result.ETag
+	}
+
+	metadata.Checksum = objectChecksum(result.ChecksumSHA256, result.ChecksumType)
+
 	return metadata, nil
 }
+
+// DeleteObject removes an object from S3. S3 deletes are idempotent, so the
+// object is looked up first to report ErrNotFound for missing keys.
+func (s *S3Service) DeleteObject(ctx context.Context, key string) error {
+	if _, err := s.HeadObject(ctx, key); err != nil {
+		return err
+	}
+
+	_, err := s.client.DeleteObject(ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(s.bucketFor(ctx, key)),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		if isS3NotFound(err) {
+			return domain.ErrNotFound
+		}
+		return fmt.Errorf("failed to delete object from S3: %w", err)
+	}
+	return nil
+}
+
+// ListObjects returns a page of objects under prefix using ListObjectsV2,
+// passing token through as the continuation token. Only the bucket prefix
+// routes to is listed.
+func (s *S3Service) ListObjects(ctx context.Context, prefix, token string) ([]domain.ObjectMetadata, string, error) {
+	input := &s3.ListObjectsV2Input{
+		Bucket:  aws.String(s.bucketFor(ctx, prefix)),
+		MaxKeys: aws.Int32(listPageSize),
+	}
+	if prefix != "" {
+		input.Prefix = aws.String(prefix)
+	}
+	if token != "" {
+		input.ContinuationToken = aws.String(token)
+	}
+
+	result, err := s.client.ListObjectsV2(ctx, input)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to list objects in S3: %w", err)
+	}
+
+	objects := make([]domain.ObjectMetadata, 0, len(result.Contents))
+	for _, object := range result.Contents {
+		objects = append(objects, domain.ObjectMetadata{
+			Key:          aws.ToString(object.Key),
+			Size:         aws.ToInt64(object.Size),
+			LastModified: aws.ToTime(object.LastModified),
+			ETag:         aws.ToString(object.ETag),
+		})
+	}
+
+	var nextToken string
+	if aws.ToBool(result.IsTruncated) {
+		nextToken = aws.ToString(result.NextContinuationToken)
+	}
+
+	return objects, nextToken, nil
+}
+
+// PresignGetObject returns a presigned GET URL for an object valid for expiry,
+// capped at the seven days S3 allows. Browsers following the URL cannot send
+// an SSE-C key, so it is unavailable with one.
+func (s *S3Service) PresignGetObject(ctx context.Context, key string, expiry time.Duration) (string, error) {
+	if s.presigner == nil || s.sseCustomerKey != nil {
+		return "", domain.ErrPresignNotSupported
+	}
+	if expiry > maxPresignExpiry {
+		expiry = maxPresignExpiry
+	}
+
+	req, err := s.presigner.PresignGetObject(ctx, &s3.GetObjectInput{
+		Bucket:    aws.String(s.bucketFor(ctx, key)),
+		Key:       aws.String(key),
+		VersionId: objectVersion(ctx),
+	}, s3.WithPresignExpires(expiry))
+	if err != nil {
+		return "", fmt.Errorf("failed to presign S3 object: %w", err)
+	}
+	return req.URL, nil
+}
+
+// PresignPutObject returns a presigned PUT URL uploading an object, valid for
+// expiry capped at the seven days S3 allows. It is unavailable with an SSE-C
+// key, as uploads would store objects the key cannot read.
+func (s *S3Service) PresignPutObject(ctx context.Context, key string, expiry time.Duration) (string, error) {
+	if s.presigner == nil || s.sseCustomerKey != nil {
+		return "", domain.ErrPresignNotSupported
+	}
+	if expiry > maxPresignExpiry {
+		expiry = maxPresignExpiry
+	}
+
+	req, err := s.presigner.PresignPutObject(ctx, &s3.PutObjectInput{
+		Bucket: aws.String(s.bucketFor(ctx, key)),
+		Key:    aws.String(key),
+	}, s3.WithPresignExpires(expiry))
+	if err != nil {
+		return "", fmt.Errorf("failed to presign S3 upload: %w", err)
+	}
+	return req.URL, nil
+}
+
+// HealthCheck verifies the bucket is reachable
+func (s *S3Service) HealthCheck(ctx context.Context) error {
+	_, err := s.client.HeadBucket(ctx, &s3.HeadBucketInput{
+		Bucket: aws.String(s.bucketFor(ctx, "")),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to head bucket in S3: %w", err)
+	}
+	return nil
+}
+
+// readOptions returns the S3 client options of object reads, which leave
+// retries to retry when WithRetry enabled them
+func (s *S3Service) readOptions() []func(*s3.Options) {
+	if s.retryAttempts <= 1 {
+		return nil
+	}
+	return []func(*s3.Options){func(o *s3.Options) { o.RetryMaxAttempts = 1 }}
+}
+
+// retry calls fn until it succeeds, fails with an error that is not
+// transient, or runs out of attempts. It stops early when ctx is done or its
+// deadline would pass during the next backoff, returning the last error.
+func (s *S3Service) retry(ctx context.Context, fn func() error) error {
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil || attempt >= s.retryAttempts || !isS3Retryable(err) {
+			return err
+		}
+
+		delay := retryDelay(s.retryBaseDelay, attempt)
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < delay {
+			return err
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+}
+
+// retryDelay returns a random backoff before the retry following attempt,
+// up to baseDelay doubled per attempt and capped at maxRetryDelay
+func retryDelay(baseDelay time.Duration, attempt int) time.Duration {
+	ceiling := maxRetryDelay
+	if shift := attempt - 1; shift < 32 && baseDelay<<shift < maxRetryDelay {
+		ceiling = baseDelay << shift
+	}
+	if ceiling <= 0 {
+		return 0
+	}
+	return rand.N(ceiling + 1)
+}
+
+// isS3Retryable reports whether err is a transient S3 error worth retrying:
+// throttling, internal errors and timeouts, or a 5xx response. Missing
+// objects and denied access are never retried.
+func isS3Retryable(err error) bool {
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) {
+		switch apiErr.ErrorCode() {
+		case "SlowDown", "ServiceUnavailable", "InternalError", "RequestTimeout", "Throttling", "ThrottlingException":
+			return true
+		}
+	}
+
+	var statusErr interface{ HTTPStatusCode() int }
+	if errors.As(err, &statusErr) {
+		switch statusErr.HTTPStatusCode() {
+		case http.StatusInternalServerError, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+			return true
+		}
+	}
+
+	return false
+}
+
+// isS3NotFound reports whether err is an S3 missing-object error. GetObject
+// returns NoSuchKey, or NoSuchVersion for a missing version, while
+// HeadObject, having no body, returns a bare NotFound.
+func isS3NotFound(err error) bool {
+	var noSuchKey *types.NoSuchKey
+	if errors.As(err, &noSuchKey) {
+		return true
+	}
+
+	var notFound *types.NotFound
+	if errors.As(err, &notFound) {
+		return true
+	}
+
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) {
+		switch apiErr.ErrorCode() {
+		case "NoSuchKey", "NoSuchVersion", "NotFound":
+			return true
+		}
+	}
+
+	return false
+}
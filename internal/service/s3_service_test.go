@@ -0,0 +1,905 @@
+package service
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/url"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
+	"github.com/vchitai/go-s3-sharing/internal/domain"
+)
+
+// stubS3API is a stub implementation of S3API
+type stubS3API struct {
+	getOutput  *s3.GetObjectOutput
+	headOutput *s3.HeadObjectOutput
+	err        error
+	// errs are returned by successive GetObject and HeadObject calls before err
+	errs []error
+	// calls counts GetObject and HeadObject calls
+	calls   int
+	deleted []string
+	// listPages maps continuation tokens to ListObjectsV2 pages; "" is the first
+	listPages  map[string]*s3.ListObjectsV2Output
+	listInputs []*s3.ListObjectsV2Input
+	// getInputs and headInputs record GetObject and HeadObject calls
+	getInputs  []*s3.GetObjectInput
+	headInputs []*s3.HeadObjectInput
+	// bucketInputs records HeadBucket calls
+	bucketInputs []*s3.HeadBucketInput
+	// retryMaxAttempts records the client retry attempts of GetObject and
+	// HeadObject calls
+	retryMaxAttempts []int
+}
+
+func (s *stubS3API) GetObject(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
+	s.getInputs = append(s.getInputs, params)
+	s.recordOptions(optFns)
+	return s.getOutput, s.nextErr()
+}
+
+func (s *stubS3API) HeadObject(ctx context.Context, params *s3.HeadObjectInput, optFns ...func(*s3.Options)) (*s3.HeadObjectOutput, error) {
+	s.headInputs = append(s.headInputs, params)
+	s.recordOptions(optFns)
+	return s.headOutput, s.nextErr()
+}
+
+// recordOptions records the client options of a call
+func (s *stubS3API) recordOptions(optFns []func(*s3.Options)) {
+	var o s3.Options
+	for _, fn := range optFns {
+		fn(&o)
+	}
+	s.retryMaxAttempts = append(s.retryMaxAttempts, o.RetryMaxAttempts)
+}
+
+// nextErr counts a call and returns its error
+func (s *stubS3API) nextErr() error {
+	s.calls++
+	if len(s.errs) > 0 {
+		err := s.errs[0]
+		s.errs = s.errs[1:]
+		return err
+	}
+	return s.err
+}
+
+func (s *stubS3API) HeadBucket(ctx context.Context, params *s3.HeadBucketInput, optFns ...func(*s3.Options)) (*s3.HeadBucketOutput, error) {
+	s.bucketInputs = append(s.bucketInputs, params)
+	return &s3.HeadBucketOutput{}, s.err
+}
+
+func (s *stubS3API) ListObjectsV2(ctx context.Context, params *s3.ListObjectsV2Input, optFns ...func(*s3.Options)) (*s3.ListObjectsV2Output, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	s.listInputs = append(s.listInputs, params)
+	page, exists := s.listPages[aws.ToString(params.ContinuationToken)]
+	if !exists {
+		return nil, errors.New("unknown continuation token")
+	}
+	return page, nil
+}
+
+func (s *stubS3API) DeleteObject(ctx context.Context, params *s3.DeleteObjectInput, optFns ...func(*s3.Options)) (*s3.DeleteObjectOutput, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	s.deleted = append(s.deleted, aws.ToString(params.Key))
+	return &s3.DeleteObjectOutput{}, nil
+}
+
+func TestS3Service_NotFoundErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		notFound bool
+	}{
+		{name: "NoSuchKey", err: &types.NoSuchKey{}, notFound: true},
+		{name: "NotFound", err: &types.NotFound{}, notFound: true},
+		{name: "generic NotFound code", err: &smithy.GenericAPIError{Code: "NotFound"}, notFound: true},
+		{name: "access denied", err: &smithy.GenericAPIError{Code: "AccessDenied"}},
+		{name: "network error", err: errors.New("connection reset")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storage := NewS3Service(&stubS3API{err: tt.err}, "test-bucket")
+
+			_, getErr := storage.GetObject(context.Background(), "images/photo.jpg")
+			_, headErr := storage.HeadObject(context.Background(), "images/photo.jpg")
+
+			for op, err := range map[string]error{"GetObject": getErr, "HeadObject": headErr} {
+				if tt.notFound {
+					if !errors.Is(err, domain.ErrNotFound) {
+						t.Errorf("%s: expected %v, got %v", op, domain.ErrNotFound, err)
+					}
+					continue
+				}
+				if errors.Is(err, domain.ErrNotFound) || !errors.Is(err, tt.err) {
+					t.Errorf("%s: expected wrapped %v, got %v", op, tt.err, err)
+				}
+			}
+		})
+	}
+}
+
+func TestS3Service_GetObject(t *testing.T) {
+	storage := NewS3Service(&stubS3API{getOutput: &s3.GetObjectOutput{
+		Body:          io.NopCloser(strings.NewReader("jpeg")),
+		ContentType:   aws.String("image/jpeg"),
+		ContentLength: aws.Int64(4),
+		ETag:          aws.String(`"abc"`),
+	}}, "test-bucket")
+
+	reader, err := storage.GetObject(context.Background(), "images/photo.jpg")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer reader.Close()
+
+	if reader.ContentType() != "image/jpeg" || reader.Size() != 4 || reader.ETag() != `"abc"` {
+		t.Errorf("unexpected reader attrs: %s %d %s", reader.ContentType(), reader.Size(), reader.ETag())
+	}
+}
+
+func TestS3Service_UnknownContentLength(t *testing.T) {
+	storage := NewS3Service(&stubS3API{
+		getOutput:  &s3.GetObjectOutput{Body: io.NopCloser(strings.NewReader("jpeg")), ContentType: aws.String("image/jpeg")},
+		headOutput: &s3.HeadObjectOutput{ContentType: aws.String("image/jpeg")},
+	}, "test-bucket")
+
+	reader, err := storage.GetObject(context.Background(), "images/photo.jpg")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer reader.Close()
+	if reader.Size() != -1 {
+		t.Errorf("expected size -1 without a content length, got %d", reader.Size())
+	}
+
+	metadata, err := storage.HeadObject(context.Background(), "images/photo.jpg")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if metadata.Size != -1 {
+		t.Errorf("expected metadata size -1 without a content length, got %d", metadata.Size)
+	}
+}
+
+func TestS3Service_PresignGetObject(t *testing.T) {
+	client := s3.New(s3.Options{
+		Region:       "us-east-1",
+		BaseEndpoint: aws.String("https://s3.example.com"),
+		UsePathStyle: true,
+		Credentials:  credentials.NewStaticCredentialsProvider("AKIDEXAMPLE", "secret", ""),
+	})
+	storage := NewS3Service(client, "test-bucket")
+
+	tests := []struct {
+		name            string
+		expiry          time.Duration
+		expectedExpires string
+	}{
+		{name: "requested expiry", expiry: time.Hour, expectedExpires: "3600"},
+		{name: "clamped to seven days", expiry: 30 * 24 * time.Hour, expectedExpires: "604800"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			presigned, err := storage.PresignGetObject(context.Background(), "images/photo.jpg", tt.expiry)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			u, err := url.Parse(presigned)
+			if err != nil {
+				t.Fatalf("invalid presigned URL %q: %v", presigned, err)
+			}
+			if u.Host != "s3.example.com" || u.Path != "/test-bucket/images/photo.jpg" {
+				t.Errorf("unexpected presigned URL %s", presigned)
+			}
+			if got := u.Query().Get("X-Amz-Expires"); got != tt.expectedExpires {
+				t.Errorf("expected X-Amz-Expires %s, got %s", tt.expectedExpires, got)
+			}
+			if u.Query().Get("X-Amz-Signature") == "" {
+				t.Error("expected a signature in the presigned URL")
+			}
+		})
+	}
+}
+
+func TestS3Service_PresignGetObjectUnsupported(t *testing.T) {
+	storage := NewS3Service(&stubS3API{}, "test-bucket")
+
+	if _, err := storage.PresignGetObject(context.Background(), "images/photo.jpg", time.Hour); !errors.Is(err, domain.ErrPresignNotSupported) {
+		t.Errorf("expected %v, got %v", domain.ErrPresignNotSupported, err)
+	}
+}
+
+func TestS3Service_PresignPutObject(t *testing.T) {
+	client := s3.New(s3.Options{
+		Region:       "us-east-1",
+		BaseEndpoint: aws.String("https://s3.example.com"),
+		UsePathStyle: true,
+		Credentials:  credentials.NewStaticCredentialsProvider("AKIDEXAMPLE", "secret", ""),
+	})
+	storage := NewS3Service(client, "test-bucket")
+
+	presigned, err := storage.PresignPutObject(context.Background(), "uploads/report.pdf", 15*time.Minute)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	u, err := url.Parse(presigned)
+	if err != nil {
+		t.Fatalf("invalid presigned URL %q: %v", presigned, err)
+	}
+	if u.Scheme != "https" || u.Host != "s3.example.com" || u.Path != "/test-bucket/uploads/report.pdf" {
+		t.Errorf("unexpected presigned URL %s", presigned)
+	}
+
+	query := u.Query()
+	if got := query.Get("x-id"); got != "PutObject" {
+		t.Errorf("expected a PutObject URL, got x-id %q", got)
+	}
+	if got := query.Get("X-Amz-Algorithm"); got != "AWS4-HMAC-SHA256" {
+		t.Errorf("expected SigV4 algorithm, got %q", got)
+	}
+	if got := query.Get("X-Amz-Expires"); got != "900" {
+		t.Errorf("expected X-Amz-Expires 900, got %s", got)
+	}
+	if !strings.HasPrefix(query.Get("X-Amz-Credential"), "AKIDEXAMPLE/") {
+		t.Errorf("unexpected credential %q", query.Get("X-Amz-Credential"))
+	}
+	if query.Get("X-Amz-Signature") == "" {
+		t.Error("expected a signature in the presigned URL")
+	}
+}
+
+func TestS3Service_DeleteObject(t *testing.T) {
+	t.Run("existing object", func(t *testing.T) {
+		client := &stubS3API{headOutput: &s3.HeadObjectOutput{}}
+		storage := NewS3Service(client, "test-bucket")
+
+		if err := storage.DeleteObject(context.Background(), "images/photo.jpg"); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(client.deleted) != 1 || client.deleted[0] != "images/photo.jpg" {
+			t.Errorf("expected images/photo.jpg to be deleted, got %v", client.deleted)
+		}
+	})
+
+	t.Run("missing object", func(t *testing.T) {
+		client := &stubS3API{err: &types.NotFound{}}
+		storage := NewS3Service(client, "test-bucket")
+
+		if err := storage.DeleteObject(context.Background(), "images/missing.jpg"); !errors.Is(err, domain.ErrNotFound) {
+			t.Errorf("expected %v, got %v", domain.ErrNotFound, err)
+		}
+		if len(client.deleted) != 0 {
+			t.Errorf("expected no delete call, got %v", client.deleted)
+		}
+	})
+}
+
+func TestS3Service_ListObjects(t *testing.T) {
+	modified := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	client := &stubS3API{listPages: map[string]*s3.ListObjectsV2Output{
+		"": {
+			Contents: []types.Object{
+				{Key: aws.String("images/a.jpg"), Size: aws.Int64(10), LastModified: aws.Time(modified)},
+				{Key: aws.String("images/b.jpg"), Size: aws.Int64(20), LastModified: aws.Time(modified)},
+			},
+			IsTruncated:           aws.Bool(true),
+			NextContinuationToken: aws.String("page-2"),
+		},
+		"page-2": {
+			Contents: []types.Object{
+				{Key: aws.String("images/c.jpg"), Size: aws.Int64(30), LastModified: aws.Time(modified)},
+			},
+			IsTruncated: aws.Bool(false),
+		},
+	}}
+	storage := NewS3Service(client, "test-bucket")
+	ctx := context.Background()
+
+	var keys []string
+	token := ""
+	for pages := 0; ; pages++ {
+		if pages > 2 {
+			t.Fatal("pagination did not terminate")
+		}
+
+		objects, next, err := storage.ListObjects(ctx, "images/", token)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		for _, object := range objects {
+			if !object.LastModified.Equal(modified) || object.Size == 0 {
+				t.Errorf("unexpected metadata for %s: %+v", object.Key, object)
+			}
+			keys = append(keys, object.Key)
+		}
+		if next == "" {
+			break
+		}
+		token = next
+	}
+
+	if strings.Join(keys, ",") != "images/a.jpg,images/b.jpg,images/c.jpg" {
+		t.Errorf("unexpected keys %v", keys)
+	}
+	for _, input := range client.listInputs {
+		if aws.ToString(input.Bucket) != "test-bucket" || aws.ToString(input.Prefix) != "images/" {
+			t.Errorf("unexpected ListObjectsV2 input bucket %q prefix %q", aws.ToString(input.Bucket), aws.ToString(input.Prefix))
+		}
+	}
+	if len(client.listInputs) != 2 || client.listInputs[1].ContinuationToken == nil {
+		t.Errorf("expected the second call to carry the continuation token")
+	}
+}
+
+func TestS3Service_HealthCheck(t *testing.T) {
+	t.Run("reachable bucket", func(t *testing.T) {
+		client := &stubS3API{}
+		storage := NewS3Service(client, "shared-bucket").WithTenantBuckets(map[string]string{
+			"acme": "acme-bucket",
+		})
+
+		if err := storage.HealthCheck(context.Background()); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if err := storage.HealthCheck(domain.WithTenant(context.Background(), "acme")); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(client.bucketInputs) != 2 ||
+			aws.ToString(client.bucketInputs[0].Bucket) != "shared-bucket" ||
+			aws.ToString(client.bucketInputs[1].Bucket) != "acme-bucket" {
+			t.Errorf("expected HeadBucket on shared-bucket then acme-bucket, got %v", client.bucketInputs)
+		}
+	})
+
+	t.Run("unreachable bucket", func(t *testing.T) {
+		headErr := &smithy.GenericAPIError{Code: "AccessDenied"}
+		storage := NewS3Service(&stubS3API{err: headErr}, "test-bucket")
+
+		if err := storage.HealthCheck(context.Background()); !errors.Is(err, headErr) {
+			t.Errorf("expected wrapped %v, got %v", headErr, err)
+		}
+	})
+}
+
+func TestS3Service_TenantBuckets(t *testing.T) {
+	client := &stubS3API{listPages: map[string]*s3.ListObjectsV2Output{"": {}}}
+	storage := NewS3Service(client, "shared-bucket").WithTenantBuckets(map[string]string{
+		"acme": "acme-bucket",
+	})
+
+	tests := []struct {
+		name   string
+		tenant string
+		bucket string
+	}{
+		{name: "default tenant", tenant: "", bucket: "shared-bucket"},
+		{name: "mapped tenant", tenant: "acme", bucket: "acme-bucket"},
+		{name: "unmapped tenant", tenant: "globex", bucket: "shared-bucket"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client.listInputs = nil
+			ctx := domain.WithTenant(context.Background(), tt.tenant)
+
+			if _, _, err := storage.ListObjects(ctx, "", ""); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(client.listInputs) != 1 || aws.ToString(client.listInputs[0].Bucket) != tt.bucket {
+				t.Errorf("expected bucket %s, got %v", tt.bucket, client.listInputs)
+			}
+		})
+	}
+}
+
+func TestS3Service_PrefixBuckets(t *testing.T) {
+	client := &stubS3API{}
+	storage := NewS3Service(client, "shared-bucket").
+		WithTenantBuckets(map[string]string{"acme": "acme-bucket"}).
+		WithPrefixBuckets(map[string]string{
+			"images/":         "images-bucket",
+			"images/banners/": "banners-bucket",
+			"videos/":         "videos-bucket",
+		})
+
+	tests := []struct {
+		name   string
+		tenant string
+		key    string
+		bucket string
+	}{
+		{name: "routed prefix", key: "videos/clip.mp4", bucket: "videos-bucket"},
+		{name: "longest prefix wins", key: "images/banners/top.png", bucket: "banners-bucket"},
+		{name: "shorter prefix", key: "images/photo.jpg", bucket: "images-bucket"},
+		{name: "prefix must match from the start", key: "archive/images/photo.jpg", bucket: "shared-bucket"},
+		{name: "unrouted key falls back to default", key: "docs/report.pdf", bucket: "shared-bucket"},
+		{name: "tenant bucket overrides routes", tenant: "acme", key: "videos/clip.mp4", bucket: "acme-bucket"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client.getInputs, client.headInputs = nil, nil
+			client.getOutput = &s3.GetObjectOutput{Body: io.NopCloser(strings.NewReader("data"))}
+			client.headOutput = &s3.HeadObjectOutput{}
+			ctx := domain.WithTenant(context.Background(), tt.tenant)
+
+			reader, err := storage.GetObject(ctx, tt.key)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			reader.Close()
+			if _, err := storage.HeadObject(ctx, tt.key); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if got := aws.ToString(client.getInputs[0].Bucket); got != tt.bucket {
+				t.Errorf("GetObject: expected bucket %s, got %s", tt.bucket, got)
+			}
+			if got := aws.ToString(client.headInputs[0].Bucket); got != tt.bucket {
+				t.Errorf("HeadObject: expected bucket %s, got %s", tt.bucket, got)
+			}
+			if got := aws.ToString(client.getInputs[0].Key); got != tt.key {
+				t.Errorf("expected the key to be kept as %s, got %s", tt.key, got)
+			}
+		})
+	}
+
+	t.Run("listing uses the bucket of its prefix", func(t *testing.T) {
+		client.listPages = map[string]*s3.ListObjectsV2Output{"": {}}
+		client.listInputs = nil
+
+		if _, _, err := storage.ListObjects(context.Background(), "videos/2024/", ""); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(client.listInputs) != 1 || aws.ToString(client.listInputs[0].Bucket) != "videos-bucket" {
+			t.Errorf("expected bucket videos-bucket, got %v", client.listInputs)
+		}
+	})
+
+	t.Run("traversal is rejected before routing", func(t *testing.T) {
+		client.getInputs = nil
+		service := NewShareService(storage, &mockCacheService{store: make(map[string]string)}, &ShareConfig{BaseURL: "https://example.com"})
+
+		if _, err := service.GetObject(context.Background(), "images/../../videos/clip.mp4"); !errors.Is(err, domain.ErrInvalidPath) {
+			t.Errorf("expected %v, got %v", domain.ErrInvalidPath, err)
+		}
+		if len(client.getInputs) != 0 {
+			t.Errorf("expected no S3 calls, got %d", len(client.getInputs))
+		}
+	})
+}
+
+func TestS3Service_SSECustomerKey(t *testing.T) {
+	key := []byte("0123456789abcdef0123456789abcdef")
+	digest := md5.Sum(key)
+	expectedKey := base64.StdEncoding.EncodeToString(key)
+	expectedMD5 := base64.StdEncoding.EncodeToString(digest[:])
+
+	client := &stubS3API{
+		getOutput:  &s3.GetObjectOutput{Body: io.NopCloser(strings.NewReader("data"))},
+		headOutput: &s3.HeadObjectOutput{},
+	}
+	storage := NewS3Service(client, "test-bucket").WithSSECustomerKey(key)
+	ctx := context.Background()
+
+	if _, err := storage.GetObject(ctx, "secure/report.pdf"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := storage.GetObjectRange(ctx, "secure/report.pdf", 0, 1); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := storage.HeadObject(ctx, "secure/report.pdf"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	type sseParams struct{ algorithm, key, keyMD5 *string }
+	calls := map[string]sseParams{
+		"GetObject":      {client.getInputs[0].SSECustomerAlgorithm, client.getInputs[0].SSECustomerKey, client.getInputs[0].SSECustomerKeyMD5},
+		"GetObjectRange": {client.getInputs[1].SSECustomerAlgorithm, client.getInputs[1].SSECustomerKey, client.getInputs[1].SSECustomerKeyMD5},
+		"HeadObject":     {client.headInputs[0].SSECustomerAlgorithm, client.headInputs[0].SSECustomerKey, client.headInputs[0].SSECustomerKeyMD5},
+	}
+	for op, params := range calls {
+		if aws.ToString(params.algorithm) != "AES256" {
+			t.Errorf("%s: expected algorithm AES256, got %q", op, aws.ToString(params.algorithm))
+		}
+		if aws.ToString(params.key) != expectedKey {
+			t.Errorf("%s: expected key %s, got %q", op, expectedKey, aws.ToString(params.key))
+		}
+		if aws.ToString(params.keyMD5) != expectedMD5 {
+			t.Errorf("%s: expected key MD5 %s, got %q", op, expectedMD5, aws.ToString(params.keyMD5))
+		}
+	}
+
+	if _, err := storage.PresignGetObject(ctx, "secure/report.pdf", time.Hour); !errors.Is(err, domain.ErrPresignNotSupported) {
+		t.Errorf("expected %v for presigned reads, got %v", domain.ErrPresignNotSupported, err)
+	}
+
+	t.Run("without a key", func(t *testing.T) {
+		client.getInputs, client.headInputs = nil, nil
+		plain := NewS3Service(client, "test-bucket")
+
+		if _, err := plain.GetObject(ctx, "images/photo.jpg"); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if _, err := plain.HeadObject(ctx, "images/photo.jpg"); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		get, head := client.getInputs[0], client.headInputs[0]
+		if get.SSECustomerAlgorithm != nil || get.SSECustomerKey != nil || get.SSECustomerKeyMD5 != nil {
+			t.Errorf("expected no SSE-C parameters on GetObject, got %+v", get)
+		}
+		if head.SSECustomerAlgorithm != nil || head.SSECustomerKey != nil || head.SSECustomerKeyMD5 != nil {
+			t.Errorf("expected no SSE-C parameters on HeadObject, got %+v", head)
+		}
+	})
+}
+
+func TestS3Service_Retry(t *testing.T) {
+	slowDown := &smithy.GenericAPIError{Code: "SlowDown", Message: "Please reduce your request rate."}
+
+	tests := []struct {
+		name          string
+		errs          []error
+		maxAttempts   int
+		expectErr     bool
+		expectedCalls int
+	}{
+		{name: "fails twice then succeeds", errs: []error{slowDown, slowDown}, maxAttempts: 3, expectedCalls: 3},
+		{name: "attempts exhausted", errs: []error{slowDown, slowDown, slowDown}, maxAttempts: 3, expectErr: true, expectedCalls: 3},
+		{name: "retries disabled", errs: []error{slowDown}, maxAttempts: 1, expectErr: true, expectedCalls: 1},
+		{name: "missing object fails fast", errs: []error{&types.NoSuchKey{}}, maxAttempts: 3, expectErr: true, expectedCalls: 1},
+		{name: "access denied fails fast", errs: []error{&smithy.GenericAPIError{Code: "AccessDenied"}}, maxAttempts: 3, expectErr: true, expectedCalls: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &stubS3API{
+				getOutput: &s3.GetObjectOutput{Body: io.NopCloser(strings.NewReader("data"))},
+				errs:      tt.errs,
+			}
+			storage := NewS3Service(client, "test-bucket").WithRetry(tt.maxAttempts, time.Millisecond)
+
+			_, err := storage.GetObject(context.Background(), "images/photo.jpg")
+			if (err != nil) != tt.expectErr {
+				t.Errorf("expected error %v, got %v", tt.expectErr, err)
+			}
+			if client.calls != tt.expectedCalls {
+				t.Errorf("expected %d calls, got %d", tt.expectedCalls, client.calls)
+			}
+		})
+	}
+
+	t.Run("client retries disabled", func(t *testing.T) {
+		client := &stubS3API{
+			getOutput:  &s3.GetObjectOutput{Body: io.NopCloser(strings.NewReader("data"))},
+			headOutput: &s3.HeadObjectOutput{},
+		}
+		storage := NewS3Service(client, "test-bucket").WithRetry(3, time.Millisecond)
+		if _, err := storage.GetObject(context.Background(), "images/photo.jpg"); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if _, err := storage.HeadObject(context.Background(), "images/photo.jpg"); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !slices.Equal(client.retryMaxAttempts, []int{1, 1}) {
+			t.Errorf("expected reads with a single client attempt, got %v", client.retryMaxAttempts)
+		}
+
+		// Without retries of its own, the service keeps those of the client
+		client.retryMaxAttempts = nil
+		if _, err := NewS3Service(client, "test-bucket").HeadObject(context.Background(), "images/photo.jpg"); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !slices.Equal(client.retryMaxAttempts, []int{0}) {
+			t.Errorf("expected the client retry attempts to be kept, got %v", client.retryMaxAttempts)
+		}
+	})
+
+	t.Run("context deadline", func(t *testing.T) {
+		client := &stubS3API{err: slowDown}
+		storage := NewS3Service(client, "test-bucket").WithRetry(10, time.Second)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+
+		start := time.Now()
+		if _, err := storage.HeadObject(ctx, "images/photo.jpg"); err == nil {
+			t.Fatal("expected error")
+		}
+		if elapsed := time.Since(start); elapsed > time.Second {
+			t.Errorf("expected retries to stop at the context deadline, took %v", elapsed)
+		}
+	})
+}
+
+func TestRetryDelay(t *testing.T) {
+	for attempt := 1; attempt <= 40; attempt++ {
+		ceiling := min(100*time.Millisecond<<min(attempt-1, 20), maxRetryDelay)
+		if delay := retryDelay(100*time.Millisecond, attempt); delay < 0 || delay > ceiling {
+			t.Errorf("attempt %d: expected delay within [0, %v], got %v", attempt, ceiling, delay)
+		}
+	}
+}
+
+func TestS3Service_ContentTypeFallback(t *testing.T) {
+	tests := []struct {
+		name     string
+		key      string
+		reported *string
+		expected string
+	}{
+		{name: "octet-stream JPEG", key: "images/photo.jpg", reported: aws.String("application/octet-stream"), expected: "image/jpeg"},
+		{name: "missing SVG type", key: "icons/logo.svg", expected: "image/svg+xml"},
+		{name: "unknown extension", key: "data/blob.unknownext", reported: aws.String("application/octet-stream"), expected: "application/octet-stream"},
+		{name: "no extension", key: "data/blob", expected: "application/octet-stream"},
+		{name: "specific type kept", key: "images/photo.jpg", reported: aws.String("image/webp"), expected: "image/webp"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storage := NewS3Service(&stubS3API{
+				getOutput:  &s3.GetObjectOutput{Body: io.NopCloser(strings.NewReader("")), ContentType: tt.reported},
+				headOutput: &s3.HeadObjectOutput{ContentType: tt.reported},
+			}, "test-bucket")
+
+			reader, err := storage.GetObject(context.Background(), tt.key)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			defer reader.Close()
+			if reader.ContentType() != tt.expected {
+				t.Errorf("GetObject: expected content type %s, got %s", tt.expected, reader.ContentType())
+			}
+
+			metadata, err := storage.HeadObject(context.Background(), tt.key)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if metadata.ContentType != tt.expected {
+				t.Errorf("HeadObject: expected content type %s, got %s", tt.expected, metadata.ContentType)
+			}
+		})
+	}
+
+	t.Run("configured default", func(t *testing.T) {
+		storage := NewS3Service(&stubS3API{
+			getOutput:  &s3.GetObjectOutput{Body: io.NopCloser(strings.NewReader(""))},
+			headOutput: &s3.HeadObjectOutput{ContentType: aws.String("application/octet-stream")},
+		}, "test-bucket").WithDefaultContentType("text/plain; charset=utf-8")
+
+		reader, err := storage.GetObject(context.Background(), "data/blob")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		defer reader.Close()
+		if reader.ContentType() != "text/plain; charset=utf-8" {
+			t.Errorf("GetObject: expected the configured default, got %s", reader.ContentType())
+		}
+
+		metadata, err := storage.HeadObject(context.Background(), "data/blob.unknownext")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if metadata.ContentType != "text/plain; charset=utf-8" {
+			t.Errorf("HeadObject: expected the configured default, got %s", metadata.ContentType)
+		}
+
+		metadata, err = storage.HeadObject(context.Background(), "images/photo.jpg")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if metadata.ContentType != "image/jpeg" {
+			t.Errorf("expected the extension to take precedence, got %s", metadata.ContentType)
+		}
+	})
+}
+
+func TestS3Service_Checksum(t *testing.T) {
+	sum := sha256.Sum256([]byte("data"))
+	encoded := base64.StdEncoding.EncodeToString(sum[:])
+	expected := hex.EncodeToString(sum[:])
+
+	tests := []struct {
+		name         string
+		checksum     *string
+		checksumType types.ChecksumType
+		expected     string
+	}{
+		{name: "full object", checksum: aws.String(encoded), checksumType: types.ChecksumTypeFullObject, expected: expected},
+		{name: "no type reported", checksum: aws.String(encoded), expected: expected},
+		{name: "composite", checksum: aws.String(encoded + "-3"), checksumType: types.ChecksumTypeComposite},
+		{name: "missing", checksum: nil},
+		{name: "not SHA-256", checksum: aws.String(base64.StdEncoding.EncodeToString([]byte("short")))},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &stubS3API{
+				getOutput: &s3.GetObjectOutput{
+					Body:           io.NopCloser(strings.NewReader("data")),
+					ChecksumSHA256: tt.checksum,
+					ChecksumType:   tt.checksumType,
+				},
+				headOutput: &s3.HeadObjectOutput{ChecksumSHA256: tt.checksum, ChecksumType: tt.checksumType},
+			}
+			storage := NewS3Service(client, "test-bucket")
+
+			reader, err := storage.GetObject(context.Background(), "docs/report.pdf")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			defer reader.Close()
+			if got := reader.Checksum(); got != tt.expected {
+				t.Errorf("expected reader checksum %q, got %q", tt.expected, got)
+			}
+
+			metadata, err := storage.HeadObject(context.Background(), "docs/report.pdf")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if metadata.Checksum != tt.expected {
+				t.Errorf("expected metadata checksum %q, got %q", tt.expected, metadata.Checksum)
+			}
+
+			if client.getInputs[0].ChecksumMode != types.ChecksumModeEnabled || client.headInputs[0].ChecksumMode != types.ChecksumModeEnabled {
+				t.Error("expected checksums to be requested from S3")
+			}
+		})
+	}
+}
+
+func TestS3Service_ObjectVersion(t *testing.T) {
+	client := &stubS3API{
+		getOutput:  &s3.GetObjectOutput{Body: io.NopCloser(strings.NewReader("v1"))},
+		headOutput: &s3.HeadObjectOutput{},
+	}
+	storage := NewS3Service(client, "test-bucket")
+
+	tests := []struct {
+		name    string
+		version string
+	}{
+		{name: "latest version", version: ""},
+		{name: "pinned version", version: "3HL4kqtJlcpXroDTDmJ.rmSpXd3dIbrHY"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client.getInputs, client.headInputs = nil, nil
+			ctx := domain.WithObjectVersion(context.Background(), tt.version)
+
+			if _, err := storage.GetObject(ctx, "images/photo.jpg"); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if _, err := storage.GetObjectRange(ctx, "images/photo.jpg", 0, 1); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if _, err := storage.HeadObject(ctx, "images/photo.jpg"); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			for _, input := range client.getInputs {
+				if got := aws.ToString(input.VersionId); got != tt.version {
+					t.Errorf("expected GetObject version %q, got %q", tt.version, got)
+				}
+			}
+			for _, input := range client.headInputs {
+				if got := aws.ToString(input.VersionId); got != tt.version {
+					t.Errorf("expected HeadObject version %q, got %q", tt.version, got)
+				}
+			}
+			if len(client.getInputs) != 2 || len(client.headInputs) != 1 {
+				t.Errorf("expected 2 GetObject and 1 HeadObject calls, got %d and %d", len(client.getInputs), len(client.headInputs))
+			}
+		})
+	}
+
+	t.Run("presigned URL", func(t *testing.T) {
+		presigning := NewS3Service(s3.New(s3.Options{
+			Region:      "us-east-1",
+			Credentials: credentials.NewStaticCredentialsProvider("AKIDEXAMPLE", "secret", ""),
+		}), "test-bucket")
+		ctx := domain.WithObjectVersion(context.Background(), "v1")
+
+		presigned, err := presigning.PresignGetObject(ctx, "images/photo.jpg", time.Hour)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		u, err := url.Parse(presigned)
+		if err != nil {
+			t.Fatalf("invalid presigned URL %q: %v", presigned, err)
+		}
+		if got := u.Query().Get("versionId"); got != "v1" {
+			t.Errorf("expected versionId v1, got %q", got)
+		}
+	})
+}
+
+func TestShareService_PinnedVersion(t *testing.T) {
+	client := &stubS3API{
+		getOutput:  &s3.GetObjectOutput{Body: io.NopCloser(strings.NewReader("v1"))},
+		headOutput: &s3.HeadObjectOutput{},
+	}
+	cache := &mockCacheService{store: make(map[string]string)}
+	service := NewShareService(NewS3Service(client, "test-bucket"), cache, &ShareConfig{
+		MaxAgeDays: 90,
+		BaseURL:    "https://example.com",
+	})
+	ctx := context.Background()
+
+	for _, share := range []struct{ path, version string }{
+		{path: "images/pinned.jpg", version: "v1"},
+		{path: "images/latest.jpg"},
+	} {
+		_, err := service.CreateShare(ctx, &domain.ShareRequest{
+			S3Path:    share.path,
+			Secret:    "test-secret",
+			ExpiresAt: time.Now().Add(time.Hour),
+			VersionID: share.version,
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		// Creation checks that the pinned version exists
+		if got := aws.ToString(client.headInputs[len(client.headInputs)-1].VersionId); got != share.version {
+			t.Errorf("expected %s to be checked at version %q, got %q", share.path, share.version, got)
+		}
+
+		client.getInputs = nil
+		if _, err := service.GetObject(ctx, share.path); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got := aws.ToString(client.getInputs[0].VersionId); got != share.version {
+			t.Errorf("expected %s to be fetched at version %q, got %q", share.path, share.version, got)
+		}
+	}
+
+	t.Run("prefix shares cannot pin a version", func(t *testing.T) {
+		_, err := service.CreateShare(ctx, &domain.ShareRequest{
+			S3Path:    "images/",
+			Secret:    "test-secret",
+			ExpiresAt: time.Now().Add(time.Hour),
+			VersionID: "v1",
+		})
+		if !errors.Is(err, domain.ErrInvalidPath) {
+			t.Errorf("expected %v, got %v", domain.ErrInvalidPath, err)
+		}
+	})
+
+	t.Run("missing version", func(t *testing.T) {
+		client.err = &smithy.GenericAPIError{Code: "NoSuchVersion"}
+		defer func() { client.err = nil }()
+
+		_, err := service.CreateShare(ctx, &domain.ShareRequest{
+			S3Path:    "images/pinned.jpg",
+			Secret:    "test-secret",
+			ExpiresAt: time.Now().Add(time.Hour),
+			VersionID: "v0",
+		})
+		if !errors.Is(err, domain.ErrNotFound) {
+			t.Errorf("expected %v, got %v", domain.ErrNotFound, err)
+		}
+	})
+}
@@ -0,0 +1,70 @@
+package service
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/vchitai/go-s3-sharing/internal/domain"
+)
+
+// rotatedSecretBytes is the entropy of secrets generated by RotateSecret
+const rotatedSecretBytes = 16
+
+// RotateSecret replaces the secret of the share of s3Path with a random one
+// and returns it. The previous secret stays valid for SecretGracePeriod, but
+// never past the share's expiry, so that links already handed out can be
+// replaced before they stop working; short links carry the previous secret
+// and stop working with it. It returns ErrNotFound when no share exists for
+// s3Path and ErrRotationNotSupported for public shares and signed URLs.
+func (s *ShareService) RotateSecret(ctx context.Context, s3Path string) (string, error) {
+	if !s.isValidS3Path(s3Path) {
+		return "", domain.ErrInvalidPath
+	}
+	// Signed tokens derive from the path and expiry and cannot be replaced
+	if s.config.SignedURLs {
+		return "", domain.ErrRotationNotSupported
+	}
+
+	record, err := s.getShareRecord(ctx, s3Path)
+	if err != nil {
+		if err == domain.ErrNotFound {
+			return "", domain.ErrNotFound
+		}
+		return "", fmt.Errorf("failed to load share: %w", err)
+	}
+	if record.Public {
+		return "", domain.ErrRotationNotSupported
+	}
+
+	expiration := time.Until(record.ExpiresAt)
+	if expiration <= 0 {
+		return "", domain.ErrNotFound
+	}
+
+	b := make([]byte, rotatedSecretBytes)
+	if _, err := io.ReadFull(s.random, b); err != nil {
+		return "", fmt.Errorf("failed to generate secret: %w", err)
+	}
+	secret := hex.EncodeToString(b)
+
+	record.PreviousSecret, record.PreviousSecretExpiresAt = "", time.Time{}
+	if s.config.SecretGracePeriod > 0 {
+		record.PreviousSecret = record.Secret
+		record.PreviousSecretExpiresAt = time.Now().Add(min(s.config.SecretGracePeriod, expiration))
+	}
+	record.Secret = secret
+
+	value, err := domain.EncodeShareRecord(record)
+	if err != nil {
+		return "", err
+	}
+	if err := s.cache.Set(ctx, s.generateCacheKey(ctx, s3Path), value, expiration); err != nil {
+		return "", fmt.Errorf("failed to store share in cache: %w", err)
+	}
+	s.forgetShareRecord(ctx, s3Path)
+
+	return secret, nil
+}
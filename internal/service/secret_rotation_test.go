@@ -0,0 +1,148 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/vchitai/go-s3-sharing/internal/domain"
+)
+
+func TestShareService_RotateSecret(t *testing.T) {
+	newService := func(config *ShareConfig) (*ShareService, *mockCacheService) {
+		storage := &mockStorageService{objects: map[string]*domain.ObjectMetadata{
+			"images/photo.jpg": {ContentType: "image/jpeg"},
+		}}
+		cache := &mockCacheService{store: make(map[string]string)}
+		config.MaxAgeDays = 90
+		config.BaseURL = "https://example.com"
+		return NewShareService(storage, cache, config), cache
+	}
+	ctx := context.Background()
+	expiresAt := time.Now().Add(time.Hour)
+	createShare := func(t *testing.T, service *ShareService, req *domain.ShareRequest) {
+		t.Helper()
+		if _, err := service.CreateShare(ctx, req); err != nil {
+			t.Fatalf("failed to create share: %v", err)
+		}
+	}
+
+	t.Run("both secrets valid during the grace period", func(t *testing.T) {
+		service, cache := newService(&ShareConfig{SecretGracePeriod: time.Minute})
+		createShare(t, service, &domain.ShareRequest{S3Path: "images/photo.jpg", Secret: "old-secret", ExpiresAt: expiresAt})
+
+		secret, err := service.RotateSecret(ctx, "images/photo.jpg")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(secret) != 2*rotatedSecretBytes || secret == "old-secret" {
+			t.Fatalf("expected a new random secret, got %q", secret)
+		}
+
+		for _, valid := range []string{secret, "old-secret"} {
+			if err := service.ValidateShare(ctx, "images/photo.jpg", valid, expiresAt); err != nil {
+				t.Errorf("expected secret %q to validate, got %v", valid, err)
+			}
+		}
+		if err := service.ValidateShare(ctx, "images/photo.jpg", "other-secret", expiresAt); err != domain.ErrUnauthorized {
+			t.Errorf("expected ErrUnauthorized for an unknown secret, got %v", err)
+		}
+
+		// Move the end of the grace period into the past
+		key := service.generateCacheKey(ctx, "images/photo.jpg")
+		record, err := domain.DecodeShareRecord(cache.store[key])
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if record.PreviousSecretExpiresAt.After(time.Now().Add(time.Minute)) {
+			t.Errorf("expected the grace period to end within a minute, got %v", record.PreviousSecretExpiresAt)
+		}
+		record.PreviousSecretExpiresAt = time.Now().Add(-time.Second)
+		cache.store[key], _ = domain.EncodeShareRecord(record)
+
+		if err := service.ValidateShare(ctx, "images/photo.jpg", secret, expiresAt); err != nil {
+			t.Errorf("expected the new secret to validate, got %v", err)
+		}
+		if err := service.ValidateShare(ctx, "images/photo.jpg", "old-secret", expiresAt); err != domain.ErrUnauthorized {
+			t.Errorf("expected ErrUnauthorized for the old secret after the grace period, got %v", err)
+		}
+	})
+
+	t.Run("rotating again drops the first secret", func(t *testing.T) {
+		service, _ := newService(&ShareConfig{SecretGracePeriod: time.Minute})
+		createShare(t, service, &domain.ShareRequest{S3Path: "images/photo.jpg", Secret: "old-secret", ExpiresAt: expiresAt})
+
+		second, err := service.RotateSecret(ctx, "images/photo.jpg")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		third, err := service.RotateSecret(ctx, "images/photo.jpg")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		for _, valid := range []string{second, third} {
+			if err := service.ValidateShare(ctx, "images/photo.jpg", valid, expiresAt); err != nil {
+				t.Errorf("expected secret %q to validate, got %v", valid, err)
+			}
+		}
+		if err := service.ValidateShare(ctx, "images/photo.jpg", "old-secret", expiresAt); err != domain.ErrUnauthorized {
+			t.Errorf("expected ErrUnauthorized for the first secret, got %v", err)
+		}
+	})
+
+	t.Run("no grace period", func(t *testing.T) {
+		service, _ := newService(&ShareConfig{})
+		createShare(t, service, &domain.ShareRequest{S3Path: "images/photo.jpg", Secret: "old-secret", ExpiresAt: expiresAt})
+
+		secret, err := service.RotateSecret(ctx, "images/photo.jpg")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if err := service.ValidateShare(ctx, "images/photo.jpg", secret, expiresAt); err != nil {
+			t.Errorf("expected the new secret to validate, got %v", err)
+		}
+		if err := service.ValidateShare(ctx, "images/photo.jpg", "old-secret", expiresAt); err != domain.ErrUnauthorized {
+			t.Errorf("expected ErrUnauthorized for the old secret, got %v", err)
+		}
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		tests := []struct {
+			name        string
+			config      *ShareConfig
+			req         *domain.ShareRequest
+			s3Path      string
+			expectedErr error
+		}{
+			{name: "missing share", config: &ShareConfig{}, s3Path: "images/photo.jpg", expectedErr: domain.ErrNotFound},
+			{name: "invalid path", config: &ShareConfig{}, s3Path: "../etc/passwd", expectedErr: domain.ErrInvalidPath},
+			{
+				name:        "public share",
+				config:      &ShareConfig{},
+				req:         &domain.ShareRequest{S3Path: "images/photo.jpg", Public: true, ExpiresAt: expiresAt},
+				s3Path:      "images/photo.jpg",
+				expectedErr: domain.ErrRotationNotSupported,
+			},
+			{
+				name:        "signed URLs",
+				config:      &ShareConfig{SignedURLs: true, SigningKey: "signing-key"},
+				req:         &domain.ShareRequest{S3Path: "images/photo.jpg", ExpiresAt: expiresAt},
+				s3Path:      "images/photo.jpg",
+				expectedErr: domain.ErrRotationNotSupported,
+			},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				service, _ := newService(tt.config)
+				if tt.req != nil {
+					createShare(t, service, tt.req)
+				}
+				if _, err := service.RotateSecret(ctx, tt.s3Path); err != tt.expectedErr {
+					t.Errorf("expected error %v, got %v", tt.expectedErr, err)
+				}
+			})
+		}
+	})
+}
@@ -0,0 +1,35 @@
+package service
+
+import (
+	"github.com/vchitai/go-s3-sharing/internal/config"
+)
+
+// NewShareConfig creates the share service configuration of cfg, so that the
+// server and the CLI create and validate shares alike. Hooks into the
+// transport and the audit sink, which need connections of their own, are
+// left to the caller.
+func NewShareConfig(cfg *config.Config) *ShareConfig {
+	return &ShareConfig{
+		MaxAgeDays:          cfg.Security.MaxAgeDays,
+		BaseURL:             cfg.BaseURL,
+		SigningKey:          cfg.Security.SigningKey,
+		SignedURLs:          cfg.Security.SignedURLs,
+		SignedURLFallback:   cfg.Security.SignedURLFallback,
+		MaxFailedAttempts:   cfg.Security.MaxFailedAttempts,
+		LockoutWindow:       cfg.Security.LockoutWindow,
+		IdempotencyWindow:   cfg.Security.IdempotencyWindow,
+		SecretGracePeriod:   cfg.Security.SecretGracePeriod,
+		KeyPrefix:           cfg.Redis.KeyPrefix,
+		TenantKeyPrefixes:   cfg.TenantKeyPrefixes(),
+		ObjectCacheMaxBytes: cfg.ObjectCache.MaxBytes,
+		ObjectCacheTTL:      cfg.ObjectCache.TTL,
+		ResizeMaxDimension:  cfg.Resize.MaxDimension,
+		ResizeMaxPixels:     cfg.Resize.MaxPixels,
+		ResizeSizes:         cfg.Resize.Sizes,
+		ResizeCacheTTL:      cfg.Resize.CacheTTL,
+		Webhooks:            NewWebhookSender(cfg.Webhook.Timeout, cfg.Webhook.MaxAttempts),
+		AllowedReferers:     cfg.Security.AllowedReferers,
+		AllowEmptyReferer:   cfg.Security.AllowEmptyReferer,
+		ShareablePrefixes:   cfg.Security.ShareablePrefixes,
+	}
+}
@@ -0,0 +1,37 @@
+package service
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/vchitai/go-s3-sharing/internal/config"
+)
+
+func TestNewShareConfig(t *testing.T) {
+	cfg := &config.Config{
+		BaseURL: "https://files.example.com",
+		Security: config.SecurityConfig{
+			MaxAgeDays:        30,
+			SigningKey:        "signing-key",
+			SignedURLs:        true,
+			LockoutWindow:     time.Minute,
+			ShareablePrefixes: []string{"public/"},
+		},
+		Redis: config.RedisConfig{KeyPrefix: "staging"},
+	}
+
+	shareConfig := NewShareConfig(cfg)
+	if shareConfig.BaseURL != cfg.BaseURL || shareConfig.MaxAgeDays != 30 || shareConfig.KeyPrefix != "staging" {
+		t.Errorf("unexpected share config: %+v", shareConfig)
+	}
+	if !shareConfig.SignedURLs || shareConfig.SigningKey != "signing-key" || shareConfig.LockoutWindow != time.Minute {
+		t.Errorf("expected the security config to carry over, got %+v", shareConfig)
+	}
+	if !slices.Equal(shareConfig.ShareablePrefixes, []string{"public/"}) {
+		t.Errorf("expected shareable prefixes [public/], got %v", shareConfig.ShareablePrefixes)
+	}
+	if shareConfig.Webhooks == nil {
+		t.Error("expected a webhook sender")
+	}
+}
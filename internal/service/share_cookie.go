@@ -0,0 +1,115 @@
+package service
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/vchitai/go-s3-sharing/internal/domain"
+)
+
+// SignShareCookie returns the value of a cookie granting the share of s3Path
+// until expiresAt, signed with the signing key. The cookie is bound to the
+// current generation of the share, so rotating its secret or revoking and
+// creating it again invalidates cookies issued before. It returns
+// ErrCookiesDisabled when no signing key is configured and ErrNotFound when
+// no share exists for s3Path.
+func (s *ShareService) SignShareCookie(ctx context.Context, s3Path string, expiresAt time.Time) (string, error) {
+	if s.config.SigningKey == "" {
+		return "", domain.ErrCookiesDisabled
+	}
+
+	record, err := s.getShareRecord(ctx, s3Path)
+	if err != nil {
+		if err == domain.ErrNotFound {
+			return "", err
+		}
+		return "", fmt.Errorf("failed to sign share cookie: %w", err)
+	}
+
+	expiry := strconv.FormatInt(expiresAt.Unix(), 10)
+	path := base64.RawURLEncoding.EncodeToString([]byte(s3Path))
+	generation := shareGeneration(record)
+	return path + "." + expiry + "." + generation + "." + s.signCookie(ctx, path, expiry, generation), nil
+}
+
+// ParseShareCookie verifies the signature of a share cookie value and
+// returns its grant, which may have expired. It returns ErrInvalidSignature
+// for malformed or altered values and values signed for another tenant.
+func (s *ShareService) ParseShareCookie(ctx context.Context, value string) (*domain.ShareCookie, error) {
+	if s.config.SigningKey == "" {
+		return nil, domain.ErrCookiesDisabled
+	}
+
+	fields := strings.Split(value, ".")
+	if len(fields) != 4 {
+		return nil, domain.ErrInvalidSignature
+	}
+	path, expiry, generation, signature := fields[0], fields[1], fields[2], fields[3]
+	if !hmac.Equal([]byte(signature), []byte(s.signCookie(ctx, path, expiry, generation))) {
+		return nil, domain.ErrInvalidSignature
+	}
+
+	s3Path, err := base64.RawURLEncoding.DecodeString(path)
+	if err != nil {
+		return nil, domain.ErrInvalidSignature
+	}
+	seconds, err := strconv.ParseInt(expiry, 10, 64)
+	if err != nil {
+		return nil, domain.ErrInvalidSignature
+	}
+	return &domain.ShareCookie{
+		S3Path:     string(s3Path),
+		ExpiresAt:  time.Unix(seconds, 0).UTC(),
+		Generation: generation,
+	}, nil
+}
+
+// ValidateShareCookie checks that the grant of a verified share cookie is
+// still in force. It returns ErrExpired once the cookie has expired and
+// ErrUnauthorized when its share was revoked or consumed, or replaced by a
+// new generation since the cookie was issued.
+func (s *ShareService) ValidateShareCookie(ctx context.Context, cookie *domain.ShareCookie) error {
+	if time.Now().After(cookie.ExpiresAt) {
+		return domain.ErrExpired
+	}
+
+	record, err := s.getShareRecord(ctx, cookie.S3Path)
+	if err != nil {
+		if err == domain.ErrNotFound {
+			return domain.ErrUnauthorized
+		}
+		return fmt.Errorf("failed to validate share cookie: %w", err)
+	}
+	if !hmac.Equal([]byte(shareGeneration(record)), []byte(cookie.Generation)) {
+		return domain.ErrUnauthorized
+	}
+	return nil
+}
+
+// shareGeneration identifies the generation of a share record by a hash of
+// its secret and creation time, which change when the secret is rotated and
+// when the share is created again
+func shareGeneration(record *domain.ShareRecord) string {
+	sum := sha256.Sum256([]byte(record.Secret + "|" + record.CreatedAt.UTC().Format(time.RFC3339Nano)))
+	return base64.RawURLEncoding.EncodeToString(sum[:12])
+}
+
+// signCookie computes a base64url HMAC-SHA256 over the encoded path, expiry
+// and share generation of a share cookie. The "cookie|" prefix keeps cookie signatures
+// apart from link tokens, and tenants other than the default are included
+// so that a cookie never validates for another tenant.
+func (s *ShareService) signCookie(ctx context.Context, path, expiry, generation string) string {
+	mac := hmac.New(sha256.New, []byte(s.config.SigningKey))
+	fmt.Fprint(mac, "cookie|")
+	if tenantID := domain.TenantFromContext(ctx); tenantID != "" {
+		fmt.Fprintf(mac, "%s|", tenantID)
+	}
+	fmt.Fprintf(mac, "%s|%s|%s", path, expiry, generation)
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,145 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/vchitai/go-s3-sharing/internal/domain"
+)
+
+func newShareCookieTestService() *ShareService {
+	storage := &mockStorageService{objects: map[string]*domain.ObjectMetadata{
+		"images/photo.jpg": {ContentType: "image/jpeg"},
+	}}
+	return NewShareService(storage, &mockCacheService{store: make(map[string]string)}, &ShareConfig{
+		MaxAgeDays: 90,
+		BaseURL:    "https://example.com",
+		SigningKey: "test-signing-key",
+	})
+}
+
+func TestShareService_ShareCookieRoundTrip(t *testing.T) {
+	service := newShareCookieTestService()
+	ctx := context.Background()
+	expiresAt := time.Now().Add(time.Hour).Truncate(time.Second).UTC()
+
+	if _, err := service.CreateShare(ctx, &domain.ShareRequest{
+		S3Path:    "images/photo.jpg",
+		Secret:    "test-secret",
+		ExpiresAt: time.Now().Add(72 * time.Hour),
+	}); err != nil {
+		t.Fatalf("failed to create share: %v", err)
+	}
+
+	value, err := service.SignShareCookie(ctx, "images/photo.jpg", expiresAt)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	cookie, err := service.ParseShareCookie(ctx, value)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cookie.S3Path != "images/photo.jpg" {
+		t.Errorf("expected path images/photo.jpg, got %s", cookie.S3Path)
+	}
+	if !cookie.ExpiresAt.Equal(expiresAt) {
+		t.Errorf("expected expiry %v, got %v", expiresAt, cookie.ExpiresAt)
+	}
+
+	fields := strings.Split(value, ".")
+	tests := []struct {
+		name  string
+		ctx   context.Context
+		value string
+	}{
+		{name: "malformed", ctx: ctx, value: "not-a-cookie"},
+		{name: "extended", ctx: ctx, value: fields[0] + "." + "9999999999" + "." + fields[2] + "." + fields[3]},
+		{name: "other path", ctx: ctx, value: "aW1hZ2VzLw." + fields[1] + "." + fields[2] + "." + fields[3]},
+		{name: "other generation", ctx: ctx, value: fields[0] + "." + fields[1] + "." + "AAAAAAAAAAAAAAAA" + "." + fields[3]},
+		{name: "other tenant", ctx: domain.WithTenant(ctx, "acme"), value: value},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := service.ParseShareCookie(tt.ctx, tt.value); err != domain.ErrInvalidSignature {
+				t.Errorf("expected ErrInvalidSignature, got %v", err)
+			}
+		})
+	}
+}
+
+func TestShareService_ShareCookieDisabled(t *testing.T) {
+	service, _ := newShortLinkTestService()
+
+	if _, err := service.SignShareCookie(context.Background(), "images/photo.jpg", time.Now().Add(time.Hour)); err != domain.ErrCookiesDisabled {
+		t.Errorf("expected ErrCookiesDisabled, got %v", err)
+	}
+}
+
+func TestShareService_ValidateShareCookie(t *testing.T) {
+	service := newShareCookieTestService()
+	ctx := context.Background()
+
+	if _, err := service.CreateShare(ctx, &domain.ShareRequest{
+		S3Path:    "images/photo.jpg",
+		Secret:    "test-secret",
+		ExpiresAt: time.Now().Add(72 * time.Hour),
+	}); err != nil {
+		t.Fatalf("failed to create share: %v", err)
+	}
+
+	issue := func(t *testing.T, expiresAt time.Time) *domain.ShareCookie {
+		t.Helper()
+		value, err := service.SignShareCookie(ctx, "images/photo.jpg", expiresAt)
+		if err != nil {
+			t.Fatalf("failed to sign cookie: %v", err)
+		}
+		cookie, err := service.ParseShareCookie(ctx, value)
+		if err != nil {
+			t.Fatalf("failed to parse cookie: %v", err)
+		}
+		return cookie
+	}
+
+	valid := issue(t, time.Now().Add(time.Hour))
+	if err := service.ValidateShareCookie(ctx, valid); err != nil {
+		t.Errorf("expected valid cookie, got %v", err)
+	}
+
+	expired := issue(t, time.Now().Add(-time.Second))
+	if err := service.ValidateShareCookie(ctx, expired); err != domain.ErrExpired {
+		t.Errorf("expected ErrExpired, got %v", err)
+	}
+
+	// Rotating the secret starts a new generation of the share
+	if _, err := service.RotateSecret(ctx, "images/photo.jpg"); err != nil {
+		t.Fatalf("failed to rotate secret: %v", err)
+	}
+	if err := service.ValidateShareCookie(ctx, valid); err != domain.ErrUnauthorized {
+		t.Errorf("expected ErrUnauthorized after rotation, got %v", err)
+	}
+
+	valid = issue(t, time.Now().Add(time.Hour))
+	if err := service.RevokeShare(ctx, "images/photo.jpg"); err != nil {
+		t.Fatalf("failed to revoke share: %v", err)
+	}
+	if err := service.ValidateShareCookie(ctx, valid); err != domain.ErrUnauthorized {
+		t.Errorf("expected ErrUnauthorized after revocation, got %v", err)
+	}
+
+	// A share created again under the same secret does not honour cookies of
+	// the revoked one
+	if _, err := service.CreateShare(ctx, &domain.ShareRequest{
+		S3Path:    "images/photo.jpg",
+		Secret:    "test-secret",
+		ExpiresAt: time.Now().Add(72 * time.Hour),
+	}); err != nil {
+		t.Fatalf("failed to create share: %v", err)
+	}
+	if err := service.ValidateShareCookie(ctx, valid); err != domain.ErrUnauthorized {
+		t.Errorf("expected ErrUnauthorized after the share was created again, got %v", err)
+	}
+}
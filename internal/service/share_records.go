@@ -0,0 +1,65 @@
+package service
+
+import (
+	"context"
+	"sync"
+
+	"github.com/vchitai/go-s3-sharing/internal/domain"
+)
+
+// shareRecordsKey is the context key of the share records loaded by a request
+type shareRecordsKey struct{}
+
+// shareRecords holds the encoded share records loaded by one request, by
+// cache key. Records that do not exist are held as the empty string.
+type shareRecords struct {
+	mu     sync.Mutex
+	values map[string]string
+}
+
+// WithShareRecords returns ctx remembering the share records loaded through
+// it, so that the checks serving one share link load its record from the
+// cache once. Records written through ctx are loaded again.
+func WithShareRecords(ctx context.Context) context.Context {
+	return context.WithValue(ctx, shareRecordsKey{}, &shareRecords{values: make(map[string]string)})
+}
+
+// loadShareRecord returns the encoded share record stored under cacheKey,
+// from the records of ctx when it was already loaded through ctx
+func (s *ShareService) loadShareRecord(ctx context.Context, cacheKey string) (string, error) {
+	records, _ := ctx.Value(shareRecordsKey{}).(*shareRecords)
+	if records == nil {
+		return s.cache.Get(ctx, cacheKey)
+	}
+
+	records.mu.Lock()
+	defer records.mu.Unlock()
+	if value, ok := records.values[cacheKey]; ok {
+		if value == "" {
+			return "", domain.ErrNotFound
+		}
+		return value, nil
+	}
+
+	value, err := s.cache.Get(ctx, cacheKey)
+	switch err {
+	case nil:
+		records.values[cacheKey] = value
+	case domain.ErrNotFound:
+		records.values[cacheKey] = ""
+	}
+	return value, err
+}
+
+// forgetShareRecord drops the share record of s3Path from the records of ctx
+// once it is written, so that it is loaded again
+func (s *ShareService) forgetShareRecord(ctx context.Context, s3Path string) {
+	records, _ := ctx.Value(shareRecordsKey{}).(*shareRecords)
+	if records == nil {
+		return
+	}
+
+	records.mu.Lock()
+	defer records.mu.Unlock()
+	delete(records.values, s.generateCacheKey(ctx, s3Path))
+}
@@ -2,25 +2,113 @@ package service
 
 import (
 	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"io"
 	"path"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 
+	"go.opentelemetry.io/otel/attribute"
+	"golang.org/x/crypto/bcrypt"
+
 	"github.com/vchitai/go-s3-sharing/internal/domain"
 )
 
+// uploadURLExpiry is the longest lifetime of presigned upload URLs
+const uploadURLExpiry = 15 * time.Minute
+
+// resumeWindow is how long counted downloads can be resumed without being
+// counted again
+const resumeWindow = 24 * time.Hour
+
+// DefaultKeyPrefix is the prefix of share record keys unless configured
+const DefaultKeyPrefix = "image-auth"
+
 // ShareService implements the domain ShareService interface
 type ShareService struct {
 	storage domain.StorageService
 	cache   domain.CacheService
 	config  *ShareConfig
+	// random is the entropy source for short codes
+	random io.Reader
 }
 
 // ShareConfig holds configuration for the share service
 type ShareConfig struct {
 	MaxAgeDays int
 	BaseURL    string
+	// SigningKey is the server-side key used to sign share tokens
+	SigningKey string
+	// SignedURLs enables HMAC-signed tokens instead of caller-provided secrets
+	SignedURLs bool
+	// SignedURLFallback serves signed links on their verified signature and
+	// expiry alone while the cache is unreachable, without the restrictions
+	// stored in their share records
+	SignedURLFallback bool
+	// MaxFailedAttempts is the number of invalid secrets within LockoutWindow
+	// after which a path rejects every secret; zero disables the lockout
+	MaxFailedAttempts int
+	// LockoutWindow is how long failed attempts are remembered from the first
+	// failure, and so how long a locked path stays locked at most; later
+	// failures do not extend it
+	LockoutWindow time.Duration
+	// SecretGracePeriod is how long RotateSecret keeps accepting the
+	// replaced secret; zero invalidates it at once
+	SecretGracePeriod time.Duration
+	// IdempotencyWindow is how long CreateShareIdempotent replays the
+	// response of an idempotency key; zero disables replays
+	IdempotencyWindow time.Duration
+	// KeyPrefix names the cache keys of share records, "{prefix}:{path}", and
+	// derives the names of every other key, so that environments sharing a
+	// cache do not see each other's shares; empty means DefaultKeyPrefix
+	KeyPrefix string
+	// TenantKeyPrefixes overrides the cache key prefix of individual tenants;
+	// tenants without an entry use "tenant:{id}:"
+	TenantKeyPrefixes map[string]string
+	// ObjectCacheMaxBytes is the size up to which objects are cached for
+	// ObjectCacheTTL; zero disables the object cache
+	ObjectCacheMaxBytes int
+	// ObjectCacheTTL is how long cached objects are served, and so how long
+	// an object overwritten in storage may be served stale
+	ObjectCacheTTL time.Duration
+	// OnObjectCacheLookup, when set, is called with the outcome of every
+	// object cache lookup
+	OnObjectCacheLookup func(hit bool)
+	// ResizeMaxDimension bounds the width and height images are resized to;
+	// zero disables resizing
+	ResizeMaxDimension int
+	// ResizeMaxPixels bounds the width times height of images decoded for
+	// resizing
+	ResizeMaxPixels int
+	// ResizeSizes, when set, lists the sizes requested widths and heights
+	// are rounded up to
+	ResizeSizes []int
+	// ResizeCacheTTL is how long resized images are cached; zero disables
+	// the resize cache
+	ResizeCacheTTL time.Duration
+	// Audit, when set, records every access to a share link
+	Audit domain.AuditSink
+	// Webhooks delivers the first access events of shares with a webhook
+	// URL; shares cannot have one when it is nil
+	Webhooks *WebhookSender
+	// AllowedReferers restricts downloads of shares without allowed referers
+	// of their own to pages on these hosts; empty allows every referer
+	AllowedReferers []string
+	// AllowEmptyReferer lets requests without a Referer through shares with
+	// a referer allowlist
+	AllowEmptyReferer bool
+	// ShareablePrefixes restricts new shares to paths under these key
+	// prefixes; empty allows every path
+	ShareablePrefixes []string
 }
 
 // NewShareService creates a new share service
@@ -29,56 +117,383 @@ func NewShareService(storage domain.StorageService, cache domain.CacheService, c
 		storage: storage,
 		cache:   cache,
 		config:  config,
+		random:  rand.Reader,
 	}
 }
 
-// CreateShare creates a new shareable link
+// CreateShare creates a new shareable link. A path ending in "/" shares every
+// object under that prefix as a single archive download.
 func (s *ShareService) CreateShare(ctx context.Context, req *domain.ShareRequest) (*domain.ShareResponse, error) {
 	// Validate S3 path
 	if !s.isValidS3Path(req.S3Path) {
 		return nil, domain.ErrInvalidPath
 	}
+	if !s.isShareablePath(req.S3Path) {
+		return nil, domain.ErrForbiddenPath
+	}
+
+	if IsPrefixShare(req.S3Path) {
+		// Only single objects have versions
+		if req.VersionID != "" {
+			return nil, domain.ErrInvalidPath
+		}
+
+		// Archives are built by the service and cannot be presigned
+		if req.Mode == domain.ShareModeRedirect {
+			return nil, domain.ErrPresignNotSupported
+		}
+
+		// Check the prefix holds at least one object
+		objects, _, err := s.storage.ListObjects(ctx, req.S3Path, "")
+		if err != nil {
+			return nil, fmt.Errorf("failed to list prefix: %w", err)
+		}
+		if len(objects) == 0 {
+			return nil, fmt.Errorf("prefix is empty: %w", domain.ErrNotFound)
+		}
+
+		return s.storeShare(ctx, req)
+	}
 
-	// Check if object exists
-	_, err := s.storage.HeadObject(ctx, req.S3Path)
+	// Check if the object, or the pinned version of it, exists
+	headCtx := ctx
+	if req.VersionID != "" {
+		headCtx = domain.WithObjectVersion(ctx, req.VersionID)
+	}
+	_, err := s.storage.HeadObject(headCtx, req.S3Path)
 	if err != nil {
 		return nil, fmt.Errorf("object not found: %w", err)
 	}
 
+	return s.storeShare(ctx, req)
+}
+
+// PublicLinkSegment stands in for the secret segment of public share links,
+// /expiry/-/path, so that they are told apart from secret links by their
+// shape alone
+const PublicLinkSegment = "-"
+
+// IsPrefixShare reports whether s3Path names a prefix shared as an archive
+// rather than a single object
+func IsPrefixShare(s3Path string) bool {
+	return strings.HasSuffix(s3Path, "/")
+}
+
+// CreateShares creates every share of reqs like CreateShare, returning one
+// result per request in the same order. Requests fail independently, so an
+// invalid path fails only its own result.
+func (s *ShareService) CreateShares(ctx context.Context, reqs []*domain.ShareRequest) []domain.ShareResult {
+	results := make([]domain.ShareResult, len(reqs))
+	for i, req := range reqs {
+		results[i].Response, results[i].Err = s.CreateShare(ctx, req)
+	}
+	return results
+}
+
+// CreateUpload creates a share for an object that does not exist yet and
+// returns a presigned URL through which the client uploads it. The upload URL
+// is valid for at most uploadURLExpiry and never outlives the share.
+func (s *ShareService) CreateUpload(ctx context.Context, req *domain.ShareRequest) (*domain.UploadResponse, error) {
+	// Validate S3 path
+	if !s.isValidS3Path(req.S3Path) {
+		return nil, domain.ErrInvalidPath
+	}
+	if !s.isShareablePath(req.S3Path) {
+		return nil, domain.ErrForbiddenPath
+	}
+
+	// Uploads target a new single object, never a prefix or an old version
+	if IsPrefixShare(req.S3Path) || req.VersionID != "" {
+		return nil, domain.ErrInvalidPath
+	}
+
+	presigner, ok := s.storage.(domain.Presigner)
+	if !ok {
+		return nil, domain.ErrPresignNotSupported
+	}
+
+	share, err := s.storeShare(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	uploadExpiry := min(uploadURLExpiry, share.MaxAge)
+	uploadURL, err := presigner.PresignPutObject(ctx, req.S3Path, uploadExpiry)
+	if err != nil {
+		return nil, fmt.Errorf("failed to presign upload: %w", err)
+	}
+
+	return &domain.UploadResponse{
+		UploadURL:       uploadURL,
+		UploadExpiresAt: time.Now().Add(uploadExpiry),
+		Share:           share,
+	}, nil
+}
+
+// storeShare validates the expiry of req and stores its share record
+func (s *ShareService) storeShare(ctx context.Context, req *domain.ShareRequest) (*domain.ShareResponse, error) {
 	// Generate cache key
-	cacheKey := s.generateCacheKey(req.S3Path)
+	cacheKey := s.generateCacheKey(ctx, req.S3Path)
 
 	// Store in cache
 	expiration := time.Until(req.ExpiresAt)
 	if expiration <= 0 {
-		return nil, fmt.Errorf("expiration time must be in the future")
+		return nil, fmt.Errorf("expiration time must be in the future: %w", domain.ErrInvalidDate)
+	}
+
+	// Enforce the configured maximum share lifetime
+	if s.config.MaxAgeDays > 0 && expiration > s.maxAge() {
+		return nil, domain.ErrMaxAgeExceeded
+	}
+
+	// Redirect shares need a storage backend that can presign URLs
+	if req.Mode == domain.ShareModeRedirect {
+		if _, ok := s.storage.(domain.Presigner); !ok {
+			return nil, domain.ErrPresignNotSupported
+		}
+	}
+
+	if req.WebhookURL != "" && s.config.Webhooks == nil {
+		return nil, domain.ErrWebhooksDisabled
+	}
+
+	// Use a signed token in place of the caller secret when enabled; public
+	// shares have no secret at all. Tokens of shares with restrictions kept
+	// in their record say so, so that they never fall back to the token alone.
+	secret := req.Secret
+	switch {
+	case req.Public:
+		secret = ""
+	case s.config.SignedURLs:
+		restricted := req.Password != "" || req.MaxDownloads > 0 || len(req.AllowedIPs) > 0 || len(req.AllowedReferers) > 0
+		secret = s.signToken(ctx, req.S3Path, shareURLExpiry(req.ExpiresAt), restricted)
 	}
 
-	err = s.cache.Set(ctx, cacheKey, req.Secret, expiration)
+	record := &domain.ShareRecord{
+		Secret:          secret,
+		CreatedAt:       time.Now(),
+		ExpiresAt:       req.ExpiresAt,
+		MaxDownloads:    req.MaxDownloads,
+		Mode:            req.Mode,
+		Public:          req.Public,
+		VersionID:       req.VersionID,
+		WebhookURL:      req.WebhookURL,
+		AllowedReferers: req.AllowedReferers,
+		AllowedIPs:      req.AllowedIPs,
+	}
+
+	// Store only a hash of the password
+	if req.Password != "" {
+		hash, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+		if err != nil {
+			return nil, fmt.Errorf("failed to hash password: %w", err)
+		}
+		record.PasswordHash = string(hash)
+	}
+
+	// Short codes are recorded on the share so that extending it extends
+	// them too
+	if req.Short {
+		code, err := s.createShortCode(ctx, req.S3Path, secret, req.ExpiresAt, expiration)
+		if err != nil {
+			return nil, err
+		}
+		record.ShortCode = code
+	}
+
+	value, err := domain.EncodeShareRecord(record)
 	if err != nil {
+		return nil, err
+	}
+
+	// Reset the download counter of any previous share for this path
+	if err := s.cache.Delete(ctx, s.generateDownloadKey(ctx, req.S3Path)); err != nil {
+		return nil, fmt.Errorf("failed to reset download counter: %w", err)
+	}
+	if err := s.cache.Delete(ctx, s.generateAccessKey(ctx, req.S3Path)); err != nil {
+		return nil, fmt.Errorf("failed to reset last access: %w", err)
+	}
+	if err := s.cache.Delete(ctx, s.generateConsumedKey(ctx, req.S3Path)); err != nil {
+		return nil, fmt.Errorf("failed to reset consumed share: %w", err)
+	}
+
+	// A new share notifies its webhook again, even on a path accessed before
+	if req.WebhookURL != "" {
+		if err := s.ReleaseFirstAccess(ctx, req.S3Path); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.cache.Set(ctx, cacheKey, value, expiration); err != nil {
 		return nil, fmt.Errorf("failed to store share in cache: %w", err)
 	}
+	s.forgetShareRecord(ctx, req.S3Path)
 
-	// Generate shareable URL
-	url := s.generateShareURL(req.S3Path, req.ExpiresAt)
+	// Generate shareable URL embedding the stored secret, or a short code
+	// resolving to it
+	url := s.generateShareURL(ctx, req.S3Path, secret, req.ExpiresAt)
+	if record.ShortCode != "" {
+		url = s.generateShortURL(ctx, record.ShortCode)
+	}
 
 	return &domain.ShareResponse{
 		URL:       url,
+		Secret:    secret,
 		ExpiresAt: req.ExpiresAt,
 		MaxAge:    expiration,
 	}, nil
 }
 
-// ValidateShare validates a share request
-func (s *ShareService) ValidateShare(ctx context.Context, s3Path, secret string) error {
+// ValidateShare validates a share request. expiresAt is the expiry encoded in
+// the share URL and is only used to verify signed tokens, which are checked
+// before the cache is consulted: a link whose path or expiry was altered
+// fails with ErrInvalidSignature even once its record is gone. Paths that see
+// too many invalid secrets are locked out and reject every secret until the
+// lockout window passes. With SignedURLFallback, verified signed tokens of
+// shares without a password, download limit or allowlist are accepted while
+// the cache is unavailable.
+func (s *ShareService) ValidateShare(ctx context.Context, s3Path, secret string, expiresAt time.Time) (err error) {
+	ctx, span := startSpan(ctx, "ShareService.ValidateShare", attribute.String("share.path", s3Path))
+	defer func() { endSpan(span, err) }()
+
 	// Validate S3 path
 	if !s.isValidS3Path(s3Path) {
 		return domain.ErrInvalidPath
 	}
 
+	// Links of public shares carry no token to verify
+	fallback := secret != ""
+	if s.config.SignedURLs && secret != "" {
+		switch {
+		case hmac.Equal([]byte(s.signToken(ctx, s3Path, expiresAt, false)), []byte(secret)):
+		case hmac.Equal([]byte(s.signToken(ctx, s3Path, expiresAt, true)), []byte(secret)):
+			// The restrictions of the share cannot be checked without its
+			// record
+			fallback = false
+		default:
+			return domain.ErrInvalidSignature
+		}
+	}
+
+	// A verified token vouches for its link until its expiry while the share
+	// record is out of reach
+	err = s.checkSecret(ctx, s3Path, secret)
+	if fallback && s.cacheFallback(err) && time.Now().Before(expiresAt) {
+		return nil
+	}
+	return err
+}
+
+// checkSecret checks secret against the share record of s3Path, counting
+// invalid secrets towards the lockout. Only paths with a share record count
+// failures, so guessing at unshared paths cannot fill the cache with
+// counters.
+func (s *ShareService) checkSecret(ctx context.Context, s3Path, secret string) error {
+	if !s.lockoutEnabled() {
+		_, err := s.validateSecret(ctx, s3Path, secret)
+		return err
+	}
+
+	locked, lockErr := s.isLockedOut(ctx, s.generateFailureKey(ctx, s3Path))
+	if lockErr != nil {
+		return fmt.Errorf("failed to check lockout: %w", lockErr)
+	}
+	if locked {
+		return domain.ErrUnauthorized
+	}
+
+	shared, err := s.validateSecret(ctx, s3Path, secret)
+	if shared && err == domain.ErrUnauthorized {
+		if incrErr := s.recordFailedAttempt(ctx, s.generateFailureKey(ctx, s3Path)); incrErr != nil {
+			return incrErr
+		}
+	}
+	return err
+}
+
+// ValidatePassword checks password against the share's password hash and
+// succeeds for shares without a password. It returns ErrPasswordRequired when
+// no password is given and ErrUnauthorized when it does not match; wrong
+// passwords count towards the lockout like invalid secrets.
+func (s *ShareService) ValidatePassword(ctx context.Context, s3Path, password string) error {
+	record, err := s.getShareRecord(ctx, s3Path)
+	if err != nil {
+		if err == domain.ErrNotFound {
+			return domain.ErrUnauthorized
+		}
+		if s.cacheFallback(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to validate password: %w", err)
+	}
+
+	if record.PasswordHash == "" {
+		return nil
+	}
+	if password == "" {
+		return domain.ErrPasswordRequired
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(record.PasswordHash), []byte(password)); err != nil {
+		if s.lockoutEnabled() {
+			if incrErr := s.recordFailedAttempt(ctx, s.generateFailureKey(ctx, s3Path)); incrErr != nil {
+				return incrErr
+			}
+		}
+		return domain.ErrUnauthorized
+	}
+
+	return nil
+}
+
+// recordFailedAttempt counts a failed attempt on the failed attempt counter
+// key. The window is fixed from the first failure: later failures do not
+// extend it, so a steady trickle of guesses cannot keep a lockout forever.
+func (s *ShareService) recordFailedAttempt(ctx context.Context, key string) error {
+	count, err := s.cache.Incr(ctx, key, 0)
+	if err != nil {
+		return fmt.Errorf("failed to record failed attempt: %w", err)
+	}
+	if count == 1 {
+		if err := s.cache.Expire(ctx, key, s.config.LockoutWindow); err != nil {
+			return fmt.Errorf("failed to record failed attempt: %w", err)
+		}
+	}
+	return nil
+}
+
+// validateSecret checks secret against the stored share record, reporting
+// whether a share record exists for s3Path
+func (s *ShareService) validateSecret(ctx context.Context, s3Path, secret string) (bool, error) {
 	// Check cache
-	cacheKey := s.generateCacheKey(s3Path)
-	cachedSecret, err := s.cache.Get(ctx, cacheKey)
+	record, err := s.getShareRecord(ctx, s3Path)
+	if err != nil {
+		if err == domain.ErrNotFound {
+			return false, s.validateConsumed(ctx, s3Path, secret)
+		}
+		return false, fmt.Errorf("failed to validate share: %w", err)
+	}
+
+	// Public shares accept any secret; expiry is enforced by the record TTL
+	// and the URL date
+	if record.Public {
+		if time.Now().After(record.ExpiresAt) {
+			return true, domain.ErrUnauthorized
+		}
+		return true, nil
+	}
+
+	if !matchesSecret(record, secret) {
+		return true, domain.ErrUnauthorized
+	}
+	return true, nil
+}
+
+// validateConsumed tells links of shares consumed by their download limit
+// apart from links of shares that never existed or were revoked, returning
+// ErrConsumed only for secrets the consumed share accepted
+func (s *ShareService) validateConsumed(ctx context.Context, s3Path, secret string) error {
+	value, err := s.cache.Get(ctx, s.generateConsumedKey(ctx, s3Path))
 	if err != nil {
 		if err == domain.ErrNotFound {
 			return domain.ErrUnauthorized
@@ -86,23 +501,559 @@ func (s *ShareService) ValidateShare(ctx context.Context, s3Path, secret string)
 		return fmt.Errorf("failed to validate share: %w", err)
 	}
 
-	// Validate secret
-	if cachedSecret != secret {
+	record, err := domain.DecodeShareRecord(value)
+	if err != nil {
+		return err
+	}
+	if !record.Public && !matchesSecret(record, secret) {
 		return domain.ErrUnauthorized
 	}
+	return domain.ErrConsumed
+}
+
+// matchesSecret reports whether secret is the secret of record, or the secret
+// a rotation replaced during its grace period
+func matchesSecret(record *domain.ShareRecord, secret string) bool {
+	// Validate secret in constant time; a length mismatch also returns 0
+	if subtle.ConstantTimeCompare([]byte(record.Secret), []byte(secret)) == 1 {
+		return true
+	}
+
+	// The secret replaced by a rotation stays valid for its grace period
+	return record.PreviousSecret != "" && time.Now().Before(record.PreviousSecretExpiresAt) &&
+		subtle.ConstantTimeCompare([]byte(record.PreviousSecret), []byte(secret)) == 1
+}
+
+// ExtendShare moves the expiry of an existing share to expiresAt, keeping its
+// secret so that links already handed out keep working until then. The new
+// expiry is bounded by MaxAgeDays from the creation of the share, not from
+// now, so that repeated extensions cannot keep a share alive forever. It
+// returns ErrNotFound when no share exists for s3Path.
+func (s *ShareService) ExtendShare(ctx context.Context, s3Path string, expiresAt time.Time) error {
+	// Validate S3 path
+	if !s.isValidS3Path(s3Path) {
+		return domain.ErrInvalidPath
+	}
+
+	expiration := time.Until(expiresAt)
+	if expiration <= 0 {
+		return fmt.Errorf("expiration time must be in the future: %w", domain.ErrInvalidDate)
+	}
+
+	record, err := s.getShareRecord(ctx, s3Path)
+	if err != nil {
+		if err == domain.ErrNotFound {
+			return domain.ErrNotFound
+		}
+		return fmt.Errorf("failed to load share: %w", err)
+	}
+
+	// Records stored as a bare secret carry no creation time; start their
+	// lifetime now
+	if record.CreatedAt.IsZero() {
+		record.CreatedAt = time.Now()
+	}
+	if s.config.MaxAgeDays > 0 && expiresAt.Sub(record.CreatedAt) > s.maxAge() {
+		return domain.ErrMaxAgeExceeded
+	}
+
+	// Links keep encoding the expiry the share had before its first extension
+	if record.LinkExpiresAt.IsZero() {
+		record.LinkExpiresAt = record.ExpiresAt
+	}
+	record.ExpiresAt = expiresAt
+
+	value, err := domain.EncodeShareRecord(record)
+	if err != nil {
+		return err
+	}
+	if err := s.cache.Set(ctx, s.generateCacheKey(ctx, s3Path), value, expiration); err != nil {
+		return fmt.Errorf("failed to store share in cache: %w", err)
+	}
+	s.forgetShareRecord(ctx, s3Path)
+
+	// Keep the download counter for as long as the share, so that the limit
+	// is not reset when the original expiry passes
+	if err := s.cache.Expire(ctx, s.generateDownloadKey(ctx, s3Path), expiration); err != nil {
+		return fmt.Errorf("failed to extend download counter: %w", err)
+	}
+	if err := s.cache.Expire(ctx, s.generateAccessKey(ctx, s3Path), expiration); err != nil {
+		return fmt.Errorf("failed to extend last access: %w", err)
+	}
+	if record.ShortCode != "" {
+		if err := s.cache.Expire(ctx, s.generateShortCodeKey(ctx, record.ShortCode), expiration); err != nil {
+			return fmt.Errorf("failed to extend short code: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// IsExtended reports whether linkExpiry, the expiry encoded in a link of
+// s3Path, is the original expiry of a share since extended with ExtendShare
+// that is still active. Links carrying any other expiry are not revived.
+func (s *ShareService) IsExtended(ctx context.Context, s3Path string, linkExpiry time.Time) (bool, error) {
+	if !s.isValidS3Path(s3Path) {
+		return false, nil
+	}
+
+	record, err := s.getShareRecord(ctx, s3Path)
+	if err != nil {
+		if err == domain.ErrNotFound {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to look up share: %w", err)
+	}
+
+	return !record.LinkExpiresAt.IsZero() &&
+		shareURLExpiry(record.LinkExpiresAt).Equal(linkExpiry) &&
+		time.Now().Before(record.ExpiresAt), nil
+}
+
+// RevokeShare deletes a share so that its link stops validating
+func (s *ShareService) RevokeShare(ctx context.Context, s3Path string) error {
+	// Validate S3 path
+	if !s.isValidS3Path(s3Path) {
+		return domain.ErrInvalidPath
+	}
+
+	if _, err := s.cache.Get(ctx, s.generateCacheKey(ctx, s3Path)); err != nil {
+		if err == domain.ErrNotFound {
+			return domain.ErrNotFound
+		}
+		return fmt.Errorf("failed to look up share: %w", err)
+	}
+
+	return s.deleteShare(ctx, s3Path)
+}
+
+// DeleteObject deletes a stored object together with any share of it. The
+// share is revoked first so that a failed delete can leave an unshared object
+// behind but never a share pointing at a deleted one.
+func (s *ShareService) DeleteObject(ctx context.Context, s3Path string) (err error) {
+	ctx, span := startSpan(ctx, "ShareService.DeleteObject", attribute.String("share.path", s3Path))
+	defer func() { endSpan(span, err) }()
+
+	// Validate S3 path
+	if !s.isValidS3Path(s3Path) {
+		return domain.ErrInvalidPath
+	}
+
+	if err := s.deleteShare(ctx, s3Path); err != nil {
+		return err
+	}
+
+	if err := s.storage.DeleteObject(ctx, s3Path); err != nil {
+		return fmt.Errorf("failed to delete object: %w", err)
+	}
+
+	if s.objectCacheEnabled() {
+		if err := s.cache.Delete(ctx, s.generateObjectKey(ctx, s3Path)); err != nil {
+			return fmt.Errorf("failed to evict cached object: %w", err)
+		}
+	}
 
 	return nil
 }
 
+// ListObjects returns a page of stored objects under prefix; an empty prefix
+// lists the whole bucket
+func (s *ShareService) ListObjects(ctx context.Context, prefix, token string) (_ []domain.ObjectMetadata, _ string, err error) {
+	ctx, span := startSpan(ctx, "ShareService.ListObjects", attribute.String("list.prefix", prefix))
+	defer func() { endSpan(span, err) }()
+
+	// Validate the prefix like an object key
+	if prefix != "" && !s.isValidS3Path(prefix) {
+		return nil, "", domain.ErrInvalidPath
+	}
+
+	objects, next, err := s.storage.ListObjects(ctx, prefix, token)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to list objects: %w", err)
+	}
+
+	return objects, next, nil
+}
+
+// deleteShare removes the share record, download counter and last access of
+// s3Path, succeeding when none exists
+func (s *ShareService) deleteShare(ctx context.Context, s3Path string) error {
+	if err := s.cache.Delete(ctx, s.generateCacheKey(ctx, s3Path)); err != nil {
+		return fmt.Errorf("failed to revoke share: %w", err)
+	}
+	s.forgetShareRecord(ctx, s3Path)
+
+	if err := s.cache.Delete(ctx, s.generateDownloadKey(ctx, s3Path)); err != nil {
+		return fmt.Errorf("failed to delete download counter: %w", err)
+	}
+
+	if err := s.cache.Delete(ctx, s.generateAccessKey(ctx, s3Path)); err != nil {
+		return fmt.Errorf("failed to delete last access: %w", err)
+	}
+
+	if err := s.cache.Delete(ctx, s.generateConsumedKey(ctx, s3Path)); err != nil {
+		return fmt.Errorf("failed to delete consumed share: %w", err)
+	}
+
+	return nil
+}
+
+// RecordDownload counts a download of the share, records its time as the
+// last access, and revokes shares with a download limit once it is reached.
+// The counter is incremented atomically so concurrent downloads can never
+// exceed the limit; downloads past the limit return ErrConsumed, as do later
+// links of the share until it would have expired. The revoked record is kept
+// as consumed for resumeWindow, so that the downloads it counted can resume.
+func (s *ShareService) RecordDownload(ctx context.Context, s3Path string) error {
+	record, err := s.getShareRecord(ctx, s3Path)
+	if err != nil {
+		if err == domain.ErrNotFound {
+			return domain.ErrUnauthorized
+		}
+		// Downloads that cannot be counted are not limited either
+		if s.cacheFallback(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to load share: %w", err)
+	}
+
+	// Usage is tracked for as long as the share lives; records stored as a
+	// bare secret carry no expiry and are tracked until deleted
+	expiration := max(time.Until(record.ExpiresAt), 0)
+	count, err := s.cache.Incr(ctx, s.generateDownloadKey(ctx, s3Path), expiration)
+	if err != nil {
+		return fmt.Errorf("failed to record download: %w", err)
+	}
+	accessedAt := time.Now().UTC().Format(time.RFC3339Nano)
+	if err := s.cache.Set(ctx, s.generateAccessKey(ctx, s3Path), accessedAt, expiration); err != nil {
+		return fmt.Errorf("failed to record last access: %w", err)
+	}
+
+	if record.MaxDownloads <= 0 {
+		return nil
+	}
+	if count > int64(record.MaxDownloads) {
+		return domain.ErrConsumed
+	}
+
+	// The last permitted download revokes the share for everyone else,
+	// remembering the consumed record to tell its links apart
+	if count == int64(record.MaxDownloads) {
+		value, err := domain.EncodeShareRecord(record)
+		if err != nil {
+			return err
+		}
+		if err := s.cache.Set(ctx, s.generateConsumedKey(ctx, s3Path), value, expiration); err != nil {
+			return fmt.Errorf("failed to record consumed share: %w", err)
+		}
+
+		record.Consumed = true
+		value, err = domain.EncodeShareRecord(record)
+		if err != nil {
+			return err
+		}
+		retention := resumeWindow
+		if expiration > 0 {
+			retention = min(expiration, resumeWindow)
+		}
+		if err := s.cache.Set(ctx, s.generateCacheKey(ctx, s3Path), value, retention); err != nil {
+			return fmt.Errorf("failed to revoke consumed share: %w", err)
+		}
+		s.forgetShareRecord(ctx, s3Path)
+	}
+
+	return nil
+}
+
+// resumedDownloadKey is the context key of the download a request resumes
+type resumedDownloadKey struct{}
+
+// WithResumedDownload returns ctx resuming the download identified by
+// downloadID, as passed to RecordResumableDownload. A share consumed by that
+// download stays available through ctx for resumeWindow.
+func WithResumedDownload(ctx context.Context, downloadID string) context.Context {
+	return context.WithValue(ctx, resumedDownloadKey{}, downloadID)
+}
+
+// resumesDownload reports whether ctx resumes a download of the share of
+// s3Path recorded by RecordResumableDownload
+func (s *ShareService) resumesDownload(ctx context.Context, s3Path string) bool {
+	downloadID, _ := ctx.Value(resumedDownloadKey{}).(string)
+	if downloadID == "" {
+		return false
+	}
+	_, err := s.cache.Get(ctx, s.generateResumeKey(ctx, s3Path, downloadID))
+	return err == nil
+}
+
+// RecordResumableDownload records a download of the share of s3Path like
+// RecordDownload and remembers it under downloadID, which identifies the
+// client and the version of the object, for resumeWindow. Resumed downloads
+// whose downloadID is remembered continue a download already counted and are
+// not counted again.
+func (s *ShareService) RecordResumableDownload(ctx context.Context, s3Path, downloadID string, resumed bool) error {
+	key := s.generateResumeKey(ctx, s3Path, downloadID)
+	if resumed {
+		if _, err := s.cache.Get(ctx, key); err == nil {
+			return nil
+		}
+	}
+
+	if err := s.RecordDownload(ctx, s3Path); err != nil {
+		return err
+	}
+	// A lost marker only counts the resumed download again
+	_ = s.cache.Set(ctx, key, "1", resumeWindow)
+	return nil
+}
+
+// PresignShare returns a presigned storage URL for a share created in redirect
+// mode, valid no longer than the share itself. It returns an empty URL for
+// shares that are proxied.
+func (s *ShareService) PresignShare(ctx context.Context, s3Path string) (string, error) {
+	record, err := s.getShareRecord(ctx, s3Path)
+	if err != nil {
+		if err == domain.ErrNotFound {
+			return "", domain.ErrUnauthorized
+		}
+		if s.cacheFallback(err) {
+			return "", nil
+		}
+		return "", fmt.Errorf("failed to load share: %w", err)
+	}
+
+	if record.Mode != domain.ShareModeRedirect {
+		return "", nil
+	}
+
+	presigner, ok := s.storage.(domain.Presigner)
+	if !ok {
+		return "", domain.ErrPresignNotSupported
+	}
+
+	// Clamp the URL lifetime to what remains of the share
+	remaining := time.Until(record.ExpiresAt)
+	if remaining <= 0 {
+		return "", domain.ErrExpired
+	}
+
+	if record.VersionID != "" {
+		ctx = domain.WithObjectVersion(ctx, record.VersionID)
+	}
+	url, err := presigner.PresignGetObject(ctx, s3Path, remaining)
+	if err != nil {
+		return "", fmt.Errorf("failed to presign object: %w", err)
+	}
+	return url, nil
+}
+
+// GetShareInfo reports the expiry and remaining lifetime of an existing share
+func (s *ShareService) GetShareInfo(ctx context.Context, s3Path string) (*domain.ShareInfo, error) {
+	// Validate S3 path
+	if !s.isValidS3Path(s3Path) {
+		return nil, domain.ErrInvalidPath
+	}
+
+	record, err := s.getShareRecord(ctx, s3Path)
+	if err != nil {
+		if err == domain.ErrNotFound {
+			return nil, domain.ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get share info: %w", err)
+	}
+
+	ttl, err := s.cache.TTL(ctx, s.generateCacheKey(ctx, s3Path))
+	if err != nil {
+		if err == domain.ErrNotFound {
+			return nil, domain.ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get share TTL: %w", err)
+	}
+
+	// Records stored as a bare secret carry no expiry; derive it from the TTL
+	expiresAt := record.ExpiresAt
+	if expiresAt.IsZero() && ttl > 0 {
+		expiresAt = time.Now().Add(ttl)
+	}
+
+	downloadCount, lastAccessedAt, err := s.shareUsage(ctx, s3Path)
+	if err != nil {
+		return nil, err
+	}
+
+	return &domain.ShareInfo{
+		S3Path:         s3Path,
+		ExpiresAt:      expiresAt,
+		TTL:            ttl,
+		Active:         expiresAt.IsZero() || time.Now().Before(expiresAt),
+		DownloadCount:  downloadCount,
+		LastAccessedAt: lastAccessedAt,
+	}, nil
+}
+
+// shareUsage returns the number of downloads of the share of s3Path and the
+// time of the latest one, zero for shares never downloaded
+func (s *ShareService) shareUsage(ctx context.Context, s3Path string) (int64, time.Time, error) {
+	var count int64
+	value, err := s.cache.Get(ctx, s.generateDownloadKey(ctx, s3Path))
+	switch {
+	case err == nil:
+		count, err = strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return 0, time.Time{}, fmt.Errorf("failed to decode download counter: %w", err)
+		}
+	case err != domain.ErrNotFound:
+		return 0, time.Time{}, fmt.Errorf("failed to get download counter: %w", err)
+	}
+
+	var accessedAt time.Time
+	value, err = s.cache.Get(ctx, s.generateAccessKey(ctx, s3Path))
+	switch {
+	case err == nil:
+		accessedAt, err = time.Parse(time.RFC3339Nano, value)
+		if err != nil {
+			return 0, time.Time{}, fmt.Errorf("failed to decode last access: %w", err)
+		}
+	case err != domain.ErrNotFound:
+		return 0, time.Time{}, fmt.Errorf("failed to get last access: %w", err)
+	}
+
+	return count, accessedAt, nil
+}
+
+// ListShares returns the active shares of the tenant sorted by path. Keys are
+// enumerated incrementally with Scan so that large keyspaces do not block the
+// cache, and shares expiring during the scan are skipped.
+func (s *ShareService) ListShares(ctx context.Context) ([]domain.ShareInfo, error) {
+	prefix := s.generateCacheKey(ctx, "")
+
+	seen := make(map[string]bool)
+	var cursor uint64
+	for {
+		keys, next, err := s.cache.Scan(ctx, prefix, cursor)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan shares: %w", err)
+		}
+		for _, key := range keys {
+			seen[strings.TrimPrefix(key, prefix)] = true
+		}
+		if next == 0 {
+			break
+		}
+		cursor = next
+	}
+
+	shares := make([]domain.ShareInfo, 0, len(seen))
+	for s3Path := range seen {
+		info, err := s.GetShareInfo(ctx, s3Path)
+		if err != nil {
+			if err == domain.ErrNotFound || err == domain.ErrInvalidPath {
+				continue
+			}
+			return nil, err
+		}
+		shares = append(shares, *info)
+	}
+	sort.Slice(shares, func(i, j int) bool { return shares[i].S3Path < shares[j].S3Path })
+
+	return shares, nil
+}
+
+// SweepExpiredShares deletes the share records of the tenant whose expiry is
+// before now but which are still stored, as left behind by keys written
+// without a TTL, and returns how many it deleted. Records stored as a bare
+// secret carry no expiry and are kept.
+func (s *ShareService) SweepExpiredShares(ctx context.Context, now time.Time) (int, error) {
+	prefix := s.generateCacheKey(ctx, "")
+
+	// Collect the expired paths before deleting any, so that deletions do not
+	// disturb the scan; keys may be returned more than once
+	expired := make(map[string]bool)
+	var cursor uint64
+	for {
+		keys, next, err := s.cache.Scan(ctx, prefix, cursor)
+		if err != nil {
+			return 0, fmt.Errorf("failed to scan shares: %w", err)
+		}
+		for _, key := range keys {
+			value, err := s.cache.Get(ctx, key)
+			if err != nil {
+				// Records expiring or revoked during the sweep are gone already
+				if err == domain.ErrNotFound {
+					continue
+				}
+				return 0, fmt.Errorf("failed to load share: %w", err)
+			}
+			// Undecodable records are left for inspection
+			record, err := domain.DecodeShareRecord(value)
+			if err != nil || record.ExpiresAt.IsZero() || !record.ExpiresAt.Before(now) {
+				continue
+			}
+			expired[strings.TrimPrefix(key, prefix)] = true
+		}
+		if next == 0 {
+			break
+		}
+		cursor = next
+	}
+
+	reaped := 0
+	for s3Path := range expired {
+		if err := s.deleteShare(ctx, s3Path); err != nil {
+			return reaped, err
+		}
+		reaped++
+	}
+	return reaped, nil
+}
+
+// GetCachePolicy reports how responses for an existing share may be cached.
+// It must be called before RecordDownload, which revokes the share once its
+// last permitted download is recorded.
+func (s *ShareService) GetCachePolicy(ctx context.Context, s3Path string) (*domain.CachePolicy, error) {
+	record, err := s.getShareRecord(ctx, s3Path)
+	if err != nil {
+		if err == domain.ErrNotFound {
+			return nil, domain.ErrUnauthorized
+		}
+		// Responses of shares whose restrictions are unknown stay uncached
+		if s.cacheFallback(err) {
+			return &domain.CachePolicy{Private: true}, nil
+		}
+		return nil, fmt.Errorf("failed to load share: %w", err)
+	}
+
+	// A cached response of an IP- or referer-restricted share would be
+	// served to clients outside the allowed networks or pages
+	restricted := len(record.AllowedIPs) > 0 || len(record.AllowedReferers) > 0 || len(s.config.AllowedReferers) > 0
+	return &domain.CachePolicy{
+		Private:   record.PasswordHash != "" || record.MaxDownloads > 0 || restricted,
+		ExpiresAt: record.ExpiresAt,
+	}, nil
+}
+
 // GetObject retrieves an object for sharing
-func (s *ShareService) GetObject(ctx context.Context, s3Path string) (domain.ObjectReader, error) {
+func (s *ShareService) GetObject(ctx context.Context, s3Path string) (_ domain.ObjectReader, err error) {
+	ctx, span := startSpan(ctx, "ShareService.GetObject", attribute.String("share.path", s3Path))
+	defer func() { endSpan(span, err) }()
+
 	// Validate S3 path
 	if !s.isValidS3Path(s3Path) {
 		return nil, domain.ErrInvalidPath
 	}
 
-	// Get object from storage
-	reader, err := s.storage.GetObject(ctx, s3Path)
+	ctx, err = s.withShareVersion(ctx, s3Path)
+	if err != nil {
+		return nil, err
+	}
+
+	// Get object from the object cache or storage
+	var reader domain.ObjectReader
+	if s.objectCacheEnabled() {
+		reader, err = s.getCachedObject(ctx, s3Path)
+	} else {
+		reader, err = s.storage.GetObject(ctx, s3Path)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to get object: %w", err)
 	}
@@ -110,10 +1061,141 @@ func (s *ShareService) GetObject(ctx context.Context, s3Path string) (domain.Obj
 	return reader, nil
 }
 
+// GetObjectRange retrieves the inclusive byte range [start, end] of an object
+func (s *ShareService) GetObjectRange(ctx context.Context, s3Path string, start, end int64) (_ domain.ObjectReader, err error) {
+	ctx, span := startSpan(ctx, "ShareService.GetObjectRange",
+		attribute.String("share.path", s3Path),
+		attribute.Int64("range.start", start),
+		attribute.Int64("range.end", end),
+	)
+	defer func() { endSpan(span, err) }()
+
+	// Validate S3 path
+	if !s.isValidS3Path(s3Path) {
+		return nil, domain.ErrInvalidPath
+	}
+
+	ctx, err = s.withShareVersion(ctx, s3Path)
+	if err != nil {
+		return nil, err
+	}
+
+	reader, err := s.storage.GetObjectRange(ctx, s3Path, start, end)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get object range: %w", err)
+	}
+
+	return reader, nil
+}
+
+// HeadObject retrieves metadata for a shared object
+func (s *ShareService) HeadObject(ctx context.Context, s3Path string) (_ *domain.ObjectMetadata, err error) {
+	ctx, span := startSpan(ctx, "ShareService.HeadObject", attribute.String("share.path", s3Path))
+	defer func() { endSpan(span, err) }()
+
+	// Validate S3 path
+	if !s.isValidS3Path(s3Path) {
+		return nil, domain.ErrInvalidPath
+	}
+
+	ctx, err = s.withShareVersion(ctx, s3Path)
+	if err != nil {
+		return nil, err
+	}
+
+	metadata, err := s.storage.HeadObject(ctx, s3Path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to head object: %w", err)
+	}
+
+	return metadata, nil
+}
+
+// CheckDependencies pings the cache and storage backends and returns the
+// error of each failing dependency keyed by its name
+func (s *ShareService) CheckDependencies(ctx context.Context) map[string]error {
+	failures := make(map[string]error)
+
+	if err := s.cache.Ping(ctx); err != nil {
+		failures["cache"] = err
+	}
+	if err := s.storage.HealthCheck(ctx); err != nil {
+		failures["storage"] = err
+	}
+
+	return failures
+}
+
+// getShareRecord loads and decodes the share record stored for s3Path.
+// Consumed records are not found, except by the downloads they counted
+// resuming through ctx.
+func (s *ShareService) getShareRecord(ctx context.Context, s3Path string) (*domain.ShareRecord, error) {
+	value, err := s.loadShareRecord(ctx, s.generateCacheKey(ctx, s3Path))
+	if err != nil {
+		return nil, err
+	}
+	record, err := domain.DecodeShareRecord(value)
+	if err != nil {
+		return nil, err
+	}
+	if record.Consumed && !s.resumesDownload(ctx, s3Path) {
+		return nil, domain.ErrNotFound
+	}
+	return record, nil
+}
+
+// withShareVersion pins ctx to the object version the share of s3Path was
+// created for, if any. Paths without a share of their own, such as the
+// objects of archive shares, address the latest version.
+func (s *ShareService) withShareVersion(ctx context.Context, s3Path string) (context.Context, error) {
+	record, err := s.getShareRecord(ctx, s3Path)
+	if err != nil {
+		if err == domain.ErrNotFound || s.cacheFallback(err) {
+			return ctx, nil
+		}
+		return nil, fmt.Errorf("failed to load share: %w", err)
+	}
+
+	if record.VersionID == "" {
+		return ctx, nil
+	}
+	return domain.WithObjectVersion(ctx, record.VersionID), nil
+}
+
+// cacheFallback reports whether err from loading share state means the
+// cache is unavailable and signed links fall back to their signature
+func (s *ShareService) cacheFallback(err error) bool {
+	return s.config.SignedURLs && s.config.SignedURLFallback && errors.Is(err, domain.ErrCacheUnavailable)
+}
+
 // isValidS3Path validates that the S3 path is safe
 func (s *ShareService) isValidS3Path(s3Path string) bool {
-	// Clean the path to prevent directory traversal
+	return isValidObjectKey(s3Path)
+}
+
+// isShareablePath reports whether s3Path lies under one of the shareable
+// prefixes. The cleaned path is checked, so that "public/../internal/x"
+// cannot reach keys outside them on backends that clean paths.
+func (s *ShareService) isShareablePath(s3Path string) bool {
+	if len(s.config.ShareablePrefixes) == 0 {
+		return true
+	}
 	cleanPath := path.Clean(s3Path)
+	if IsPrefixShare(s3Path) {
+		cleanPath += "/"
+	}
+	for _, prefix := range s.config.ShareablePrefixes {
+		if strings.HasPrefix(cleanPath, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// isValidObjectKey validates that an object key is a safe relative path
+func isValidObjectKey(key string) bool {
+	// Clean the path to prevent directory traversal
+	cleanPath := path.Clean(key)
 
 	// Ensure it doesn't start with "/" or contain ".."
 	if strings.HasPrefix(cleanPath, "/") || strings.Contains(cleanPath, "..") {
@@ -124,26 +1206,156 @@ func (s *ShareService) isValidS3Path(s3Path string) bool {
 	return cleanPath != "" && cleanPath != "."
 }
 
+// lockoutEnabled reports whether failed attempts lock paths out
+func (s *ShareService) lockoutEnabled() bool {
+	return s.config.MaxFailedAttempts > 0 && s.config.LockoutWindow > 0
+}
+
+// isLockedOut reports whether the failed attempt counter key has reached the
+// failed attempt limit
+func (s *ShareService) isLockedOut(ctx context.Context, key string) (bool, error) {
+	value, err := s.cache.Get(ctx, key)
+	if err != nil {
+		if err == domain.ErrNotFound {
+			return false, nil
+		}
+		return false, err
+	}
+
+	failures, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return false, fmt.Errorf("invalid failed attempt counter: %w", err)
+	}
+	return failures >= int64(s.config.MaxFailedAttempts), nil
+}
+
+// maxAge returns the maximum share lifetime configured by MaxAgeDays
+func (s *ShareService) maxAge() time.Duration {
+	return time.Duration(s.config.MaxAgeDays) * 24 * time.Hour
+}
+
+// tenantKeyPrefix returns the cache key prefix of the tenant ctx is scoped
+// to. The default tenant has none so that keys written before tenants existed
+// stay valid; other tenants use their configured prefix or "tenant:{id}:".
+func (s *ShareService) tenantKeyPrefix(ctx context.Context) string {
+	tenantID := domain.TenantFromContext(ctx)
+	if tenantID == "" {
+		return ""
+	}
+	if prefix, ok := s.config.TenantKeyPrefixes[tenantID]; ok {
+		return prefix
+	}
+	return fmt.Sprintf("tenant:%s:", tenantID)
+}
+
+// keyPrefix returns the configured prefix of share record keys
+func (s *ShareService) keyPrefix() string {
+	if s.config.KeyPrefix == "" {
+		return DefaultKeyPrefix
+	}
+	return s.config.KeyPrefix
+}
+
+// keyName returns the name of the cache keys of kind, such as "downloads".
+// Keys of the default prefix keep the "image-{kind}" names they had before
+// the prefix was configurable; other prefixes use "{prefix}-{kind}".
+func (s *ShareService) keyName(kind string) string {
+	prefix := s.keyPrefix()
+	if prefix == DefaultKeyPrefix {
+		return "image-" + kind
+	}
+	return prefix + "-" + kind
+}
+
 // generateCacheKey creates a cache key for the S3 path
-func (s *ShareService) generateCacheKey(s3Path string) string {
-	return fmt.Sprintf("image-auth:%s", s3Path)
+func (s *ShareService) generateCacheKey(ctx context.Context, s3Path string) string {
+	return fmt.Sprintf("%s%s:%s", s.tenantKeyPrefix(ctx), s.keyPrefix(), s3Path)
+}
+
+// generateObjectKey creates the cache key of the cached body of the S3 path,
+// keeping the body of a version pinned by ctx apart from the latest version
+func (s *ShareService) generateObjectKey(ctx context.Context, s3Path string) string {
+	if versionID := domain.ObjectVersionFromContext(ctx); versionID != "" {
+		return fmt.Sprintf("%s%s:%s:%s", s.tenantKeyPrefix(ctx), s.keyName("object-versions"), versionID, s3Path)
+	}
+	return fmt.Sprintf("%s%s:%s", s.tenantKeyPrefix(ctx), s.keyName("objects"), s3Path)
+}
+
+// generateDownloadKey creates the cache key of the download counter for the S3 path
+func (s *ShareService) generateDownloadKey(ctx context.Context, s3Path string) string {
+	return fmt.Sprintf("%s%s:%s", s.tenantKeyPrefix(ctx), s.keyName("downloads"), s3Path)
+}
+
+// generateAccessKey creates the cache key of the last access time of the S3 path
+func (s *ShareService) generateAccessKey(ctx context.Context, s3Path string) string {
+	return fmt.Sprintf("%s%s:%s", s.tenantKeyPrefix(ctx), s.keyName("accessed"), s3Path)
+}
+
+// generateConsumedKey creates the cache key of the record of a share consumed
+// by its download limit
+func (s *ShareService) generateConsumedKey(ctx context.Context, s3Path string) string {
+	return fmt.Sprintf("%s%s:%s", s.tenantKeyPrefix(ctx), s.keyName("consumed"), s3Path)
+}
+
+// generateResumeKey creates the cache key remembering a counted download of
+// the S3 path, hashing the download ID so that keys stay short
+func (s *ShareService) generateResumeKey(ctx context.Context, s3Path, downloadID string) string {
+	sum := sha256.Sum256([]byte(downloadID))
+	return fmt.Sprintf("%s%s:%s:%s", s.tenantKeyPrefix(ctx), s.keyName("resumes"), hex.EncodeToString(sum[:16]), s3Path)
+}
+
+// generateFailureKey creates the cache key of the failed attempt counter for the S3 path
+func (s *ShareService) generateFailureKey(ctx context.Context, s3Path string) string {
+	return fmt.Sprintf("%s%s:%s", s.tenantKeyPrefix(ctx), s.keyName("failures"), s3Path)
+}
+
+// tenantBaseURL returns the base URL of links for the tenant ctx is scoped to;
+// tenant links carry the tenant in a /t/{id} path prefix
+func (s *ShareService) tenantBaseURL(ctx context.Context) string {
+	if tenantID := domain.TenantFromContext(ctx); tenantID != "" {
+		return fmt.Sprintf("%s/t/%s", s.config.BaseURL, tenantID)
+	}
+	return s.config.BaseURL
 }
 
 // generateShareURL creates a shareable URL
-func (s *ShareService) generateShareURL(s3Path string, expiresAt time.Time) string {
-	// Format date as YY/MM/DD
-	dateStr := expiresAt.Format("06/01/02")
+func (s *ShareService) generateShareURL(ctx context.Context, s3Path, secret string, expiresAt time.Time) string {
+	// Encode the expiry as unix seconds so the link expires with the record
+	expiry := strconv.FormatInt(expiresAt.Unix(), 10)
 
-	// Generate a secret (in real implementation, this should be cryptographically secure)
-	secret := s.generateSecret(s3Path, expiresAt)
+	// Prefix shares are served as archives from a separate route
+	baseURL := s.tenantBaseURL(ctx)
+	if IsPrefixShare(s3Path) {
+		baseURL += "/archive"
+	}
 
-	// Construct URL
-	return fmt.Sprintf("%s/%s/%s/%s", s.config.BaseURL, dateStr, secret, s3Path)
+	// Public shares have no secret to embed
+	if secret == "" {
+		secret = PublicLinkSegment
+	}
+
+	// Construct URL with the same secret that ValidateShare checks against
+	return fmt.Sprintf("%s/%s/%s/%s", baseURL, expiry, secret, s3Path)
+}
+
+// signToken computes a base64url HMAC-SHA256 over the canonical s3Path|expiresAt
+// string, prefixed with "tenant|" for tenants other than the default so a
+// token never validates for another tenant, and suffixed with "|restricted"
+// for shares whose restrictions rule out the cache fallback
+func (s *ShareService) signToken(ctx context.Context, s3Path string, expiresAt time.Time, restricted bool) string {
+	mac := hmac.New(sha256.New, []byte(s.config.SigningKey))
+	if tenantID := domain.TenantFromContext(ctx); tenantID != "" {
+		fmt.Fprintf(mac, "%s|", tenantID)
+	}
+	fmt.Fprintf(mac, "%s|%d", s3Path, expiresAt.Unix())
+	if restricted {
+		fmt.Fprint(mac, "|restricted")
+	}
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
 }
 
-// generateSecret generates a secret for the share (simplified for demo)
-func (s *ShareService) generateSecret(s3Path string, expiresAt time.Time) string {
-	// In a real implementation, use a proper secret generation method
-	// This is just for demonstration
-	return fmt.Sprintf("secret_%x", len(s3Path)+int(expiresAt.Unix()))
+// shareURLExpiry truncates expiresAt to the second precision encoded in
+// share URLs
+func shareURLExpiry(expiresAt time.Time) time.Time {
+	return time.Unix(expiresAt.Unix(), 0).UTC()
 }
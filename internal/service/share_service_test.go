@@ -3,6 +3,10 @@ package service
 import (
 	"context"
 	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
 	"testing"
 	"time"
 
@@ -21,6 +25,14 @@ func (m *mockStorageService) GetObject(ctx context.Context, key string) (domain.
 	return &mockObjectReader{}, nil
 }
 
+func (m *mockStorageService) GetObjectRange(ctx context.Context, key string, start, end int64) (domain.ObjectReader, error) {
+	return m.GetObject(ctx, key)
+}
+
+func (m *mockStorageService) HealthCheck(ctx context.Context) error {
+	return nil
+}
+
 func (m *mockStorageService) HeadObject(ctx context.Context, key string) (*domain.ObjectMetadata, error) {
 	if metadata, exists := m.objects[key]; exists {
 		return metadata, nil
@@ -28,17 +40,70 @@ func (m *mockStorageService) HeadObject(ctx context.Context, key string) (*domai
 	return nil, domain.ErrNotFound
 }
 
+func (m *mockStorageService) ListObjects(ctx context.Context, prefix, token string) ([]domain.ObjectMetadata, string, error) {
+	var objects []domain.ObjectMetadata
+	for key, metadata := range m.objects {
+		if strings.HasPrefix(key, prefix) {
+			object := *metadata
+			object.Key = key
+			objects = append(objects, object)
+		}
+	}
+	return objects, "", nil
+}
+
+func (m *mockStorageService) DeleteObject(ctx context.Context, key string) error {
+	if _, exists := m.objects[key]; !exists {
+		return domain.ErrNotFound
+	}
+	delete(m.objects, key)
+	return nil
+}
+
+// mockPresigningStorage is a mockStorageService that also presigns URLs
+type mockPresigningStorage struct {
+	*mockStorageService
+	expiry time.Duration
+}
+
+func (m *mockPresigningStorage) PresignGetObject(ctx context.Context, key string, expiry time.Duration) (string, error) {
+	m.expiry = expiry
+	return "https://bucket.s3.example.com/" + key + "?X-Amz-Signature=sig", nil
+}
+
+func (m *mockPresigningStorage) PresignPutObject(ctx context.Context, key string, expiry time.Duration) (string, error) {
+	m.expiry = expiry
+	return "https://bucket.s3.example.com/" + key + "?X-Amz-Signature=put", nil
+}
+
 // mockCacheService is a mock implementation of CacheService
 type mockCacheService struct {
 	store map[string]string
+	ttls  map[string]time.Duration
+	// getErr, when set, is returned by every Get
+	getErr error
 }
 
 func (m *mockCacheService) Set(ctx context.Context, key, value string, expiration time.Duration) error {
+	if m.ttls == nil {
+		m.ttls = make(map[string]time.Duration)
+	}
 	m.store[key] = value
+	m.ttls[key] = expiration
 	return nil
 }
 
+func (m *mockCacheService) SetNX(ctx context.Context, key, value string, expiration time.Duration) (bool, error) {
+	if _, exists := m.store[key]; exists {
+		return false, nil
+	}
+	return true, m.Set(ctx, key, value, expiration)
+}
+
 func (m *mockCacheService) Get(ctx context.Context, key string) (string, error) {
+	if m.getErr != nil {
+		return "", m.getErr
+	}
 	if value, exists := m.store[key]; exists {
 		return value, nil
 	}
@@ -47,6 +112,69 @@ func (m *mockCacheService) Get(ctx context.Context, key string) (string, error)
 
 func (m *mockCacheService) Delete(ctx context.Context, key string) error {
 	delete(m.store, key)
+	delete(m.ttls, key)
+	return nil
+}
+
+func (m *mockCacheService) Incr(ctx context.Context, key string, expiration time.Duration) (int64, error) {
+	return m.IncrBy(ctx, key, 1, expiration)
+}
+
+func (m *mockCacheService) IncrBy(ctx context.Context, key string, delta int64, expiration time.Duration) (int64, error) {
+	count, _ := strconv.ParseInt(m.store[key], 10, 64)
+	count += delta
+	m.store[key] = strconv.FormatInt(count, 10)
+	return count, nil
+}
+
+func (m *mockCacheService) TTL(ctx context.Context, key string) (time.Duration, error) {
+	if _, exists := m.store[key]; !exists {
+		return 0, domain.ErrNotFound
+	}
+	if ttl, exists := m.ttls[key]; exists && ttl > 0 {
+		return ttl, nil
+	}
+	return -1, nil
+}
+
+func (m *mockCacheService) Expire(ctx context.Context, key string, expiration time.Duration) error {
+	if _, exists := m.store[key]; !exists {
+		return nil
+	}
+	if m.ttls == nil {
+		m.ttls = make(map[string]time.Duration)
+	}
+	m.ttls[key] = expiration
+	return nil
+}
+
+// mockScanBatchSize is the number of new keys per mockCacheService.Scan batch
+const mockScanBatchSize = 2
+
+// Scan returns matching keys in key order a few at a time, repeating the last
+// key of the previous batch as Redis SCAN may
+func (m *mockCacheService) Scan(ctx context.Context, prefix string, cursor uint64) ([]string, uint64, error) {
+	var keys []string
+	for key := range m.store {
+		if strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	start := min(cursor, uint64(len(keys)))
+	end := min(start+mockScanBatchSize, uint64(len(keys)))
+	batch := keys[start:end]
+	if start > 0 {
+		batch = append([]string{keys[start-1]}, batch...)
+	}
+	if end == uint64(len(keys)) {
+		return batch, 0, nil
+	}
+	return batch, end, nil
+}
+
+func (m *mockCacheService) Ping(ctx context.Context) error {
 	return nil
 }
 
@@ -69,6 +197,18 @@ func (m *mockObjectReader) Size() int64 {
 	return 1024
 }
 
+func (m *mockObjectReader) ETag() string {
+	return `"mock-etag"`
+}
+
+func (m *mockObjectReader) LastModified() time.Time {
+	return time.Time{}
+}
+
+func (m *mockObjectReader) Checksum() string {
+	return ""
+}
+
 func TestShareService_CreateShare(t *testing.T) {
 	tests := []struct {
 		name        string
@@ -118,6 +258,37 @@ func TestShareService_CreateShare(t *testing.T) {
 			expectError: true,
 			errorType:   domain.ErrInvalidPath,
 		},
+		{
+			name: "expiry exactly at max age",
+			req: &domain.ShareRequest{
+				S3Path:    "images/photo.jpg",
+				Secret:    "test-secret",
+				ExpiresAt: time.Now().Add(90 * 24 * time.Hour),
+			},
+			setupMocks: func(storage *mockStorageService, cache *mockCacheService) {
+				storage.objects["images/photo.jpg"] = &domain.ObjectMetadata{
+					ContentType: "image/jpeg",
+					Size:        1024,
+				}
+			},
+			expectError: false,
+		},
+		{
+			name: "expiry one second past max age",
+			req: &domain.ShareRequest{
+				S3Path:    "images/photo.jpg",
+				Secret:    "test-secret",
+				ExpiresAt: time.Now().Add(90*24*time.Hour + time.Second),
+			},
+			setupMocks: func(storage *mockStorageService, cache *mockCacheService) {
+				storage.objects["images/photo.jpg"] = &domain.ObjectMetadata{
+					ContentType: "image/jpeg",
+					Size:        1024,
+				}
+			},
+			expectError: true,
+			errorType:   domain.ErrMaxAgeExceeded,
+		},
 		{
 			name: "expired link",
 			req: &domain.ShareRequest{
@@ -132,6 +303,7 @@ func TestShareService_CreateShare(t *testing.T) {
 				}
 			},
 			expectError: true,
+			errorType:   domain.ErrInvalidDate,
 		},
 	}
 
@@ -241,7 +413,7 @@ func TestShareService_ValidateShare(t *testing.T) {
 				BaseURL:    "https://example.com",
 			})
 
-			err := service.ValidateShare(context.Background(), tt.s3Path, tt.secret)
+			err := service.ValidateShare(context.Background(), tt.s3Path, tt.secret, time.Now().Add(24*time.Hour))
 
 			if tt.expectError {
 				if err == nil {
@@ -260,3 +432,1433 @@ func TestShareService_ValidateShare(t *testing.T) {
 		})
 	}
 }
+
+func TestShareService_CreateShareURLValidates(t *testing.T) {
+	storage := &mockStorageService{objects: map[string]*domain.ObjectMetadata{
+		"images/photo.jpg": {ContentType: "image/jpeg", Size: 1024},
+	}}
+	cache := &mockCacheService{store: make(map[string]string)}
+
+	service := NewShareService(storage, cache, &ShareConfig{
+		MaxAgeDays: 90,
+		BaseURL:    "https://example.com",
+	})
+
+	resp, err := service.CreateShare(context.Background(), &domain.ShareRequest{
+		S3Path:    "images/photo.jpg",
+		Secret:    "test-secret",
+		ExpiresAt: time.Now().Add(24 * time.Hour),
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Parse the URL the same way HandleImage does: /expiry/secret/path
+	path := strings.Trim(strings.TrimPrefix(resp.URL, "https://example.com"), "/")
+	parts := strings.Split(path, "/")
+	if len(parts) < 3 {
+		t.Fatalf("unexpected URL format: %s", resp.URL)
+	}
+	seconds, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		t.Fatalf("unexpected expiry in URL: %v", err)
+	}
+	expiresAt := time.Unix(seconds, 0)
+	secret := parts[1]
+	s3Path := strings.Join(parts[2:], "/")
+
+	if s3Path != "images/photo.jpg" {
+		t.Errorf("expected path images/photo.jpg, got %s", s3Path)
+	}
+
+	if err := service.ValidateShare(context.Background(), s3Path, secret, expiresAt); err != nil {
+		t.Errorf("expected URL to validate, got %v", err)
+	}
+}
+
+func TestShareService_SignedTokens(t *testing.T) {
+	storage := &mockStorageService{objects: map[string]*domain.ObjectMetadata{
+		"images/photo.jpg": {ContentType: "image/jpeg", Size: 1024},
+		"images/other.jpg": {ContentType: "image/jpeg", Size: 1024},
+	}}
+	cache := &mockCacheService{store: make(map[string]string)}
+
+	service := NewShareService(storage, cache, &ShareConfig{
+		MaxAgeDays: 90,
+		BaseURL:    "https://example.com",
+		SigningKey: "test-signing-key",
+		SignedURLs: true,
+	})
+
+	expiresAt := time.Now().Add(48 * time.Hour)
+	resp, err := service.CreateShare(context.Background(), &domain.ShareRequest{
+		S3Path:    "images/photo.jpg",
+		Secret:    "ignored-secret",
+		ExpiresAt: expiresAt,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	parts := strings.Split(strings.TrimPrefix(resp.URL, "https://example.com/"), "/")
+	seconds, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		t.Fatalf("unexpected expiry in URL: %v", err)
+	}
+	urlExpiry := time.Unix(seconds, 0)
+	token := parts[1]
+
+	if token == "ignored-secret" {
+		t.Fatalf("expected signed token in URL, got caller secret")
+	}
+
+	// Give the second path a share so only the signature can reject it
+	cache.store["image-auth:images/other.jpg"] = token
+
+	tests := []struct {
+		name      string
+		s3Path    string
+		token     string
+		expiresAt time.Time
+		errorType error
+	}{
+		{
+			name:      "valid token",
+			s3Path:    "images/photo.jpg",
+			token:     token,
+			expiresAt: urlExpiry,
+		},
+		{
+			name:      "tampered path",
+			s3Path:    "images/other.jpg",
+			token:     token,
+			expiresAt: urlExpiry,
+			errorType: domain.ErrInvalidSignature,
+		},
+		{
+			name:      "tampered expiry",
+			s3Path:    "images/photo.jpg",
+			token:     token,
+			expiresAt: urlExpiry.AddDate(0, 0, 30),
+			errorType: domain.ErrInvalidSignature,
+		},
+		{
+			name:      "expiry moved by a second",
+			s3Path:    "images/photo.jpg",
+			token:     token,
+			expiresAt: urlExpiry.Add(time.Second),
+			errorType: domain.ErrInvalidSignature,
+		},
+		{
+			name:      "tampered expiry of an evicted share",
+			s3Path:    "images/evicted.jpg",
+			token:     token,
+			expiresAt: urlExpiry.AddDate(0, 0, 30),
+			errorType: domain.ErrInvalidSignature,
+		},
+		{
+			name:      "forged token",
+			s3Path:    "images/photo.jpg",
+			token:     "secret_deadbeef",
+			expiresAt: urlExpiry,
+			errorType: domain.ErrInvalidSignature,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := service.ValidateShare(context.Background(), tt.s3Path, tt.token, tt.expiresAt)
+			if tt.errorType == nil {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, tt.errorType) {
+				t.Errorf("expected error type %v, got %v", tt.errorType, err)
+			}
+		})
+	}
+
+	t.Run("tampered links do not count towards the lockout", func(t *testing.T) {
+		locking := NewShareService(storage, cache, &ShareConfig{
+			MaxAgeDays:        90,
+			BaseURL:           "https://example.com",
+			SigningKey:        "test-signing-key",
+			SignedURLs:        true,
+			MaxFailedAttempts: 1,
+			LockoutWindow:     time.Minute,
+		})
+		if err := locking.ValidateShare(context.Background(), "images/photo.jpg", token, urlExpiry.AddDate(0, 0, 30)); !errors.Is(err, domain.ErrInvalidSignature) {
+			t.Fatalf("expected %v, got %v", domain.ErrInvalidSignature, err)
+		}
+		if _, exists := cache.store["image-failures:images/photo.jpg"]; exists {
+			t.Errorf("expected no failed attempt to be recorded")
+		}
+		if err := locking.ValidateShare(context.Background(), "images/photo.jpg", token, urlExpiry); err != nil {
+			t.Errorf("expected the genuine link to stay valid, got %v", err)
+		}
+	})
+}
+
+func TestShareService_SignedURLFallback(t *testing.T) {
+	storage := &mockStorageService{objects: map[string]*domain.ObjectMetadata{
+		"images/photo.jpg":   {ContentType: "image/jpeg", Size: 1024},
+		"images/private.jpg": {ContentType: "image/jpeg", Size: 1024},
+	}}
+	cache := &mockCacheService{store: make(map[string]string)}
+	config := &ShareConfig{
+		MaxAgeDays:        90,
+		BaseURL:           "https://example.com",
+		SigningKey:        "test-signing-key",
+		SignedURLs:        true,
+		SignedURLFallback: true,
+		MaxFailedAttempts: 3,
+		LockoutWindow:     time.Minute,
+	}
+	service := NewShareService(storage, cache, config)
+	ctx := context.Background()
+
+	expiresAt := time.Now().Add(48 * time.Hour)
+	resp, err := service.CreateShare(ctx, &domain.ShareRequest{
+		S3Path:    "images/photo.jpg",
+		ExpiresAt: expiresAt,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	restricted, err := service.CreateShare(ctx, &domain.ShareRequest{
+		S3Path:       "images/private.jpg",
+		ExpiresAt:    expiresAt,
+		MaxDownloads: 1,
+		AllowedIPs:   []string{"10.0.0.0/8"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	urlExpiry := shareURLExpiry(expiresAt)
+	token := resp.Secret
+	restrictedToken := restricted.Secret
+	if token != service.signToken(ctx, "images/photo.jpg", urlExpiry, false) || restrictedToken != service.signToken(ctx, "images/private.jpg", urlExpiry, true) {
+		t.Fatalf("expected only the restricted share to have a restricted token")
+	}
+	past := time.Now().Add(-time.Hour).Truncate(time.Second)
+
+	unavailable := fmt.Errorf("failed to get key from Redis: %w: %w", domain.ErrCacheUnavailable, errors.New("dial tcp 127.0.0.1:6379: connect: connection refused"))
+	serverErr := errors.New("failed to get key from Redis: CLUSTERDOWN The cluster is down")
+
+	tests := []struct {
+		name      string
+		getErr    error
+		fallback  bool
+		s3Path    string
+		token     string
+		expiresAt time.Time
+		errorType error
+	}{
+		{name: "valid token", getErr: unavailable, fallback: true, s3Path: "images/photo.jpg", token: token, expiresAt: urlExpiry},
+		{name: "restricted share", getErr: unavailable, fallback: true, s3Path: "images/private.jpg", token: restrictedToken, expiresAt: urlExpiry, errorType: domain.ErrCacheUnavailable},
+		{name: "restricted token on another path", getErr: unavailable, fallback: true, s3Path: "images/photo.jpg", token: restrictedToken, expiresAt: urlExpiry, errorType: domain.ErrInvalidSignature},
+		{name: "tampered expiry", getErr: unavailable, fallback: true, s3Path: "images/photo.jpg", token: token, expiresAt: urlExpiry.Add(time.Hour), errorType: domain.ErrInvalidSignature},
+		{name: "expired token", getErr: unavailable, fallback: true, s3Path: "images/photo.jpg", token: service.signToken(ctx, "images/photo.jpg", past, false), expiresAt: past, errorType: domain.ErrCacheUnavailable},
+		{name: "public link", getErr: unavailable, fallback: true, s3Path: "images/photo.jpg", token: "", expiresAt: urlExpiry, errorType: domain.ErrCacheUnavailable},
+		{name: "fallback disabled", getErr: unavailable, s3Path: "images/photo.jpg", token: token, expiresAt: urlExpiry, errorType: domain.ErrCacheUnavailable},
+		{name: "server error", getErr: serverErr, fallback: true, s3Path: "images/photo.jpg", token: token, expiresAt: urlExpiry, errorType: serverErr},
+		{name: "missing share", fallback: true, s3Path: "images/missing.jpg", token: service.signToken(ctx, "images/missing.jpg", urlExpiry, false), expiresAt: urlExpiry, errorType: domain.ErrUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cache.getErr = tt.getErr
+			config.SignedURLFallback = tt.fallback
+			defer func() { cache.getErr = nil }()
+
+			err := service.ValidateShare(ctx, tt.s3Path, tt.token, tt.expiresAt)
+			if tt.errorType == nil {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, tt.errorType) {
+				t.Errorf("expected error type %v, got %v", tt.errorType, err)
+			}
+		})
+	}
+
+	t.Run("restrictions are skipped while the cache is unavailable", func(t *testing.T) {
+		cache.getErr = unavailable
+		config.SignedURLFallback = true
+		defer func() { cache.getErr = nil }()
+
+		if err := service.ValidateClientIP(ctx, "images/photo.jpg", "192.0.2.1"); err != nil {
+			t.Errorf("unexpected error from ValidateClientIP: %v", err)
+		}
+		if err := service.ValidatePassword(ctx, "images/photo.jpg", ""); err != nil {
+			t.Errorf("unexpected error from ValidatePassword: %v", err)
+		}
+		policy, err := service.GetCachePolicy(ctx, "images/photo.jpg")
+		if err != nil || !policy.Private {
+			t.Errorf("expected a private cache policy, got %+v with error %v", policy, err)
+		}
+		if url, err := service.PresignShare(ctx, "images/photo.jpg"); err != nil || url != "" {
+			t.Errorf("expected a proxied share, got %q with error %v", url, err)
+		}
+		for range 2 {
+			if err := service.RecordDownload(ctx, "images/photo.jpg"); err != nil {
+				t.Errorf("unexpected error from RecordDownload: %v", err)
+			}
+		}
+		if _, err := service.GetObject(ctx, "images/photo.jpg"); err != nil {
+			t.Errorf("unexpected error from GetObject: %v", err)
+		}
+	})
+
+	t.Run("restrictions apply again once the cache recovers", func(t *testing.T) {
+		if err := service.ValidateShare(ctx, "images/private.jpg", restrictedToken, urlExpiry); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if err := service.ValidateClientIP(ctx, "images/private.jpg", "192.0.2.1"); err != domain.ErrIPNotAllowed {
+			t.Errorf("expected %v, got %v", domain.ErrIPNotAllowed, err)
+		}
+		if err := service.RecordDownload(ctx, "images/private.jpg"); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if err := service.ValidateShare(ctx, "images/private.jpg", restrictedToken, urlExpiry); err != domain.ErrConsumed {
+			t.Errorf("expected the consumed share to deny access, got %v", err)
+		}
+	})
+}
+
+func TestShareService_ValidateShareWrongSecretLengths(t *testing.T) {
+	cache := &mockCacheService{store: map[string]string{
+		"image-auth:images/photo.jpg": "test-secret",
+	}}
+
+	service := NewShareService(nil, cache, &ShareConfig{
+		MaxAgeDays: 90,
+		BaseURL:    "https://example.com",
+	})
+
+	secrets := []string{
+		"",
+		"t",
+		"test-secre",
+		"test-secreT",
+		"test-secret-",
+		"test-secret-but-much-longer",
+	}
+
+	for _, secret := range secrets {
+		err := service.ValidateShare(context.Background(), "images/photo.jpg", secret, time.Now())
+		if !errors.Is(err, domain.ErrUnauthorized) {
+			t.Errorf("secret %q: expected %v, got %v", secret, domain.ErrUnauthorized, err)
+		}
+	}
+}
+
+func TestShareService_ValidateShareLockout(t *testing.T) {
+	cache := NewMemoryCacheService()
+	defer cache.Close()
+
+	ctx := context.Background()
+	if err := cache.Set(ctx, "image-auth:images/photo.jpg", "test-secret", time.Hour); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	const window = 100 * time.Millisecond
+	service := NewShareService(nil, cache, &ShareConfig{
+		MaxAgeDays:        90,
+		BaseURL:           "https://example.com",
+		MaxFailedAttempts: 3,
+		LockoutWindow:     window,
+	})
+
+	// Failures below the threshold leave the correct secret working
+	for i := 0; i < 2; i++ {
+		if err := service.ValidateShare(ctx, "images/photo.jpg", "wrong-secret", time.Now()); !errors.Is(err, domain.ErrUnauthorized) {
+			t.Fatalf("attempt %d: expected %v, got %v", i, domain.ErrUnauthorized, err)
+		}
+	}
+	if err := service.ValidateShare(ctx, "images/photo.jpg", "test-secret", time.Now()); err != nil {
+		t.Fatalf("expected correct secret to validate below threshold, got %v", err)
+	}
+
+	// Reaching the threshold locks the path even for the correct secret
+	if err := service.ValidateShare(ctx, "images/photo.jpg", "wrong-secret", time.Now()); !errors.Is(err, domain.ErrUnauthorized) {
+		t.Fatalf("expected %v, got %v", domain.ErrUnauthorized, err)
+	}
+	if err := service.ValidateShare(ctx, "images/photo.jpg", "test-secret", time.Now()); !errors.Is(err, domain.ErrUnauthorized) {
+		t.Errorf("expected %v while locked out, got %v", domain.ErrUnauthorized, err)
+	}
+
+	// The lockout clears once the window passes
+	time.Sleep(window + 50*time.Millisecond)
+	if err := service.ValidateShare(ctx, "images/photo.jpg", "test-secret", time.Now()); err != nil {
+		t.Errorf("expected lockout to clear after window, got %v", err)
+	}
+
+	// Later failures do not extend the window of the first
+	failureKey := service.generateFailureKey(ctx, "images/photo.jpg")
+	_ = service.ValidateShare(ctx, "images/photo.jpg", "wrong-secret", time.Now())
+	time.Sleep(window / 2)
+	_ = service.ValidateShare(ctx, "images/photo.jpg", "wrong-secret", time.Now())
+	if ttl, err := cache.TTL(ctx, failureKey); err != nil || ttl > window/2 {
+		t.Errorf("expected the window to stay fixed at the first failure, got TTL %v with error %v", ttl, err)
+	}
+
+	// Paths without a share record count no failures
+	for i := 0; i < 5; i++ {
+		if err := service.ValidateShare(ctx, "images/missing.jpg", "wrong-secret", time.Now()); !errors.Is(err, domain.ErrUnauthorized) {
+			t.Fatalf("expected %v, got %v", domain.ErrUnauthorized, err)
+		}
+	}
+	if _, err := cache.Get(ctx, service.generateFailureKey(ctx, "images/missing.jpg")); !errors.Is(err, domain.ErrNotFound) {
+		t.Errorf("expected no failure counter for an unshared path, got %v", err)
+	}
+}
+
+func TestShareService_ValidatePassword(t *testing.T) {
+	storage := &mockStorageService{objects: map[string]*domain.ObjectMetadata{
+		"images/photo.jpg": {ContentType: "image/jpeg"},
+		"images/open.jpg":  {ContentType: "image/jpeg"},
+	}}
+	cache := &mockCacheService{store: make(map[string]string)}
+
+	service := NewShareService(storage, cache, &ShareConfig{
+		MaxAgeDays: 90,
+		BaseURL:    "https://example.com",
+	})
+	ctx := context.Background()
+	expiresAt := time.Now().Add(time.Hour)
+
+	if _, err := service.CreateShare(ctx, &domain.ShareRequest{S3Path: "images/photo.jpg", Secret: "test-secret", ExpiresAt: expiresAt, Password: "correct horse"}); err != nil {
+		t.Fatalf("failed to create share: %v", err)
+	}
+	if _, err := service.CreateShare(ctx, &domain.ShareRequest{S3Path: "images/open.jpg", Secret: "test-secret", ExpiresAt: expiresAt}); err != nil {
+		t.Fatalf("failed to create share: %v", err)
+	}
+
+	// Only the hash may reach the cache
+	for key, value := range cache.store {
+		if strings.Contains(value, "correct horse") {
+			t.Errorf("plaintext password stored under %s: %s", key, value)
+		}
+	}
+	record, err := service.getShareRecord(ctx, "images/photo.jpg")
+	if err != nil {
+		t.Fatalf("failed to load share record: %v", err)
+	}
+	if !strings.HasPrefix(record.PasswordHash, "$2") {
+		t.Errorf("expected a bcrypt hash, got %q", record.PasswordHash)
+	}
+
+	tests := []struct {
+		name      string
+		s3Path    string
+		password  string
+		errorType error
+	}{
+		{name: "correct password", s3Path: "images/photo.jpg", password: "correct horse"},
+		{name: "wrong password", s3Path: "images/photo.jpg", password: "battery staple", errorType: domain.ErrUnauthorized},
+		{name: "missing password", s3Path: "images/photo.jpg", errorType: domain.ErrPasswordRequired},
+		{name: "share without password", s3Path: "images/open.jpg", password: "anything"},
+		{name: "missing share", s3Path: "images/missing.jpg", password: "correct horse", errorType: domain.ErrUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := service.ValidatePassword(ctx, tt.s3Path, tt.password)
+			if !errors.Is(err, tt.errorType) {
+				t.Errorf("expected %v, got %v", tt.errorType, err)
+			}
+		})
+	}
+}
+
+func TestShareService_PublicShare(t *testing.T) {
+	storage := &mockStorageService{objects: map[string]*domain.ObjectMetadata{
+		"images/photo.jpg": {ContentType: "image/jpeg"},
+	}}
+	cache := &mockCacheService{store: make(map[string]string)}
+
+	service := NewShareService(storage, cache, &ShareConfig{
+		MaxAgeDays: 90,
+		BaseURL:    "https://example.com",
+		SigningKey: "signing-key",
+		SignedURLs: true,
+	})
+	ctx := context.Background()
+	expiresAt := time.Now().Add(48 * time.Hour)
+
+	resp, err := service.CreateShare(ctx, &domain.ShareRequest{S3Path: "images/photo.jpg", ExpiresAt: expiresAt, Public: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := "https://example.com/" + strconv.FormatInt(expiresAt.Unix(), 10) + "/-/images/photo.jpg"; resp.URL != want {
+		t.Errorf("expected URL %s, got %s", want, resp.URL)
+	}
+
+	if err := service.ValidateShare(ctx, "images/photo.jpg", "", shareURLExpiry(expiresAt)); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	// Tokens are verified before the share is looked up, so even a public
+	// share rejects a token that is not signed
+	if err := service.ValidateShare(ctx, "images/photo.jpg", "anything", shareURLExpiry(expiresAt)); !errors.Is(err, domain.ErrInvalidSignature) {
+		t.Errorf("expected %v, got %v", domain.ErrInvalidSignature, err)
+	}
+
+	t.Run("expired record", func(t *testing.T) {
+		value, err := domain.EncodeShareRecord(&domain.ShareRecord{ExpiresAt: time.Now().Add(-time.Minute), Public: true})
+		if err != nil {
+			t.Fatalf("failed to encode record: %v", err)
+		}
+		cache.store["image-auth:images/old.jpg"] = value
+
+		if err := service.ValidateShare(ctx, "images/old.jpg", "", time.Now()); !errors.Is(err, domain.ErrUnauthorized) {
+			t.Errorf("expected %v, got %v", domain.ErrUnauthorized, err)
+		}
+	})
+
+	t.Run("private share needs its secret", func(t *testing.T) {
+		storage.objects["images/private.jpg"] = &domain.ObjectMetadata{}
+		if _, err := service.CreateShare(ctx, &domain.ShareRequest{S3Path: "images/private.jpg", Secret: "test-secret", ExpiresAt: expiresAt}); err != nil {
+			t.Fatalf("failed to create share: %v", err)
+		}
+		if err := service.ValidateShare(ctx, "images/private.jpg", "", shareURLExpiry(expiresAt)); !errors.Is(err, domain.ErrUnauthorized) {
+			t.Errorf("expected %v, got %v", domain.ErrUnauthorized, err)
+		}
+	})
+}
+
+func TestShareService_RevokeShare(t *testing.T) {
+	cache := &mockCacheService{store: map[string]string{
+		"image-auth:images/photo.jpg": "test-secret",
+	}}
+
+	service := NewShareService(nil, cache, &ShareConfig{
+		MaxAgeDays: 90,
+		BaseURL:    "https://example.com",
+	})
+	ctx := context.Background()
+
+	if err := service.RevokeShare(ctx, "images/photo.jpg"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := service.ValidateShare(ctx, "images/photo.jpg", "test-secret", time.Now()); !errors.Is(err, domain.ErrUnauthorized) {
+		t.Errorf("expected %v after revocation, got %v", domain.ErrUnauthorized, err)
+	}
+
+	if err := service.RevokeShare(ctx, "images/photo.jpg"); !errors.Is(err, domain.ErrNotFound) {
+		t.Errorf("expected %v for missing share, got %v", domain.ErrNotFound, err)
+	}
+
+	if err := service.RevokeShare(ctx, "../etc/passwd"); !errors.Is(err, domain.ErrInvalidPath) {
+		t.Errorf("expected %v for invalid path, got %v", domain.ErrInvalidPath, err)
+	}
+}
+
+func TestShareService_ExtendShare(t *testing.T) {
+	storage := &mockStorageService{objects: map[string]*domain.ObjectMetadata{
+		"images/photo.jpg": {ContentType: "image/jpeg", Size: 1024},
+	}}
+	cache := &mockCacheService{store: make(map[string]string), ttls: make(map[string]time.Duration)}
+	service := NewShareService(storage, cache, &ShareConfig{
+		MaxAgeDays: 7,
+		BaseURL:    "https://example.com",
+	})
+	ctx := context.Background()
+
+	linkExpiry := time.Now().Add(24 * time.Hour)
+	if _, err := service.CreateShare(ctx, &domain.ShareRequest{
+		S3Path:       "images/photo.jpg",
+		Secret:       "test-secret",
+		ExpiresAt:    linkExpiry,
+		MaxDownloads: 5,
+	}); err != nil {
+		t.Fatalf("failed to create share: %v", err)
+	}
+	if err := service.RecordDownload(ctx, "images/photo.jpg"); err != nil {
+		t.Fatalf("failed to record download: %v", err)
+	}
+
+	t.Run("valid extension", func(t *testing.T) {
+		newExpiry := time.Now().Add(72 * time.Hour)
+		if err := service.ExtendShare(ctx, "images/photo.jpg", newExpiry); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		info, err := service.GetShareInfo(ctx, "images/photo.jpg")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !info.ExpiresAt.Equal(newExpiry) {
+			t.Errorf("expected expiry %v, got %v", newExpiry, info.ExpiresAt)
+		}
+		if info.TTL <= 71*time.Hour || info.TTL > 72*time.Hour {
+			t.Errorf("expected TTL close to 72h, got %v", info.TTL)
+		}
+		if ttl := cache.ttls["image-downloads:images/photo.jpg"]; ttl <= 71*time.Hour {
+			t.Errorf("expected the download counter to live as long as the share, got %v", ttl)
+		}
+		if count := cache.store["image-downloads:images/photo.jpg"]; count != "1" {
+			t.Errorf("expected the download count to be kept, got %q", count)
+		}
+
+		// The secret is unchanged, so existing links stay valid
+		if err := service.ValidateShare(ctx, "images/photo.jpg", "test-secret", time.Now()); err != nil {
+			t.Errorf("expected the original secret to stay valid, got %v", err)
+		}
+	})
+
+	t.Run("exceeding the cap from creation", func(t *testing.T) {
+		// Within seven days from now, but not from the creation of the share
+		record, err := service.getShareRecord(ctx, "images/photo.jpg")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		record.CreatedAt = time.Now().Add(-3 * 24 * time.Hour)
+		value, _ := domain.EncodeShareRecord(record)
+		cache.store["image-auth:images/photo.jpg"] = value
+
+		err = service.ExtendShare(ctx, "images/photo.jpg", time.Now().Add(5*24*time.Hour))
+		if !errors.Is(err, domain.ErrMaxAgeExceeded) {
+			t.Errorf("expected %v, got %v", domain.ErrMaxAgeExceeded, err)
+		}
+	})
+
+	tests := []struct {
+		name      string
+		s3Path    string
+		expiresAt time.Time
+		errorType error
+	}{
+		{name: "unknown share", s3Path: "images/missing.jpg", expiresAt: time.Now().Add(time.Hour), errorType: domain.ErrNotFound},
+		{name: "past expiry", s3Path: "images/photo.jpg", expiresAt: time.Now().Add(-time.Hour), errorType: domain.ErrInvalidDate},
+		{name: "invalid path", s3Path: "../etc/passwd", expiresAt: time.Now().Add(time.Hour), errorType: domain.ErrInvalidPath},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := service.ExtendShare(ctx, tt.s3Path, tt.expiresAt); !errors.Is(err, tt.errorType) {
+				t.Errorf("expected %v, got %v", tt.errorType, err)
+			}
+		})
+	}
+}
+
+func TestShareService_IsExtended(t *testing.T) {
+	linkExpiry := time.Now().Add(-time.Hour).Truncate(time.Second).UTC()
+	extended, _ := domain.EncodeShareRecord(&domain.ShareRecord{
+		Secret:        "test-secret",
+		CreatedAt:     time.Now().Add(-2 * time.Hour),
+		ExpiresAt:     time.Now().Add(time.Hour),
+		LinkExpiresAt: linkExpiry,
+	})
+	lapsed, _ := domain.EncodeShareRecord(&domain.ShareRecord{
+		Secret:        "test-secret",
+		CreatedAt:     time.Now().Add(-3 * time.Hour),
+		ExpiresAt:     time.Now().Add(-time.Minute),
+		LinkExpiresAt: linkExpiry,
+	})
+	plain, _ := domain.EncodeShareRecord(&domain.ShareRecord{
+		Secret:    "test-secret",
+		CreatedAt: time.Now().Add(-2 * time.Hour),
+		ExpiresAt: time.Now().Add(time.Hour),
+	})
+	cache := &mockCacheService{store: map[string]string{
+		"image-auth:images/extended.jpg": extended,
+		"image-auth:images/lapsed.jpg":   lapsed,
+		"image-auth:images/plain.jpg":    plain,
+	}}
+	service := NewShareService(nil, cache, &ShareConfig{MaxAgeDays: 90, BaseURL: "https://example.com"})
+
+	tests := []struct {
+		name       string
+		s3Path     string
+		linkExpiry time.Time
+		expected   bool
+	}{
+		{name: "original link of an extended share", s3Path: "images/extended.jpg", linkExpiry: linkExpiry, expected: true},
+		{name: "other expiry", s3Path: "images/extended.jpg", linkExpiry: linkExpiry.Add(-time.Minute), expected: false},
+		{name: "extension lapsed", s3Path: "images/lapsed.jpg", linkExpiry: linkExpiry, expected: false},
+		{name: "never extended", s3Path: "images/plain.jpg", linkExpiry: linkExpiry, expected: false},
+		{name: "missing share", s3Path: "images/missing.jpg", linkExpiry: linkExpiry, expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			extended, err := service.IsExtended(context.Background(), tt.s3Path, tt.linkExpiry)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if extended != tt.expected {
+				t.Errorf("expected %v, got %v", tt.expected, extended)
+			}
+		})
+	}
+}
+
+func TestShareService_DeleteObject(t *testing.T) {
+	storage := &mockStorageService{objects: map[string]*domain.ObjectMetadata{
+		"images/photo.jpg": {ContentType: "image/jpeg"},
+	}}
+	cache := &mockCacheService{store: make(map[string]string)}
+
+	service := NewShareService(storage, cache, &ShareConfig{
+		MaxAgeDays: 90,
+		BaseURL:    "https://example.com",
+	})
+	ctx := context.Background()
+
+	if _, err := service.CreateShare(ctx, &domain.ShareRequest{S3Path: "images/photo.jpg", Secret: "test-secret", ExpiresAt: time.Now().Add(time.Hour), MaxDownloads: 3}); err != nil {
+		t.Fatalf("failed to create share: %v", err)
+	}
+	if err := service.RecordDownload(ctx, "images/photo.jpg"); err != nil {
+		t.Fatalf("failed to record download: %v", err)
+	}
+
+	t.Run("delete with share", func(t *testing.T) {
+		if err := service.DeleteObject(ctx, "images/photo.jpg"); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if _, exists := storage.objects["images/photo.jpg"]; exists {
+			t.Error("expected object to be deleted")
+		}
+		if len(cache.store) != 0 {
+			t.Errorf("expected share keys to be removed, got %v", cache.store)
+		}
+		if err := service.ValidateShare(ctx, "images/photo.jpg", "test-secret", time.Now()); !errors.Is(err, domain.ErrUnauthorized) {
+			t.Errorf("expected %v after deletion, got %v", domain.ErrUnauthorized, err)
+		}
+	})
+
+	t.Run("delete nonexistent", func(t *testing.T) {
+		if err := service.DeleteObject(ctx, "images/photo.jpg"); !errors.Is(err, domain.ErrNotFound) {
+			t.Errorf("expected %v, got %v", domain.ErrNotFound, err)
+		}
+	})
+
+	t.Run("invalid path", func(t *testing.T) {
+		if err := service.DeleteObject(ctx, "../etc/passwd"); !errors.Is(err, domain.ErrInvalidPath) {
+			t.Errorf("expected %v, got %v", domain.ErrInvalidPath, err)
+		}
+	})
+}
+
+func TestShareService_CreatePrefixShare(t *testing.T) {
+	storage := &mockPresigningStorage{mockStorageService: &mockStorageService{objects: map[string]*domain.ObjectMetadata{
+		"images/photo.jpg": {Size: 10},
+	}}}
+	service := NewShareService(storage, &mockCacheService{store: make(map[string]string)}, &ShareConfig{
+		MaxAgeDays: 90,
+		BaseURL:    "https://example.com",
+	})
+	ctx := context.Background()
+	expiresAt := time.Now().Add(48 * time.Hour)
+
+	resp, err := service.CreateShare(ctx, &domain.ShareRequest{S3Path: "images/", Secret: "test-secret", ExpiresAt: expiresAt})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := "https://example.com/archive/" + strconv.FormatInt(expiresAt.Unix(), 10) + "/test-secret/images/"; resp.URL != want {
+		t.Errorf("expected URL %s, got %s", want, resp.URL)
+	}
+
+	tests := []struct {
+		name      string
+		req       *domain.ShareRequest
+		errorType error
+	}{
+		{name: "empty prefix", req: &domain.ShareRequest{S3Path: "videos/", Secret: "test-secret", ExpiresAt: expiresAt}, errorType: domain.ErrNotFound},
+		{name: "redirect mode", req: &domain.ShareRequest{S3Path: "images/", Secret: "test-secret", ExpiresAt: expiresAt, Mode: domain.ShareModeRedirect}, errorType: domain.ErrPresignNotSupported},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := service.CreateShare(ctx, tt.req); !errors.Is(err, tt.errorType) {
+				t.Errorf("expected %v, got %v", tt.errorType, err)
+			}
+		})
+	}
+
+	if _, err := service.CreateUpload(ctx, &domain.ShareRequest{S3Path: "images/", Secret: "test-secret", ExpiresAt: expiresAt}); !errors.Is(err, domain.ErrInvalidPath) {
+		t.Errorf("expected uploads to reject prefixes with %v, got %v", domain.ErrInvalidPath, err)
+	}
+}
+
+func TestShareService_ShareablePrefixes(t *testing.T) {
+	storage := &mockPresigningStorage{mockStorageService: &mockStorageService{objects: map[string]*domain.ObjectMetadata{
+		"public/photo.jpg":        {Size: 10},
+		"public/albums/cover.jpg": {Size: 10},
+		"internal/report.pdf":     {Size: 10},
+		"publications/paper.pdf":  {Size: 10},
+	}}}
+	service := NewShareService(storage, &mockCacheService{store: make(map[string]string)}, &ShareConfig{
+		MaxAgeDays:        90,
+		BaseURL:           "https://example.com",
+		ShareablePrefixes: []string{"public/"},
+	})
+	ctx := context.Background()
+	expiresAt := time.Now().Add(48 * time.Hour)
+
+	tests := []struct {
+		name      string
+		s3Path    string
+		errorType error
+	}{
+		{name: "object under an allowed prefix", s3Path: "public/photo.jpg"},
+		{name: "nested object under an allowed prefix", s3Path: "public/albums/cover.jpg"},
+		{name: "allowed prefix share", s3Path: "public/albums/"},
+		{name: "object outside the allowlist", s3Path: "internal/report.pdf", errorType: domain.ErrForbiddenPath},
+		{name: "key sharing the prefix text outside it", s3Path: "publications/paper.pdf", errorType: domain.ErrForbiddenPath},
+		{name: "traversal out of an allowed prefix", s3Path: "public/../internal/report.pdf", errorType: domain.ErrForbiddenPath},
+		{name: "parent of an allowed prefix", s3Path: "internal/", errorType: domain.ErrForbiddenPath},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := service.CreateShare(ctx, &domain.ShareRequest{S3Path: tt.s3Path, Secret: "test-secret", ExpiresAt: expiresAt})
+			if tt.errorType == nil {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, tt.errorType) {
+				t.Errorf("expected %v, got %v", tt.errorType, err)
+			}
+		})
+	}
+
+	t.Run("uploads", func(t *testing.T) {
+		if _, err := service.CreateUpload(ctx, &domain.ShareRequest{S3Path: "internal/new.pdf", Secret: "test-secret", ExpiresAt: expiresAt}); !errors.Is(err, domain.ErrForbiddenPath) {
+			t.Errorf("expected %v, got %v", domain.ErrForbiddenPath, err)
+		}
+		if _, err := service.CreateUpload(ctx, &domain.ShareRequest{S3Path: "public/new.pdf", Secret: "test-secret", ExpiresAt: expiresAt}); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("empty allowlist allows every path", func(t *testing.T) {
+		unrestricted := NewShareService(storage, &mockCacheService{store: make(map[string]string)}, &ShareConfig{
+			MaxAgeDays: 90,
+			BaseURL:    "https://example.com",
+		})
+		if _, err := unrestricted.CreateShare(ctx, &domain.ShareRequest{S3Path: "internal/report.pdf", Secret: "test-secret", ExpiresAt: expiresAt}); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	})
+}
+
+func TestShareService_ListObjects(t *testing.T) {
+	storage := &mockStorageService{objects: map[string]*domain.ObjectMetadata{
+		"images/photo.jpg": {Size: 10},
+		"docs/readme.txt":  {Size: 20},
+	}}
+	service := NewShareService(storage, &mockCacheService{store: make(map[string]string)}, &ShareConfig{MaxAgeDays: 90})
+	ctx := context.Background()
+
+	objects, _, err := service.ListObjects(ctx, "images/", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(objects) != 1 || objects[0].Key != "images/photo.jpg" {
+		t.Errorf("unexpected objects %+v", objects)
+	}
+
+	for _, prefix := range []string{"../", "/etc", "images/../../"} {
+		if _, _, err := service.ListObjects(ctx, prefix, ""); !errors.Is(err, domain.ErrInvalidPath) {
+			t.Errorf("ListObjects(%q): expected %v, got %v", prefix, domain.ErrInvalidPath, err)
+		}
+	}
+}
+
+func TestShareService_GetShareInfo(t *testing.T) {
+	storage := &mockStorageService{objects: map[string]*domain.ObjectMetadata{
+		"images/photo.jpg":    {ContentType: "image/jpeg", Size: 1024},
+		"images/expiring.jpg": {ContentType: "image/jpeg", Size: 1024},
+	}}
+	cache := &mockCacheService{store: make(map[string]string)}
+
+	service := NewShareService(storage, cache, &ShareConfig{
+		MaxAgeDays: 90,
+		BaseURL:    "https://example.com",
+	})
+	ctx := context.Background()
+
+	expiresAt := time.Now().Add(24 * time.Hour)
+	if _, err := service.CreateShare(ctx, &domain.ShareRequest{S3Path: "images/photo.jpg", Secret: "test-secret", ExpiresAt: expiresAt}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := service.CreateShare(ctx, &domain.ShareRequest{S3Path: "images/expiring.jpg", Secret: "test-secret", ExpiresAt: time.Now().Add(2 * time.Second)}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	t.Run("active share", func(t *testing.T) {
+		info, err := service.GetShareInfo(ctx, "images/photo.jpg")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !info.Active {
+			t.Errorf("expected share to be active")
+		}
+		if !info.ExpiresAt.Equal(expiresAt) {
+			t.Errorf("expected expires at %v, got %v", expiresAt, info.ExpiresAt)
+		}
+		if info.TTL <= 23*time.Hour {
+			t.Errorf("expected TTL close to 24h, got %v", info.TTL)
+		}
+	})
+
+	t.Run("about to expire", func(t *testing.T) {
+		info, err := service.GetShareInfo(ctx, "images/expiring.jpg")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !info.Active {
+			t.Errorf("expected share to still be active")
+		}
+		if info.TTL <= 0 || info.TTL > 2*time.Second {
+			t.Errorf("expected TTL within 2s, got %v", info.TTL)
+		}
+	})
+
+	t.Run("legacy bare secret record", func(t *testing.T) {
+		cache.store["image-auth:images/legacy.jpg"] = "test-secret"
+		cache.ttls["image-auth:images/legacy.jpg"] = time.Hour
+
+		info, err := service.GetShareInfo(ctx, "images/legacy.jpg")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !info.Active || info.ExpiresAt.IsZero() {
+			t.Errorf("expected expiry derived from TTL, got %+v", info)
+		}
+	})
+
+	t.Run("missing share", func(t *testing.T) {
+		if _, err := service.GetShareInfo(ctx, "images/missing.jpg"); !errors.Is(err, domain.ErrNotFound) {
+			t.Errorf("expected %v, got %v", domain.ErrNotFound, err)
+		}
+	})
+}
+
+func TestShareService_SweepExpiredShares(t *testing.T) {
+	storage := &mockStorageService{objects: map[string]*domain.ObjectMetadata{
+		"images/stale.jpg": {ContentType: "image/jpeg", Size: 1024},
+		"images/fresh.jpg": {ContentType: "image/jpeg", Size: 1024},
+	}}
+	cache := &mockCacheService{store: make(map[string]string), ttls: make(map[string]time.Duration)}
+	service := NewShareService(storage, cache, &ShareConfig{
+		MaxAgeDays: 90,
+		BaseURL:    "https://example.com",
+	})
+	ctx := context.Background()
+
+	for path, expiresIn := range map[string]time.Duration{"images/stale.jpg": time.Hour, "images/fresh.jpg": 48 * time.Hour} {
+		if _, err := service.CreateShare(ctx, &domain.ShareRequest{S3Path: path, Secret: "test-secret", ExpiresAt: time.Now().Add(expiresIn)}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if err := service.RecordDownload(ctx, "images/stale.jpg"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// Bare secret records carry no expiry, and tenants are swept separately
+	cache.store["image-auth:images/legacy.jpg"] = "secret"
+	tenantRecord, _ := domain.EncodeShareRecord(&domain.ShareRecord{Secret: "secret", ExpiresAt: time.Now().Add(time.Hour)})
+	cache.store["tenant:acme:image-auth:images/stale.jpg"] = tenantRecord
+
+	// The mock cache never expires keys, like records written without a TTL;
+	// sweep as of a clock past the stale share's expiry
+	now := time.Now().Add(2 * time.Hour)
+	reaped, err := service.SweepExpiredShares(ctx, now)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if reaped != 1 {
+		t.Errorf("expected 1 reaped share, got %d", reaped)
+	}
+	for _, key := range []string{"image-auth:images/stale.jpg", "image-downloads:images/stale.jpg", "image-accessed:images/stale.jpg"} {
+		if _, exists := cache.store[key]; exists {
+			t.Errorf("expected %s to be deleted", key)
+		}
+	}
+	for _, key := range []string{"image-auth:images/fresh.jpg", "image-auth:images/legacy.jpg", "tenant:acme:image-auth:images/stale.jpg"} {
+		if _, exists := cache.store[key]; !exists {
+			t.Errorf("expected %s to be kept", key)
+		}
+	}
+
+	if reaped, err := service.SweepExpiredShares(ctx, now); err != nil || reaped != 0 {
+		t.Errorf("expected nothing left to reap, got %d, %v", reaped, err)
+	}
+	if reaped, err := service.SweepExpiredShares(domain.WithTenant(ctx, "acme"), now); err != nil || reaped != 1 {
+		t.Errorf("expected the tenant share to be reaped, got %d, %v", reaped, err)
+	}
+}
+
+func TestShareService_ListShares(t *testing.T) {
+	paths := []string{"images/c.jpg", "images/a.jpg", "docs/report.pdf", "images/b.jpg", "images/d.jpg"}
+	storage := &mockStorageService{objects: map[string]*domain.ObjectMetadata{}}
+	for _, p := range paths {
+		storage.objects[p] = &domain.ObjectMetadata{ContentType: "image/jpeg", Size: 1024}
+	}
+	cache := &mockCacheService{store: make(map[string]string), ttls: make(map[string]time.Duration)}
+
+	service := NewShareService(storage, cache, &ShareConfig{
+		MaxAgeDays: 90,
+		BaseURL:    "https://example.com",
+	})
+	ctx := context.Background()
+
+	for _, p := range paths {
+		if _, err := service.CreateShare(ctx, &domain.ShareRequest{S3Path: p, Secret: "test-secret", ExpiresAt: time.Now().Add(24 * time.Hour)}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	// Keys sharing the prefix of other key families or tenants are not shares
+	cache.store["image-downloads:images/a.jpg"] = "1"
+	cache.store["tenant:acme:image-auth:images/other.jpg"] = "secret"
+
+	shares, err := service.ListShares(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := []string{"docs/report.pdf", "images/a.jpg", "images/b.jpg", "images/c.jpg", "images/d.jpg"}
+	if len(shares) != len(expected) {
+		t.Fatalf("expected %d shares, got %d: %v", len(expected), len(shares), shares)
+	}
+	for i, share := range shares {
+		if share.S3Path != expected[i] {
+			t.Errorf("expected share %d to be %s, got %s", i, expected[i], share.S3Path)
+		}
+		if !share.Active || share.TTL <= 23*time.Hour {
+			t.Errorf("expected %s active with a TTL close to 24h, got %v", share.S3Path, share.TTL)
+		}
+	}
+
+	t.Run("tenant scoped", func(t *testing.T) {
+		shares, err := service.ListShares(domain.WithTenant(ctx, "acme"))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(shares) != 1 || shares[0].S3Path != "images/other.jpg" {
+			t.Errorf("expected only the tenant share, got %v", shares)
+		}
+	})
+
+	t.Run("revoked shares are omitted", func(t *testing.T) {
+		if err := service.RevokeShare(ctx, "images/b.jpg"); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		shares, err := service.ListShares(ctx)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		for _, share := range shares {
+			if share.S3Path == "images/b.jpg" {
+				t.Errorf("expected revoked share to be omitted")
+			}
+		}
+	})
+
+	t.Run("empty", func(t *testing.T) {
+		empty := NewShareService(storage, &mockCacheService{store: make(map[string]string)}, &ShareConfig{MaxAgeDays: 90})
+		shares, err := empty.ListShares(ctx)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(shares) != 0 {
+			t.Errorf("expected no shares, got %v", shares)
+		}
+	})
+}
+
+func TestShareService_PresignShare(t *testing.T) {
+	objects := map[string]*domain.ObjectMetadata{
+		"images/photo.jpg": {ContentType: "image/jpeg", Size: 1024},
+		"images/video.mp4": {ContentType: "video/mp4", Size: 4096},
+	}
+	storage := &mockPresigningStorage{mockStorageService: &mockStorageService{objects: objects}}
+	cache := &mockCacheService{store: make(map[string]string)}
+
+	service := NewShareService(storage, cache, &ShareConfig{
+		MaxAgeDays: 90,
+		BaseURL:    "https://example.com",
+	})
+	ctx := context.Background()
+
+	expiresAt := time.Now().Add(2 * time.Hour)
+	if _, err := service.CreateShare(ctx, &domain.ShareRequest{S3Path: "images/video.mp4", Secret: "test-secret", ExpiresAt: expiresAt, Mode: domain.ShareModeRedirect}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := service.CreateShare(ctx, &domain.ShareRequest{S3Path: "images/photo.jpg", Secret: "test-secret", ExpiresAt: expiresAt}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	t.Run("redirect share", func(t *testing.T) {
+		url, err := service.PresignShare(ctx, "images/video.mp4")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if url != "https://bucket.s3.example.com/images/video.mp4?X-Amz-Signature=sig" {
+			t.Errorf("unexpected presigned URL %s", url)
+		}
+		// The presign lifetime is clamped to the share's remaining lifetime
+		if storage.expiry <= 0 || storage.expiry > 2*time.Hour {
+			t.Errorf("expected presign expiry within the share lifetime, got %v", storage.expiry)
+		}
+	})
+
+	t.Run("proxied share", func(t *testing.T) {
+		url, err := service.PresignShare(ctx, "images/photo.jpg")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if url != "" {
+			t.Errorf("expected no presigned URL for a proxied share, got %s", url)
+		}
+	})
+
+	t.Run("missing share", func(t *testing.T) {
+		if _, err := service.PresignShare(ctx, "images/missing.jpg"); !errors.Is(err, domain.ErrUnauthorized) {
+			t.Errorf("expected %v, got %v", domain.ErrUnauthorized, err)
+		}
+	})
+
+	t.Run("storage without presigning", func(t *testing.T) {
+		plain := NewShareService(&mockStorageService{objects: objects}, cache, &ShareConfig{
+			MaxAgeDays: 90,
+			BaseURL:    "https://example.com",
+		})
+		_, err := plain.CreateShare(ctx, &domain.ShareRequest{S3Path: "images/photo.jpg", Secret: "test-secret", ExpiresAt: expiresAt, Mode: domain.ShareModeRedirect})
+		if !errors.Is(err, domain.ErrPresignNotSupported) {
+			t.Errorf("expected %v, got %v", domain.ErrPresignNotSupported, err)
+		}
+	})
+}
+
+func TestShareService_CreateUpload(t *testing.T) {
+	storage := &mockPresigningStorage{mockStorageService: &mockStorageService{objects: map[string]*domain.ObjectMetadata{}}}
+	cache := &mockCacheService{store: make(map[string]string)}
+
+	service := NewShareService(storage, cache, &ShareConfig{
+		MaxAgeDays: 90,
+		BaseURL:    "https://example.com",
+	})
+	ctx := context.Background()
+
+	t.Run("upload URL and share for a missing object", func(t *testing.T) {
+		resp, err := service.CreateUpload(ctx, &domain.ShareRequest{S3Path: "uploads/report.pdf", Secret: "test-secret", ExpiresAt: time.Now().Add(48 * time.Hour)})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if resp.UploadURL != "https://bucket.s3.example.com/uploads/report.pdf?X-Amz-Signature=put" {
+			t.Errorf("unexpected upload URL %s", resp.UploadURL)
+		}
+		if storage.expiry != uploadURLExpiry {
+			t.Errorf("expected upload expiry %v, got %v", uploadURLExpiry, storage.expiry)
+		}
+		if err := service.ValidateShare(ctx, "uploads/report.pdf", "test-secret", time.Now()); err != nil {
+			t.Errorf("expected share to validate, got %v", err)
+		}
+	})
+
+	t.Run("upload URL clamped to a short share", func(t *testing.T) {
+		if _, err := service.CreateUpload(ctx, &domain.ShareRequest{S3Path: "uploads/short.pdf", Secret: "test-secret", ExpiresAt: time.Now().Add(5 * time.Minute)}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if storage.expiry <= 0 || storage.expiry > 5*time.Minute {
+			t.Errorf("expected upload expiry within 5m, got %v", storage.expiry)
+		}
+	})
+
+	t.Run("path traversal", func(t *testing.T) {
+		_, err := service.CreateUpload(ctx, &domain.ShareRequest{S3Path: "../etc/passwd", Secret: "test-secret", ExpiresAt: time.Now().Add(time.Hour)})
+		if !errors.Is(err, domain.ErrInvalidPath) {
+			t.Errorf("expected %v, got %v", domain.ErrInvalidPath, err)
+		}
+	})
+}
+
+func TestShareService_TenantIsolation(t *testing.T) {
+	storage := &mockStorageService{objects: map[string]*domain.ObjectMetadata{
+		"images/photo.jpg": {ContentType: "image/jpeg"},
+	}}
+	cache := &mockCacheService{store: make(map[string]string)}
+
+	service := NewShareService(storage, cache, &ShareConfig{
+		MaxAgeDays:        90,
+		BaseURL:           "https://example.com",
+		TenantKeyPrefixes: map[string]string{"globex": "gx:"},
+	})
+	acme := domain.WithTenant(context.Background(), "acme")
+	globex := domain.WithTenant(context.Background(), "globex")
+	expiresAt := time.Now().Add(time.Hour)
+
+	t.Run("cache keys are tenant scoped", func(t *testing.T) {
+		tests := []struct {
+			ctx      context.Context
+			expected string
+		}{
+			{ctx: context.Background(), expected: "image-auth:images/photo.jpg"},
+			{ctx: acme, expected: "tenant:acme:image-auth:images/photo.jpg"},
+			{ctx: globex, expected: "gx:image-auth:images/photo.jpg"},
+		}
+		for _, tt := range tests {
+			if key := service.generateCacheKey(tt.ctx, "images/photo.jpg"); key != tt.expected {
+				t.Errorf("expected key %s, got %s", tt.expected, key)
+			}
+		}
+	})
+
+	resp, err := service.CreateShare(acme, &domain.ShareRequest{S3Path: "images/photo.jpg", Secret: "acme-secret", ExpiresAt: expiresAt})
+	if err != nil {
+		t.Fatalf("failed to create share: %v", err)
+	}
+	if !strings.HasPrefix(resp.URL, "https://example.com/t/acme/") {
+		t.Errorf("expected tenant share URL, got %s", resp.URL)
+	}
+	if _, exists := cache.store["tenant:acme:image-auth:images/photo.jpg"]; !exists {
+		t.Errorf("expected share stored under the tenant prefix, got %v", cache.store)
+	}
+
+	t.Run("owning tenant validates", func(t *testing.T) {
+		if err := service.ValidateShare(acme, "images/photo.jpg", "acme-secret", expiresAt); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("other tenants cannot validate", func(t *testing.T) {
+		for _, ctx := range []context.Context{context.Background(), globex} {
+			if err := service.ValidateShare(ctx, "images/photo.jpg", "acme-secret", expiresAt); !errors.Is(err, domain.ErrUnauthorized) {
+				t.Errorf("expected %v, got %v", domain.ErrUnauthorized, err)
+			}
+		}
+	})
+
+	t.Run("other tenants cannot revoke", func(t *testing.T) {
+		if err := service.RevokeShare(globex, "images/photo.jpg"); !errors.Is(err, domain.ErrNotFound) {
+			t.Errorf("expected %v, got %v", domain.ErrNotFound, err)
+		}
+		if err := service.ValidateShare(acme, "images/photo.jpg", "acme-secret", expiresAt); err != nil {
+			t.Errorf("expected share to survive a cross-tenant revoke, got %v", err)
+		}
+	})
+
+	t.Run("signed tokens are tenant scoped", func(t *testing.T) {
+		if service.signToken(acme, "images/photo.jpg", expiresAt, false) == service.signToken(globex, "images/photo.jpg", expiresAt, false) {
+			t.Error("expected tokens to differ between tenants")
+		}
+	})
+}
+
+func TestShareService_KeyPrefixIsolation(t *testing.T) {
+	storage := &mockStorageService{objects: map[string]*domain.ObjectMetadata{
+		"images/photo.jpg": {ContentType: "image/jpeg"},
+	}}
+	cache := &mockCacheService{store: make(map[string]string), ttls: make(map[string]time.Duration)}
+
+	staging := NewShareService(storage, cache, &ShareConfig{
+		MaxAgeDays: 90,
+		BaseURL:    "https://staging.example.com",
+		KeyPrefix:  "staging",
+	})
+	prod := NewShareService(storage, cache, &ShareConfig{
+		MaxAgeDays: 90,
+		BaseURL:    "https://example.com",
+	})
+	ctx := context.Background()
+	expiresAt := time.Now().Add(time.Hour)
+
+	t.Run("keys are prefixed", func(t *testing.T) {
+		tests := []struct {
+			name     string
+			key      string
+			expected string
+		}{
+			{name: "default share", key: prod.generateCacheKey(ctx, "images/photo.jpg"), expected: "image-auth:images/photo.jpg"},
+			{name: "default counter", key: prod.generateDownloadKey(ctx, "images/photo.jpg"), expected: "image-downloads:images/photo.jpg"},
+			{name: "default quota", key: prod.generateQuotaKey("ci", expiresAt), expected: "api-quota:ci:" + expiresAt.Format("2006-01-02")},
+			{name: "share", key: staging.generateCacheKey(ctx, "images/photo.jpg"), expected: "staging:images/photo.jpg"},
+			{name: "counter", key: staging.generateDownloadKey(ctx, "images/photo.jpg"), expected: "staging-downloads:images/photo.jpg"},
+			{name: "short code", key: staging.generateShortCodeKey(ctx, "abc"), expected: "staging-codes:abc"},
+			{name: "quota", key: staging.generateQuotaKey("ci", expiresAt), expected: "staging-api-quota:ci:" + expiresAt.Format("2006-01-02")},
+		}
+		for _, tt := range tests {
+			if tt.key != tt.expected {
+				t.Errorf("%s: expected key %s, got %s", tt.name, tt.expected, tt.key)
+			}
+		}
+	})
+
+	if _, err := staging.CreateShare(ctx, &domain.ShareRequest{S3Path: "images/photo.jpg", Secret: "staging-secret", ExpiresAt: expiresAt}); err != nil {
+		t.Fatalf("failed to create share: %v", err)
+	}
+
+	t.Run("other prefixes cannot validate", func(t *testing.T) {
+		if err := prod.ValidateShare(ctx, "images/photo.jpg", "staging-secret", expiresAt); !errors.Is(err, domain.ErrUnauthorized) {
+			t.Errorf("expected %v, got %v", domain.ErrUnauthorized, err)
+		}
+		if err := staging.ValidateShare(ctx, "images/photo.jpg", "staging-secret", expiresAt); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("other prefixes do not list", func(t *testing.T) {
+		if _, err := prod.CreateShare(ctx, &domain.ShareRequest{S3Path: "images/photo.jpg", Secret: "prod-secret", ExpiresAt: expiresAt}); err != nil {
+			t.Fatalf("failed to create share: %v", err)
+		}
+
+		for _, svc := range []*ShareService{staging, prod} {
+			shares, err := svc.ListShares(ctx)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(shares) != 1 || shares[0].S3Path != "images/photo.jpg" {
+				t.Errorf("expected only the service's own share, got %+v", shares)
+			}
+		}
+	})
+
+	t.Run("other prefixes cannot revoke", func(t *testing.T) {
+		if err := prod.RevokeShare(ctx, "images/photo.jpg"); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if err := prod.RevokeShare(ctx, "images/photo.jpg"); !errors.Is(err, domain.ErrNotFound) {
+			t.Errorf("expected %v, got %v", domain.ErrNotFound, err)
+		}
+		if err := staging.ValidateShare(ctx, "images/photo.jpg", "staging-secret", expiresAt); err != nil {
+			t.Errorf("expected share to survive a revoke under another prefix, got %v", err)
+		}
+	})
+}
+
+func TestShareService_CreateShares(t *testing.T) {
+	storage := &mockStorageService{objects: map[string]*domain.ObjectMetadata{
+		"images/photo.jpg": {ContentType: "image/jpeg", Size: 1024},
+		"images/other.jpg": {ContentType: "image/jpeg", Size: 1024},
+	}}
+	cache := &mockCacheService{store: make(map[string]string), ttls: make(map[string]time.Duration)}
+	service := NewShareService(storage, cache, &ShareConfig{
+		MaxAgeDays: 90,
+		BaseURL:    "https://example.com",
+	})
+	ctx := context.Background()
+	expiresAt := time.Now().Add(24 * time.Hour)
+
+	results := service.CreateShares(ctx, []*domain.ShareRequest{
+		{S3Path: "images/photo.jpg", Secret: "test-secret", ExpiresAt: expiresAt},
+		{S3Path: "../etc/passwd", Secret: "test-secret", ExpiresAt: expiresAt},
+		{S3Path: "images/missing.jpg", Secret: "test-secret", ExpiresAt: expiresAt},
+		{S3Path: "images/other.jpg", Secret: "test-secret", ExpiresAt: time.Now().Add(-time.Hour)},
+		{S3Path: "images/other.jpg", Secret: "test-secret", ExpiresAt: expiresAt},
+	})
+
+	expected := []error{nil, domain.ErrInvalidPath, domain.ErrNotFound, domain.ErrInvalidDate, nil}
+	if len(results) != len(expected) {
+		t.Fatalf("expected %d results, got %d", len(expected), len(results))
+	}
+	for i, result := range results {
+		if expected[i] == nil {
+			if result.Err != nil {
+				t.Errorf("expected result %d to succeed, got %v", i, result.Err)
+			} else if result.Response == nil || result.Response.URL == "" {
+				t.Errorf("expected result %d to have a URL", i)
+			}
+			continue
+		}
+		if !errors.Is(result.Err, expected[i]) {
+			t.Errorf("expected result %d to fail with %v, got %v", i, expected[i], result.Err)
+		}
+		if result.Response != nil {
+			t.Errorf("expected no response for failed result %d", i)
+		}
+	}
+
+	// Failed items do not prevent the others from being created
+	for _, s3Path := range []string{"images/photo.jpg", "images/other.jpg"} {
+		if err := service.ValidateShare(ctx, s3Path, "test-secret", expiresAt); err != nil {
+			t.Errorf("expected a share for %s, got %v", s3Path, err)
+		}
+	}
+}
+
+func TestShareService_ConsumedShare(t *testing.T) {
+	storage := &mockStorageService{objects: map[string]*domain.ObjectMetadata{
+		"images/photo.jpg": {ContentType: "image/jpeg"},
+	}}
+	service := NewShareService(storage, &mockCacheService{store: make(map[string]string)}, &ShareConfig{
+		MaxAgeDays: 90,
+		BaseURL:    "https://example.com",
+	})
+	ctx := context.Background()
+	req := &domain.ShareRequest{S3Path: "images/photo.jpg", Secret: "test-secret", ExpiresAt: time.Now().Add(time.Hour), MaxDownloads: 1}
+
+	if _, err := service.CreateShare(ctx, req); err != nil {
+		t.Fatalf("failed to create share: %v", err)
+	}
+	if err := service.RecordDownload(ctx, "images/photo.jpg"); err != nil {
+		t.Fatalf("failed to record download: %v", err)
+	}
+
+	if err := service.ValidateShare(ctx, "images/photo.jpg", "test-secret", time.Now()); err != domain.ErrConsumed {
+		t.Errorf("expected %v, got %v", domain.ErrConsumed, err)
+	}
+	if err := service.ValidateShare(ctx, "images/photo.jpg", "wrong-secret", time.Now()); err != domain.ErrUnauthorized {
+		t.Errorf("expected %v for a wrong secret, got %v", domain.ErrUnauthorized, err)
+	}
+
+	// Sharing the path again starts afresh
+	if _, err := service.CreateShare(ctx, req); err != nil {
+		t.Fatalf("failed to create share: %v", err)
+	}
+	if err := service.ValidateShare(ctx, "images/photo.jpg", "test-secret", time.Now()); err != nil {
+		t.Errorf("expected the new share to be valid, got %v", err)
+	}
+}
+
+func TestShareService_GetCachePolicy(t *testing.T) {
+	storage := &mockStorageService{objects: map[string]*domain.ObjectMetadata{
+		"images/photo.jpg": {ContentType: "image/jpeg"},
+	}}
+	expiresAt := time.Now().Add(time.Hour)
+
+	tests := []struct {
+		name     string
+		referers []string
+		share    domain.ShareRequest
+		private  bool
+	}{
+		{name: "unrestricted share", share: domain.ShareRequest{}},
+		{name: "IP-restricted share", share: domain.ShareRequest{AllowedIPs: []string{"10.0.0.0/8"}}, private: true},
+		{name: "referer-restricted share", share: domain.ShareRequest{AllowedReferers: []string{"example.com"}}, private: true},
+		{name: "configured referers", referers: []string{"example.com"}, share: domain.ShareRequest{}, private: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := NewShareService(storage, &mockCacheService{store: make(map[string]string)}, &ShareConfig{
+				MaxAgeDays:      90,
+				BaseURL:         "https://example.com",
+				AllowedReferers: tt.referers,
+			})
+			ctx := context.Background()
+
+			tt.share.S3Path, tt.share.Secret, tt.share.ExpiresAt = "images/photo.jpg", "test-secret", expiresAt
+			if _, err := service.CreateShare(ctx, &tt.share); err != nil {
+				t.Fatalf("failed to create share: %v", err)
+			}
+			policy, err := service.GetCachePolicy(ctx, "images/photo.jpg")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if policy.Private != tt.private {
+				t.Errorf("expected private %v, got %v", tt.private, policy.Private)
+			}
+		})
+	}
+}
@@ -0,0 +1,161 @@
+package service
+
+import (
+	"context"
+	"encoding/base32"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/vchitai/go-s3-sharing/internal/domain"
+)
+
+const (
+	// shortCodeBytes is the entropy of a short code, which is a bearer
+	// credential like a link secret; 16 bytes encode to 26 base32 characters
+	shortCodeBytes = 16
+	// legacyShortCodeBytes is the entropy of codes issued before
+	// shortCodeBytes was raised, which keep resolving until they expire
+	legacyShortCodeBytes = 5
+	// maxShortCodeAttempts bounds retries when a generated code is taken
+	maxShortCodeAttempts = 5
+)
+
+// shortCodeAlphabet is lowercase base32 so codes read cleanly in URLs
+const shortCodeAlphabet = "abcdefghijklmnopqrstuvwxyz234567"
+
+// shortCodeEncoding encodes short codes without padding
+var shortCodeEncoding = base32.NewEncoding(shortCodeAlphabet).WithPadding(base32.NoPadding)
+
+// ResolveShortCode returns the share a short code links to. It returns
+// ErrNotFound for unknown or malformed codes and ErrExpired once the share
+// has expired; codes of extended shares resolve until the new expiry.
+// Unknown codes count towards the lockout of clientIP, which resolves no code
+// until the lockout window passes.
+func (s *ShareService) ResolveShortCode(ctx context.Context, code, clientIP string) (*domain.ShortLink, error) {
+	failureKey := s.generateCodeFailureKey(ctx, clientIP)
+	if s.lockoutEnabled() {
+		locked, err := s.isLockedOut(ctx, failureKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check lockout: %w", err)
+		}
+		if locked {
+			return nil, domain.ErrNotFound
+		}
+	}
+
+	value, err := s.lookupShortCode(ctx, code)
+	if err != nil {
+		if err == domain.ErrNotFound && s.lockoutEnabled() {
+			if incrErr := s.recordFailedAttempt(ctx, failureKey); incrErr != nil {
+				return nil, incrErr
+			}
+		}
+		return nil, err
+	}
+
+	var link domain.ShortLink
+	if err := json.Unmarshal([]byte(value), &link); err != nil {
+		return nil, fmt.Errorf("failed to decode short link: %w", err)
+	}
+
+	if time.Now().After(link.ExpiresAt) {
+		extended, err := s.IsExtended(ctx, link.S3Path, link.ExpiresAt)
+		if err != nil {
+			return nil, err
+		}
+		if !extended {
+			return nil, domain.ErrExpired
+		}
+	}
+
+	return &link, nil
+}
+
+// lookupShortCode returns the stored short link of code, or ErrNotFound for
+// unknown and malformed codes
+func (s *ShareService) lookupShortCode(ctx context.Context, code string) (string, error) {
+	if !isValidShortCode(code) {
+		return "", domain.ErrNotFound
+	}
+
+	value, err := s.cache.Get(ctx, s.generateShortCodeKey(ctx, code))
+	if err != nil {
+		if err == domain.ErrNotFound {
+			return "", domain.ErrNotFound
+		}
+		return "", fmt.Errorf("failed to resolve short code: %w", err)
+	}
+	return value, nil
+}
+
+// createShortCode stores a new random code resolving to the share of s3Path,
+// retrying when a generated code is already taken
+func (s *ShareService) createShortCode(ctx context.Context, s3Path, secret string, expiresAt time.Time, expiration time.Duration) (string, error) {
+	value, err := json.Marshal(&domain.ShortLink{
+		S3Path:    s3Path,
+		Secret:    secret,
+		ExpiresAt: shareURLExpiry(expiresAt),
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to encode short link: %w", err)
+	}
+
+	for attempt := 0; attempt < maxShortCodeAttempts; attempt++ {
+		code, err := s.generateShortCode()
+		if err != nil {
+			return "", err
+		}
+
+		stored, err := s.cache.SetNX(ctx, s.generateShortCodeKey(ctx, code), string(value), expiration)
+		if err != nil {
+			return "", fmt.Errorf("failed to store short code: %w", err)
+		}
+		if stored {
+			return code, nil
+		}
+	}
+
+	return "", fmt.Errorf("failed to allocate a unique short code after %d attempts", maxShortCodeAttempts)
+}
+
+// generateShortCode returns a random base32 short code
+func (s *ShareService) generateShortCode() (string, error) {
+	b := make([]byte, shortCodeBytes)
+	if _, err := io.ReadFull(s.random, b); err != nil {
+		return "", fmt.Errorf("failed to generate short code: %w", err)
+	}
+	return shortCodeEncoding.EncodeToString(b), nil
+}
+
+// generateShortCodeKey creates a cache key for a short code
+func (s *ShareService) generateShortCodeKey(ctx context.Context, code string) string {
+	return fmt.Sprintf("%s%s:%s", s.tenantKeyPrefix(ctx), s.keyName("codes"), code)
+}
+
+// generateCodeFailureKey creates the cache key of the failed short code
+// lookup counter of a client
+func (s *ShareService) generateCodeFailureKey(ctx context.Context, clientIP string) string {
+	return fmt.Sprintf("%s%s:%s", s.tenantKeyPrefix(ctx), s.keyName("code-failures"), clientIP)
+}
+
+// generateShortURL creates the shareable URL of a short code
+func (s *ShareService) generateShortURL(ctx context.Context, code string) string {
+	return fmt.Sprintf("%s/s/%s", s.tenantBaseURL(ctx), code)
+}
+
+// isValidShortCode reports whether code has the shape of a generated code
+func isValidShortCode(code string) bool {
+	if len(code) != shortCodeEncoding.EncodedLen(shortCodeBytes) &&
+		len(code) != shortCodeEncoding.EncodedLen(legacyShortCodeBytes) {
+		return false
+	}
+	for _, r := range code {
+		if !strings.ContainsRune(shortCodeAlphabet, r) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,193 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/vchitai/go-s3-sharing/internal/domain"
+)
+
+func newShortLinkTestService() (*ShareService, *mockCacheService) {
+	storage := &mockStorageService{objects: map[string]*domain.ObjectMetadata{
+		"images/photo.jpg": {ContentType: "image/jpeg"},
+	}}
+	cache := &mockCacheService{store: make(map[string]string)}
+	return NewShareService(storage, cache, &ShareConfig{
+		MaxAgeDays: 90,
+		BaseURL:    "https://example.com",
+	}), cache
+}
+
+func TestShareService_ShortCodeUniqueness(t *testing.T) {
+	service, _ := newShortLinkTestService()
+
+	seen := make(map[string]bool)
+	for i := 0; i < 1000; i++ {
+		code, err := service.generateShortCode()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !isValidShortCode(code) {
+			t.Fatalf("generated malformed code %q", code)
+		}
+		if seen[code] {
+			t.Fatalf("duplicate code %q after %d codes", code, i)
+		}
+		seen[code] = true
+	}
+}
+
+func TestShareService_ShortCodeCollision(t *testing.T) {
+	service, cache := newShortLinkTestService()
+	ctx := context.Background()
+
+	taken := bytes.Repeat([]byte{0x00}, shortCodeBytes)
+	free := bytes.Repeat([]byte{0xff}, shortCodeBytes)
+	cache.store[service.generateShortCodeKey(context.Background(), shortCodeEncoding.EncodeToString(taken))] = "{}"
+
+	t.Run("retries a taken code", func(t *testing.T) {
+		service.random = bytes.NewReader(append(append([]byte{}, taken...), free...))
+
+		resp, err := service.CreateShare(ctx, &domain.ShareRequest{S3Path: "images/photo.jpg", Secret: "test-secret", ExpiresAt: time.Now().Add(time.Hour), Short: true})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if want := "https://example.com/s/" + shortCodeEncoding.EncodeToString(free); resp.URL != want {
+			t.Errorf("expected URL %s, got %s", want, resp.URL)
+		}
+	})
+
+	t.Run("gives up after repeated collisions", func(t *testing.T) {
+		service.random = bytes.NewReader(bytes.Repeat(taken, maxShortCodeAttempts))
+
+		_, err := service.CreateShare(ctx, &domain.ShareRequest{S3Path: "images/photo.jpg", Secret: "test-secret", ExpiresAt: time.Now().Add(time.Hour), Short: true})
+		if err == nil || !strings.Contains(err.Error(), "unique short code") {
+			t.Errorf("expected allocation failure, got %v", err)
+		}
+	})
+}
+
+func TestShareService_ResolveShortCode(t *testing.T) {
+	service, cache := newShortLinkTestService()
+	ctx := context.Background()
+
+	resp, err := service.CreateShare(ctx, &domain.ShareRequest{S3Path: "images/photo.jpg", Secret: "test-secret", ExpiresAt: time.Now().Add(time.Hour), Short: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.Contains(resp.URL, "images/photo.jpg") || strings.Contains(resp.URL, "test-secret") {
+		t.Errorf("short URL leaks the share: %s", resp.URL)
+	}
+	code := strings.TrimPrefix(resp.URL, "https://example.com/s/")
+
+	expired, err := json.Marshal(&domain.ShortLink{S3Path: "images/photo.jpg", Secret: "test-secret", ExpiresAt: time.Now().Add(-time.Second)})
+	if err != nil {
+		t.Fatalf("failed to encode link: %v", err)
+	}
+	cache.store[service.generateShortCodeKey(context.Background(), "aaaaaaaa")] = string(expired)
+
+	t.Run("valid code", func(t *testing.T) {
+		link, err := service.ResolveShortCode(ctx, code, "192.0.2.1")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if link.S3Path != "images/photo.jpg" || link.Secret != "test-secret" {
+			t.Errorf("unexpected link %+v", link)
+		}
+		if err := service.ValidateShare(ctx, link.S3Path, link.Secret, link.ExpiresAt); err != nil {
+			t.Errorf("expected resolved link to validate, got %v", err)
+		}
+	})
+
+	tests := []struct {
+		name      string
+		code      string
+		errorType error
+	}{
+		{name: "expired code", code: "aaaaaaaa", errorType: domain.ErrExpired},
+		{name: "unknown code", code: "bbbbbbbb", errorType: domain.ErrNotFound},
+		{name: "malformed code", code: "../../etc", errorType: domain.ErrNotFound},
+		{name: "uppercase code", code: strings.ToUpper(code), errorType: domain.ErrNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := service.ResolveShortCode(ctx, tt.code, "192.0.2.1"); !errors.Is(err, tt.errorType) {
+				t.Errorf("expected %v, got %v", tt.errorType, err)
+			}
+		})
+	}
+}
+
+func TestShareService_ExtendShortCode(t *testing.T) {
+	service, cache := newShortLinkTestService()
+	ctx := context.Background()
+
+	resp, err := service.CreateShare(ctx, &domain.ShareRequest{S3Path: "images/photo.jpg", Secret: "test-secret", ExpiresAt: time.Now().Add(time.Hour), Short: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	code := strings.TrimPrefix(resp.URL, "https://example.com/s/")
+	codeKey := service.generateShortCodeKey(ctx, code)
+
+	if err := service.ExtendShare(ctx, "images/photo.jpg", time.Now().Add(72*time.Hour)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if ttl := cache.ttls[codeKey]; ttl <= 71*time.Hour {
+		t.Errorf("expected the short code to live as long as the share, got %v", ttl)
+	}
+
+	// Once the original expiry passes, the code resolves until the new one
+	linkExpiry := time.Now().Add(-time.Minute).Truncate(time.Second).UTC()
+	record, err := service.getShareRecord(ctx, "images/photo.jpg")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	record.LinkExpiresAt = linkExpiry
+	value, _ := domain.EncodeShareRecord(record)
+	cache.store[service.generateCacheKey(ctx, "images/photo.jpg")] = value
+	link, _ := json.Marshal(&domain.ShortLink{S3Path: "images/photo.jpg", Secret: "test-secret", ExpiresAt: linkExpiry})
+	cache.store[codeKey] = string(link)
+
+	if _, err := service.ResolveShortCode(ctx, code, "192.0.2.1"); err != nil {
+		t.Errorf("expected the code of an extended share to resolve, got %v", err)
+	}
+}
+
+func TestShareService_ShortCodeLockout(t *testing.T) {
+	storage := &mockStorageService{objects: map[string]*domain.ObjectMetadata{
+		"images/photo.jpg": {ContentType: "image/jpeg"},
+	}}
+	service := NewShareService(storage, &mockCacheService{store: make(map[string]string)}, &ShareConfig{
+		MaxAgeDays:        90,
+		BaseURL:           "https://example.com",
+		MaxFailedAttempts: 2,
+		LockoutWindow:     time.Minute,
+	})
+	ctx := context.Background()
+
+	resp, err := service.CreateShare(ctx, &domain.ShareRequest{S3Path: "images/photo.jpg", Secret: "test-secret", ExpiresAt: time.Now().Add(time.Hour), Short: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	code := strings.TrimPrefix(resp.URL, "https://example.com/s/")
+
+	for _, guess := range []string{"bbbbbbbb", "../../etc"} {
+		if _, err := service.ResolveShortCode(ctx, guess, "192.0.2.1"); !errors.Is(err, domain.ErrNotFound) {
+			t.Fatalf("expected %v, got %v", domain.ErrNotFound, err)
+		}
+	}
+
+	// The guessing client resolves no code, other clients are unaffected
+	if _, err := service.ResolveShortCode(ctx, code, "192.0.2.1"); !errors.Is(err, domain.ErrNotFound) {
+		t.Errorf("expected %v while locked out, got %v", domain.ErrNotFound, err)
+	}
+	if _, err := service.ResolveShortCode(ctx, code, "192.0.2.2"); err != nil {
+		t.Errorf("expected another client to resolve the code, got %v", err)
+	}
+}
@@ -0,0 +1,124 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	"cloud.google.com/go/storage"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+
+	"github.com/vchitai/go-s3-sharing/internal/config"
+	"github.com/vchitai/go-s3-sharing/internal/domain"
+)
+
+// StorageFactory creates a storage backend from the service configuration
+type StorageFactory func(ctx context.Context, cfg *config.Config) (domain.StorageService, error)
+
+// storageBackends holds the registered storage factories by name
+var (
+	storageBackendsMu sync.RWMutex
+	storageBackends   = map[string]StorageFactory{
+		"s3":  newS3Backend,
+		"gcs": newGCSBackend,
+		"fs":  newFSBackend,
+	}
+)
+
+// RegisterStorageBackend makes the backend created by factory selectable as
+// name with STORAGE_BACKEND. It is meant to be called from init functions and
+// panics if name is already registered or factory is nil.
+func RegisterStorageBackend(name string, factory StorageFactory) {
+	storageBackendsMu.Lock()
+	defer storageBackendsMu.Unlock()
+
+	if factory == nil {
+		panic("service: RegisterStorageBackend factory is nil")
+	}
+	if _, exists := storageBackends[name]; exists {
+		panic("service: RegisterStorageBackend called twice for backend " + name)
+	}
+	storageBackends[name] = factory
+}
+
+// StorageBackends returns the names of the registered storage backends, sorted
+func StorageBackends() []string {
+	storageBackendsMu.RLock()
+	defer storageBackendsMu.RUnlock()
+
+	names := make([]string, 0, len(storageBackends))
+	for name := range storageBackends {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// NewStorageBackend creates the storage backend registered as name
+func NewStorageBackend(ctx context.Context, name string, cfg *config.Config) (domain.StorageService, error) {
+	storageBackendsMu.RLock()
+	factory, ok := storageBackends[name]
+	storageBackendsMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown storage backend %q, registered: %v", name, StorageBackends())
+	}
+
+	backend, err := factory(ctx, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s storage backend: %w", name, err)
+	}
+	return backend, nil
+}
+
+// newS3Backend creates the S3 backend with the default AWS credential chain
+func newS3Backend(ctx context.Context, cfg *config.Config) (domain.StorageService, error) {
+	awsCfg, err := awsconfig.LoadDefaultConfig(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load AWS config: %w", err)
+	}
+
+	client := s3.NewFromConfig(awsCfg, S3ClientOptions(cfg))
+
+	s3Storage := NewS3Service(client, cfg.AWS.Bucket).
+		WithTenantBuckets(cfg.TenantBuckets()).
+		WithPrefixBuckets(cfg.AWS.PrefixBuckets).
+		WithRetry(cfg.AWS.RetryMaxAttempts, cfg.AWS.RetryBaseDelay).
+		WithDefaultContentType(cfg.AWS.DefaultContentType)
+	if key := cfg.AWS.SSECustomerKeyBytes(); key != nil {
+		s3Storage.WithSSECustomerKey(key)
+	}
+	return s3Storage, nil
+}
+
+// S3ClientOptions applies the endpoint and addressing options of cfg to an S3
+// client
+func S3ClientOptions(cfg *config.Config) func(*s3.Options) {
+	return func(o *s3.Options) {
+		if cfg.AWS.Endpoint != "" {
+			o.BaseEndpoint = aws.String(cfg.AWS.Endpoint)
+		}
+		o.UsePathStyle = cfg.AWS.UsePathStyle
+		o.UseAccelerate = cfg.AWS.UseAccelerate
+		if cfg.AWS.UseDualStack {
+			o.EndpointOptions.UseDualStackEndpoint = aws.DualStackEndpointStateEnabled
+		}
+	}
+}
+
+// newGCSBackend creates the Google Cloud Storage backend with application
+// default credentials
+func newGCSBackend(ctx context.Context, cfg *config.Config) (domain.StorageService, error) {
+	client, err := storage.NewClient(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create GCS client: %w", err)
+	}
+	return NewGCSService(client, cfg.Storage.GCSBucket), nil
+}
+
+// newFSBackend creates the local filesystem backend
+func newFSBackend(ctx context.Context, cfg *config.Config) (domain.StorageService, error) {
+	return NewFSService(cfg.Storage.FSRoot), nil
+}
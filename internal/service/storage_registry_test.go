@@ -0,0 +1,141 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+
+	"github.com/vchitai/go-s3-sharing/internal/config"
+	"github.com/vchitai/go-s3-sharing/internal/domain"
+)
+
+func TestStorageRegistry(t *testing.T) {
+	fake := &mockStorageService{objects: map[string]*domain.ObjectMetadata{}}
+	var received *config.Config
+	RegisterStorageBackend("fake-registry-test", func(ctx context.Context, cfg *config.Config) (domain.StorageService, error) {
+		received = cfg
+		return fake, nil
+	})
+
+	t.Run("construct by name", func(t *testing.T) {
+		cfg := &config.Config{}
+		backend, err := NewStorageBackend(context.Background(), "fake-registry-test", cfg)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if backend != fake {
+			t.Errorf("expected the fake backend, got %T", backend)
+		}
+		if received != cfg {
+			t.Error("expected the factory to receive the configuration")
+		}
+	})
+
+	t.Run("listed with the built-in backends", func(t *testing.T) {
+		names := StorageBackends()
+		for _, name := range []string{"fake-registry-test", "fs", "gcs", "s3"} {
+			if !slices.Contains(names, name) {
+				t.Errorf("expected backend %s in %v", name, names)
+			}
+		}
+		if !slices.IsSorted(names) {
+			t.Errorf("expected sorted names, got %v", names)
+		}
+	})
+
+	t.Run("built-in fs backend", func(t *testing.T) {
+		backend, err := NewStorageBackend(context.Background(), "fs", &config.Config{Storage: config.StorageConfig{FSRoot: t.TempDir()}})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if _, ok := backend.(*FSService); !ok {
+			t.Errorf("expected an FSService, got %T", backend)
+		}
+	})
+
+	t.Run("built-in s3 backend", func(t *testing.T) {
+		t.Setenv("AWS_REGION", "us-east-1")
+		key := bytes.Repeat([]byte{0x42}, 32)
+		cfg := &config.Config{AWS: config.AWSConfig{
+			Bucket:         "default-bucket",
+			PrefixBuckets:  map[string]string{"archive/": "archive-bucket"},
+			SSECustomerKey: base64.StdEncoding.EncodeToString(key),
+		}}
+		backend, err := NewStorageBackend(context.Background(), "s3", cfg)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		s3Storage, ok := backend.(*S3Service)
+		if !ok {
+			t.Fatalf("expected an S3Service, got %T", backend)
+		}
+		if bucket := s3Storage.bucketFor(context.Background(), "archive/2024.zip"); bucket != "archive-bucket" {
+			t.Errorf("expected prefixed keys in archive-bucket, got %s", bucket)
+		}
+		if s3Storage.sseCustomerKey == nil || *s3Storage.sseCustomerKey != cfg.AWS.SSECustomerKey {
+			t.Errorf("expected reads with the configured SSE-C key, got %v", s3Storage.sseCustomerKey)
+		}
+	})
+
+	t.Run("unknown backend", func(t *testing.T) {
+		if _, err := NewStorageBackend(context.Background(), "tape", &config.Config{}); err == nil {
+			t.Error("expected error for an unknown backend")
+		}
+	})
+
+	t.Run("factory errors are wrapped", func(t *testing.T) {
+		errOffline := errors.New("backend offline")
+		RegisterStorageBackend("failing-registry-test", func(ctx context.Context, cfg *config.Config) (domain.StorageService, error) {
+			return nil, errOffline
+		})
+
+		if _, err := NewStorageBackend(context.Background(), "failing-registry-test", &config.Config{}); !errors.Is(err, errOffline) {
+			t.Errorf("expected wrapped %v, got %v", errOffline, err)
+		}
+	})
+
+	t.Run("duplicate registration panics", func(t *testing.T) {
+		defer func() {
+			if recover() == nil {
+				t.Error("expected a panic registering a backend twice")
+			}
+		}()
+		RegisterStorageBackend("s3", newS3Backend)
+	})
+}
+
+func TestS3ClientOptions(t *testing.T) {
+	t.Run("defaults", func(t *testing.T) {
+		var o s3.Options
+		S3ClientOptions(&config.Config{})(&o)
+
+		if o.BaseEndpoint != nil || o.UsePathStyle || o.UseAccelerate || o.EndpointOptions.UseDualStackEndpoint != aws.DualStackEndpointStateUnset {
+			t.Errorf("expected default client options, got %+v", o)
+		}
+	})
+
+	t.Run("configured", func(t *testing.T) {
+		var o s3.Options
+		S3ClientOptions(&config.Config{AWS: config.AWSConfig{
+			Endpoint:      "http://localhost:9000",
+			UseAccelerate: true,
+			UseDualStack:  true,
+		}})(&o)
+
+		if o.BaseEndpoint == nil || *o.BaseEndpoint != "http://localhost:9000" {
+			t.Errorf("expected endpoint http://localhost:9000, got %v", o.BaseEndpoint)
+		}
+		if !o.UseAccelerate {
+			t.Error("expected UseAccelerate to be set")
+		}
+		if o.EndpointOptions.UseDualStackEndpoint != aws.DualStackEndpointStateEnabled {
+			t.Error("expected dual-stack endpoints to be enabled")
+		}
+	})
+}
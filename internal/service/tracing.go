@@ -0,0 +1,39 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/vchitai/go-s3-sharing/internal/domain"
+)
+
+// tracerName identifies the spans started by this package
+const tracerName = "github.com/vchitai/go-s3-sharing/internal/service"
+
+// startSpan starts a child span of ctx using the global tracer provider
+func startSpan(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(tracerName).Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// endSpan records err on span and ends it. ErrNotFound is an expected
+// outcome and does not mark the span as failed.
+func endSpan(span trace.Span, err error) {
+	if err != nil && !errors.Is(err, domain.ErrNotFound) {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// startRedisSpan starts a client span for a Redis command
+func startRedisSpan(ctx context.Context, command string) (context.Context, trace.Span) {
+	return otel.Tracer(tracerName).Start(ctx, "redis."+command,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attribute.String("db.system", "redis")),
+	)
+}
@@ -0,0 +1,130 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/vchitai/go-s3-sharing/internal/domain"
+)
+
+// webhookRetryBaseDelay is the backoff before the first retry of a failed
+// webhook delivery, doubling after each further attempt
+const webhookRetryBaseDelay = 500 * time.Millisecond
+
+// WebhookSender posts share access events to webhook URLs
+type WebhookSender struct {
+	client      *http.Client
+	maxAttempts int
+	// baseDelay is replaced in tests
+	baseDelay time.Duration
+}
+
+// NewWebhookSender creates a sender giving each delivery attempt up to
+// timeout and retrying failed deliveries up to maxAttempts attempts in total
+func NewWebhookSender(timeout time.Duration, maxAttempts int) *WebhookSender {
+	return &WebhookSender{
+		client:      &http.Client{Timeout: timeout},
+		maxAttempts: maxAttempts,
+		baseDelay:   webhookRetryBaseDelay,
+	}
+}
+
+// Send posts event to url as JSON, retrying with jittered exponential backoff
+// on network errors, 429 and 5xx responses. Other responses outside 2xx fail
+// at once.
+func (w *WebhookSender) Send(ctx context.Context, url string, event *domain.WebhookEvent) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to encode webhook event: %w", err)
+	}
+
+	for attempt := 1; ; attempt++ {
+		retryable, err := w.post(ctx, url, body)
+		if err == nil || !retryable || attempt >= w.maxAttempts {
+			return err
+		}
+
+		timer := time.NewTimer(retryDelay(w.baseDelay, attempt))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+}
+
+// post makes a single delivery attempt, reporting whether a failure is worth
+// retrying
+func (w *WebhookSender) post(ctx context.Context, url string, body []byte) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return false, fmt.Errorf("failed to create webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return true, fmt.Errorf("failed to deliver webhook: %w", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		retryable := resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+		return retryable, fmt.Errorf("webhook responded with status %d", resp.StatusCode)
+	}
+	return false, nil
+}
+
+// ClaimFirstAccess returns the webhook URL of the share of s3Path if this is
+// the first access to claim it, and an empty URL otherwise. Use
+// ReleaseFirstAccess when the claimed access fails so that the next one
+// notifies the webhook instead.
+func (s *ShareService) ClaimFirstAccess(ctx context.Context, s3Path string) (string, error) {
+	record, err := s.getShareRecord(ctx, s3Path)
+	if err != nil {
+		if err == domain.ErrNotFound {
+			return "", nil
+		}
+		return "", fmt.Errorf("failed to load share: %w", err)
+	}
+	if record.WebhookURL == "" {
+		return "", nil
+	}
+
+	claimed, err := s.cache.SetNX(ctx, s.generateFirstAccessKey(ctx, s3Path), "1", time.Until(record.ExpiresAt))
+	if err != nil {
+		return "", fmt.Errorf("failed to claim first access: %w", err)
+	}
+	if !claimed {
+		return "", nil
+	}
+	return record.WebhookURL, nil
+}
+
+// ReleaseFirstAccess gives up a first access claimed with ClaimFirstAccess
+func (s *ShareService) ReleaseFirstAccess(ctx context.Context, s3Path string) error {
+	if err := s.cache.Delete(ctx, s.generateFirstAccessKey(ctx, s3Path)); err != nil {
+		return fmt.Errorf("failed to release first access: %w", err)
+	}
+	return nil
+}
+
+// NotifyWebhook delivers event to url, blocking until it is delivered or
+// every attempt has failed
+func (s *ShareService) NotifyWebhook(ctx context.Context, url string, event *domain.WebhookEvent) error {
+	if s.config.Webhooks == nil {
+		return domain.ErrWebhooksDisabled
+	}
+	return s.config.Webhooks.Send(ctx, url, event)
+}
+
+// generateFirstAccessKey creates the key marking the first access of the
+// share of s3Path as claimed
+func (s *ShareService) generateFirstAccessKey(ctx context.Context, s3Path string) string {
+	return fmt.Sprintf("%s%s:%s", s.tenantKeyPrefix(ctx), s.keyName("first-access"), s3Path)
+}
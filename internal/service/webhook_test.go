@@ -0,0 +1,162 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/vchitai/go-s3-sharing/internal/domain"
+)
+
+func TestWebhookSender_Send(t *testing.T) {
+	event := &domain.WebhookEvent{
+		S3Path:     "images/photo.jpg",
+		AccessedAt: time.Date(2024, 12, 30, 10, 15, 0, 0, time.UTC),
+		ClientIP:   "203.0.113.7",
+	}
+
+	tests := []struct {
+		name             string
+		statuses         []int
+		expectedAttempts int32
+		expectError      bool
+	}{
+		{name: "delivered", statuses: []int{http.StatusNoContent}, expectedAttempts: 1},
+		{name: "retried after server error", statuses: []int{http.StatusBadGateway, http.StatusOK}, expectedAttempts: 2},
+		{name: "retried after throttling", statuses: []int{http.StatusTooManyRequests, http.StatusOK}, expectedAttempts: 2},
+		{name: "attempts exhausted", statuses: []int{http.StatusServiceUnavailable}, expectedAttempts: 3, expectError: true},
+		{name: "client error not retried", statuses: []int{http.StatusNotFound}, expectedAttempts: 1, expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var attempts atomic.Int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				n := int(attempts.Add(1))
+				var received domain.WebhookEvent
+				if err := json.NewDecoder(r.Body).Decode(&received); err != nil {
+					t.Errorf("failed to decode event: %v", err)
+				}
+				if received != *event {
+					t.Errorf("expected event %+v, got %+v", *event, received)
+				}
+				if contentType := r.Header.Get("Content-Type"); contentType != "application/json" {
+					t.Errorf("expected Content-Type application/json, got %q", contentType)
+				}
+				w.WriteHeader(tt.statuses[min(n, len(tt.statuses))-1])
+			}))
+			defer server.Close()
+
+			sender := NewWebhookSender(time.Second, 3)
+			sender.baseDelay = time.Millisecond
+
+			err := sender.Send(context.Background(), server.URL, event)
+			if tt.expectError && err == nil {
+				t.Error("expected error, got nil")
+			}
+			if !tt.expectError && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if got := attempts.Load(); got != tt.expectedAttempts {
+				t.Errorf("expected %d attempts, got %d", tt.expectedAttempts, got)
+			}
+		})
+	}
+
+	t.Run("timeout", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			time.Sleep(100 * time.Millisecond)
+		}))
+		defer server.Close()
+
+		sender := NewWebhookSender(10*time.Millisecond, 1)
+		if err := sender.Send(context.Background(), server.URL, event); err == nil {
+			t.Error("expected error for a webhook exceeding the timeout")
+		}
+	})
+}
+
+func TestShareService_ClaimFirstAccess(t *testing.T) {
+	storage := &mockStorageService{objects: map[string]*domain.ObjectMetadata{
+		"images/photo.jpg": {ContentType: "image/jpeg", Size: 1024},
+		"images/plain.jpg": {ContentType: "image/jpeg", Size: 1024},
+	}}
+	cache := &mockCacheService{store: make(map[string]string), ttls: make(map[string]time.Duration)}
+	service := NewShareService(storage, cache, &ShareConfig{
+		MaxAgeDays: 90,
+		BaseURL:    "https://example.com",
+		Webhooks:   NewWebhookSender(time.Second, 1),
+	})
+	ctx := context.Background()
+
+	create := func(s3Path, webhookURL string) {
+		t.Helper()
+		_, err := service.CreateShare(ctx, &domain.ShareRequest{
+			S3Path:     s3Path,
+			Secret:     "test-secret",
+			ExpiresAt:  time.Now().Add(24 * time.Hour),
+			WebhookURL: webhookURL,
+		})
+		if err != nil {
+			t.Fatalf("failed to create share: %v", err)
+		}
+	}
+	claim := func(s3Path string) string {
+		t.Helper()
+		webhookURL, err := service.ClaimFirstAccess(ctx, s3Path)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return webhookURL
+	}
+
+	create("images/photo.jpg", "https://hooks.example.com/accessed")
+	create("images/plain.jpg", "")
+
+	if got := claim("images/photo.jpg"); got != "https://hooks.example.com/accessed" {
+		t.Errorf("expected the first access to claim the webhook, got %q", got)
+	}
+	if got := claim("images/photo.jpg"); got != "" {
+		t.Errorf("expected later accesses not to claim the webhook, got %q", got)
+	}
+	if got := claim("images/plain.jpg"); got != "" {
+		t.Errorf("expected no webhook for a share without one, got %q", got)
+	}
+	if got := claim("images/missing.jpg"); got != "" {
+		t.Errorf("expected no webhook for a missing share, got %q", got)
+	}
+
+	t.Run("released claim", func(t *testing.T) {
+		if err := service.ReleaseFirstAccess(ctx, "images/photo.jpg"); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got := claim("images/photo.jpg"); got == "" {
+			t.Error("expected the next access to claim a released webhook")
+		}
+	})
+
+	t.Run("new share", func(t *testing.T) {
+		create("images/photo.jpg", "https://hooks.example.com/again")
+		if got := claim("images/photo.jpg"); got != "https://hooks.example.com/again" {
+			t.Errorf("expected a new share to notify its webhook again, got %q", got)
+		}
+	})
+
+	t.Run("webhooks disabled", func(t *testing.T) {
+		disabled := NewShareService(storage, cache, &ShareConfig{MaxAgeDays: 90, BaseURL: "https://example.com"})
+		_, err := disabled.CreateShare(ctx, &domain.ShareRequest{
+			S3Path:     "images/photo.jpg",
+			Secret:     "test-secret",
+			ExpiresAt:  time.Now().Add(24 * time.Hour),
+			WebhookURL: "https://hooks.example.com/accessed",
+		})
+		if !errors.Is(err, domain.ErrWebhooksDisabled) {
+			t.Errorf("expected ErrWebhooksDisabled, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,58 @@
+// Package telemetry configures OpenTelemetry tracing for the service.
+package telemetry
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+)
+
+// ShutdownFunc flushes and stops the tracer provider
+type ShutdownFunc func(ctx context.Context) error
+
+// Setup installs the W3C trace context propagator and, when endpoint is set,
+// a tracer provider exporting spans over OTLP/HTTP to endpoint, e.g.
+// "http://localhost:4318". With an empty
+// endpoint the global no-op tracer provider is left in place.
+func Setup(ctx context.Context, endpoint, serviceName string) (ShutdownFunc, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	if endpoint == "" {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	endpointURL, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("invalid OTLP endpoint: %w", err)
+	}
+	// Like OTEL_EXPORTER_OTLP_ENDPOINT, a bare collector URL gets the traces path
+	if endpointURL.Path == "" || endpointURL.Path == "/" {
+		endpointURL.Path = "/v1/traces"
+	}
+
+	exporter, err := otlptracehttp.New(ctx, otlptracehttp.WithEndpointURL(endpointURL.String()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create OTLP exporter: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewWithAttributes(
+			semconv.SchemaURL,
+			semconv.ServiceName(serviceName),
+		)),
+	)
+	otel.SetTracerProvider(provider)
+
+	return provider.Shutdown, nil
+}
@@ -0,0 +1,54 @@
+package telemetry
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel"
+)
+
+func TestSetup_NoEndpoint(t *testing.T) {
+	shutdown, err := Setup(context.Background(), "", "s3-sharing")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer shutdown(context.Background())
+
+	fields := otel.GetTextMapPropagator().Fields()
+	found := false
+	for _, field := range fields {
+		if field == "traceparent" {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("expected traceparent propagation, got fields %v", fields)
+	}
+
+	// Without an endpoint spans are not recorded
+	_, span := otel.Tracer("test").Start(context.Background(), "span")
+	defer span.End()
+	if span.IsRecording() {
+		t.Error("expected no-op span without an endpoint")
+	}
+}
+
+func TestSetup_WithEndpoint(t *testing.T) {
+	shutdown, err := Setup(context.Background(), "http://localhost:4318", "s3-sharing")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer func() {
+		// Nothing listens on the endpoint; do not wait for the export
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+		shutdown(ctx)
+	}()
+
+	_, span := otel.Tracer("test").Start(context.Background(), "span")
+	defer span.End()
+	if !span.IsRecording() {
+		t.Error("expected recording span with an endpoint")
+	}
+}
@@ -0,0 +1,159 @@
+package http
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/vchitai/go-s3-sharing/internal/config"
+	"github.com/vchitai/go-s3-sharing/internal/domain"
+	"github.com/vchitai/go-s3-sharing/internal/service"
+)
+
+const (
+	// apiKeyHeader carries the API key of machine-to-machine callers
+	apiKeyHeader = "X-API-Key"
+	// quotaRemainingHeader reports how many shares an API key may still
+	// create today
+	quotaRemainingHeader = "X-Quota-Remaining"
+)
+
+// apiKey is a configured API key
+type apiKey struct {
+	// hash is the SHA-256 of the key, so that every comparison runs over the
+	// same length in constant time
+	hash [sha256.Size]byte
+	// id names the key's quota counter without revealing the key
+	id string
+	// dailyQuota is the number of shares the key may create per UTC day;
+	// zero is unlimited
+	dailyQuota int
+	// tenants lists the tenants the key may act on
+	tenants []string
+}
+
+// quotaContextKey carries the daily quota of the API key of a batch creation,
+// which the handler charges once it knows the number of shares
+type quotaContextKey struct{}
+
+// dailyQuota is the daily share creation quota of an API key
+type dailyQuota struct {
+	shareService *service.ShareService
+	id           string
+	limit        int
+}
+
+// consume charges uses against the quota and sets the X-Quota-Remaining
+// header, writing the error response and returning false when the quota has
+// fewer uses left
+func (q *dailyQuota) consume(w http.ResponseWriter, r *http.Request, uses int) bool {
+	remaining, err := q.shareService.ConsumeDailyQuota(r.Context(), q.id, q.limit, uses)
+	switch {
+	case errors.Is(err, domain.ErrQuotaExceeded):
+		w.Header().Set(quotaRemainingHeader, "0")
+		w.Header().Set("Retry-After", strconv.Itoa(secondsUntilQuotaReset(time.Now())))
+		writeAuthError(w, http.StatusTooManyRequests, "", "daily quota exceeded")
+		return false
+	case err != nil:
+		writeAuthError(w, http.StatusInternalServerError, "", "internal error")
+		return false
+	}
+	w.Header().Set(quotaRemainingHeader, strconv.Itoa(remaining))
+	return true
+}
+
+// consumeBatchQuota charges one use per share of a batch creation against the
+// daily quota of its API key, if any, writing the error response and
+// returning false when the quota has fewer uses left
+func consumeBatchQuota(w http.ResponseWriter, r *http.Request, shares int) bool {
+	quota, ok := r.Context().Value(quotaContextKey{}).(*dailyQuota)
+	return !ok || quota.consume(w, r, shares)
+}
+
+// apiKeyAuthenticator checks the API keys of admin API requests and enforces
+// their daily share creation quotas
+type apiKeyAuthenticator struct {
+	keys         []apiKey
+	shareService *service.ShareService
+}
+
+// newAPIKeyAuthenticator creates an authenticator accepting the API keys of
+// cfg and counting their quotas through shareService
+func newAPIKeyAuthenticator(cfg config.AuthConfig, shareService *service.ShareService) *apiKeyAuthenticator {
+	keys := make([]apiKey, 0, len(cfg.APIKeys))
+	for _, key := range cfg.APIKeys {
+		hash := sha256.Sum256([]byte(key.Key))
+		quota := key.DailyQuota
+		if quota == 0 {
+			quota = cfg.APIKeyDailyQuota
+		}
+		keys = append(keys, apiKey{
+			hash:       hash,
+			id:         hex.EncodeToString(hash[:16]),
+			dailyQuota: quota,
+			tenants:    key.Tenants,
+		})
+	}
+
+	return &apiKeyAuthenticator{keys: keys, shareService: shareService}
+}
+
+// Middleware rejects requests without a known API key with 401 Unauthorized,
+// and requests for a tenant the key is not granted with 403 Forbidden.
+// POST requests, which create shares, count against the key's daily quota
+// and are rejected with 429 Too Many Requests once it is used up. Batch
+// creations count one use per share, charged by the handler.
+func (a *apiKeyAuthenticator) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key, ok := a.lookup(r.Header.Get(apiKeyHeader))
+		if !ok {
+			writeAuthError(w, http.StatusUnauthorized, "", "invalid API key")
+			return
+		}
+		if !tenantAllowed(key.tenants, domain.TenantFromContext(r.Context())) {
+			writeAuthError(w, http.StatusForbidden, "", "tenant not allowed")
+			return
+		}
+
+		if r.Method == http.MethodPost && key.dailyQuota > 0 {
+			quota := &dailyQuota{shareService: a.shareService, id: key.id, limit: key.dailyQuota}
+			if r.URL.Path == "/api/shares/batch" {
+				r = r.WithContext(context.WithValue(r.Context(), quotaContextKey{}, quota))
+			} else if !quota.consume(w, r, 1) {
+				return
+			}
+		}
+
+		next.ServeHTTP(w, withCaller(r, "api-key:"+key.id))
+	})
+}
+
+// lookup returns the configured key matching key. Every configured key is
+// compared so the time taken does not reveal which one matched.
+func (a *apiKeyAuthenticator) lookup(key string) (*apiKey, bool) {
+	if key == "" {
+		return nil, false
+	}
+
+	hash := sha256.Sum256([]byte(key))
+	var match *apiKey
+	for i := range a.keys {
+		if subtle.ConstantTimeCompare(hash[:], a.keys[i].hash[:]) == 1 {
+			match = &a.keys[i]
+		}
+	}
+	return match, match != nil
+}
+
+// secondsUntilQuotaReset returns the whole seconds from now until daily
+// quotas reset at the next UTC midnight
+func secondsUntilQuotaReset(now time.Time) int {
+	now = now.UTC()
+	midnight := time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, time.UTC)
+	return int(midnight.Sub(now).Seconds()) + 1
+}
@@ -0,0 +1,215 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/vchitai/go-s3-sharing/internal/config"
+	"github.com/vchitai/go-s3-sharing/internal/domain"
+)
+
+func TestAPIKeyAuthenticator(t *testing.T) {
+	_, shareService := newTestHandler(map[string]mockObject{})
+	cfg := config.AuthConfig{
+		APIKeys: []config.APIKeyConfig{
+			{Name: "ci", Key: "ci-key", DailyQuota: 2},
+			{Name: "batch", Key: "batch-key"},
+		},
+		APIKeyDailyQuota: 100,
+	}
+	authenticator := newAPIKeyAuthenticator(cfg, shareService)
+	handler := authenticator.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	send := func(method, key string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/api/shares", nil)
+		if key != "" {
+			req.Header.Set(apiKeyHeader, key)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w
+	}
+
+	tests := []struct {
+		name              string
+		method            string
+		key               string
+		expectedStatus    int
+		expectedRemaining string
+	}{
+		{name: "valid key", method: http.MethodPost, key: "ci-key", expectedStatus: http.StatusOK, expectedRemaining: "1"},
+		{name: "reads do not count", method: http.MethodGet, key: "ci-key", expectedStatus: http.StatusOK},
+		{name: "last share of the day", method: http.MethodPost, key: "ci-key", expectedStatus: http.StatusOK, expectedRemaining: "0"},
+		{name: "quota exceeded", method: http.MethodPost, key: "ci-key", expectedStatus: http.StatusTooManyRequests, expectedRemaining: "0"},
+		{name: "default quota", method: http.MethodPost, key: "batch-key", expectedStatus: http.StatusOK, expectedRemaining: "99"},
+		{name: "unknown key", method: http.MethodPost, key: "ci-key2", expectedStatus: http.StatusUnauthorized},
+		{name: "missing key", method: http.MethodPost, expectedStatus: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := send(tt.method, tt.key)
+			if w.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d: %s", tt.expectedStatus, w.Code, w.Body.String())
+			}
+			if remaining := w.Header().Get(quotaRemainingHeader); remaining != tt.expectedRemaining {
+				t.Errorf("expected %s %q, got %q", quotaRemainingHeader, tt.expectedRemaining, remaining)
+			}
+		})
+	}
+
+	t.Run("retry after quota reset", func(t *testing.T) {
+		w := send(http.MethodPost, "ci-key")
+		retryAfter, err := strconv.Atoi(w.Header().Get("Retry-After"))
+		if err != nil || retryAfter < 1 || retryAfter > 24*60*60+1 {
+			t.Errorf("expected Retry-After until midnight, got %q", w.Header().Get("Retry-After"))
+		}
+	})
+}
+
+func TestAPIKeyAuthenticator_BatchQuota(t *testing.T) {
+	handler, shareService := newTestHandler(map[string]mockObject{
+		"images/photo.jpg": {contentType: "image/jpeg", data: []byte("photo")},
+	})
+	cfg := config.AuthConfig{APIKeys: []config.APIKeyConfig{{Name: "ci", Key: "ci-key", DailyQuota: 3}}}
+	batch := newAPIKeyAuthenticator(cfg, shareService).Middleware(http.HandlerFunc(handler.HandleCreateShareBatch))
+
+	send := func(shares int) *httptest.ResponseRecorder {
+		item := `{"s3_path": "images/photo.jpg", "secret": "test-secret", "expires_in": "1h"}`
+		body := "[" + strings.Repeat(item+",", shares-1) + item + "]"
+		req := httptest.NewRequest(http.MethodPost, "/api/shares/batch", strings.NewReader(body))
+		req.Header.Set(apiKeyHeader, "ci-key")
+		w := httptest.NewRecorder()
+		batch.ServeHTTP(w, req)
+		return w
+	}
+
+	// A batch counts one use per share
+	if w := send(2); w.Code != http.StatusMultiStatus || w.Header().Get(quotaRemainingHeader) != "1" {
+		t.Fatalf("expected 1 use remaining after a batch of 2, got %d %q: %s", w.Code, w.Header().Get(quotaRemainingHeader), w.Body.String())
+	}
+	if w := send(2); w.Code != http.StatusTooManyRequests {
+		t.Errorf("expected status %d for a batch exceeding the quota, got %d", http.StatusTooManyRequests, w.Code)
+	}
+	if w := send(1); w.Code != http.StatusMultiStatus || w.Header().Get(quotaRemainingHeader) != "0" {
+		t.Errorf("expected the last use to remain after a refused batch, got %d %q", w.Code, w.Header().Get(quotaRemainingHeader))
+	}
+}
+
+func TestAPIAuthMiddleware(t *testing.T) {
+	_, shareService := newTestHandler(map[string]mockObject{})
+	cfg := config.AuthConfig{
+		JWTSecret: testJWTSecret,
+		APIKeys:   []config.APIKeyConfig{{Key: "ci-key"}},
+	}
+	var caller string
+	handler := apiAuthMiddleware(newAPIKeyAuthenticator(cfg, shareService), newJWTAuthenticator(cfg),
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			caller = callerFromContext(r.Context())
+			w.WriteHeader(http.StatusOK)
+		}))
+	token := signTestToken(t, jwt.SigningMethodHS256, []byte(testJWTSecret), "", testClaims(time.Hour, "", ""))
+
+	tests := []struct {
+		name           string
+		key            string
+		token          string
+		expectedStatus int
+		expectedCaller string
+	}{
+		{name: "API key", key: "ci-key", expectedStatus: http.StatusOK, expectedCaller: "api-key:"},
+		{name: "bearer token", token: token, expectedStatus: http.StatusOK, expectedCaller: "jwt:"},
+		{name: "invalid API key with valid token", key: "wrong-key", token: token, expectedStatus: http.StatusUnauthorized},
+		{name: "neither", expectedStatus: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/api/shares", nil)
+			if tt.key != "" {
+				req.Header.Set(apiKeyHeader, tt.key)
+			}
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			caller = ""
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+			if w.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d", tt.expectedStatus, w.Code)
+			}
+			if tt.expectedCaller != "" && !strings.HasPrefix(caller, tt.expectedCaller) {
+				t.Errorf("expected a caller starting with %q, got %q", tt.expectedCaller, caller)
+			}
+		})
+	}
+}
+
+func TestAPIAuthMiddleware_Tenants(t *testing.T) {
+	_, shareService := newTestHandler(map[string]mockObject{})
+	cfg := config.AuthConfig{
+		JWTSecret: testJWTSecret,
+		APIKeys: []config.APIKeyConfig{
+			{Name: "default", Key: "default-key"},
+			{Name: "acme", Key: "acme-key", Tenants: []string{"acme"}},
+			{Name: "ops", Key: "ops-key", Tenants: []string{"*"}},
+		},
+	}
+	handler := apiAuthMiddleware(newAPIKeyAuthenticator(cfg, shareService), newJWTAuthenticator(cfg),
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		}))
+	tenantToken := func(tenant string) string {
+		claims := testClaims(time.Hour, "", "")
+		claims.Tenant = tenant
+		return signTestToken(t, jwt.SigningMethodHS256, []byte(testJWTSecret), "", claims)
+	}
+
+	tests := []struct {
+		name           string
+		tenant         string
+		key            string
+		token          string
+		expectedStatus int
+	}{
+		{name: "unbound key on the default tenant", key: "default-key", expectedStatus: http.StatusOK},
+		{name: "unbound key on a tenant", tenant: "acme", key: "default-key", expectedStatus: http.StatusForbidden},
+		{name: "bound key on its tenant", tenant: "acme", key: "acme-key", expectedStatus: http.StatusOK},
+		{name: "bound key on another tenant", tenant: "other", key: "acme-key", expectedStatus: http.StatusForbidden},
+		{name: "bound key on the default tenant", key: "acme-key", expectedStatus: http.StatusForbidden},
+		{name: "wildcard key", tenant: "other", key: "ops-key", expectedStatus: http.StatusOK},
+		{name: "unbound token on the default tenant", token: tenantToken(""), expectedStatus: http.StatusOK},
+		{name: "unbound token on a tenant", tenant: "acme", token: tenantToken(""), expectedStatus: http.StatusForbidden},
+		{name: "bound token on its tenant", tenant: "acme", token: tenantToken("acme"), expectedStatus: http.StatusOK},
+		{name: "bound token on another tenant", tenant: "other", token: tenantToken("acme"), expectedStatus: http.StatusForbidden},
+		{name: "wildcard token", tenant: "other", token: tenantToken("*"), expectedStatus: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/api/shares", nil)
+			if tt.tenant != "" {
+				req = req.WithContext(domain.WithTenant(req.Context(), tt.tenant))
+			}
+			if tt.key != "" {
+				req.Header.Set(apiKeyHeader, tt.key)
+			}
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+			if w.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d: %s", tt.expectedStatus, w.Code, w.Body.String())
+			}
+		})
+	}
+}
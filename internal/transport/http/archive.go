@@ -0,0 +1,149 @@
+package http
+
+import (
+	"archive/zip"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"path"
+	"strings"
+
+	"golang.org/x/time/rate"
+
+	"github.com/vchitai/go-s3-sharing/internal/domain"
+)
+
+// HandleArchive serves a prefix share as a ZIP archive of every object under
+// the prefix. Archive links have the form /archive/expiry/secret/prefix/,
+// with - in place of the secret for public shares.
+// Entries are compressed and written as each object is read, so memory use
+// stays bounded regardless of the prefix size.
+func (h *Handler) HandleArchive(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		h.writeMethodNotAllowed(w, archiveMethods...)
+		return
+	}
+
+	// Parse URL path: /archive/expiry/secret/path/to/prefix/, or
+	// /archive/expiry/-/path/to/prefix/ for public shares
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/archive"), "/"), "/")
+	if len(parts) < 2 {
+		http.NotFound(w, r)
+		return
+	}
+
+	expiresAt, segments, ok := h.parseLinkExpiry(w, r, parts)
+	if !ok {
+		return
+	}
+	r = withShareRecords(r)
+	secret, prefix, ok := h.splitShareLink(w, r, segments, "/")
+	if !ok {
+		return
+	}
+
+	// Audit every access to the share, whether it is served or denied
+	rec := newResponseRecorder(w)
+	defer h.auditAccess(r, prefix, rec)
+	w = rec
+
+	if !h.validateShareLink(w, r, expiresAt, secret, prefix) {
+		return
+	}
+
+	webhookURL := h.claimFirstAccess(r, prefix)
+	h.serveArchive(w, r, prefix)
+	if webhookURL != "" {
+		h.notifyFirstAccess(r, prefix, webhookURL, rec.status)
+	}
+}
+
+// serveArchive streams the archive of a validated prefix share
+func (h *Handler) serveArchive(w http.ResponseWriter, r *http.Request, prefix string) {
+	release, ok := h.acquireStream(w, r)
+	if !ok {
+		return
+	}
+	defer release()
+
+	// Count the download against the share's limit before streaming
+	if !h.recordDownload(w, r, prefix) {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", attachmentDisposition(path.Base(prefix)+".zip"))
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+
+	// Headers are sent, so failures can only be logged; the client receives a
+	// truncated archive that fails to open
+	defer h.streams.begin(downloadArchive)()
+	zw := zip.NewWriter(w)
+	if err := h.writeArchive(r.Context(), zw, prefix, newDownloadLimiter(h.downloadBytesPerSec)); err != nil {
+		h.logStreamError(r.Context(), "failed to stream archive", err, "prefix", prefix)
+		return
+	}
+	if err := zw.Close(); err != nil {
+		h.logger.Error("failed to finish archive", "prefix", prefix, "error", err)
+	}
+}
+
+// writeArchive adds every object under prefix to zw, naming entries by their
+// key relative to prefix. Entries share limiter so the whole archive streams
+// at the download rate.
+func (h *Handler) writeArchive(ctx context.Context, zw *zip.Writer, prefix string, limiter *rate.Limiter) error {
+	token := ""
+	for {
+		objects, next, err := h.shareService.ListObjects(ctx, prefix, token)
+		if err != nil {
+			return err
+		}
+
+		for _, object := range objects {
+			// Skip folder placeholder objects
+			if strings.HasSuffix(object.Key, "/") {
+				continue
+			}
+
+			if err := h.addArchiveEntry(ctx, zw, strings.TrimPrefix(object.Key, prefix), object, limiter); err != nil {
+				return err
+			}
+		}
+
+		if next == "" {
+			return nil
+		}
+		token = next
+	}
+}
+
+// addArchiveEntry streams the object into a new entry of zw named name.
+// Objects whose keys fail path validation are skipped rather than aborting
+// the archive.
+func (h *Handler) addArchiveEntry(ctx context.Context, zw *zip.Writer, name string, object domain.ObjectMetadata, limiter *rate.Limiter) error {
+	reader, err := h.shareService.GetObject(ctx, object.Key)
+	if err != nil {
+		if errors.Is(err, domain.ErrInvalidPath) {
+			h.logger.Warn("skipping archive entry with unsafe key", "key", object.Key)
+			return nil
+		}
+		return fmt.Errorf("failed to get %s: %w", object.Key, err)
+	}
+	defer reader.Close()
+
+	entry, err := zw.CreateHeader(&zip.FileHeader{
+		Name:     name,
+		Method:   zip.Deflate,
+		Modified: object.LastModified,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to add %s: %w", object.Key, err)
+	}
+
+	if err := h.copyStream(ctx, entry, reader, limiter, downloadArchive); err != nil {
+		return fmt.Errorf("failed to stream %s: %w", object.Key, err)
+	}
+	return nil
+}
@@ -0,0 +1,142 @@
+package http
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/vchitai/go-s3-sharing/internal/domain"
+)
+
+func TestHandler_Archive(t *testing.T) {
+	handler, shareService := newTestHandler(map[string]mockObject{
+		"images/a.jpg":       {contentType: "image/jpeg", data: []byte("jpeg-a")},
+		"images/b.png":       {contentType: "image/png", data: []byte("png-b")},
+		"images/sub/c.txt":   {contentType: "text/plain", data: []byte("text-c")},
+		"images/sub/":        {},
+		"images-other/d.jpg": {contentType: "image/jpeg", data: []byte("not-included")},
+	})
+
+	resp, err := shareService.CreateShare(context.Background(), &domain.ShareRequest{
+		S3Path:    "images/",
+		Secret:    "test-secret",
+		ExpiresAt: time.Now().Add(72 * time.Hour),
+	})
+	if err != nil {
+		t.Fatalf("failed to create share: %v", err)
+	}
+	path := strings.TrimPrefix(resp.URL, "https://example.com")
+	if !strings.HasPrefix(path, "/archive/") {
+		t.Fatalf("expected an archive URL, got %s", resp.URL)
+	}
+
+	t.Run("streams every object under the prefix", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		w := httptest.NewRecorder()
+		handler.HandleArchive(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+		}
+		if got := w.Header().Get("Content-Type"); got != "application/zip" {
+			t.Errorf("expected Content-Type application/zip, got %s", got)
+		}
+		if got := w.Header().Get("Content-Disposition"); !strings.Contains(got, `filename="images.zip"`) {
+			t.Errorf("expected images.zip attachment, got %s", got)
+		}
+
+		body := w.Body.Bytes()
+		zr, err := zip.NewReader(bytes.NewReader(body), int64(len(body)))
+		if err != nil {
+			t.Fatalf("failed to open archive: %v", err)
+		}
+
+		expected := map[string]string{
+			"a.jpg":     "jpeg-a",
+			"b.png":     "png-b",
+			"sub/c.txt": "text-c",
+		}
+		if len(zr.File) != len(expected) {
+			t.Errorf("expected %d entries, got %d", len(expected), len(zr.File))
+		}
+		for _, f := range zr.File {
+			want, ok := expected[f.Name]
+			if !ok {
+				t.Errorf("unexpected entry %s", f.Name)
+				continue
+			}
+			rc, err := f.Open()
+			if err != nil {
+				t.Fatalf("failed to open entry %s: %v", f.Name, err)
+			}
+			data, err := io.ReadAll(rc)
+			rc.Close()
+			if err != nil {
+				t.Fatalf("failed to read entry %s: %v", f.Name, err)
+			}
+			if string(data) != want {
+				t.Errorf("entry %s: expected %q, got %q", f.Name, want, data)
+			}
+		}
+	})
+
+	t.Run("wrong secret", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, strings.Replace(path, "test-secret", "wrong-secret", 1), nil)
+		w := httptest.NewRecorder()
+		handler.HandleArchive(w, req)
+
+		if w.Code != http.StatusUnauthorized {
+			t.Errorf("expected status %d, got %d", http.StatusUnauthorized, w.Code)
+		}
+	})
+
+	t.Run("object share is not an archive", func(t *testing.T) {
+		objectPath := createTestShare(t, shareService, "images/a.jpg")
+		req := httptest.NewRequest(http.MethodGet, "/archive"+objectPath, nil)
+		w := httptest.NewRecorder()
+		handler.HandleArchive(w, req)
+
+		if w.Code != http.StatusUnauthorized {
+			t.Errorf("expected status %d, got %d", http.StatusUnauthorized, w.Code)
+		}
+	})
+}
+
+func TestHandler_ArchiveTracked(t *testing.T) {
+	handler, shareService, webhookURL, events := newTrackedHandler(t, map[string]mockObject{
+		"images/a.jpg": {contentType: "image/jpeg", data: []byte("jpeg-a")},
+	})
+	resp, err := shareService.CreateShare(context.Background(), &domain.ShareRequest{
+		S3Path:     "images/",
+		Secret:     "test-secret",
+		ExpiresAt:  time.Now().Add(time.Hour),
+		WebhookURL: webhookURL,
+	})
+	if err != nil {
+		t.Fatalf("failed to create share: %v", err)
+	}
+	path := strings.TrimPrefix(resp.URL, "https://example.com")
+
+	for _, access := range []struct {
+		path           string
+		expectedStatus int
+	}{
+		{path: strings.Replace(path, "test-secret", "wrong-secret", 1), expectedStatus: http.StatusUnauthorized},
+		{path: path, expectedStatus: http.StatusOK},
+		{path: path, expectedStatus: http.StatusOK},
+	} {
+		w := httptest.NewRecorder()
+		handler.HandleArchive(w, httptest.NewRequest(http.MethodGet, access.path, nil))
+		if w.Code != access.expectedStatus {
+			t.Fatalf("expected status %d, got %d: %s", access.expectedStatus, w.Code, w.Body.String())
+		}
+	}
+
+	expectTracked(t, shareService, events, "images/", http.StatusOK, http.StatusOK, http.StatusUnauthorized)
+}
@@ -0,0 +1,177 @@
+package http
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/vchitai/go-s3-sharing/internal/config"
+	"github.com/vchitai/go-s3-sharing/internal/domain"
+)
+
+// Signing algorithms accepted for each kind of verification key
+var (
+	hmacSigningMethods       = []string{"HS256", "HS384", "HS512"}
+	asymmetricSigningMethods = []string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512"}
+)
+
+// authClaims are the registered JWT claims plus the OAuth 2.0 scope claim
+// and the tenant claim
+type authClaims struct {
+	jwt.RegisteredClaims
+	// Scope is a space-separated list of granted scopes
+	Scope string `json:"scope,omitempty"`
+	// Tenant is the tenant the token may act on, allTenants granting every
+	// tenant; tokens without one act on the default tenant only
+	Tenant string `json:"tenant,omitempty"`
+}
+
+// allTenants grants an API key or token every tenant
+const allTenants = "*"
+
+// callerContextKey carries the identity of the authenticated API caller
+type callerContextKey struct{}
+
+// withCaller returns r carrying caller as the authenticated API caller
+func withCaller(r *http.Request, caller string) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), callerContextKey{}, caller))
+}
+
+// callerFromContext returns the authenticated API caller of ctx, or "" for
+// requests without authentication
+func callerFromContext(ctx context.Context) string {
+	caller, _ := ctx.Value(callerContextKey{}).(string)
+	return caller
+}
+
+// jwtAuthenticator verifies the bearer tokens of admin API requests
+type jwtAuthenticator struct {
+	parser        *jwt.Parser
+	keyfunc       jwt.Keyfunc
+	requiredScope string
+}
+
+// newJWTAuthenticator creates an authenticator verifying tokens against the
+// shared secret or JWKS URL of cfg. Tokens must carry an expiry, and the
+// audience and scope are checked when configured.
+func newJWTAuthenticator(cfg config.AuthConfig) *jwtAuthenticator {
+	options := []jwt.ParserOption{jwt.WithExpirationRequired()}
+	if cfg.Audience != "" {
+		options = append(options, jwt.WithAudience(cfg.Audience))
+	}
+
+	a := &jwtAuthenticator{requiredScope: cfg.RequiredScope}
+	if cfg.JWKSURL != "" {
+		a.keyfunc = newJWKSKeySet(cfg.JWKSURL).Keyfunc
+		options = append(options, jwt.WithValidMethods(asymmetricSigningMethods))
+	} else {
+		secret := []byte(cfg.JWTSecret)
+		a.keyfunc = func(*jwt.Token) (any, error) { return secret, nil }
+		options = append(options, jwt.WithValidMethods(hmacSigningMethods))
+	}
+	a.parser = jwt.NewParser(options...)
+
+	return a
+}
+
+// Middleware rejects requests without a valid bearer token with 401
+// Unauthorized, and tokens lacking the required scope or the tenant of the
+// request with 403 Forbidden
+func (a *jwtAuthenticator) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := bearerToken(r)
+		if !ok {
+			writeAuthError(w, http.StatusUnauthorized, "Bearer", "missing bearer token")
+			return
+		}
+
+		var claims authClaims
+		if _, err := a.parser.ParseWithClaims(token, &claims, a.keyfunc); err != nil {
+			writeAuthError(w, http.StatusUnauthorized, `Bearer error="invalid_token"`, "invalid token")
+			return
+		}
+
+		if a.requiredScope != "" && !hasScope(claims.Scope, a.requiredScope) {
+			challenge := fmt.Sprintf(`Bearer error="insufficient_scope", scope=%q`, a.requiredScope)
+			writeAuthError(w, http.StatusForbidden, challenge, "insufficient scope")
+			return
+		}
+
+		var tenants []string
+		if claims.Tenant != "" {
+			tenants = []string{claims.Tenant}
+		}
+		if !tenantAllowed(tenants, domain.TenantFromContext(r.Context())) {
+			writeAuthError(w, http.StatusForbidden, "", "tenant not allowed")
+			return
+		}
+
+		next.ServeHTTP(w, withCaller(r, "jwt:"+claims.Issuer+" "+claims.Subject))
+	})
+}
+
+// tenantAllowed reports whether a caller granted tenants may act on tenantID,
+// "" being the default tenant, which callers granted no tenants act on
+func tenantAllowed(tenants []string, tenantID string) bool {
+	if slices.Contains(tenants, allTenants) {
+		return true
+	}
+	if tenantID == "" {
+		return len(tenants) == 0
+	}
+	return slices.Contains(tenants, tenantID)
+}
+
+// bearerToken extracts the token of an "Authorization: Bearer" header
+func bearerToken(r *http.Request) (string, bool) {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+	token = strings.TrimSpace(token)
+	return token, token != ""
+}
+
+// hasScope reports whether the space-separated scopes include scope
+func hasScope(scopes, scope string) bool {
+	for _, granted := range strings.Fields(scopes) {
+		if granted == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// apiAuthMiddleware authenticates admin API requests with an API key when
+// they send X-API-Key and with a bearer token otherwise. Either authenticator
+// may be nil when it is not configured, but not both.
+func apiAuthMiddleware(keys *apiKeyAuthenticator, tokens *jwtAuthenticator, next http.Handler) http.Handler {
+	var viaKey, viaToken http.Handler
+	if keys != nil {
+		viaKey = keys.Middleware(next)
+	}
+	if tokens != nil {
+		viaToken = tokens.Middleware(next)
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if viaKey != nil && (viaToken == nil || r.Header.Get(apiKeyHeader) != "") {
+			viaKey.ServeHTTP(w, r)
+			return
+		}
+		viaToken.ServeHTTP(w, r)
+	})
+}
+
+// writeAuthError rejects a request, adding a WWW-Authenticate challenge when
+// one is given
+func writeAuthError(w http.ResponseWriter, statusCode int, challenge, message string) {
+	if challenge != "" {
+		w.Header().Set("WWW-Authenticate", challenge)
+	}
+	writeErrorResponse(w, statusErrorCode(statusCode), message, statusCode)
+}
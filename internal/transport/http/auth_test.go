@@ -0,0 +1,206 @@
+package http
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/vchitai/go-s3-sharing/internal/config"
+)
+
+const testJWTSecret = "test-jwt-secret"
+
+// signTestToken signs claims with method and key, setting kid when non-empty
+func signTestToken(t *testing.T, method jwt.SigningMethod, key any, kid string, claims jwt.Claims) string {
+	t.Helper()
+
+	token := jwt.NewWithClaims(method, claims)
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatalf("failed to sign token: %v", err)
+	}
+	return signed
+}
+
+// testClaims returns claims expiring after ttl with the given scope and audience
+func testClaims(ttl time.Duration, scope, audience string) *authClaims {
+	claims := &authClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   "admin",
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(ttl)),
+		},
+		Scope: scope,
+	}
+	if audience != "" {
+		claims.Audience = jwt.ClaimStrings{audience}
+	}
+	return claims
+}
+
+// serveAuthenticated sends a request with token through the authenticator
+func serveAuthenticated(authenticator *jwtAuthenticator, token string) *httptest.ResponseRecorder {
+	handler := authenticator.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	req := httptest.NewRequest(http.MethodPost, "/api/shares", nil)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	return w
+}
+
+func TestJWTAuthenticator_Secret(t *testing.T) {
+	authenticator := newJWTAuthenticator(config.AuthConfig{
+		JWTSecret:     testJWTSecret,
+		Audience:      "s3-sharing",
+		RequiredScope: "shares:write",
+	})
+	secret := []byte(testJWTSecret)
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+
+	tests := []struct {
+		name           string
+		token          string
+		expectedStatus int
+	}{
+		{
+			name:           "valid token",
+			token:          signTestToken(t, jwt.SigningMethodHS256, secret, "", testClaims(time.Hour, "shares:read shares:write", "s3-sharing")),
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "missing token",
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "malformed token",
+			token:          "not-a-jwt",
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "expired token",
+			token:          signTestToken(t, jwt.SigningMethodHS256, secret, "", testClaims(-time.Minute, "shares:write", "s3-sharing")),
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "token without expiry",
+			token:          signTestToken(t, jwt.SigningMethodHS256, secret, "", &authClaims{Scope: "shares:write", RegisteredClaims: jwt.RegisteredClaims{Audience: jwt.ClaimStrings{"s3-sharing"}}}),
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "wrong secret",
+			token:          signTestToken(t, jwt.SigningMethodHS256, []byte("other-secret"), "", testClaims(time.Hour, "shares:write", "s3-sharing")),
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "asymmetric algorithm",
+			token:          signTestToken(t, jwt.SigningMethodRS256, rsaKey, "", testClaims(time.Hour, "shares:write", "s3-sharing")),
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "wrong audience",
+			token:          signTestToken(t, jwt.SigningMethodHS256, secret, "", testClaims(time.Hour, "shares:write", "other-service")),
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "wrong scope",
+			token:          signTestToken(t, jwt.SigningMethodHS256, secret, "", testClaims(time.Hour, "shares:read", "s3-sharing")),
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:           "scope prefix only",
+			token:          signTestToken(t, jwt.SigningMethodHS256, secret, "", testClaims(time.Hour, "shares:writer", "s3-sharing")),
+			expectedStatus: http.StatusForbidden,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := serveAuthenticated(authenticator, tt.token)
+			if w.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d: %s", tt.expectedStatus, w.Code, w.Body.String())
+			}
+			if tt.expectedStatus != http.StatusOK && w.Header().Get("WWW-Authenticate") == "" {
+				t.Error("expected a WWW-Authenticate challenge")
+			}
+		})
+	}
+}
+
+func TestJWTAuthenticator_JWKS(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("failed to generate RSA key: %v", err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate EC key: %v", err)
+	}
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("failed to generate RSA key: %v", err)
+	}
+
+	encode := func(i *big.Int) string { return base64.RawURLEncoding.EncodeToString(i.Bytes()) }
+	fetches := 0
+	jwks := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches++
+		json.NewEncoder(w).Encode(map[string][]jwk{"keys": {
+			{Kty: "RSA", Kid: "rsa-1", Use: "sig", N: encode(rsaKey.N), E: encode(big.NewInt(int64(rsaKey.E)))},
+			{Kty: "EC", Kid: "ec-1", Crv: "P-256", X: encode(ecKey.X), Y: encode(ecKey.Y)},
+			{Kty: "RSA", Kid: "enc-1", Use: "enc", N: encode(otherKey.N), E: encode(big.NewInt(int64(otherKey.E)))},
+			{Kty: "oct", Kid: "hmac-1"},
+		}})
+	}))
+	defer jwks.Close()
+
+	authenticator := newJWTAuthenticator(config.AuthConfig{JWKSURL: jwks.URL})
+	claims := testClaims(time.Hour, "", "")
+
+	tests := []struct {
+		name           string
+		token          string
+		expectedStatus int
+	}{
+		{name: "RSA key", token: signTestToken(t, jwt.SigningMethodRS256, rsaKey, "rsa-1", claims), expectedStatus: http.StatusOK},
+		{name: "EC key", token: signTestToken(t, jwt.SigningMethodES256, ecKey, "ec-1", claims), expectedStatus: http.StatusOK},
+		{name: "unknown key ID", token: signTestToken(t, jwt.SigningMethodRS256, rsaKey, "rsa-2", claims), expectedStatus: http.StatusUnauthorized},
+		{name: "wrong key for key ID", token: signTestToken(t, jwt.SigningMethodRS256, otherKey, "rsa-1", claims), expectedStatus: http.StatusUnauthorized},
+		{name: "encryption key", token: signTestToken(t, jwt.SigningMethodRS256, otherKey, "enc-1", claims), expectedStatus: http.StatusUnauthorized},
+		{name: "HMAC token", token: signTestToken(t, jwt.SigningMethodHS256, []byte(testJWTSecret), "hmac-1", claims), expectedStatus: http.StatusUnauthorized},
+		{name: "expired token", token: signTestToken(t, jwt.SigningMethodRS256, rsaKey, "rsa-1", testClaims(-time.Minute, "", "")), expectedStatus: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := serveAuthenticated(authenticator, tt.token)
+			if w.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d: %s", tt.expectedStatus, w.Code, w.Body.String())
+			}
+		})
+	}
+
+	// Unknown key IDs must not refetch the key set on every request
+	if fetches != 1 {
+		t.Errorf("expected the key set to be fetched once, got %d fetches", fetches)
+	}
+}
@@ -0,0 +1,102 @@
+package http
+
+import (
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/vchitai/go-s3-sharing/internal/config"
+	"github.com/vchitai/go-s3-sharing/internal/domain"
+)
+
+// privateCacheControl keeps private shares out of every cache, so that
+// passwords and download limits cannot be bypassed
+const privateCacheControl = "private, no-store"
+
+// cacheControl chooses the Cache-Control header of shared objects
+type cacheControl struct {
+	rules    []config.CacheRule
+	fallback string
+}
+
+// defaultCacheControl applies to handlers created without a configuration
+var defaultCacheControl = cacheControl{fallback: "public, max-age=3600"}
+
+// value returns the Cache-Control header for an object of contentType
+// shared under policy at now, or "" when none should be sent. The max-age
+// and s-maxage of the chosen directives are capped at the share's remaining
+// lifetime.
+func (c cacheControl) value(contentType string, policy *domain.CachePolicy, now time.Time) string {
+	if policy.Private {
+		return privateCacheControl
+	}
+
+	directives := c.fallback
+	if mediaType, _, err := mime.ParseMediaType(contentType); err == nil {
+		for _, rule := range c.rules {
+			if matchesMediaType(mediaType, rule.ContentType) {
+				directives = rule.Directives
+				break
+			}
+		}
+	}
+
+	if directives == "" || policy.ExpiresAt.IsZero() {
+		return directives
+	}
+	return capMaxAge(directives, policy.ExpiresAt.Sub(now))
+}
+
+// capMaxAge lowers the max-age and s-maxage of directives to remaining
+func capMaxAge(directives string, remaining time.Duration) string {
+	limit := max(int64(remaining/time.Second), 0)
+
+	parts := strings.Split(directives, ",")
+	for i, part := range parts {
+		name, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			continue
+		}
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name != "max-age" && name != "s-maxage" {
+			continue
+		}
+		if seconds, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64); err == nil && seconds <= limit {
+			continue
+		}
+		parts[i] = " " + name + "=" + strconv.FormatInt(limit, 10)
+	}
+	return strings.TrimSpace(strings.Join(parts, ","))
+}
+
+// setCacheControl sets the Cache-Control header for an object of contentType
+// shared under policy
+func (h *Handler) setCacheControl(w http.ResponseWriter, contentType string, policy *domain.CachePolicy) {
+	if value := h.cacheControl.value(contentType, policy, time.Now()); value != "" {
+		w.Header().Set("Cache-Control", value)
+	}
+}
+
+// cachePolicy loads the cache policy of a share, writing the error response
+// and returning false when it cannot be served
+func (h *Handler) cachePolicy(w http.ResponseWriter, r *http.Request, s3Path string) (*domain.CachePolicy, bool) {
+	policy, err := h.shareService.GetCachePolicy(r.Context(), s3Path)
+	if err != nil {
+		switch err {
+		case domain.ErrUnauthorized:
+			h.writeError(w, "unauthorized", http.StatusUnauthorized)
+		default:
+			h.writeError(w, "internal error", http.StatusInternalServerError)
+			h.logger.Error("failed to load cache policy", "path", s3Path, "error", err)
+		}
+		return nil, false
+	}
+	// Clean URLs granted by share cookies carry no secret, so shared caches
+	// must never store their responses
+	if !policy.Private && h.shareCookie(r, strings.Trim(r.URL.Path, "/")) != nil {
+		policy = &domain.CachePolicy{Private: true, ExpiresAt: policy.ExpiresAt}
+	}
+	return policy, true
+}
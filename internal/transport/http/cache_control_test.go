@@ -0,0 +1,140 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/vchitai/go-s3-sharing/internal/config"
+	"github.com/vchitai/go-s3-sharing/internal/domain"
+)
+
+func TestHandler_CacheControl(t *testing.T) {
+	handler, shareService := newTestHandler(map[string]mockObject{
+		"images/photo.jpg": {contentType: "image/jpeg", data: []byte("jpeg")},
+		"docs/report.txt":  {contentType: "text/plain; charset=utf-8", data: []byte("text")},
+	})
+	handler.cacheControl = cacheControl{
+		rules:    []config.CacheRule{{ContentType: "image/*", Directives: "public, max-age=86400, immutable"}},
+		fallback: "public, max-age=3600",
+	}
+
+	tests := []struct {
+		name     string
+		share    domain.ShareRequest
+		method   string
+		header   http.Header
+		expected string
+		// prefix matches expected against the start of the header only, for
+		// values depending on the time remaining
+		prefix bool
+	}{
+		{
+			name:     "image gets long caching",
+			share:    domain.ShareRequest{S3Path: "images/photo.jpg", ExpiresAt: time.Now().Add(72 * time.Hour)},
+			expected: "public, max-age=86400, immutable",
+		},
+		{
+			name:     "unmatched type gets the default",
+			share:    domain.ShareRequest{S3Path: "docs/report.txt", ExpiresAt: time.Now().Add(72 * time.Hour)},
+			expected: "public, max-age=3600",
+		},
+		{
+			name:     "max-age capped at remaining lifetime",
+			share:    domain.ShareRequest{S3Path: "images/photo.jpg", ExpiresAt: time.Now().Add(10*time.Minute + 25*time.Second)},
+			expected: "public, max-age=62",
+			prefix:   true,
+		},
+		{
+			name:     "HEAD request",
+			share:    domain.ShareRequest{S3Path: "images/photo.jpg", ExpiresAt: time.Now().Add(72 * time.Hour)},
+			method:   http.MethodHead,
+			expected: "public, max-age=86400, immutable",
+		},
+		{
+			name:     "range request",
+			share:    domain.ShareRequest{S3Path: "images/photo.jpg", ExpiresAt: time.Now().Add(72 * time.Hour)},
+			header:   http.Header{"Range": {"bytes=0-1"}},
+			expected: "public, max-age=86400, immutable",
+		},
+		{
+			name:     "one-time share",
+			share:    domain.ShareRequest{S3Path: "images/photo.jpg", ExpiresAt: time.Now().Add(72 * time.Hour), MaxDownloads: 1},
+			expected: privateCacheControl,
+		},
+		{
+			name:     "password-protected share",
+			share:    domain.ShareRequest{S3Path: "images/photo.jpg", ExpiresAt: time.Now().Add(72 * time.Hour), Password: "hunter2"},
+			header:   http.Header{sharePasswordHeader: {"hunter2"}},
+			expected: privateCacheControl,
+		},
+		{
+			name:     "IP-restricted share",
+			share:    domain.ShareRequest{S3Path: "images/photo.jpg", ExpiresAt: time.Now().Add(72 * time.Hour), AllowedIPs: []string{"192.0.2.0/24"}},
+			expected: privateCacheControl,
+		},
+		{
+			name:     "referer-restricted share",
+			share:    domain.ShareRequest{S3Path: "images/photo.jpg", ExpiresAt: time.Now().Add(72 * time.Hour), AllowedReferers: []string{"example.com"}},
+			header:   http.Header{"Referer": {"https://example.com/gallery"}},
+			expected: privateCacheControl,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.share.Secret = "test-secret"
+			resp, err := shareService.CreateShare(context.Background(), &tt.share)
+			if err != nil {
+				t.Fatalf("failed to create share: %v", err)
+			}
+
+			method := tt.method
+			if method == "" {
+				method = http.MethodGet
+			}
+			req := httptest.NewRequest(method, strings.TrimPrefix(resp.URL, "https://example.com"), nil)
+			for name, values := range tt.header {
+				req.Header[name] = values
+			}
+			w := httptest.NewRecorder()
+			handler.HandleImage(w, req)
+
+			if w.Code != http.StatusOK && w.Code != http.StatusPartialContent {
+				t.Fatalf("expected success, got %d: %s", w.Code, w.Body.String())
+			}
+			got := w.Header().Get("Cache-Control")
+			if tt.prefix && strings.HasPrefix(got, tt.expected) {
+				return
+			}
+			if got != tt.expected {
+				t.Errorf("expected Cache-Control %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestCapMaxAge(t *testing.T) {
+	tests := []struct {
+		directives string
+		remaining  time.Duration
+		expected   string
+	}{
+		{directives: "public, max-age=3600", remaining: 2 * time.Hour, expected: "public, max-age=3600"},
+		{directives: "public, max-age=3600", remaining: 90 * time.Second, expected: "public, max-age=90"},
+		{directives: "public, Max-Age=3600, s-maxage=7200", remaining: time.Minute, expected: "public, max-age=60, s-maxage=60"},
+		{directives: "public, max-age=3600", remaining: -time.Minute, expected: "public, max-age=0"},
+		{directives: "no-cache", remaining: time.Minute, expected: "no-cache"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.directives, func(t *testing.T) {
+			if got := capMaxAge(tt.directives, tt.remaining); got != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,16 @@
+package http
+
+import "net/http"
+
+// Middleware wraps a handler with behavior run around it
+type Middleware func(http.Handler) http.Handler
+
+// Chain wraps h in mws, the first outermost: a request passes through
+// mws[0], then mws[1] and so on before reaching h, and responses unwind in
+// reverse
+func Chain(h http.Handler, mws ...Middleware) http.Handler {
+	for i := len(mws) - 1; i >= 0; i-- {
+		h = mws[i](h)
+	}
+	return h
+}
@@ -0,0 +1,50 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestChain(t *testing.T) {
+	var events []string
+	marker := func(name string) Middleware {
+		return func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				events = append(events, "enter "+name)
+				next.ServeHTTP(w, r)
+				events = append(events, "leave "+name)
+			})
+		}
+	}
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		events = append(events, "handler")
+	})
+
+	tests := []struct {
+		name     string
+		mws      []Middleware
+		expected string
+	}{
+		{name: "no middlewares", expected: "handler"},
+		{name: "single middleware", mws: []Middleware{marker("a")}, expected: "enter a, handler, leave a"},
+		{
+			name:     "outermost first",
+			mws:      []Middleware{marker("a"), marker("b"), marker("c")},
+			expected: "enter a, enter b, enter c, handler, leave c, leave b, leave a",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			events = nil
+
+			Chain(handler, tt.mws...).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+			if got := strings.Join(events, ", "); got != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
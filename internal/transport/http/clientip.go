@@ -0,0 +1,66 @@
+package http
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/vchitai/go-s3-sharing/internal/domain"
+)
+
+// clientIPKey is the context key of the client IP resolved by
+// clientIPMiddleware
+type clientIPKey struct{}
+
+// clientIPMiddleware resolves the client IP of each request once, honoring
+// X-Forwarded-For only from trustedProxies, for clientIP to return
+func clientIPMiddleware(trustedProxies []string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ip := forwardedClientIP(r, trustedProxies)
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), clientIPKey{}, ip)))
+	})
+}
+
+// clientIP returns the IP of the client of r: the address resolved by
+// clientIPMiddleware, or the connection's remote address outside it
+func clientIP(r *http.Request) string {
+	if ip, ok := r.Context().Value(clientIPKey{}).(string); ok {
+		return ip
+	}
+	return remoteIP(r)
+}
+
+// forwardedClientIP returns the originating client IP of r. X-Forwarded-For
+// is only read when the connection comes from one of trustedProxies, walking
+// it from the nearest hop while the hops are trusted proxies too, so that
+// clients cannot spoof their address by sending the header themselves.
+func forwardedClientIP(r *http.Request, trustedProxies []string) string {
+	ip := remoteIP(r)
+	if len(trustedProxies) == 0 || !domain.MatchIP(ip, trustedProxies) {
+		return ip
+	}
+
+	hops := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := strings.TrimSpace(hops[i])
+		if hop == "" || net.ParseIP(hop) == nil {
+			// Malformed entries end the chain; the last trusted hop stands
+			return ip
+		}
+		ip = hop
+		if !domain.MatchIP(hop, trustedProxies) {
+			return ip
+		}
+	}
+	return ip
+}
+
+// remoteIP returns the host of the connection's remote address
+func remoteIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
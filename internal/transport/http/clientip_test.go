@@ -0,0 +1,65 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestForwardedClientIP(t *testing.T) {
+	trusted := []string{"10.0.0.0/8", "2001:db8:ffff::1"}
+	tests := []struct {
+		name         string
+		remoteAddr   string
+		forwardedFor string
+		proxies      []string
+		expected     string
+	}{
+		{name: "remote addr", remoteAddr: "192.0.2.1:1234", proxies: trusted, expected: "192.0.2.1"},
+		{name: "ipv6 remote addr", remoteAddr: "[2001:db8::1]:1234", proxies: trusted, expected: "2001:db8::1"},
+		{name: "remote addr without port", remoteAddr: "192.0.2.1", proxies: trusted, expected: "192.0.2.1"},
+		{name: "trusted proxy", remoteAddr: "10.0.0.1:1234", forwardedFor: "203.0.113.7", proxies: trusted, expected: "203.0.113.7"},
+		{name: "trusted ipv6 proxy", remoteAddr: "[2001:db8:ffff::1]:1234", forwardedFor: "203.0.113.7", proxies: trusted, expected: "203.0.113.7"},
+		{name: "chain of trusted proxies", remoteAddr: "10.0.0.1:1234", forwardedFor: " 203.0.113.7 , 10.0.0.2", proxies: trusted, expected: "203.0.113.7"},
+		{name: "spoofed entries before the client", remoteAddr: "10.0.0.1:1234", forwardedFor: "198.51.100.1, 203.0.113.7", proxies: trusted, expected: "203.0.113.7"},
+		{name: "only trusted hops", remoteAddr: "10.0.0.1:1234", forwardedFor: "10.0.0.3, 10.0.0.2", proxies: trusted, expected: "10.0.0.3"},
+		{name: "malformed entry", remoteAddr: "10.0.0.1:1234", forwardedFor: "203.0.113.7, unknown", proxies: trusted, expected: "10.0.0.1"},
+		{name: "trusted proxy without header", remoteAddr: "10.0.0.1:1234", proxies: trusted, expected: "10.0.0.1"},
+		{name: "untrusted peer", remoteAddr: "192.0.2.1:1234", forwardedFor: "203.0.113.7", proxies: trusted, expected: "192.0.2.1"},
+		{name: "no trusted proxies", remoteAddr: "10.0.0.1:1234", forwardedFor: "203.0.113.7", expected: "10.0.0.1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.RemoteAddr = tt.remoteAddr
+			if tt.forwardedFor != "" {
+				req.Header.Set("X-Forwarded-For", tt.forwardedFor)
+			}
+
+			if got := forwardedClientIP(req, tt.proxies); got != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestClientIPMiddleware(t *testing.T) {
+	var got string
+	handler := clientIPMiddleware([]string{"10.0.0.0/8"}, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = clientIP(r)
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.RemoteAddr = "10.0.0.1:1234"
+	req.Header.Set("X-Forwarded-For", "203.0.113.7")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+	if got != "203.0.113.7" {
+		t.Errorf("expected the forwarded client, got %q", got)
+	}
+
+	// Outside the middleware only the connection's address is known
+	if ip := clientIP(req); ip != "10.0.0.1" {
+		t.Errorf("expected the remote address, got %q", ip)
+	}
+}
@@ -0,0 +1,147 @@
+package http
+
+import (
+	"compress/gzip"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// compressionMiddleware gzips successful responses whose Content-Type is in
+// contentTypes when the client accepts gzip. Entries may be exact media types
+// or wildcards such as "text/*". Already compressed media types are never
+// compressed again, even when listed.
+func compressionMiddleware(contentTypes []string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gw := &gzipResponseWriter{
+			ResponseWriter: w,
+			contentTypes:   contentTypes,
+			acceptsGzip:    acceptsGzip(r.Header.Get("Accept-Encoding")),
+			head:           r.Method == http.MethodHead,
+		}
+		defer gw.Close()
+
+		next.ServeHTTP(gw, r)
+	})
+}
+
+// gzipResponseWriter decides whether to compress when the header is written
+type gzipResponseWriter struct {
+	http.ResponseWriter
+	contentTypes []string
+	acceptsGzip  bool
+	head         bool
+	wroteHeader  bool
+	gz           *gzip.Writer
+}
+
+// WriteHeader switches the response to gzip when it is compressible
+func (g *gzipResponseWriter) WriteHeader(status int) {
+	if g.wroteHeader {
+		return
+	}
+	g.wroteHeader = true
+
+	header := g.Header()
+	if header.Get("Content-Encoding") == "" && isCompressible(header.Get("Content-Type"), g.contentTypes) {
+		header.Add("Vary", "Accept-Encoding")
+
+		// Partial and bodiless responses are passed through untouched
+		if g.acceptsGzip && status == http.StatusOK && !g.head {
+			header.Set("Content-Encoding", "gzip")
+			header.Del("Content-Length")
+			// The encoded body differs byte-for-byte from the stored object
+			if etag := header.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+				header.Set("ETag", "W/"+etag)
+			}
+			g.gz = gzip.NewWriter(g.ResponseWriter)
+		}
+	}
+
+	g.ResponseWriter.WriteHeader(status)
+}
+
+// Write writes p, compressed when gzip was selected
+func (g *gzipResponseWriter) Write(p []byte) (int, error) {
+	if !g.wroteHeader {
+		g.WriteHeader(http.StatusOK)
+	}
+	if g.gz != nil {
+		return g.gz.Write(p)
+	}
+	return g.ResponseWriter.Write(p)
+}
+
+// Close flushes the gzip stream, if any
+func (g *gzipResponseWriter) Close() error {
+	if g.gz == nil {
+		return nil
+	}
+	return g.gz.Close()
+}
+
+// Unwrap returns the underlying ResponseWriter for http.ResponseController
+func (g *gzipResponseWriter) Unwrap() http.ResponseWriter {
+	return g.ResponseWriter
+}
+
+// acceptsGzip reports whether an Accept-Encoding header allows gzip
+func acceptsGzip(header string) bool {
+	for _, part := range strings.Split(header, ",") {
+		coding, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		coding = strings.TrimSpace(coding)
+		if coding != "gzip" && coding != "*" {
+			continue
+		}
+		name, value, ok := strings.Cut(strings.TrimSpace(params), "=")
+		if !ok || strings.TrimSpace(name) != "q" {
+			return true
+		}
+		q, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		return err == nil && q > 0
+	}
+	return false
+}
+
+// isCompressible reports whether contentType matches contentTypes and is
+// not an already compressed media type
+func isCompressible(contentType string, contentTypes []string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil || isPrecompressed(mediaType) {
+		return false
+	}
+
+	for _, candidate := range contentTypes {
+		if matchesMediaType(mediaType, candidate) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesMediaType reports whether mediaType equals pattern or falls under a
+// wildcard pattern such as "text/*"
+func matchesMediaType(mediaType, pattern string) bool {
+	if prefix, ok := strings.CutSuffix(pattern, "/*"); ok {
+		return strings.HasPrefix(mediaType, prefix+"/")
+	}
+	return mediaType == pattern
+}
+
+// isPrecompressed reports whether mediaType is already compressed, such as
+// raster images, audio, video and archives
+func isPrecompressed(mediaType string) bool {
+	switch mediaType {
+	case "image/svg+xml":
+		return false
+	case "application/zip", "application/gzip", "application/x-gzip",
+		"application/x-bzip2", "application/x-xz", "application/zstd",
+		"application/x-7z-compressed", "application/x-rar-compressed",
+		"application/pdf":
+		return true
+	}
+	return strings.HasPrefix(mediaType, "image/") ||
+		strings.HasPrefix(mediaType, "audio/") ||
+		strings.HasPrefix(mediaType, "video/")
+}
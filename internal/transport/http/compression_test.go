@@ -0,0 +1,110 @@
+package http
+
+import (
+	"compress/gzip"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/vchitai/go-s3-sharing/internal/config"
+)
+
+func TestServer_Compression(t *testing.T) {
+	svg := []byte(`<svg xmlns="http://www.w3.org/2000/svg">` + strings.Repeat(`<rect width="1" height="1"/>`, 50) + `</svg>`)
+	jpeg := []byte("jpeg-bytes")
+
+	_, shareService := newTestHandler(map[string]mockObject{
+		"images/logo.svg":  {contentType: "image/svg+xml", data: svg, etag: `"svg-v1"`},
+		"images/photo.jpg": {contentType: "image/jpeg", data: jpeg},
+	})
+	svgPath := createTestShare(t, shareService, "images/logo.svg")
+	jpegPath := createTestShare(t, shareService, "images/photo.jpg")
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	server := NewServer(&config.Config{
+		Compression: config.CompressionConfig{ContentTypes: []string{"image/svg+xml", "image/jpeg", "text/*"}},
+	}, shareService, logger)
+	handler := server.server.Handler
+
+	tests := []struct {
+		name           string
+		path           string
+		acceptEncoding string
+		rangeHeader    string
+		expectGzip     bool
+		expectedBody   []byte
+	}{
+		{name: "svg is compressed", path: svgPath, acceptEncoding: "gzip, deflate", expectGzip: true, expectedBody: svg},
+		{name: "jpeg is never compressed", path: jpegPath, acceptEncoding: "gzip", expectedBody: jpeg},
+		{name: "client without gzip", path: svgPath, expectedBody: svg},
+		{name: "gzip refused with q=0", path: svgPath, acceptEncoding: "gzip;q=0", expectedBody: svg},
+		{name: "range responses are not compressed", path: svgPath, acceptEncoding: "gzip", rangeHeader: "bytes=0-3", expectedBody: svg[:4]},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.acceptEncoding != "" {
+				req.Header.Set("Accept-Encoding", tt.acceptEncoding)
+			}
+			if tt.rangeHeader != "" {
+				req.Header.Set("Range", tt.rangeHeader)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+
+			body := w.Body.Bytes()
+			if tt.expectGzip {
+				if got := w.Header().Get("Content-Encoding"); got != "gzip" {
+					t.Fatalf("expected Content-Encoding gzip, got %q", got)
+				}
+				if got := w.Header().Get("Content-Length"); got != "" {
+					t.Errorf("expected no Content-Length, got %q", got)
+				}
+				if got := w.Header().Get("ETag"); got != `W/"svg-v1"` {
+					t.Errorf("expected weak ETag, got %q", got)
+				}
+
+				reader, err := gzip.NewReader(w.Body)
+				if err != nil {
+					t.Fatalf("failed to read gzip body: %v", err)
+				}
+				if body, err = io.ReadAll(reader); err != nil {
+					t.Fatalf("failed to decompress body: %v", err)
+				}
+			} else if got := w.Header().Get("Content-Encoding"); got != "" {
+				t.Errorf("expected no Content-Encoding, got %q", got)
+			}
+
+			if string(body) != string(tt.expectedBody) {
+				t.Errorf("expected body %q, got %q", tt.expectedBody, body)
+			}
+		})
+	}
+}
+
+func TestIsCompressible(t *testing.T) {
+	types := []string{"text/*", "application/json", "image/svg+xml", "image/png", "application/zip"}
+
+	tests := []struct {
+		contentType string
+		expected    bool
+	}{
+		{"text/css", true},
+		{"text/plain; charset=utf-8", true},
+		{"application/json", true},
+		{"image/svg+xml", true},
+		{"application/javascript", false},
+		{"image/png", false},
+		{"application/zip", false},
+		{"", false},
+	}
+
+	for _, tt := range tests {
+		if got := isCompressible(tt.contentType, types); got != tt.expected {
+			t.Errorf("isCompressible(%q): expected %v, got %v", tt.contentType, tt.expected, got)
+		}
+	}
+}
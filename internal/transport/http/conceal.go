@@ -0,0 +1,90 @@
+package http
+
+import (
+	"net/http"
+	"time"
+)
+
+// isConcealedStatus reports whether hardened mode hides a response of status
+// behind a 404. These are the statuses telling a missing share apart from an
+// expired, tampered or password-protected one.
+func isConcealedStatus(status int) bool {
+	switch status {
+	case http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden,
+		http.StatusNotFound, http.StatusGone:
+		return true
+	default:
+		return false
+	}
+}
+
+// concealMiddleware answers every failed share link with the same 404, sent
+// no earlier than delay after the request arrived, so that neither the
+// response nor its timing tells which paths are shared. Server errors pass
+// through unchanged.
+func concealMiddleware(delay time.Duration, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		cw := &concealingWriter{ResponseWriter: w, header: w.Header().Clone()}
+		next.ServeHTTP(cw, r)
+		if !cw.concealed {
+			return
+		}
+
+		if wait := time.Until(start.Add(delay)); wait > 0 {
+			timer := time.NewTimer(wait)
+			select {
+			case <-timer.C:
+			case <-r.Context().Done():
+				timer.Stop()
+				return
+			}
+		}
+		writeErrorResponse(w, CodeNotFound, "not found", http.StatusNotFound)
+	})
+}
+
+// concealingWriter discards responses with a concealed status, restoring the
+// headers set before the handler ran so that none of its headers leak
+type concealingWriter struct {
+	http.ResponseWriter
+	// header holds the headers of the response before the handler ran
+	header      http.Header
+	wroteHeader bool
+	concealed   bool
+}
+
+// WriteHeader holds back concealed statuses, dropping the headers the handler
+// set for them
+func (c *concealingWriter) WriteHeader(statusCode int) {
+	if c.wroteHeader {
+		return
+	}
+	c.wroteHeader = true
+	if isConcealedStatus(statusCode) {
+		c.concealed = true
+		header := c.ResponseWriter.Header()
+		clear(header)
+		for key, values := range c.header {
+			header[key] = values
+		}
+		return
+	}
+	c.ResponseWriter.WriteHeader(statusCode)
+}
+
+// Write discards the body of concealed responses
+func (c *concealingWriter) Write(p []byte) (int, error) {
+	if !c.wroteHeader {
+		c.WriteHeader(http.StatusOK)
+	}
+	if c.concealed {
+		return len(p), nil
+	}
+	return c.ResponseWriter.Write(p)
+}
+
+// Unwrap returns the underlying ResponseWriter for http.ResponseController
+func (c *concealingWriter) Unwrap() http.ResponseWriter {
+	return c.ResponseWriter
+}
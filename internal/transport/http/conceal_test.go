@@ -0,0 +1,88 @@
+package http
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/vchitai/go-s3-sharing/internal/config"
+)
+
+func TestServer_HardenedErrors(t *testing.T) {
+	_, shareService, storage := newTestHandlerWithStorage(map[string]mockObject{
+		"images/photo.jpg":   {contentType: "image/jpeg", data: []byte("jpeg-bytes")},
+		"images/deleted.jpg": {contentType: "image/jpeg", data: []byte("jpeg-bytes")},
+	})
+	validPath := createTestShare(t, shareService, "images/photo.jpg")
+	// The share exists but its object was deleted from storage
+	missingPath := createTestShare(t, shareService, "images/deleted.jpg")
+	delete(storage.objects, "images/deleted.jpg")
+	failures := []struct {
+		name           string
+		path           string
+		expectedStatus int
+	}{
+		{name: "missing object", path: missingPath, expectedStatus: http.StatusNotFound},
+		{name: "never shared", path: strings.Replace(validPath, "photo.jpg", "unknown.jpg", 1), expectedStatus: http.StatusUnauthorized},
+		{name: "wrong secret", path: strings.Replace(validPath, "test-secret", "wrong-secret", 1), expectedStatus: http.StatusUnauthorized},
+		{name: "expired", path: fmt.Sprintf("/%d/test-secret/images/photo.jpg", time.Now().Add(-time.Hour).Unix()), expectedStatus: http.StatusForbidden},
+	}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	t.Run("detailed by default", func(t *testing.T) {
+		handler := NewServer(&config.Config{}, shareService, logger).server.Handler
+		for _, tt := range failures {
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, httptest.NewRequest("GET", tt.path, nil))
+			if w.Code != tt.expectedStatus {
+				t.Errorf("%s: expected status %d, got %d", tt.name, tt.expectedStatus, w.Code)
+			}
+		}
+	})
+
+	t.Run("hardened", func(t *testing.T) {
+		const delay = 50 * time.Millisecond
+		handler := NewServer(&config.Config{
+			Security: config.SecurityConfig{HardenedErrors: true, HardenedErrorDelay: delay},
+		}, shareService, logger).server.Handler
+
+		var firstBody string
+		var firstHeader http.Header
+		for i, tt := range failures {
+			w := httptest.NewRecorder()
+			start := time.Now()
+			handler.ServeHTTP(w, httptest.NewRequest("GET", tt.path, nil))
+			if elapsed := time.Since(start); elapsed < delay {
+				t.Errorf("%s: expected the response to take at least %v, took %v", tt.name, delay, elapsed)
+			}
+			if w.Code != http.StatusNotFound {
+				t.Errorf("%s: expected status %d, got %d", tt.name, http.StatusNotFound, w.Code)
+			}
+
+			// Request IDs differ per request by design
+			header := w.Header().Clone()
+			header.Del("X-Request-ID")
+			if i == 0 {
+				firstBody, firstHeader = w.Body.String(), header
+				continue
+			}
+			if body := w.Body.String(); body != firstBody {
+				t.Errorf("%s: expected body %q, got %q", tt.name, firstBody, body)
+			}
+			if fmt.Sprint(header) != fmt.Sprint(firstHeader) {
+				t.Errorf("%s: expected headers %v, got %v", tt.name, firstHeader, header)
+			}
+		}
+
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest("GET", validPath, nil))
+		if w.Code != http.StatusOK || w.Body.String() != "jpeg-bytes" {
+			t.Errorf("expected valid links to be served, got %d %q", w.Code, w.Body.String())
+		}
+	})
+}
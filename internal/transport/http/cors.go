@@ -0,0 +1,67 @@
+package http
+
+import (
+	"net/http"
+)
+
+// CORS headers sent for the image and download routes
+const (
+	corsAllowedMethods = "GET, HEAD, OPTIONS"
+	corsAllowedHeaders = "Range, If-None-Match, X-Share-Password, X-Tenant-ID"
+	corsExposedHeaders = "Accept-Ranges, Content-Disposition, Content-Length, Content-Range, ETag, X-Content-SHA256"
+	corsMaxAge         = "600"
+)
+
+// corsMiddleware adds CORS headers for requests from allowedOrigins and
+// answers preflight requests. An origin of "*" allows every origin.
+func corsMiddleware(allowedOrigins []string, next http.Handler) http.Handler {
+	allowAll := false
+	origins := make(map[string]bool, len(allowedOrigins))
+	for _, origin := range allowedOrigins {
+		if origin == "*" {
+			allowAll = true
+		}
+		origins[origin] = true
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		if origin == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		preflight := r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+		allowed := allowAll || origins[origin]
+
+		if !allowAll {
+			w.Header().Add("Vary", "Origin")
+		}
+
+		if !allowed {
+			if preflight {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if allowAll {
+			w.Header().Set("Access-Control-Allow-Origin", "*")
+		} else {
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+		}
+
+		if preflight {
+			w.Header().Set("Access-Control-Allow-Methods", corsAllowedMethods)
+			w.Header().Set("Access-Control-Allow-Headers", corsAllowedHeaders)
+			w.Header().Set("Access-Control-Max-Age", corsMaxAge)
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		w.Header().Set("Access-Control-Expose-Headers", corsExposedHeaders)
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,119 @@
+package http
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/vchitai/go-s3-sharing/internal/config"
+)
+
+func TestServer_CORS(t *testing.T) {
+	_, shareService := newTestHandler(map[string]mockObject{
+		"images/photo.jpg": {contentType: "image/jpeg", data: []byte("jpeg-bytes")},
+	})
+	urlPath := createTestShare(t, shareService, "images/photo.jpg")
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	server := NewServer(&config.Config{
+		CORS: config.CORSConfig{AllowedOrigins: []string{"https://app.example.com"}},
+	}, shareService, logger)
+	handler := server.server.Handler
+
+	tests := []struct {
+		name           string
+		method         string
+		path           string
+		origin         string
+		preflight      bool
+		expectedStatus int
+		expectedOrigin string
+	}{
+		{
+			name:           "preflight from allowed origin",
+			method:         http.MethodOptions,
+			path:           urlPath,
+			origin:         "https://app.example.com",
+			preflight:      true,
+			expectedStatus: http.StatusNoContent,
+			expectedOrigin: "https://app.example.com",
+		},
+		{
+			name:           "preflight from disallowed origin",
+			method:         http.MethodOptions,
+			path:           urlPath,
+			origin:         "https://evil.example.com",
+			preflight:      true,
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:           "download from allowed origin",
+			method:         http.MethodGet,
+			path:           urlPath,
+			origin:         "https://app.example.com",
+			expectedStatus: http.StatusOK,
+			expectedOrigin: "https://app.example.com",
+		},
+		{
+			name:           "download from disallowed origin",
+			method:         http.MethodGet,
+			path:           urlPath,
+			origin:         "https://evil.example.com",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "admin API is not CORS enabled",
+			method:         http.MethodGet,
+			path:           "/api/shares?s3_path=images/photo.jpg",
+			origin:         "https://app.example.com",
+			expectedStatus: http.StatusOK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			req.Header.Set("Origin", tt.origin)
+			if tt.preflight {
+				req.Header.Set("Access-Control-Request-Method", http.MethodGet)
+				req.Header.Set("Access-Control-Request-Headers", "Range")
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d", tt.expectedStatus, w.Code)
+			}
+			if got := w.Header().Get("Access-Control-Allow-Origin"); got != tt.expectedOrigin {
+				t.Errorf("expected Access-Control-Allow-Origin %q, got %q", tt.expectedOrigin, got)
+			}
+			if tt.preflight && tt.expectedOrigin != "" {
+				if got := w.Header().Get("Access-Control-Allow-Methods"); got != corsAllowedMethods {
+					t.Errorf("expected Access-Control-Allow-Methods %q, got %q", corsAllowedMethods, got)
+				}
+				if got := w.Header().Get("Access-Control-Allow-Headers"); got != corsAllowedHeaders {
+					t.Errorf("expected Access-Control-Allow-Headers %q, got %q", corsAllowedHeaders, got)
+				}
+			}
+		})
+	}
+}
+
+func TestCORSMiddleware_Wildcard(t *testing.T) {
+	handler := corsMiddleware([]string{"*"}, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/24/12/31/secret/images/photo.jpg", nil)
+	req.Header.Set("Origin", "https://anywhere.example.com")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	if got := w.Header().Get("Access-Control-Allow-Origin"); got != "*" {
+		t.Errorf("expected Access-Control-Allow-Origin %q, got %q", "*", got)
+	}
+	if got := w.Header().Get("Access-Control-Expose-Headers"); got != corsExposedHeaders {
+		t.Errorf("expected Access-Control-Expose-Headers %q, got %q", corsExposedHeaders, got)
+	}
+}
@@ -0,0 +1,102 @@
+package http
+
+import (
+	"bytes"
+	"html/template"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// errorPageMiddleware lets the error responses of next be rendered with page
+// for requests preferring HTML, such as browsers opening a share link
+func errorPageMiddleware(page *template.Template, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(&errorPageWriter{
+			ResponseWriter: w,
+			page:           page,
+			html:           prefersHTML(r.Header.Get("Accept")),
+		}, r)
+	})
+}
+
+// errorPageWriter carries the error page of a request to writeErrorResponse
+type errorPageWriter struct {
+	http.ResponseWriter
+	page *template.Template
+	// html reports whether the request prefers HTML to JSON
+	html bool
+}
+
+// Unwrap returns the underlying ResponseWriter for http.ResponseController
+func (e *errorPageWriter) Unwrap() http.ResponseWriter {
+	return e.ResponseWriter
+}
+
+// errorPageOf returns the errorPageWriter w is or wraps, or nil when the
+// route has no error page
+func errorPageOf(w http.ResponseWriter) *errorPageWriter {
+	for {
+		switch writer := w.(type) {
+		case *errorPageWriter:
+			return writer
+		case interface{ Unwrap() http.ResponseWriter }:
+			w = writer.Unwrap()
+		default:
+			return nil
+		}
+	}
+}
+
+// writeErrorPage renders resp with the error page of w, returning false
+// without writing anything when the route has no error page, the request
+// prefers JSON or the template fails
+func writeErrorPage(w http.ResponseWriter, resp ErrorResponse) bool {
+	page := errorPageOf(w)
+	if page == nil {
+		return false
+	}
+	// The body depends on the Accept header whichever is chosen
+	w.Header().Add("Vary", "Accept")
+	if !page.html {
+		return false
+	}
+
+	var buf bytes.Buffer
+	if err := page.page.Execute(&buf, resp); err != nil {
+		return false
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(resp.Status)
+	w.Write(buf.Bytes())
+	return true
+}
+
+// prefersHTML reports whether an Accept header lists text/html with a higher
+// quality than it accepts JSON with. Wildcards count for JSON only, so that
+// clients accepting anything keep getting JSON.
+func prefersHTML(header string) bool {
+	var htmlQ, jsonQ float64
+	for _, part := range strings.Split(header, ",") {
+		mediaType, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		q := 1.0
+		for _, param := range strings.Split(params, ";") {
+			name, value, ok := strings.Cut(strings.TrimSpace(param), "=")
+			if ok && strings.TrimSpace(name) == "q" {
+				parsed, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+				if err != nil {
+					parsed = 0
+				}
+				q = parsed
+			}
+		}
+
+		switch strings.ToLower(strings.TrimSpace(mediaType)) {
+		case "text/html", "application/xhtml+xml":
+			htmlQ = max(htmlQ, q)
+		case "application/json", "application/*", "*/*":
+			jsonQ = max(jsonQ, q)
+		}
+	}
+	return htmlQ > jsonQ
+}
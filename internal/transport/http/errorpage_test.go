@@ -0,0 +1,120 @@
+package http
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/vchitai/go-s3-sharing/internal/config"
+)
+
+func TestServer_ErrorPage(t *testing.T) {
+	_, shareService := newTestHandler(map[string]mockObject{
+		"images/photo.jpg": {contentType: "image/jpeg", data: []byte("jpeg-bytes")},
+	})
+	expiredPath := fmt.Sprintf("/%d/test-secret/images/photo.jpg", time.Now().Add(-time.Hour).Unix())
+	page := template.Must(template.New("error").Parse(`<h1>{{.Status}} {{.Code}}</h1><p>{{.Message}}</p>`))
+	handler := NewServer(&config.Config{}, shareService, slog.New(slog.NewTextHandler(io.Discard, nil)), WithErrorPage(page)).server.Handler
+
+	browser := "text/html,application/xhtml+xml,application/xml;q=0.9,image/avif,image/webp,*/*;q=0.8"
+
+	t.Run("browser gets HTML", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, expiredPath, nil)
+		req.Header.Set("Accept", browser)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+
+		if w.Code != http.StatusForbidden {
+			t.Errorf("expected status %d, got %d", http.StatusForbidden, w.Code)
+		}
+		if ct := w.Header().Get("Content-Type"); ct != "text/html; charset=utf-8" {
+			t.Errorf("expected an HTML content type, got %q", ct)
+		}
+		if body := w.Body.String(); body != "<h1>403 EXPIRED</h1><p>link expired</p>" {
+			t.Errorf("unexpected page %q", body)
+		}
+		if vary := w.Header().Get("Vary"); vary != "Accept" {
+			t.Errorf("expected Vary: Accept, got %q", vary)
+		}
+	})
+
+	for _, accept := range []string{"application/json", "", "*/*", "image/avif,image/webp,*/*;q=0.8"} {
+		t.Run("JSON for "+accept, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, expiredPath, nil)
+			if accept != "" {
+				req.Header.Set("Accept", accept)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+
+			if w.Code != http.StatusForbidden {
+				t.Errorf("expected status %d, got %d", http.StatusForbidden, w.Code)
+			}
+			var resp ErrorResponse
+			if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+				t.Fatalf("expected a JSON error, got %v", err)
+			}
+			if resp.Code != CodeExpired {
+				t.Errorf("expected code %s, got %s", CodeExpired, resp.Code)
+			}
+			if vary := w.Header().Get("Vary"); vary != "Accept" {
+				t.Errorf("expected Vary: Accept, got %q", vary)
+			}
+		})
+	}
+
+	t.Run("API routes keep JSON", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/api/shares", nil)
+		req.Header.Set("Accept", browser)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+
+		if ct := w.Header().Get("Content-Type"); ct != "application/json" {
+			t.Errorf("expected a JSON content type, got %q", ct)
+		}
+	})
+
+	t.Run("hardened errors render the page", func(t *testing.T) {
+		hardened := NewServer(&config.Config{
+			Security: config.SecurityConfig{HardenedErrors: true},
+		}, shareService, slog.New(slog.NewTextHandler(io.Discard, nil)), WithErrorPage(page)).server.Handler
+		req := httptest.NewRequest(http.MethodGet, expiredPath, nil)
+		req.Header.Set("Accept", browser)
+		w := httptest.NewRecorder()
+		hardened.ServeHTTP(w, req)
+
+		if body := w.Body.String(); w.Code != http.StatusNotFound || body != "<h1>404 NOT_FOUND</h1><p>not found</p>" {
+			t.Errorf("expected the 404 page, got %d %q", w.Code, body)
+		}
+	})
+}
+
+func TestPrefersHTML(t *testing.T) {
+	tests := []struct {
+		accept   string
+		expected bool
+	}{
+		{accept: "text/html", expected: true},
+		{accept: "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8", expected: true},
+		{accept: "application/json, text/html;q=0.5", expected: false},
+		{accept: "text/html;q=0.9, application/json;q=0.1", expected: true},
+		{accept: "text/html, application/json", expected: false},
+		{accept: "text/html;q=0", expected: false},
+		{accept: "*/*", expected: false},
+		{accept: "", expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.accept, func(t *testing.T) {
+			if got := prefersHTML(tt.accept); got != tt.expected {
+				t.Errorf("expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,120 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+// expiredAtHeader carries the time an expired link stopped working
+const expiredAtHeader = "X-Expired-At"
+
+// expiryNearMiss is how recently a link must have expired for its error body
+// to describe the expiry, so that clients can ask for a renewal
+const expiryNearMiss = 24 * time.Hour
+
+// Machine-readable error codes of ErrorResponse. They are part of the API
+// and must not change once released.
+const (
+	CodeBadRequest          = "BAD_REQUEST"
+	CodeUnauthorized        = "UNAUTHORIZED"
+	CodeForbidden           = "FORBIDDEN"
+	CodeNotFound            = "NOT_FOUND"
+	CodeMethodNotAllowed    = "METHOD_NOT_ALLOWED"
+	CodeConflict            = "CONFLICT"
+	CodeRequestTooLarge     = "REQUEST_TOO_LARGE"
+	CodeRangeNotSatisfiable = "RANGE_NOT_SATISFIABLE"
+	CodeRateLimited         = "RATE_LIMITED"
+	CodeInternal            = "INTERNAL"
+	CodeNotImplemented      = "NOT_IMPLEMENTED"
+	CodeStorageUnavailable  = "STORAGE_UNAVAILABLE"
+	CodeTooManyStreams      = "TOO_MANY_STREAMS"
+
+	CodeExpired           = "EXPIRED"
+	CodeConsumed          = "CONSUMED"
+	CodeInvalidSignature  = "INVALID_SIGNATURE"
+	CodeInvalidPath       = "INVALID_PATH"
+	CodeForbiddenPath     = "FORBIDDEN_PATH"
+	CodeInvalidDate       = "INVALID_DATE"
+	CodeInvalidTenant     = "INVALID_TENANT"
+	CodePasswordRequired  = "PASSWORD_REQUIRED"
+	CodeInvalidPassword   = "INVALID_PASSWORD"
+	CodeRefererNotAllowed = "REFERER_NOT_ALLOWED"
+	CodeIPNotAllowed      = "IP_NOT_ALLOWED"
+	CodeMaxAgeExceeded    = "MAX_AGE_EXCEEDED"
+	CodeNotSupported      = "NOT_SUPPORTED"
+	CodeImageTooLarge     = "IMAGE_TOO_LARGE"
+)
+
+// statusErrorCode returns the generic error code of an HTTP status, used when
+// no more specific code applies
+func statusErrorCode(statusCode int) string {
+	switch statusCode {
+	case http.StatusUnauthorized:
+		return CodeUnauthorized
+	case http.StatusForbidden:
+		return CodeForbidden
+	case http.StatusNotFound:
+		return CodeNotFound
+	case http.StatusMethodNotAllowed:
+		return CodeMethodNotAllowed
+	case http.StatusConflict:
+		return CodeConflict
+	case http.StatusRequestEntityTooLarge:
+		return CodeRequestTooLarge
+	case http.StatusRequestedRangeNotSatisfiable:
+		return CodeRangeNotSatisfiable
+	case http.StatusTooManyRequests:
+		return CodeRateLimited
+	case http.StatusNotImplemented:
+		return CodeNotImplemented
+	case http.StatusServiceUnavailable:
+		return CodeStorageUnavailable
+	}
+	if statusCode >= 500 {
+		return CodeInternal
+	}
+	return CodeBadRequest
+}
+
+// writeErrorResponse writes an error response with code, message and status
+// as JSON, or with the route's error page for requests preferring HTML
+func writeErrorResponse(w http.ResponseWriter, code, message string, statusCode int) {
+	writeErrorBody(w, ErrorResponse{
+		Error:   message,
+		Code:    code,
+		Status:  statusCode,
+		Message: message,
+	})
+}
+
+// writeExpiredResponse writes the 403 EXPIRED response of a link that expired
+// at expiredAt, reporting the expiry in an X-Expired-At header and, for links
+// that expired within expiryNearMiss, in the expired_at field of the body
+func writeExpiredResponse(w http.ResponseWriter, message string, expiredAt time.Time) {
+	w.Header().Set(expiredAtHeader, expiredAt.UTC().Format(http.TimeFormat))
+
+	resp := ErrorResponse{
+		Error:   message,
+		Code:    CodeExpired,
+		Status:  http.StatusForbidden,
+		Message: message,
+	}
+	if time.Since(expiredAt) <= expiryNearMiss {
+		expiredAt = expiredAt.UTC()
+		resp.ExpiredAt = &expiredAt
+	}
+	writeErrorBody(w, resp)
+}
+
+// writeErrorBody writes resp as JSON, or with the route's error page for
+// requests preferring HTML
+func writeErrorBody(w http.ResponseWriter, resp ErrorResponse) {
+	if writeErrorPage(w, resp) {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(resp.Status)
+	json.NewEncoder(w).Encode(resp)
+}
@@ -1,10 +1,18 @@
 package http
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
-	"io"
+	"errors"
+	"fmt"
 	"log/slog"
+	"mime"
 	"net/http"
+	"net/url"
+	"path"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
@@ -13,191 +21,1807 @@ import (
 	"github.com/vchitai/go-s3-sharing/internal/service"
 )
 
+// sharePasswordHeader carries the password of password-protected shares
+const sharePasswordHeader = "X-Share-Password"
+
+// shareExpiresAtHeader carries the expiry of a share in HEAD /api/shares
+// responses
+const shareExpiresAtHeader = "X-Share-Expires-At"
+
+// contentSHA256Header carries the hex-encoded SHA-256 of a served object, so
+// that clients can verify their download
+const contentSHA256Header = "X-Content-SHA256"
+
+// maxPasswordBytes is the longest share password bcrypt can hash
+const maxPasswordBytes = 72
+
+// defaultMaxBatchSize bounds batch share creations unless configured
+const defaultMaxBatchSize = 100
+
+// defaultMaxRequestBytes bounds API request bodies unless configured
+const defaultMaxRequestBytes = 1 << 20
+
+// Page sizes of the audit log endpoint
+const (
+	defaultAuditLimit = 100
+	maxAuditLimit     = 1000
+)
+
+// Idempotency-Key makes retried share creations return the original response,
+// marked with Idempotent-Replayed
+const (
+	idempotencyKeyHeader     = "Idempotency-Key"
+	idempotentReplayedHeader = "Idempotent-Replayed"
+	// maxIdempotencyKeyLength bounds the keys clients may send
+	maxIdempotencyKeyLength = 255
+)
+
+// Range parsing errors
+var (
+	errInvalidRange   = errors.New("invalid range")
+	errMultipleRanges = errors.New("multiple ranges")
+)
+
 // Handler handles HTTP requests for the S3 sharing service
 type Handler struct {
 	shareService *service.ShareService
 	logger       *slog.Logger
+	// downloadBytesPerSec limits the rate of each download stream; zero is
+	// unlimited
+	downloadBytesPerSec int
+	// cacheControl chooses the Cache-Control header of shared objects
+	cacheControl cacheControl
+	// streams tracks downloads in flight for graceful shutdown
+	streams streamTracker
+	// streamSlots bounds the downloads streaming at once; nil is unlimited
+	streamSlots *streamLimiter
+	// maxBatchSize bounds the number of shares of a batch creation
+	maxBatchSize int
+	// maxRequestBytes bounds the JSON bodies of API requests and the password
+	// forms of share links
+	maxRequestBytes int64
+	// streamBuffers holds the copy buffers of download streams
+	streamBuffers *bufferPool
+	// shareCookies accepts share cookies in place of link secrets
+	shareCookies bool
+	// secureCookies marks share cookies Secure, for services reached over
+	// HTTPS
+	secureCookies bool
 }
 
 // NewHandler creates a new HTTP handler
 func NewHandler(shareService *service.ShareService, logger *slog.Logger) *Handler {
 	return &Handler{
-		shareService: shareService,
-		logger:       logger,
+		shareService:    shareService,
+		logger:          logger,
+		cacheControl:    defaultCacheControl,
+		maxBatchSize:    defaultMaxBatchSize,
+		maxRequestBytes: defaultMaxRequestBytes,
+		streamBuffers:   newBufferPool(defaultStreamBufferSize),
 	}
 }
 
 // HandleImage handles image sharing requests
 func (h *Handler) HandleImage(w http.ResponseWriter, r *http.Request) {
-	ctx := r.Context()
-
 	// Skip API routes and health checks - these should be handled by specific handlers
-	if strings.HasPrefix(r.URL.Path, "/api/") ||
-		r.URL.Path == "/health" ||
-		r.URL.Path == "/ready" {
+	if strings.HasPrefix(r.URL.Path, "/api/") || isOperationalPath(r.URL.Path) {
 		http.NotFound(w, r)
 		return
 	}
+	if !slices.Contains(shareLinkMethods, r.Method) {
+		h.writeMethodNotAllowed(w, shareLinkMethods...)
+		return
+	}
+	r = withResumedDownload(withShareRecords(r))
 
-	// Parse URL path: /yy/mm/dd/secret/path/to/file.jpg
+	// Clients holding a share cookie fetch what it grants by path alone
 	path := strings.Trim(r.URL.Path, "/")
+	if cookie := h.shareCookie(r, path); cookie != nil {
+		h.serveCookieShare(w, r, cookie, path)
+		return
+	}
+
+	// Parse URL path: /expiry/secret/path/to/file.jpg, or
+	// /expiry/-/path/to/file.jpg for public shares
 	parts := strings.Split(path, "/")
 
-	if len(parts) < 5 {
+	if len(parts) < 2 {
 		http.NotFound(w, r)
 		return
 	}
 
 	// Extract components
-	dateStr := strings.Join(parts[0:3], "-") // e.g. "25-09-13"
-	secret := parts[3]
-	s3Path := strings.Join(parts[4:], "/")
+	expiresAt, segments, ok := h.parseLinkExpiry(w, r, parts)
+	if !ok {
+		return
+	}
+	secret, s3Path, ok := h.splitShareLink(w, r, segments, "")
+	if !ok {
+		return
+	}
+
+	// Audit every access to the share, whether it is served or denied
+	rec := newResponseRecorder(w)
+	defer h.auditAccess(r, s3Path, rec)
+	w = rec
+
+	if !h.validateShareLink(w, r, expiresAt, secret, s3Path) {
+		return
+	}
+
+	webhookURL := h.claimFirstAccess(r, s3Path)
+	h.serveShare(w, r, s3Path, s3Path)
+	if webhookURL != "" {
+		h.notifyFirstAccess(r, s3Path, webhookURL, rec.status)
+	}
+}
+
+// withShareRecords returns r loading the record of the share it is served
+// from once, however many checks of the share consult it
+func withShareRecords(r *http.Request) *http.Request {
+	return r.WithContext(service.WithShareRecords(r.Context()))
+}
 
-	// Validate date
-	expiresAt, err := h.parseDate(dateStr)
+// withResumedDownload returns r resuming the download of the client under the
+// entity tag of its If-Range header, if it sends a Range, so that a share its
+// download consumed can still serve the rest
+func withResumedDownload(r *http.Request) *http.Request {
+	ifRange := r.Header.Get("If-Range")
+	if r.Header.Get("Range") == "" || !strings.HasPrefix(ifRange, `"`) {
+		return r
+	}
+	return r.WithContext(service.WithResumedDownload(r.Context(), downloadID(r, ifRange)))
+}
+
+// downloadID identifies the download of the object with entity tag etag by
+// the client of r
+func downloadID(r *http.Request, etag string) string {
+	return clientIP(r) + " " + etag
+}
+
+// claimFirstAccess returns the webhook URL to notify when this GET is the
+// first access to a share with a webhook, and an empty URL otherwise
+func (h *Handler) claimFirstAccess(r *http.Request, s3Path string) string {
+	if r.Method != http.MethodGet {
+		return ""
+	}
+	webhookURL, err := h.shareService.ClaimFirstAccess(r.Context(), s3Path)
+	if err != nil {
+		h.logger.Warn("failed to claim first share access", "path", s3Path, "error", err)
+		return ""
+	}
+	return webhookURL
+}
+
+// notifyFirstAccess posts the first access event of s3Path to webhookURL in
+// the background once the download succeeded with status, and otherwise
+// releases the claim for the next access. Failures are logged and never fail
+// the download.
+func (h *Handler) notifyFirstAccess(r *http.Request, s3Path, webhookURL string, status int) {
+	// Deliver even when the client went away once the download finished
+	ctx := context.WithoutCancel(r.Context())
+
+	if status >= http.StatusBadRequest || status == http.StatusNotModified {
+		if err := h.shareService.ReleaseFirstAccess(ctx, s3Path); err != nil {
+			h.logger.Warn("failed to release first share access", "path", s3Path, "error", err)
+		}
+		return
+	}
+
+	event := &domain.WebhookEvent{
+		S3Path:     s3Path,
+		AccessedAt: time.Now().UTC(),
+		ClientIP:   clientIP(r),
+	}
+	go func() {
+		if err := h.shareService.NotifyWebhook(ctx, webhookURL, event); err != nil {
+			h.logger.Warn("failed to deliver share webhook", "path", s3Path, "error", err)
+		}
+	}()
+}
+
+// auditAccess records an access to the share of s3Path with the status it
+// was answered with. Failures are logged and never fail the download.
+func (h *Handler) auditAccess(r *http.Request, s3Path string, rec *responseRecorder) {
+	// Record accesses even when the client went away mid-download
+	ctx := context.WithoutCancel(r.Context())
+	err := h.shareService.RecordAccess(ctx, &domain.AuditEntry{
+		S3Path:    s3Path,
+		Timestamp: time.Now().UTC(),
+		ClientIP:  clientIP(r),
+		UserAgent: r.UserAgent(),
+		Status:    rec.status,
+	})
+	if err != nil {
+		h.logger.Warn("failed to record share access", "path", s3Path, "error", err)
+	}
+}
+
+// HandleShortLink serves the share a /s/{code} short link resolves to,
+// without the object path ever appearing in the URL
+func (h *Handler) HandleShortLink(w http.ResponseWriter, r *http.Request) {
+	if !slices.Contains(shareLinkMethods, r.Method) {
+		h.writeMethodNotAllowed(w, shareLinkMethods...)
+		return
+	}
+	code := strings.TrimPrefix(r.URL.Path, "/s/")
+	r = withResumedDownload(withShareRecords(r))
+
+	link, err := h.shareService.ResolveShortCode(r.Context(), code, clientIP(r))
 	if err != nil {
-		h.writeError(w, "invalid date format", http.StatusBadRequest)
-		h.logger.Error("invalid date", "date", dateStr, "error", err)
+		switch err {
+		case domain.ErrNotFound:
+			http.NotFound(w, r)
+		case domain.ErrExpired:
+			validationFailuresTotal.WithLabelValues("expired").Inc()
+			h.writeErrorCode(w, CodeExpired, "link expired", http.StatusForbidden)
+		default:
+			h.writeError(w, "internal error", http.StatusInternalServerError)
+			h.logger.Error("failed to resolve short code", "error", err)
+		}
 		return
 	}
 
-	// Check if expired
-	if time.Now().After(expiresAt) {
-		h.writeError(w, "link expired", http.StatusForbidden)
-		h.logger.Info("expired link accessed", "date", dateStr, "age", time.Since(expiresAt))
+	// Audit every access to the share, whether it is served or denied
+	rec := newResponseRecorder(w)
+	defer h.auditAccess(r, link.S3Path, rec)
+	w = rec
+
+	if !h.validateShareLink(w, r, link.ExpiresAt, link.Secret, link.S3Path) {
 		return
 	}
 
-	// Validate share
-	err = h.shareService.ValidateShare(ctx, s3Path, secret)
+	webhookURL := h.claimFirstAccess(r, link.S3Path)
+	if service.IsPrefixShare(link.S3Path) {
+		h.serveArchive(w, r, link.S3Path)
+	} else {
+		h.serveShare(w, r, link.S3Path, link.S3Path)
+	}
+	if webhookURL != "" {
+		h.notifyFirstAccess(r, link.S3Path, webhookURL, rec.status)
+	}
+}
+
+// serveShare serves the object at s3Path of the validated share of sharePath,
+// which is s3Path itself or a prefix share containing it, redirecting,
+// answering conditional and HEAD requests, or streaming the body
+func (h *Handler) serveShare(w http.ResponseWriter, r *http.Request, sharePath, s3Path string) {
+	ctx := r.Context()
+
+	// Redirect shares hand the client a presigned storage URL instead of
+	// proxying; prefix shares are never redirected
+	presignedURL, err := h.shareService.PresignShare(ctx, sharePath)
 	if err != nil {
 		switch err {
 		case domain.ErrUnauthorized:
 			h.writeError(w, "unauthorized", http.StatusUnauthorized)
-		case domain.ErrInvalidPath:
-			h.writeError(w, "invalid path", http.StatusBadRequest)
+		case domain.ErrExpired:
+			h.writeErrorCode(w, CodeExpired, "link expired", http.StatusForbidden)
 		default:
 			h.writeError(w, "internal error", http.StatusInternalServerError)
-			h.logger.Error("share validation failed", "error", err)
+			h.logger.Error("failed to presign share", "path", s3Path, "error", err)
+		}
+		return
+	}
+	if presignedURL != "" {
+		if r.Method != http.MethodHead && !h.recordDownload(w, r, sharePath) {
+			return
+		}
+		// The presigned URL is short-lived and must not be cached
+		w.Header().Set("Cache-Control", "no-store")
+		http.Redirect(w, r, presignedURL, http.StatusFound)
+		return
+	}
+
+	// Load the caching rules before recording the download, which deletes
+	// shares on their last permitted download
+	policy, ok := h.cachePolicy(w, r, sharePath)
+	if !ok {
+		return
+	}
+
+	// Resize images when the link asks for a size; other objects ignore it
+	opts, err := parseResizeOptions(r.URL.Query())
+	if err != nil {
+		h.writeError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if opts != nil && h.serveResized(w, r, sharePath, s3Path, *opts, policy) {
+		return
+	}
+
+	w.Header().Set("Accept-Ranges", "bytes")
+
+	// Answer conditional requests from metadata without fetching the body
+	ifNoneMatch := r.Header.Get("If-None-Match")
+	var ifModifiedSince string
+	if r.Method == http.MethodGet || r.Method == http.MethodHead {
+		ifModifiedSince = r.Header.Get("If-Modified-Since")
+	}
+	if ifNoneMatch != "" || ifModifiedSince != "" {
+		metadata, err := h.shareService.HeadObject(ctx, s3Path)
+		if err != nil {
+			h.writeObjectError(w, "failed to head object", s3Path, err)
+			return
+		}
+
+		if notModified(ifNoneMatch, ifModifiedSince, metadata) {
+			setETag(w, metadata.ETag)
+			setLastModified(w, metadata.LastModified)
+			h.setCacheControl(w, metadata.ContentType, policy)
+			w.WriteHeader(http.StatusNotModified)
+			return
 		}
+	}
+
+	// Answer HEAD requests from metadata without fetching the body
+	if r.Method == http.MethodHead {
+		metadata, err := h.shareService.HeadObject(ctx, s3Path)
+		if err != nil {
+			h.writeObjectError(w, "failed to head object", s3Path, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", metadata.ContentType)
+		setContentLength(w, metadata.Size)
+		h.setCacheControl(w, metadata.ContentType, policy)
+		setETag(w, metadata.ETag)
+		setLastModified(w, metadata.LastModified)
+		setChecksum(w, metadata.Checksum)
+		setContentDisposition(w, r, s3Path)
+		w.WriteHeader(http.StatusOK)
 		return
 	}
 
+	release, ok := h.acquireStream(w, r)
+	if !ok {
+		return
+	}
+	defer release()
+
+	// Serve a partial response when a single byte range is requested
+	if rangeHeader := r.Header.Get("Range"); rangeHeader != "" {
+		if h.serveRange(w, r, sharePath, s3Path, rangeHeader, policy) {
+			return
+		}
+	}
+
 	// Get object from storage
 	reader, err := h.shareService.GetObject(ctx, s3Path)
 	if err != nil {
-		h.writeError(w, "not found", http.StatusNotFound)
-		h.logger.Error("failed to get object", "path", s3Path, "error", err)
+		h.writeObjectError(w, "failed to get object", s3Path, err)
 		return
 	}
 	defer reader.Close()
 
+	// Count the download against the share's limit once the object is open,
+	// so that storage failures cost no download
+	if !h.recordObjectDownload(w, r, sharePath, reader.ETag(), false) {
+		return
+	}
+
 	// Set response headers
 	w.Header().Set("Content-Type", reader.ContentType())
-	w.Header().Set("Content-Length", strconv.FormatInt(reader.Size(), 10))
-	w.Header().Set("Cache-Control", "public, max-age=3600")
+	setContentLength(w, reader.Size())
+	h.setCacheControl(w, reader.ContentType(), policy)
+	setETag(w, reader.ETag())
+	setLastModified(w, reader.LastModified())
+	setChecksum(w, reader.Checksum())
+	setContentDisposition(w, r, s3Path)
 	w.WriteHeader(http.StatusOK)
 
-	// Stream the object
-	_, err = io.Copy(w, reader)
-	if err != nil {
-		h.logger.Error("failed to stream object", "path", s3Path, "error", err)
+	// Stream the object, stopping when the client goes away
+	defer h.streams.begin(downloadFull)()
+	if err := h.copyStream(ctx, w, reader, newDownloadLimiter(h.downloadBytesPerSec), downloadFull); err != nil {
+		h.logStreamError(ctx, "failed to stream object", err, "path", s3Path)
 	}
 }
 
-// HandleCreateShare handles share creation requests
-func (h *Handler) HandleCreateShare(w http.ResponseWriter, r *http.Request) {
+// serveResized serves the image at s3Path of the validated share of
+// sharePath resized as opts asks, answering conditional and HEAD requests
+// from the resized image. It returns false without writing a response when
+// the object is not a resizable image, leaving it to be served unchanged.
+func (h *Handler) serveResized(w http.ResponseWriter, r *http.Request, sharePath, s3Path string, opts domain.ResizeOptions, policy *domain.CachePolicy) bool {
 	ctx := r.Context()
 
-	if r.Method != http.MethodPost {
-		h.writeError(w, "method not allowed", http.StatusMethodNotAllowed)
-		return
+	// Resizing decodes the whole image, so it takes a stream slot like the
+	// download itself; the slot is released before unresizable objects are
+	// served unchanged
+	release, ok := h.acquireStream(w, r)
+	if !ok {
+		return true
 	}
+	defer release()
 
-	var req CreateShareRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		h.writeError(w, "invalid request body", http.StatusBadRequest)
-		return
+	reader, err := h.shareService.ResizeImage(ctx, s3Path, opts)
+	if err != nil {
+		switch {
+		case errors.Is(err, domain.ErrNotResizable):
+			return false
+		case errors.Is(err, domain.ErrImageTooLarge):
+			h.writeErrorCode(w, CodeImageTooLarge, "image too large to resize", http.StatusUnprocessableEntity)
+		default:
+			h.writeObjectError(w, "failed to resize image", s3Path, err)
+		}
+		return true
 	}
+	defer reader.Close()
 
-	// Validate request
-	if req.S3Path == "" {
-		h.writeError(w, "s3_path is required", http.StatusBadRequest)
-		return
+	var ifModifiedSince string
+	if r.Method == http.MethodGet || r.Method == http.MethodHead {
+		ifModifiedSince = r.Header.Get("If-Modified-Since")
+	}
+	if ifNoneMatch := r.Header.Get("If-None-Match"); ifNoneMatch != "" || ifModifiedSince != "" {
+		metadata := &domain.ObjectMetadata{ETag: reader.ETag(), LastModified: reader.LastModified()}
+		if notModified(ifNoneMatch, ifModifiedSince, metadata) {
+			setETag(w, reader.ETag())
+			setLastModified(w, reader.LastModified())
+			h.setCacheControl(w, reader.ContentType(), policy)
+			w.WriteHeader(http.StatusNotModified)
+			return true
+		}
 	}
 
-	if req.Secret == "" {
-		h.writeError(w, "secret is required", http.StatusBadRequest)
-		return
+	// Count the download against the share's limit before streaming
+	if r.Method != http.MethodHead && !h.recordDownload(w, r, sharePath) {
+		return true
 	}
 
-	// Set default expiration if not provided
-	expiresAt := req.ExpiresAt
-	if expiresAt.IsZero() {
-		expiresAt = time.Now().Add(24 * time.Hour)
+	w.Header().Set("Content-Type", reader.ContentType())
+	setContentLength(w, reader.Size())
+	h.setCacheControl(w, reader.ContentType(), policy)
+	setETag(w, reader.ETag())
+	setLastModified(w, reader.LastModified())
+	setContentDisposition(w, r, s3Path)
+	w.WriteHeader(http.StatusOK)
+	if r.Method == http.MethodHead {
+		return true
+	}
+
+	defer h.streams.begin(downloadFull)()
+	if err := h.copyStream(ctx, w, reader, newDownloadLimiter(h.downloadBytesPerSec), downloadFull); err != nil {
+		h.logStreamError(ctx, "failed to stream resized image", err, "path", s3Path)
+	}
+	return true
+}
+
+// parseResizeOptions parses the w and h query parameters, positive sizes in
+// pixels, and the fit parameter of a resized download. It returns nil when
+// neither size is set.
+func parseResizeOptions(query url.Values) (*domain.ResizeOptions, error) {
+	if !query.Has("w") && !query.Has("h") {
+		return nil, nil
+	}
+
+	var opts domain.ResizeOptions
+	for _, param := range []struct {
+		name  string
+		value *int
+	}{{"w", &opts.Width}, {"h", &opts.Height}} {
+		if !query.Has(param.name) {
+			continue
+		}
+		size, err := strconv.Atoi(query.Get(param.name))
+		if err != nil || size <= 0 {
+			return nil, fmt.Errorf("%s must be a positive number of pixels", param.name)
+		}
+		*param.value = size
+	}
+
+	switch fit := domain.ResizeFit(query.Get("fit")); fit {
+	case "", domain.ResizeFitContain, domain.ResizeFitCover, domain.ResizeFitFill:
+		opts.Fit = fit
+	default:
+		return nil, errors.New("fit must be contain, cover or fill")
+	}
+	return &opts, nil
+}
+
+// splitShareLink splits the segments of a share link after its date into the
+// secret and the shared path, appending suffix to the path. Public share links
+// carry service.PublicLinkSegment in place of the secret. It writes the error
+// response and returns false when the link is malformed.
+func (h *Handler) splitShareLink(w http.ResponseWriter, r *http.Request, segments []string, suffix string) (secret, s3Path string, ok bool) {
+	if len(segments) < 2 {
+		http.NotFound(w, r)
+		return "", "", false
 	}
 
-	// Create share
-	shareReq := &domain.ShareRequest{
-		S3Path:    req.S3Path,
-		Secret:    req.Secret,
-		ExpiresAt: expiresAt,
+	secret = segments[0]
+	if secret == service.PublicLinkSegment {
+		secret = ""
 	}
+	return secret, strings.Join(segments[1:], "/") + suffix, true
+}
+
+// parseLinkExpiry parses the expiry leading the segments of a share link and
+// returns it with the remaining segments. Links carry the expiry as unix
+// seconds; links issued before that carry a yy/mm/dd date and expire at the
+// start of that day. It writes the error response and returns false when the
+// segments are not a share link.
+func (h *Handler) parseLinkExpiry(w http.ResponseWriter, r *http.Request, parts []string) (time.Time, []string, bool) {
+	// Legacy links: /yy/mm/dd/...
+	if len(parts) > 3 && len(parts[0]) == 2 {
+		dateStr := strings.Join(parts[0:3], "-") // e.g. "25-09-13"
+		expiresAt, err := h.parseDate(dateStr)
+		if err != nil {
+			validationFailuresTotal.WithLabelValues("invalid_date").Inc()
+			h.writeErrorCode(w, CodeInvalidDate, "invalid date format", http.StatusBadRequest)
+			h.logger.Error("invalid date", "date", dateStr, "error", err)
+			return time.Time{}, nil, false
+		}
+		return expiresAt, parts[3:], true
+	}
+
+	seconds, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil || len(parts) < 2 {
+		http.NotFound(w, r)
+		return time.Time{}, nil, false
+	}
+	return time.Unix(seconds, 0).UTC(), parts[1:], true
+}
 
-	resp, err := h.shareService.CreateShare(ctx, shareReq)
+// isExtended reports whether the share of s3Path was extended past the link
+// expiry expiresAt, treating lookup failures as not extended
+func (h *Handler) isExtended(r *http.Request, s3Path string, expiresAt time.Time) bool {
+	extended, err := h.shareService.IsExtended(r.Context(), s3Path, expiresAt)
 	if err != nil {
-		h.writeError(w, "failed to create share", http.StatusInternalServerError)
-		h.logger.Error("failed to create share", "error", err)
-		return
+		h.logger.Error("failed to check share extension", "path", s3Path, "error", err)
+		return false
 	}
+	return extended
+}
 
-	// Return response
-	response := CreateShareResponse{
-		URL:       resp.URL,
-		ExpiresAt: resp.ExpiresAt,
-		MaxAge:    int(resp.MaxAge.Seconds()),
+// validateShareLink checks the URL expiry and secret of a share link for
+// s3Path, writing the error response and returning false when the link is not
+// valid
+func (h *Handler) validateShareLink(w http.ResponseWriter, r *http.Request, expiresAt time.Time, secret, s3Path string) bool {
+	// Check if expired; links of extended shares stay valid until the new
+	// expiry
+	if time.Now().After(expiresAt) && !h.isExtended(r, s3Path, expiresAt) {
+		validationFailuresTotal.WithLabelValues("expired").Inc()
+		writeExpiredResponse(w, "link expired", expiresAt)
+		h.logger.Info("expired link accessed", "expires_at", expiresAt, "age", time.Since(expiresAt))
+		return false
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response)
+	// Validate share
+	err := h.shareService.ValidateShare(r.Context(), s3Path, secret, expiresAt)
+	if err != nil {
+		switch err {
+		case domain.ErrInvalidSignature:
+			validationFailuresTotal.WithLabelValues("invalid_signature").Inc()
+			h.writeErrorCode(w, CodeInvalidSignature, "link has been tampered with", http.StatusForbidden)
+			h.logger.Warn("share link signature mismatch", "path", s3Path, "expires_at", expiresAt)
+		case domain.ErrUnauthorized:
+			validationFailuresTotal.WithLabelValues("unauthorized").Inc()
+			h.writeError(w, "unauthorized", http.StatusUnauthorized)
+		case domain.ErrConsumed:
+			validationFailuresTotal.WithLabelValues("consumed").Inc()
+			h.writeErrorCode(w, CodeConsumed, "share consumed", http.StatusGone)
+		case domain.ErrInvalidPath:
+			validationFailuresTotal.WithLabelValues("invalid_path").Inc()
+			h.writeErrorCode(w, CodeInvalidPath, "invalid path", http.StatusBadRequest)
+		default:
+			h.writeError(w, "internal error", http.StatusInternalServerError)
+			h.logger.Error("share validation failed", "error", err)
+		}
+		return false
+	}
+
+	if !h.validateShareRestrictions(w, r, s3Path) {
+		return false
+	}
+
+	// Password-protected shares also need the password, sent in a header or
+	// a POSTed form field so that it stays out of URLs
+	password, ok := h.sharePassword(w, r)
+	if !ok {
+		return false
+	}
+	err = h.shareService.ValidatePassword(r.Context(), s3Path, password)
+	if err != nil {
+		switch err {
+		case domain.ErrPasswordRequired:
+			w.Header().Set("WWW-Authenticate", `SharePassword realm="share"`)
+			h.writeErrorCode(w, CodePasswordRequired, "password required", http.StatusUnauthorized)
+		case domain.ErrUnauthorized:
+			validationFailuresTotal.WithLabelValues("password").Inc()
+			h.writeErrorCode(w, CodeInvalidPassword, "invalid password", http.StatusUnauthorized)
+		default:
+			h.writeError(w, "internal error", http.StatusInternalServerError)
+			h.logger.Error("password validation failed", "error", err)
+		}
+		return false
+	}
+
+	return true
 }
 
-// parseDate parses a date string in YY-MM-DD format
-func (h *Handler) parseDate(dateStr string) (time.Time, error) {
-	return time.Parse("06-01-02", dateStr)
+// sharePassword returns the share password of r from its X-Share-Password
+// header or the password field of a POSTed urlencoded form, reading at most
+// maxRequestBytes of the form. It writes the error response and returns false
+// when the form is too large, invalid or of another media type.
+func (h *Handler) sharePassword(w http.ResponseWriter, r *http.Request) (string, bool) {
+	if password := r.Header.Get(sharePasswordHeader); password != "" {
+		return password, true
+	}
+	if r.Method != http.MethodPost || r.Header.Get("Content-Type") == "" {
+		return "", true
+	}
+
+	// Multipart forms would spill to temporary files
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType != "application/x-www-form-urlencoded" {
+		h.writeError(w, "password forms must be application/x-www-form-urlencoded", http.StatusUnsupportedMediaType)
+		return "", false
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, h.maxRequestBytes)
+	if err := r.ParseForm(); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			h.writeError(w, fmt.Sprintf("request body must be at most %d bytes", tooLarge.Limit), http.StatusRequestEntityTooLarge)
+			return "", false
+		}
+		h.writeError(w, "invalid password form", http.StatusBadRequest)
+		return "", false
+	}
+	return r.PostForm.Get("password"), true
 }
 
-// writeError writes an error response
-func (h *Handler) writeError(w http.ResponseWriter, message string, statusCode int) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(statusCode)
+// validateShareRestrictions checks the referer and client IP of a request
+// for the share of s3Path against the share's restrictions, writing the error
+// response and returning false when the request is not allowed
+func (h *Handler) validateShareRestrictions(w http.ResponseWriter, r *http.Request, s3Path string) bool {
+	// Shares restricted to referers reject hotlinks from other sites
+	err := h.shareService.ValidateReferer(r.Context(), s3Path, r.Header.Get("Referer"))
+	if err != nil {
+		switch err {
+		case domain.ErrRefererNotAllowed:
+			validationFailuresTotal.WithLabelValues("referer").Inc()
+			h.writeErrorCode(w, CodeRefererNotAllowed, "referer not allowed", http.StatusForbidden)
+		case domain.ErrUnauthorized:
+			h.writeError(w, "unauthorized", http.StatusUnauthorized)
+		default:
+			h.writeError(w, "internal error", http.StatusInternalServerError)
+			h.logger.Error("referer validation failed", "error", err)
+		}
+		return false
+	}
 
-	errorResp := ErrorResponse{
-		Error:   message,
-		Code:    statusCode,
-		Message: message,
+	// Shares restricted to IP ranges reject clients outside them
+	err = h.shareService.ValidateClientIP(r.Context(), s3Path, clientIP(r))
+	if err != nil {
+		switch err {
+		case domain.ErrIPNotAllowed:
+			validationFailuresTotal.WithLabelValues("ip").Inc()
+			h.writeErrorCode(w, CodeIPNotAllowed, "client IP not allowed", http.StatusForbidden)
+		case domain.ErrUnauthorized:
+			h.writeError(w, "unauthorized", http.StatusUnauthorized)
+		default:
+			h.writeError(w, "internal error", http.StatusInternalServerError)
+			h.logger.Error("client IP validation failed", "error", err)
+		}
+		return false
 	}
 
-	json.NewEncoder(w).Encode(errorResp)
+	return true
 }
 
-// CreateShareRequest represents a request to create a share
-type CreateShareRequest struct {
-	S3Path    string    `json:"s3_path"`
-	Secret    string    `json:"secret"`
-	ExpiresAt time.Time `json:"expires_at,omitempty"`
+// recordDownload counts a download against the share's limit, writing the
+// error response and returning false when the download must not proceed
+func (h *Handler) recordDownload(w http.ResponseWriter, r *http.Request, s3Path string) bool {
+	return h.checkRecordedDownload(w, s3Path, h.shareService.RecordDownload(r.Context(), s3Path))
 }
 
-// CreateShareResponse represents a response after creating a share
-type CreateShareResponse struct {
-	URL       string    `json:"url"`
-	ExpiresAt time.Time `json:"expires_at"`
-	MaxAge    int       `json:"max_age_seconds"`
+// recordObjectDownload counts a download of the object with entity tag etag
+// from the share of s3Path like recordDownload. Downloads of objects with a
+// strong entity tag are remembered per client, so that resumed downloads
+// continuing a download already counted are not counted again.
+func (h *Handler) recordObjectDownload(w http.ResponseWriter, r *http.Request, s3Path, etag string, resumed bool) bool {
+	if etag == "" || strings.HasPrefix(etag, "W/") {
+		return h.recordDownload(w, r, s3Path)
+	}
+	err := h.shareService.RecordResumableDownload(r.Context(), s3Path, downloadID(r, etag), resumed)
+	return h.checkRecordedDownload(w, s3Path, err)
 }
 
-// ErrorResponse represents an error response
-type ErrorResponse struct {
-	Error   string `json:"error"`
-	Code    int    `json:"code"`
-	Message string `json:"message"`
+// checkRecordedDownload writes the error response of a download of the share
+// of s3Path that could not be recorded, returning false when err is set
+func (h *Handler) checkRecordedDownload(w http.ResponseWriter, s3Path string, err error) bool {
+	if err != nil {
+		switch err {
+		case domain.ErrUnauthorized:
+			h.writeError(w, "unauthorized", http.StatusUnauthorized)
+		case domain.ErrConsumed:
+			validationFailuresTotal.WithLabelValues("download_limit").Inc()
+			h.writeErrorCode(w, CodeConsumed, "share consumed", http.StatusGone)
+		default:
+			h.writeError(w, "internal error", http.StatusInternalServerError)
+			h.logger.Error("failed to record download", "path", s3Path, "error", err)
+		}
+		return false
+	}
+	return true
+}
+
+// serveRange writes a 206 or 416 response for a Range request of the object
+// at s3Path of the share of sharePath, counting the download unless it
+// resumes one already counted. It returns false when the full object should
+// be served instead (multi-range requests, objects of unknown size, or an
+// If-Range validator the object no longer matches).
+func (h *Handler) serveRange(w http.ResponseWriter, r *http.Request, sharePath, s3Path, rangeHeader string, policy *domain.CachePolicy) bool {
+	ctx := r.Context()
+
+	metadata, err := h.shareService.HeadObject(ctx, s3Path)
+	if err != nil {
+		h.writeObjectError(w, "failed to head object", s3Path, err)
+		return true
+	}
+	if ifRange := r.Header.Get("If-Range"); ifRange != "" && !ifRangeMatches(ifRange, metadata) {
+		return false
+	}
+	// Ranges of an object of unknown size cannot be resolved
+	if metadata.Size < 0 {
+		return false
+	}
+
+	start, end, err := parseByteRange(rangeHeader, metadata.Size)
+	if err == errMultipleRanges {
+		return false
+	}
+	if err != nil {
+		w.Header().Set("Content-Range", fmt.Sprintf("bytes */%d", metadata.Size))
+		h.writeError(w, "range not satisfiable", http.StatusRequestedRangeNotSatisfiable)
+		return true
+	}
+
+	reader, err := h.shareService.GetObjectRange(ctx, s3Path, start, end)
+	if err != nil {
+		h.writeObjectError(w, "failed to get object range", s3Path, err)
+		return true
+	}
+	defer reader.Close()
+
+	// A range past the start under a matching If-Range resumes a download
+	resumed := start > 0 && r.Header.Get("If-Range") != ""
+	if !h.recordObjectDownload(w, r, sharePath, metadata.ETag, resumed) {
+		return true
+	}
+
+	// Set response headers
+	w.Header().Set("Content-Type", reader.ContentType())
+	w.Header().Set("Content-Length", strconv.FormatInt(end-start+1, 10))
+	w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, end, metadata.Size))
+	h.setCacheControl(w, reader.ContentType(), policy)
+	setETag(w, metadata.ETag)
+	setLastModified(w, metadata.LastModified)
+	setContentDisposition(w, r, s3Path)
+	w.WriteHeader(http.StatusPartialContent)
+
+	// Stream the range, stopping when the client goes away
+	defer h.streams.begin(downloadRange)()
+	if err := h.copyStream(ctx, w, reader, newDownloadLimiter(h.downloadBytesPerSec), downloadRange); err != nil {
+		h.logStreamError(ctx, "failed to stream object range", err, "path", s3Path)
+	}
+	return true
+}
+
+// HandleShares dispatches /api/shares requests by method
+func (h *Handler) HandleShares(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		h.HandleShareInfo(w, r)
+	case http.MethodHead:
+		h.HandleShareExists(w, r)
+	case http.MethodPost:
+		h.HandleCreateShare(w, r)
+	case http.MethodPatch:
+		h.HandleExtendShare(w, r)
+	case http.MethodDelete:
+		h.HandleRevokeShare(w, r)
+	default:
+		h.writeMethodNotAllowed(w, sharesMethods...)
+	}
+}
+
+// HandleCreateShare handles share creation requests
+func (h *Handler) HandleCreateShare(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	if r.Method != http.MethodPost {
+		h.writeMethodNotAllowed(w, http.MethodPost)
+		return
+	}
+
+	body, shareReq, ok := h.decodeShareRequest(w, r)
+	if !ok {
+		return
+	}
+
+	idempotencyKey := service.IdempotencyKey{
+		Caller:      callerFromContext(ctx),
+		Key:         r.Header.Get(idempotencyKeyHeader),
+		RequestHash: shareRequestHash(body),
+	}
+	if len(idempotencyKey.Key) > maxIdempotencyKeyLength {
+		h.writeError(w, fmt.Sprintf("%s must be at most %d characters", idempotencyKeyHeader, maxIdempotencyKeyLength), http.StatusBadRequest)
+		return
+	}
+
+	resp, replayed, err := h.shareService.CreateShareIdempotent(ctx, idempotencyKey, shareReq)
+	if err != nil {
+		switch {
+		case errors.Is(err, domain.ErrIdempotencyInProgress):
+			h.writeError(w, "a request with this idempotency key is in progress", http.StatusConflict)
+		case errors.Is(err, domain.ErrIdempotencyKeyReused):
+			h.writeError(w, "idempotency key was used for a different request", http.StatusUnprocessableEntity)
+		default:
+			h.writeShareError(w, "failed to create share", err)
+		}
+		return
+	}
+
+	if replayed {
+		w.Header().Set(idempotentReplayedHeader, "true")
+	} else {
+		sharesCreatedTotal.Inc()
+	}
+
+	// Return response
+	response := CreateShareResponse{
+		URL:       resp.URL,
+		ExpiresAt: resp.ExpiresAt,
+		MaxAge:    int(resp.MaxAge.Seconds()),
+	}
+	if verbose, _ := strconv.ParseBool(r.URL.Query().Get("verbose")); verbose {
+		response.Secret = resp.Secret
+		response.Curl = curlExample(resp.URL, shareReq)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// curlExample returns a curl command downloading the share of req from
+// shareURL, sending the password of protected shares from $SHARE_PASSWORD
+func curlExample(shareURL string, req *domain.ShareRequest) string {
+	filename := path.Base(req.S3Path)
+	if service.IsPrefixShare(req.S3Path) {
+		filename += ".zip"
+	}
+
+	command := "curl -fL"
+	if req.Password != "" {
+		command += ` -H "` + sharePasswordHeader + `: $SHARE_PASSWORD"`
+	}
+	return command + " -o " + shellQuote(filename) + " " + shellQuote(shareURL)
+}
+
+// shellQuote quotes s as a single POSIX shell word
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// HandleCreateShareBatch handles POST /api/shares/batch, creating every share
+// of a JSON array. Items fail independently: the response is always 207
+// Multi-Status with one result per item, in request order.
+func (h *Handler) HandleCreateShareBatch(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		h.writeMethodNotAllowed(w, shareBatchMethods...)
+		return
+	}
+
+	var reqs []CreateShareRequest
+	if !h.decodeBody(w, r, &reqs) {
+		return
+	}
+	if len(reqs) == 0 {
+		h.writeError(w, "at least one share is required", http.StatusBadRequest)
+		return
+	}
+	if len(reqs) > h.maxBatchSize {
+		h.writeError(w, fmt.Sprintf("batch must contain at most %d shares", h.maxBatchSize), http.StatusRequestEntityTooLarge)
+		return
+	}
+	if !consumeBatchQuota(w, r, len(reqs)) {
+		return
+	}
+
+	// Create the valid items, remembering where each result belongs
+	results := make([]BatchShareResult, len(reqs))
+	shareReqs := make([]*domain.ShareRequest, 0, len(reqs))
+	indexes := make([]int, 0, len(reqs))
+	for i := range reqs {
+		shareReq, err := parseShareRequest(&reqs[i])
+		if err != nil {
+			results[i] = BatchShareResult{Status: http.StatusBadRequest, Code: CodeBadRequest, Error: err.Error()}
+			continue
+		}
+		shareReqs = append(shareReqs, shareReq)
+		indexes = append(indexes, i)
+	}
+
+	for j, created := range h.shareService.CreateShares(r.Context(), shareReqs) {
+		i := indexes[j]
+		if created.Err != nil {
+			code, message, statusCode := h.shareErrorStatus("failed to create share", created.Err)
+			results[i] = BatchShareResult{Status: statusCode, Code: code, Error: message}
+			continue
+		}
+
+		sharesCreatedTotal.Inc()
+		results[i] = BatchShareResult{
+			Status: http.StatusOK,
+			CreateShareResponse: &CreateShareResponse{
+				URL:       created.Response.URL,
+				ExpiresAt: created.Response.ExpiresAt,
+				MaxAge:    int(created.Response.MaxAge.Seconds()),
+			},
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusMultiStatus)
+	json.NewEncoder(w).Encode(results)
+}
+
+// HandleUpload handles POST /api/uploads, creating a share for an object the
+// client has yet to upload through the returned presigned PUT URL
+func (h *Handler) HandleUpload(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	if r.Method != http.MethodPost {
+		h.writeMethodNotAllowed(w, uploadsMethods...)
+		return
+	}
+
+	_, shareReq, ok := h.decodeShareRequest(w, r)
+	if !ok {
+		return
+	}
+	if shareReq.VersionID != "" {
+		h.writeError(w, "version_id cannot be used with an upload", http.StatusBadRequest)
+		return
+	}
+
+	resp, err := h.shareService.CreateUpload(ctx, shareReq)
+	if err != nil {
+		if errors.Is(err, domain.ErrPresignNotSupported) {
+			h.writeError(w, "uploads are not supported by the storage backend", http.StatusNotImplemented)
+			return
+		}
+		h.writeShareError(w, "failed to create upload", err)
+		return
+	}
+
+	sharesCreatedTotal.Inc()
+
+	response := UploadResponse{
+		UploadURL:       resp.UploadURL,
+		UploadMethod:    http.MethodPut,
+		UploadExpiresAt: resp.UploadExpiresAt,
+		ShareURL:        resp.Share.URL,
+		ExpiresAt:       resp.Share.ExpiresAt,
+		MaxAge:          int(resp.Share.MaxAge.Seconds()),
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// decodeShareRequest decodes and validates a share creation body, returning
+// the body and the share request it describes. It writes the error response
+// and returns false when the body is invalid.
+func (h *Handler) decodeShareRequest(w http.ResponseWriter, r *http.Request) (*CreateShareRequest, *domain.ShareRequest, bool) {
+	var req CreateShareRequest
+	if !h.decodeBody(w, r, &req) {
+		return nil, nil, false
+	}
+
+	shareReq, err := parseShareRequest(&req)
+	if err != nil {
+		h.writeError(w, err.Error(), http.StatusBadRequest)
+		return nil, nil, false
+	}
+	return &req, shareReq, true
+}
+
+// shareRequestHash fingerprints a share creation body for idempotency keys.
+// The body is hashed as decoded, so that retries differing only in
+// formatting match, and before expires_in is resolved, so that retries of a
+// relative expiry match too.
+func shareRequestHash(req *CreateShareRequest) string {
+	encoded, _ := json.Marshal(req)
+	hash := sha256.Sum256(encoded)
+	return hex.EncodeToString(hash[:])
+}
+
+// parseShareRequest validates a share creation body and resolves its expiry.
+// Its errors are messages for the client.
+func parseShareRequest(req *CreateShareRequest) (*domain.ShareRequest, error) {
+	// Validate request
+	if req.S3Path == "" {
+		return nil, errors.New("s3_path is required")
+	}
+
+	if req.Secret == "" && !req.Public {
+		return nil, errors.New("secret is required")
+	}
+	if req.Secret == service.PublicLinkSegment {
+		return nil, fmt.Errorf("secret must not be %q", service.PublicLinkSegment)
+	}
+
+	// Resolve expiration: expires_at wins over expires_in, default is 24h
+	expiresAt := req.ExpiresAt
+	if expiresAt.IsZero() && req.ExpiresIn != "" {
+		expiresIn, err := time.ParseDuration(req.ExpiresIn)
+		if err != nil {
+			return nil, errors.New("expires_in must be a valid duration")
+		}
+		if expiresIn <= 0 {
+			return nil, errors.New("expires_in must be positive")
+		}
+		expiresAt = time.Now().Add(expiresIn)
+	}
+	if expiresAt.IsZero() {
+		expiresAt = time.Now().Add(24 * time.Hour)
+	}
+
+	if req.MaxDownloads < 0 {
+		return nil, errors.New("max_downloads must not be negative")
+	}
+
+	// bcrypt only hashes the first 72 bytes of a password
+	if len(req.Password) > maxPasswordBytes {
+		return nil, fmt.Errorf("password must be at most %d bytes", maxPasswordBytes)
+	}
+
+	mode := domain.ShareMode(req.Mode)
+	if mode != "" && mode != domain.ShareModeProxy && mode != domain.ShareModeRedirect {
+		return nil, errors.New("mode must be proxy or redirect")
+	}
+
+	if req.VersionID != "" && service.IsPrefixShare(req.S3Path) {
+		return nil, errors.New("version_id cannot be used with a prefix share")
+	}
+
+	if req.WebhookURL != "" && !isWebhookURL(req.WebhookURL) {
+		return nil, errors.New("webhook_url must be an absolute http or https URL")
+	}
+
+	for _, pattern := range req.AllowedReferers {
+		if !domain.ValidRefererPattern(pattern) {
+			return nil, fmt.Errorf("allowed_referers entry %q must be a host name, optionally starting with *.", pattern)
+		}
+	}
+
+	for _, pattern := range req.AllowedIPs {
+		if !domain.ValidIPPattern(pattern) {
+			return nil, fmt.Errorf("allowed_ips entry %q must be a CIDR or an IP address", pattern)
+		}
+	}
+
+	return &domain.ShareRequest{
+		S3Path:          req.S3Path,
+		Secret:          req.Secret,
+		ExpiresAt:       expiresAt,
+		MaxDownloads:    req.MaxDownloads,
+		Mode:            mode,
+		Password:        req.Password,
+		Public:          req.Public,
+		Short:           req.Short,
+		VersionID:       req.VersionID,
+		WebhookURL:      req.WebhookURL,
+		AllowedReferers: req.AllowedReferers,
+		AllowedIPs:      req.AllowedIPs,
+	}, nil
+}
+
+// isWebhookURL reports whether rawURL is an absolute http or https URL
+func isWebhookURL(rawURL string) bool {
+	u, err := url.Parse(rawURL)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// writeShareError maps share creation errors to responses, logging
+// unexpected ones with message
+func (h *Handler) writeShareError(w http.ResponseWriter, message string, err error) {
+	code, message, statusCode := h.shareErrorStatus(message, err)
+	h.writeErrorCode(w, code, message, statusCode)
+}
+
+// shareErrorStatus returns the error code, client message and status of a
+// share creation error, logging unexpected ones with message
+func (h *Handler) shareErrorStatus(message string, err error) (string, string, int) {
+	switch {
+	case errors.Is(err, domain.ErrInvalidPath):
+		return CodeInvalidPath, "invalid path", http.StatusBadRequest
+	case errors.Is(err, domain.ErrForbiddenPath):
+		return CodeForbiddenPath, "path is not shareable", http.StatusForbidden
+	case errors.Is(err, domain.ErrInvalidDate):
+		return CodeInvalidDate, "expiration time must be in the future", http.StatusBadRequest
+	case errors.Is(err, domain.ErrMaxAgeExceeded):
+		return CodeMaxAgeExceeded, "expiration exceeds maximum share age", http.StatusBadRequest
+	case errors.Is(err, domain.ErrPresignNotSupported):
+		return CodeNotSupported, "redirect mode is not supported by the storage backend", http.StatusBadRequest
+	case errors.Is(err, domain.ErrNotFound):
+		return CodeNotFound, "object not found", http.StatusNotFound
+	case errors.Is(err, domain.ErrStorageUnavailable):
+		return CodeStorageUnavailable, "storage unavailable", http.StatusServiceUnavailable
+	case errors.Is(err, domain.ErrVersionsNotSupported):
+		return CodeNotSupported, "object versions are not supported by the storage backend", http.StatusBadRequest
+	case errors.Is(err, domain.ErrWebhooksDisabled):
+		return CodeNotImplemented, "webhooks are not enabled", http.StatusNotImplemented
+	default:
+		h.logger.Error(message, "error", err)
+		return CodeInternal, message, http.StatusInternalServerError
+	}
+}
+
+// HandleExtendShare handles PATCH /api/shares, moving the expiry of an
+// existing share without changing its links
+func (h *Handler) HandleExtendShare(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPatch {
+		h.writeMethodNotAllowed(w, http.MethodPatch)
+		return
+	}
+
+	var req ExtendShareRequest
+	if !h.decodeBody(w, r, &req) {
+		return
+	}
+
+	if req.S3Path == "" {
+		h.writeError(w, "s3_path is required", http.StatusBadRequest)
+		return
+	}
+
+	// expires_at wins over expires_in, as on creation
+	expiresAt := req.ExpiresAt
+	if expiresAt.IsZero() && req.ExpiresIn != "" {
+		expiresIn, err := time.ParseDuration(req.ExpiresIn)
+		if err != nil || expiresIn <= 0 {
+			h.writeError(w, "expires_in must be a positive duration", http.StatusBadRequest)
+			return
+		}
+		expiresAt = time.Now().Add(expiresIn)
+	}
+	if expiresAt.IsZero() {
+		h.writeError(w, "expires_at or expires_in is required", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.shareService.ExtendShare(r.Context(), req.S3Path, expiresAt); err != nil {
+		switch {
+		case errors.Is(err, domain.ErrInvalidPath):
+			h.writeErrorCode(w, CodeInvalidPath, "invalid path", http.StatusBadRequest)
+		case errors.Is(err, domain.ErrInvalidDate):
+			h.writeErrorCode(w, CodeInvalidDate, "expiration time must be in the future", http.StatusBadRequest)
+		case errors.Is(err, domain.ErrMaxAgeExceeded):
+			h.writeErrorCode(w, CodeMaxAgeExceeded, "expiration exceeds maximum share age", http.StatusBadRequest)
+		case errors.Is(err, domain.ErrNotFound):
+			h.writeErrorCode(w, CodeNotFound, "share not found", http.StatusNotFound)
+		default:
+			h.writeError(w, "failed to extend share", http.StatusInternalServerError)
+			h.logger.Error("failed to extend share", "error", err)
+		}
+		return
+	}
+
+	response := ShareInfoResponse{
+		S3Path:     req.S3Path,
+		ExpiresAt:  expiresAt,
+		TTLSeconds: int(time.Until(expiresAt).Seconds()),
+		Active:     true,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// HandleRevokeShare handles share revocation requests
+func (h *Handler) HandleRevokeShare(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	if r.Method != http.MethodDelete {
+		h.writeMethodNotAllowed(w, http.MethodDelete)
+		return
+	}
+
+	var req RevokeShareRequest
+	if !h.decodeBody(w, r, &req) {
+		return
+	}
+
+	if req.S3Path == "" {
+		h.writeError(w, "s3_path is required", http.StatusBadRequest)
+		return
+	}
+
+	err := h.shareService.RevokeShare(ctx, req.S3Path)
+	if err != nil {
+		switch {
+		case errors.Is(err, domain.ErrInvalidPath):
+			h.writeErrorCode(w, CodeInvalidPath, "invalid path", http.StatusBadRequest)
+		case errors.Is(err, domain.ErrNotFound):
+			h.writeErrorCode(w, CodeNotFound, "share not found", http.StatusNotFound)
+		default:
+			h.writeError(w, "failed to revoke share", http.StatusInternalServerError)
+			h.logger.Error("failed to revoke share", "error", err)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// HandleObjects dispatches /api/objects requests by method
+func (h *Handler) HandleObjects(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		h.HandleListObjects(w, r)
+	case http.MethodDelete:
+		h.HandleDeleteObject(w, r)
+	default:
+		h.writeMethodNotAllowed(w, objectsMethods...)
+	}
+}
+
+// HandleListObjects handles object listing requests, returning one page of
+// objects under the prefix query parameter
+func (h *Handler) HandleListObjects(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	query := r.URL.Query()
+	objects, next, err := h.shareService.ListObjects(ctx, query.Get("prefix"), query.Get("token"))
+	if err != nil {
+		switch {
+		case errors.Is(err, domain.ErrInvalidPath):
+			h.writeError(w, "invalid prefix", http.StatusBadRequest)
+		case errors.Is(err, domain.ErrStorageUnavailable):
+			h.writeError(w, "storage unavailable", http.StatusServiceUnavailable)
+		default:
+			h.writeError(w, "failed to list objects", http.StatusInternalServerError)
+			h.logger.Error("failed to list objects", "error", err)
+		}
+		return
+	}
+
+	response := ListObjectsResponse{
+		Objects:   make([]ObjectResponse, 0, len(objects)),
+		NextToken: next,
+	}
+	for _, object := range objects {
+		response.Objects = append(response.Objects, ObjectResponse{
+			Key:          object.Key,
+			Size:         object.Size,
+			LastModified: object.LastModified,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// HandleDeleteObject handles object deletion requests, revoking any share of
+// the object before deleting it
+func (h *Handler) HandleDeleteObject(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	if r.Method != http.MethodDelete {
+		h.writeMethodNotAllowed(w, http.MethodDelete)
+		return
+	}
+
+	var req DeleteObjectRequest
+	if !h.decodeBody(w, r, &req) {
+		return
+	}
+
+	if req.S3Path == "" {
+		h.writeError(w, "s3_path is required", http.StatusBadRequest)
+		return
+	}
+
+	err := h.shareService.DeleteObject(ctx, req.S3Path)
+	if err != nil {
+		switch {
+		case errors.Is(err, domain.ErrInvalidPath):
+			h.writeErrorCode(w, CodeInvalidPath, "invalid path", http.StatusBadRequest)
+		case errors.Is(err, domain.ErrNotFound):
+			h.writeErrorCode(w, CodeNotFound, "object not found", http.StatusNotFound)
+		case errors.Is(err, domain.ErrStorageUnavailable):
+			h.writeErrorCode(w, CodeStorageUnavailable, "storage unavailable", http.StatusServiceUnavailable)
+		default:
+			h.writeError(w, "failed to delete object", http.StatusInternalServerError)
+			h.logger.Error("failed to delete object", "error", err)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// HandleShareInfo handles share inspection requests
+func (h *Handler) HandleShareInfo(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	s3Path := r.URL.Query().Get("s3_path")
+	if s3Path == "" {
+		h.writeError(w, "s3_path is required", http.StatusBadRequest)
+		return
+	}
+
+	info, err := h.shareService.GetShareInfo(ctx, s3Path)
+	if err != nil {
+		switch {
+		case errors.Is(err, domain.ErrInvalidPath):
+			h.writeErrorCode(w, CodeInvalidPath, "invalid path", http.StatusBadRequest)
+		case errors.Is(err, domain.ErrNotFound):
+			h.writeErrorCode(w, CodeNotFound, "share not found", http.StatusNotFound)
+		default:
+			h.writeError(w, "failed to get share info", http.StatusInternalServerError)
+			h.logger.Error("failed to get share info", "error", err)
+		}
+		return
+	}
+
+	response := ShareInfoResponse{
+		S3Path:        info.S3Path,
+		ExpiresAt:     info.ExpiresAt,
+		TTLSeconds:    int(info.TTL.Seconds()),
+		Active:        info.Active,
+		DownloadCount: info.DownloadCount,
+	}
+	if !info.LastAccessedAt.IsZero() {
+		response.LastAccessedAt = &info.LastAccessedAt
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// HandleShareExists handles HEAD /api/shares, answering 200 with the expiry
+// in X-Share-Expires-At when an active share exists for the s3_path query
+// parameter and 404 otherwise. Responses never have a body.
+func (h *Handler) HandleShareExists(w http.ResponseWriter, r *http.Request) {
+	s3Path := r.URL.Query().Get("s3_path")
+	if s3Path == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	info, err := h.shareService.GetShareInfo(r.Context(), s3Path)
+	if err != nil {
+		switch {
+		case errors.Is(err, domain.ErrInvalidPath):
+			w.WriteHeader(http.StatusBadRequest)
+		case errors.Is(err, domain.ErrNotFound):
+			w.WriteHeader(http.StatusNotFound)
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+			h.logger.Error("failed to get share info", "error", err)
+		}
+		return
+	}
+	if !info.Active {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	if !info.ExpiresAt.IsZero() {
+		w.Header().Set(shareExpiresAtHeader, info.ExpiresAt.UTC().Format(time.RFC3339))
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// HandleShareAudit handles GET /api/shares/audit, returning the most recent
+// accesses to the share links of the s3_path query parameter, newest first
+func (h *Handler) HandleShareAudit(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		h.writeMethodNotAllowed(w, shareAuditMethods...)
+		return
+	}
+
+	query := r.URL.Query()
+	s3Path := query.Get("s3_path")
+	if s3Path == "" {
+		h.writeError(w, "s3_path is required", http.StatusBadRequest)
+		return
+	}
+
+	limit := defaultAuditLimit
+	if value := query.Get("limit"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 || n > maxAuditLimit {
+			h.writeError(w, fmt.Sprintf("limit must be between 1 and %d", maxAuditLimit), http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+
+	entries, err := h.shareService.AuditLog(r.Context(), s3Path, limit)
+	if err != nil {
+		switch {
+		case errors.Is(err, domain.ErrAuditDisabled):
+			h.writeError(w, "audit logging is not enabled", http.StatusNotImplemented)
+		case errors.Is(err, domain.ErrInvalidPath):
+			h.writeErrorCode(w, CodeInvalidPath, "invalid path", http.StatusBadRequest)
+		default:
+			h.writeError(w, "failed to read audit log", http.StatusInternalServerError)
+			h.logger.Error("failed to read audit log", "error", err)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(AuditResponse{S3Path: s3Path, Entries: entries})
+}
+
+// parseDate parses a legacy link date string in YY-MM-DD format
+func (h *Handler) parseDate(dateStr string) (time.Time, error) {
+	return time.Parse("06-01-02", dateStr)
+}
+
+// parseByteRange parses a single "bytes=" Range header against the object size
+// and returns the inclusive start and end offsets
+func parseByteRange(header string, size int64) (int64, int64, error) {
+	spec, ok := strings.CutPrefix(header, "bytes=")
+	if !ok {
+		return 0, 0, errInvalidRange
+	}
+	if strings.Contains(spec, ",") {
+		return 0, 0, errMultipleRanges
+	}
+
+	startStr, endStr, ok := strings.Cut(strings.TrimSpace(spec), "-")
+	if !ok {
+		return 0, 0, errInvalidRange
+	}
+
+	// Suffix range: last N bytes
+	if startStr == "" {
+		n, err := strconv.ParseInt(endStr, 10, 64)
+		if err != nil || n <= 0 || size == 0 {
+			return 0, 0, errInvalidRange
+		}
+		if n > size {
+			n = size
+		}
+		return size - n, size - 1, nil
+	}
+
+	start, err := strconv.ParseInt(startStr, 10, 64)
+	if err != nil || start < 0 || start >= size {
+		return 0, 0, errInvalidRange
+	}
+
+	end := size - 1
+	if endStr != "" {
+		end, err = strconv.ParseInt(endStr, 10, 64)
+		if err != nil || end < start {
+			return 0, 0, errInvalidRange
+		}
+		if end >= size {
+			end = size - 1
+		}
+	}
+
+	return start, end, nil
+}
+
+// etagMatches reports whether an If-None-Match header matches the given ETag
+// using weak comparison
+func etagMatches(header, etag string) bool {
+	if etag == "" {
+		return false
+	}
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+	return false
+}
+
+// ifRangeMatches reports whether the If-Range validator of a range request
+// still identifies the object. Entity tags compare strongly, so weak tags
+// never match; dates must equal the modification time to the second.
+func ifRangeMatches(ifRange string, metadata *domain.ObjectMetadata) bool {
+	if strings.HasPrefix(ifRange, "W/") || strings.HasPrefix(ifRange, `"`) {
+		return metadata.ETag != "" && !strings.HasPrefix(metadata.ETag, "W/") && ifRange == metadata.ETag
+	}
+	if metadata.LastModified.IsZero() {
+		return false
+	}
+	date, err := http.ParseTime(ifRange)
+	if err != nil {
+		return false
+	}
+	return date.Equal(metadata.LastModified.Truncate(time.Second))
+}
+
+// notModified reports whether a conditional request may be answered with
+// 304 Not Modified. If-Modified-Since is only evaluated without If-None-Match,
+// and never for objects without a modification time.
+func notModified(ifNoneMatch, ifModifiedSince string, metadata *domain.ObjectMetadata) bool {
+	if ifNoneMatch != "" {
+		return etagMatches(ifNoneMatch, metadata.ETag)
+	}
+	if metadata.LastModified.IsZero() {
+		return false
+	}
+	since, err := http.ParseTime(ifModifiedSince)
+	if err != nil {
+		return false
+	}
+	// HTTP dates have whole-second precision
+	return !metadata.LastModified.Truncate(time.Second).After(since)
+}
+
+// setContentLength sets the Content-Length header when the size is known.
+// Without it the body is sent with chunked transfer encoding.
+func setContentLength(w http.ResponseWriter, size int64) {
+	if size >= 0 {
+		w.Header().Set("Content-Length", strconv.FormatInt(size, 10))
+	}
+}
+
+// setLastModified sets the Last-Modified header when the time is known
+func setLastModified(w http.ResponseWriter, lastModified time.Time) {
+	if !lastModified.IsZero() {
+		w.Header().Set("Last-Modified", lastModified.UTC().Format(http.TimeFormat))
+	}
+}
+
+// setETag sets the ETag response header when one is known
+func setETag(w http.ResponseWriter, etag string) {
+	if etag != "" {
+		w.Header().Set("ETag", etag)
+	}
+}
+
+// setChecksum sets the X-Content-SHA256 header when the object's checksum is
+// known. Partial and resized responses have none, as it covers the whole
+// stored object.
+func setChecksum(w http.ResponseWriter, checksum string) {
+	if checksum != "" {
+		w.Header().Set(contentSHA256Header, checksum)
+	}
+}
+
+// setContentDisposition marks the response as an attachment when the
+// download query parameter is set; inline remains the default
+func setContentDisposition(w http.ResponseWriter, r *http.Request, s3Path string) {
+	values, ok := r.URL.Query()["download"]
+	if !ok {
+		return
+	}
+	if values[0] != "" {
+		if download, err := strconv.ParseBool(values[0]); err != nil || !download {
+			return
+		}
+	}
+
+	w.Header().Set("Content-Disposition", attachmentDisposition(path.Base(s3Path)))
+}
+
+// attachmentDisposition builds an attachment Content-Disposition value with an
+// ASCII fallback filename and an RFC 5987 encoded filename* parameter
+func attachmentDisposition(filename string) string {
+	var fallback, encoded strings.Builder
+	for _, r := range filename {
+		if r < 0x20 || r > 0x7e || r == '"' || r == '\\' {
+			fallback.WriteByte('_')
+		} else {
+			fallback.WriteRune(r)
+		}
+	}
+	for _, b := range []byte(filename) {
+		if isAttrChar(b) {
+			encoded.WriteByte(b)
+		} else {
+			fmt.Fprintf(&encoded, "%%%02X", b)
+		}
+	}
+
+	return fmt.Sprintf(`attachment; filename="%s"; filename*=UTF-8''%s`, fallback.String(), encoded.String())
+}
+
+// isAttrChar reports whether b is an RFC 5987 attr-char
+func isAttrChar(b byte) bool {
+	switch {
+	case b >= 'a' && b <= 'z', b >= 'A' && b <= 'Z', b >= '0' && b <= '9':
+		return true
+	}
+	return strings.IndexByte("!#$&+-.^_`|~", b) >= 0
+}
+
+// writeObjectError writes a 404 for missing objects and a 500 for any other
+// storage failure, logging the latter
+func (h *Handler) writeObjectError(w http.ResponseWriter, msg, s3Path string, err error) {
+	if errors.Is(err, domain.ErrNotFound) {
+		h.writeError(w, "not found", http.StatusNotFound)
+		return
+	}
+	if errors.Is(err, domain.ErrStorageUnavailable) {
+		h.writeError(w, "storage unavailable", http.StatusServiceUnavailable)
+		return
+	}
+
+	h.writeError(w, "internal error", http.StatusInternalServerError)
+	h.logger.Error(msg, "path", s3Path, "error", err)
+}
+
+// decodeBody decodes the JSON body of r into v, reading at most
+// maxRequestBytes. It writes the error response and returns false when the
+// body is too large or invalid.
+func (h *Handler) decodeBody(w http.ResponseWriter, r *http.Request, v any) bool {
+	err := json.NewDecoder(http.MaxBytesReader(w, r.Body, h.maxRequestBytes)).Decode(v)
+	if err == nil {
+		return true
+	}
+
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		h.writeError(w, fmt.Sprintf("request body must be at most %d bytes", tooLarge.Limit), http.StatusRequestEntityTooLarge)
+		return false
+	}
+	h.writeError(w, "invalid request body", http.StatusBadRequest)
+	return false
+}
+
+// writeError writes an error response with the generic code of statusCode
+func (h *Handler) writeError(w http.ResponseWriter, message string, statusCode int) {
+	writeErrorResponse(w, statusErrorCode(statusCode), message, statusCode)
+}
+
+// writeErrorCode writes an error response with a specific code
+func (h *Handler) writeErrorCode(w http.ResponseWriter, code, message string, statusCode int) {
+	writeErrorResponse(w, code, message, statusCode)
+}
+
+// CreateShareRequest represents a request to create a share
+type CreateShareRequest struct {
+	S3Path       string    `json:"s3_path"`
+	Secret       string    `json:"secret"`
+	ExpiresAt    time.Time `json:"expires_at,omitempty"`
+	ExpiresIn    string    `json:"expires_in,omitempty"`
+	MaxDownloads int       `json:"max_downloads,omitempty"`
+	Mode         string    `json:"mode,omitempty"`
+	Password     string    `json:"password,omitempty"`
+	Public       bool      `json:"public,omitempty"`
+	Short        bool      `json:"short,omitempty"`
+	VersionID    string    `json:"version_id,omitempty"`
+	WebhookURL   string    `json:"webhook_url,omitempty"`
+	// AllowedReferers lists the hosts, optionally "*.example.com", whose
+	// pages may embed or link the share
+	AllowedReferers []string `json:"allowed_referers,omitempty"`
+	// AllowedIPs lists the CIDRs, such as "203.0.113.0/24", or single
+	// addresses of the clients that may download the share
+	AllowedIPs []string `json:"allowed_ips,omitempty"`
+}
+
+// UploadResponse represents the response body for upload creation
+type UploadResponse struct {
+	UploadURL       string    `json:"upload_url"`
+	UploadMethod    string    `json:"upload_method"`
+	UploadExpiresAt time.Time `json:"upload_expires_at"`
+	ShareURL        string    `json:"share_url"`
+	ExpiresAt       time.Time `json:"expires_at"`
+	MaxAge          int       `json:"max_age_seconds"`
+}
+
+// ExtendShareRequest represents a request to move the expiry of a share
+type ExtendShareRequest struct {
+	S3Path    string    `json:"s3_path"`
+	ExpiresAt time.Time `json:"expires_at,omitempty"`
+	ExpiresIn string    `json:"expires_in,omitempty"`
+}
+
+// ShareCookieRequest represents a request for a share cookie, carrying the
+// path, secret and expiry of a share link
+type ShareCookieRequest struct {
+	S3Path    string    `json:"s3_path"`
+	Secret    string    `json:"secret"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// ShareCookieResponse describes the grant of an issued share cookie
+type ShareCookieResponse struct {
+	S3Path    string    `json:"s3_path"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// RevokeShareRequest represents a request to revoke a share
+type RevokeShareRequest struct {
+	S3Path string `json:"s3_path"`
+}
+
+// DeleteObjectRequest represents a request to delete an object
+type DeleteObjectRequest struct {
+	S3Path string `json:"s3_path"`
+}
+
+// ObjectResponse describes a stored object in a listing
+type ObjectResponse struct {
+	Key          string    `json:"key"`
+	Size         int64     `json:"size"`
+	LastModified time.Time `json:"last_modified"`
+}
+
+// ListObjectsResponse represents a page of an object listing
+type ListObjectsResponse struct {
+	Objects   []ObjectResponse `json:"objects"`
+	NextToken string           `json:"next_token,omitempty"`
+}
+
+// CreateShareResponse represents a response after creating a share
+type CreateShareResponse struct {
+	URL       string    `json:"url"`
+	ExpiresAt time.Time `json:"expires_at"`
+	MaxAge    int       `json:"max_age_seconds"`
+	// Secret and Curl are only included with ?verbose=true
+	Secret string `json:"secret,omitempty"`
+	Curl   string `json:"curl,omitempty"`
+}
+
+// BatchShareResult represents the outcome of one item of a batch share
+// creation: the created share, or the error it failed with
+type BatchShareResult struct {
+	// Status is the HTTP status the item would have been answered with on
+	// its own
+	Status int `json:"status"`
+	*CreateShareResponse
+	// Code and Error describe failed items as in ErrorResponse
+	Code  string `json:"code,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+// ShareInfoResponse represents the state of an existing share
+type ShareInfoResponse struct {
+	S3Path        string    `json:"s3_path"`
+	ExpiresAt     time.Time `json:"expires_at"`
+	TTLSeconds    int       `json:"ttl_seconds"`
+	Active        bool      `json:"active"`
+	DownloadCount int64     `json:"download_count"`
+	// LastAccessedAt is omitted for shares never downloaded
+	LastAccessedAt *time.Time `json:"last_accessed_at,omitempty"`
+}
+
+// AuditResponse represents the response body of the audit log endpoint
+type AuditResponse struct {
+	S3Path  string              `json:"s3_path"`
+	Entries []domain.AuditEntry `json:"entries"`
+}
+
+// ErrorResponse represents an error response
+type ErrorResponse struct {
+	Error string `json:"error"`
+	// Code is a stable machine-readable code such as EXPIRED
+	Code string `json:"code"`
+	// Status is the HTTP status code
+	Status  int    `json:"status"`
+	Message string `json:"message"`
+	// ExpiredAt is when the link of an EXPIRED error stopped working, for
+	// links that expired recently
+	ExpiredAt *time.Time `json:"expired_at,omitempty"`
+}
+
+// HealthResponse represents the health check response
+type HealthResponse struct {
+	Status        string `json:"status"`
+	Version       string `json:"version"`
+	GitCommit     string `json:"git_commit"`
+	BuildTime     string `json:"build_time"`
+	UptimeSeconds int64  `json:"uptime_seconds"`
+}
+
+// ReadyResponse represents a readiness check response
+type ReadyResponse struct {
+	Status string   `json:"status"`
+	Failed []string `json:"failed,omitempty"`
 }
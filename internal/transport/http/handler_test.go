@@ -3,12 +3,28 @@ package http
 import (
 	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"io"
+	"log/slog"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/vchitai/go-s3-sharing/internal/domain"
+	"github.com/vchitai/go-s3-sharing/internal/service"
+	"github.com/vchitai/go-s3-sharing/internal/version"
 )
 
 // mockShareService is a mock implementation of ShareService
@@ -22,10 +38,18 @@ func (m *mockShareService) CreateShare(ctx context.Context, req *domain.ShareReq
 	}, nil
 }
 
-func (m *mockShareService) ValidateShare(ctx context.Context, s3Path, secret string) error {
+func (m *mockShareService) ValidateShare(ctx context.Context, s3Path, secret string, expiresAt time.Time) error {
 	return nil
 }
 
+func (m *mockShareService) RevokeShare(ctx context.Context, s3Path string) error {
+	return nil
+}
+
+func (m *mockShareService) GetShareInfo(ctx context.Context, s3Path string) (*domain.ShareInfo, error) {
+	return &domain.ShareInfo{S3Path: s3Path, ExpiresAt: time.Now().Add(time.Hour), TTL: time.Hour, Active: true}, nil
+}
+
 func (m *mockShareService) GetObject(ctx context.Context, s3Path string) (domain.ObjectReader, error) {
 	return &mockObjectReader{}, nil
 }
@@ -49,6 +73,275 @@ func (m *mockObjectReader) Size() int64 {
 	return 1024
 }
 
+func (m *mockObjectReader) ETag() string {
+	return `"mock-etag"`
+}
+
+func (m *mockObjectReader) LastModified() time.Time {
+	return time.Time{}
+}
+
+func (m *mockObjectReader) Checksum() string {
+	return ""
+}
+
+// mockObject is an object body stored in mockStorageService
+type mockObject struct {
+	contentType string
+	data        []byte
+	etag        string
+	// lastModified is reported by readers and metadata of the object
+	lastModified time.Time
+	// checksum is reported by readers and metadata of the object
+	checksum string
+	// unknownSize makes readers and metadata of the object report a size of
+	// -1, as backends that do not know it do
+	unknownSize bool
+	// reader, when set, is returned by GetObject in place of data
+	reader domain.ObjectReader
+}
+
+// size returns the size readers and metadata of the object report
+func (o mockObject) size() int64 {
+	if o.unknownSize {
+		return -1
+	}
+	return int64(len(o.data))
+}
+
+// mockStorageService is an in-memory implementation of StorageService
+type mockStorageService struct {
+	objects  map[string]mockObject
+	getCalls atomic.Int64
+}
+
+func (m *mockStorageService) GetObject(ctx context.Context, key string) (domain.ObjectReader, error) {
+	m.getCalls.Add(1)
+	obj, exists := m.objects[key]
+	if !exists {
+		return nil, domain.ErrNotFound
+	}
+	if obj.reader != nil {
+		return obj.reader, nil
+	}
+	return &mockBodyReader{Reader: bytes.NewReader(obj.data), contentType: obj.contentType, size: obj.size(), etag: obj.etag, lastModified: obj.lastModified, checksum: obj.checksum}, nil
+}
+
+func (m *mockStorageService) GetObjectRange(ctx context.Context, key string, start, end int64) (domain.ObjectReader, error) {
+	obj, exists := m.objects[key]
+	if !exists {
+		return nil, domain.ErrNotFound
+	}
+	data := obj.data[start : end+1]
+	return &mockBodyReader{Reader: bytes.NewReader(data), contentType: obj.contentType, size: int64(len(data)), etag: obj.etag, lastModified: obj.lastModified, checksum: obj.checksum}, nil
+}
+
+func (m *mockStorageService) HealthCheck(ctx context.Context) error {
+	return nil
+}
+
+func (m *mockStorageService) HeadObject(ctx context.Context, key string) (*domain.ObjectMetadata, error) {
+	obj, exists := m.objects[key]
+	if !exists {
+		return nil, domain.ErrNotFound
+	}
+	return &domain.ObjectMetadata{ContentType: obj.contentType, Size: obj.size(), ETag: obj.etag, LastModified: obj.lastModified, Checksum: obj.checksum}, nil
+}
+
+// mockListPageSize is the page size of mockStorageService listings
+const mockListPageSize = 2
+
+func (m *mockStorageService) ListObjects(ctx context.Context, prefix, token string) ([]domain.ObjectMetadata, string, error) {
+	var keys []string
+	for key := range m.objects {
+		if strings.HasPrefix(key, prefix) && key > token {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	var next string
+	if len(keys) > mockListPageSize {
+		keys = keys[:mockListPageSize]
+		next = keys[len(keys)-1]
+	}
+
+	objects := make([]domain.ObjectMetadata, 0, len(keys))
+	for _, key := range keys {
+		objects = append(objects, domain.ObjectMetadata{Key: key, Size: int64(len(m.objects[key].data))})
+	}
+	return objects, next, nil
+}
+
+func (m *mockStorageService) DeleteObject(ctx context.Context, key string) error {
+	if _, exists := m.objects[key]; !exists {
+		return domain.ErrNotFound
+	}
+	delete(m.objects, key)
+	return nil
+}
+
+// mockBodyReader is an ObjectReader over an in-memory body
+type mockBodyReader struct {
+	*bytes.Reader
+	contentType  string
+	size         int64
+	etag         string
+	lastModified time.Time
+	checksum     string
+}
+
+func (m *mockBodyReader) Close() error {
+	return nil
+}
+
+func (m *mockBodyReader) ContentType() string {
+	return m.contentType
+}
+
+func (m *mockBodyReader) Size() int64 {
+	return m.size
+}
+
+func (m *mockBodyReader) ETag() string {
+	return m.etag
+}
+
+func (m *mockBodyReader) LastModified() time.Time {
+	return m.lastModified
+}
+
+func (m *mockBodyReader) Checksum() string {
+	return m.checksum
+}
+
+// mockCacheService is a mock implementation of CacheService
+type mockCacheService struct {
+	store   map[string]string
+	ttls    map[string]time.Duration
+	pingErr error
+	// gets counts the loads of each key when set
+	gets map[string]int
+}
+
+func (m *mockCacheService) Set(ctx context.Context, key, value string, expiration time.Duration) error {
+	if m.ttls == nil {
+		m.ttls = make(map[string]time.Duration)
+	}
+	m.store[key] = value
+	m.ttls[key] = expiration
+	return nil
+}
+
+func (m *mockCacheService) SetNX(ctx context.Context, key, value string, expiration time.Duration) (bool, error) {
+	if _, exists := m.store[key]; exists {
+		return false, nil
+	}
+	return true, m.Set(ctx, key, value, expiration)
+}
+
+func (m *mockCacheService) Get(ctx context.Context, key string) (string, error) {
+	if m.gets != nil {
+		m.gets[key]++
+	}
+	if value, exists := m.store[key]; exists {
+		return value, nil
+	}
+	return "", domain.ErrNotFound
+}
+
+func (m *mockCacheService) Delete(ctx context.Context, key string) error {
+	delete(m.store, key)
+	delete(m.ttls, key)
+	return nil
+}
+
+func (m *mockCacheService) Incr(ctx context.Context, key string, expiration time.Duration) (int64, error) {
+	return m.IncrBy(ctx, key, 1, expiration)
+}
+
+func (m *mockCacheService) IncrBy(ctx context.Context, key string, delta int64, expiration time.Duration) (int64, error) {
+	count, _ := strconv.ParseInt(m.store[key], 10, 64)
+	count += delta
+	m.store[key] = strconv.FormatInt(count, 10)
+	return count, nil
+}
+
+func (m *mockCacheService) TTL(ctx context.Context, key string) (time.Duration, error) {
+	if _, exists := m.store[key]; !exists {
+		return 0, domain.ErrNotFound
+	}
+	if ttl, exists := m.ttls[key]; exists && ttl > 0 {
+		return ttl, nil
+	}
+	return -1, nil
+}
+
+func (m *mockCacheService) Expire(ctx context.Context, key string, expiration time.Duration) error {
+	if _, exists := m.store[key]; !exists {
+		return nil
+	}
+	if m.ttls == nil {
+		m.ttls = make(map[string]time.Duration)
+	}
+	m.ttls[key] = expiration
+	return nil
+}
+
+func (m *mockCacheService) Scan(ctx context.Context, prefix string, cursor uint64) ([]string, uint64, error) {
+	var keys []string
+	for key := range m.store {
+		if strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+	}
+	return keys, 0, nil
+}
+
+func (m *mockCacheService) Ping(ctx context.Context) error {
+	return m.pingErr
+}
+
+// newTestHandler creates a handler backed by a share service over in-memory mocks
+func newTestHandler(objects map[string]mockObject) (*Handler, *service.ShareService) {
+	handler, shareService, _ := newTestHandlerWithStorage(objects)
+	return handler, shareService
+}
+
+// newTestHandlerWithStorage is like newTestHandler but also returns the storage mock
+func newTestHandlerWithStorage(objects map[string]mockObject) (*Handler, *service.ShareService, *mockStorageService) {
+	storage := &mockStorageService{objects: objects}
+	handler, shareService := newTestHandlerWithMocks(storage, &mockCacheService{store: make(map[string]string)})
+	return handler, shareService, storage
+}
+
+// newTestHandlerWithMocks creates a handler over the given storage and cache mocks
+func newTestHandlerWithMocks(storage *mockStorageService, cache domain.CacheService) (*Handler, *service.ShareService) {
+	shareService := service.NewShareService(storage, cache, &service.ShareConfig{
+		MaxAgeDays: 90,
+		BaseURL:    "https://example.com",
+	})
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	return NewHandler(shareService, logger), shareService
+}
+
+// createTestShare creates a share for s3Path and returns the request path of its URL
+func createTestShare(t *testing.T, shareService *service.ShareService, s3Path string) string {
+	t.Helper()
+
+	resp, err := shareService.CreateShare(context.Background(), &domain.ShareRequest{
+		S3Path:    s3Path,
+		Secret:    "test-secret",
+		ExpiresAt: time.Now().Add(72 * time.Hour),
+	})
+	if err != nil {
+		t.Fatalf("failed to create share: %v", err)
+	}
+
+	return strings.TrimPrefix(resp.URL, "https://example.com")
+}
+
 func TestHandler_Routing(t *testing.T) {
 	// Create a simple test that focuses on routing logic
 	// We'll test the routing by checking if the right handlers are called
@@ -71,6 +364,33 @@ func TestHandler_Routing(t *testing.T) {
 		}
 	})
 
+	t.Run("Health endpoint reports the build", func(t *testing.T) {
+		defer func(v, commit, built string) {
+			version.Version, version.GitCommit, version.BuildTime = v, commit, built
+		}(version.Version, version.GitCommit, version.BuildTime)
+		version.Version, version.GitCommit, version.BuildTime = "v1.2.0", "8f3c2a1", "2024-03-01T12:00:00Z"
+
+		req := httptest.NewRequest("GET", "/health", nil)
+		w := httptest.NewRecorder()
+
+		handler := &Handler{}
+		handler.HandleHealth(w, req)
+
+		var resp HealthResponse
+		if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		if resp.Status != "healthy" {
+			t.Errorf("expected status healthy, got %s", resp.Status)
+		}
+		if resp.Version != "v1.2.0" || resp.GitCommit != "8f3c2a1" || resp.BuildTime != "2024-03-01T12:00:00Z" {
+			t.Errorf("unexpected build info %+v", resp)
+		}
+		if resp.UptimeSeconds < 0 {
+			t.Errorf("expected a non-negative uptime, got %d", resp.UptimeSeconds)
+		}
+	})
+
 	t.Run("Ready endpoint", func(t *testing.T) {
 		req := httptest.NewRequest("GET", "/ready", nil)
 		w := httptest.NewRecorder()
@@ -137,3 +457,2999 @@ func TestHandler_PathValidation(t *testing.T) {
 		})
 	}
 }
+
+func TestHandler_RangeRequests(t *testing.T) {
+	body := []byte("0123456789")
+	handler, shareService := newTestHandler(map[string]mockObject{
+		"videos/clip.mp4": {contentType: "video/mp4", data: body},
+	})
+	path := createTestShare(t, shareService, "videos/clip.mp4")
+
+	tests := []struct {
+		name          string
+		rangeHeader   string
+		expectedCode  int
+		expectedBody  string
+		expectedRange string
+	}{
+		{
+			name:         "no range serves full object",
+			expectedCode: http.StatusOK,
+			expectedBody: "0123456789",
+		},
+		{
+			name:          "bounded range",
+			rangeHeader:   "bytes=2-5",
+			expectedCode:  http.StatusPartialContent,
+			expectedBody:  "2345",
+			expectedRange: "bytes 2-5/10",
+		},
+		{
+			name:          "open-ended range",
+			rangeHeader:   "bytes=7-",
+			expectedCode:  http.StatusPartialContent,
+			expectedBody:  "789",
+			expectedRange: "bytes 7-9/10",
+		},
+		{
+			name:          "suffix range",
+			rangeHeader:   "bytes=-3",
+			expectedCode:  http.StatusPartialContent,
+			expectedBody:  "789",
+			expectedRange: "bytes 7-9/10",
+		},
+		{
+			name:          "end past size is clamped",
+			rangeHeader:   "bytes=8-100",
+			expectedCode:  http.StatusPartialContent,
+			expectedBody:  "89",
+			expectedRange: "bytes 8-9/10",
+		},
+		{
+			name:          "unsatisfiable range",
+			rangeHeader:   "bytes=20-30",
+			expectedCode:  http.StatusRequestedRangeNotSatisfiable,
+			expectedRange: "bytes */10",
+		},
+		{
+			name:          "malformed range",
+			rangeHeader:   "items=0-1",
+			expectedCode:  http.StatusRequestedRangeNotSatisfiable,
+			expectedRange: "bytes */10",
+		},
+		{
+			name:         "multiple ranges serve full object",
+			rangeHeader:  "bytes=0-1,4-5",
+			expectedCode: http.StatusOK,
+			expectedBody: "0123456789",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", path, nil)
+			if tt.rangeHeader != "" {
+				req.Header.Set("Range", tt.rangeHeader)
+			}
+			w := httptest.NewRecorder()
+
+			handler.HandleImage(w, req)
+
+			if w.Code != tt.expectedCode {
+				t.Fatalf("expected status %d, got %d: %s", tt.expectedCode, w.Code, w.Body.String())
+			}
+			if got := w.Header().Get("Content-Range"); got != tt.expectedRange {
+				t.Errorf("expected Content-Range %q, got %q", tt.expectedRange, got)
+			}
+			if got := w.Header().Get("Accept-Ranges"); got != "bytes" {
+				t.Errorf("expected Accept-Ranges bytes, got %q", got)
+			}
+			if tt.expectedBody != "" && w.Body.String() != tt.expectedBody {
+				t.Errorf("expected body %q, got %q", tt.expectedBody, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestHandler_IfRange(t *testing.T) {
+	modified := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	handler, shareService := newTestHandler(map[string]mockObject{
+		"videos/clip.mp4": {contentType: "video/mp4", data: []byte("0123456789"), etag: `"v1"`, lastModified: modified},
+		"videos/weak.mp4": {contentType: "video/mp4", data: []byte("0123456789"), etag: `W/"v1"`, lastModified: modified},
+	})
+	path := createTestShare(t, shareService, "videos/clip.mp4")
+	weakPath := createTestShare(t, shareService, "videos/weak.mp4")
+
+	tests := []struct {
+		name         string
+		path         string
+		ifRange      string
+		expectedCode int
+		expectedBody string
+	}{
+		{name: "matching etag serves range", path: path, ifRange: `"v1"`, expectedCode: http.StatusPartialContent, expectedBody: "2345"},
+		{name: "changed etag serves full object", path: path, ifRange: `"v0"`, expectedCode: http.StatusOK, expectedBody: "0123456789"},
+		{name: "weak validator serves full object", path: path, ifRange: `W/"v1"`, expectedCode: http.StatusOK, expectedBody: "0123456789"},
+		{name: "weak object etag serves full object", path: weakPath, ifRange: `W/"v1"`, expectedCode: http.StatusOK, expectedBody: "0123456789"},
+		{name: "matching date serves range", path: path, ifRange: modified.Format(http.TimeFormat), expectedCode: http.StatusPartialContent, expectedBody: "2345"},
+		{name: "older date serves full object", path: path, ifRange: modified.Add(-time.Hour).Format(http.TimeFormat), expectedCode: http.StatusOK, expectedBody: "0123456789"},
+		{name: "malformed validator serves full object", path: path, ifRange: "yesterday", expectedCode: http.StatusOK, expectedBody: "0123456789"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", tt.path, nil)
+			req.Header.Set("Range", "bytes=2-5")
+			req.Header.Set("If-Range", tt.ifRange)
+			w := httptest.NewRecorder()
+
+			handler.HandleImage(w, req)
+
+			if w.Code != tt.expectedCode {
+				t.Fatalf("expected status %d, got %d: %s", tt.expectedCode, w.Code, w.Body.String())
+			}
+			if w.Body.String() != tt.expectedBody {
+				t.Errorf("expected body %q, got %q", tt.expectedBody, w.Body.String())
+			}
+			if tt.expectedCode == http.StatusOK && w.Header().Get("Content-Range") != "" {
+				t.Errorf("expected no Content-Range on a full response, got %q", w.Header().Get("Content-Range"))
+			}
+		})
+	}
+}
+
+func TestHandler_ResumedDownloadCounting(t *testing.T) {
+	handler, shareService := newTestHandler(map[string]mockObject{
+		"videos/clip.mp4": {contentType: "video/mp4", data: []byte("0123456789"), etag: `"v1"`},
+	})
+	ctx := context.Background()
+	resp, err := shareService.CreateShare(ctx, &domain.ShareRequest{
+		S3Path:       "videos/clip.mp4",
+		Secret:       "test-secret",
+		ExpiresAt:    time.Now().Add(72 * time.Hour),
+		MaxDownloads: 5,
+	})
+	if err != nil {
+		t.Fatalf("failed to create share: %v", err)
+	}
+	path := strings.TrimPrefix(resp.URL, "https://example.com")
+
+	download := func(t *testing.T, remoteAddr string, header http.Header) {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.RemoteAddr = remoteAddr
+		for name, values := range header {
+			req.Header[name] = values
+		}
+		w := httptest.NewRecorder()
+		handler.HandleImage(w, req)
+		if w.Code != http.StatusOK && w.Code != http.StatusPartialContent {
+			t.Fatalf("expected success, got %d: %s", w.Code, w.Body.String())
+		}
+	}
+	downloads := func(t *testing.T) int64 {
+		t.Helper()
+		info, err := shareService.GetShareInfo(ctx, "videos/clip.mp4")
+		if err != nil {
+			t.Fatalf("failed to get share info: %v", err)
+		}
+		return info.DownloadCount
+	}
+
+	resume := http.Header{"Range": {"bytes=4-"}, "If-Range": {`"v1"`}}
+	download(t, "192.0.2.1:1234", nil)
+	download(t, "192.0.2.1:1234", resume)
+	download(t, "192.0.2.1:1234", resume)
+	if got := downloads(t); got != 1 {
+		t.Errorf("expected resumed ranges not to be counted, got %d downloads", got)
+	}
+
+	// Ranges from the start, without If-Range or from another client are new
+	// downloads
+	download(t, "192.0.2.1:1234", http.Header{"Range": {"bytes=4-"}})
+	download(t, "192.0.2.2:1234", resume)
+	if got := downloads(t); got != 3 {
+		t.Errorf("expected new downloads to be counted, got %d downloads", got)
+	}
+}
+
+func TestHandler_ResumeConsumingDownload(t *testing.T) {
+	handler, shareService := newTestHandler(map[string]mockObject{
+		"videos/clip.mp4": {contentType: "video/mp4", data: []byte("0123456789"), etag: `"v1"`},
+	})
+	resp, err := shareService.CreateShare(context.Background(), &domain.ShareRequest{
+		S3Path:       "videos/clip.mp4",
+		Secret:       "test-secret",
+		ExpiresAt:    time.Now().Add(72 * time.Hour),
+		MaxDownloads: 1,
+	})
+	if err != nil {
+		t.Fatalf("failed to create share: %v", err)
+	}
+	path := strings.TrimPrefix(resp.URL, "https://example.com")
+
+	resume := http.Header{"Range": {"bytes=4-"}, "If-Range": {`"v1"`}}
+	tests := []struct {
+		name           string
+		remoteAddr     string
+		header         http.Header
+		expectedStatus int
+		expectedBody   string
+	}{
+		{name: "last permitted download", remoteAddr: "192.0.2.1:1234", expectedStatus: http.StatusOK, expectedBody: "0123456789"},
+		{name: "resumed by its client", remoteAddr: "192.0.2.1:1234", header: resume, expectedStatus: http.StatusPartialContent, expectedBody: "456789"},
+		{name: "resumed again", remoteAddr: "192.0.2.1:1234", header: resume, expectedStatus: http.StatusPartialContent, expectedBody: "456789"},
+		{name: "new download", remoteAddr: "192.0.2.1:1234", expectedStatus: http.StatusGone},
+		{name: "range without If-Range", remoteAddr: "192.0.2.1:1234", header: http.Header{"Range": {"bytes=4-"}}, expectedStatus: http.StatusGone},
+		{name: "other entity tag", remoteAddr: "192.0.2.1:1234", header: http.Header{"Range": {"bytes=4-"}, "If-Range": {`"v2"`}}, expectedStatus: http.StatusGone},
+		{name: "other client", remoteAddr: "192.0.2.2:1234", header: resume, expectedStatus: http.StatusGone},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, path, nil)
+			req.RemoteAddr = tt.remoteAddr
+			for name, values := range tt.header {
+				req.Header[name] = values
+			}
+			w := httptest.NewRecorder()
+			handler.HandleImage(w, req)
+			if w.Code != tt.expectedStatus {
+				t.Fatalf("expected status %d, got %d: %s", tt.expectedStatus, w.Code, w.Body.String())
+			}
+			if tt.expectedBody != "" && w.Body.String() != tt.expectedBody {
+				t.Errorf("expected body %q, got %q", tt.expectedBody, w.Body.String())
+			}
+		})
+	}
+
+	if _, err := shareService.GetShareInfo(context.Background(), "videos/clip.mp4"); !errors.Is(err, domain.ErrNotFound) {
+		t.Errorf("expected the consumed share to be gone, got %v", err)
+	}
+}
+
+func TestHandler_DownloadCountedAfterOpen(t *testing.T) {
+	handler, shareService, storage := newTestHandlerWithStorage(map[string]mockObject{
+		"videos/clip.mp4": {contentType: "video/mp4", data: []byte("0123456789")},
+	})
+	ctx := context.Background()
+	resp, err := shareService.CreateShare(ctx, &domain.ShareRequest{
+		S3Path:       "videos/clip.mp4",
+		Secret:       "test-secret",
+		ExpiresAt:    time.Now().Add(72 * time.Hour),
+		MaxDownloads: 1,
+	})
+	if err != nil {
+		t.Fatalf("failed to create share: %v", err)
+	}
+	// The object disappears from storage after the share was created
+	delete(storage.objects, "videos/clip.mp4")
+
+	w := httptest.NewRecorder()
+	handler.HandleImage(w, httptest.NewRequest(http.MethodGet, strings.TrimPrefix(resp.URL, "https://example.com"), nil))
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusNotFound, w.Code, w.Body.String())
+	}
+
+	info, err := shareService.GetShareInfo(ctx, "videos/clip.mp4")
+	if err != nil {
+		t.Fatalf("expected the share to survive a failed download, got %v", err)
+	}
+	if info.DownloadCount != 0 {
+		t.Errorf("expected no download to be counted, got %d", info.DownloadCount)
+	}
+}
+
+func TestHandler_UnknownContentLength(t *testing.T) {
+	handler, shareService := newTestHandler(map[string]mockObject{
+		"videos/live.mp4": {contentType: "video/mp4", data: []byte("0123456789"), unknownSize: true},
+	})
+	path := createTestShare(t, shareService, "videos/live.mp4")
+
+	tests := []struct {
+		name         string
+		method       string
+		rangeHeader  string
+		expectedBody string
+	}{
+		{name: "get", method: http.MethodGet, expectedBody: "0123456789"},
+		{name: "head", method: http.MethodHead},
+		{name: "range serves full object", method: http.MethodGet, rangeHeader: "bytes=2-5", expectedBody: "0123456789"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, path, nil)
+			if tt.rangeHeader != "" {
+				req.Header.Set("Range", tt.rangeHeader)
+			}
+			w := httptest.NewRecorder()
+
+			handler.HandleImage(w, req)
+
+			if w.Code != http.StatusOK {
+				t.Fatalf("expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+			}
+			if got, ok := w.Header()["Content-Length"]; ok {
+				t.Errorf("expected no Content-Length for an unknown size, got %q", got)
+			}
+			if got := w.Header().Get("Content-Range"); got != "" {
+				t.Errorf("expected no Content-Range, got %q", got)
+			}
+			if w.Body.String() != tt.expectedBody {
+				t.Errorf("expected body %q, got %q", tt.expectedBody, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestHandler_HeadRequests(t *testing.T) {
+	handler, shareService := newTestHandler(map[string]mockObject{
+		"images/photo.jpg": {contentType: "image/jpeg", data: []byte("jpeg-bytes")},
+	})
+	path := createTestShare(t, shareService, "images/photo.jpg")
+
+	t.Run("valid share returns headers without body", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodHead, path, nil)
+		w := httptest.NewRecorder()
+
+		handler.HandleImage(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status %d, got %d", http.StatusOK, w.Code)
+		}
+		if w.Body.Len() != 0 {
+			t.Errorf("expected empty body, got %q", w.Body.String())
+		}
+		if got := w.Header().Get("Content-Type"); got != "image/jpeg" {
+			t.Errorf("expected Content-Type image/jpeg, got %q", got)
+		}
+		if got := w.Header().Get("Content-Length"); got != "10" {
+			t.Errorf("expected Content-Length 10, got %q", got)
+		}
+		if got := w.Header().Get("Cache-Control"); got != "public, max-age=3600" {
+			t.Errorf("expected Cache-Control header, got %q", got)
+		}
+	})
+
+	t.Run("wrong secret is unauthorized", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodHead, strings.Replace(path, "test-secret", "wrong-secret", 1), nil)
+		w := httptest.NewRecorder()
+
+		handler.HandleImage(w, req)
+
+		if w.Code != http.StatusUnauthorized {
+			t.Errorf("expected status %d, got %d", http.StatusUnauthorized, w.Code)
+		}
+	})
+
+	t.Run("expired link is forbidden", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodHead, "/20/01/01/test-secret/images/photo.jpg", nil)
+		w := httptest.NewRecorder()
+
+		handler.HandleImage(w, req)
+
+		if w.Code != http.StatusForbidden {
+			t.Errorf("expected status %d, got %d", http.StatusForbidden, w.Code)
+		}
+	})
+}
+
+func TestHandler_ConditionalETag(t *testing.T) {
+	handler, shareService, storage := newTestHandlerWithStorage(map[string]mockObject{
+		"images/photo.jpg": {contentType: "image/jpeg", data: []byte("jpeg-bytes"), etag: `"abc123"`},
+	})
+	path := createTestShare(t, shareService, "images/photo.jpg")
+
+	t.Run("matching ETag returns 304 without fetching body", func(t *testing.T) {
+		storage.getCalls.Store(0)
+		req := httptest.NewRequest("GET", path, nil)
+		req.Header.Set("If-None-Match", `"abc123"`)
+		w := httptest.NewRecorder()
+
+		handler.HandleImage(w, req)
+
+		if w.Code != http.StatusNotModified {
+			t.Fatalf("expected status %d, got %d", http.StatusNotModified, w.Code)
+		}
+		if w.Body.Len() != 0 {
+			t.Errorf("expected empty body, got %q", w.Body.String())
+		}
+		if got := w.Header().Get("ETag"); got != `"abc123"` {
+			t.Errorf("expected ETag header, got %q", got)
+		}
+		if calls := storage.getCalls.Load(); calls != 0 {
+			t.Errorf("expected GetObject not to be called, got %d calls", calls)
+		}
+	})
+
+	t.Run("non-matching ETag streams object", func(t *testing.T) {
+		req := httptest.NewRequest("GET", path, nil)
+		req.Header.Set("If-None-Match", `"stale", W/"older"`)
+		w := httptest.NewRecorder()
+
+		handler.HandleImage(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status %d, got %d", http.StatusOK, w.Code)
+		}
+		if got := w.Header().Get("ETag"); got != `"abc123"` {
+			t.Errorf("expected ETag header, got %q", got)
+		}
+		if w.Body.String() != "jpeg-bytes" {
+			t.Errorf("expected object body, got %q", w.Body.String())
+		}
+	})
+}
+
+func TestHandler_ConditionalLastModified(t *testing.T) {
+	modified := time.Date(2024, 3, 1, 12, 30, 45, 500, time.UTC)
+	handler, shareService, storage := newTestHandlerWithStorage(map[string]mockObject{
+		"images/photo.jpg": {contentType: "image/jpeg", data: []byte("jpeg-bytes"), etag: `"abc123"`, lastModified: modified},
+	})
+	path := createTestShare(t, shareService, "images/photo.jpg")
+	lastModified := modified.Format(http.TimeFormat)
+
+	tests := []struct {
+		name           string
+		header         http.Header
+		expectedStatus int
+	}{
+		{
+			name:           "unmodified since the modification time",
+			header:         http.Header{"If-Modified-Since": {lastModified}},
+			expectedStatus: http.StatusNotModified,
+		},
+		{
+			name:           "unmodified since a later time",
+			header:         http.Header{"If-Modified-Since": {modified.Add(time.Hour).Format(http.TimeFormat)}},
+			expectedStatus: http.StatusNotModified,
+		},
+		{
+			name:           "modified since an earlier time",
+			header:         http.Header{"If-Modified-Since": {modified.Add(-time.Second).Format(http.TimeFormat)}},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "invalid date is ignored",
+			header:         http.Header{"If-Modified-Since": {"yesterday"}},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "If-None-Match takes precedence",
+			header:         http.Header{"If-Modified-Since": {lastModified}, "If-None-Match": {`"stale"`}},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "unconditional request",
+			expectedStatus: http.StatusOK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storage.getCalls.Store(0)
+			req := httptest.NewRequest("GET", path, nil)
+			for name, values := range tt.header {
+				req.Header[name] = values
+			}
+			w := httptest.NewRecorder()
+
+			handler.HandleImage(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Fatalf("expected status %d, got %d", tt.expectedStatus, w.Code)
+			}
+			if got := w.Header().Get("Last-Modified"); got != lastModified {
+				t.Errorf("expected Last-Modified %q, got %q", lastModified, got)
+			}
+			if tt.expectedStatus == http.StatusNotModified {
+				if w.Body.Len() != 0 {
+					t.Errorf("expected empty body, got %q", w.Body.String())
+				}
+				if calls := storage.getCalls.Load(); calls != 0 {
+					t.Errorf("expected GetObject not to be called, got %d calls", calls)
+				}
+			} else if w.Body.String() != "jpeg-bytes" {
+				t.Errorf("expected object body, got %q", w.Body.String())
+			}
+		})
+	}
+}
+
+func TestHandler_DownloadDisposition(t *testing.T) {
+	handler, shareService := newTestHandler(map[string]mockObject{
+		"docs/report.pdf":           {contentType: "application/pdf", data: []byte("pdf")},
+		"docs/annual report.pdf":    {contentType: "application/pdf", data: []byte("pdf")},
+		"docs/résumé \"final\".pdf": {contentType: "application/pdf", data: []byte("pdf")},
+	})
+
+	tests := []struct {
+		name     string
+		s3Path   string
+		query    string
+		expected string
+	}{
+		{
+			name:     "inline by default",
+			s3Path:   "docs/report.pdf",
+			expected: "",
+		},
+		{
+			name:     "download disabled explicitly",
+			s3Path:   "docs/report.pdf",
+			query:    "?download=false",
+			expected: "",
+		},
+		{
+			name:     "download flag",
+			s3Path:   "docs/report.pdf",
+			query:    "?download",
+			expected: `attachment; filename="report.pdf"; filename*=UTF-8''report.pdf`,
+		},
+		{
+			name:     "filename with spaces",
+			s3Path:   "docs/annual report.pdf",
+			query:    "?download=true",
+			expected: `attachment; filename="annual report.pdf"; filename*=UTF-8''annual%20report.pdf`,
+		},
+		{
+			name:     "filename with unicode and quotes",
+			s3Path:   "docs/résumé \"final\".pdf",
+			query:    "?download=1",
+			expected: `attachment; filename="r_sum_ _final_.pdf"; filename*=UTF-8''r%C3%A9sum%C3%A9%20%22final%22.pdf`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := createTestShare(t, shareService, tt.s3Path)
+			req := httptest.NewRequest("GET", "https://example.com"+(&url.URL{Path: path}).EscapedPath()+tt.query, nil)
+			w := httptest.NewRecorder()
+
+			handler.HandleImage(w, req)
+
+			if w.Code != http.StatusOK {
+				t.Fatalf("expected status %d, got %d", http.StatusOK, w.Code)
+			}
+			if got := w.Header().Get("Content-Disposition"); got != tt.expected {
+				t.Errorf("expected Content-Disposition %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestHandler_ReadyDependencies(t *testing.T) {
+	t.Run("all dependencies healthy", func(t *testing.T) {
+		handler, _ := newTestHandler(map[string]mockObject{})
+
+		req := httptest.NewRequest("GET", "/ready", nil)
+		w := httptest.NewRecorder()
+
+		handler.HandleReady(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Errorf("expected status %d, got %d", http.StatusOK, w.Code)
+		}
+	})
+
+	t.Run("failing cache", func(t *testing.T) {
+		storage := &mockStorageService{objects: map[string]mockObject{}}
+		cache := &mockCacheService{store: make(map[string]string), pingErr: errors.New("connection refused")}
+		handler, _ := newTestHandlerWithMocks(storage, cache)
+
+		req := httptest.NewRequest("GET", "/ready", nil)
+		w := httptest.NewRecorder()
+
+		handler.HandleReady(w, req)
+
+		if w.Code != http.StatusServiceUnavailable {
+			t.Fatalf("expected status %d, got %d", http.StatusServiceUnavailable, w.Code)
+		}
+
+		var resp ReadyResponse
+		if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		if resp.Status != "not ready" {
+			t.Errorf("expected status not ready, got %s", resp.Status)
+		}
+		if len(resp.Failed) != 1 || resp.Failed[0] != "cache" {
+			t.Errorf("expected failed dependencies [cache], got %v", resp.Failed)
+		}
+	})
+}
+
+func TestHandler_PasswordProtectedShare(t *testing.T) {
+	handler, shareService := newTestHandler(map[string]mockObject{
+		"images/photo.jpg": {contentType: "image/jpeg", data: []byte("jpeg-bytes")},
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/api/shares", strings.NewReader(`{"s3_path":"images/photo.jpg","secret":"test-secret","expires_in":"48h","password":"correct horse"}`))
+	w := httptest.NewRecorder()
+	handler.HandleCreateShare(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	var created CreateShareResponse
+	if err := json.NewDecoder(w.Body).Decode(&created); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	path := strings.TrimPrefix(created.URL, "https://example.com")
+
+	info, err := shareService.GetShareInfo(context.Background(), "images/photo.jpg")
+	if err != nil || !info.Active {
+		t.Fatalf("expected an active share, got %+v, %v", info, err)
+	}
+
+	tests := []struct {
+		name           string
+		method         string
+		query          string
+		header         string
+		form           string
+		contentType    string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{name: "correct header", method: http.MethodGet, header: "correct horse", expectedStatus: http.StatusOK, expectedBody: "jpeg-bytes"},
+		{name: "correct form field", method: http.MethodPost, form: "password=correct+horse", expectedStatus: http.StatusOK, expectedBody: "jpeg-bytes"},
+		{name: "wrong header", method: http.MethodGet, header: "battery staple", expectedStatus: http.StatusUnauthorized, expectedBody: "invalid password"},
+		{name: "wrong form field", method: http.MethodPost, form: "password=battery+staple", expectedStatus: http.StatusUnauthorized, expectedBody: "invalid password"},
+		{name: "missing password", method: http.MethodGet, expectedStatus: http.StatusUnauthorized, expectedBody: "password required"},
+		{name: "password in query is ignored", method: http.MethodGet, query: "?password=correct+horse", expectedStatus: http.StatusUnauthorized, expectedBody: "password required"},
+		{name: "multipart form", method: http.MethodPost, form: "--x\r\nContent-Disposition: form-data; name=\"password\"\r\n\r\ncorrect horse\r\n--x--\r\n", contentType: "multipart/form-data; boundary=x", expectedStatus: http.StatusUnsupportedMediaType},
+		{name: "form too large", method: http.MethodPost, form: "password=" + strings.Repeat("x", defaultMaxRequestBytes), expectedStatus: http.StatusRequestEntityTooLarge},
+		{name: "other methods", method: http.MethodPut, header: "correct horse", expectedStatus: http.StatusMethodNotAllowed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, path+tt.query, strings.NewReader(tt.form))
+			if tt.form != "" {
+				contentType := tt.contentType
+				if contentType == "" {
+					contentType = "application/x-www-form-urlencoded"
+				}
+				req.Header.Set("Content-Type", contentType)
+			}
+			if tt.header != "" {
+				req.Header.Set(sharePasswordHeader, tt.header)
+			}
+			w := httptest.NewRecorder()
+			handler.HandleImage(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Fatalf("expected status %d, got %d: %s", tt.expectedStatus, w.Code, w.Body.String())
+			}
+			if !strings.Contains(w.Body.String(), tt.expectedBody) {
+				t.Errorf("expected body to contain %q, got %q", tt.expectedBody, w.Body.String())
+			}
+		})
+	}
+
+	t.Run("password too long", func(t *testing.T) {
+		body := `{"s3_path":"images/photo.jpg","secret":"test-secret","password":"` + strings.Repeat("x", maxPasswordBytes+1) + `"}`
+		req := httptest.NewRequest(http.MethodPost, "/api/shares", strings.NewReader(body))
+		w := httptest.NewRecorder()
+		handler.HandleCreateShare(w, req)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("expected status %d, got %d", http.StatusBadRequest, w.Code)
+		}
+	})
+}
+
+func TestHandler_SubDayExpiry(t *testing.T) {
+	handler, shareService := newTestHandler(map[string]mockObject{
+		"images/photo.jpg": {contentType: "image/jpeg", data: []byte("jpeg-bytes")},
+	})
+
+	resp, err := shareService.CreateShare(context.Background(), &domain.ShareRequest{
+		S3Path:    "images/photo.jpg",
+		Secret:    "test-secret",
+		ExpiresAt: time.Now().Add(time.Hour),
+	})
+	if err != nil {
+		t.Fatalf("failed to create share: %v", err)
+	}
+	path := strings.TrimPrefix(resp.URL, "https://example.com")
+
+	// The URL carries the exact expiry rather than its day
+	expiry := strings.Split(strings.Trim(path, "/"), "/")[0]
+	if expiry != strconv.FormatInt(resp.ExpiresAt.Unix(), 10) {
+		t.Fatalf("expected unix expiry %d in URL, got %s", resp.ExpiresAt.Unix(), path)
+	}
+
+	past := strconv.FormatInt(time.Now().Add(-time.Second).Unix(), 10)
+	today := time.Now().UTC().Format("06/01/02")
+
+	tests := []struct {
+		name           string
+		path           string
+		expectedStatus int
+	}{
+		{name: "one hour share is valid now", path: path, expectedStatus: http.StatusOK},
+		{name: "expired a second ago", path: strings.Replace(path, expiry, past, 1), expectedStatus: http.StatusForbidden},
+		{name: "legacy date link expires at start of day", path: strings.Replace(path, expiry, today, 1), expectedStatus: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			w := httptest.NewRecorder()
+			handler.HandleImage(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d: %s", tt.expectedStatus, w.Code, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestHandler_TamperedSignedLink(t *testing.T) {
+	storage := &mockStorageService{objects: map[string]mockObject{
+		"images/photo.jpg": {contentType: "image/jpeg", data: []byte("jpeg-bytes")},
+	}}
+	cache := &mockCacheService{store: make(map[string]string)}
+	shareService := service.NewShareService(storage, cache, &service.ShareConfig{
+		MaxAgeDays: 90,
+		BaseURL:    "https://example.com",
+		SigningKey: "test-signing-key",
+		SignedURLs: true,
+	})
+	handler := NewHandler(shareService, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	path := createTestShare(t, shareService, "images/photo.jpg")
+	expiry := strings.Split(strings.Trim(path, "/"), "/")[0]
+	seconds, _ := strconv.ParseInt(expiry, 10, 64)
+	shift := func(d time.Duration) string {
+		return strings.Replace(path, expiry, strconv.FormatInt(seconds+int64(d.Seconds()), 10), 1)
+	}
+
+	tests := []struct {
+		name           string
+		path           string
+		evict          bool
+		expectedStatus int
+	}{
+		{name: "genuine link", path: path, expectedStatus: http.StatusOK},
+		{name: "extended by a month", path: shift(30 * 24 * time.Hour), expectedStatus: http.StatusForbidden},
+		{name: "extended by a second", path: shift(time.Second), expectedStatus: http.StatusForbidden},
+		{name: "shortened", path: shift(-time.Hour), expectedStatus: http.StatusForbidden},
+		{name: "extended after eviction", path: shift(24 * time.Hour), evict: true, expectedStatus: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.evict {
+				delete(cache.store, "image-auth:images/photo.jpg")
+			}
+
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			w := httptest.NewRecorder()
+			handler.HandleImage(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d: %s", tt.expectedStatus, w.Code, w.Body.String())
+			}
+			if tt.expectedStatus == http.StatusForbidden && !strings.Contains(w.Body.String(), "tampered") {
+				t.Errorf("expected a tampering error, got %s", w.Body.String())
+			}
+		})
+	}
+}
+
+func TestHandler_ShortLink(t *testing.T) {
+	handler, shareService := newTestHandler(map[string]mockObject{
+		"images/photo.jpg": {contentType: "image/jpeg", data: []byte("jpeg-bytes")},
+		"images/other.jpg": {contentType: "image/jpeg", data: []byte("other-bytes")},
+	})
+
+	createShort := func(t *testing.T, s3Path string) string {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodPost, "/api/shares", strings.NewReader(`{"s3_path":"`+s3Path+`","secret":"test-secret","expires_in":"1h","short":true}`))
+		w := httptest.NewRecorder()
+		handler.HandleCreateShare(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+		}
+		var resp CreateShareResponse
+		if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		if !strings.HasPrefix(resp.URL, "https://example.com/s/") || strings.Contains(resp.URL, s3Path) {
+			t.Fatalf("expected a short URL hiding the path, got %s", resp.URL)
+		}
+		return strings.TrimPrefix(resp.URL, "https://example.com")
+	}
+
+	photo := createShort(t, "images/photo.jpg")
+	revoked := createShort(t, "images/other.jpg")
+	if err := shareService.RevokeShare(context.Background(), "images/other.jpg"); err != nil {
+		t.Fatalf("failed to revoke share: %v", err)
+	}
+
+	tests := []struct {
+		name           string
+		path           string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{name: "valid code", path: photo, expectedStatus: http.StatusOK, expectedBody: "jpeg-bytes"},
+		{name: "revoked share", path: revoked, expectedStatus: http.StatusUnauthorized},
+		{name: "unknown code", path: "/s/aaaaaaaa", expectedStatus: http.StatusNotFound},
+		{name: "malformed code", path: "/s/not-a-code", expectedStatus: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			w := httptest.NewRecorder()
+			handler.HandleShortLink(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Fatalf("expected status %d, got %d: %s", tt.expectedStatus, w.Code, w.Body.String())
+			}
+			if tt.expectedBody != "" && w.Body.String() != tt.expectedBody {
+				t.Errorf("expected body %q, got %q", tt.expectedBody, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestHandler_PublicShare(t *testing.T) {
+	handler, shareService := newTestHandler(map[string]mockObject{
+		"images/photo.jpg": {contentType: "image/jpeg", data: []byte("jpeg-bytes")},
+		"photo.jpg":        {contentType: "image/jpeg", data: []byte("root-bytes")},
+		"private.jpg":      {contentType: "image/jpeg", data: []byte("private-bytes")},
+	})
+
+	createPublic := func(t *testing.T, s3Path string) string {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodPost, "/api/shares", strings.NewReader(`{"s3_path":"`+s3Path+`","public":true,"expires_in":"48h"}`))
+		w := httptest.NewRecorder()
+		handler.HandleCreateShare(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+		}
+		var resp CreateShareResponse
+		if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		return strings.TrimPrefix(resp.URL, "https://example.com")
+	}
+
+	nested := createPublic(t, "images/photo.jpg")
+	root := createPublic(t, "photo.jpg")
+	expiry := strings.Split(strings.Trim(nested, "/"), "/")[0]
+	if nested != "/"+expiry+"/-/images/photo.jpg" {
+		t.Errorf("expected URL with the public segment in place of the secret, got %s", nested)
+	}
+	createTestShare(t, shareService, "private.jpg")
+
+	tests := []struct {
+		name           string
+		path           string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{name: "nested public share", path: nested, expectedStatus: http.StatusOK, expectedBody: "jpeg-bytes"},
+		{name: "top-level public share", path: root, expectedStatus: http.StatusOK, expectedBody: "root-bytes"},
+		{name: "expired date", path: "/20/01/01/images/photo.jpg", expectedStatus: http.StatusForbidden},
+		{name: "expired timestamp", path: "/1577836800/-/images/photo.jpg", expectedStatus: http.StatusForbidden},
+		{name: "private share without secret", path: "/" + expiry + "/-/private.jpg", expectedStatus: http.StatusUnauthorized},
+		{name: "public share with a secret", path: "/" + expiry + "/test-secret/images/photo.jpg", expectedStatus: http.StatusOK, expectedBody: "jpeg-bytes"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			w := httptest.NewRecorder()
+			handler.HandleImage(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Fatalf("expected status %d, got %d: %s", tt.expectedStatus, w.Code, w.Body.String())
+			}
+			if tt.expectedBody != "" && w.Body.String() != tt.expectedBody {
+				t.Errorf("expected body %q, got %q", tt.expectedBody, w.Body.String())
+			}
+		})
+	}
+
+	t.Run("secret reserved for public links", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/api/shares", strings.NewReader(`{"s3_path":"private.jpg","secret":"-"}`))
+		w := httptest.NewRecorder()
+		handler.HandleCreateShare(w, req)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("expected status %d, got %d", http.StatusBadRequest, w.Code)
+		}
+	})
+
+	t.Run("secret still required for private shares", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/api/shares", strings.NewReader(`{"s3_path":"private.jpg"}`))
+		w := httptest.NewRecorder()
+		handler.HandleCreateShare(w, req)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("expected status %d, got %d", http.StatusBadRequest, w.Code)
+		}
+	})
+}
+
+func TestHandler_ShareRecordLoadedOnce(t *testing.T) {
+	storage := &mockStorageService{objects: map[string]mockObject{
+		"images/photo.jpg": {contentType: "image/jpeg", data: []byte("jpeg-bytes")},
+	}}
+	cache := &mockCacheService{store: make(map[string]string)}
+	handler, shareService := newTestHandlerWithMocks(storage, cache)
+	resp, err := shareService.CreateShare(context.Background(), &domain.ShareRequest{
+		S3Path:       "images/photo.jpg",
+		Secret:       "test-secret",
+		ExpiresAt:    time.Now().Add(time.Hour),
+		MaxDownloads: 2,
+		AllowedIPs:   []string{"192.0.2.0/24"},
+	})
+	if err != nil {
+		t.Fatalf("failed to create share: %v", err)
+	}
+
+	cache.gets = make(map[string]int)
+	req := httptest.NewRequest(http.MethodGet, strings.TrimPrefix(resp.URL, "https://example.com"), nil)
+	w := httptest.NewRecorder()
+	handler.HandleImage(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	if gets := cache.gets["image-auth:images/photo.jpg"]; gets != 1 {
+		t.Errorf("expected the share record to be loaded once, got %d loads", gets)
+	}
+}
+
+func TestHandler_RevokeShare(t *testing.T) {
+	handler, shareService := newTestHandler(map[string]mockObject{
+		"images/photo.jpg": {contentType: "image/jpeg", data: []byte("jpeg-bytes")},
+	})
+	path := createTestShare(t, shareService, "images/photo.jpg")
+
+	tests := []struct {
+		name           string
+		body           string
+		expectedStatus int
+	}{
+		{name: "existing share", body: `{"s3_path":"images/photo.jpg"}`, expectedStatus: http.StatusNoContent},
+		{name: "already revoked", body: `{"s3_path":"images/photo.jpg"}`, expectedStatus: http.StatusNotFound},
+		{name: "missing path", body: `{}`, expectedStatus: http.StatusBadRequest},
+		{name: "invalid path", body: `{"s3_path":"../etc/passwd"}`, expectedStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodDelete, "/api/shares", strings.NewReader(tt.body))
+			w := httptest.NewRecorder()
+
+			handler.HandleShares(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d: %s", tt.expectedStatus, w.Code, w.Body.String())
+			}
+		})
+	}
+
+	// The revoked link must no longer be served
+	req := httptest.NewRequest("GET", path, nil)
+	w := httptest.NewRecorder()
+	handler.HandleImage(w, req)
+
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("expected revoked link to return %d, got %d", http.StatusUnauthorized, w.Code)
+	}
+}
+
+func TestHandler_DeleteObject(t *testing.T) {
+	handler, shareService, storage := newTestHandlerWithStorage(map[string]mockObject{
+		"images/photo.jpg": {contentType: "image/jpeg", data: []byte("jpeg-bytes")},
+	})
+	path := createTestShare(t, shareService, "images/photo.jpg")
+
+	tests := []struct {
+		name           string
+		method         string
+		body           string
+		expectedStatus int
+	}{
+		{name: "wrong method", method: http.MethodPost, body: `{"s3_path":"images/photo.jpg"}`, expectedStatus: http.StatusMethodNotAllowed},
+		{name: "delete with share", method: http.MethodDelete, body: `{"s3_path":"images/photo.jpg"}`, expectedStatus: http.StatusNoContent},
+		{name: "delete nonexistent", method: http.MethodDelete, body: `{"s3_path":"images/photo.jpg"}`, expectedStatus: http.StatusNotFound},
+		{name: "missing path", method: http.MethodDelete, body: `{}`, expectedStatus: http.StatusBadRequest},
+		{name: "invalid path", method: http.MethodDelete, body: `{"s3_path":"../etc/passwd"}`, expectedStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/api/objects", strings.NewReader(tt.body))
+			w := httptest.NewRecorder()
+
+			handler.HandleDeleteObject(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d: %s", tt.expectedStatus, w.Code, w.Body.String())
+			}
+		})
+	}
+
+	if _, exists := storage.objects["images/photo.jpg"]; exists {
+		t.Error("expected object to be deleted")
+	}
+
+	// The share of the deleted object must be gone too
+	req := httptest.NewRequest(http.MethodGet, "/api/shares?s3_path=images/photo.jpg", nil)
+	w := httptest.NewRecorder()
+	handler.HandleShares(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected share info to return %d, got %d", http.StatusNotFound, w.Code)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, path, nil)
+	w = httptest.NewRecorder()
+	handler.HandleImage(w, req)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("expected deleted object link to return %d, got %d", http.StatusUnauthorized, w.Code)
+	}
+}
+
+func TestHandler_ListObjects(t *testing.T) {
+	handler, _ := newTestHandler(map[string]mockObject{
+		"images/a.jpg":    {data: []byte("a")},
+		"images/b.jpg":    {data: []byte("bb")},
+		"images/c.jpg":    {data: []byte("ccc")},
+		"docs/readme.txt": {data: []byte("readme")},
+	})
+
+	list := func(t *testing.T, query string) (int, ListObjectsResponse) {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, "/api/objects?"+query, nil)
+		w := httptest.NewRecorder()
+		handler.HandleObjects(w, req)
+
+		var resp ListObjectsResponse
+		if w.Code == http.StatusOK {
+			if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+		}
+		return w.Code, resp
+	}
+
+	t.Run("paginated prefix", func(t *testing.T) {
+		var keys []string
+		query := "prefix=images/"
+		for pages := 0; ; pages++ {
+			if pages > 2 {
+				t.Fatal("pagination did not terminate")
+			}
+			code, resp := list(t, query)
+			if code != http.StatusOK {
+				t.Fatalf("expected status %d, got %d", http.StatusOK, code)
+			}
+			for _, object := range resp.Objects {
+				keys = append(keys, object.Key)
+			}
+			if resp.NextToken == "" {
+				break
+			}
+			query = "prefix=images/&token=" + url.QueryEscape(resp.NextToken)
+		}
+
+		if strings.Join(keys, ",") != "images/a.jpg,images/b.jpg,images/c.jpg" {
+			t.Errorf("unexpected keys %v", keys)
+		}
+	})
+
+	t.Run("empty prefix lists everything", func(t *testing.T) {
+		code, resp := list(t, "")
+		if code != http.StatusOK || len(resp.Objects) != mockListPageSize || resp.NextToken == "" {
+			t.Errorf("expected a full first page, got %d %+v", code, resp)
+		}
+	})
+
+	t.Run("no matches", func(t *testing.T) {
+		code, resp := list(t, "prefix=videos/")
+		if code != http.StatusOK || resp.Objects == nil || len(resp.Objects) != 0 {
+			t.Errorf("expected an empty page, got %d %+v", code, resp)
+		}
+	})
+
+	t.Run("traversal prefix", func(t *testing.T) {
+		if code, _ := list(t, "prefix=../"); code != http.StatusBadRequest {
+			t.Errorf("expected status %d, got %d", http.StatusBadRequest, code)
+		}
+	})
+}
+
+func TestHandler_ShareInfo(t *testing.T) {
+	handler, shareService := newTestHandler(map[string]mockObject{
+		"images/photo.jpg": {contentType: "image/jpeg", data: []byte("jpeg-bytes")},
+	})
+	createTestShare(t, shareService, "images/photo.jpg")
+
+	t.Run("active share", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/api/shares?s3_path=images/photo.jpg", nil)
+		w := httptest.NewRecorder()
+
+		handler.HandleShares(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status %d, got %d", http.StatusOK, w.Code)
+		}
+
+		var resp ShareInfoResponse
+		if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		if resp.S3Path != "images/photo.jpg" || !resp.Active {
+			t.Errorf("unexpected share info: %+v", resp)
+		}
+		if resp.TTLSeconds <= 0 || resp.ExpiresAt.Before(time.Now()) {
+			t.Errorf("expected future expiry, got %+v", resp)
+		}
+	})
+
+	t.Run("missing share", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/api/shares?s3_path=images/other.jpg", nil)
+		w := httptest.NewRecorder()
+
+		handler.HandleShares(w, req)
+
+		if w.Code != http.StatusNotFound {
+			t.Errorf("expected status %d, got %d", http.StatusNotFound, w.Code)
+		}
+	})
+
+	t.Run("missing s3_path", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/api/shares", nil)
+		w := httptest.NewRecorder()
+
+		handler.HandleShares(w, req)
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("expected status %d, got %d", http.StatusBadRequest, w.Code)
+		}
+	})
+}
+
+func TestHandler_ShareUsage(t *testing.T) {
+	handler, shareService := newTestHandler(map[string]mockObject{
+		"images/photo.jpg": {contentType: "image/jpeg", data: []byte("jpeg-bytes")},
+	})
+	path := createTestShare(t, shareService, "images/photo.jpg")
+
+	shareInfo := func(t *testing.T) ShareInfoResponse {
+		t.Helper()
+		w := httptest.NewRecorder()
+		handler.HandleShares(w, httptest.NewRequest(http.MethodGet, "/api/shares?s3_path=images/photo.jpg", nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status %d, got %d", http.StatusOK, w.Code)
+		}
+		var resp ShareInfoResponse
+		if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		return resp
+	}
+
+	if resp := shareInfo(t); resp.DownloadCount != 0 || resp.LastAccessedAt != nil {
+		t.Errorf("expected no usage before the first download, got %d at %v", resp.DownloadCount, resp.LastAccessedAt)
+	}
+
+	before := time.Now()
+	for range 2 {
+		w := httptest.NewRecorder()
+		handler.HandleImage(w, httptest.NewRequest(http.MethodGet, path, nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status %d, got %d", http.StatusOK, w.Code)
+		}
+	}
+	// HEAD requests fetch no body and are not downloads
+	handler.HandleImage(httptest.NewRecorder(), httptest.NewRequest(http.MethodHead, path, nil))
+
+	resp := shareInfo(t)
+	if resp.DownloadCount != 2 {
+		t.Errorf("expected download count 2, got %d", resp.DownloadCount)
+	}
+	if resp.LastAccessedAt == nil || resp.LastAccessedAt.Before(before) || resp.LastAccessedAt.After(time.Now()) {
+		t.Errorf("expected a last access since %v, got %v", before, resp.LastAccessedAt)
+	}
+
+	createTestShare(t, shareService, "images/photo.jpg")
+	if resp := shareInfo(t); resp.DownloadCount != 0 || resp.LastAccessedAt != nil {
+		t.Errorf("expected a new share to reset usage, got %d at %v", resp.DownloadCount, resp.LastAccessedAt)
+	}
+}
+
+func TestHandler_ShareExists(t *testing.T) {
+	handler, shareService := newTestHandler(map[string]mockObject{
+		"images/photo.jpg": {contentType: "image/jpeg", data: []byte("jpeg-bytes")},
+	})
+	createTestShare(t, shareService, "images/photo.jpg")
+
+	t.Run("exists", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		handler.HandleShares(w, httptest.NewRequest(http.MethodHead, "/api/shares?s3_path=images/photo.jpg", nil))
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status %d, got %d", http.StatusOK, w.Code)
+		}
+		expiresAt, err := time.Parse(time.RFC3339, w.Header().Get("X-Share-Expires-At"))
+		if err != nil {
+			t.Fatalf("expected an RFC 3339 X-Share-Expires-At, got %q", w.Header().Get("X-Share-Expires-At"))
+		}
+		if until := time.Until(expiresAt); until < 71*time.Hour || until > 72*time.Hour {
+			t.Errorf("expected expiry in about 72h, got %v", expiresAt)
+		}
+		if w.Body.Len() != 0 {
+			t.Errorf("expected no body, got %q", w.Body.String())
+		}
+	})
+
+	t.Run("does not exist", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		handler.HandleShares(w, httptest.NewRequest(http.MethodHead, "/api/shares?s3_path=images/other.jpg", nil))
+
+		if w.Code != http.StatusNotFound {
+			t.Errorf("expected status %d, got %d", http.StatusNotFound, w.Code)
+		}
+		if header := w.Header().Get("X-Share-Expires-At"); header != "" {
+			t.Errorf("expected no X-Share-Expires-At, got %q", header)
+		}
+		if w.Body.Len() != 0 {
+			t.Errorf("expected no body, got %q", w.Body.String())
+		}
+	})
+
+	t.Run("missing s3_path", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		handler.HandleShares(w, httptest.NewRequest(http.MethodHead, "/api/shares", nil))
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("expected status %d, got %d", http.StatusBadRequest, w.Code)
+		}
+	})
+}
+
+func TestHandler_OneTimeShare(t *testing.T) {
+	storage := &mockStorageService{objects: map[string]mockObject{
+		"docs/secret.pdf": {contentType: "application/pdf", data: []byte("classified")},
+	}}
+	cache := service.NewMemoryCacheService()
+	defer cache.Close()
+	handler, shareService := newTestHandlerWithMocks(storage, cache)
+
+	resp, err := shareService.CreateShare(context.Background(), &domain.ShareRequest{
+		S3Path:       "docs/secret.pdf",
+		Secret:       "test-secret",
+		ExpiresAt:    time.Now().Add(72 * time.Hour),
+		MaxDownloads: 1,
+	})
+	if err != nil {
+		t.Fatalf("failed to create share: %v", err)
+	}
+	path := strings.TrimPrefix(resp.URL, "https://example.com")
+
+	// Two concurrent downloads race for the single permitted download
+	var wg sync.WaitGroup
+	codes := make([]int, 2)
+	for i := range codes {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			req := httptest.NewRequest("GET", path, nil)
+			w := httptest.NewRecorder()
+			handler.HandleImage(w, req)
+			codes[i] = w.Code
+		}(i)
+	}
+	wg.Wait()
+
+	succeeded := 0
+	for _, code := range codes {
+		switch code {
+		case http.StatusOK:
+			succeeded++
+		case http.StatusUnauthorized, http.StatusGone:
+		default:
+			t.Errorf("unexpected status %d", code)
+		}
+	}
+	if succeeded != 1 {
+		t.Errorf("expected exactly one successful download, got %d (%v)", succeeded, codes)
+	}
+
+	// The share is gone once consumed
+	req := httptest.NewRequest("GET", path, nil)
+	w := httptest.NewRecorder()
+	handler.HandleImage(w, req)
+
+	if w.Code != http.StatusGone {
+		t.Errorf("expected consumed share to return %d, got %d", http.StatusGone, w.Code)
+	}
+}
+
+func TestHandler_ExpiredAndConsumed(t *testing.T) {
+	handler, shareService := newTestHandler(map[string]mockObject{
+		"docs/report.pdf": {contentType: "application/pdf", data: []byte("report")},
+	})
+
+	resp, err := shareService.CreateShare(context.Background(), &domain.ShareRequest{
+		S3Path:       "docs/report.pdf",
+		Secret:       "test-secret",
+		ExpiresAt:    time.Now().Add(72 * time.Hour),
+		MaxDownloads: 1,
+	})
+	if err != nil {
+		t.Fatalf("failed to create share: %v", err)
+	}
+	path := strings.TrimPrefix(resp.URL, "https://example.com")
+
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	w := httptest.NewRecorder()
+	handler.HandleImage(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected the first download to succeed, got %d: %s", w.Code, w.Body.String())
+	}
+
+	recent := time.Now().Add(-time.Hour).Truncate(time.Second)
+	stale := time.Now().Add(-30 * 24 * time.Hour).Truncate(time.Second)
+	linkAt := func(expiresAt time.Time, secret string) string {
+		return fmt.Sprintf("/%d/%s/docs/report.pdf", expiresAt.Unix(), secret)
+	}
+
+	tests := []struct {
+		name              string
+		path              string
+		expectedStatus    int
+		expectedCode      string
+		expectedExpiredAt time.Time
+		expectedNearMiss  bool
+	}{
+		{
+			name:              "recently expired",
+			path:              linkAt(recent, "test-secret"),
+			expectedStatus:    http.StatusForbidden,
+			expectedCode:      CodeExpired,
+			expectedExpiredAt: recent,
+			expectedNearMiss:  true,
+		},
+		{
+			name:              "long expired",
+			path:              linkAt(stale, "test-secret"),
+			expectedStatus:    http.StatusForbidden,
+			expectedCode:      CodeExpired,
+			expectedExpiredAt: stale,
+		},
+		{
+			name:           "consumed",
+			path:           path,
+			expectedStatus: http.StatusGone,
+			expectedCode:   CodeConsumed,
+		},
+		{
+			name:           "consumed with a wrong secret",
+			path:           strings.Replace(path, "test-secret", "wrong-secret", 1),
+			expectedStatus: http.StatusUnauthorized,
+			expectedCode:   CodeUnauthorized,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			w := httptest.NewRecorder()
+			handler.HandleImage(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Fatalf("expected status %d, got %d: %s", tt.expectedStatus, w.Code, w.Body.String())
+			}
+			var body ErrorResponse
+			if err := json.NewDecoder(w.Body).Decode(&body); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if body.Code != tt.expectedCode {
+				t.Errorf("expected code %s, got %s", tt.expectedCode, body.Code)
+			}
+
+			header := w.Header().Get(expiredAtHeader)
+			if tt.expectedExpiredAt.IsZero() {
+				if header != "" {
+					t.Errorf("expected no %s header, got %s", expiredAtHeader, header)
+				}
+			} else if expected := tt.expectedExpiredAt.UTC().Format(http.TimeFormat); header != expected {
+				t.Errorf("expected %s %s, got %s", expiredAtHeader, expected, header)
+			}
+
+			switch {
+			case tt.expectedNearMiss && (body.ExpiredAt == nil || !body.ExpiredAt.Equal(tt.expectedExpiredAt)):
+				t.Errorf("expected expired_at %v, got %v", tt.expectedExpiredAt, body.ExpiredAt)
+			case !tt.expectedNearMiss && body.ExpiredAt != nil:
+				t.Errorf("expected no expired_at, got %v", body.ExpiredAt)
+			}
+		})
+	}
+}
+
+func TestHandler_CreateShare(t *testing.T) {
+	handler, _ := newTestHandler(map[string]mockObject{
+		"images/photo.jpg": {contentType: "image/jpeg", data: []byte("jpeg-bytes")},
+	})
+
+	tests := []struct {
+		name           string
+		body           string
+		expectedStatus int
+	}{
+		{
+			name:           "default expiry",
+			body:           `{"s3_path":"images/photo.jpg","secret":"test-secret"}`,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "expiry beyond max age",
+			body:           `{"s3_path":"images/photo.jpg","secret":"test-secret","expires_at":"` + time.Now().AddDate(1, 0, 0).Format(time.RFC3339) + `"}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "expiry in the past",
+			body:           `{"s3_path":"images/photo.jpg","secret":"test-secret","expires_at":"` + time.Now().Add(-time.Hour).Format(time.RFC3339) + `"}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "missing object",
+			body:           `{"s3_path":"images/missing.jpg","secret":"test-secret"}`,
+			expectedStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/api/shares", strings.NewReader(tt.body))
+			w := httptest.NewRecorder()
+
+			handler.HandleShares(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d: %s", tt.expectedStatus, w.Code, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestHandler_CreateShareVerbose(t *testing.T) {
+	storage := &mockStorageService{objects: map[string]mockObject{
+		"images/photo.jpg": {contentType: "image/jpeg", data: []byte("jpeg-bytes")},
+	}}
+	cache := &mockCacheService{store: make(map[string]string)}
+	signed := service.NewShareService(storage, cache, &service.ShareConfig{
+		MaxAgeDays: 90,
+		BaseURL:    "https://example.com",
+		SigningKey: "test-signing-key",
+		SignedURLs: true,
+	})
+	plain, plainService := newTestHandlerWithMocks(storage, cache)
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	tests := []struct {
+		name         string
+		handler      *Handler
+		shareService *service.ShareService
+		body         string
+		expectedCurl string
+	}{
+		{
+			name:         "caller secret",
+			handler:      plain,
+			shareService: plainService,
+			body:         `{"s3_path":"images/photo.jpg","secret":"test-secret"}`,
+			expectedCurl: "curl -fL -o 'photo.jpg' ",
+		},
+		{
+			name:         "signed token",
+			handler:      NewHandler(signed, logger),
+			shareService: signed,
+			body:         `{"s3_path":"images/photo.jpg","secret":"test-secret"}`,
+			expectedCurl: "curl -fL -o 'photo.jpg' ",
+		},
+		{
+			name:         "password",
+			handler:      plain,
+			shareService: plainService,
+			body:         `{"s3_path":"images/photo.jpg","secret":"test-secret","password":"hunter2"}`,
+			expectedCurl: `curl -fL -H "X-Share-Password: $SHARE_PASSWORD" -o 'photo.jpg' `,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/api/shares?verbose=true", strings.NewReader(tt.body))
+			w := httptest.NewRecorder()
+			tt.handler.HandleShares(w, req)
+
+			if w.Code != http.StatusOK {
+				t.Fatalf("expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+			}
+			var resp CreateShareResponse
+			if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+
+			// The secret is the segment of the URL that ValidateShare checks
+			parts := strings.SplitN(strings.TrimPrefix(resp.URL, "https://example.com/"), "/", 3)
+			if resp.Secret == "" || parts[1] != resp.Secret {
+				t.Fatalf("expected secret %q to match the URL %s", resp.Secret, resp.URL)
+			}
+			if err := tt.shareService.ValidateShare(context.Background(), "images/photo.jpg", resp.Secret, resp.ExpiresAt); err != nil {
+				t.Errorf("expected the secret to validate, got %v", err)
+			}
+
+			if expected := tt.expectedCurl + "'" + resp.URL + "'"; resp.Curl != expected {
+				t.Errorf("expected curl %q, got %q", expected, resp.Curl)
+			}
+		})
+	}
+
+	t.Run("omitted by default", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/api/shares", strings.NewReader(`{"s3_path":"images/photo.jpg","secret":"test-secret"}`))
+		w := httptest.NewRecorder()
+		plain.HandleShares(w, req)
+
+		if body := w.Body.String(); strings.Contains(body, `"secret"`) || strings.Contains(body, `"curl"`) {
+			t.Errorf("expected no secret or curl fields, got %s", body)
+		}
+	})
+}
+
+func TestShellQuote(t *testing.T) {
+	if got := shellQuote("it's"); got != `'it'\''s'` {
+		t.Errorf("expected quoted word, got %s", got)
+	}
+}
+
+func TestHandler_CreateShareForbiddenPath(t *testing.T) {
+	storage := &mockStorageService{objects: map[string]mockObject{
+		"public/photo.jpg":    {contentType: "image/jpeg", data: []byte("jpeg-bytes")},
+		"internal/report.pdf": {contentType: "application/pdf", data: []byte("pdf-bytes")},
+	}}
+	shareService := service.NewShareService(storage, &mockCacheService{store: make(map[string]string)}, &service.ShareConfig{
+		MaxAgeDays:        90,
+		BaseURL:           "https://example.com",
+		ShareablePrefixes: []string{"public/"},
+	})
+	handler := NewHandler(shareService, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	tests := []struct {
+		name           string
+		s3Path         string
+		expectedStatus int
+		expectedCode   string
+	}{
+		{name: "allowed prefix", s3Path: "public/photo.jpg", expectedStatus: http.StatusOK},
+		{name: "disallowed prefix", s3Path: "internal/report.pdf", expectedStatus: http.StatusForbidden, expectedCode: CodeForbiddenPath},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := `{"s3_path":"` + tt.s3Path + `","secret":"test-secret"}`
+			req := httptest.NewRequest(http.MethodPost, "/api/shares", strings.NewReader(body))
+			w := httptest.NewRecorder()
+
+			handler.HandleShares(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Fatalf("expected status %d, got %d: %s", tt.expectedStatus, w.Code, w.Body.String())
+			}
+			if tt.expectedCode == "" {
+				return
+			}
+			var resp ErrorResponse
+			if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if resp.Code != tt.expectedCode {
+				t.Errorf("expected code %s, got %s", tt.expectedCode, resp.Code)
+			}
+		})
+	}
+}
+
+func TestHandler_CreateShareIdempotency(t *testing.T) {
+	storage := &mockStorageService{objects: map[string]mockObject{
+		"images/photo.jpg": {contentType: "image/jpeg", data: []byte("jpeg-bytes")},
+	}}
+	shareService := service.NewShareService(storage, &mockCacheService{store: make(map[string]string)}, &service.ShareConfig{
+		MaxAgeDays:        90,
+		BaseURL:           "https://example.com",
+		IdempotencyWindow: time.Hour,
+	})
+	handler := NewHandler(shareService, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	create := func(caller, key, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/shares", strings.NewReader(body))
+		if key != "" {
+			req.Header.Set(idempotencyKeyHeader, key)
+		}
+		if caller != "" {
+			req = withCaller(req, caller)
+		}
+		w := httptest.NewRecorder()
+		handler.HandleShares(w, req)
+		return w
+	}
+
+	body := `{"s3_path":"images/photo.jpg","secret":"test-secret","expires_in":"1h"}`
+	first := create("", "retry-1", body)
+	if first.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, first.Code, first.Body.String())
+	}
+
+	tests := []struct {
+		name             string
+		caller           string
+		key              string
+		body             string
+		expectedStatus   int
+		expectedReplayed bool
+		expectedFirst    bool
+	}{
+		{name: "repeated key", key: "retry-1", body: body, expectedStatus: http.StatusOK, expectedReplayed: true, expectedFirst: true},
+		{name: "repeated key with reformatted body", key: "retry-1", body: `{"expires_in": "1h", "secret": "test-secret", "s3_path": "images/photo.jpg"}`, expectedStatus: http.StatusOK, expectedReplayed: true, expectedFirst: true},
+		{name: "different key", key: "retry-2", body: body, expectedStatus: http.StatusOK},
+		{name: "no key", body: body, expectedStatus: http.StatusOK},
+		{name: "key of another caller", caller: "api-key:other", key: "retry-1", body: body, expectedStatus: http.StatusOK},
+		{name: "key reused for another path", key: "retry-1", body: `{"s3_path":"images/other.jpg","secret":"test-secret"}`, expectedStatus: http.StatusUnprocessableEntity},
+		{name: "key reused with another secret", key: "retry-1", body: `{"s3_path":"images/photo.jpg","secret":"other-secret","expires_in":"1h"}`, expectedStatus: http.StatusUnprocessableEntity},
+		{name: "key too long", key: strings.Repeat("k", maxIdempotencyKeyLength+1), body: body, expectedStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Let new shares get a different expiry than the first
+			time.Sleep(time.Millisecond)
+			w := create(tt.caller, tt.key, tt.body)
+			if w.Code != tt.expectedStatus {
+				t.Fatalf("expected status %d, got %d: %s", tt.expectedStatus, w.Code, w.Body.String())
+			}
+			if replayed := w.Header().Get(idempotentReplayedHeader) == "true"; replayed != tt.expectedReplayed {
+				t.Errorf("expected replayed %v, got %v", tt.expectedReplayed, replayed)
+			}
+			if w.Code == http.StatusOK {
+				if same := w.Body.String() == first.Body.String(); same != tt.expectedFirst {
+					t.Errorf("expected identical response %v, got %s and %s", tt.expectedFirst, first.Body.String(), w.Body.String())
+				}
+			}
+		})
+	}
+}
+
+func TestHandler_CreateShareExpiry(t *testing.T) {
+	handler, _ := newTestHandler(map[string]mockObject{
+		"images/photo.jpg": {contentType: "image/jpeg", data: []byte("jpeg-bytes")},
+	})
+	expiresAt := time.Now().Add(48 * time.Hour).UTC().Truncate(time.Second)
+
+	tests := []struct {
+		name           string
+		fields         string
+		expectedStatus int
+		expectedExpiry time.Duration
+		exactExpiry    time.Time
+	}{
+		{
+			name:           "neither defaults to 24h",
+			expectedStatus: http.StatusOK,
+			expectedExpiry: 24 * time.Hour,
+		},
+		{
+			name:           "expires_in only",
+			fields:         `,"expires_in":"2h"`,
+			expectedStatus: http.StatusOK,
+			expectedExpiry: 2 * time.Hour,
+		},
+		{
+			name:           "expires_at only",
+			fields:         `,"expires_at":"` + expiresAt.Format(time.RFC3339) + `"`,
+			expectedStatus: http.StatusOK,
+			exactExpiry:    expiresAt,
+		},
+		{
+			name:           "expires_at wins over expires_in",
+			fields:         `,"expires_at":"` + expiresAt.Format(time.RFC3339) + `","expires_in":"2h"`,
+			expectedStatus: http.StatusOK,
+			exactExpiry:    expiresAt,
+		},
+		{
+			name:           "malformed expires_in",
+			fields:         `,"expires_in":"tomorrow"`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "negative expires_in",
+			fields:         `,"expires_in":"-1h"`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "expires_in beyond max age",
+			fields:         `,"expires_in":"2400h"`,
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := `{"s3_path":"images/photo.jpg","secret":"test-secret"` + tt.fields + `}`
+			req := httptest.NewRequest(http.MethodPost, "/api/shares", strings.NewReader(body))
+			w := httptest.NewRecorder()
+
+			before := time.Now()
+			handler.HandleShares(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Fatalf("expected status %d, got %d: %s", tt.expectedStatus, w.Code, w.Body.String())
+			}
+			if w.Code != http.StatusOK {
+				return
+			}
+
+			var resp CreateShareResponse
+			if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+
+			if !tt.exactExpiry.IsZero() {
+				if !resp.ExpiresAt.Equal(tt.exactExpiry) {
+					t.Errorf("expected expires at %v, got %v", tt.exactExpiry, resp.ExpiresAt)
+				}
+				return
+			}
+			if got := resp.ExpiresAt.Sub(before); got < tt.expectedExpiry || got > tt.expectedExpiry+time.Minute {
+				t.Errorf("expected expiry about %v from now, got %v", tt.expectedExpiry, got)
+			}
+		})
+	}
+}
+
+// mockPresigningStorage is a mockStorageService that also presigns URLs
+type mockPresigningStorage struct {
+	*mockStorageService
+}
+
+func (m *mockPresigningStorage) PresignGetObject(ctx context.Context, key string, expiry time.Duration) (string, error) {
+	return "https://bucket.s3.example.com/" + key + "?X-Amz-Expires=" + strconv.Itoa(int(expiry.Seconds())), nil
+}
+
+func (m *mockPresigningStorage) PresignPutObject(ctx context.Context, key string, expiry time.Duration) (string, error) {
+	return "https://bucket.s3.example.com/" + key + "?X-Amz-Expires=" + strconv.Itoa(int(expiry.Seconds())) + "&upload=true", nil
+}
+
+func TestHandler_RedirectMode(t *testing.T) {
+	storage := &mockPresigningStorage{mockStorageService: &mockStorageService{objects: map[string]mockObject{
+		"videos/big.mp4": {contentType: "video/mp4", data: []byte("video-bytes")},
+	}}}
+	shareService := service.NewShareService(storage, &mockCacheService{store: make(map[string]string)}, &service.ShareConfig{
+		MaxAgeDays: 90,
+		BaseURL:    "https://example.com",
+	})
+	handler := NewHandler(shareService, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	body := `{"s3_path":"videos/big.mp4","secret":"test-secret","expires_in":"48h","max_downloads":1,"mode":"redirect"}`
+	req := httptest.NewRequest(http.MethodPost, "/api/shares", strings.NewReader(body))
+	w := httptest.NewRecorder()
+	handler.HandleShares(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+
+	var created CreateShareResponse
+	if err := json.NewDecoder(w.Body).Decode(&created); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	urlPath := strings.TrimPrefix(created.URL, "https://example.com")
+
+	// HEAD redirects without consuming a download
+	req = httptest.NewRequest(http.MethodHead, urlPath, nil)
+	w = httptest.NewRecorder()
+	handler.HandleImage(w, req)
+	if w.Code != http.StatusFound {
+		t.Fatalf("expected HEAD status %d, got %d", http.StatusFound, w.Code)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, urlPath, nil)
+	w = httptest.NewRecorder()
+	handler.HandleImage(w, req)
+
+	if w.Code != http.StatusFound {
+		t.Fatalf("expected status %d, got %d", http.StatusFound, w.Code)
+	}
+	location, err := url.Parse(w.Header().Get("Location"))
+	if err != nil || location.Host != "bucket.s3.example.com" || location.Path != "/videos/big.mp4" {
+		t.Fatalf("unexpected Location %q", w.Header().Get("Location"))
+	}
+	expires, _ := strconv.Atoi(location.Query().Get("X-Amz-Expires"))
+	if expires <= 0 || expires > 48*3600 {
+		t.Errorf("expected presign expiry clamped to the share lifetime, got %ds", expires)
+	}
+	if got := w.Header().Get("Cache-Control"); got != "no-store" {
+		t.Errorf("expected Cache-Control no-store, got %q", got)
+	}
+	if storage.getCalls.Load() != 0 {
+		t.Errorf("expected the object not to be proxied, got %d storage reads", storage.getCalls.Load())
+	}
+
+	// The redirect counted against max_downloads
+	req = httptest.NewRequest(http.MethodGet, urlPath, nil)
+	w = httptest.NewRecorder()
+	handler.HandleImage(w, req)
+	if w.Code != http.StatusGone {
+		t.Errorf("expected status %d after the last download, got %d", http.StatusGone, w.Code)
+	}
+}
+
+func TestHandler_CreateShareMode(t *testing.T) {
+	handler, _ := newTestHandler(map[string]mockObject{
+		"images/photo.jpg": {contentType: "image/jpeg", data: []byte("jpeg-bytes")},
+	})
+
+	tests := []struct {
+		name           string
+		mode           string
+		expectedStatus int
+	}{
+		{name: "proxy", mode: "proxy", expectedStatus: http.StatusOK},
+		{name: "redirect without presigning storage", mode: "redirect", expectedStatus: http.StatusBadRequest},
+		{name: "unknown mode", mode: "teleport", expectedStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := `{"s3_path":"images/photo.jpg","secret":"test-secret","mode":"` + tt.mode + `"}`
+			req := httptest.NewRequest(http.MethodPost, "/api/shares", strings.NewReader(body))
+			w := httptest.NewRecorder()
+			handler.HandleShares(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d: %s", tt.expectedStatus, w.Code, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestHandler_Upload(t *testing.T) {
+	storage := &mockPresigningStorage{mockStorageService: &mockStorageService{objects: map[string]mockObject{}}}
+	shareService := service.NewShareService(storage, &mockCacheService{store: make(map[string]string)}, &service.ShareConfig{
+		MaxAgeDays: 90,
+		BaseURL:    "https://example.com",
+	})
+	handler := NewHandler(shareService, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	tests := []struct {
+		name           string
+		s3Path         string
+		expectedStatus int
+	}{
+		{name: "upload", s3Path: "uploads/report.pdf", expectedStatus: http.StatusOK},
+		{name: "path traversal", s3Path: "../etc/passwd", expectedStatus: http.StatusBadRequest},
+		{name: "nested traversal", s3Path: "uploads/../../etc/passwd", expectedStatus: http.StatusBadRequest},
+		{name: "absolute path", s3Path: "/etc/passwd", expectedStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := `{"s3_path":"` + tt.s3Path + `","secret":"test-secret","expires_in":"48h"}`
+			req := httptest.NewRequest(http.MethodPost, "/api/uploads", strings.NewReader(body))
+			w := httptest.NewRecorder()
+			handler.HandleUpload(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Fatalf("expected status %d, got %d: %s", tt.expectedStatus, w.Code, w.Body.String())
+			}
+			if w.Code != http.StatusOK {
+				return
+			}
+
+			var resp UploadResponse
+			if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if resp.UploadMethod != http.MethodPut {
+				t.Errorf("expected upload method PUT, got %s", resp.UploadMethod)
+			}
+			uploadURL, err := url.Parse(resp.UploadURL)
+			if err != nil || uploadURL.Path != "/"+tt.s3Path || uploadURL.Query().Get("upload") != "true" {
+				t.Errorf("unexpected upload URL %q", resp.UploadURL)
+			}
+			if !resp.UploadExpiresAt.Before(resp.ExpiresAt) {
+				t.Errorf("expected upload to expire before the share")
+			}
+
+			// Once uploaded, the object is served through the share URL
+			storage.objects[tt.s3Path] = mockObject{contentType: "application/pdf", data: []byte("pdf-bytes")}
+			req = httptest.NewRequest(http.MethodGet, strings.TrimPrefix(resp.ShareURL, "https://example.com"), nil)
+			w = httptest.NewRecorder()
+			handler.HandleImage(w, req)
+			if w.Code != http.StatusOK || w.Body.String() != "pdf-bytes" {
+				t.Errorf("expected uploaded object to be shared, got %d %q", w.Code, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestHandler_UploadUnsupportedStorage(t *testing.T) {
+	handler, _ := newTestHandler(map[string]mockObject{})
+
+	req := httptest.NewRequest(http.MethodPost, "/api/uploads", strings.NewReader(`{"s3_path":"uploads/report.pdf","secret":"test-secret"}`))
+	w := httptest.NewRecorder()
+	handler.HandleUpload(w, req)
+
+	if w.Code != http.StatusNotImplemented {
+		t.Errorf("expected status %d, got %d", http.StatusNotImplemented, w.Code)
+	}
+}
+
+// unavailableStorage is a mockStorageService whose reads fail as if a
+// circuit breaker were open
+type unavailableStorage struct {
+	*mockStorageService
+}
+
+func (u *unavailableStorage) GetObject(ctx context.Context, key string) (domain.ObjectReader, error) {
+	return nil, domain.ErrStorageUnavailable
+}
+
+func (u *unavailableStorage) HeadObject(ctx context.Context, key string) (*domain.ObjectMetadata, error) {
+	return nil, domain.ErrStorageUnavailable
+}
+
+func TestHandler_StorageUnavailable(t *testing.T) {
+	cache := &mockCacheService{store: make(map[string]string)}
+	healthy, shareService := newTestHandlerWithMocks(&mockStorageService{objects: map[string]mockObject{
+		"images/photo.jpg": {contentType: "image/jpeg", data: []byte("photo")},
+	}}, cache)
+	path := createTestShare(t, shareService, "images/photo.jpg")
+
+	unavailable := service.NewShareService(&unavailableStorage{mockStorageService: &mockStorageService{}}, cache, &service.ShareConfig{
+		MaxAgeDays: 90,
+		BaseURL:    "https://example.com",
+	})
+	handler := NewHandler(unavailable, healthy.logger)
+
+	t.Run("download", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		handler.HandleImage(w, httptest.NewRequest(http.MethodGet, path, nil))
+		if w.Code != http.StatusServiceUnavailable {
+			t.Errorf("expected status %d, got %d", http.StatusServiceUnavailable, w.Code)
+		}
+	})
+
+	t.Run("create share", func(t *testing.T) {
+		body, _ := json.Marshal(CreateShareRequest{S3Path: "images/photo.jpg", Secret: "test-secret", ExpiresIn: "1h"})
+		w := httptest.NewRecorder()
+		handler.HandleCreateShare(w, httptest.NewRequest(http.MethodPost, "/api/shares", bytes.NewReader(body)))
+		if w.Code != http.StatusServiceUnavailable {
+			t.Errorf("expected status %d, got %d", http.StatusServiceUnavailable, w.Code)
+		}
+	})
+}
+
+// mockAuditSink keeps audit entries in memory, newest first
+type mockAuditSink struct {
+	entries map[string][]domain.AuditEntry
+}
+
+func (m *mockAuditSink) Append(ctx context.Context, key string, entry *domain.AuditEntry) error {
+	m.entries[key] = append([]domain.AuditEntry{*entry}, m.entries[key]...)
+	return nil
+}
+
+func (m *mockAuditSink) Recent(ctx context.Context, key string, limit int) ([]domain.AuditEntry, error) {
+	entries := m.entries[key]
+	return entries[:min(limit, len(entries))], nil
+}
+
+func TestHandler_ShareAudit(t *testing.T) {
+	storage := &mockStorageService{objects: map[string]mockObject{
+		"images/photo.jpg": {contentType: "image/jpeg", data: []byte("jpeg-bytes")},
+	}}
+	shareService := service.NewShareService(storage, &mockCacheService{store: make(map[string]string)}, &service.ShareConfig{
+		MaxAgeDays: 90,
+		BaseURL:    "https://example.com",
+		Audit:      &mockAuditSink{entries: make(map[string][]domain.AuditEntry)},
+	})
+	handler := NewHandler(shareService, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	path := createTestShare(t, shareService, "images/photo.jpg")
+
+	accesses := []struct {
+		path      string
+		userAgent string
+	}{
+		{path: path, userAgent: "browser"},
+		{path: strings.Replace(path, "test-secret", "wrong-secret", 1), userAgent: "scanner"},
+	}
+	for _, access := range accesses {
+		req := httptest.NewRequest(http.MethodGet, access.path, nil)
+		req.Header.Set("User-Agent", access.userAgent)
+		req.RemoteAddr = "203.0.113.7:4321"
+		handler.HandleImage(httptest.NewRecorder(), req)
+	}
+
+	tests := []struct {
+		name           string
+		query          string
+		expectedStatus int
+		expectedAgents []string
+		expectedCodes  []int
+	}{
+		{
+			name:           "newest first",
+			query:          "?s3_path=images/photo.jpg",
+			expectedStatus: http.StatusOK,
+			expectedAgents: []string{"scanner", "browser"},
+			expectedCodes:  []int{http.StatusUnauthorized, http.StatusOK},
+		},
+		{
+			name:           "limit",
+			query:          "?s3_path=images/photo.jpg&limit=1",
+			expectedStatus: http.StatusOK,
+			expectedAgents: []string{"scanner"},
+			expectedCodes:  []int{http.StatusUnauthorized},
+		},
+		{
+			name:           "path without accesses",
+			query:          "?s3_path=images/other.jpg",
+			expectedStatus: http.StatusOK,
+		},
+		{name: "missing path", query: "", expectedStatus: http.StatusBadRequest},
+		{name: "invalid limit", query: "?s3_path=images/photo.jpg&limit=0", expectedStatus: http.StatusBadRequest},
+		{name: "invalid path", query: "?s3_path=../etc/passwd", expectedStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			handler.HandleShareAudit(w, httptest.NewRequest(http.MethodGet, "/api/shares/audit"+tt.query, nil))
+			if w.Code != tt.expectedStatus {
+				t.Fatalf("expected status %d, got %d: %s", tt.expectedStatus, w.Code, w.Body.String())
+			}
+			if tt.expectedStatus != http.StatusOK {
+				return
+			}
+
+			var resp AuditResponse
+			if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if len(resp.Entries) != len(tt.expectedAgents) {
+				t.Fatalf("expected %d entries, got %d", len(tt.expectedAgents), len(resp.Entries))
+			}
+			for i, entry := range resp.Entries {
+				if entry.UserAgent != tt.expectedAgents[i] {
+					t.Errorf("expected entry %d user agent %q, got %q", i, tt.expectedAgents[i], entry.UserAgent)
+				}
+				if entry.Status != tt.expectedCodes[i] {
+					t.Errorf("expected entry %d status %d, got %d", i, tt.expectedCodes[i], entry.Status)
+				}
+				if entry.ClientIP != "203.0.113.7" {
+					t.Errorf("expected entry %d client IP 203.0.113.7, got %q", i, entry.ClientIP)
+				}
+				if entry.S3Path != "images/photo.jpg" || entry.Timestamp.IsZero() {
+					t.Errorf("expected entry %d for images/photo.jpg with a timestamp, got %+v", i, entry)
+				}
+			}
+		})
+	}
+}
+
+func TestHandler_ShareAuditDisabled(t *testing.T) {
+	handler, _ := newTestHandler(map[string]mockObject{})
+
+	w := httptest.NewRecorder()
+	handler.HandleShareAudit(w, httptest.NewRequest(http.MethodGet, "/api/shares/audit?s3_path=images/photo.jpg", nil))
+	if w.Code != http.StatusNotImplemented {
+		t.Errorf("expected status %d, got %d", http.StatusNotImplemented, w.Code)
+	}
+}
+
+func TestHandler_ShareWebhook(t *testing.T) {
+	events := make(chan domain.WebhookEvent, 2)
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var event domain.WebhookEvent
+		if err := json.NewDecoder(r.Body).Decode(&event); err != nil {
+			t.Errorf("failed to decode event: %v", err)
+		}
+		events <- event
+	}))
+	defer webhook.Close()
+
+	storage := &mockStorageService{objects: map[string]mockObject{
+		"images/photo.jpg": {contentType: "image/jpeg", data: []byte("jpeg-bytes")},
+	}}
+	shareService := service.NewShareService(storage, &mockCacheService{store: make(map[string]string)}, &service.ShareConfig{
+		MaxAgeDays: 90,
+		BaseURL:    "https://example.com",
+		Webhooks:   service.NewWebhookSender(time.Second, 1),
+	})
+	handler := NewHandler(shareService, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	body, _ := json.Marshal(CreateShareRequest{
+		S3Path:     "images/photo.jpg",
+		Secret:     "test-secret",
+		ExpiresIn:  "1h",
+		WebhookURL: webhook.URL,
+	})
+	w := httptest.NewRecorder()
+	handler.HandleCreateShare(w, httptest.NewRequest(http.MethodPost, "/api/shares", bytes.NewReader(body)))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	var created CreateShareResponse
+	if err := json.NewDecoder(w.Body).Decode(&created); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	path := strings.TrimPrefix(created.URL, "https://example.com")
+
+	download := func(path string) int {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.RemoteAddr = "203.0.113.7:4321"
+		w := httptest.NewRecorder()
+		handler.HandleImage(w, req)
+		return w.Code
+	}
+
+	// Denied accesses do not count as the first access
+	if code := download(strings.Replace(path, "test-secret", "wrong-secret", 1)); code != http.StatusUnauthorized {
+		t.Fatalf("expected status %d, got %d", http.StatusUnauthorized, code)
+	}
+	select {
+	case event := <-events:
+		t.Fatalf("expected no event for a denied access, got %+v", event)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	before := time.Now()
+	if code := download(path); code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, code)
+	}
+	select {
+	case event := <-events:
+		if event.S3Path != "images/photo.jpg" {
+			t.Errorf("expected event for images/photo.jpg, got %q", event.S3Path)
+		}
+		if event.ClientIP != "203.0.113.7" {
+			t.Errorf("expected client IP 203.0.113.7, got %q", event.ClientIP)
+		}
+		if event.AccessedAt.Before(before.Add(-time.Second)) || event.AccessedAt.After(time.Now()) {
+			t.Errorf("expected the access time, got %v", event.AccessedAt)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected the first access to deliver an event")
+	}
+
+	// Only the first successful access is delivered
+	if code := download(path); code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, code)
+	}
+	select {
+	case event := <-events:
+		t.Errorf("expected no event for a later access, got %+v", event)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+// newTrackedHandler creates a handler over objects whose share service audits
+// every access and posts first-access webhooks to the returned event channel
+func newTrackedHandler(t *testing.T, objects map[string]mockObject) (*Handler, *service.ShareService, string, <-chan domain.WebhookEvent) {
+	t.Helper()
+
+	events := make(chan domain.WebhookEvent, 2)
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var event domain.WebhookEvent
+		if err := json.NewDecoder(r.Body).Decode(&event); err != nil {
+			t.Errorf("failed to decode event: %v", err)
+		}
+		events <- event
+	}))
+	t.Cleanup(webhook.Close)
+
+	shareService := service.NewShareService(&mockStorageService{objects: objects}, &mockCacheService{store: make(map[string]string)}, &service.ShareConfig{
+		MaxAgeDays: 90,
+		BaseURL:    "https://example.com",
+		Webhooks:   service.NewWebhookSender(time.Second, 1),
+		Audit:      &mockAuditSink{entries: make(map[string][]domain.AuditEntry)},
+	})
+	return NewHandler(shareService, slog.New(slog.NewTextHandler(io.Discard, nil))), shareService, webhook.URL, events
+}
+
+// expectTracked checks that s3Path was audited with statuses, newest first,
+// and that its first access delivered exactly one webhook event
+func expectTracked(t *testing.T, shareService *service.ShareService, events <-chan domain.WebhookEvent, s3Path string, statuses ...int) {
+	t.Helper()
+
+	select {
+	case event := <-events:
+		if event.S3Path != s3Path {
+			t.Errorf("expected event for %s, got %q", s3Path, event.S3Path)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected the first access to deliver an event")
+	}
+	select {
+	case event := <-events:
+		t.Errorf("expected no event for a later access, got %+v", event)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	entries, err := shareService.AuditLog(context.Background(), s3Path, 10)
+	if err != nil {
+		t.Fatalf("failed to read audit log: %v", err)
+	}
+	var got []int
+	for _, entry := range entries {
+		got = append(got, entry.Status)
+	}
+	if !slices.Equal(got, statuses) {
+		t.Errorf("expected audited statuses %v, got %v", statuses, got)
+	}
+}
+
+func TestHandler_ShortLinkTracked(t *testing.T) {
+	handler, shareService, webhookURL, events := newTrackedHandler(t, map[string]mockObject{
+		"images/photo.jpg": {contentType: "image/jpeg", data: []byte("jpeg-bytes")},
+	})
+	resp, err := shareService.CreateShare(context.Background(), &domain.ShareRequest{
+		S3Path:     "images/photo.jpg",
+		Secret:     "test-secret",
+		ExpiresAt:  time.Now().Add(time.Hour),
+		Short:      true,
+		WebhookURL: webhookURL,
+	})
+	if err != nil {
+		t.Fatalf("failed to create share: %v", err)
+	}
+	path := strings.TrimPrefix(resp.URL, "https://example.com")
+
+	for range 2 {
+		w := httptest.NewRecorder()
+		handler.HandleShortLink(w, httptest.NewRequest(http.MethodGet, path, nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+		}
+	}
+
+	expectTracked(t, shareService, events, "images/photo.jpg", http.StatusOK, http.StatusOK)
+}
+
+func TestHandler_ShareWebhookFailure(t *testing.T) {
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer webhook.Close()
+
+	storage := &mockStorageService{objects: map[string]mockObject{
+		"images/photo.jpg": {contentType: "image/jpeg", data: []byte("jpeg-bytes")},
+	}}
+	shareService := service.NewShareService(storage, &mockCacheService{store: make(map[string]string)}, &service.ShareConfig{
+		MaxAgeDays: 90,
+		BaseURL:    "https://example.com",
+		Webhooks:   service.NewWebhookSender(time.Second, 1),
+	})
+	handler := NewHandler(shareService, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	resp, err := shareService.CreateShare(context.Background(), &domain.ShareRequest{
+		S3Path:     "images/photo.jpg",
+		Secret:     "test-secret",
+		ExpiresAt:  time.Now().Add(time.Hour),
+		WebhookURL: webhook.URL,
+	})
+	if err != nil {
+		t.Fatalf("failed to create share: %v", err)
+	}
+
+	w := httptest.NewRecorder()
+	handler.HandleImage(w, httptest.NewRequest(http.MethodGet, strings.TrimPrefix(resp.URL, "https://example.com"), nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("expected status %d despite the failing webhook, got %d", http.StatusOK, w.Code)
+	}
+	if w.Body.String() != "jpeg-bytes" {
+		t.Errorf("expected the object body, got %q", w.Body.String())
+	}
+}
+
+func TestHandler_CreateShareWebhookURL(t *testing.T) {
+	handler, _ := newTestHandler(map[string]mockObject{
+		"images/photo.jpg": {contentType: "image/jpeg", data: []byte("photo")},
+	})
+
+	tests := []struct {
+		name           string
+		webhookURL     string
+		expectedStatus int
+	}{
+		{name: "relative URL", webhookURL: "/hooks/accessed", expectedStatus: http.StatusBadRequest},
+		{name: "unsupported scheme", webhookURL: "ftp://hooks.example.com/accessed", expectedStatus: http.StatusBadRequest},
+		{name: "webhooks disabled", webhookURL: "https://hooks.example.com/accessed", expectedStatus: http.StatusNotImplemented},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, _ := json.Marshal(CreateShareRequest{S3Path: "images/photo.jpg", Secret: "test-secret", ExpiresIn: "1h", WebhookURL: tt.webhookURL})
+			w := httptest.NewRecorder()
+			handler.HandleCreateShare(w, httptest.NewRequest(http.MethodPost, "/api/shares", bytes.NewReader(body)))
+			if w.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d: %s", tt.expectedStatus, w.Code, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestHandler_CreateShareBatch(t *testing.T) {
+	handler, _ := newTestHandler(map[string]mockObject{
+		"images/photo.jpg": {contentType: "image/jpeg", data: []byte("photo")},
+		"images/other.jpg": {contentType: "image/jpeg", data: []byte("other")},
+	})
+	handler.maxBatchSize = 4
+
+	send := func(body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		handler.HandleCreateShareBatch(w, httptest.NewRequest(http.MethodPost, "/api/shares/batch", strings.NewReader(body)))
+		return w
+	}
+
+	t.Run("partial success", func(t *testing.T) {
+		w := send(`[
+			{"s3_path": "images/photo.jpg", "secret": "test-secret", "expires_in": "1h"},
+			{"s3_path": "images/missing.jpg", "secret": "test-secret", "expires_in": "1h"},
+			{"s3_path": "images/other.jpg", "expires_in": "1h"},
+			{"s3_path": "images/other.jpg", "secret": "test-secret", "expires_in": "1h"}
+		]`)
+		if w.Code != http.StatusMultiStatus {
+			t.Fatalf("expected status %d, got %d: %s", http.StatusMultiStatus, w.Code, w.Body.String())
+		}
+
+		var results []BatchShareResult
+		if err := json.NewDecoder(w.Body).Decode(&results); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+
+		expected := []struct {
+			status int
+			error  string
+		}{
+			{status: http.StatusOK},
+			{status: http.StatusNotFound, error: "object not found"},
+			{status: http.StatusBadRequest, error: "secret is required"},
+			{status: http.StatusOK},
+		}
+		if len(results) != len(expected) {
+			t.Fatalf("expected %d results, got %d", len(expected), len(results))
+		}
+		for i, result := range results {
+			if result.Status != expected[i].status {
+				t.Errorf("expected result %d status %d, got %d", i, expected[i].status, result.Status)
+			}
+			if result.Error != expected[i].error {
+				t.Errorf("expected result %d error %q, got %q", i, expected[i].error, result.Error)
+			}
+			hasURL := result.CreateShareResponse != nil && result.URL != ""
+			if hasURL != (expected[i].status == http.StatusOK) {
+				t.Errorf("expected result %d to have a URL only on success, got %+v", i, result)
+			}
+		}
+	})
+
+	tests := []struct {
+		name           string
+		body           string
+		expectedStatus int
+	}{
+		{name: "not an array", body: `{"s3_path": "images/photo.jpg"}`, expectedStatus: http.StatusBadRequest},
+		{name: "empty", body: `[]`, expectedStatus: http.StatusBadRequest},
+		{name: "too large", body: `[{}, {}, {}, {}, {}]`, expectedStatus: http.StatusRequestEntityTooLarge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if w := send(tt.body); w.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d", tt.expectedStatus, w.Code)
+			}
+		})
+	}
+}
+
+func TestHandler_ExtendShare(t *testing.T) {
+	storage := &mockStorageService{objects: map[string]mockObject{
+		"images/photo.jpg": {contentType: "image/jpeg", data: []byte("jpeg-bytes")},
+	}}
+	cache := &mockCacheService{store: make(map[string]string)}
+	handler, shareService := newTestHandlerWithMocks(storage, cache)
+	path := createTestShare(t, shareService, "images/photo.jpg")
+
+	send := func(body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		handler.HandleShares(w, httptest.NewRequest(http.MethodPatch, "/api/shares", strings.NewReader(body)))
+		return w
+	}
+
+	t.Run("valid extension", func(t *testing.T) {
+		w := send(`{"s3_path": "images/photo.jpg", "expires_in": "240h"}`)
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+		}
+
+		var resp ShareInfoResponse
+		if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		if resp.TTLSeconds < 239*60*60 || !resp.Active {
+			t.Errorf("expected an active share for about 240h, got %+v", resp)
+		}
+
+		// The link handed out before the extension still works
+		w = httptest.NewRecorder()
+		handler.HandleImage(w, httptest.NewRequest(http.MethodGet, path, nil))
+		if w.Code != http.StatusOK {
+			t.Errorf("expected the original link to keep working, got %d", w.Code)
+		}
+	})
+
+	tests := []struct {
+		name           string
+		body           string
+		expectedStatus int
+		expectedCode   string
+	}{
+		{name: "exceeding the maximum age", body: `{"s3_path": "images/photo.jpg", "expires_in": "2400h"}`, expectedStatus: http.StatusBadRequest, expectedCode: CodeMaxAgeExceeded},
+		{name: "past expiry", body: `{"s3_path": "images/photo.jpg", "expires_at": "2020-01-01T00:00:00Z"}`, expectedStatus: http.StatusBadRequest, expectedCode: CodeInvalidDate},
+		{name: "unknown share", body: `{"s3_path": "images/missing.jpg", "expires_in": "1h"}`, expectedStatus: http.StatusNotFound, expectedCode: CodeNotFound},
+		{name: "missing expiry", body: `{"s3_path": "images/photo.jpg"}`, expectedStatus: http.StatusBadRequest, expectedCode: CodeBadRequest},
+		{name: "invalid expires_in", body: `{"s3_path": "images/photo.jpg", "expires_in": "-1h"}`, expectedStatus: http.StatusBadRequest, expectedCode: CodeBadRequest},
+		{name: "missing path", body: `{"expires_in": "1h"}`, expectedStatus: http.StatusBadRequest, expectedCode: CodeBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := send(tt.body)
+			if w.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d: %s", tt.expectedStatus, w.Code, w.Body.String())
+			}
+			var resp ErrorResponse
+			if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if resp.Code != tt.expectedCode {
+				t.Errorf("expected code %s, got %s", tt.expectedCode, resp.Code)
+			}
+		})
+	}
+}
+
+func TestHandler_ExtendedLinkPastOriginalExpiry(t *testing.T) {
+	storage := &mockStorageService{objects: map[string]mockObject{
+		"images/photo.jpg": {contentType: "image/jpeg", data: []byte("jpeg-bytes")},
+	}}
+	cache := &mockCacheService{store: make(map[string]string)}
+	handler, _ := newTestHandlerWithMocks(storage, cache)
+
+	// A share whose links expired an hour ago, extended by another hour
+	linkExpiry := time.Now().Add(-time.Hour).Truncate(time.Second)
+	record, _ := domain.EncodeShareRecord(&domain.ShareRecord{
+		Secret:        "test-secret",
+		CreatedAt:     time.Now().Add(-2 * time.Hour),
+		ExpiresAt:     time.Now().Add(time.Hour),
+		LinkExpiresAt: linkExpiry,
+	})
+	cache.store["image-auth:images/photo.jpg"] = record
+
+	tests := []struct {
+		name           string
+		linkExpiry     time.Time
+		expectedStatus int
+	}{
+		{name: "original link", linkExpiry: linkExpiry, expectedStatus: http.StatusOK},
+		{name: "other expired link", linkExpiry: linkExpiry.Add(-time.Minute), expectedStatus: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := "/" + strconv.FormatInt(tt.linkExpiry.Unix(), 10) + "/test-secret/images/photo.jpg"
+			w := httptest.NewRecorder()
+			handler.HandleImage(w, httptest.NewRequest(http.MethodGet, path, nil))
+			if w.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d: %s", tt.expectedStatus, w.Code, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestHandler_RequestBodyLimit(t *testing.T) {
+	handler, _ := newTestHandler(map[string]mockObject{
+		"images/photo.jpg": {contentType: "image/jpeg", data: []byte("photo")},
+	})
+	handler.maxRequestBytes = 128
+
+	oversized := `{"s3_path": "images/photo.jpg", "secret": "` + strings.Repeat("a", 256) + `", "expires_in": "1h"}`
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		handle http.HandlerFunc
+	}{
+		{name: "create", method: http.MethodPost, path: "/api/shares", handle: handler.HandleShares},
+		{name: "batch", method: http.MethodPost, path: "/api/shares/batch", handle: handler.HandleCreateShareBatch},
+		{name: "extend", method: http.MethodPatch, path: "/api/shares", handle: handler.HandleShares},
+		{name: "revoke", method: http.MethodDelete, path: "/api/shares", handle: handler.HandleShares},
+		{name: "upload", method: http.MethodPost, path: "/api/uploads", handle: handler.HandleUpload},
+		{name: "delete object", method: http.MethodDelete, path: "/api/objects", handle: handler.HandleObjects},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := oversized
+			if tt.path == "/api/shares/batch" {
+				body = "[" + oversized + "]"
+			}
+
+			w := httptest.NewRecorder()
+			tt.handle(w, httptest.NewRequest(tt.method, tt.path, strings.NewReader(body)))
+			if w.Code != http.StatusRequestEntityTooLarge {
+				t.Errorf("expected status %d, got %d: %s", http.StatusRequestEntityTooLarge, w.Code, w.Body.String())
+			}
+		})
+	}
+
+	t.Run("within limit", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		body := `{"s3_path": "images/photo.jpg", "secret": "test-secret", "expires_in": "1h"}`
+		handler.HandleShares(w, httptest.NewRequest(http.MethodPost, "/api/shares", strings.NewReader(body)))
+		if w.Code != http.StatusOK {
+			t.Errorf("expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+		}
+	})
+}
+
+func TestHandler_ErrorCodes(t *testing.T) {
+	objects := map[string]mockObject{
+		"images/photo.jpg":  {contentType: "image/jpeg", data: []byte("photo")},
+		"images/locked.jpg": {contentType: "image/jpeg", data: []byte("locked")},
+		"images/gone.jpg":   {contentType: "image/jpeg", data: []byte("gone")},
+	}
+	handler, shareService := newTestHandler(objects)
+
+	link := createTestShare(t, shareService, "images/photo.jpg")
+	goneLink := createTestShare(t, shareService, "images/gone.jpg")
+	delete(objects, "images/gone.jpg")
+
+	resp, err := shareService.CreateShare(context.Background(), &domain.ShareRequest{
+		S3Path:    "images/locked.jpg",
+		Secret:    "test-secret",
+		ExpiresAt: time.Now().Add(24 * time.Hour),
+		Password:  "hunter2",
+	})
+	if err != nil {
+		t.Fatalf("failed to create share: %v", err)
+	}
+	lockedLink := strings.TrimPrefix(resp.URL, "https://example.com")
+
+	expired := "/" + strconv.FormatInt(time.Now().Add(-time.Hour).Unix(), 10) + "/test-secret/images/photo.jpg"
+	wrongSecret := strings.Replace(link, "test-secret", "wrong-secret", 1)
+
+	tests := []struct {
+		name           string
+		handle         http.HandlerFunc
+		request        *http.Request
+		expectedStatus int
+		expectedCode   string
+	}{
+		{name: "expired", handle: handler.HandleImage, request: httptest.NewRequest(http.MethodGet, expired, nil), expectedStatus: http.StatusForbidden, expectedCode: "EXPIRED"},
+		{name: "unauthorized", handle: handler.HandleImage, request: httptest.NewRequest(http.MethodGet, wrongSecret, nil), expectedStatus: http.StatusUnauthorized, expectedCode: "UNAUTHORIZED"},
+		{name: "password required", handle: handler.HandleImage, request: httptest.NewRequest(http.MethodGet, lockedLink, nil), expectedStatus: http.StatusUnauthorized, expectedCode: "PASSWORD_REQUIRED"},
+		{name: "invalid date", handle: handler.HandleImage, request: httptest.NewRequest(http.MethodGet, "/24/13/45/test-secret/images/photo.jpg", nil), expectedStatus: http.StatusBadRequest, expectedCode: "INVALID_DATE"},
+		{name: "not found", handle: handler.HandleImage, request: httptest.NewRequest(http.MethodGet, goneLink, nil), expectedStatus: http.StatusNotFound, expectedCode: "NOT_FOUND"},
+		{
+			name:   "invalid path",
+			handle: handler.HandleShares,
+			request: httptest.NewRequest(http.MethodPost, "/api/shares",
+				strings.NewReader(`{"s3_path": "../etc/passwd", "secret": "test-secret", "expires_in": "1h"}`)),
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   "INVALID_PATH",
+		},
+		{
+			name:   "max age exceeded",
+			handle: handler.HandleShares,
+			request: httptest.NewRequest(http.MethodPost, "/api/shares",
+				strings.NewReader(`{"s3_path": "images/photo.jpg", "secret": "test-secret", "expires_in": "8760h"}`)),
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   "MAX_AGE_EXCEEDED",
+		},
+		{name: "method not allowed", handle: handler.HandleShares, request: httptest.NewRequest(http.MethodPut, "/api/shares", nil), expectedStatus: http.StatusMethodNotAllowed, expectedCode: "METHOD_NOT_ALLOWED"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			tt.handle(w, tt.request)
+			if w.Code != tt.expectedStatus {
+				t.Fatalf("expected status %d, got %d: %s", tt.expectedStatus, w.Code, w.Body.String())
+			}
+
+			var response ErrorResponse
+			if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if response.Code != tt.expectedCode {
+				t.Errorf("expected code %s, got %s", tt.expectedCode, response.Code)
+			}
+			if response.Status != tt.expectedStatus {
+				t.Errorf("expected status field %d, got %d", tt.expectedStatus, response.Status)
+			}
+		})
+	}
+}
+
+func TestHandler_RefererProtection(t *testing.T) {
+	newHandler := func(config *service.ShareConfig) (*Handler, *service.ShareService) {
+		storage := &mockStorageService{objects: map[string]mockObject{
+			"images/photo.jpg":  {contentType: "image/jpeg", data: []byte("photo")},
+			"images/banner.jpg": {contentType: "image/jpeg", data: []byte("banner")},
+		}}
+		config.MaxAgeDays = 90
+		config.BaseURL = "https://example.com"
+		shareService := service.NewShareService(storage, &mockCacheService{store: make(map[string]string)}, config)
+		return NewHandler(shareService, slog.New(slog.NewTextHandler(io.Discard, nil))), shareService
+	}
+	createShare := func(t *testing.T, shareService *service.ShareService, s3Path string, referers []string) string {
+		t.Helper()
+		resp, err := shareService.CreateShare(context.Background(), &domain.ShareRequest{
+			S3Path:          s3Path,
+			Secret:          "test-secret",
+			ExpiresAt:       time.Now().Add(24 * time.Hour),
+			AllowedReferers: referers,
+		})
+		if err != nil {
+			t.Fatalf("failed to create share: %v", err)
+		}
+		return strings.TrimPrefix(resp.URL, "https://example.com")
+	}
+	get := func(handler *Handler, path, referer string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if referer != "" {
+			req.Header.Set("Referer", referer)
+		}
+		w := httptest.NewRecorder()
+		handler.HandleImage(w, req)
+		return w
+	}
+
+	handler, shareService := newHandler(&service.ShareConfig{
+		AllowedReferers:   []string{"blog.example.org"},
+		AllowEmptyReferer: true,
+	})
+	restricted := createShare(t, shareService, "images/photo.jpg", []string{"*.example.com"})
+	fallback := createShare(t, shareService, "images/banner.jpg", nil)
+
+	tests := []struct {
+		name           string
+		path           string
+		referer        string
+		expectedStatus int
+	}{
+		{name: "allowed subdomain", path: restricted, referer: "https://www.example.com/gallery", expectedStatus: http.StatusOK},
+		{name: "disallowed site", path: restricted, referer: "https://hotlinker.net/page", expectedStatus: http.StatusForbidden},
+		{name: "apex of wildcard", path: restricted, referer: "https://example.com/", expectedStatus: http.StatusForbidden},
+		{name: "empty referer allowed", path: restricted, expectedStatus: http.StatusOK},
+		{name: "global allowlist", path: fallback, referer: "https://blog.example.org/post", expectedStatus: http.StatusOK},
+		{name: "share list overrides global", path: restricted, referer: "https://blog.example.org/post", expectedStatus: http.StatusForbidden},
+		{name: "global allowlist disallowed", path: fallback, referer: "https://www.example.com/", expectedStatus: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := get(handler, tt.path, tt.referer)
+			if w.Code != tt.expectedStatus {
+				t.Fatalf("expected status %d, got %d: %s", tt.expectedStatus, w.Code, w.Body.String())
+			}
+			if tt.expectedStatus == http.StatusForbidden && !strings.Contains(w.Body.String(), "REFERER_NOT_ALLOWED") {
+				t.Errorf("expected code REFERER_NOT_ALLOWED, got %s", w.Body.String())
+			}
+		})
+	}
+
+	t.Run("empty referer rejected", func(t *testing.T) {
+		strict, strictService := newHandler(&service.ShareConfig{AllowEmptyReferer: false})
+		path := createShare(t, strictService, "images/photo.jpg", []string{"example.com"})
+
+		if w := get(strict, path, ""); w.Code != http.StatusForbidden {
+			t.Errorf("expected status %d, got %d", http.StatusForbidden, w.Code)
+		}
+		if w := get(strict, path, "https://example.com/"); w.Code != http.StatusOK {
+			t.Errorf("expected status %d, got %d", http.StatusOK, w.Code)
+		}
+	})
+
+	t.Run("unrestricted share", func(t *testing.T) {
+		open, openService := newHandler(&service.ShareConfig{})
+		path := createShare(t, openService, "images/photo.jpg", nil)
+
+		if w := get(open, path, "https://anywhere.net/"); w.Code != http.StatusOK {
+			t.Errorf("expected status %d, got %d", http.StatusOK, w.Code)
+		}
+	})
+
+	t.Run("invalid pattern", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		body := `{"s3_path": "images/photo.jpg", "secret": "test-secret", "expires_in": "1h", "allowed_referers": ["https://example.com/"]}`
+		handler.HandleShares(w, httptest.NewRequest(http.MethodPost, "/api/shares", strings.NewReader(body)))
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("expected status %d, got %d: %s", http.StatusBadRequest, w.Code, w.Body.String())
+		}
+	})
+}
+
+func TestHandler_IPAllowlist(t *testing.T) {
+	storage := &mockStorageService{objects: map[string]mockObject{
+		"images/photo.jpg":  {contentType: "image/jpeg", data: []byte("photo")},
+		"images/banner.jpg": {contentType: "image/jpeg", data: []byte("banner")},
+		"images/logo.png":   {contentType: "image/png", data: []byte("logo")},
+	}}
+	shareService := service.NewShareService(storage, &mockCacheService{store: make(map[string]string)}, &service.ShareConfig{
+		MaxAgeDays: 90,
+		BaseURL:    "https://example.com",
+	})
+	handler := NewHandler(shareService, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	createShare := func(t *testing.T, s3Path string, ips []string) string {
+		t.Helper()
+		resp, err := shareService.CreateShare(context.Background(), &domain.ShareRequest{
+			S3Path:     s3Path,
+			Secret:     "test-secret",
+			ExpiresAt:  time.Now().Add(24 * time.Hour),
+			AllowedIPs: ips,
+		})
+		if err != nil {
+			t.Fatalf("failed to create share: %v", err)
+		}
+		return strings.TrimPrefix(resp.URL, "https://example.com")
+	}
+	ipv4 := createShare(t, "images/photo.jpg", []string{"203.0.113.0/24", "198.51.100.7"})
+	ipv6 := createShare(t, "images/banner.jpg", []string{"2001:db8:abcd::/48"})
+	open := createShare(t, "images/logo.png", nil)
+
+	tests := []struct {
+		name           string
+		path           string
+		remoteAddr     string
+		forwardedFor   string
+		expectedStatus int
+	}{
+		{name: "allowed range", path: ipv4, remoteAddr: "203.0.113.42:5000", expectedStatus: http.StatusOK},
+		{name: "allowed single address", path: ipv4, remoteAddr: "198.51.100.7:5000", expectedStatus: http.StatusOK},
+		{name: "disallowed address", path: ipv4, remoteAddr: "192.0.2.1:5000", expectedStatus: http.StatusForbidden},
+		{name: "allowed forwarded address", path: ipv4, remoteAddr: "10.0.0.1:5000", forwardedFor: "203.0.113.9, 10.0.0.2", expectedStatus: http.StatusOK},
+		{name: "disallowed forwarded address", path: ipv4, remoteAddr: "10.0.0.1:5000", forwardedFor: "192.0.2.1", expectedStatus: http.StatusForbidden},
+		{name: "forwarded address from an untrusted peer", path: ipv4, remoteAddr: "192.0.2.1:5000", forwardedFor: "203.0.113.9", expectedStatus: http.StatusForbidden},
+		{name: "allowed IPv6 range", path: ipv6, remoteAddr: "[2001:db8:abcd:12::1]:5000", expectedStatus: http.StatusOK},
+		{name: "disallowed IPv6 address", path: ipv6, remoteAddr: "[2001:db8:ffff::1]:5000", expectedStatus: http.StatusForbidden},
+		{name: "IPv4 client of IPv6 share", path: ipv6, remoteAddr: "203.0.113.42:5000", expectedStatus: http.StatusForbidden},
+		{name: "unrestricted share", path: open, remoteAddr: "192.0.2.1:5000", expectedStatus: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			req.RemoteAddr = tt.remoteAddr
+			if tt.forwardedFor != "" {
+				req.Header.Set("X-Forwarded-For", tt.forwardedFor)
+			}
+			w := httptest.NewRecorder()
+			clientIPMiddleware([]string{"10.0.0.0/8"}, http.HandlerFunc(handler.HandleImage)).ServeHTTP(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Fatalf("expected status %d, got %d: %s", tt.expectedStatus, w.Code, w.Body.String())
+			}
+			if tt.expectedStatus == http.StatusForbidden && !strings.Contains(w.Body.String(), "IP_NOT_ALLOWED") {
+				t.Errorf("expected code IP_NOT_ALLOWED, got %s", w.Body.String())
+			}
+		})
+	}
+
+	t.Run("invalid CIDR", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		body := `{"s3_path": "images/photo.jpg", "secret": "test-secret", "expires_in": "1h", "allowed_ips": ["203.0.113.0/33"]}`
+		handler.HandleShares(w, httptest.NewRequest(http.MethodPost, "/api/shares", strings.NewReader(body)))
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("expected status %d, got %d: %s", http.StatusBadRequest, w.Code, w.Body.String())
+		}
+	})
+}
+
+// encodeTestJPEG encodes a width by height JPEG image
+func encodeTestJPEG(t *testing.T, width, height int) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for i := range img.Pix {
+		img.Pix[i] = 0x80
+	}
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, nil); err != nil {
+		t.Fatalf("failed to encode test image: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestHandler_ImageResize(t *testing.T) {
+	pdf := []byte("%PDF-1.4 test document")
+	storage := &mockStorageService{objects: map[string]mockObject{
+		"images/photo.jpg": {contentType: "image/jpeg", data: encodeTestJPEG(t, 200, 100), etag: `"photo-v1"`},
+		"docs/report.pdf":  {contentType: "application/pdf", data: pdf},
+	}}
+	shareService := service.NewShareService(storage, &mockCacheService{store: make(map[string]string)}, &service.ShareConfig{
+		MaxAgeDays:         90,
+		BaseURL:            "https://example.com",
+		ResizeMaxDimension: 1000,
+		ResizeMaxPixels:    1_000_000,
+		ResizeCacheTTL:     time.Hour,
+	})
+	handler := NewHandler(shareService, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	photo := createTestShare(t, shareService, "images/photo.jpg")
+	report := createTestShare(t, shareService, "docs/report.pdf")
+
+	get := func(path string, header http.Header) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		for name, values := range header {
+			req.Header[name] = values
+		}
+		w := httptest.NewRecorder()
+		handler.HandleImage(w, req)
+		return w
+	}
+
+	t.Run("resized JPEG", func(t *testing.T) {
+		tests := []struct {
+			query          string
+			expectedWidth  int
+			expectedHeight int
+		}{
+			{query: "w=50", expectedWidth: 50, expectedHeight: 25},
+			{query: "h=20", expectedWidth: 40, expectedHeight: 20},
+			{query: "w=50&h=50", expectedWidth: 50, expectedHeight: 25},
+			{query: "w=50&h=50&fit=cover", expectedWidth: 50, expectedHeight: 50},
+			{query: "w=30&h=60&fit=fill", expectedWidth: 30, expectedHeight: 60},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.query, func(t *testing.T) {
+				w := get(photo+"?"+tt.query, nil)
+				if w.Code != http.StatusOK {
+					t.Fatalf("expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+				}
+				if contentType := w.Header().Get("Content-Type"); contentType != "image/jpeg" {
+					t.Errorf("expected Content-Type image/jpeg, got %s", contentType)
+				}
+				if length := w.Header().Get("Content-Length"); length != strconv.Itoa(w.Body.Len()) {
+					t.Errorf("expected Content-Length %d, got %s", w.Body.Len(), length)
+				}
+
+				config, err := jpeg.DecodeConfig(w.Body)
+				if err != nil {
+					t.Fatalf("failed to decode resized image: %v", err)
+				}
+				if config.Width != tt.expectedWidth || config.Height != tt.expectedHeight {
+					t.Errorf("expected %dx%d, got %dx%d", tt.expectedWidth, tt.expectedHeight, config.Width, config.Height)
+				}
+			})
+		}
+	})
+
+	t.Run("cached and revalidated", func(t *testing.T) {
+		first := get(photo+"?w=64", nil)
+		if first.Code != http.StatusOK {
+			t.Fatalf("expected status %d, got %d", http.StatusOK, first.Code)
+		}
+		etag := first.Header().Get("ETag")
+		if etag == "" || etag == `"photo-v1"` {
+			t.Errorf("expected an ETag of the resized image, got %q", etag)
+		}
+
+		calls := storage.getCalls.Load()
+		second := get(photo+"?w=64", nil)
+		if !bytes.Equal(second.Body.Bytes(), first.Body.Bytes()) {
+			t.Error("expected the cached image to match the first response")
+		}
+		if storage.getCalls.Load() != calls {
+			t.Error("expected the resized image to be served from the cache")
+		}
+
+		if w := get(photo+"?w=64", http.Header{"If-None-Match": {etag}}); w.Code != http.StatusNotModified {
+			t.Errorf("expected status %d, got %d", http.StatusNotModified, w.Code)
+		}
+	})
+
+	t.Run("PDF passes through unchanged", func(t *testing.T) {
+		w := get(report+"?w=50&h=50", nil)
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+		}
+		if contentType := w.Header().Get("Content-Type"); contentType != "application/pdf" {
+			t.Errorf("expected Content-Type application/pdf, got %s", contentType)
+		}
+		if !bytes.Equal(w.Body.Bytes(), pdf) {
+			t.Errorf("expected the original document, got %q", w.Body.String())
+		}
+	})
+
+	t.Run("invalid parameters", func(t *testing.T) {
+		tests := []struct {
+			query          string
+			expectedStatus int
+			expectedCode   string
+		}{
+			{query: "w=abc", expectedStatus: http.StatusBadRequest, expectedCode: CodeBadRequest},
+			{query: "w=0", expectedStatus: http.StatusBadRequest, expectedCode: CodeBadRequest},
+			{query: "w=50&fit=stretch", expectedStatus: http.StatusBadRequest, expectedCode: CodeBadRequest},
+			{query: "w=5000", expectedStatus: http.StatusUnprocessableEntity, expectedCode: CodeImageTooLarge},
+			{query: "h=900", expectedStatus: http.StatusUnprocessableEntity, expectedCode: CodeImageTooLarge},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.query, func(t *testing.T) {
+				w := get(photo+"?"+tt.query, nil)
+				if w.Code != tt.expectedStatus {
+					t.Fatalf("expected status %d, got %d: %s", tt.expectedStatus, w.Code, w.Body.String())
+				}
+				if !strings.Contains(w.Body.String(), tt.expectedCode) {
+					t.Errorf("expected code %s, got %s", tt.expectedCode, w.Body.String())
+				}
+			})
+		}
+	})
+}
+
+func TestHandler_ContentChecksum(t *testing.T) {
+	checksum := "3a6eb0790f39ac87c94f3856b2dd2c5d110e6811602261a9a923d3bb23adc8b7"
+	handler, shareService := newTestHandler(map[string]mockObject{
+		"docs/report.pdf":  {contentType: "application/pdf", data: []byte("data"), checksum: checksum},
+		"docs/unknown.pdf": {contentType: "application/pdf", data: []byte("data")},
+	})
+	verified := createTestShare(t, shareService, "docs/report.pdf")
+	unverified := createTestShare(t, shareService, "docs/unknown.pdf")
+
+	tests := []struct {
+		name             string
+		method           string
+		path             string
+		rangeHeader      string
+		expectedStatus   int
+		expectedChecksum string
+	}{
+		{name: "GET with checksum", method: http.MethodGet, path: verified, expectedStatus: http.StatusOK, expectedChecksum: checksum},
+		{name: "HEAD with checksum", method: http.MethodHead, path: verified, expectedStatus: http.StatusOK, expectedChecksum: checksum},
+		{name: "partial content", method: http.MethodGet, path: verified, rangeHeader: "bytes=0-1", expectedStatus: http.StatusPartialContent},
+		{name: "GET without checksum", method: http.MethodGet, path: unverified, expectedStatus: http.StatusOK},
+		{name: "HEAD without checksum", method: http.MethodHead, path: unverified, expectedStatus: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			if tt.rangeHeader != "" {
+				req.Header.Set("Range", tt.rangeHeader)
+			}
+			w := httptest.NewRecorder()
+			handler.HandleImage(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Fatalf("expected status %d, got %d: %s", tt.expectedStatus, w.Code, w.Body.String())
+			}
+			if got, ok := w.Header()["X-Content-Sha256"]; tt.expectedChecksum == "" && ok {
+				t.Errorf("expected no X-Content-SHA256 header, got %v", got)
+			}
+			if got := w.Header().Get("X-Content-SHA256"); got != tt.expectedChecksum {
+				t.Errorf("expected X-Content-SHA256 %q, got %q", tt.expectedChecksum, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,180 @@
+package http
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	// jwksFetchTimeout bounds a single fetch of the key set
+	jwksFetchTimeout = 5 * time.Second
+	// jwksRefreshInterval is the minimum time between fetches, so tokens with
+	// unknown key IDs cannot hammer the JWKS endpoint
+	jwksRefreshInterval = time.Minute
+	// maxJWKSBytes bounds the size of a fetched key set
+	maxJWKSBytes = 1 << 20
+)
+
+// jwk is a JSON Web Key as served in a JWKS document
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	// RSA modulus and exponent
+	N string `json:"n"`
+	E string `json:"e"`
+	// EC curve and point coordinates
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// jwksKeySet caches the signing keys served at a JWKS URL, refetching them
+// when a token names a key ID it does not know
+type jwksKeySet struct {
+	url    string
+	client *http.Client
+
+	mu        sync.Mutex
+	keys      map[string]any
+	fetchedAt time.Time
+}
+
+// newJWKSKeySet creates a key set fetched lazily from url
+func newJWKSKeySet(url string) *jwksKeySet {
+	return &jwksKeySet{
+		url:    url,
+		client: &http.Client{Timeout: jwksFetchTimeout},
+	}
+}
+
+// Keyfunc returns the public key named by the kid header of token
+func (k *jwksKeySet) Keyfunc(token *jwt.Token) (any, error) {
+	kid, _ := token.Header["kid"].(string)
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if key, ok := k.keys[kid]; ok {
+		return key, nil
+	}
+
+	if time.Since(k.fetchedAt) >= jwksRefreshInterval {
+		// Record the attempt first so that a failing endpoint is also rate limited
+		k.fetchedAt = time.Now()
+		keys, err := k.fetch()
+		if err != nil {
+			return nil, err
+		}
+		k.keys = keys
+	}
+
+	if key, ok := k.keys[kid]; ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("unknown signing key %q", kid)
+}
+
+// fetch downloads the key set and parses its signing keys by key ID,
+// skipping keys of unsupported types
+func (k *jwksKeySet) fetch() (map[string]any, error) {
+	resp, err := k.client.Get(k.url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch JWKS: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch JWKS: unexpected status %d", resp.StatusCode)
+	}
+
+	var document struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.NewDecoder(http.MaxBytesReader(nil, resp.Body, maxJWKSBytes)).Decode(&document); err != nil {
+		return nil, fmt.Errorf("failed to decode JWKS: %w", err)
+	}
+
+	keys := make(map[string]any, len(document.Keys))
+	for _, key := range document.Keys {
+		if key.Use != "" && key.Use != "sig" {
+			continue
+		}
+		publicKey, err := key.publicKey()
+		if err != nil {
+			continue
+		}
+		keys[key.Kid] = publicKey
+	}
+	return keys, nil
+}
+
+// errUnsupportedKey reports a JWK of a type or curve that cannot verify tokens
+var errUnsupportedKey = errors.New("unsupported key")
+
+// publicKey converts an RSA or EC JWK to its crypto public key
+func (j jwk) publicKey() (any, error) {
+	switch j.Kty {
+	case "RSA":
+		n, err := decodeJWKInt(j.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeJWKInt(j.E)
+		if err != nil {
+			return nil, err
+		}
+		if !e.IsInt64() || e.Int64() < 2 || e.Int64() > 1<<31-1 {
+			return nil, errUnsupportedKey
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+
+	case "EC":
+		var curve elliptic.Curve
+		switch j.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, errUnsupportedKey
+		}
+		x, err := decodeJWKInt(j.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeJWKInt(j.Y)
+		if err != nil {
+			return nil, err
+		}
+		if !curve.IsOnCurve(x, y) {
+			return nil, errUnsupportedKey
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+
+	default:
+		return nil, errUnsupportedKey
+	}
+}
+
+// decodeJWKInt decodes a base64url big-endian integer JWK member
+func decodeJWKInt(value string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil || len(b) == 0 {
+		return nil, errUnsupportedKey
+	}
+	return new(big.Int).SetBytes(b), nil
+}
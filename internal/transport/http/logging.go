@@ -0,0 +1,103 @@
+package http
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// requestIDHeader carries the request ID to and from clients
+const requestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength bounds the length of propagated request IDs
+const maxRequestIDLength = 128
+
+// requestIDKey is the context key of the request ID
+type requestIDKey struct{}
+
+// RequestIDFromContext returns the request ID stored in ctx by the request
+// logging middleware, or an empty string
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// responseRecorder captures the status code and body size written by a handler
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int64
+}
+
+// newResponseRecorder wraps w, defaulting the status to 200 OK
+func newResponseRecorder(w http.ResponseWriter) *responseRecorder {
+	return &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+}
+
+// WriteHeader records the status code before writing it
+func (r *responseRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+// Write counts the bytes written to the body
+func (r *responseRecorder) Write(p []byte) (int, error) {
+	n, err := r.ResponseWriter.Write(p)
+	r.bytes += int64(n)
+	return n, err
+}
+
+// Unwrap returns the underlying ResponseWriter for http.ResponseController
+func (r *responseRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+// requestLoggingMiddleware assigns each request an ID, propagating a valid
+// incoming X-Request-ID, and writes an access log entry of the redacted path
+// once it completes
+func requestLoggingMiddleware(logger *slog.Logger, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+
+		requestID := r.Header.Get(requestIDHeader)
+		if !validRequestID(requestID) {
+			requestID = newRequestID()
+		}
+		w.Header().Set(requestIDHeader, requestID)
+
+		rec := newResponseRecorder(w)
+		next.ServeHTTP(rec, r.WithContext(context.WithValue(r.Context(), requestIDKey{}, requestID)))
+
+		logger.Info("request completed",
+			"request_id", requestID,
+			"method", r.Method,
+			"path", redactPath(r.URL.Path),
+			"status", rec.status,
+			"duration", time.Since(start),
+			"bytes", rec.bytes,
+		)
+	})
+}
+
+// validRequestID reports whether id is a non-empty, bounded, printable ASCII string
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < '!' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+// newRequestID generates a random 128-bit hex request ID
+func newRequestID() string {
+	var b [16]byte
+	rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
@@ -0,0 +1,94 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRequestLoggingMiddleware(t *testing.T) {
+	var logs bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&logs, nil))
+
+	var seenID string
+	handler := requestLoggingMiddleware(logger, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seenID = RequestIDFromContext(r.Context())
+		w.WriteHeader(http.StatusTeapot)
+		w.Write([]byte("short and stout"))
+	}))
+
+	tests := []struct {
+		name       string
+		incomingID string
+		expectSame bool
+	}{
+		{name: "generated", incomingID: ""},
+		{name: "propagated", incomingID: "req-123", expectSame: true},
+		{name: "invalid replaced", incomingID: "bad id\nwith newline"},
+		{name: "too long replaced", incomingID: strings.Repeat("a", maxRequestIDLength+1)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logs.Reset()
+
+			req := httptest.NewRequest(http.MethodGet, "/24/12/31/secret/images/photo.jpg", nil)
+			if tt.incomingID != "" {
+				req.Header.Set(requestIDHeader, tt.incomingID)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+
+			requestID := w.Header().Get(requestIDHeader)
+			if requestID == "" {
+				t.Fatal("expected X-Request-ID response header")
+			}
+			if tt.expectSame && requestID != tt.incomingID {
+				t.Errorf("expected propagated request ID %q, got %q", tt.incomingID, requestID)
+			}
+			if !tt.expectSame && requestID == tt.incomingID {
+				t.Errorf("expected a generated request ID, got %q", requestID)
+			}
+			if seenID != requestID {
+				t.Errorf("expected context request ID %q, got %q", requestID, seenID)
+			}
+
+			var entry struct {
+				RequestID string `json:"request_id"`
+				Method    string `json:"method"`
+				Path      string `json:"path"`
+				Status    int    `json:"status"`
+				Bytes     int64  `json:"bytes"`
+			}
+			if err := json.Unmarshal(logs.Bytes(), &entry); err != nil {
+				t.Fatalf("failed to decode log entry %q: %v", logs.String(), err)
+			}
+			if entry.RequestID != requestID {
+				t.Errorf("expected logged request ID %q, got %q", requestID, entry.RequestID)
+			}
+			if entry.Method != http.MethodGet || entry.Path != "/24/12/31/REDACTED/images/photo.jpg" {
+				t.Errorf("unexpected logged method and path: %s %s", entry.Method, entry.Path)
+			}
+			if strings.Contains(logs.String(), "secret") {
+				t.Errorf("expected log entry to omit the link secret, got %q", logs.String())
+			}
+			if entry.Status != http.StatusTeapot {
+				t.Errorf("expected logged status %d, got %d", http.StatusTeapot, entry.Status)
+			}
+			if entry.Bytes != int64(len("short and stout")) {
+				t.Errorf("expected logged bytes %d, got %d", len("short and stout"), entry.Bytes)
+			}
+		})
+	}
+}
+
+func TestRequestIDFromContext_Missing(t *testing.T) {
+	if id := RequestIDFromContext(context.Background()); id != "" {
+		t.Errorf("expected empty request ID, got %q", id)
+	}
+}
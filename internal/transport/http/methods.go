@@ -0,0 +1,47 @@
+package http
+
+import (
+	"net/http"
+	"strings"
+)
+
+// Methods of the API, archive and share link routes, listed in their Allow
+// headers. Share links accept POST for password forms.
+var (
+	sharesMethods      = []string{http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPatch, http.MethodDelete}
+	shareAuditMethods  = []string{http.MethodGet}
+	shareBatchMethods  = []string{http.MethodPost}
+	shareCookieMethods = []string{http.MethodPost}
+	uploadsMethods     = []string{http.MethodPost}
+	objectsMethods     = []string{http.MethodGet, http.MethodDelete}
+	archiveMethods     = []string{http.MethodGet}
+	shareLinkMethods   = []string{http.MethodGet, http.MethodHead, http.MethodPost}
+)
+
+// allowHeader lists methods and OPTIONS, which every such route answers
+func allowHeader(methods []string) string {
+	return strings.Join(methods, ", ") + ", " + http.MethodOptions
+}
+
+// writeMethodNotAllowed writes a 405 response whose Allow header lists the
+// methods the route supports
+func (h *Handler) writeMethodNotAllowed(w http.ResponseWriter, methods ...string) {
+	w.Header().Set("Allow", allowHeader(methods))
+	h.writeError(w, "method not allowed", http.StatusMethodNotAllowed)
+}
+
+// optionsMiddleware answers OPTIONS requests with 204 and an Allow header
+// listing methods. It is installed outside authentication so that preflight
+// requests, which carry no credentials, succeed; other methods are checked by
+// the handlers once authenticated.
+func optionsMiddleware(methods []string, next http.Handler) http.Handler {
+	allow := allowHeader(methods)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodOptions {
+			next.ServeHTTP(w, r)
+			return
+		}
+		w.Header().Set("Allow", allow)
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
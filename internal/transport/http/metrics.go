@@ -0,0 +1,284 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/vchitai/go-s3-sharing/internal/domain"
+	"github.com/vchitai/go-s3-sharing/internal/service"
+)
+
+// Metric names exposed on /metrics
+const (
+	// metricHTTPRequestsTotal counts HTTP requests by method and status code
+	metricHTTPRequestsTotal = "s3share_http_requests_total"
+	// metricSharesCreatedTotal counts successfully created shares
+	metricSharesCreatedTotal = "s3share_shares_created_total"
+	// metricValidationFailuresTotal counts rejected share links by reason
+	metricValidationFailuresTotal = "s3share_validation_failures_total"
+	// metricBytesStreamedTotal counts object bytes written to clients
+	metricBytesStreamedTotal = "s3share_bytes_streamed_total"
+	// metricBackendCallDuration observes storage and cache call latencies
+	// by backend and operation
+	metricBackendCallDuration = "s3share_backend_call_duration_seconds"
+	// metricStorageBreakerState reports the storage circuit breaker state:
+	// 0 closed, 1 open, 2 half-open
+	metricStorageBreakerState = "s3share_storage_breaker_state"
+	// metricActiveStreams reports the download streams in flight
+	metricActiveStreams = "s3share_active_streams"
+	// metricStreamsRejectedTotal counts downloads rejected because every
+	// stream slot was taken
+	metricStreamsRejectedTotal = "s3share_streams_rejected_total"
+	// metricObjectCacheLookupsTotal counts object cache lookups by result
+	metricObjectCacheLookupsTotal = "s3share_object_cache_lookups_total"
+	// metricDownloadsTotal counts downloads by type: full, range or archive
+	metricDownloadsTotal = "s3share_downloads_total"
+	// metricDownloadBytesTotal counts object bytes written to clients by
+	// download type
+	metricDownloadBytesTotal = "s3share_download_bytes_total"
+)
+
+// Download types labelling the download metrics
+const (
+	// downloadFull is a 200 response with the whole object, resized or not
+	downloadFull = "full"
+	// downloadRange is a 206 response with a byte range of the object
+	downloadRange = "range"
+	// downloadArchive is a zip archive of a prefix share
+	downloadArchive = "archive"
+)
+
+var (
+	httpRequestsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: metricHTTPRequestsTotal,
+		Help: "HTTP requests by method and status code.",
+	}, []string{"method", "code"})
+
+	sharesCreatedTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Name: metricSharesCreatedTotal,
+		Help: "Shares created successfully.",
+	})
+
+	validationFailuresTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: metricValidationFailuresTotal,
+		Help: "Share links rejected during validation by reason.",
+	}, []string{"reason"})
+
+	bytesStreamedTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Name: metricBytesStreamedTotal,
+		Help: "Object bytes streamed to clients.",
+	})
+
+	backendCallDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    metricBackendCallDuration,
+		Help:    "Latency of storage and cache calls by backend and operation.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"backend", "operation"})
+
+	storageBreakerState = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: metricStorageBreakerState,
+		Help: "Storage circuit breaker state: 0 closed, 1 open, 2 half-open.",
+	})
+
+	activeStreams = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: metricActiveStreams,
+		Help: "Download streams in flight.",
+	})
+
+	streamsRejectedTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Name: metricStreamsRejectedTotal,
+		Help: "Downloads rejected because the concurrent stream limit was reached.",
+	})
+
+	objectCacheLookupsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: metricObjectCacheLookupsTotal,
+		Help: "Small-object cache lookups by result (hit or miss).",
+	}, []string{"result"})
+
+	downloadsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: metricDownloadsTotal,
+		Help: "Downloads started by type (full, range or archive).",
+	}, []string{"type"})
+
+	downloadBytesTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: metricDownloadBytesTotal,
+		Help: "Object bytes streamed to clients by download type.",
+	}, []string{"type"})
+)
+
+// metricsMiddleware counts requests by method and response status code
+func metricsMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rec := newResponseRecorder(w)
+		next.ServeHTTP(rec, r)
+		httpRequestsTotal.WithLabelValues(r.Method, strconv.Itoa(rec.status)).Inc()
+	})
+}
+
+// ObserveBreakerState exports the state of the storage circuit breaker on /metrics
+func ObserveBreakerState(state service.BreakerState) {
+	storageBreakerState.Set(float64(state))
+}
+
+// ObserveObjectCacheLookup counts a small-object cache hit or miss on /metrics
+func ObserveObjectCacheLookup(hit bool) {
+	result := "miss"
+	if hit {
+		result = "hit"
+	}
+	objectCacheLookupsTotal.WithLabelValues(result).Inc()
+}
+
+// observeBackendCall records the latency of a backend call started at start
+func observeBackendCall(backend, operation string, start time.Time) {
+	backendCallDuration.WithLabelValues(backend, operation).Observe(time.Since(start).Seconds())
+}
+
+// instrumentedStorage records call latencies of a StorageService
+type instrumentedStorage struct {
+	next    domain.StorageService
+	backend string
+}
+
+// instrumentedPresigningStorage is an instrumentedStorage over a backend that
+// also presigns URLs
+type instrumentedPresigningStorage struct {
+	*instrumentedStorage
+	presigner domain.Presigner
+}
+
+// InstrumentStorage wraps storage so its call latencies are exported on
+// /metrics under the given backend name, e.g. "s3". The wrapper implements
+// domain.Presigner exactly when storage does.
+func InstrumentStorage(backend string, storage domain.StorageService) domain.StorageService {
+	instrumented := &instrumentedStorage{next: storage, backend: backend}
+	if presigner, ok := storage.(domain.Presigner); ok {
+		return &instrumentedPresigningStorage{instrumentedStorage: instrumented, presigner: presigner}
+	}
+	return instrumented
+}
+
+// PresignGetObject presigns an object URL and records the call latency
+func (s *instrumentedPresigningStorage) PresignGetObject(ctx context.Context, key string, expiry time.Duration) (string, error) {
+	defer observeBackendCall(s.backend, "presign_get_object", time.Now())
+	return s.presigner.PresignGetObject(ctx, key, expiry)
+}
+
+// PresignPutObject presigns an upload URL and records the call latency
+func (s *instrumentedPresigningStorage) PresignPutObject(ctx context.Context, key string, expiry time.Duration) (string, error) {
+	defer observeBackendCall(s.backend, "presign_put_object", time.Now())
+	return s.presigner.PresignPutObject(ctx, key, expiry)
+}
+
+// GetObject retrieves an object and records the call latency
+func (s *instrumentedStorage) GetObject(ctx context.Context, key string) (domain.ObjectReader, error) {
+	defer observeBackendCall(s.backend, "get_object", time.Now())
+	return s.next.GetObject(ctx, key)
+}
+
+// GetObjectRange retrieves an object range and records the call latency
+func (s *instrumentedStorage) GetObjectRange(ctx context.Context, key string, start, end int64) (domain.ObjectReader, error) {
+	defer observeBackendCall(s.backend, "get_object_range", time.Now())
+	return s.next.GetObjectRange(ctx, key, start, end)
+}
+
+// HeadObject retrieves object metadata and records the call latency
+func (s *instrumentedStorage) HeadObject(ctx context.Context, key string) (*domain.ObjectMetadata, error) {
+	defer observeBackendCall(s.backend, "head_object", time.Now())
+	return s.next.HeadObject(ctx, key)
+}
+
+// DeleteObject removes an object and records the call latency
+func (s *instrumentedStorage) DeleteObject(ctx context.Context, key string) error {
+	defer observeBackendCall(s.backend, "delete_object", time.Now())
+	return s.next.DeleteObject(ctx, key)
+}
+
+// ListObjects lists objects and records the call latency
+func (s *instrumentedStorage) ListObjects(ctx context.Context, prefix, token string) ([]domain.ObjectMetadata, string, error) {
+	defer observeBackendCall(s.backend, "list_objects", time.Now())
+	return s.next.ListObjects(ctx, prefix, token)
+}
+
+// HealthCheck checks the backend and records the call latency
+func (s *instrumentedStorage) HealthCheck(ctx context.Context) error {
+	defer observeBackendCall(s.backend, "health_check", time.Now())
+	return s.next.HealthCheck(ctx)
+}
+
+// instrumentedCache records call latencies of a CacheService
+type instrumentedCache struct {
+	next    domain.CacheService
+	backend string
+}
+
+// InstrumentCache wraps cache so its call latencies are exported on
+// /metrics under the given backend name, e.g. "redis"
+func InstrumentCache(backend string, cache domain.CacheService) domain.CacheService {
+	return &instrumentedCache{next: cache, backend: backend}
+}
+
+// Set stores a value and records the call latency
+func (c *instrumentedCache) Set(ctx context.Context, key, value string, expiration time.Duration) error {
+	defer observeBackendCall(c.backend, "set", time.Now())
+	return c.next.Set(ctx, key, value, expiration)
+}
+
+// SetNX stores a value if absent and records the call latency
+func (c *instrumentedCache) SetNX(ctx context.Context, key, value string, expiration time.Duration) (bool, error) {
+	defer observeBackendCall(c.backend, "setnx", time.Now())
+	return c.next.SetNX(ctx, key, value, expiration)
+}
+
+// Get retrieves a value and records the call latency
+func (c *instrumentedCache) Get(ctx context.Context, key string) (string, error) {
+	defer observeBackendCall(c.backend, "get", time.Now())
+	return c.next.Get(ctx, key)
+}
+
+// Delete removes a key and records the call latency
+func (c *instrumentedCache) Delete(ctx context.Context, key string) error {
+	defer observeBackendCall(c.backend, "delete", time.Now())
+	return c.next.Delete(ctx, key)
+}
+
+// Incr increments a counter and records the call latency
+func (c *instrumentedCache) Incr(ctx context.Context, key string, expiration time.Duration) (int64, error) {
+	defer observeBackendCall(c.backend, "incr", time.Now())
+	return c.next.Incr(ctx, key, expiration)
+}
+
+// IncrBy adds to a counter and records the call latency
+func (c *instrumentedCache) IncrBy(ctx context.Context, key string, delta int64, expiration time.Duration) (int64, error) {
+	defer observeBackendCall(c.backend, "incrby", time.Now())
+	return c.next.IncrBy(ctx, key, delta, expiration)
+}
+
+// TTL retrieves a key's time to live and records the call latency
+func (c *instrumentedCache) TTL(ctx context.Context, key string) (time.Duration, error) {
+	defer observeBackendCall(c.backend, "ttl", time.Now())
+	return c.next.TTL(ctx, key)
+}
+
+// Expire updates a key's expiration and records the call latency
+func (c *instrumentedCache) Expire(ctx context.Context, key string, expiration time.Duration) error {
+	defer observeBackendCall(c.backend, "expire", time.Now())
+	return c.next.Expire(ctx, key, expiration)
+}
+
+// Scan iterates keys and records the call latency
+func (c *instrumentedCache) Scan(ctx context.Context, prefix string, cursor uint64) ([]string, uint64, error) {
+	defer observeBackendCall(c.backend, "scan", time.Now())
+	return c.next.Scan(ctx, prefix, cursor)
+}
+
+// Ping checks the backend and records the call latency
+func (c *instrumentedCache) Ping(ctx context.Context) error {
+	defer observeBackendCall(c.backend, "ping", time.Now())
+	return c.next.Ping(ctx)
+}
@@ -0,0 +1,185 @@
+package http
+
+import (
+	"bufio"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/vchitai/go-s3-sharing/internal/config"
+	"github.com/vchitai/go-s3-sharing/internal/service"
+)
+
+// scrapeMetric fetches /metrics and returns the value of the sample whose
+// name and labels equal series, or zero when it is absent
+func scrapeMetric(t *testing.T, handler http.Handler, series string) float64 {
+	t.Helper()
+
+	req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected /metrics status %d, got %d", http.StatusOK, w.Code)
+	}
+
+	scanner := bufio.NewScanner(w.Body)
+	for scanner.Scan() {
+		name, value, ok := strings.Cut(scanner.Text(), " ")
+		if !ok || name != series {
+			continue
+		}
+		parsed, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			t.Fatalf("invalid value for %s: %q", series, value)
+		}
+		return parsed
+	}
+	return 0
+}
+
+func TestServer_Metrics(t *testing.T) {
+	storage := &mockStorageService{objects: map[string]mockObject{
+		"images/photo.jpg": {contentType: "image/jpeg", data: []byte("jpeg-bytes")},
+	}}
+	cache := &mockCacheService{store: make(map[string]string)}
+	shareService := service.NewShareService(InstrumentStorage("test", storage), InstrumentCache("test", cache), &service.ShareConfig{
+		MaxAgeDays: 90,
+		BaseURL:    "https://example.com",
+	})
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	server := NewServer(&config.Config{}, shareService, logger)
+	handler := server.server.Handler
+
+	okSeries := metricHTTPRequestsTotal + `{code="200",method="GET"}`
+	unauthorizedSeries := metricValidationFailuresTotal + `{reason="unauthorized"}`
+	headSeries := metricBackendCallDuration + `_count{backend="test",operation="head_object"}`
+
+	okBefore := scrapeMetric(t, handler, okSeries)
+	createdBefore := scrapeMetric(t, handler, metricSharesCreatedTotal)
+	unauthorizedBefore := scrapeMetric(t, handler, unauthorizedSeries)
+	bytesBefore := scrapeMetric(t, handler, metricBytesStreamedTotal)
+	headBefore := scrapeMetric(t, handler, headSeries)
+
+	urlPath := createTestShare(t, shareService, "images/photo.jpg")
+
+	req := httptest.NewRequest(http.MethodGet, urlPath, nil)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, w.Code)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, strings.Replace(urlPath, "test-secret", "wrong-secret", 1), nil)
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("expected status %d, got %d", http.StatusUnauthorized, w.Code)
+	}
+
+	// The earlier scrapes are themselves successful GET requests
+	if got := scrapeMetric(t, handler, okSeries) - okBefore; got < 2 {
+		t.Errorf("expected %s to grow by at least 2, grew by %v", okSeries, got)
+	}
+	if got := scrapeMetric(t, handler, unauthorizedSeries) - unauthorizedBefore; got != 1 {
+		t.Errorf("expected %s to grow by 1, grew by %v", unauthorizedSeries, got)
+	}
+	if got := scrapeMetric(t, handler, metricBytesStreamedTotal) - bytesBefore; got != float64(len("jpeg-bytes")) {
+		t.Errorf("expected %s to grow by %d, grew by %v", metricBytesStreamedTotal, len("jpeg-bytes"), got)
+	}
+	if got := scrapeMetric(t, handler, headSeries) - headBefore; got != 1 {
+		t.Errorf("expected %s to grow by 1, grew by %v", headSeries, got)
+	}
+
+	// Shares created through the API are counted
+	req = httptest.NewRequest(http.MethodPost, "/api/shares", strings.NewReader(`{"s3_path":"images/photo.jpg","secret":"test-secret"}`))
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	if got := scrapeMetric(t, handler, metricSharesCreatedTotal) - createdBefore; got != 1 {
+		t.Errorf("expected %s to grow by 1, grew by %v", metricSharesCreatedTotal, got)
+	}
+}
+
+func TestServer_DownloadMetrics(t *testing.T) {
+	_, shareService := newTestHandler(map[string]mockObject{
+		"images/photo.jpg": {contentType: "image/jpeg", data: []byte("jpeg-bytes")},
+	})
+	urlPath := createTestShare(t, shareService, "images/photo.jpg")
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	handler := NewServer(&config.Config{}, shareService, logger).server.Handler
+
+	series := func(metric, kind string) string { return metric + `{type="` + kind + `"}` }
+	rangeBefore := scrapeMetric(t, handler, series(metricDownloadsTotal, downloadRange))
+	fullBefore := scrapeMetric(t, handler, series(metricDownloadsTotal, downloadFull))
+	rangeBytesBefore := scrapeMetric(t, handler, series(metricDownloadBytesTotal, downloadRange))
+	fullBytesBefore := scrapeMetric(t, handler, series(metricDownloadBytesTotal, downloadFull))
+
+	req := httptest.NewRequest(http.MethodGet, urlPath, nil)
+	req.Header.Set("Range", "bytes=0-3")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	if w.Code != http.StatusPartialContent {
+		t.Fatalf("expected status %d, got %d", http.StatusPartialContent, w.Code)
+	}
+
+	if got := scrapeMetric(t, handler, series(metricDownloadsTotal, downloadRange)) - rangeBefore; got != 1 {
+		t.Errorf("expected 1 range download, got %v", got)
+	}
+	if got := scrapeMetric(t, handler, series(metricDownloadBytesTotal, downloadRange)) - rangeBytesBefore; got != 4 {
+		t.Errorf("expected 4 range bytes, got %v", got)
+	}
+	if got := scrapeMetric(t, handler, series(metricDownloadsTotal, downloadFull)) - fullBefore; got != 0 {
+		t.Errorf("expected no full download, got %v", got)
+	}
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, urlPath, nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, w.Code)
+	}
+	if got := scrapeMetric(t, handler, series(metricDownloadsTotal, downloadFull)) - fullBefore; got != 1 {
+		t.Errorf("expected 1 full download, got %v", got)
+	}
+	if got := scrapeMetric(t, handler, series(metricDownloadBytesTotal, downloadFull)) - fullBytesBefore; got != float64(len("jpeg-bytes")) {
+		t.Errorf("expected %d full bytes, got %v", len("jpeg-bytes"), got)
+	}
+}
+
+func TestObserveBreakerState(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	handler := NewServer(&config.Config{}, nil, logger).server.Handler
+	defer ObserveBreakerState(service.BreakerClosed)
+
+	for _, state := range []service.BreakerState{service.BreakerOpen, service.BreakerHalfOpen, service.BreakerClosed} {
+		ObserveBreakerState(state)
+		if value := scrapeMetric(t, handler, metricStorageBreakerState); value != float64(state) {
+			t.Errorf("expected %s state %d, got %v", state, state, value)
+		}
+	}
+}
+
+func TestObserveObjectCacheLookup(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	handler := NewServer(&config.Config{}, nil, logger).server.Handler
+	hits := metricObjectCacheLookupsTotal + `{result="hit"}`
+	misses := metricObjectCacheLookupsTotal + `{result="miss"}`
+	hitsBefore, missesBefore := scrapeMetric(t, handler, hits), scrapeMetric(t, handler, misses)
+
+	ObserveObjectCacheLookup(true)
+	ObserveObjectCacheLookup(true)
+	ObserveObjectCacheLookup(false)
+
+	if got := scrapeMetric(t, handler, hits) - hitsBefore; got != 2 {
+		t.Errorf("expected 2 hits, got %v", got)
+	}
+	if got := scrapeMetric(t, handler, misses) - missesBefore; got != 1 {
+		t.Errorf("expected 1 miss, got %v", got)
+	}
+}
@@ -0,0 +1,136 @@
+package http
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// Rate limiter bucket eviction settings
+const (
+	rateLimitSweepInterval = time.Minute
+	rateLimitIdleTTL       = 3 * time.Minute
+)
+
+// visitor is the token bucket of a single client IP
+type visitor struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+// rateLimiter limits requests per client IP with a token bucket per IP.
+// Buckets idle for longer than rateLimitIdleTTL are evicted in the background.
+type rateLimiter struct {
+	mu       sync.Mutex
+	visitors map[string]*visitor
+	rps      rate.Limit
+	burst    int
+	now      func() time.Time
+	stop     chan struct{}
+	once     sync.Once
+}
+
+// newRateLimiter creates a rate limiter allowing rps requests per second with
+// the given burst per client IP and starts its background sweeper. Call Close
+// to stop the sweeper.
+func newRateLimiter(rps float64, burst int) *rateLimiter {
+	l := &rateLimiter{
+		visitors: make(map[string]*visitor),
+		rps:      rate.Limit(rps),
+		burst:    burst,
+		now:      time.Now,
+		stop:     make(chan struct{}),
+	}
+	go l.sweep(rateLimitSweepInterval)
+	return l
+}
+
+// Middleware rejects requests over the client's limit with 429 Too Many
+// Requests. Probes and metrics scrapes are never limited, so that a busy
+// client sharing their IP cannot fail health checks.
+func (l *rateLimiter) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if isOperationalPath(r.URL.Path) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if delay, ok := l.allow(clientIP(r)); !ok {
+			seconds := int(math.Ceil(delay.Seconds()))
+			if seconds < 1 {
+				seconds = 1
+			}
+			w.Header().Set("Retry-After", strconv.Itoa(seconds))
+			writeErrorResponse(w, CodeRateLimited, "Too many requests", http.StatusTooManyRequests)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// isOperationalPath reports whether urlPath is a health, readiness or metrics
+// endpoint
+func isOperationalPath(urlPath string) bool {
+	return urlPath == "/health" || urlPath == "/ready" || urlPath == "/metrics"
+}
+
+// allow takes a token for ip, returning false and the wait until the next
+// token when the bucket is empty
+func (l *rateLimiter) allow(ip string) (time.Duration, bool) {
+	now := l.now()
+
+	l.mu.Lock()
+	v, ok := l.visitors[ip]
+	if !ok {
+		v = &visitor{limiter: rate.NewLimiter(l.rps, l.burst)}
+		l.visitors[ip] = v
+	}
+	v.lastSeen = now
+	l.mu.Unlock()
+
+	res := v.limiter.ReserveN(now, 1)
+	if !res.OK() {
+		return time.Second, false
+	}
+	if delay := res.DelayFrom(now); delay > 0 {
+		res.CancelAt(now)
+		return delay, false
+	}
+	return 0, true
+}
+
+// Close stops the background sweeper
+func (l *rateLimiter) Close() {
+	l.once.Do(func() { close(l.stop) })
+}
+
+// sweep periodically evicts idle buckets until Close is called
+func (l *rateLimiter) sweep(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			l.evictIdle()
+		case <-l.stop:
+			return
+		}
+	}
+}
+
+// evictIdle removes buckets not used within rateLimitIdleTTL
+func (l *rateLimiter) evictIdle() {
+	cutoff := l.now().Add(-rateLimitIdleTTL)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for ip, v := range l.visitors {
+		if v.lastSeen.Before(cutoff) {
+			delete(l.visitors, ip)
+		}
+	}
+}
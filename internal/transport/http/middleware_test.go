@@ -0,0 +1,106 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestRateLimiter_Burst(t *testing.T) {
+	limiter := newRateLimiter(1, 3)
+	defer limiter.Close()
+
+	now := time.Now()
+	limiter.now = func() time.Time { return now }
+
+	handler := clientIPMiddleware([]string{"10.0.0.0/8"}, limiter.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})))
+
+	send := func(remoteAddr, forwardedFor string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/24/12/31/secret/images/photo.jpg", nil)
+		req.RemoteAddr = remoteAddr
+		if forwardedFor != "" {
+			req.Header.Set("X-Forwarded-For", forwardedFor)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w
+	}
+
+	// The burst is allowed, the next request is rejected
+	for i := 0; i < 3; i++ {
+		if w := send("10.0.0.1:1234", ""); w.Code != http.StatusOK {
+			t.Fatalf("request %d: expected status %d, got %d", i, http.StatusOK, w.Code)
+		}
+	}
+
+	w := send("10.0.0.1:5678", "")
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected status %d, got %d", http.StatusTooManyRequests, w.Code)
+	}
+	retryAfter, err := strconv.Atoi(w.Header().Get("Retry-After"))
+	if err != nil || retryAfter < 1 {
+		t.Errorf("expected positive Retry-After, got %q", w.Header().Get("Retry-After"))
+	}
+
+	// Other clients have their own bucket
+	if w := send("10.0.0.2:1234", ""); w.Code != http.StatusOK {
+		t.Errorf("expected other client status %d, got %d", http.StatusOK, w.Code)
+	}
+
+	// X-Forwarded-For identifies the client behind a trusted proxy
+	for i := 0; i < 3; i++ {
+		send("10.0.0.1:1234", "203.0.113.7, 10.0.0.1")
+	}
+	if w := send("10.0.0.3:1234", "203.0.113.7"); w.Code != http.StatusTooManyRequests {
+		t.Errorf("expected forwarded client status %d, got %d", http.StatusTooManyRequests, w.Code)
+	}
+	// Other peers cannot pose as that client
+	if w := send("192.0.2.9:1234", "203.0.113.7"); w.Code != http.StatusOK {
+		t.Errorf("expected untrusted peer status %d, got %d", http.StatusOK, w.Code)
+	}
+
+	// Probes and metrics are not limited
+	for _, path := range []string{"/health", "/ready", "/metrics"} {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.RemoteAddr = "10.0.0.1:1234"
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Errorf("expected %s status %d, got %d", path, http.StatusOK, w.Code)
+		}
+	}
+
+	// Tokens refill over time
+	now = now.Add(time.Second)
+	if w := send("10.0.0.1:1234", ""); w.Code != http.StatusOK {
+		t.Errorf("expected status %d after refill, got %d", http.StatusOK, w.Code)
+	}
+}
+
+func TestRateLimiter_EvictIdle(t *testing.T) {
+	limiter := newRateLimiter(1, 1)
+	defer limiter.Close()
+
+	now := time.Now()
+	limiter.now = func() time.Time { return now }
+
+	limiter.allow("10.0.0.1")
+	now = now.Add(rateLimitIdleTTL / 2)
+	limiter.allow("10.0.0.2")
+
+	now = now.Add(rateLimitIdleTTL/2 + time.Second)
+	limiter.evictIdle()
+
+	limiter.mu.Lock()
+	defer limiter.mu.Unlock()
+	if _, ok := limiter.visitors["10.0.0.1"]; ok {
+		t.Error("expected idle bucket to be evicted")
+	}
+	if _, ok := limiter.visitors["10.0.0.2"]; !ok {
+		t.Error("expected recent bucket to be kept")
+	}
+}
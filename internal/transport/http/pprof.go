@@ -0,0 +1,29 @@
+package http
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"net/http/pprof"
+)
+
+// newPprofHandler serves the net/http/pprof endpoints under /debug/pprof/.
+// They are registered on their own mux rather than http.DefaultServeMux so
+// that they only exist on the debug listener.
+func newPprofHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	return mux
+}
+
+// serveDebug serves the profiling endpoints on ln until the server is stopped
+func (s *Server) serveDebug(ln net.Listener) {
+	s.logger.Info("starting profiling server", "addr", ln.Addr().String())
+	if err := s.debug.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		s.logger.Error("profiling server error", "error", err)
+	}
+}
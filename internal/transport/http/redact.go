@@ -0,0 +1,60 @@
+package http
+
+import (
+	"strconv"
+	"strings"
+)
+
+// redactedSegment replaces the credential segments of recorded share links
+const redactedSegment = "REDACTED"
+
+// redactPath returns urlPath with the credentials of share links replaced by
+// redactedSegment: the secret of /expiry/secret/path and archive links and the
+// code of short links, which is itself a credential. Spans and access logs
+// record the redacted path so exporters never see link secrets. Tenant paths,
+// /t/tenant/..., are redacted after the tenant prefix.
+func redactPath(urlPath string) string {
+	tenantPrefix := ""
+	if rest, ok := strings.CutPrefix(urlPath, "/t/"); ok {
+		tenant, tail, found := strings.Cut(rest, "/")
+		if !found {
+			return urlPath
+		}
+		tenantPrefix = "/t/" + tenant
+		urlPath = "/" + tail
+	}
+
+	parts := strings.Split(strings.TrimPrefix(urlPath, "/"), "/")
+	switch parts[0] {
+	case "s":
+		if len(parts) > 1 && parts[1] != "" {
+			parts[1] = redactedSegment
+		}
+	case "archive":
+		redactLinkSecret(parts[1:])
+	default:
+		redactLinkSecret(parts)
+	}
+	return tenantPrefix + "/" + strings.Join(parts, "/")
+}
+
+// redactLinkSecret replaces the secret following the expiry of the share link
+// segments parts, leaving parts that do not start with an expiry untouched.
+// The - standing in for the secret of public share links is redacted alike.
+func redactLinkSecret(parts []string) {
+	if len(parts) < 2 || !isDigits(parts[0]) {
+		return
+	}
+	// Legacy links: yy/mm/dd/secret/...
+	if len(parts) > 3 && len(parts[0]) == 2 {
+		parts[3] = redactedSegment
+		return
+	}
+	parts[1] = redactedSegment
+}
+
+// isDigits reports whether s is a non-empty decimal number
+func isDigits(s string) bool {
+	_, err := strconv.ParseUint(s, 10, 64)
+	return err == nil
+}
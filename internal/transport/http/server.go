@@ -2,68 +2,331 @@ package http
 
 import (
 	"context"
+	"crypto/tls"
+	"encoding/json"
 	"fmt"
+	"html/template"
 	"log/slog"
+	"maps"
+	"net"
 	"net/http"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 
 	"github.com/vchitai/go-s3-sharing/internal/config"
 	"github.com/vchitai/go-s3-sharing/internal/service"
+	"github.com/vchitai/go-s3-sharing/internal/version"
 )
 
+// readyTimeout bounds how long readiness dependency checks may take
+const readyTimeout = 2 * time.Second
+
+// processStart is when the process started, as reported by the health uptime
+var processStart = time.Now()
+
 // Server represents the HTTP server
 type Server struct {
 	server *http.Server
-	logger *slog.Logger
+	// debug serves the profiling endpoints on a separate listener; nil
+	// unless profiling is enabled
+	debug    *http.Server
+	limiter  *rateLimiter
+	streams  *streamTracker
+	certFile string
+	keyFile  string
+	logger   *slog.Logger
+	// stopSweeper stops the sweeper of expired share records; nil unless
+	// sweeping is enabled
+	stopSweeper context.CancelFunc
+}
+
+// ServerOption customizes the routing of a Server created by NewServer
+type ServerOption func(*serverOptions)
+
+// serverOptions collects the ServerOptions passed to NewServer
+type serverOptions struct {
+	mux         *http.ServeMux
+	middlewares []Middleware
+	routes      []extraRoute
+	errorPage   *template.Template
+}
+
+// extraRoute is a route added with WithExtraRoute
+type extraRoute struct {
+	pattern string
+	handler http.Handler
+}
+
+// WithMux registers the service's routes on mux instead of a new ServeMux,
+// so that routes the caller registers on it are served alongside them.
+// Registering a route the service also serves panics, as with any ServeMux.
+func WithMux(mux *http.ServeMux) ServerOption {
+	return func(o *serverOptions) { o.mux = mux }
+}
+
+// WithMiddleware wraps every route in mw. Middlewares run in the order they
+// are given, after request logging, tracing, metrics and rate limiting, and
+// after tenant scoping, so they see the request's tenant and its path
+// without the /t/{tenant} prefix.
+func WithMiddleware(mw Middleware) ServerOption {
+	return func(o *serverOptions) { o.middlewares = append(o.middlewares, mw) }
+}
+
+// WithExtraRoute serves handler for pattern next to the service's routes.
+// The handler is served as is, without the API authentication of /api routes.
+func WithExtraRoute(pattern string, handler http.Handler) ServerOption {
+	return func(o *serverOptions) { o.routes = append(o.routes, extraRoute{pattern: pattern, handler: handler}) }
 }
 
-// NewServer creates a new HTTP server
-func NewServer(cfg *config.Config, shareService *service.ShareService, logger *slog.Logger) *Server {
+// WithErrorPage renders the errors of share links with page for requests
+// preferring HTML, such as browsers opening an expired link; other clients
+// keep getting JSON. The template is executed with the ErrorResponse.
+func WithErrorPage(page *template.Template) ServerOption {
+	return func(o *serverOptions) { o.errorPage = page }
+}
+
+// NewServer creates a new HTTP server, customized by opts
+func NewServer(cfg *config.Config, shareService *service.ShareService, logger *slog.Logger, opts ...ServerOption) *Server {
+	var options serverOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+
 	handler := NewHandler(shareService, logger)
+	handler.downloadBytesPerSec = cfg.RateLimit.DownloadBytesPerSec
+	handler.streamSlots = newStreamLimiter(cfg.Server.MaxConcurrentStreams, cfg.Server.StreamQueueTimeout)
+	handler.cacheControl = cacheControl{rules: cfg.Cache.Rules, fallback: cfg.Cache.Default}
+	if cfg.Server.MaxBatchSize > 0 {
+		handler.maxBatchSize = cfg.Server.MaxBatchSize
+	}
+	if cfg.Server.MaxRequestBytes > 0 {
+		handler.maxRequestBytes = int64(cfg.Server.MaxRequestBytes)
+	}
+	if cfg.Server.StreamBufferSize > 0 {
+		handler.streamBuffers = newBufferPool(cfg.Server.StreamBufferSize)
+	}
+	handler.shareCookies = cfg.Security.ShareCookies
+	handler.secureCookies = strings.HasPrefix(cfg.BaseURL, "https://") || cfg.Server.TLSCertFile != ""
 
-	mux := http.NewServeMux()
+	// Require an API key or bearer token on the admin API when configured;
+	// share links stay unauthenticated
+	api := func(h http.HandlerFunc) http.Handler { return h }
+	if cfg.Auth.Enabled() {
+		var keys *apiKeyAuthenticator
+		if len(cfg.Auth.APIKeys) > 0 {
+			keys = newAPIKeyAuthenticator(cfg.Auth, shareService)
+		}
+		var tokens *jwtAuthenticator
+		if cfg.Auth.JWTEnabled() {
+			tokens = newJWTAuthenticator(cfg.Auth)
+		}
+		api = func(h http.HandlerFunc) http.Handler { return apiAuthMiddleware(keys, tokens, h) }
+	}
+
+	// Hardened mode answers every failed share link with the same 404, hiding
+	// which paths are shared
+	conceal := func(h http.Handler) http.Handler { return h }
+	if cfg.Security.HardenedErrors {
+		conceal = func(h http.Handler) http.Handler { return concealMiddleware(cfg.Security.HardenedErrorDelay, h) }
+	}
+	// Browsers opening share links may get an HTML error page
+	errorPage := func(h http.Handler) http.Handler { return h }
+	if options.errorPage != nil {
+		errorPage = func(h http.Handler) http.Handler { return errorPageMiddleware(options.errorPage, h) }
+	}
+
+	mux := options.mux
+	if mux == nil {
+		mux = http.NewServeMux()
+	}
 	// Register specific routes first (most specific to least specific)
-	mux.HandleFunc("/api/shares", handler.HandleCreateShare)
+	mux.Handle("/api/shares", optionsMiddleware(sharesMethods, api(handler.HandleShares)))
+	mux.Handle("/api/shares/audit", optionsMiddleware(shareAuditMethods, api(handler.HandleShareAudit)))
+	mux.Handle("/api/shares/batch", optionsMiddleware(shareBatchMethods, api(handler.HandleCreateShareBatch)))
+	// Share cookies are issued to holders of share links, without API
+	// authentication
+	if cfg.Security.ShareCookies {
+		mux.Handle("/api/shares/cookie", optionsMiddleware(shareCookieMethods, conceal(http.HandlerFunc(handler.HandleShareCookie))))
+	}
+	mux.Handle("/api/uploads", optionsMiddleware(uploadsMethods, api(handler.HandleUpload)))
+	mux.Handle("/api/objects", optionsMiddleware(objectsMethods, api(handler.HandleObjects)))
+	mux.Handle("/archive/", optionsMiddleware(archiveMethods, conceal(downloadTimeoutMiddleware(cfg.Server.DownloadWriteTimeout, http.HandlerFunc(handler.HandleArchive)))))
+	mux.Handle("/s/", optionsMiddleware(shareLinkMethods, errorPage(conceal(downloadTimeoutMiddleware(cfg.Server.DownloadWriteTimeout, http.HandlerFunc(handler.HandleShortLink))))))
 	mux.HandleFunc("/health", handler.HandleHealth)
 	mux.HandleFunc("/ready", handler.HandleReady)
-	// Register the catch-all image handler last
-	mux.HandleFunc("/", handler.HandleImage)
+	mux.Handle("/metrics", promhttp.Handler())
+	// Register the catch-all image handler last; only it is compressed and
+	// exposed to CORS
+	imageHandler := optionsMiddleware(shareLinkMethods, errorPage(conceal(downloadTimeoutMiddleware(cfg.Server.DownloadWriteTimeout, http.HandlerFunc(handler.HandleImage)))))
+	if len(cfg.Compression.ContentTypes) > 0 {
+		imageHandler = compressionMiddleware(cfg.Compression.ContentTypes, imageHandler)
+	}
+	if len(cfg.CORS.AllowedOrigins) > 0 {
+		imageHandler = corsMiddleware(cfg.CORS.AllowedOrigins, imageHandler)
+	}
+	mux.Handle("/", imageHandler)
+	for _, route := range options.routes {
+		mux.Handle(route.pattern, route.handler)
+	}
+
+	middlewares := []Middleware{
+		// Resolve the client IP, honoring X-Forwarded-For from trusted proxies only
+		func(next http.Handler) http.Handler { return clientIPMiddleware(cfg.Server.TrustedProxies, next) },
+		// Log every request with a request ID
+		func(next http.Handler) http.Handler { return requestLoggingMiddleware(logger, next) },
+		// Trace every request, continuing traces propagated by callers
+		tracingMiddleware,
+		// Count every request, including rate-limited ones
+		metricsMiddleware,
+	}
+	// Rate limit every route per client IP when enabled
+	var limiter *rateLimiter
+	if cfg.RateLimit.RPS > 0 {
+		limiter = newRateLimiter(cfg.RateLimit.RPS, cfg.RateLimit.Burst)
+		middlewares = append(middlewares, limiter.Middleware)
+	}
+	// Scope every request to its tenant, then run the injected middlewares
+	// before routing
+	middlewares = append(middlewares, tenantMiddleware)
+	middlewares = append(middlewares, options.middlewares...)
+	root := Chain(mux, middlewares...)
 
 	server := &http.Server{
 		Addr:         ":" + cfg.Server.Port,
-		Handler:      mux,
+		Handler:      root,
 		ReadTimeout:  cfg.Server.ReadTimeout,
 		WriteTimeout: cfg.Server.WriteTimeout,
 		IdleTimeout:  cfg.Server.IdleTimeout,
 	}
 
-	return &Server{
-		server: server,
-		logger: logger,
+	s := &Server{
+		server:  server,
+		limiter: limiter,
+		streams: &handler.streams,
+		logger:  logger,
 	}
+	if cfg.Redis.SweepInterval > 0 {
+		ctx, cancel := context.WithCancel(context.Background())
+		s.stopSweeper = cancel
+		sweeper := newShareSweeper(shareService, logger, cfg.Redis.SweepInterval, slices.Sorted(maps.Keys(cfg.Tenants)))
+		go sweeper.run(ctx)
+	}
+	if cfg.Server.PprofEnabled {
+		// Profiles stream for as long as requested, so only the headers are
+		// bounded
+		s.debug = &http.Server{
+			Addr:              cfg.Server.PprofAddr,
+			Handler:           newPprofHandler(),
+			ReadHeaderTimeout: readyTimeout,
+		}
+	}
+	if cfg.Server.TLSEnabled() {
+		server.TLSConfig = &tls.Config{MinVersion: uint16(cfg.Server.TLSMinVersion)}
+		s.certFile = cfg.Server.TLSCertFile
+		s.keyFile = cfg.Server.TLSKeyFile
+	}
+
+	return s
 }
 
-// Start starts the HTTP server
+// Start starts the HTTP server, serving HTTPS when a TLS certificate is
+// configured, and the profiling server when enabled
 func (s *Server) Start() error {
-	s.logger.Info("starting server", "addr", s.server.Addr)
-	return s.server.ListenAndServe()
+	ln, err := net.Listen("tcp", s.server.Addr)
+	if err != nil {
+		return err
+	}
+	if s.debug != nil {
+		debugLn, err := net.Listen("tcp", s.debug.Addr)
+		if err != nil {
+			ln.Close()
+			return err
+		}
+		go s.serveDebug(debugLn)
+	}
+	return s.serve(ln)
 }
 
-// Stop gracefully stops the HTTP server
+// serve accepts connections on ln until the server is stopped
+func (s *Server) serve(ln net.Listener) error {
+	if s.certFile != "" {
+		s.logger.Info("starting server", "addr", ln.Addr().String(), "tls", true)
+		return s.server.ServeTLS(ln, s.certFile, s.keyFile)
+	}
+
+	s.logger.Info("starting server", "addr", ln.Addr().String(), "tls", false)
+	return s.server.Serve(ln)
+}
+
+// Stop gracefully stops the HTTP server, waiting until ctx is done for
+// in-flight downloads to finish
 func (s *Server) Stop(ctx context.Context) error {
-	s.logger.Info("stopping server")
-	return s.server.Shutdown(ctx)
+	s.logger.Info("stopping server", "active_streams", s.streams.Active())
+	if s.limiter != nil {
+		defer s.limiter.Close()
+	}
+	if s.stopSweeper != nil {
+		s.stopSweeper()
+	}
+	// Profiles in flight are of no use past shutdown, so they are cut off
+	if s.debug != nil {
+		s.debug.Close()
+	}
+
+	err := s.server.Shutdown(ctx)
+	// Shutdown returns once connections are idle or at the deadline; wait for
+	// the streams themselves too so that any cut off are reported
+	if waitErr := s.streams.wait(ctx); waitErr != nil {
+		s.logger.Warn("shutdown deadline reached with downloads still streaming", "active_streams", s.streams.Active())
+		if err == nil {
+			err = waitErr
+		}
+	}
+	return err
 }
 
-// HandleHealth handles health check requests
+// HandleHealth handles health check requests, reporting the running build
 func (h *Handler) HandleHealth(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
-	fmt.Fprint(w, `{"status":"healthy"}`)
+	json.NewEncoder(w).Encode(HealthResponse{
+		Status:        "healthy",
+		Version:       version.Version,
+		GitCommit:     version.GitCommit,
+		BuildTime:     version.BuildTime,
+		UptimeSeconds: int64(time.Since(processStart).Seconds()),
+	})
 }
 
 // HandleReady handles readiness check requests
 func (h *Handler) HandleReady(w http.ResponseWriter, r *http.Request) {
-	// In a real implementation, check dependencies (Redis, S3)
+	// Check dependencies (cache, storage) with a short timeout
+	if h.shareService != nil {
+		ctx, cancel := context.WithTimeout(r.Context(), readyTimeout)
+		defer cancel()
+
+		if failures := h.shareService.CheckDependencies(ctx); len(failures) > 0 {
+			failed := make([]string, 0, len(failures))
+			for name, err := range failures {
+				failed = append(failed, name)
+				h.logger.Error("readiness check failed", "dependency", name, "error", err)
+			}
+			sort.Strings(failed)
+
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			json.NewEncoder(w).Encode(ReadyResponse{
+				Status: "not ready",
+				Failed: failed,
+			})
+			return
+		}
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	fmt.Fprint(w, `{"status":"ready"}`)
@@ -0,0 +1,344 @@
+package http
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"io"
+	"log"
+	"log/slog"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/vchitai/go-s3-sharing/internal/config"
+	"github.com/vchitai/go-s3-sharing/internal/domain"
+	"github.com/vchitai/go-s3-sharing/internal/service"
+)
+
+// writeSelfSignedCert writes a self-signed certificate for 127.0.0.1 into
+// dir and returns the certificate and key file paths with the certificate
+func writeSelfSignedCert(t *testing.T, dir string) (string, string, *x509.Certificate) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "127.0.0.1"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("failed to create certificate: %v", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("failed to parse certificate: %v", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("failed to marshal key: %v", err)
+	}
+
+	certFile := filepath.Join(dir, "cert.pem")
+	keyFile := filepath.Join(dir, "key.pem")
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600); err != nil {
+		t.Fatalf("failed to write certificate: %v", err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600); err != nil {
+		t.Fatalf("failed to write key: %v", err)
+	}
+
+	return certFile, keyFile, cert
+}
+
+func TestServer_TLS(t *testing.T) {
+	certFile, keyFile, cert := writeSelfSignedCert(t, t.TempDir())
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	server := NewServer(&config.Config{
+		Server: config.ServerConfig{
+			TLSCertFile:   certFile,
+			TLSKeyFile:    keyFile,
+			TLSMinVersion: tls.VersionTLS12,
+		},
+	}, nil, logger)
+	// The rejected TLS 1.1 handshake below is expected
+	server.server.ErrorLog = log.New(io.Discard, "", 0)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+
+	served := make(chan error, 1)
+	go func() { served <- server.serve(ln) }()
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := server.Stop(ctx); err != nil {
+			t.Errorf("failed to stop server: %v", err)
+		}
+		if err := <-served; !errors.Is(err, http.ErrServerClosed) {
+			t.Errorf("expected %v, got %v", http.ErrServerClosed, err)
+		}
+	}()
+
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+	client := &http.Client{
+		Timeout: 5 * time.Second,
+		Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{RootCAs: pool},
+		},
+	}
+
+	resp, err := client.Get("https://" + ln.Addr().String() + "/health")
+	if err != nil {
+		t.Fatalf("TLS request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("expected status %d, got %d", http.StatusOK, resp.StatusCode)
+	}
+	if resp.TLS == nil {
+		t.Fatal("expected a TLS connection")
+	}
+	if resp.TLS.Version < tls.VersionTLS12 {
+		t.Errorf("expected TLS 1.2 or newer, got %x", resp.TLS.Version)
+	}
+
+	// Clients limited to versions below the minimum are rejected
+	oldClient := &http.Client{
+		Timeout: 5 * time.Second,
+		Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{RootCAs: pool, MaxVersion: tls.VersionTLS11},
+		},
+	}
+	if resp, err := oldClient.Get("https://" + ln.Addr().String() + "/health"); err == nil {
+		resp.Body.Close()
+		t.Error("expected TLS 1.1 client to be rejected")
+	}
+}
+
+func TestServer_AdminAuth(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	server := NewServer(&config.Config{
+		Auth: config.AuthConfig{JWTSecret: testJWTSecret},
+	}, nil, logger)
+
+	tests := []struct {
+		path           string
+		expectedStatus int
+	}{
+		{path: "/api/shares", expectedStatus: http.StatusUnauthorized},
+		{path: "/api/uploads", expectedStatus: http.StatusUnauthorized},
+		{path: "/api/objects", expectedStatus: http.StatusUnauthorized},
+		{path: "/health", expectedStatus: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			server.server.Handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+			if w.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d", tt.expectedStatus, w.Code)
+			}
+		})
+	}
+}
+
+func TestServer_Pprof(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	shareService := service.NewShareService(
+		&mockStorageService{objects: map[string]mockObject{}},
+		&mockCacheService{store: make(map[string]string)},
+		&service.ShareConfig{MaxAgeDays: 90, BaseURL: "https://example.com"},
+	)
+
+	t.Run("disabled", func(t *testing.T) {
+		server := NewServer(&config.Config{}, shareService, logger)
+		if server.debug != nil {
+			t.Fatal("expected no profiling server when disabled")
+		}
+
+		w := httptest.NewRecorder()
+		server.server.Handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/debug/pprof/", nil))
+		if w.Code == http.StatusOK {
+			t.Errorf("expected no profiling route on the main listener, got status %d", w.Code)
+		}
+	})
+
+	t.Run("enabled", func(t *testing.T) {
+		server := NewServer(&config.Config{
+			Server: config.ServerConfig{PprofEnabled: true, PprofAddr: "127.0.0.1:0"},
+		}, shareService, logger)
+		if server.debug == nil {
+			t.Fatal("expected a profiling server when enabled")
+		}
+
+		for _, path := range []string{"/debug/pprof/", "/debug/pprof/cmdline", "/debug/pprof/heap"} {
+			w := httptest.NewRecorder()
+			server.debug.Handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+			if w.Code != http.StatusOK {
+				t.Errorf("expected status 200 for %s, got %d", path, w.Code)
+			}
+		}
+
+		// The main listener still only serves the share routes
+		w := httptest.NewRecorder()
+		server.server.Handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/debug/pprof/", nil))
+		if w.Code == http.StatusOK {
+			t.Errorf("expected no profiling route on the main listener, got status %d", w.Code)
+		}
+	})
+}
+
+func TestServer_AllowMethods(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	t.Run("disallowed methods", func(t *testing.T) {
+		server := NewServer(&config.Config{}, nil, logger)
+
+		tests := []struct {
+			method        string
+			path          string
+			expectedAllow string
+		}{
+			{method: http.MethodPut, path: "/api/shares", expectedAllow: "GET, HEAD, POST, PATCH, DELETE, OPTIONS"},
+			{method: http.MethodGet, path: "/api/shares/batch", expectedAllow: "POST, OPTIONS"},
+			{method: http.MethodPost, path: "/api/shares/audit", expectedAllow: "GET, OPTIONS"},
+			{method: http.MethodGet, path: "/api/uploads", expectedAllow: "POST, OPTIONS"},
+			{method: http.MethodPost, path: "/api/objects", expectedAllow: "GET, DELETE, OPTIONS"},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+				w := httptest.NewRecorder()
+				server.server.Handler.ServeHTTP(w, httptest.NewRequest(tt.method, tt.path, nil))
+				if w.Code != http.StatusMethodNotAllowed {
+					t.Errorf("expected status %d, got %d", http.StatusMethodNotAllowed, w.Code)
+				}
+				if allow := w.Header().Get("Allow"); allow != tt.expectedAllow {
+					t.Errorf("expected Allow %q, got %q", tt.expectedAllow, allow)
+				}
+			})
+		}
+	})
+
+	t.Run("options without credentials", func(t *testing.T) {
+		server := NewServer(&config.Config{
+			Auth: config.AuthConfig{JWTSecret: testJWTSecret},
+		}, nil, logger)
+
+		tests := []struct {
+			path          string
+			expectedAllow string
+		}{
+			{path: "/api/shares", expectedAllow: "GET, HEAD, POST, PATCH, DELETE, OPTIONS"},
+			{path: "/api/shares/batch", expectedAllow: "POST, OPTIONS"},
+			{path: "/api/objects", expectedAllow: "GET, DELETE, OPTIONS"},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.path, func(t *testing.T) {
+				w := httptest.NewRecorder()
+				server.server.Handler.ServeHTTP(w, httptest.NewRequest(http.MethodOptions, tt.path, nil))
+				if w.Code != http.StatusNoContent {
+					t.Errorf("expected status %d, got %d", http.StatusNoContent, w.Code)
+				}
+				if allow := w.Header().Get("Allow"); allow != tt.expectedAllow {
+					t.Errorf("expected Allow %q, got %q", tt.expectedAllow, allow)
+				}
+			})
+		}
+	})
+}
+
+func TestServer_Options(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	t.Run("middlewares run in order around every route", func(t *testing.T) {
+		var calls []string
+		record := func(name string) func(http.Handler) http.Handler {
+			return func(next http.Handler) http.Handler {
+				return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					calls = append(calls, name+" "+domain.TenantFromContext(r.Context())+" "+r.URL.Path)
+					w.Header().Set("X-Injected", name)
+					next.ServeHTTP(w, r)
+				})
+			}
+		}
+		server := NewServer(&config.Config{}, nil, logger, WithMiddleware(record("outer")), WithMiddleware(record("inner")))
+
+		w := httptest.NewRecorder()
+		server.server.Handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/t/acme/health", nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status %d, got %d", http.StatusOK, w.Code)
+		}
+		if expected := []string{"outer acme /health", "inner acme /health"}; len(calls) != 2 || calls[0] != expected[0] || calls[1] != expected[1] {
+			t.Errorf("expected calls %q, got %q", expected, calls)
+		}
+		if got := w.Header().Get("X-Injected"); got != "inner" {
+			t.Errorf("expected the inner middleware to run last, got %q", got)
+		}
+	})
+
+	t.Run("extra routes are reachable", func(t *testing.T) {
+		server := NewServer(&config.Config{}, nil, logger, WithExtraRoute("/version", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			io.WriteString(w, "v1.2.0")
+		})))
+
+		w := httptest.NewRecorder()
+		server.server.Handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/version", nil))
+		if w.Code != http.StatusOK || w.Body.String() != "v1.2.0" {
+			t.Errorf("expected the extra route, got status %d: %s", w.Code, w.Body.String())
+		}
+
+		w = httptest.NewRecorder()
+		server.server.Handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/health", nil))
+		if w.Code != http.StatusOK {
+			t.Errorf("expected built-in routes to keep working, got status %d", w.Code)
+		}
+	})
+
+	t.Run("routes registered on a provided mux", func(t *testing.T) {
+		mux := http.NewServeMux()
+		mux.HandleFunc("/internal/status", func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusAccepted)
+		})
+		server := NewServer(&config.Config{}, nil, logger, WithMux(mux))
+
+		w := httptest.NewRecorder()
+		server.server.Handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/internal/status", nil))
+		if w.Code != http.StatusAccepted {
+			t.Errorf("expected status %d from the caller's route, got %d", http.StatusAccepted, w.Code)
+		}
+
+		w = httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/health", nil))
+		if w.Code != http.StatusOK {
+			t.Errorf("expected the service's routes on the provided mux, got status %d", w.Code)
+		}
+	})
+}
@@ -0,0 +1,158 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/vchitai/go-s3-sharing/internal/domain"
+)
+
+// shareCookieName names the cookies holding share cookie grants. Each cookie
+// is scoped to the path of its share, so a client keeps one per share and
+// sends every cookie whose share covers the requested path.
+const shareCookieName = "share_grant"
+
+// HandleShareCookie handles POST /api/shares/cookie, exchanging the secret of
+// a valid share link for a signed, HTTP-only cookie granting the share until
+// it expires. Requests carrying the cookie fetch the shared object by its
+// path alone, /path/to/file.jpg, and for prefix shares any object under the
+// prefix. The link is validated as when it is opened, password included.
+func (h *Handler) HandleShareCookie(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	if r.Method != http.MethodPost {
+		h.writeMethodNotAllowed(w, shareCookieMethods...)
+		return
+	}
+
+	var req ShareCookieRequest
+	if !h.decodeBody(w, r, &req) {
+		return
+	}
+	if req.S3Path == "" || req.ExpiresAt.IsZero() {
+		h.writeError(w, "s3_path and expires_at are required", http.StatusBadRequest)
+		return
+	}
+
+	if !h.validateShareLink(w, r, req.ExpiresAt, req.Secret, req.S3Path) {
+		return
+	}
+
+	// The cookie expires with the share, which may outlive the link
+	info, err := h.shareService.GetShareInfo(ctx, req.S3Path)
+	if err != nil {
+		if err == domain.ErrNotFound {
+			h.writeError(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		h.writeError(w, "internal error", http.StatusInternalServerError)
+		h.logger.Error("failed to load share for cookie", "path", req.S3Path, "error", err)
+		return
+	}
+	// Cookies carry their expiry in whole seconds
+	expiresAt := info.ExpiresAt.Truncate(time.Second)
+	value, err := h.shareService.SignShareCookie(ctx, req.S3Path, expiresAt)
+	if err != nil {
+		if err == domain.ErrNotFound {
+			h.writeError(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		h.writeError(w, "internal error", http.StatusInternalServerError)
+		h.logger.Error("failed to sign share cookie", "path", req.S3Path, "error", err)
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     shareCookieName,
+		Value:    value,
+		Path:     shareCookiePath(r, req.S3Path),
+		Expires:  expiresAt,
+		HttpOnly: true,
+		Secure:   h.secureCookies,
+		SameSite: http.SameSiteLaxMode,
+	})
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(ShareCookieResponse{
+		S3Path:    req.S3Path,
+		ExpiresAt: expiresAt,
+	})
+}
+
+// shareCookiePath returns the cookie path scoping a share cookie to the
+// share of s3Path, below the /t/{tenant} prefix for tenant shares
+func shareCookiePath(r *http.Request, s3Path string) string {
+	path := "/" + s3Path
+	if tenantID := domain.TenantFromContext(r.Context()); tenantID != "" {
+		path = tenantPathPrefix + tenantID + path
+	}
+	return (&url.URL{Path: path}).EscapedPath()
+}
+
+// shareCookie returns the grant of a share cookie of r granting the object at
+// s3Path, or nil when share cookies are disabled or no cookie whose signature
+// verifies grants it
+func (h *Handler) shareCookie(r *http.Request, s3Path string) *domain.ShareCookie {
+	if !h.shareCookies {
+		return nil
+	}
+
+	for _, c := range r.Cookies() {
+		if c.Name != shareCookieName {
+			continue
+		}
+		cookie, err := h.shareService.ParseShareCookie(r.Context(), c.Value)
+		if err != nil {
+			h.logger.Debug("ignoring invalid share cookie", "error", err)
+			continue
+		}
+		if cookie.Grants(s3Path) {
+			return cookie
+		}
+	}
+	return nil
+}
+
+// serveCookieShare serves the object at s3Path granted by a share cookie
+func (h *Handler) serveCookieShare(w http.ResponseWriter, r *http.Request, cookie *domain.ShareCookie, s3Path string) {
+	// Audit the access under the share the cookie grants
+	rec := newResponseRecorder(w)
+	defer h.auditAccess(r, cookie.S3Path, rec)
+	w = rec
+
+	if !h.validateShareCookie(w, r, cookie) {
+		return
+	}
+
+	webhookURL := h.claimFirstAccess(r, cookie.S3Path)
+	h.serveShare(w, r, cookie.S3Path, s3Path)
+	if webhookURL != "" {
+		h.notifyFirstAccess(r, cookie.S3Path, webhookURL, rec.status)
+	}
+}
+
+// validateShareCookie checks that a share cookie is unexpired and its share
+// still exists and admits the request, writing the error response and
+// returning false otherwise. The password of protected shares was checked
+// when the cookie was issued.
+func (h *Handler) validateShareCookie(w http.ResponseWriter, r *http.Request, cookie *domain.ShareCookie) bool {
+	err := h.shareService.ValidateShareCookie(r.Context(), cookie)
+	if err != nil {
+		switch err {
+		case domain.ErrExpired:
+			validationFailuresTotal.WithLabelValues("expired").Inc()
+			writeExpiredResponse(w, "share cookie expired", cookie.ExpiresAt)
+		case domain.ErrUnauthorized:
+			validationFailuresTotal.WithLabelValues("unauthorized").Inc()
+			h.writeError(w, "unauthorized", http.StatusUnauthorized)
+		default:
+			h.writeError(w, "internal error", http.StatusInternalServerError)
+			h.logger.Error("share cookie validation failed", "error", err)
+		}
+		return false
+	}
+
+	return h.validateShareRestrictions(w, r, cookie.S3Path)
+}
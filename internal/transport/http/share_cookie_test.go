@@ -0,0 +1,231 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/vchitai/go-s3-sharing/internal/domain"
+	"github.com/vchitai/go-s3-sharing/internal/service"
+)
+
+// newShareCookieTestHandler creates a handler accepting share cookies
+func newShareCookieTestHandler(objects map[string]mockObject) (*Handler, *service.ShareService) {
+	storage := &mockStorageService{objects: objects}
+	shareService := service.NewShareService(storage, &mockCacheService{store: make(map[string]string)}, &service.ShareConfig{
+		MaxAgeDays: 90,
+		BaseURL:    "https://example.com",
+		SigningKey: "test-signing-key",
+	})
+	handler := NewHandler(shareService, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	handler.shareCookies = true
+	return handler, shareService
+}
+
+// requestShareCookie exchanges the secret of the share of s3Path for a share
+// cookie
+func requestShareCookie(handler *Handler, s3Path, secret string, expiresAt time.Time) *httptest.ResponseRecorder {
+	body, _ := json.Marshal(ShareCookieRequest{
+		S3Path:    s3Path,
+		Secret:    secret,
+		ExpiresAt: expiresAt,
+	})
+	req := httptest.NewRequest(http.MethodPost, "/api/shares/cookie", bytes.NewReader(body))
+	w := httptest.NewRecorder()
+	handler.HandleShareCookie(w, req)
+	return w
+}
+
+func TestHandler_ShareCookie(t *testing.T) {
+	handler, shareService := newShareCookieTestHandler(map[string]mockObject{
+		"images/photo.jpg":  {contentType: "image/jpeg", data: []byte("jpeg-bytes")},
+		"gallery/a.jpg":     {contentType: "image/jpeg", data: []byte("jpeg-a")},
+		"gallery/sub/b.png": {contentType: "image/png", data: []byte("png-b")},
+		"private/c.jpg":     {contentType: "image/jpeg", data: []byte("private")},
+	})
+	ctx := context.Background()
+
+	share, err := shareService.CreateShare(ctx, &domain.ShareRequest{
+		S3Path:    "images/photo.jpg",
+		Secret:    "test-secret",
+		ExpiresAt: time.Now().Add(72 * time.Hour),
+	})
+	if err != nil {
+		t.Fatalf("failed to create share: %v", err)
+	}
+	gallery, err := shareService.CreateShare(ctx, &domain.ShareRequest{
+		S3Path:    "gallery/",
+		Secret:    "test-secret",
+		ExpiresAt: time.Now().Add(72 * time.Hour),
+	})
+	if err != nil {
+		t.Fatalf("failed to create share: %v", err)
+	}
+
+	cookieFor := func(t *testing.T, share *domain.ShareResponse, s3Path string) *http.Cookie {
+		t.Helper()
+		w := requestShareCookie(handler, s3Path, "test-secret", share.ExpiresAt)
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+		}
+		cookies := w.Result().Cookies()
+		if len(cookies) != 1 {
+			t.Fatalf("expected a cookie, got %v", cookies)
+		}
+		return cookies[0]
+	}
+
+	t.Run("issues an http-only cookie", func(t *testing.T) {
+		w := requestShareCookie(handler, "images/photo.jpg", "test-secret", share.ExpiresAt)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+		}
+		cookies := w.Result().Cookies()
+		if len(cookies) != 1 || cookies[0].Name != shareCookieName {
+			t.Fatalf("expected a %s cookie, got %v", shareCookieName, cookies)
+		}
+		if cookies[0].Path != "/images/photo.jpg" {
+			t.Errorf("expected the cookie scoped to /images/photo.jpg, got %q", cookies[0].Path)
+		}
+		if !cookies[0].HttpOnly {
+			t.Error("expected an http-only cookie")
+		}
+		if cookies[0].SameSite != http.SameSiteLaxMode {
+			t.Errorf("expected SameSite=Lax, got %v", cookies[0].SameSite)
+		}
+		if got := w.Header().Get("Cache-Control"); got != "no-store" {
+			t.Errorf("expected Cache-Control no-store, got %s", got)
+		}
+
+		var resp ShareCookieResponse
+		if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		if resp.S3Path != "images/photo.jpg" {
+			t.Errorf("expected path images/photo.jpg, got %s", resp.S3Path)
+		}
+		if !resp.ExpiresAt.Equal(cookies[0].Expires) {
+			t.Errorf("expected the cookie to expire at %v, got %v", resp.ExpiresAt, cookies[0].Expires)
+		}
+	})
+
+	t.Run("rejects a wrong secret", func(t *testing.T) {
+		w := requestShareCookie(handler, "images/photo.jpg", "wrong-secret", share.ExpiresAt)
+
+		if w.Code != http.StatusUnauthorized {
+			t.Errorf("expected status %d, got %d", http.StatusUnauthorized, w.Code)
+		}
+		if len(w.Result().Cookies()) != 0 {
+			t.Error("expected no cookie for a wrong secret")
+		}
+	})
+
+	t.Run("scopes tenant cookies below the tenant prefix", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/api/shares/cookie", nil)
+		req = req.WithContext(domain.WithTenant(req.Context(), "acme"))
+
+		if got := shareCookiePath(req, "gallery/"); got != "/t/acme/gallery/" {
+			t.Errorf("expected path /t/acme/gallery/, got %q", got)
+		}
+	})
+
+	t.Run("cookies of several shares", func(t *testing.T) {
+		for _, path := range []string{"/images/photo.jpg", "/gallery/a.jpg"} {
+			req := httptest.NewRequest(http.MethodGet, path, nil)
+			req.AddCookie(cookieFor(t, share, "images/photo.jpg"))
+			req.AddCookie(cookieFor(t, gallery, "gallery/"))
+			w := httptest.NewRecorder()
+			handler.HandleImage(w, req)
+
+			if w.Code != http.StatusOK {
+				t.Errorf("%s: expected status %d, got %d: %s", path, http.StatusOK, w.Code, w.Body.String())
+			}
+		}
+	})
+
+	tests := []struct {
+		name           string
+		cookie         func(t *testing.T) *http.Cookie
+		path           string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "object share",
+			cookie:         func(t *testing.T) *http.Cookie { return cookieFor(t, share, "images/photo.jpg") },
+			path:           "/images/photo.jpg",
+			expectedStatus: http.StatusOK,
+			expectedBody:   "jpeg-bytes",
+		},
+		{
+			name:           "object under a prefix share",
+			cookie:         func(t *testing.T) *http.Cookie { return cookieFor(t, gallery, "gallery/") },
+			path:           "/gallery/sub/b.png",
+			expectedStatus: http.StatusOK,
+			expectedBody:   "png-b",
+		},
+		{
+			name:           "object outside the share",
+			cookie:         func(t *testing.T) *http.Cookie { return cookieFor(t, gallery, "gallery/") },
+			path:           "/private/c.jpg",
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name: "expired cookie",
+			cookie: func(t *testing.T) *http.Cookie {
+				value, err := shareService.SignShareCookie(ctx, "images/photo.jpg", time.Now().Add(-time.Minute))
+				if err != nil {
+					t.Fatalf("failed to sign cookie: %v", err)
+				}
+				return &http.Cookie{Name: shareCookieName, Value: value}
+			},
+			path:           "/images/photo.jpg",
+			expectedStatus: http.StatusForbidden,
+			expectedBody:   "share cookie expired",
+		},
+		{
+			name: "tampered cookie",
+			cookie: func(t *testing.T) *http.Cookie {
+				c := cookieFor(t, share, "images/photo.jpg")
+				c.Value += "x"
+				return c
+			},
+			path:           "/images/photo.jpg",
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:           "no cookie",
+			path:           "/images/photo.jpg",
+			expectedStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.cookie != nil {
+				req.AddCookie(tt.cookie(t))
+			}
+			w := httptest.NewRecorder()
+			handler.HandleImage(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d: %s", tt.expectedStatus, w.Code, w.Body.String())
+			}
+			if tt.expectedBody != "" && !strings.Contains(w.Body.String(), tt.expectedBody) {
+				t.Errorf("expected body containing %q, got %q", tt.expectedBody, w.Body.String())
+			}
+			if w.Code == http.StatusOK && !strings.Contains(w.Header().Get("Cache-Control"), "private") {
+				t.Errorf("expected a private response, got Cache-Control %q", w.Header().Get("Cache-Control"))
+			}
+		})
+	}
+}
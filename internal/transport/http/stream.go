@@ -0,0 +1,200 @@
+package http
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// contextReader fails reads once ctx is done, so that copies stop pulling
+// from storage as soon as the client goes away
+type contextReader struct {
+	ctx    context.Context
+	reader io.Reader
+}
+
+// Read returns the context error once ctx is done and reads otherwise
+func (r *contextReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return r.reader.Read(p)
+}
+
+// downloadTimeoutMiddleware replaces the server's write timeout, sized for
+// API responses, with timeout on download routes, so that large or slow
+// downloads are not cut off mid-stream. Zero removes the write deadline.
+func downloadTimeoutMiddleware(timeout time.Duration, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var deadline time.Time
+		if timeout > 0 {
+			deadline = time.Now().Add(timeout)
+		}
+		// Writers without deadlines, such as test recorders, keep none
+		_ = http.NewResponseController(w).SetWriteDeadline(deadline)
+		next.ServeHTTP(w, r)
+	})
+}
+
+// streamDrainPollInterval is how often shutdown checks for finished streams
+const streamDrainPollInterval = 50 * time.Millisecond
+
+// streamTracker counts the download streams in flight so that shutdown can
+// wait for them to finish
+type streamTracker struct {
+	active atomic.Int64
+}
+
+// begin records the start of a stream of a download of kind and returns the
+// func that ends it
+func (t *streamTracker) begin(kind string) func() {
+	t.active.Add(1)
+	activeStreams.Inc()
+	downloadsTotal.WithLabelValues(kind).Inc()
+	return func() {
+		t.active.Add(-1)
+		activeStreams.Dec()
+	}
+}
+
+// Active returns the number of streams in flight
+func (t *streamTracker) Active() int64 {
+	return t.active.Load()
+}
+
+// wait blocks until no stream is in flight or ctx is done
+func (t *streamTracker) wait(ctx context.Context) error {
+	ticker := time.NewTicker(streamDrainPollInterval)
+	defer ticker.Stop()
+	for t.Active() > 0 {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+	return nil
+}
+
+// streamRetryAfter is the Retry-After, in seconds, of downloads rejected
+// because every stream slot is taken
+const streamRetryAfter = 1
+
+// streamLimiter bounds the download streams in flight with a semaphore of
+// slots
+type streamLimiter struct {
+	slots chan struct{}
+	// queueTimeout is how long downloads wait for a free slot; zero rejects
+	// them at once
+	queueTimeout time.Duration
+}
+
+// newStreamLimiter returns a limiter admitting maxStreams concurrent streams,
+// or nil when maxStreams is zero and streams are unlimited
+func newStreamLimiter(maxStreams int, queueTimeout time.Duration) *streamLimiter {
+	if maxStreams <= 0 {
+		return nil
+	}
+	return &streamLimiter{slots: make(chan struct{}, maxStreams), queueTimeout: queueTimeout}
+}
+
+// acquire takes a slot, waiting up to the queue timeout for one to free up,
+// and returns the func that releases it. It returns false when no slot freed
+// up in time or ctx is done first. A nil limiter admits every stream.
+func (l *streamLimiter) acquire(ctx context.Context) (func(), bool) {
+	if l == nil {
+		return func() {}, true
+	}
+	release := func() { <-l.slots }
+
+	select {
+	case l.slots <- struct{}{}:
+		return release, true
+	default:
+	}
+	if l.queueTimeout <= 0 {
+		return nil, false
+	}
+
+	timer := time.NewTimer(l.queueTimeout)
+	defer timer.Stop()
+	select {
+	case l.slots <- struct{}{}:
+		return release, true
+	case <-timer.C:
+		return nil, false
+	case <-ctx.Done():
+		return nil, false
+	}
+}
+
+// acquireStream takes a stream slot for a download, writing 503 Service
+// Unavailable with a Retry-After header and returning false when every slot
+// stays taken. Callers take the slot before counting the download, so that
+// rejected downloads do not count against the share's limit.
+func (h *Handler) acquireStream(w http.ResponseWriter, r *http.Request) (func(), bool) {
+	release, ok := h.streamSlots.acquire(r.Context())
+	if !ok {
+		streamsRejectedTotal.Inc()
+		w.Header().Set("Retry-After", strconv.Itoa(streamRetryAfter))
+		h.writeErrorCode(w, CodeTooManyStreams, "too many concurrent downloads", http.StatusServiceUnavailable)
+		return nil, false
+	}
+	return release, true
+}
+
+// defaultStreamBufferSize is the size of stream copy buffers unless
+// configured, matching io.Copy
+const defaultStreamBufferSize = 32 << 10
+
+// bufferPool reuses the copy buffers of download streams, so that streams
+// under load do not allocate a buffer each
+type bufferPool struct {
+	pool sync.Pool
+}
+
+// newBufferPool creates a pool of size-byte buffers
+func newBufferPool(size int) *bufferPool {
+	p := &bufferPool{}
+	p.pool.New = func() any {
+		buf := make([]byte, size)
+		return &buf
+	}
+	return p
+}
+
+// writerOnly hides the ReadFrom method of a writer such as
+// http.ResponseWriter, which io.CopyBuffer would use instead of its buffer
+type writerOnly struct {
+	io.Writer
+}
+
+// copyStream copies reader to w through a pooled buffer at the rate limiter
+// admits until reader is drained or ctx is done, counting the bytes streamed
+// for a download of kind
+func (h *Handler) copyStream(ctx context.Context, w io.Writer, reader io.Reader, limiter *rate.Limiter, kind string) error {
+	buf := h.streamBuffers.pool.Get().(*[]byte)
+	defer h.streamBuffers.pool.Put(buf)
+
+	n, err := io.CopyBuffer(writerOnly{w}, throttle(ctx, &contextReader{ctx: ctx, reader: reader}, limiter), *buf)
+	bytesStreamedTotal.Add(float64(n))
+	downloadBytesTotal.WithLabelValues(kind).Add(float64(n))
+	return err
+}
+
+// logStreamError logs a failed stream, at debug level when it failed because
+// the client went away
+func (h *Handler) logStreamError(ctx context.Context, msg string, err error, args ...any) {
+	args = append(args, "error", err)
+	if ctx.Err() != nil {
+		h.logger.DebugContext(ctx, "client disconnected during stream", append(args, "stream", msg)...)
+		return
+	}
+	h.logger.ErrorContext(ctx, msg, args...)
+}
@@ -0,0 +1,513 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/vchitai/go-s3-sharing/internal/config"
+	"github.com/vchitai/go-s3-sharing/internal/domain"
+	"github.com/vchitai/go-s3-sharing/internal/service"
+)
+
+// endlessReader is an ObjectReader streaming zeros until closed
+type endlessReader struct {
+	reads  atomic.Int64
+	closed atomic.Bool
+}
+
+func (r *endlessReader) Read(p []byte) (int, error) {
+	r.reads.Add(1)
+	time.Sleep(time.Millisecond)
+	clear(p)
+	return len(p), nil
+}
+
+func (r *endlessReader) Close() error {
+	r.closed.Store(true)
+	return nil
+}
+
+func (r *endlessReader) ContentType() string {
+	return "application/octet-stream"
+}
+
+func (r *endlessReader) Size() int64 {
+	return 1 << 40
+}
+
+func (r *endlessReader) ETag() string {
+	return ""
+}
+
+func (r *endlessReader) LastModified() time.Time {
+	return time.Time{}
+}
+
+func (r *endlessReader) Checksum() string {
+	return ""
+}
+
+func TestHandler_StreamCancellation(t *testing.T) {
+	body := &endlessReader{}
+	handler, shareService := newTestHandler(map[string]mockObject{
+		"videos/stream.bin": {contentType: "application/octet-stream", data: []byte("x"), reader: body},
+	})
+	var logs bytes.Buffer
+	handler.logger = slog.New(slog.NewTextHandler(&logs, nil))
+	path := createTestShare(t, shareService, "videos/stream.bin")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		handler.HandleImage(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil).WithContext(ctx))
+	}()
+
+	// Let the stream start, then disconnect the client
+	deadline := time.Now().Add(time.Second)
+	for body.reads.Load() == 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("expected the copy to stop promptly after cancellation")
+	}
+
+	if !body.closed.Load() {
+		t.Error("expected the object body to be closed")
+	}
+	if strings.Contains(logs.String(), "level=ERROR") {
+		t.Errorf("expected disconnects not to be logged as errors, got %s", logs.String())
+	}
+}
+
+// slowReader is an ObjectReader streaming size zeros in small delayed chunks
+type slowReader struct {
+	remaining int
+	started   chan struct{}
+}
+
+func (r *slowReader) Read(p []byte) (int, error) {
+	if r.remaining == 0 {
+		return 0, io.EOF
+	}
+	select {
+	case <-r.started:
+	default:
+		close(r.started)
+	}
+	time.Sleep(10 * time.Millisecond)
+	n := min(len(p), r.remaining, 64)
+	clear(p[:n])
+	r.remaining -= n
+	return n, nil
+}
+
+func (r *slowReader) Close() error {
+	return nil
+}
+
+func (r *slowReader) ContentType() string {
+	return "application/octet-stream"
+}
+
+func (r *slowReader) Size() int64 {
+	return int64(r.remaining)
+}
+
+func (r *slowReader) ETag() string {
+	return ""
+}
+
+func (r *slowReader) LastModified() time.Time {
+	return time.Time{}
+}
+
+func (r *slowReader) Checksum() string {
+	return ""
+}
+
+// lockedBuffer is a bytes.Buffer safe for concurrent use, for logs written
+// by requests still running when a test reads them
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+// startStreamingServer serves a share of body and starts downloading it,
+// returning the server and the response once the stream has started
+func startStreamingServer(t *testing.T, body domain.ObjectReader, started <-chan struct{}, logs io.Writer) (*Server, *http.Response) {
+	t.Helper()
+
+	_, shareService := newTestHandler(map[string]mockObject{
+		"videos/stream.bin": {contentType: "application/octet-stream", data: []byte("x"), reader: body},
+	})
+	path := createTestShare(t, shareService, "videos/stream.bin")
+	server := NewServer(&config.Config{}, shareService, slog.New(slog.NewTextHandler(logs, nil)))
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	go server.serve(ln)
+
+	resp, err := http.Get("http://" + ln.Addr().String() + path)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+
+	select {
+	case <-started:
+	case <-time.After(time.Second):
+		t.Fatal("expected the stream to start")
+	}
+	return server, resp
+}
+
+func TestServer_StopDrainsStreams(t *testing.T) {
+	const size = 2048
+	body := &slowReader{remaining: size, started: make(chan struct{})}
+	server, resp := startStreamingServer(t, body, body.started, io.Discard)
+
+	stopped := make(chan error, 1)
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		stopped <- server.Stop(ctx)
+	}()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("expected the stream to finish, got %v", err)
+	}
+	if len(data) != size {
+		t.Errorf("expected %d bytes, got %d", size, len(data))
+	}
+
+	select {
+	case err := <-stopped:
+		if err != nil {
+			t.Errorf("expected a clean stop, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected Stop to return once the stream finished")
+	}
+	if active := server.streams.Active(); active != 0 {
+		t.Errorf("expected no active streams, got %d", active)
+	}
+}
+
+func TestServer_StopDeadline(t *testing.T) {
+	body := &endlessReader{}
+	started := make(chan struct{})
+	go func() {
+		for body.reads.Load() == 0 {
+			time.Sleep(time.Millisecond)
+		}
+		close(started)
+	}()
+	logs := &lockedBuffer{}
+	server, _ := startStreamingServer(t, body, started, logs)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	begin := time.Now()
+	if err := server.Stop(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected %v, got %v", context.DeadlineExceeded, err)
+	}
+	if elapsed := time.Since(begin); elapsed > time.Second {
+		t.Errorf("expected Stop to give up at the deadline, took %v", elapsed)
+	}
+	if !strings.Contains(logs.String(), "active_streams=1") {
+		t.Errorf("expected the active stream count to be logged, got %s", logs.String())
+	}
+}
+
+func TestServer_DownloadWriteTimeout(t *testing.T) {
+	// The stream outlasts the API write timeout many times over
+	const size = 4096
+	tests := []struct {
+		name             string
+		downloadTimeout  time.Duration
+		expectIncomplete bool
+	}{
+		{name: "disabled", downloadTimeout: 0},
+		{name: "longer than the stream", downloadTimeout: 5 * time.Second},
+		{name: "shorter than the stream", downloadTimeout: 100 * time.Millisecond, expectIncomplete: true},
+	}
+
+	for _, tt := range tests {
+		for _, short := range []bool{false, true} {
+			name := tt.name
+			if short {
+				name += " through a short link"
+			}
+			t.Run(name, func(t *testing.T) {
+				testDownloadWriteTimeout(t, size, tt.downloadTimeout, tt.expectIncomplete, short)
+			})
+		}
+	}
+}
+
+// testDownloadWriteTimeout streams size bytes through a share link, or its
+// short link, of a server with downloadTimeout
+func testDownloadWriteTimeout(t *testing.T, size int, downloadTimeout time.Duration, expectIncomplete, short bool) {
+	_, shareService := newTestHandler(map[string]mockObject{
+		"videos/stream.bin": {
+			contentType: "application/octet-stream",
+			data:        []byte("x"),
+			reader:      &slowReader{remaining: size, started: make(chan struct{})},
+		},
+	})
+	resp, err := shareService.CreateShare(context.Background(), &domain.ShareRequest{
+		S3Path:    "videos/stream.bin",
+		Secret:    "test-secret",
+		ExpiresAt: time.Now().Add(time.Hour),
+		Short:     short,
+	})
+	if err != nil {
+		t.Fatalf("failed to create share: %v", err)
+	}
+	path := strings.TrimPrefix(resp.URL, "https://example.com")
+	server := NewServer(&config.Config{Server: config.ServerConfig{
+		WriteTimeout:         100 * time.Millisecond,
+		DownloadWriteTimeout: downloadTimeout,
+	}}, shareService, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	go server.serve(ln)
+	defer server.Stop(context.Background())
+
+	// Buffered headers are lost with the body when the write deadline
+	// passes before the first flush
+	var data []byte
+	download, err := http.Get("http://" + ln.Addr().String() + path)
+	if err == nil {
+		defer download.Body.Close()
+		data, err = io.ReadAll(download.Body)
+	}
+	if expectIncomplete {
+		if err == nil && len(data) == size {
+			t.Error("expected the download to be cut off at its write timeout")
+		}
+		return
+	}
+	if err != nil {
+		t.Fatalf("expected the download to finish, got %v", err)
+	}
+	if len(data) != size {
+		t.Errorf("expected %d bytes, got %d", size, len(data))
+	}
+}
+
+// discardWriter drops writes without implementing io.ReaderFrom, like the
+// response writers wrapped by middleware
+type discardWriter struct{}
+
+func (discardWriter) Write(p []byte) (int, error) {
+	return len(p), nil
+}
+
+func TestHandler_CopyStream(t *testing.T) {
+	data := bytes.Repeat([]byte("0123456789"), 10_000)
+	handler := &Handler{streamBuffers: newBufferPool(4 << 10)}
+
+	for _, name := range []string{"first", "reused buffer"} {
+		t.Run(name, func(t *testing.T) {
+			var out bytes.Buffer
+			if err := handler.copyStream(context.Background(), &out, bytes.NewReader(data), nil, downloadFull); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !bytes.Equal(out.Bytes(), data) {
+				t.Errorf("expected %d bytes copied intact, got %d", len(data), out.Len())
+			}
+		})
+	}
+}
+
+func BenchmarkCopyStream(b *testing.B) {
+	data := make([]byte, 1<<20)
+	ctx := context.Background()
+
+	b.Run("io.Copy", func(b *testing.B) {
+		b.ReportAllocs()
+		b.SetBytes(int64(len(data)))
+		for i := 0; i < b.N; i++ {
+			if _, err := io.Copy(discardWriter{}, &contextReader{ctx: ctx, reader: bytes.NewReader(data)}); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	b.Run("pooled", func(b *testing.B) {
+		handler := &Handler{streamBuffers: newBufferPool(defaultStreamBufferSize)}
+		b.ReportAllocs()
+		b.SetBytes(int64(len(data)))
+		for i := 0; i < b.N; i++ {
+			if err := handler.copyStream(ctx, discardWriter{}, bytes.NewReader(data), nil, downloadFull); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func TestHandler_StreamLimit(t *testing.T) {
+	// holdStream starts an endless download on handler, returning once it
+	// streams and the func that ends it
+	holdStream := func(t *testing.T, handler *Handler, path string, body *endlessReader) func() {
+		t.Helper()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			handler.HandleImage(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil).WithContext(ctx))
+		}()
+
+		deadline := time.Now().Add(time.Second)
+		for body.reads.Load() == 0 && time.Now().Before(deadline) {
+			time.Sleep(time.Millisecond)
+		}
+		if body.reads.Load() == 0 {
+			t.Fatal("expected the stream to start")
+		}
+		return func() {
+			cancel()
+			<-done
+		}
+	}
+
+	newLimitedHandler := func(t *testing.T, queueTimeout time.Duration) (*Handler, string, string, *endlessReader) {
+		body := &endlessReader{}
+		handler, shareService := newTestHandler(map[string]mockObject{
+			"videos/stream.bin": {contentType: "application/octet-stream", data: []byte("x"), reader: body},
+			"docs/small.txt":    {contentType: "text/plain", data: []byte("small")},
+		})
+		handler.streamSlots = newStreamLimiter(1, queueTimeout)
+		return handler, createTestShare(t, shareService, "videos/stream.bin"), createTestShare(t, shareService, "docs/small.txt"), body
+	}
+
+	t.Run("rejects streams over the limit", func(t *testing.T) {
+		handler, streamPath, smallPath, body := newLimitedHandler(t, 0)
+		stop := holdStream(t, handler, streamPath, body)
+		defer stop()
+
+		w := httptest.NewRecorder()
+		handler.HandleImage(w, httptest.NewRequest(http.MethodGet, smallPath, nil))
+
+		if w.Code != http.StatusServiceUnavailable {
+			t.Fatalf("expected status %d, got %d: %s", http.StatusServiceUnavailable, w.Code, w.Body.String())
+		}
+		if got := w.Header().Get("Retry-After"); got != "1" {
+			t.Errorf("expected Retry-After 1, got %q", got)
+		}
+		if !strings.Contains(w.Body.String(), CodeTooManyStreams) {
+			t.Errorf("expected code %s, got %s", CodeTooManyStreams, w.Body.String())
+		}
+
+		// Requests that stream nothing are not limited
+		w = httptest.NewRecorder()
+		handler.HandleImage(w, httptest.NewRequest(http.MethodHead, smallPath, nil))
+		if w.Code != http.StatusOK {
+			t.Errorf("expected HEAD to succeed, got %d", w.Code)
+		}
+		w = httptest.NewRecorder()
+		handler.HandleHealth(w, httptest.NewRequest(http.MethodGet, "/health", nil))
+		if w.Code != http.StatusOK {
+			t.Errorf("expected health checks to succeed, got %d", w.Code)
+		}
+	})
+
+	t.Run("queued streams start once a slot frees up", func(t *testing.T) {
+		handler, streamPath, smallPath, body := newLimitedHandler(t, 5*time.Second)
+		stop := holdStream(t, handler, streamPath, body)
+
+		time.AfterFunc(50*time.Millisecond, stop)
+		w := httptest.NewRecorder()
+		handler.HandleImage(w, httptest.NewRequest(http.MethodGet, smallPath, nil))
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+		}
+		if w.Body.String() != "small" {
+			t.Errorf("expected the object body, got %q", w.Body.String())
+		}
+	})
+
+	t.Run("resizing takes a slot", func(t *testing.T) {
+		body := &endlessReader{}
+		storage := &mockStorageService{objects: map[string]mockObject{
+			"videos/stream.bin": {contentType: "application/octet-stream", data: []byte("x"), reader: body},
+			"images/photo.jpg":  {contentType: "image/jpeg", data: encodeTestJPEG(t, 200, 100)},
+		}}
+		shareService := service.NewShareService(storage, &mockCacheService{store: make(map[string]string)}, &service.ShareConfig{
+			MaxAgeDays:         90,
+			BaseURL:            "https://example.com",
+			ResizeMaxDimension: 1000,
+			ResizeMaxPixels:    1_000_000,
+		})
+		handler := NewHandler(shareService, slog.New(slog.NewTextHandler(io.Discard, nil)))
+		handler.streamSlots = newStreamLimiter(1, 0)
+		stop := holdStream(t, handler, createTestShare(t, shareService, "videos/stream.bin"), body)
+		defer stop()
+		photoPath := createTestShare(t, shareService, "images/photo.jpg")
+		gets := storage.getCalls.Load()
+
+		w := httptest.NewRecorder()
+		handler.HandleImage(w, httptest.NewRequest(http.MethodGet, photoPath+"?w=50", nil))
+
+		if w.Code != http.StatusServiceUnavailable {
+			t.Errorf("expected status %d, got %d", http.StatusServiceUnavailable, w.Code)
+		}
+		if got := storage.getCalls.Load() - gets; got != 0 {
+			t.Errorf("expected the image not to be read for resizing, got %d reads", got)
+		}
+	})
+
+	t.Run("queued streams time out", func(t *testing.T) {
+		handler, streamPath, smallPath, body := newLimitedHandler(t, 20*time.Millisecond)
+		stop := holdStream(t, handler, streamPath, body)
+		defer stop()
+
+		start := time.Now()
+		w := httptest.NewRecorder()
+		handler.HandleImage(w, httptest.NewRequest(http.MethodGet, smallPath, nil))
+
+		if w.Code != http.StatusServiceUnavailable {
+			t.Errorf("expected status %d, got %d", http.StatusServiceUnavailable, w.Code)
+		}
+		if elapsed := time.Since(start); elapsed < 20*time.Millisecond {
+			t.Errorf("expected the request to wait for the queue timeout, waited %v", elapsed)
+		}
+	})
+}
@@ -0,0 +1,73 @@
+package http
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/vchitai/go-s3-sharing/internal/domain"
+	"github.com/vchitai/go-s3-sharing/internal/service"
+)
+
+// shareSweeper periodically deletes share records past their expiry that
+// are still stored, in the default namespace and that of every tenant
+type shareSweeper struct {
+	shares   *service.ShareService
+	logger   *slog.Logger
+	interval time.Duration
+	// tenants lists the configured tenant IDs, swept after the default
+	// namespace
+	tenants []string
+	now     func() time.Time
+}
+
+// newShareSweeper creates a sweeper of the shares of shareService and of
+// each of tenants, running every interval once started
+func newShareSweeper(shareService *service.ShareService, logger *slog.Logger, interval time.Duration, tenants []string) *shareSweeper {
+	return &shareSweeper{
+		shares:   shareService,
+		logger:   logger,
+		interval: interval,
+		tenants:  tenants,
+		now:      time.Now,
+	}
+}
+
+// run sweeps every interval until ctx is done
+func (s *shareSweeper) run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.sweep(ctx)
+		}
+	}
+}
+
+// sweep deletes the expired share records of every namespace, logging how
+// many it reaped. Failures are logged and retried on the next sweep.
+func (s *shareSweeper) sweep(ctx context.Context) {
+	now := s.now()
+	reaped := 0
+	for _, tenantID := range append([]string{""}, s.tenants...) {
+		tenantCtx := ctx
+		if tenantID != "" {
+			tenantCtx = domain.WithTenant(ctx, tenantID)
+		}
+		n, err := s.shares.SweepExpiredShares(tenantCtx, now)
+		reaped += n
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			s.logger.Warn("failed to sweep expired shares", "tenant", tenantID, "error", err)
+		}
+	}
+	if reaped > 0 {
+		s.logger.Info("reaped expired share records", "count", reaped)
+	}
+}
@@ -0,0 +1,58 @@
+package http
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/vchitai/go-s3-sharing/internal/domain"
+)
+
+func TestShareSweeper(t *testing.T) {
+	cache := &mockCacheService{store: make(map[string]string)}
+	_, shareService := newTestHandlerWithMocks(&mockStorageService{objects: map[string]mockObject{
+		"images/photo.jpg": {contentType: "image/jpeg", data: []byte("jpeg-bytes")},
+	}}, cache)
+	createTestShare(t, shareService, "images/photo.jpg")
+	// Tenant records are swept in their own namespace
+	record, err := domain.EncodeShareRecord(&domain.ShareRecord{Secret: "secret", ExpiresAt: time.Now().Add(time.Hour)})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	cache.store["tenant:acme:image-auth:images/photo.jpg"] = record
+
+	logs := &lockedBuffer{}
+	sweeper := newShareSweeper(shareService, slog.New(slog.NewTextHandler(logs, nil)), time.Millisecond, []string{"acme"})
+	// The mock cache never expires keys; a clock past the expiry of both
+	// shares makes them stale
+	sweeper.now = func() time.Time { return time.Now().Add(100 * time.Hour) }
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		sweeper.run(ctx)
+		close(done)
+	}()
+
+	deadline := time.Now().Add(time.Second)
+	for !strings.Contains(logs.String(), "count=2") && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("expected the sweeper to stop when its context is cancelled")
+	}
+
+	if !strings.Contains(logs.String(), "count=2") {
+		t.Errorf("expected the reaped count to be logged, got %s", logs.String())
+	}
+	for _, key := range []string{"image-auth:images/photo.jpg", "tenant:acme:image-auth:images/photo.jpg"} {
+		if _, exists := cache.store[key]; exists {
+			t.Errorf("expected %s to be reaped", key)
+		}
+	}
+}
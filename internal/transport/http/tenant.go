@@ -0,0 +1,54 @@
+package http
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/vchitai/go-s3-sharing/internal/domain"
+)
+
+// tenantHeader selects the tenant of API requests
+const tenantHeader = "X-Tenant-ID"
+
+// tenantPathPrefix selects the tenant of share links, which cannot carry a
+// header, as in /t/{tenant}/{expiry}/{secret}/{path}
+const tenantPathPrefix = "/t/"
+
+// tenantMiddleware scopes each request to the tenant named by the X-Tenant-ID
+// header or a /t/{tenant} path prefix, which is stripped before routing.
+// Requests naming neither belong to the default tenant; invalid tenant IDs
+// and a header contradicting the path are rejected.
+func tenantMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tenantID := r.Header.Get(tenantHeader)
+
+		if rest, ok := strings.CutPrefix(r.URL.Path, tenantPathPrefix); ok {
+			pathTenant, remainder, _ := strings.Cut(rest, "/")
+			if pathTenant == "" || (tenantID != "" && tenantID != pathTenant) {
+				writeTenantError(w)
+				return
+			}
+			tenantID = pathTenant
+
+			r = r.Clone(r.Context())
+			r.URL.Path = "/" + remainder
+			r.URL.RawPath = ""
+		}
+
+		if tenantID == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if !domain.IsValidTenantID(tenantID) {
+			writeTenantError(w)
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(domain.WithTenant(r.Context(), tenantID)))
+	})
+}
+
+// writeTenantError rejects a request naming an invalid or conflicting tenant
+func writeTenantError(w http.ResponseWriter) {
+	writeErrorResponse(w, CodeInvalidTenant, "invalid tenant", http.StatusBadRequest)
+}
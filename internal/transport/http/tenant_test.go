@@ -0,0 +1,126 @@
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/vchitai/go-s3-sharing/internal/domain"
+)
+
+func TestTenantMiddleware(t *testing.T) {
+	tests := []struct {
+		name           string
+		path           string
+		header         string
+		expectedStatus int
+		expectedTenant string
+		expectedPath   string
+	}{
+		{name: "no tenant", path: "/api/shares", expectedStatus: http.StatusOK, expectedPath: "/api/shares"},
+		{name: "header", path: "/api/shares", header: "acme", expectedStatus: http.StatusOK, expectedTenant: "acme", expectedPath: "/api/shares"},
+		{name: "path prefix", path: "/t/acme/1735689599/secret/a.jpg", expectedStatus: http.StatusOK, expectedTenant: "acme", expectedPath: "/1735689599/secret/a.jpg"},
+		{name: "bare path prefix", path: "/t/acme", expectedStatus: http.StatusOK, expectedTenant: "acme", expectedPath: "/"},
+		{name: "matching header and path", path: "/t/acme/health", header: "acme", expectedStatus: http.StatusOK, expectedTenant: "acme", expectedPath: "/health"},
+		{name: "conflicting header and path", path: "/t/acme/health", header: "globex", expectedStatus: http.StatusBadRequest},
+		{name: "empty path tenant", path: "/t//health", expectedStatus: http.StatusBadRequest},
+		{name: "invalid header", path: "/api/shares", header: "acme:image-auth", expectedStatus: http.StatusBadRequest},
+		{name: "invalid path tenant", path: "/t/ac.me/health", expectedStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotTenant, gotPath string
+			handler := tenantMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				gotTenant = domain.TenantFromContext(r.Context())
+				gotPath = r.URL.Path
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.header != "" {
+				req.Header.Set(tenantHeader, tt.header)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Fatalf("expected status %d, got %d", tt.expectedStatus, w.Code)
+			}
+			if gotTenant != tt.expectedTenant {
+				t.Errorf("expected tenant %q, got %q", tt.expectedTenant, gotTenant)
+			}
+			if gotPath != tt.expectedPath {
+				t.Errorf("expected path %q, got %q", tt.expectedPath, gotPath)
+			}
+		})
+	}
+}
+
+func TestHandler_TenantIsolation(t *testing.T) {
+	handler, _ := newTestHandler(map[string]mockObject{
+		"images/photo.jpg": {contentType: "image/jpeg", data: []byte("acme photo")},
+	})
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/shares", handler.HandleShares)
+	mux.HandleFunc("/", handler.HandleImage)
+	root := tenantMiddleware(mux)
+
+	body, _ := json.Marshal(CreateShareRequest{S3Path: "images/photo.jpg", Secret: "acme-secret", ExpiresIn: "1h"})
+	req := httptest.NewRequest(http.MethodPost, "/api/shares", bytes.NewReader(body))
+	req.Header.Set(tenantHeader, "acme")
+	w := httptest.NewRecorder()
+	root.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d creating share, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+
+	var resp CreateShareResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	linkPath, ok := strings.CutPrefix(resp.URL, "https://example.com/t/acme")
+	if !ok {
+		t.Fatalf("expected tenant share URL, got %s", resp.URL)
+	}
+
+	tests := []struct {
+		name           string
+		path           string
+		expectedStatus int
+	}{
+		{name: "owning tenant", path: "/t/acme" + linkPath, expectedStatus: http.StatusOK},
+		{name: "default tenant", path: linkPath, expectedStatus: http.StatusUnauthorized},
+		{name: "other tenant", path: "/t/globex" + linkPath, expectedStatus: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			root.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+			if w.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d", tt.expectedStatus, w.Code)
+			}
+		})
+	}
+
+	t.Run("other tenant cannot revoke", func(t *testing.T) {
+		body, _ := json.Marshal(RevokeShareRequest{S3Path: "images/photo.jpg"})
+		req := httptest.NewRequest(http.MethodDelete, "/api/shares", bytes.NewReader(body))
+		req.Header.Set(tenantHeader, "globex")
+		w := httptest.NewRecorder()
+		root.ServeHTTP(w, req)
+		if w.Code != http.StatusNotFound {
+			t.Errorf("expected status %d, got %d", http.StatusNotFound, w.Code)
+		}
+
+		w = httptest.NewRecorder()
+		root.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/t/acme"+linkPath, nil))
+		if w.Code != http.StatusOK {
+			t.Errorf("expected share to survive a cross-tenant revoke, got status %d", w.Code)
+		}
+	})
+}
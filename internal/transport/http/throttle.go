@@ -0,0 +1,53 @@
+package http
+
+import (
+	"context"
+	"io"
+
+	"golang.org/x/time/rate"
+)
+
+// maxThrottleBurst bounds the bytes a throttled stream may read at once, so
+// that throttled streams stay smooth rather than stalling for whole seconds
+const maxThrottleBurst = 32 * 1024
+
+// newDownloadLimiter returns a limiter admitting bytesPerSec bytes per second,
+// or nil when bytesPerSec is zero and downloads are unlimited
+func newDownloadLimiter(bytesPerSec int) *rate.Limiter {
+	if bytesPerSec <= 0 {
+		return nil
+	}
+	return rate.NewLimiter(rate.Limit(bytesPerSec), min(bytesPerSec, maxThrottleBurst))
+}
+
+// throttledReader reads from reader no faster than limiter admits, giving up
+// as soon as ctx is done
+type throttledReader struct {
+	ctx     context.Context
+	reader  io.Reader
+	limiter *rate.Limiter
+}
+
+// throttle returns reader limited by limiter for the lifetime of ctx, or
+// reader itself when limiter is nil
+func throttle(ctx context.Context, reader io.Reader, limiter *rate.Limiter) io.Reader {
+	if limiter == nil {
+		return reader
+	}
+	return &throttledReader{ctx: ctx, reader: reader, limiter: limiter}
+}
+
+// Read reads at most one burst and waits until the limiter admits the bytes read
+func (r *throttledReader) Read(p []byte) (int, error) {
+	if burst := r.limiter.Burst(); len(p) > burst {
+		p = p[:burst]
+	}
+
+	n, err := r.reader.Read(p)
+	if n > 0 {
+		if waitErr := r.limiter.WaitN(r.ctx, n); waitErr != nil {
+			return 0, waitErr
+		}
+	}
+	return n, err
+}
@@ -0,0 +1,78 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestThrottle(t *testing.T) {
+	t.Run("unlimited", func(t *testing.T) {
+		reader := bytes.NewReader(nil)
+		if throttle(context.Background(), reader, newDownloadLimiter(0)) != io.Reader(reader) {
+			t.Error("expected an unlimited reader to be returned unchanged")
+		}
+	})
+
+	t.Run("minimum duration", func(t *testing.T) {
+		const bytesPerSec = 64 * 1024
+		const size = 96 * 1024
+		data := bytes.Repeat([]byte("x"), size)
+
+		start := time.Now()
+		var out bytes.Buffer
+		n, err := io.Copy(&out, throttle(context.Background(), bytes.NewReader(data), newDownloadLimiter(bytesPerSec)))
+		elapsed := time.Since(start)
+
+		if err != nil || n != size || !bytes.Equal(out.Bytes(), data) {
+			t.Fatalf("expected %d bytes copied intact, got %d, %v", size, n, err)
+		}
+		// The initial burst is free, the rest streams at bytesPerSec
+		minimum := time.Duration(size-maxThrottleBurst) * time.Second / bytesPerSec
+		if elapsed < minimum {
+			t.Errorf("expected copy to take at least %v, took %v", minimum, elapsed)
+		}
+	})
+
+	t.Run("cancellation", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		time.AfterFunc(50*time.Millisecond, cancel)
+
+		start := time.Now()
+		data := bytes.Repeat([]byte("x"), 1024*1024)
+		_, err := io.Copy(io.Discard, throttle(ctx, bytes.NewReader(data), newDownloadLimiter(1024)))
+
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("expected %v, got %v", context.Canceled, err)
+		}
+		if elapsed := time.Since(start); elapsed > time.Second {
+			t.Errorf("expected cancellation to stop the copy promptly, took %v", elapsed)
+		}
+	})
+}
+
+func TestHandler_DownloadThrottle(t *testing.T) {
+	data := bytes.Repeat([]byte("x"), 48*1024)
+	handler, shareService := newTestHandler(map[string]mockObject{
+		"files/large.bin": {contentType: "application/octet-stream", data: data},
+	})
+	handler.downloadBytesPerSec = 32 * 1024
+	path := createTestShare(t, shareService, "files/large.bin")
+
+	start := time.Now()
+	w := httptest.NewRecorder()
+	handler.HandleImage(w, httptest.NewRequest(http.MethodGet, path, nil))
+	elapsed := time.Since(start)
+
+	if w.Code != http.StatusOK || !bytes.Equal(w.Body.Bytes(), data) {
+		t.Fatalf("expected the full object, got status %d with %d bytes", w.Code, w.Body.Len())
+	}
+	if minimum := 500 * time.Millisecond; elapsed < minimum {
+		t.Errorf("expected a throttled download to take at least %v, took %v", minimum, elapsed)
+	}
+}
@@ -0,0 +1,45 @@
+package http
+
+import (
+	"net/http"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracerName identifies the spans started by this package
+const tracerName = "github.com/vchitai/go-s3-sharing/internal/transport/http"
+
+// tracingMiddleware starts a server span per request, continuing any trace
+// propagated by the caller in W3C traceparent headers. The span records the
+// redacted path so that link secrets never reach the collector.
+func tracingMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+		ctx, span := otel.Tracer(tracerName).Start(ctx, r.Method,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				semconv.HTTPRequestMethodKey.String(r.Method),
+				semconv.URLPath(redactPath(r.URL.Path)),
+			),
+		)
+		defer span.End()
+
+		rec := newResponseRecorder(w)
+		r = r.WithContext(ctx)
+		next.ServeHTTP(rec, r)
+
+		// The mux records the matched pattern on the request
+		if r.Pattern != "" {
+			span.SetName(r.Method + " " + r.Pattern)
+			span.SetAttributes(semconv.HTTPRoute(r.Pattern))
+		}
+		span.SetAttributes(semconv.HTTPResponseStatusCode(rec.status))
+		if rec.status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(rec.status))
+		}
+	})
+}
@@ -0,0 +1,121 @@
+package http
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/vchitai/go-s3-sharing/internal/config"
+)
+
+func TestServer_TracingSpans(t *testing.T) {
+	_, shareService := newTestHandler(map[string]mockObject{
+		"images/photo.jpg": {contentType: "image/jpeg", data: []byte("jpeg-bytes")},
+	})
+	urlPath := createTestShare(t, shareService, "images/photo.jpg")
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	server := NewServer(&config.Config{}, shareService, logger)
+
+	// Record only the spans of the download itself
+	recorder := tracetest.NewSpanRecorder()
+	prevProvider, prevPropagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	defer func() {
+		otel.SetTracerProvider(prevProvider)
+		otel.SetTextMapPropagator(prevPropagator)
+	}()
+
+	const (
+		traceID      = "4bf92f3577b34da6a3ce929d0e0e4736"
+		parentSpanID = "00f067aa0ba902b7"
+	)
+	req := httptest.NewRequest(http.MethodGet, urlPath, nil)
+	req.Header.Set("traceparent", "00-"+traceID+"-"+parentSpanID+"-01")
+	w := httptest.NewRecorder()
+	server.server.Handler.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, w.Code)
+	}
+
+	spans := make(map[string]sdktrace.ReadOnlySpan)
+	for _, span := range recorder.Ended() {
+		spans[span.Name()] = span
+	}
+
+	root, ok := spans["GET /"]
+	if !ok {
+		t.Fatalf("expected root span %q, got %v", "GET /", spanNames(recorder.Ended()))
+	}
+	if root.SpanKind() != trace.SpanKindServer {
+		t.Errorf("expected root span kind %v, got %v", trace.SpanKindServer, root.SpanKind())
+	}
+	if got := root.SpanContext().TraceID().String(); got != traceID {
+		t.Errorf("expected trace id %s from traceparent, got %s", traceID, got)
+	}
+	if got := root.Parent().SpanID().String(); got != parentSpanID {
+		t.Errorf("expected root parent %s from traceparent, got %s", parentSpanID, got)
+	}
+
+	for _, attr := range root.Attributes() {
+		if strings.Contains(attr.Value.Emit(), "test-secret") {
+			t.Errorf("expected span attribute %s to omit the link secret, got %q", attr.Key, attr.Value.Emit())
+		}
+	}
+
+	for _, name := range []string{"ShareService.ValidateShare", "ShareService.GetObject"} {
+		span, ok := spans[name]
+		if !ok {
+			t.Errorf("expected span %q, got %v", name, spanNames(recorder.Ended()))
+			continue
+		}
+		if span.Parent().SpanID() != root.SpanContext().SpanID() {
+			t.Errorf("expected %q to be a child of the request span", name)
+		}
+	}
+}
+
+func TestRedactPath(t *testing.T) {
+	tests := []struct {
+		path     string
+		expected string
+	}{
+		{"/1767225600/secret/images/photo.jpg", "/1767225600/REDACTED/images/photo.jpg"},
+		{"/24/12/31/secret/images/photo.jpg", "/24/12/31/REDACTED/images/photo.jpg"},
+		{"/archive/1767225600/secret/images/", "/archive/1767225600/REDACTED/images/"},
+		{"/s/abc123", "/s/REDACTED"},
+		{"/t/acme/1767225600/secret/images/photo.jpg", "/t/acme/1767225600/REDACTED/images/photo.jpg"},
+		{"/t/acme/s/abc123", "/t/acme/s/REDACTED"},
+		{"/images/photo.jpg", "/images/photo.jpg"},
+		{"/api/shares", "/api/shares"},
+		{"/health", "/health"},
+		{"/", "/"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			if got := redactPath(tt.path); got != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+// spanNames lists the names of spans for failure messages
+func spanNames(spans []sdktrace.ReadOnlySpan) []string {
+	names := make([]string, 0, len(spans))
+	for _, span := range spans {
+		names = append(names, span.Name())
+	}
+	return names
+}
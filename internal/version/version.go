@@ -0,0 +1,15 @@
+// Package version holds the build information of the running binary, set at
+// link time with -ldflags, e.g.
+//
+//	-X github.com/vchitai/go-s3-sharing/internal/version.Version=v1.2.0
+package version
+
+// Build information; binaries built without -ldflags report the defaults
+var (
+	// Version is the release version, e.g. the output of git describe
+	Version = "dev"
+	// GitCommit is the commit the binary was built from
+	GitCommit = "unknown"
+	// BuildTime is when the binary was built, in UTC
+	BuildTime = "unknown"
+)